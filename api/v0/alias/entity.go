@@ -0,0 +1,42 @@
+package alias
+
+// SetAliasRequest is a request to create or update an alias
+// so that it points to the address of a service
+type SetAliasRequest struct {
+	// Name is the human readable identifier of the service
+	Name string `json:"name"`
+
+	// Address of the service the alias points to
+	Address string `json:"address"`
+}
+
+// GetAliasRequest is a request to retrieve an alias
+type GetAliasRequest struct {
+	// Name of the alias
+	Name string `json:"name"`
+}
+
+// RemoveAliasRequest is a request to remove an alias
+type RemoveAliasRequest struct {
+	// Name of the alias
+	Name string `json:"name"`
+}
+
+// ListAliasesRequest is a request to retrieve all the aliases
+// managed by the gateway
+type ListAliasesRequest struct{}
+
+// Alias maps a human readable name to the address of a service
+type Alias struct {
+	// Name is the human readable identifier of the service
+	Name string `json:"name"`
+
+	// Address of the service the alias points to
+	Address string `json:"address"`
+}
+
+// ListAliasesResponse is the response to a ListAliasesRequest
+type ListAliasesResponse struct {
+	// Aliases managed by the gateway
+	Aliases []Alias `json:"aliases"`
+}
@@ -0,0 +1,143 @@
+package alias
+
+import (
+	"context"
+
+	backend "github.com/oasislabs/oasis-gateway/backend/core"
+	"github.com/oasislabs/oasis-gateway/errors"
+	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/oasislabs/oasis-gateway/rpc"
+)
+
+// Client interface for the underlying operations needed for the API
+// implementation
+type Client interface {
+	// SetAlias creates or updates an alias
+	SetAlias(context.Context, backend.SetAliasRequest) errors.Err
+
+	// GetAlias retrieves an alias
+	GetAlias(context.Context, backend.GetAliasRequest) (backend.Alias, errors.Err)
+
+	// RemoveAlias removes an alias
+	RemoveAlias(context.Context, backend.RemoveAliasRequest) errors.Err
+
+	// ListAliases retrieves all the aliases
+	ListAliases(context.Context) ([]backend.Alias, errors.Err)
+}
+
+// Services required by the AliasHandler execution
+type Services struct {
+	Logger log.Logger
+	Client Client
+}
+
+// AliasHandler implements the handlers for alias management
+type AliasHandler struct {
+	logger log.Logger
+	client Client
+}
+
+// SetAlias creates or updates an alias
+func (h AliasHandler) SetAlias(ctx context.Context, v interface{}) (interface{}, error) {
+	req := v.(*SetAliasRequest)
+
+	if err := h.client.SetAlias(ctx, backend.SetAliasRequest{
+		Name:    req.Name,
+		Address: req.Address,
+	}); err != nil {
+		h.logger.Debug(ctx, "request failed", log.MapFields{
+			"call_type": "SetAliasFailure",
+			"name":      req.Name,
+			"address":   req.Address,
+		}, err)
+		return nil, err
+	}
+
+	h.logger.Info(ctx, "alias set", log.MapFields{
+		"call_type": "SetAliasSuccess",
+		"name":      req.Name,
+		"address":   req.Address,
+	})
+
+	return Alias{Name: req.Name, Address: req.Address}, nil
+}
+
+// GetAlias retrieves an alias
+func (h AliasHandler) GetAlias(ctx context.Context, v interface{}) (interface{}, error) {
+	req := v.(*GetAliasRequest)
+
+	alias, err := h.client.GetAlias(ctx, backend.GetAliasRequest{Name: req.Name})
+	if err != nil {
+		h.logger.Debug(ctx, "request failed", log.MapFields{
+			"call_type": "GetAliasFailure",
+			"name":      req.Name,
+		}, err)
+		return nil, err
+	}
+
+	return Alias{Name: alias.Name, Address: alias.Address}, nil
+}
+
+// RemoveAlias removes an alias
+func (h AliasHandler) RemoveAlias(ctx context.Context, v interface{}) (interface{}, error) {
+	req := v.(*RemoveAliasRequest)
+
+	if err := h.client.RemoveAlias(ctx, backend.RemoveAliasRequest{Name: req.Name}); err != nil {
+		h.logger.Debug(ctx, "request failed", log.MapFields{
+			"call_type": "RemoveAliasFailure",
+			"name":      req.Name,
+		}, err)
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// ListAliases retrieves all the aliases
+func (h AliasHandler) ListAliases(ctx context.Context, v interface{}) (interface{}, error) {
+	_ = v.(*ListAliasesRequest)
+
+	aliases, err := h.client.ListAliases(ctx)
+	if err != nil {
+		h.logger.Debug(ctx, "request failed", log.MapFields{
+			"call_type": "ListAliasesFailure",
+		}, err)
+		return nil, err
+	}
+
+	res := ListAliasesResponse{Aliases: make([]Alias, 0, len(aliases))}
+	for _, alias := range aliases {
+		res.Aliases = append(res.Aliases, Alias{Name: alias.Name, Address: alias.Address})
+	}
+
+	return res, nil
+}
+
+func NewAliasHandler(services Services) AliasHandler {
+	if services.Client == nil {
+		panic("Client must be provided as a service")
+	}
+	if services.Logger == nil {
+		panic("Logger must be provided as a service")
+	}
+
+	return AliasHandler{
+		logger: services.Logger.ForClass("alias", "handler"),
+		client: services.Client,
+	}
+}
+
+// BindHandler binds the alias handler to the provided
+// HandlerBinder
+func BindHandler(services Services, binder rpc.HandlerBinder) {
+	handler := NewAliasHandler(services)
+
+	binder.Bind("POST", "/v0/api/alias/set", rpc.HandlerFunc(handler.SetAlias),
+		rpc.EntityFactoryFunc(func() interface{} { return &SetAliasRequest{} }))
+	binder.Bind("POST", "/v0/api/alias/get", rpc.HandlerFunc(handler.GetAlias),
+		rpc.EntityFactoryFunc(func() interface{} { return &GetAliasRequest{} }))
+	binder.Bind("POST", "/v0/api/alias/remove", rpc.HandlerFunc(handler.RemoveAlias),
+		rpc.EntityFactoryFunc(func() interface{} { return &RemoveAliasRequest{} }))
+	binder.Bind("GET", "/v0/api/alias/list", rpc.HandlerFunc(handler.ListAliases),
+		rpc.EntityFactoryFunc(func() interface{} { return &ListAliasesRequest{} }))
+}
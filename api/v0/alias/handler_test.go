@@ -0,0 +1,105 @@
+package alias
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+
+	backend "github.com/oasislabs/oasis-gateway/backend/core"
+	"github.com/oasislabs/oasis-gateway/errors"
+	"github.com/oasislabs/oasis-gateway/log"
+	mqueue "github.com/oasislabs/oasis-gateway/mqueue/core"
+	"github.com/stretchr/testify/assert"
+)
+
+var Context = context.TODO()
+
+var Logger = log.NewLogrus(log.LogrusLoggerProperties{
+	Output: ioutil.Discard,
+})
+
+const address = "0x0a51514857B379A521C580a10822Fd8A7aC491A0"
+
+type registryClient struct {
+	registry *backend.AliasRegistry
+}
+
+func (c registryClient) SetAlias(ctx context.Context, req backend.SetAliasRequest) errors.Err {
+	return c.registry.Set(ctx, backend.Alias{Name: req.Name, Address: req.Address})
+}
+
+func (c registryClient) GetAlias(ctx context.Context, req backend.GetAliasRequest) (backend.Alias, errors.Err) {
+	return c.registry.Get(ctx, req.Name)
+}
+
+func (c registryClient) RemoveAlias(ctx context.Context, req backend.RemoveAliasRequest) errors.Err {
+	return c.registry.Remove(ctx, req.Name)
+}
+
+func (c registryClient) ListAliases(ctx context.Context) ([]backend.Alias, errors.Err) {
+	return c.registry.List(ctx)
+}
+
+func createAliasHandler() AliasHandler {
+	return NewAliasHandler(Services{
+		Logger: Logger,
+		Client: registryClient{registry: backend.NewAliasRegistry(mqueue.NewMemStore())},
+	})
+}
+
+func TestSetAliasOK(t *testing.T) {
+	h := createAliasHandler()
+
+	_, err := h.SetAlias(Context, &SetAliasRequest{Name: "token", Address: address})
+	assert.Nil(t, err)
+
+	v, err := h.GetAlias(Context, &GetAliasRequest{Name: "token"})
+	assert.Nil(t, err)
+	assert.Equal(t, Alias{Name: "token", Address: address}, v)
+}
+
+func TestSetAliasErrInvalidName(t *testing.T) {
+	h := createAliasHandler()
+
+	_, err := h.SetAlias(Context, &SetAliasRequest{Name: address, Address: address})
+	assert.Error(t, err)
+	assert.Equal(t, errors.ErrInvalidAlias, err.(errors.Err).ErrorCode())
+}
+
+func TestSetAliasErrInvalidAddress(t *testing.T) {
+	h := createAliasHandler()
+
+	_, err := h.SetAlias(Context, &SetAliasRequest{Name: "token", Address: "0x01"})
+	assert.Error(t, err)
+	assert.Equal(t, errors.ErrInvalidAddress, err.(errors.Err).ErrorCode())
+}
+
+func TestRemoveAliasOK(t *testing.T) {
+	h := createAliasHandler()
+
+	_, err := h.SetAlias(Context, &SetAliasRequest{Name: "token", Address: address})
+	assert.Nil(t, err)
+
+	_, err = h.RemoveAlias(Context, &RemoveAliasRequest{Name: "token"})
+	assert.Nil(t, err)
+
+	_, err = h.GetAlias(Context, &GetAliasRequest{Name: "token"})
+	assert.Error(t, err)
+	assert.Equal(t, errors.ErrAliasNotFound, err.(errors.Err).ErrorCode())
+}
+
+func TestListAliasesOK(t *testing.T) {
+	h := createAliasHandler()
+
+	_, err := h.SetAlias(Context, &SetAliasRequest{Name: "b", Address: address})
+	assert.Nil(t, err)
+	_, err = h.SetAlias(Context, &SetAliasRequest{Name: "a", Address: address})
+	assert.Nil(t, err)
+
+	v, err := h.ListAliases(Context, &ListAliasesRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, ListAliasesResponse{Aliases: []Alias{
+		{Name: "a", Address: address},
+		{Name: "b", Address: address},
+	}}, v)
+}
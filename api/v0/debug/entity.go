@@ -0,0 +1,48 @@
+package debug
+
+// TraceTransactionRequest is a request to re-execute a
+// transaction with tracing enabled
+type TraceTransactionRequest struct {
+	// Hash is the hex encoded hash of the transaction
+	Hash string `json:"hash"`
+}
+
+// TraceStep is a step of the execution of a traced transaction
+type TraceStep struct {
+	// Pc is the program counter of the step
+	Pc uint64 `json:"pc"`
+
+	// Op is the name of the opcode executed
+	Op string `json:"op"`
+
+	// Gas is the gas available before the step
+	Gas uint64 `json:"gas"`
+
+	// GasCost is the gas consumed by the step
+	GasCost uint64 `json:"gasCost"`
+
+	// Depth is the call depth of the step
+	Depth int `json:"depth"`
+
+	// Error if set is the error that stopped the execution
+	Error string `json:"error,omitempty"`
+}
+
+// TraceTransactionResponse is the trace of the
+// execution of a transaction
+type TraceTransactionResponse struct {
+	// Hash is the hash of the transaction traced
+	Hash string `json:"hash"`
+
+	// Failed is true if the execution of the transaction failed
+	Failed bool `json:"failed"`
+
+	// Gas is the gas used by the transaction
+	Gas uint64 `json:"gas"`
+
+	// ReturnValue is the hex encoded data returned by the transaction
+	ReturnValue string `json:"returnValue"`
+
+	// Steps are the steps of the execution of the transaction
+	Steps []TraceStep `json:"steps"`
+}
@@ -0,0 +1,97 @@
+package debug
+
+import (
+	"context"
+
+	backend "github.com/oasislabs/oasis-gateway/backend/core"
+	"github.com/oasislabs/oasis-gateway/errors"
+	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/oasislabs/oasis-gateway/rpc"
+)
+
+// Client interface for the underlying operations needed for the API
+// implementation
+type Client interface {
+	// TraceTransaction re-executes a transaction with tracing enabled
+	TraceTransaction(context.Context, backend.TraceTransactionRequest) (backend.TraceTransactionResponse, errors.Err)
+}
+
+// Services required by the DebugHandler execution
+type Services struct {
+	Logger log.Logger
+	Client Client
+}
+
+// DebugHandler implements the handlers that help developers
+// debug the services they use through the gateway
+type DebugHandler struct {
+	logger log.Logger
+	client Client
+}
+
+// TraceTransaction re-executes a transaction with tracing enabled and
+// returns the steps of its execution
+func (h DebugHandler) TraceTransaction(ctx context.Context, v interface{}) (interface{}, error) {
+	req := v.(*TraceTransactionRequest)
+
+	if len(req.Hash) == 0 {
+		err := errors.New(errors.ErrInvalidTransactionHash, nil)
+		h.logger.Debug(ctx, "received empty hash", log.MapFields{
+			"call_type": "TraceTransactionFailure",
+		}, err)
+		return nil, err
+	}
+
+	trace, err := h.client.TraceTransaction(ctx, backend.TraceTransactionRequest{Hash: req.Hash})
+	if err != nil {
+		h.logger.Debug(ctx, "request failed", log.MapFields{
+			"call_type": "TraceTransactionFailure",
+			"hash":      req.Hash,
+		}, err)
+		return nil, err
+	}
+
+	res := TraceTransactionResponse{
+		Hash:        trace.Hash,
+		Failed:      trace.Failed,
+		Gas:         trace.Gas,
+		ReturnValue: trace.ReturnValue,
+		Steps:       make([]TraceStep, 0, len(trace.Steps)),
+	}
+	for _, step := range trace.Steps {
+		res.Steps = append(res.Steps, TraceStep{
+			Pc:      step.Pc,
+			Op:      step.Op,
+			Gas:     step.Gas,
+			GasCost: step.GasCost,
+			Depth:   step.Depth,
+			Error:   step.Error,
+		})
+	}
+
+	return res, nil
+}
+
+// NewDebugHandler creates a new instance of a debug handler
+func NewDebugHandler(services Services) DebugHandler {
+	if services.Logger == nil {
+		panic("Logger must be provided as a service")
+	}
+	if services.Client == nil {
+		panic("Client must be provided as a service")
+	}
+
+	return DebugHandler{
+		logger: services.Logger.ForClass("debug", "handler"),
+		client: services.Client,
+	}
+}
+
+// BindHandler binds the debug handler to the provided
+// HandlerBinder
+func BindHandler(services Services, binder rpc.HandlerBinder) {
+	handler := NewDebugHandler(services)
+
+	binder.Bind("POST", "/v0/api/debug/traceTransaction", rpc.HandlerFunc(handler.TraceTransaction),
+		rpc.EntityFactoryFunc(func() interface{} { return &TraceTransactionRequest{} }))
+}
@@ -0,0 +1,63 @@
+package debug
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+
+	backend "github.com/oasislabs/oasis-gateway/backend/core"
+	"github.com/oasislabs/oasis-gateway/errors"
+	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/stretchr/testify/assert"
+)
+
+var Context = context.TODO()
+
+var Logger = log.NewLogrus(log.LogrusLoggerProperties{
+	Output: ioutil.Discard,
+})
+
+type mockClient struct {
+	res backend.TraceTransactionResponse
+	err errors.Err
+}
+
+func (c mockClient) TraceTransaction(ctx context.Context, req backend.TraceTransactionRequest) (backend.TraceTransactionResponse, errors.Err) {
+	return c.res, c.err
+}
+
+func TestTraceTransactionOK(t *testing.T) {
+	h := NewDebugHandler(Services{Logger: Logger, Client: mockClient{
+		res: backend.TraceTransactionResponse{
+			Hash:        "0x01",
+			Gas:         21000,
+			ReturnValue: "0x",
+			Steps:       []backend.TraceStep{{Pc: 0, Op: "PUSH1", Gas: 100, GasCost: 3, Depth: 1}},
+		},
+	}})
+
+	v, err := h.TraceTransaction(Context, &TraceTransactionRequest{Hash: "0x01"})
+	assert.Nil(t, err)
+	assert.Equal(t, TraceTransactionResponse{
+		Hash:        "0x01",
+		Gas:         21000,
+		ReturnValue: "0x",
+		Steps:       []TraceStep{{Pc: 0, Op: "PUSH1", Gas: 100, GasCost: 3, Depth: 1}},
+	}, v)
+}
+
+func TestTraceTransactionErrEmptyHash(t *testing.T) {
+	h := NewDebugHandler(Services{Logger: Logger, Client: mockClient{}})
+
+	_, err := h.TraceTransaction(Context, &TraceTransactionRequest{})
+	assert.Equal(t, "[2026] error code InputError with desc Provided invalid transaction hash.", err.Error())
+}
+
+func TestTraceTransactionErrClient(t *testing.T) {
+	h := NewDebugHandler(Services{Logger: Logger, Client: mockClient{
+		err: errors.New(errors.ErrUnsupportedByBackend, nil),
+	}})
+
+	_, err := h.TraceTransaction(Context, &TraceTransactionRequest{Hash: "0x01"})
+	assert.Error(t, err)
+}
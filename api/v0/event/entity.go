@@ -28,6 +28,10 @@ type SubscribeRequest struct {
 	// Filter is a url encoded list of query parameters that specify
 	// filters to be applied to the subscribed topic
 	Filter string `json:"filter"`
+
+	// FromBlock if set is the block from which the historical events
+	// are delivered to the subscription before the live events
+	FromBlock *uint64 `json:"fromBlock,omitempty"`
 }
 
 // SubscribeResponse returns an AsyncResponse which contains the ID
@@ -47,12 +51,26 @@ type PollEventRequest struct {
 	Offset uint64 `json:"offset"`
 
 	// Count for the number of items the client would prefer to receive
-	// at most from a single response
+	// at most from a single response. If not set the default count of
+	// the gateway is used, and it is capped to the maximum count
 	Count uint `json:"count"`
 
 	// DiscardPrevious allows the client to define whether the server should
 	// discard all the events that have a sequence number lower than the offer
 	DiscardPrevious bool `json:"discardPrevious"`
+
+	// Types if set restricts the events returned to the ones of the
+	// provided types. Options are data, logRemoved, error and warning
+	Types []string `json:"types,omitempty"`
+
+	// Since if set retrieves the events produced at or after Since
+	// instead of the events starting at Offset. It is an RFC 3339
+	// timestamp such as 2024-05-01T00:00:00Z
+	Since string `json:"since,omitempty"`
+
+	// Until if set restricts the events retrieved by time to the
+	// ones produced before Until. It is an RFC 3339 timestamp
+	Until string `json:"until,omitempty"`
 }
 
 // PollEventResponse is the list of events that are returned for
@@ -62,6 +80,13 @@ type PollEventResponse struct {
 	// start
 	Offset uint64 `json:"offset"`
 
+	// Count is the number of events the server retrieved at most for
+	// the poll, after applying the default count and the maximum count
+	Count uint `json:"count"`
+
+	// MaxCount is the maximum number of events returned by a poll
+	MaxCount uint `json:"maxCount"`
+
 	// Events is the list of events that the server has starting from
 	// the provided Offset
 	Events []Event `json:"events"`
@@ -100,6 +125,35 @@ type ErrorEvent struct {
 	Cause rpc.Error `json:"cause"`
 }
 
+// LogRemovedEvent is the event that can be polled by the user when
+// a log delivered in a DataEvent is removed from the chain by a
+// reorganization, so that the client can invalidate the state
+// derived from it
+type LogRemovedEvent struct {
+	// ID to identify the event itself within the sequence of events.
+	ID uint64 `json:"id"`
+
+	// Offset is the ID of the DataEvent of the removed log
+	Offset uint64 `json:"offset"`
+}
+
+// WarningEvent is the event that can be polled by the user when
+// the consumer of a subscription is lagging behind and the
+// subscription may be closed
+type WarningEvent struct {
+	// ID to identify the event itself within the sequence of events.
+	ID uint64 `json:"id"`
+
+	// Quota is the name of the quota the consumer exceeded
+	Quota string `json:"quota"`
+
+	// Used is the amount of the quota used by the consumer
+	Used uint64 `json:"used"`
+
+	// Limit is the amount of the quota the consumer may use
+	Limit uint64 `json:"limit"`
+}
+
 // EventID is the implementation of Event for DataEvent
 func (e DataEvent) EventID() uint64 {
 	return e.ID
@@ -109,3 +163,33 @@ func (e DataEvent) EventID() uint64 {
 func (e ErrorEvent) EventID() uint64 {
 	return e.ID
 }
+
+// EventID is the implementation of Event for LogRemovedEvent
+func (e LogRemovedEvent) EventID() uint64 {
+	return e.ID
+}
+
+// EventID is the implementation of Event for WarningEvent
+func (e WarningEvent) EventID() uint64 {
+	return e.ID
+}
+
+// IssueTokenRequest is a request to mint a capability token that
+// grants poll-only access to the events of the session of the user
+type IssueTokenRequest struct {
+	// ExpiresIn is the number of seconds for which the token is
+	// valid. If it is not set or it exceeds the maximum allowed by
+	// the gateway, the maximum is used
+	ExpiresIn uint64 `json:"expiresIn"`
+}
+
+// IssueTokenResponse is the response to an IssueTokenRequest
+type IssueTokenResponse struct {
+	// Token is the capability token that the client presents
+	// in the X-OASIS-CAPABILITY-TOKEN header to poll for events
+	Token string `json:"token"`
+
+	// Expiry is the unix timestamp in seconds at which
+	// the token expires
+	Expiry int64 `json:"expiry"`
+}
@@ -3,7 +3,9 @@ package event
 import (
 	"context"
 	stderr "errors"
+	"fmt"
 	"net/url"
+	"time"
 
 	auth "github.com/oasislabs/oasis-gateway/auth/core"
 	backend "github.com/oasislabs/oasis-gateway/backend/core"
@@ -20,9 +22,54 @@ type Client interface {
 	PollEvent(context.Context, backend.PollEventRequest) (backend.Events, errors.Err)
 }
 
+// TokenIssuer mints capability tokens
+type TokenIssuer interface {
+	Issue(aad, session string, ttl time.Duration, scopes ...auth.Scope) (string, time.Time, error)
+}
+
+// Default limits on the number of events returned by a poll
+// used when they are not set in PollProps
+const (
+	DefaultPollCount uint = 10
+	MaxPollCount     uint = 100
+)
+
+// PollProps are the limits on the number of events returned
+// to a client by a single poll
+type PollProps struct {
+	// DefaultCount is the number of events returned by a poll
+	// that does not set a count
+	DefaultCount uint
+
+	// MaxCount is the maximum number of events returned by a
+	// poll. Larger counts requested are capped to MaxCount
+	MaxCount uint
+}
+
+// count returns the number of events retrieved by a poll
+// for the count requested by the client
+func (p PollProps) count(requested uint) uint {
+	if requested == 0 {
+		return p.DefaultCount
+	}
+	if requested > p.MaxCount {
+		return p.MaxCount
+	}
+
+	return requested
+}
+
 type Services struct {
 	Logger log.Logger
 	Client Client
+
+	// Poll are the limits on the number of events returned
+	// by a poll. If not set the default limits are used
+	Poll PollProps
+
+	// Tokens if set allows users to mint poll-only
+	// capability tokens for their session
+	Tokens TokenIssuer
 }
 
 // EventHandler implements the handlers associated with subscriptions and
@@ -30,6 +77,8 @@ type Services struct {
 type EventHandler struct {
 	logger log.Logger
 	client Client
+	tokens TokenIssuer
+	poll   PollProps
 }
 
 // Subscribe creates a new subscription for the client on the required
@@ -68,6 +117,8 @@ func (h EventHandler) Subscribe(ctx context.Context, v interface{}) (interface{}
 		Address:    query.Get("address"),
 		SessionKey: session,
 		Topics:     query["topic"],
+		AAD:        ctx.Value(auth.AAD{}).(string),
+		FromBlock:  req.FromBlock,
 	})
 	if err != nil {
 		h.logger.Debug(ctx, "failed to subscribe", log.MapFields{
@@ -107,15 +158,26 @@ func (h EventHandler) Unsubscribe(ctx context.Context, v interface{}) (interface
 func (h EventHandler) PollEvent(ctx context.Context, v interface{}) (interface{}, error) {
 	session := ctx.Value(auth.Session{}).(string)
 	req := v.(*PollEventRequest)
-	if req.Count == 0 {
-		req.Count = 10
+	count := h.poll.count(req.Count)
+
+	types, err := parseEventTypes(req.Types)
+	if err != nil {
+		return nil, err
+	}
+
+	since, until, err := parseTimeRange(req.Since, req.Until)
+	if err != nil {
+		return nil, err
 	}
 
 	res, err := h.client.PollEvent(ctx, backend.PollEventRequest{
 		DiscardPrevious: req.DiscardPrevious,
-		Count:           req.Count,
+		Count:           count,
 		Offset:          req.Offset,
 		ID:              req.ID,
+		Types:           types,
+		Since:           since,
+		Until:           until,
 		SessionKey:      session,
 	})
 	if err != nil {
@@ -140,14 +202,51 @@ func (h EventHandler) PollEvent(ctx context.Context, v interface{}) (interface{}
 				Data:   r.Data,
 				Topics: r.Topics,
 			})
+		case backend.LogRemovedEvent:
+			events = append(events, LogRemovedEvent{
+				ID:     r.ID,
+				Offset: r.Offset,
+			})
+		case backend.WarningEvent:
+			events = append(events, WarningEvent{
+				ID:    r.ID,
+				Quota: r.Quota,
+				Used:  r.Used,
+				Limit: r.Limit,
+			})
 		default:
 			panic("received unexpected event type from polling service")
 		}
 	}
 
 	return PollEventResponse{
-		Offset: res.Offset,
-		Events: events,
+		Offset:   res.Offset,
+		Count:    count,
+		MaxCount: h.poll.MaxCount,
+		Events:   events,
+	}, nil
+}
+
+// IssueToken mints a capability token that grants poll-only
+// access to the events of the session of the user
+func (h EventHandler) IssueToken(ctx context.Context, v interface{}) (interface{}, error) {
+	aad := auth.MustGetAAD(ctx)
+	session := ctx.Value(auth.SessionKey{}).(string)
+	req := v.(*IssueTokenRequest)
+
+	token, expiry, ierr := h.tokens.Issue(aad, session,
+		time.Duration(req.ExpiresIn)*time.Second, auth.ScopeEventPoll)
+	if ierr != nil {
+		err := errors.New(errors.ErrInternalError, ierr)
+		h.logger.Debug(ctx, "failed to issue capability token", log.MapFields{
+			"call_type": "IssueTokenFailure",
+		}, err)
+		return nil, err
+	}
+
+	return IssueTokenResponse{
+		Token:  token,
+		Expiry: expiry.Unix(),
 	}, nil
 }
 
@@ -159,9 +258,22 @@ func NewEventHandler(services Services) EventHandler {
 		panic("Logger must be provided as a service")
 	}
 
+	poll := services.Poll
+	if poll.MaxCount == 0 {
+		poll.MaxCount = MaxPollCount
+	}
+	if poll.DefaultCount == 0 {
+		poll.DefaultCount = DefaultPollCount
+	}
+	if poll.DefaultCount > poll.MaxCount {
+		poll.DefaultCount = poll.MaxCount
+	}
+
 	return EventHandler{
 		logger: services.Logger.ForClass("event", "handler"),
 		client: services.Client,
+		tokens: services.Tokens,
+		poll:   poll,
 	}
 }
 
@@ -176,4 +288,80 @@ func BindHandler(services Services, binder rpc.HandlerBinder) {
 		rpc.EntityFactoryFunc(func() interface{} { return &UnsubscribeRequest{} }))
 	binder.Bind("POST", "/v0/api/event/poll", rpc.HandlerFunc(handler.PollEvent),
 		rpc.EntityFactoryFunc(func() interface{} { return &PollEventRequest{} }))
+
+	if services.Tokens != nil {
+		binder.Bind("POST", "/v0/api/event/token", rpc.HandlerFunc(handler.IssueToken),
+			rpc.EntityFactoryFunc(func() interface{} { return &IssueTokenRequest{} }))
+	}
+}
+
+// eventTypes maps the event types a client can filter
+// a poll by to the types of the backend events
+var eventTypes = map[string]backend.EventType{
+	"data":       backend.DataEventType,
+	"logRemoved": backend.LogRemovedEventType,
+	"error":      backend.ErrorEventType,
+	"warning":    backend.WarningEventType,
+}
+
+// parseEventTypes maps the event types requested by the
+// client to the types of the backend events
+func parseEventTypes(types []string) ([]backend.EventType, errors.Err) {
+	if len(types) == 0 {
+		return nil, nil
+	}
+
+	parsed := make([]backend.EventType, 0, len(types))
+	for _, t := range types {
+		eventType, ok := eventTypes[t]
+		if !ok {
+			return nil, errors.New(errors.ErrInvalidEventType, fmt.Errorf("unknown event type %q", t))
+		}
+
+		parsed = append(parsed, eventType)
+	}
+
+	return parsed, nil
+}
+
+// timeLayouts are the layouts accepted for the time range of a
+// poll, RFC 3339 and RFC 3339 without seconds
+var timeLayouts = []string{time.RFC3339, "2006-01-02T15:04Z07:00"}
+
+// parseTime parses a timestamp of the time range of a poll
+func parseTime(s string) (time.Time, error) {
+	var err error
+	for _, layout := range timeLayouts {
+		var t time.Time
+		if t, err = time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, err
+}
+
+// parseTimeRange parses the time range of a poll. The
+// timestamps that are not set are returned as zero
+func parseTimeRange(since, until string) (time.Time, time.Time, errors.Err) {
+	var from, to time.Time
+	var err error
+	if len(since) > 0 {
+		if from, err = parseTime(since); err != nil {
+			return time.Time{}, time.Time{}, errors.New(errors.ErrInvalidTimeRange, err)
+		}
+	}
+
+	if len(until) > 0 {
+		if to, err = parseTime(until); err != nil {
+			return time.Time{}, time.Time{}, errors.New(errors.ErrInvalidTimeRange, err)
+		}
+	}
+
+	if !to.IsZero() && !to.After(from) {
+		return time.Time{}, time.Time{}, errors.New(errors.ErrInvalidTimeRange,
+			stderr.New("until must be after since"))
+	}
+
+	return from, to, nil
 }
@@ -3,7 +3,9 @@ package event
 import (
 	"context"
 	"io/ioutil"
+	"net/http"
 	"testing"
+	"time"
 
 	auth "github.com/oasislabs/oasis-gateway/auth/core"
 	backend "github.com/oasislabs/oasis-gateway/backend/core"
@@ -161,6 +163,7 @@ func TestSubscribeOKWithTopics(t *testing.T) {
 		Address:    "myaddress",
 		SessionKey: "sessionKey",
 		Topics:     []string{"topic1", "topic2"},
+		AAD:        "aad",
 	})
 }
 
@@ -187,6 +190,33 @@ func TestSubscribeOKNoTopic(t *testing.T) {
 		Address:    "myaddress",
 		SessionKey: "sessionKey",
 		Topics:     nil,
+		AAD:        "aad",
+	})
+}
+
+func TestSubscribeOKFromBlock(t *testing.T) {
+	ctx := context.WithValue(Context, auth.AAD{}, "aad")
+	ctx = context.WithValue(ctx, auth.Session{}, "sessionKey")
+
+	handler := createEventHandler()
+
+	handler.client.(*MockClient).On("Subscribe", mock.Anything, mock.Anything).
+		Return(uint64(1), nil)
+
+	fromBlock := uint64(0)
+	_, err := handler.Subscribe(ctx, &SubscribeRequest{
+		Events:    []string{"event"},
+		Filter:    "address=myaddress",
+		FromBlock: &fromBlock,
+	})
+
+	assert.Nil(t, err)
+	handler.client.(*MockClient).AssertCalled(t, "Subscribe", ctx, backend.SubscribeRequest{
+		Event:      "event",
+		Address:    "myaddress",
+		SessionKey: "sessionKey",
+		AAD:        "aad",
+		FromBlock:  &fromBlock,
 	})
 }
 
@@ -238,11 +268,66 @@ func TestPollEventOKEmpty(t *testing.T) {
 
 	assert.Nil(t, err)
 	assert.Equal(t, PollEventResponse{
-		Offset: 0,
-		Events: []Event{},
+		Offset:   0,
+		Count:    DefaultPollCount,
+		MaxCount: MaxPollCount,
+		Events:   []Event{},
 	}, res)
 }
 
+func TestPollEventCount(t *testing.T) {
+	ctx := context.WithValue(Context, auth.AAD{}, "aad")
+	ctx = context.WithValue(ctx, auth.Session{}, "sessionKey")
+
+	handler := NewEventHandler(Services{
+		Logger: Logger,
+		Client: &MockClient{},
+		Poll:   PollProps{DefaultCount: 5, MaxCount: 20},
+	})
+
+	for _, c := range []struct {
+		requested uint
+		applied   uint
+	}{{0, 5}, {15, 15}, {50, 20}} {
+		handler.client.(*MockClient).On("PollEvent",
+			mock.Anything,
+			backend.PollEventRequest{
+				Count:      c.applied,
+				ID:         1,
+				SessionKey: "sessionKey",
+			}).Return(backend.Events{}, nil)
+
+		res, err := handler.PollEvent(ctx, &PollEventRequest{ID: 1, Count: c.requested})
+		assert.Nil(t, err)
+		assert.Equal(t, c.applied, res.(PollEventResponse).Count)
+		assert.Equal(t, uint(20), res.(PollEventResponse).MaxCount)
+	}
+}
+
+func TestPollEventTypes(t *testing.T) {
+	ctx := context.WithValue(Context, auth.AAD{}, "aad")
+	ctx = context.WithValue(ctx, auth.Session{}, "sessionKey")
+
+	handler := createEventHandler()
+
+	handler.client.(*MockClient).On("PollEvent",
+		mock.Anything,
+		backend.PollEventRequest{
+			Offset:     0,
+			Count:      10,
+			ID:         1,
+			Types:      []backend.EventType{backend.DataEventType},
+			SessionKey: "sessionKey",
+		}).Return(backend.Events{}, nil)
+
+	_, err := handler.PollEvent(ctx, &PollEventRequest{ID: 1, Types: []string{"data"}})
+	assert.Nil(t, err)
+
+	_, err = handler.PollEvent(ctx, &PollEventRequest{ID: 1, Types: []string{"execute"}})
+	assert.Error(t, err)
+	assert.Equal(t, errors.ErrInvalidEventType, err.(errors.Err).ErrorCode())
+}
+
 func TestPollEventOKMultiple(t *testing.T) {
 	ctx := context.WithValue(Context, auth.AAD{}, "aad")
 	ctx = context.WithValue(ctx, auth.Session{}, "sessionKey")
@@ -269,7 +354,9 @@ func TestPollEventOKMultiple(t *testing.T) {
 
 	assert.Nil(t, err)
 	assert.Equal(t, PollEventResponse{
-		Offset: 0,
+		Offset:   0,
+		Count:    DefaultPollCount,
+		MaxCount: MaxPollCount,
 		Events: []Event{
 			DataEvent{
 				ID:   0,
@@ -369,4 +456,38 @@ func TestBindHandlerOK(t *testing.T) {
 	assert.True(t, router.HasHandler("/v0/api/event/subscribe", "POST"))
 	assert.True(t, router.HasHandler("/v0/api/event/unsubscribe", "POST"))
 	assert.True(t, router.HasHandler("/v0/api/event/poll", "POST"))
+	assert.False(t, router.HasHandler("/v0/api/event/token", "POST"))
+}
+
+func TestIssueTokenOK(t *testing.T) {
+	issuer := auth.NewCapabilityIssuer(auth.CapabilityProps{
+		Secret: []byte("secret"),
+		MaxTTL: time.Minute,
+	})
+	h := NewEventHandler(Services{
+		Logger: Logger,
+		Client: &MockClient{},
+		Tokens: issuer,
+	})
+
+	ctx := context.WithValue(Context, auth.AAD{}, "aad")
+	ctx = context.WithValue(ctx, auth.SessionKey{}, "sessionKey")
+	ctx = context.WithValue(ctx, auth.Session{}, "aadHash:sessionKey")
+
+	v, err := h.IssueToken(ctx, &IssueTokenRequest{ExpiresIn: 10})
+	assert.Nil(t, err)
+
+	res := v.(IssueTokenResponse)
+	assert.True(t, res.Expiry <= time.Now().Add(10*time.Second).Unix())
+
+	req, err := http.NewRequest("POST", "/v0/api/event/poll", nil)
+	assert.Nil(t, err)
+	req.Header.Add(auth.RequestHeaderCapabilityToken, res.Token)
+
+	req, err = auth.NewCapabilityAuth(issuer).Authenticate(req)
+	assert.Nil(t, err)
+	assert.Equal(t, "aad", req.Context().Value(auth.AAD{}))
+	assert.Equal(t, "sessionKey", req.Context().Value(auth.SessionKey{}))
+	assert.True(t, auth.HasScope(req.Context(), auth.ScopeEventPoll))
+	assert.False(t, auth.HasScope(req.Context(), auth.ScopeTokenIssue))
 }
@@ -0,0 +1,29 @@
+package feature
+
+// ListFeaturesRequest is a request to retrieve whether
+// each feature is enabled
+type ListFeaturesRequest struct{}
+
+// SetFeatureRequest is a request to enable or disable a feature
+type SetFeatureRequest struct {
+	// Feature is the name of the feature
+	Feature string `json:"feature"`
+
+	// Enabled is true if the routes of the feature are served
+	Enabled bool `json:"enabled"`
+}
+
+// Feature is the state of a feature
+type Feature struct {
+	// Feature is the name of the feature
+	Feature string `json:"feature"`
+
+	// Enabled is true if the routes of the feature are served
+	Enabled bool `json:"enabled"`
+}
+
+// ListFeaturesResponse is the response to a ListFeaturesRequest
+type ListFeaturesResponse struct {
+	// Features are all the features that can be toggled
+	Features []Feature `json:"features"`
+}
@@ -0,0 +1,95 @@
+package feature
+
+import (
+	"context"
+
+	"github.com/oasislabs/oasis-gateway/errors"
+	flags "github.com/oasislabs/oasis-gateway/feature"
+	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/oasislabs/oasis-gateway/rpc"
+)
+
+// Client interface for the underlying operations needed for the API
+// implementation
+type Client interface {
+	// Set enables or disables a feature
+	Set(name string, enabled bool) errors.Err
+
+	// States returns whether each feature is enabled
+	States() []flags.State
+}
+
+// Services required by the FeatureHandler execution
+type Services struct {
+	Logger log.Logger
+	Client Client
+}
+
+// FeatureHandler implements the handlers to toggle the
+// features of the public API
+type FeatureHandler struct {
+	logger log.Logger
+	client Client
+}
+
+// SetFeature enables or disables a feature
+func (h FeatureHandler) SetFeature(ctx context.Context, v interface{}) (interface{}, error) {
+	req := v.(*SetFeatureRequest)
+
+	if err := h.client.Set(req.Feature, req.Enabled); err != nil {
+		h.logger.Debug(ctx, "request failed", log.MapFields{
+			"call_type": "SetFeatureFailure",
+			"feature":   req.Feature,
+		}, err)
+		return nil, err
+	}
+
+	h.logger.Info(ctx, "feature set", log.MapFields{
+		"call_type": "SetFeatureSuccess",
+		"feature":   req.Feature,
+		"enabled":   req.Enabled,
+	})
+
+	return Feature{Feature: req.Feature, Enabled: req.Enabled}, nil
+}
+
+// ListFeatures retrieves whether each feature is enabled
+func (h FeatureHandler) ListFeatures(ctx context.Context, v interface{}) (interface{}, error) {
+	_ = v.(*ListFeaturesRequest)
+
+	states := h.client.States()
+	res := ListFeaturesResponse{Features: make([]Feature, 0, len(states))}
+	for _, state := range states {
+		res.Features = append(res.Features, Feature{
+			Feature: state.Feature.String(),
+			Enabled: state.Enabled,
+		})
+	}
+
+	return res, nil
+}
+
+func NewFeatureHandler(services Services) FeatureHandler {
+	if services.Client == nil {
+		panic("Client must be provided as a service")
+	}
+	if services.Logger == nil {
+		panic("Logger must be provided as a service")
+	}
+
+	return FeatureHandler{
+		logger: services.Logger.ForClass("feature", "handler"),
+		client: services.Client,
+	}
+}
+
+// BindHandler binds the feature handler to the provided
+// HandlerBinder
+func BindHandler(services Services, binder rpc.HandlerBinder) {
+	handler := NewFeatureHandler(services)
+
+	binder.Bind("POST", "/v0/api/feature/set", rpc.HandlerFunc(handler.SetFeature),
+		rpc.EntityFactoryFunc(func() interface{} { return &SetFeatureRequest{} }))
+	binder.Bind("GET", "/v0/api/feature/list", rpc.HandlerFunc(handler.ListFeatures),
+		rpc.EntityFactoryFunc(func() interface{} { return &ListFeaturesRequest{} }))
+}
@@ -0,0 +1,89 @@
+package federation
+
+import (
+	"context"
+
+	"github.com/oasislabs/oasis-gateway/errors"
+	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/oasislabs/oasis-gateway/mqueue/federation"
+	"github.com/oasislabs/oasis-gateway/rpc"
+)
+
+// Receiver interface for the underlying operations needed for the API
+// implementation
+type Receiver interface {
+	// Insert applies an element forwarded by another gateway
+	Insert(context.Context, *federation.InsertRequest) errors.Err
+
+	// Remove applies a queue removal forwarded by another gateway
+	Remove(context.Context, *federation.RemoveRequest) errors.Err
+}
+
+// Services required by the FederationHandler execution
+type Services struct {
+	Logger   log.Logger
+	Receiver Receiver
+}
+
+// FederationHandler implements the handlers for the events
+// forwarded by other gateways
+type FederationHandler struct {
+	logger   log.Logger
+	receiver Receiver
+}
+
+// Insert applies an element forwarded by another gateway
+func (h FederationHandler) Insert(ctx context.Context, v interface{}) (interface{}, error) {
+	req := v.(*federation.InsertRequest)
+
+	if err := h.receiver.Insert(ctx, req); err != nil {
+		h.logger.Debug(ctx, "request failed", log.MapFields{
+			"call_type": "FederationInsertFailure",
+			"key":       req.Key,
+			"offset":    req.Offset,
+		}, err)
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// Remove applies a queue removal forwarded by another gateway
+func (h FederationHandler) Remove(ctx context.Context, v interface{}) (interface{}, error) {
+	req := v.(*federation.RemoveRequest)
+
+	if err := h.receiver.Remove(ctx, req); err != nil {
+		h.logger.Debug(ctx, "request failed", log.MapFields{
+			"call_type": "FederationRemoveFailure",
+			"key":       req.Key,
+		}, err)
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func NewFederationHandler(services Services) FederationHandler {
+	if services.Receiver == nil {
+		panic("Receiver must be provided as a service")
+	}
+	if services.Logger == nil {
+		panic("Logger must be provided as a service")
+	}
+
+	return FederationHandler{
+		logger:   services.Logger.ForClass("federation", "handler"),
+		receiver: services.Receiver,
+	}
+}
+
+// BindHandler binds the federation handler to the provided
+// HandlerBinder
+func BindHandler(services Services, binder rpc.HandlerBinder) {
+	handler := NewFederationHandler(services)
+
+	binder.Bind("POST", federation.InsertPath, rpc.HandlerFunc(handler.Insert),
+		rpc.EntityFactoryFunc(func() interface{} { return &federation.InsertRequest{} }))
+	binder.Bind("POST", federation.RemovePath, rpc.HandlerFunc(handler.Remove),
+		rpc.EntityFactoryFunc(func() interface{} { return &federation.RemoveRequest{} }))
+}
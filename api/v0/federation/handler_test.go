@@ -0,0 +1,72 @@
+package federation
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/oasislabs/oasis-gateway/errors"
+	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/oasislabs/oasis-gateway/mqueue/core"
+	"github.com/oasislabs/oasis-gateway/mqueue/federation"
+	"github.com/oasislabs/oasis-gateway/mqueue/mem"
+	"github.com/stretchr/testify/assert"
+)
+
+var Context = context.TODO()
+
+var Logger = log.NewLogrus(log.LogrusLoggerProperties{
+	Output: ioutil.Discard,
+})
+
+var secret = []byte("secret")
+
+func newHandler() (FederationHandler, core.MQueue) {
+	m := mem.NewServer(Context, mem.Services{Logger: Logger})
+	return NewFederationHandler(Services{
+		Logger: Logger,
+		Receiver: federation.NewReceiver(federation.ReceiverServices{
+			MQueue: m,
+		}, federation.ReceiverProps{Secret: secret}),
+	}), m
+}
+
+func TestInsertOK(t *testing.T) {
+	handler, m := newHandler()
+	req := &federation.InsertRequest{Key: "key", Offset: 0, Value: "value", Timestamp: time.Now().Unix(),
+		Nonce: "0123456789abcdef"}
+	req.Signature = federation.NewSigner(secret).SignInsert(req)
+
+	v, err := handler.Insert(Context, req)
+
+	assert.Nil(t, err)
+	assert.Nil(t, v)
+	els, _ := m.Retrieve(Context, core.RetrieveRequest{Key: "key", Count: 1})
+	assert.Equal(t, 1, len(els.Elements))
+	assert.NotZero(t, els.Elements[0].Timestamp)
+	els.Elements[0].Timestamp = 0
+	assert.Equal(t, []core.Element{{Offset: 0, Value: "value"}}, els.Elements)
+}
+
+func TestInsertBadSignature(t *testing.T) {
+	handler, _ := newHandler()
+	req := &federation.InsertRequest{Key: "key", Timestamp: time.Now().Unix(), Signature: "bad"}
+
+	_, err := handler.Insert(Context, req)
+
+	assert.Equal(t, errors.ErrFederationSignature.Code(), err.(errors.Err).ErrorCode().Code())
+}
+
+func TestRemoveOK(t *testing.T) {
+	handler, m := newHandler()
+	_, _ = m.Next(Context, core.NextRequest{Key: "key"})
+	req := &federation.RemoveRequest{Key: "key", Timestamp: time.Now().Unix(), Nonce: "0123456789abcdef"}
+	req.Signature = federation.NewSigner(secret).SignRemove(req)
+
+	_, err := handler.Remove(Context, req)
+
+	assert.Nil(t, err)
+	exists, _ := m.Exists(Context, core.ExistsRequest{Key: "key"})
+	assert.False(t, exists)
+}
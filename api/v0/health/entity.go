@@ -1,6 +1,12 @@
 package health
 
-import "github.com/oasislabs/oasis-gateway/stats"
+import (
+	"io"
+	"net/http"
+
+	"github.com/oasislabs/oasis-gateway/stats"
+	"github.com/oasislabs/oasis-gateway/stats/prometheus"
+)
 
 // GetHealthRequest is a request to retrieve the health
 // status of the component.
@@ -11,3 +17,54 @@ type GetHealthResponse struct {
 	Health  stats.HealthStatus `json:"health"`
 	Metrics stats.Metrics      `json:"metrics"`
 }
+
+// GetMetricsRequest is a request to retrieve the metrics of the
+// gateway in the Prometheus text exposition format
+type GetMetricsRequest struct{}
+
+// GetMetricsResponse is the response to the metrics request. It is
+// written in the Prometheus text exposition format rather than json
+type GetMetricsResponse struct {
+	Metrics stats.Metrics
+}
+
+// Serialize is the implementation of rpc.Serializer for GetMetricsResponse
+func (r *GetMetricsResponse) Serialize(w io.Writer) error {
+	return prometheus.Encoder{Prefix: prometheus.DefaultPrefix}.Encode(w, r.Metrics)
+}
+
+// HttpHeaders is the implementation of rpc.HttpHeaderProvider
+// for GetMetricsResponse
+func (r *GetMetricsResponse) HttpHeaders() http.Header {
+	return http.Header{"Content-Type": []string{prometheus.ContentType}}
+}
+
+// GetSnapshotRequest is a request to take a snapshot of the
+// gauges of the gateway
+type GetSnapshotRequest struct {
+	// From if set is the ID of a previous snapshot with which the
+	// new snapshot is compared
+	From uint64 `json:"from"`
+}
+
+// GetSnapshotResponse is the response to the snapshot request
+type GetSnapshotResponse struct {
+	// ID identifies the snapshot so that later snapshots
+	// can be compared with it
+	ID uint64 `json:"id"`
+
+	// Timestamp is the time in milliseconds since epoch at which
+	// the snapshot was taken
+	Timestamp int64 `json:"timestamp"`
+
+	// Gauges are the numeric metrics of the gateway by their path
+	Gauges stats.Gauges `json:"gauges"`
+
+	// Elapsed is the time in milliseconds between the snapshot
+	// it is compared with and this snapshot
+	Elapsed int64 `json:"elapsed,omitempty"`
+
+	// Deltas are the change of each gauge since the snapshot it
+	// is compared with. It is only set if From is provided
+	Deltas stats.Gauges `json:"deltas,omitempty"`
+}
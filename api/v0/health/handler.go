@@ -2,21 +2,29 @@ package health
 
 import (
 	"context"
+	"fmt"
+	"time"
 
+	"github.com/oasislabs/oasis-gateway/errors"
 	"github.com/oasislabs/oasis-gateway/rpc"
 	"github.com/oasislabs/oasis-gateway/stats"
 )
 
 type Deps struct {
 	Collector stats.Collector
+
+	// Snapshots if set exposes the snapshots of the gauges
+	// of the collector
+	Snapshots *stats.Snapshotter
 }
 
 type HealthHandler struct {
 	collector stats.Collector
+	snapshots *stats.Snapshotter
 }
 
 func NewHealthHandler(deps *Deps) HealthHandler {
-	return HealthHandler{collector: deps.Collector}
+	return HealthHandler{collector: deps.Collector, snapshots: deps.Snapshots}
 }
 
 func (h HealthHandler) GetHealth(ctx context.Context, v interface{}) (interface{}, error) {
@@ -27,9 +35,56 @@ func (h HealthHandler) GetHealth(ctx context.Context, v interface{}) (interface{
 	}, nil
 }
 
+// GetMetrics retrieves the metrics of all the collectors, so
+// that they can be scraped by Prometheus
+func (h HealthHandler) GetMetrics(ctx context.Context, v interface{}) (interface{}, error) {
+	_ = v.(*GetMetricsRequest)
+	return &GetMetricsResponse{Metrics: h.collector.Stats()}, nil
+}
+
+// GetSnapshot takes a snapshot of the gauges. If a previous snapshot is
+// provided, the response also has the change of each gauge since then,
+// which helps to find resources that are not released
+func (h HealthHandler) GetSnapshot(ctx context.Context, v interface{}) (interface{}, error) {
+	req := v.(*GetSnapshotRequest)
+
+	var from stats.Snapshot
+	if req.From > 0 {
+		// the previous snapshot is looked up before the new one
+		// is taken so that it is not dropped in between
+		snapshot, ok := h.snapshots.Get(req.From)
+		if !ok {
+			return nil, errors.New(errors.ErrSnapshotNotFound,
+				fmt.Errorf("snapshot %d not found", req.From))
+		}
+		from = snapshot
+	}
+
+	snapshot := h.snapshots.Take()
+	res := &GetSnapshotResponse{
+		ID:        snapshot.ID,
+		Timestamp: snapshot.Time.UnixNano() / int64(time.Millisecond),
+		Gauges:    snapshot.Gauges,
+	}
+
+	if req.From > 0 {
+		res.Elapsed = int64(snapshot.Time.Sub(from.Time) / time.Millisecond)
+		res.Deltas = stats.Diff(from.Gauges, snapshot.Gauges)
+	}
+
+	return res, nil
+}
+
 func BindHandler(deps *Deps, binder rpc.HandlerBinder) {
 	handler := NewHealthHandler(deps)
 
 	binder.Bind("GET", "/v0/api/health", rpc.HandlerFunc(handler.GetHealth),
 		rpc.EntityFactoryFunc(func() interface{} { return &GetHealthRequest{} }))
+	binder.Bind("GET", "/metrics", rpc.HandlerFunc(handler.GetMetrics),
+		rpc.EntityFactoryFunc(func() interface{} { return &GetMetricsRequest{} }))
+
+	if deps.Snapshots != nil {
+		binder.Bind("POST", "/v0/api/health/snapshot", rpc.HandlerFunc(handler.GetSnapshot),
+			rpc.EntityFactoryFunc(func() interface{} { return &GetSnapshotRequest{} }))
+	}
 }
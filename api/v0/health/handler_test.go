@@ -0,0 +1,64 @@
+package health
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/oasislabs/oasis-gateway/rpc"
+	"github.com/oasislabs/oasis-gateway/stats"
+	"github.com/oasislabs/oasis-gateway/stats/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+type gaugeCollector struct {
+	value int
+}
+
+func (c *gaugeCollector) Stats() stats.Metrics {
+	return stats.Metrics{"runtime": stats.Metrics{"NumGoroutine": c.value}}
+}
+
+func TestGetSnapshotDeltas(t *testing.T) {
+	collector := &gaugeCollector{value: 10}
+	h := NewHealthHandler(&Deps{
+		Collector: collector,
+		Snapshots: stats.NewSnapshotter(collector, stats.SnapshotterProps{}),
+	})
+
+	v, err := h.GetSnapshot(context.TODO(), &GetSnapshotRequest{})
+	assert.Nil(t, err)
+	first := v.(*GetSnapshotResponse)
+	assert.Equal(t, stats.Gauges{"runtime.NumGoroutine": 10}, first.Gauges)
+	assert.Nil(t, first.Deltas)
+
+	collector.value = 14
+	v, err = h.GetSnapshot(context.TODO(), &GetSnapshotRequest{From: first.ID})
+	assert.Nil(t, err)
+	assert.Equal(t, stats.Gauges{"runtime.NumGoroutine": 4}, v.(*GetSnapshotResponse).Deltas)
+}
+
+func TestGetSnapshotErrNotFound(t *testing.T) {
+	collector := &gaugeCollector{}
+	h := NewHealthHandler(&Deps{
+		Collector: collector,
+		Snapshots: stats.NewSnapshotter(collector, stats.SnapshotterProps{}),
+	})
+
+	_, err := h.GetSnapshot(context.TODO(), &GetSnapshotRequest{From: 5})
+	assert.Equal(t, "[6005] error code NotFound with desc Snapshot not found. with cause snapshot 5 not found", err.Error())
+}
+
+func TestGetMetrics(t *testing.T) {
+	h := NewHealthHandler(&Deps{Collector: &gaugeCollector{value: 10}})
+
+	v, err := h.GetMetrics(context.TODO(), &GetMetricsRequest{})
+	assert.Nil(t, err)
+
+	var buf bytes.Buffer
+	assert.Nil(t, rpc.JsonEncoder{}.Encode(&buf, v))
+	assert.Equal(t, "# TYPE oasis_gateway_runtime_NumGoroutine gauge\n"+
+		"oasis_gateway_runtime_NumGoroutine 10\n", buf.String())
+	assert.Equal(t, prometheus.ContentType,
+		v.(*GetMetricsResponse).HttpHeaders().Get("Content-Type"))
+}
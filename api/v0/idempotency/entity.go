@@ -0,0 +1,77 @@
+package idempotency
+
+// GetRecordRequest is a request to retrieve the record
+// of an idempotency key used by an AAD
+type GetRecordRequest struct {
+	// AAD that used the idempotency key
+	AAD string `json:"aad"`
+
+	// Key is the idempotency key
+	Key string `json:"key"`
+}
+
+// ListRecordsRequest is a request to retrieve the records
+// of all the idempotency keys used by an AAD
+type ListRecordsRequest struct {
+	// AAD that used the idempotency keys
+	AAD string `json:"aad"`
+}
+
+// PurgeRecordsRequest is a request to remove the records of
+// idempotency keys so that clients can use them again
+type PurgeRecordsRequest struct {
+	// AAD that used the idempotency keys
+	AAD string `json:"aad"`
+
+	// Keys are the idempotency keys to purge
+	Keys []string `json:"keys"`
+
+	// All if set purges all the idempotency keys of the AAD
+	// instead of the Keys provided
+	All bool `json:"all"`
+}
+
+// SetExpiryRequest is a request to set when the record
+// of an idempotency key expires
+type SetExpiryRequest struct {
+	// AAD that used the idempotency key
+	AAD string `json:"aad"`
+
+	// Key is the idempotency key
+	Key string `json:"key"`
+
+	// TTL is the time in milliseconds from now after which the
+	// key can be used again. If 0 the record does not expire
+	TTL int64 `json:"ttl"`
+}
+
+// Record is the record of a request accepted with an idempotency key
+type Record struct {
+	// AAD that used the idempotency key
+	AAD string `json:"aad"`
+
+	// Key is the idempotency key
+	Key string `json:"key"`
+
+	// ID of the event of the request accepted with the key
+	ID uint64 `json:"id"`
+
+	// ExpiresAt is the time in milliseconds since the epoch at
+	// which the key can be used again. If 0 it does not expire
+	ExpiresAt int64 `json:"expiresAt"`
+
+	// Expired is true if the key can already be used again
+	Expired bool `json:"expired"`
+}
+
+// ListRecordsResponse is the response to a ListRecordsRequest
+type ListRecordsResponse struct {
+	// Records of the idempotency keys used by the AAD
+	Records []Record `json:"records"`
+}
+
+// PurgeRecordsResponse is the response to a PurgeRecordsRequest
+type PurgeRecordsResponse struct {
+	// Purged is the number of records removed
+	Purged int `json:"purged"`
+}
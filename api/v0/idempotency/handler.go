@@ -0,0 +1,219 @@
+package idempotency
+
+import (
+	"context"
+	stderr "errors"
+	"time"
+
+	backend "github.com/oasislabs/oasis-gateway/backend/core"
+	"github.com/oasislabs/oasis-gateway/errors"
+	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/oasislabs/oasis-gateway/rpc"
+)
+
+// Client interface for the underlying operations needed for the API
+// implementation
+type Client interface {
+	// GetIdempotencyRecord retrieves the record of an idempotency key
+	GetIdempotencyRecord(context.Context, backend.GetIdempotencyRecordRequest) (backend.IdempotencyRecord, errors.Err)
+
+	// ListIdempotencyRecords retrieves the records of the
+	// idempotency keys used by an AAD
+	ListIdempotencyRecords(context.Context, backend.ListIdempotencyRecordsRequest) ([]backend.IdempotencyRecord, errors.Err)
+
+	// PurgeIdempotencyRecords removes the records of idempotency keys
+	PurgeIdempotencyRecords(context.Context, backend.PurgeIdempotencyRecordsRequest) (int, errors.Err)
+
+	// SetIdempotencyExpiry sets when the record of an idempotency key expires
+	SetIdempotencyExpiry(context.Context, backend.SetIdempotencyExpiryRequest) (backend.IdempotencyRecord, errors.Err)
+}
+
+// Services required by the IdempotencyHandler execution
+type Services struct {
+	Logger log.Logger
+	Client Client
+}
+
+// IdempotencyHandler implements the handlers to inspect and
+// manage the records of the idempotency keys
+type IdempotencyHandler struct {
+	logger log.Logger
+	client Client
+}
+
+func newRecord(record backend.IdempotencyRecord) Record {
+	var expiresAt int64
+	if !record.ExpiresAt.IsZero() {
+		expiresAt = record.ExpiresAt.UnixNano() / int64(time.Millisecond)
+	}
+
+	return Record{
+		AAD:       record.AAD,
+		Key:       record.Key,
+		ID:        record.ID,
+		ExpiresAt: expiresAt,
+		Expired:   record.Expired(time.Now()),
+	}
+}
+
+// GetRecord retrieves the record of an idempotency key
+func (h IdempotencyHandler) GetRecord(ctx context.Context, v interface{}) (interface{}, error) {
+	req := v.(*GetRecordRequest)
+
+	if len(req.AAD) == 0 || len(req.Key) == 0 {
+		return nil, errors.New(errors.ErrEmptyInput, stderr.New("aad and key must be set"))
+	}
+
+	record, err := h.client.GetIdempotencyRecord(ctx, backend.GetIdempotencyRecordRequest{
+		AAD: req.AAD,
+		Key: req.Key,
+	})
+	if err != nil {
+		h.logger.Debug(ctx, "request failed", log.MapFields{
+			"call_type": "GetIdempotencyRecordFailure",
+			"aad":       req.AAD,
+			"key":       req.Key,
+		}, err)
+		return nil, err
+	}
+
+	return newRecord(record), nil
+}
+
+// ListRecords retrieves the records of all the idempotency keys of an AAD
+func (h IdempotencyHandler) ListRecords(ctx context.Context, v interface{}) (interface{}, error) {
+	req := v.(*ListRecordsRequest)
+
+	if len(req.AAD) == 0 {
+		return nil, errors.New(errors.ErrEmptyInput, stderr.New("aad must be set"))
+	}
+
+	records, err := h.client.ListIdempotencyRecords(ctx, backend.ListIdempotencyRecordsRequest{
+		AAD: req.AAD,
+	})
+	if err != nil {
+		h.logger.Debug(ctx, "request failed", log.MapFields{
+			"call_type": "ListIdempotencyRecordsFailure",
+			"aad":       req.AAD,
+		}, err)
+		return nil, err
+	}
+
+	res := ListRecordsResponse{Records: make([]Record, 0, len(records))}
+	for _, record := range records {
+		res.Records = append(res.Records, newRecord(record))
+	}
+
+	return res, nil
+}
+
+// PurgeRecords removes the records of idempotency keys so that
+// the clients can use them again
+func (h IdempotencyHandler) PurgeRecords(ctx context.Context, v interface{}) (interface{}, error) {
+	req := v.(*PurgeRecordsRequest)
+
+	if len(req.AAD) == 0 {
+		return nil, errors.New(errors.ErrEmptyInput, stderr.New("aad must be set"))
+	}
+
+	// purging all the keys of an AAD has to be explicit, so that
+	// a request that omits the keys does not purge them all
+	if len(req.Keys) == 0 && !req.All {
+		return nil, errors.New(errors.ErrEmptyInput, stderr.New("keys must be set unless all is set"))
+	}
+
+	var keys []string
+	if !req.All {
+		keys = req.Keys
+	}
+
+	purged, err := h.client.PurgeIdempotencyRecords(ctx, backend.PurgeIdempotencyRecordsRequest{
+		AAD:  req.AAD,
+		Keys: keys,
+	})
+	if err != nil {
+		h.logger.Debug(ctx, "request failed", log.MapFields{
+			"call_type": "PurgeIdempotencyRecordsFailure",
+			"aad":       req.AAD,
+		}, err)
+		return nil, err
+	}
+
+	h.logger.Info(ctx, "idempotency records purged", log.MapFields{
+		"call_type": "PurgeIdempotencyRecordsSuccess",
+		"aad":       req.AAD,
+		"keys":      keys,
+		"purged":    purged,
+	})
+
+	return PurgeRecordsResponse{Purged: purged}, nil
+}
+
+// SetExpiry sets when the record of an idempotency key expires
+func (h IdempotencyHandler) SetExpiry(ctx context.Context, v interface{}) (interface{}, error) {
+	req := v.(*SetExpiryRequest)
+
+	if len(req.AAD) == 0 || len(req.Key) == 0 {
+		return nil, errors.New(errors.ErrEmptyInput, stderr.New("aad and key must be set"))
+	}
+	if req.TTL < 0 {
+		return nil, errors.New(errors.ErrOutOfRange, stderr.New("ttl cannot be negative"))
+	}
+
+	var expiresAt time.Time
+	if req.TTL > 0 {
+		expiresAt = time.Now().Add(time.Duration(req.TTL) * time.Millisecond)
+	}
+
+	record, err := h.client.SetIdempotencyExpiry(ctx, backend.SetIdempotencyExpiryRequest{
+		AAD:       req.AAD,
+		Key:       req.Key,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		h.logger.Debug(ctx, "request failed", log.MapFields{
+			"call_type": "SetIdempotencyExpiryFailure",
+			"aad":       req.AAD,
+			"key":       req.Key,
+		}, err)
+		return nil, err
+	}
+
+	h.logger.Info(ctx, "idempotency record expiry set", log.MapFields{
+		"call_type": "SetIdempotencyExpirySuccess",
+		"aad":       req.AAD,
+		"key":       req.Key,
+		"ttl":       req.TTL,
+	})
+
+	return newRecord(record), nil
+}
+
+func NewIdempotencyHandler(services Services) IdempotencyHandler {
+	if services.Client == nil {
+		panic("Client must be provided as a service")
+	}
+	if services.Logger == nil {
+		panic("Logger must be provided as a service")
+	}
+
+	return IdempotencyHandler{
+		logger: services.Logger.ForClass("idempotency", "handler"),
+		client: services.Client,
+	}
+}
+
+// BindHandler binds the idempotency handler to the provided
+// HandlerBinder
+func BindHandler(services Services, binder rpc.HandlerBinder) {
+	handler := NewIdempotencyHandler(services)
+
+	binder.Bind("POST", "/v0/api/idempotency/get", rpc.HandlerFunc(handler.GetRecord),
+		rpc.EntityFactoryFunc(func() interface{} { return &GetRecordRequest{} }))
+	binder.Bind("POST", "/v0/api/idempotency/list", rpc.HandlerFunc(handler.ListRecords),
+		rpc.EntityFactoryFunc(func() interface{} { return &ListRecordsRequest{} }))
+	binder.Bind("POST", "/v0/api/idempotency/purge", rpc.HandlerFunc(handler.PurgeRecords),
+		rpc.EntityFactoryFunc(func() interface{} { return &PurgeRecordsRequest{} }))
+	binder.Bind("POST", "/v0/api/idempotency/setExpiry", rpc.HandlerFunc(handler.SetExpiry),
+		rpc.EntityFactoryFunc(func() interface{} { return &SetExpiryRequest{} }))
+}
@@ -0,0 +1,135 @@
+package idempotency
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+
+	backend "github.com/oasislabs/oasis-gateway/backend/core"
+	"github.com/oasislabs/oasis-gateway/errors"
+	"github.com/oasislabs/oasis-gateway/log"
+	mqueue "github.com/oasislabs/oasis-gateway/mqueue/core"
+	"github.com/stretchr/testify/assert"
+)
+
+var Context = context.TODO()
+
+var Logger = log.NewLogrus(log.LogrusLoggerProperties{
+	Output: ioutil.Discard,
+})
+
+type registryClient struct {
+	registry *backend.IdempotencyRegistry
+}
+
+func (c registryClient) GetIdempotencyRecord(ctx context.Context, req backend.GetIdempotencyRecordRequest) (backend.IdempotencyRecord, errors.Err) {
+	return c.registry.Get(ctx, req.AAD, req.Key)
+}
+
+func (c registryClient) ListIdempotencyRecords(ctx context.Context, req backend.ListIdempotencyRecordsRequest) ([]backend.IdempotencyRecord, errors.Err) {
+	return c.registry.List(ctx, req.AAD)
+}
+
+func (c registryClient) PurgeIdempotencyRecords(ctx context.Context, req backend.PurgeIdempotencyRecordsRequest) (int, errors.Err) {
+	return c.registry.Purge(ctx, req.AAD, req.Keys)
+}
+
+func (c registryClient) SetIdempotencyExpiry(ctx context.Context, req backend.SetIdempotencyExpiryRequest) (backend.IdempotencyRecord, errors.Err) {
+	return c.registry.SetExpiry(ctx, req.AAD, req.Key, req.ExpiresAt)
+}
+
+func createIdempotencyHandler(keys ...string) IdempotencyHandler {
+	registry := backend.NewIdempotencyRegistry(mqueue.NewMemStore(), backend.IdempotencyRegistryProps{})
+	for i, key := range keys {
+		if _, _, err := registry.Claim(Context, "aad", key, uint64(i)); err != nil {
+			panic(err)
+		}
+	}
+
+	return NewIdempotencyHandler(Services{
+		Logger: Logger,
+		Client: registryClient{registry: registry},
+	})
+}
+
+func TestGetRecordOK(t *testing.T) {
+	h := createIdempotencyHandler("a", "b")
+
+	v, err := h.GetRecord(Context, &GetRecordRequest{AAD: "aad", Key: "b"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, Record{AAD: "aad", Key: "b", ID: 1}, v)
+}
+
+func TestGetRecordErrNotFound(t *testing.T) {
+	h := createIdempotencyHandler()
+
+	_, err := h.GetRecord(Context, &GetRecordRequest{AAD: "aad", Key: "a"})
+
+	assert.Error(t, err)
+	assert.Equal(t, errors.ErrIdempotencyKeyNotFound, err.(errors.Err).ErrorCode())
+}
+
+func TestListRecordsOK(t *testing.T) {
+	h := createIdempotencyHandler("b", "a")
+
+	v, err := h.ListRecords(Context, &ListRecordsRequest{AAD: "aad"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, ListRecordsResponse{Records: []Record{
+		{AAD: "aad", Key: "a", ID: 1},
+		{AAD: "aad", Key: "b", ID: 0},
+	}}, v)
+}
+
+func TestPurgeRecordsKeys(t *testing.T) {
+	h := createIdempotencyHandler("a", "b")
+
+	v, err := h.PurgeRecords(Context, &PurgeRecordsRequest{AAD: "aad", Keys: []string{"a"}})
+	assert.Nil(t, err)
+	assert.Equal(t, PurgeRecordsResponse{Purged: 1}, v)
+
+	v, err = h.ListRecords(Context, &ListRecordsRequest{AAD: "aad"})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(v.(ListRecordsResponse).Records))
+}
+
+func TestPurgeRecordsAll(t *testing.T) {
+	h := createIdempotencyHandler("a", "b")
+
+	v, err := h.PurgeRecords(Context, &PurgeRecordsRequest{AAD: "aad", All: true})
+
+	assert.Nil(t, err)
+	assert.Equal(t, PurgeRecordsResponse{Purged: 2}, v)
+}
+
+func TestPurgeRecordsErrNoKeys(t *testing.T) {
+	h := createIdempotencyHandler("a")
+
+	_, err := h.PurgeRecords(Context, &PurgeRecordsRequest{AAD: "aad"})
+
+	assert.Error(t, err)
+	assert.Equal(t, errors.ErrEmptyInput, err.(errors.Err).ErrorCode())
+}
+
+func TestSetExpiryOK(t *testing.T) {
+	h := createIdempotencyHandler("a")
+
+	v, err := h.SetExpiry(Context, &SetExpiryRequest{AAD: "aad", Key: "a", TTL: 60000})
+	assert.Nil(t, err)
+	assert.True(t, v.(Record).ExpiresAt > 0)
+	assert.False(t, v.(Record).Expired)
+
+	v, err = h.SetExpiry(Context, &SetExpiryRequest{AAD: "aad", Key: "a"})
+	assert.Nil(t, err)
+	assert.Equal(t, Record{AAD: "aad", Key: "a", ID: 0}, v)
+}
+
+func TestSetExpiryErrNegativeTTL(t *testing.T) {
+	h := createIdempotencyHandler("a")
+
+	_, err := h.SetExpiry(Context, &SetExpiryRequest{AAD: "aad", Key: "a", TTL: -1})
+
+	assert.Error(t, err)
+	assert.Equal(t, errors.ErrOutOfRange, err.(errors.Err).ErrorCode())
+}
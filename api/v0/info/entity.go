@@ -11,3 +11,24 @@ type GetSendersResponse struct {
 	// signers of transactions.
 	Addresses []string `json:"addresses"`
 }
+
+// GetChainHeadResponse is the response to the GetChainHead request.
+type GetChainHeadResponse struct {
+	// BlockNumber is the number of the latest block known to the backend
+	BlockNumber uint64 `json:"blockNumber"`
+
+	// Timestamp is the time in seconds at which the latest block
+	// known to the backend was produced
+	Timestamp uint64 `json:"timestamp"`
+
+	// ChainID is the identifier of the chain, or 0 if the backend
+	// does not report it
+	ChainID uint64 `json:"chainId"`
+
+	// Syncing is true if the backend is still catching up with the chain
+	Syncing bool `json:"syncing"`
+
+	// HighestBlock is the number of the highest block the backend is
+	// aware of while it is syncing
+	HighestBlock uint64 `json:"highestBlock,omitempty"`
+}
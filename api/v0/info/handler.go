@@ -4,6 +4,8 @@ import (
 	"context"
 
 	ethereum "github.com/ethereum/go-ethereum/common"
+	backend "github.com/oasislabs/oasis-gateway/backend/core"
+	"github.com/oasislabs/oasis-gateway/errors"
 	"github.com/oasislabs/oasis-gateway/log"
 	"github.com/oasislabs/oasis-gateway/rpc"
 )
@@ -12,6 +14,7 @@ import (
 // implementation
 type Client interface {
 	Senders() []ethereum.Address
+	GetChainHead(context.Context) (backend.GetChainHeadResponse, errors.Err)
 }
 
 type Services struct {
@@ -60,6 +63,28 @@ func (h InfoHandler) GetSenders(ctx context.Context, v interface{}) (interface{}
 	}, nil
 }
 
+// GetChainHead returns the latest block known to the backend, the
+// chain it belongs to and whether the backend is still syncing, so
+// that clients can check that the view the gateway has of the chain
+// is fresh.
+func (h InfoHandler) GetChainHead(ctx context.Context, v interface{}) (interface{}, error) {
+	res, err := h.client.GetChainHead(ctx)
+	if err != nil {
+		h.logger.Debug(ctx, "failed to get chain head", log.MapFields{
+			"call_type": "GetChainHeadFailure",
+		}, err)
+		return nil, err
+	}
+
+	return &GetChainHeadResponse{
+		BlockNumber:  res.BlockNumber,
+		Timestamp:    res.Timestamp,
+		ChainID:      res.ChainID,
+		Syncing:      res.Syncing,
+		HighestBlock: res.HighestBlock,
+	}, nil
+}
+
 // BindHandler binds the version handler to the handler binder
 func BindHandler(services Services, binder rpc.HandlerBinder) {
 	handler := NewInfoHandler(services)
@@ -69,4 +94,7 @@ func BindHandler(services Services, binder rpc.HandlerBinder) {
 
 	binder.Bind("GET", "/v0/api/getSenders", rpc.HandlerFunc(handler.GetSenders),
 		rpc.EntityFactoryFunc(func() interface{} { return nil }))
+
+	binder.Bind("GET", "/v0/api/getChainHead", rpc.HandlerFunc(handler.GetChainHead),
+		rpc.EntityFactoryFunc(func() interface{} { return nil }))
 }
@@ -5,7 +5,11 @@ import (
 	"io/ioutil"
 	"testing"
 
+	stderr "errors"
+
 	ethereum "github.com/ethereum/go-ethereum/common"
+	backend "github.com/oasislabs/oasis-gateway/backend/core"
+	"github.com/oasislabs/oasis-gateway/errors"
 	"github.com/oasislabs/oasis-gateway/log"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -28,10 +32,23 @@ func (c *MockClient) Senders() []ethereum.Address {
 	}
 }
 
+func (c *MockClient) GetChainHead(ctx context.Context) (backend.GetChainHeadResponse, errors.Err) {
+	args := c.Called(ctx)
+	if args.Get(1) != nil {
+		return backend.GetChainHeadResponse{}, args.Get(1).(errors.Err)
+	}
+
+	return args.Get(0).(backend.GetChainHeadResponse), nil
+}
+
 func createInfoHandler() InfoHandler {
+	return createInfoHandlerWithClient(&MockClient{})
+}
+
+func createInfoHandlerWithClient(client *MockClient) InfoHandler {
 	return NewInfoHandler(Services{
 		Logger: Logger,
-		Client: &MockClient{},
+		Client: client,
 	})
 }
 
@@ -59,3 +76,39 @@ func TestGetSenders(t *testing.T) {
 		},
 	}, res)
 }
+
+func TestGetChainHead(t *testing.T) {
+	client := &MockClient{}
+	client.On("GetChainHead", mock.Anything).
+		Return(backend.GetChainHeadResponse{
+			BlockNumber:  16,
+			Timestamp:    1024,
+			ChainID:      42261,
+			Syncing:      true,
+			HighestBlock: 32,
+		}, nil)
+	h := createInfoHandlerWithClient(client)
+
+	res, err := h.GetChainHead(Context, nil)
+
+	assert.Nil(t, err)
+	assert.Equal(t, &GetChainHeadResponse{
+		BlockNumber:  16,
+		Timestamp:    1024,
+		ChainID:      42261,
+		Syncing:      true,
+		HighestBlock: 32,
+	}, res)
+}
+
+func TestGetChainHeadErr(t *testing.T) {
+	client := &MockClient{}
+	client.On("GetChainHead", mock.Anything).
+		Return(backend.GetChainHeadResponse{}, errors.New(errors.ErrInternalError, stderr.New("error")))
+	h := createInfoHandlerWithClient(client)
+
+	_, err := h.GetChainHead(Context, nil)
+
+	assert.Error(t, err)
+	assert.Equal(t, errors.ErrInternalError.Code(), err.(errors.Err).ErrorCode().Code())
+}
@@ -0,0 +1,37 @@
+package logging
+
+// ListLevelsRequest is a request to retrieve the level
+// each component logs at
+type ListLevelsRequest struct{}
+
+// SetLevelRequest is a request to set the level of a component
+type SetLevelRequest struct {
+	// Component is the name of the component, which is the pkg
+	// and class of its entries joined by a slash, or a prefix of
+	// it. If empty the default level is set
+	Component string `json:"component"`
+
+	// Level is the minimum level of the entries logged by the
+	// component. If empty the level set for the component is
+	// removed, so that it logs at the level of its parent
+	Level string `json:"level"`
+}
+
+// ComponentLevel is the level set for a component
+type ComponentLevel struct {
+	// Component is the name of the component
+	Component string `json:"component"`
+
+	// Level is the minimum level of the entries logged by the
+	// component and the components whose name starts with it
+	Level string `json:"level"`
+}
+
+// ListLevelsResponse is the response to a ListLevelsRequest
+type ListLevelsResponse struct {
+	// Default is the level of the components without a level set
+	Default string `json:"default"`
+
+	// Levels are the levels set for components, sorted by name
+	Levels []ComponentLevel `json:"levels"`
+}
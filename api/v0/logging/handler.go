@@ -0,0 +1,133 @@
+package logging
+
+import (
+	"context"
+	stderr "errors"
+
+	"github.com/oasislabs/oasis-gateway/errors"
+	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/oasislabs/oasis-gateway/rpc"
+	"github.com/sirupsen/logrus"
+)
+
+// Client interface for the underlying operations needed for the API
+// implementation
+type Client interface {
+	// Default returns the level of the components without a level set
+	Default() logrus.Level
+
+	// SetDefault sets the level of the components without a level set
+	SetDefault(level logrus.Level)
+
+	// Set sets the level of a component
+	Set(component string, level logrus.Level)
+
+	// Unset removes the level set for a component
+	Unset(component string) bool
+
+	// Overrides returns the levels set for components
+	Overrides() []log.Override
+}
+
+// Services required by the LoggingHandler execution
+type Services struct {
+	Logger log.Logger
+	Client Client
+}
+
+// LoggingHandler implements the handlers to change the
+// level of the components of the gateway
+type LoggingHandler struct {
+	logger log.Logger
+	client Client
+}
+
+// SetLevel sets or removes the level of a component
+func (h LoggingHandler) SetLevel(ctx context.Context, v interface{}) (interface{}, error) {
+	req := v.(*SetLevelRequest)
+
+	if len(req.Level) == 0 {
+		if len(req.Component) == 0 {
+			err := errors.New(errors.ErrInvalidLogLevel, stderr.New("the default level cannot be removed"))
+			h.logger.Debug(ctx, "request failed", log.MapFields{
+				"call_type": "SetLevelFailure",
+			}, err)
+			return nil, err
+		}
+
+		h.client.Unset(req.Component)
+		h.logger.Info(ctx, "log level removed", log.MapFields{
+			"call_type": "SetLevelSuccess",
+			"component": req.Component,
+		})
+		return ComponentLevel{Component: req.Component}, nil
+	}
+
+	level, perr := logrus.ParseLevel(req.Level)
+	if perr != nil {
+		err := errors.New(errors.ErrInvalidLogLevel, perr)
+		h.logger.Debug(ctx, "request failed", log.MapFields{
+			"call_type": "SetLevelFailure",
+			"component": req.Component,
+		}, err)
+		return nil, err
+	}
+
+	if len(req.Component) == 0 {
+		h.client.SetDefault(level)
+	} else {
+		h.client.Set(req.Component, level)
+	}
+
+	h.logger.Info(ctx, "log level set", log.MapFields{
+		"call_type": "SetLevelSuccess",
+		"component": req.Component,
+		"level":     level.String(),
+	})
+
+	return ComponentLevel{Component: req.Component, Level: level.String()}, nil
+}
+
+// ListLevels retrieves the default level and the levels set for components
+func (h LoggingHandler) ListLevels(ctx context.Context, v interface{}) (interface{}, error) {
+	_ = v.(*ListLevelsRequest)
+
+	overrides := h.client.Overrides()
+	res := ListLevelsResponse{
+		Default: h.client.Default().String(),
+		Levels:  make([]ComponentLevel, 0, len(overrides)),
+	}
+	for _, o := range overrides {
+		res.Levels = append(res.Levels, ComponentLevel{
+			Component: o.Component,
+			Level:     o.Level.String(),
+		})
+	}
+
+	return res, nil
+}
+
+func NewLoggingHandler(services Services) LoggingHandler {
+	if services.Client == nil {
+		panic("Client must be provided as a service")
+	}
+	if services.Logger == nil {
+		panic("Logger must be provided as a service")
+	}
+
+	return LoggingHandler{
+		logger: services.Logger.ForClass("logging", "handler"),
+		client: services.Client,
+	}
+}
+
+// BindHandler binds the logging handler to the provided
+// HandlerBinder
+func BindHandler(services Services, binder rpc.HandlerBinder) {
+	handler := NewLoggingHandler(services)
+
+	binder.Bind("POST", "/v0/api/logging/levels/set", rpc.HandlerFunc(handler.SetLevel),
+		rpc.EntityFactoryFunc(func() interface{} { return &SetLevelRequest{} }))
+	binder.Bind("GET", "/v0/api/logging/levels/list", rpc.HandlerFunc(handler.ListLevels),
+		rpc.EntityFactoryFunc(func() interface{} { return &ListLevelsRequest{} }))
+}
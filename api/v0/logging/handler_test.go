@@ -0,0 +1,79 @@
+package logging
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/oasislabs/oasis-gateway/errors"
+	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+var Context = context.TODO()
+
+var Logger = log.NewLogrus(log.LogrusLoggerProperties{
+	Output: ioutil.Discard,
+})
+
+func createLoggingHandler() (LoggingHandler, *log.Levels) {
+	levels := log.NewLevels(logrus.InfoLevel)
+	return NewLoggingHandler(Services{Logger: Logger, Client: levels}), levels
+}
+
+func TestSetLevelComponent(t *testing.T) {
+	handler, levels := createLoggingHandler()
+
+	res, err := handler.SetLevel(Context, &SetLevelRequest{Component: "mqueue/redis", Level: "warn"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, ComponentLevel{Component: "mqueue/redis", Level: "warning"}, res)
+	assert.Equal(t, logrus.WarnLevel, levels.Level("mqueue/redis/MQueue"))
+}
+
+func TestSetLevelDefault(t *testing.T) {
+	handler, levels := createLoggingHandler()
+
+	_, err := handler.SetLevel(Context, &SetLevelRequest{Level: "debug"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, logrus.DebugLevel, levels.Default())
+}
+
+func TestSetLevelRemove(t *testing.T) {
+	handler, levels := createLoggingHandler()
+	levels.Set("eth", logrus.DebugLevel)
+
+	_, err := handler.SetLevel(Context, &SetLevelRequest{Component: "eth"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, logrus.InfoLevel, levels.Level("eth/Client"))
+}
+
+func TestSetLevelErrInvalid(t *testing.T) {
+	handler, _ := createLoggingHandler()
+
+	_, err := handler.SetLevel(Context, &SetLevelRequest{Component: "eth", Level: "loud"})
+	assert.Equal(t, errors.ErrInvalidLogLevel, err.(errors.Err).ErrorCode())
+
+	_, err = handler.SetLevel(Context, &SetLevelRequest{})
+	assert.Equal(t, errors.ErrInvalidLogLevel, err.(errors.Err).ErrorCode())
+}
+
+func TestListLevels(t *testing.T) {
+	handler, levels := createLoggingHandler()
+	levels.Set("mqueue", logrus.ErrorLevel)
+	levels.Set("eth", logrus.DebugLevel)
+
+	res, err := handler.ListLevels(Context, &ListLevelsRequest{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, ListLevelsResponse{
+		Default: "info",
+		Levels: []ComponentLevel{
+			{Component: "eth", Level: "debug"},
+			{Component: "mqueue", Level: "error"},
+		},
+	}, res)
+}
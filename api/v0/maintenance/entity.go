@@ -0,0 +1,45 @@
+package maintenance
+
+// GetStatusRequest is a request to retrieve whether the
+// gateway is under maintenance
+type GetStatusRequest struct{}
+
+// SetStatusRequest is a request to put the gateway under
+// maintenance or to end the maintenance
+type SetStatusRequest struct {
+	// Active is true to put the gateway under maintenance
+	Active bool `json:"active"`
+
+	// Message is the notice of the operator for the clients
+	Message string `json:"message"`
+
+	// RetryAfter is the time in seconds after which the clients
+	// are advised to retry their requests
+	RetryAfter uint64 `json:"retryAfter"`
+}
+
+// Status is whether the gateway is under maintenance
+type Status struct {
+	// Active is true if the gateway is under maintenance
+	Active bool `json:"active"`
+
+	// Message is the notice of the operator for the clients
+	Message string `json:"message,omitempty"`
+
+	// RetryAfter is the time in seconds after which the clients
+	// are advised to retry their requests
+	RetryAfter uint64 `json:"retryAfter,omitempty"`
+
+	// Since is the time in seconds since epoch at which
+	// the maintenance started
+	Since int64 `json:"since,omitempty"`
+}
+
+// SetStatusResponse is the response to a SetStatusRequest
+type SetStatusResponse struct {
+	Status
+
+	// Announced is the number of sessions to which the
+	// change of status was announced
+	Announced int `json:"announced"`
+}
@@ -0,0 +1,122 @@
+package maintenance
+
+import (
+	"context"
+	"time"
+
+	backend "github.com/oasislabs/oasis-gateway/backend/core"
+	"github.com/oasislabs/oasis-gateway/errors"
+	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/oasislabs/oasis-gateway/maintenance"
+	"github.com/oasislabs/oasis-gateway/rpc"
+)
+
+// Client interface for the underlying operations needed for the API
+// implementation
+type Client interface {
+	// Announce inserts an event in the queue of each session
+	Announce(ctx context.Context, newEvent func(id uint64) backend.Event) (int, errors.Err)
+}
+
+// Services required by the MaintenanceHandler execution
+type Services struct {
+	Logger log.Logger
+	Client Client
+	Mode   *maintenance.Mode
+}
+
+// MaintenanceHandler implements the handlers to manage
+// the maintenance of the gateway
+type MaintenanceHandler struct {
+	logger log.Logger
+	client Client
+	mode   *maintenance.Mode
+}
+
+func (h MaintenanceHandler) status() Status {
+	notice, active := h.mode.Status()
+	if !active {
+		return Status{}
+	}
+
+	return Status{
+		Active:     true,
+		Message:    notice.Message,
+		RetryAfter: uint64(notice.RetryAfter / time.Second),
+		Since:      notice.Since.Unix(),
+	}
+}
+
+// GetStatus retrieves whether the gateway is under maintenance
+func (h MaintenanceHandler) GetStatus(ctx context.Context, v interface{}) (interface{}, error) {
+	_ = v.(*GetStatusRequest)
+	return h.status(), nil
+}
+
+// SetStatus puts the gateway under maintenance or ends the maintenance,
+// and announces the change to the sessions
+func (h MaintenanceHandler) SetStatus(ctx context.Context, v interface{}) (interface{}, error) {
+	req := v.(*SetStatusRequest)
+
+	if req.Active {
+		h.mode.Enable(maintenance.Notice{
+			Message:    req.Message,
+			RetryAfter: time.Duration(req.RetryAfter) * time.Second,
+		})
+	} else {
+		h.mode.Disable()
+	}
+
+	h.logger.Info(ctx, "maintenance status set", log.MapFields{
+		"call_type": "SetMaintenanceSuccess",
+		"active":    req.Active,
+	})
+
+	// the status is changed even if the announcement fails, since
+	// the clients also find out when their requests are rejected
+	announced, err := h.client.Announce(ctx, func(id uint64) backend.Event {
+		return backend.MaintenanceEvent{
+			ID:         id,
+			Active:     req.Active,
+			Message:    req.Message,
+			RetryAfter: req.RetryAfter,
+		}
+	})
+	if err != nil {
+		h.logger.Warn(ctx, "failed to announce maintenance status", log.MapFields{
+			"call_type": "AnnounceMaintenanceFailure",
+			"active":    req.Active,
+		}, err)
+	}
+
+	return SetStatusResponse{Status: h.status(), Announced: announced}, nil
+}
+
+func NewMaintenanceHandler(services Services) MaintenanceHandler {
+	if services.Client == nil {
+		panic("Client must be provided as a service")
+	}
+	if services.Logger == nil {
+		panic("Logger must be provided as a service")
+	}
+	if services.Mode == nil {
+		panic("Mode must be provided as a service")
+	}
+
+	return MaintenanceHandler{
+		logger: services.Logger.ForClass("maintenance", "handler"),
+		client: services.Client,
+		mode:   services.Mode,
+	}
+}
+
+// BindHandler binds the maintenance handler to the provided
+// HandlerBinder
+func BindHandler(services Services, binder rpc.HandlerBinder) {
+	handler := NewMaintenanceHandler(services)
+
+	binder.Bind("POST", "/v0/api/maintenance/set", rpc.HandlerFunc(handler.SetStatus),
+		rpc.EntityFactoryFunc(func() interface{} { return &SetStatusRequest{} }))
+	binder.Bind("GET", "/v0/api/maintenance/status", rpc.HandlerFunc(handler.GetStatus),
+		rpc.EntityFactoryFunc(func() interface{} { return &GetStatusRequest{} }))
+}
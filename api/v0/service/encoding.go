@@ -0,0 +1,71 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/oasislabs/oasis-gateway/errors"
+)
+
+const (
+	// HexEncoding is the default encoding for data and outputs, a 0x
+	// prefixed hex string
+	HexEncoding = "hex"
+
+	// Base64Encoding encodes data and outputs using standard base64
+	// encoding, which avoids the size overhead of hex for large payloads
+	Base64Encoding = "base64"
+)
+
+// decodeData validates the data provided by the client in the
+// requested encoding and returns it as a 0x prefixed hex string,
+// which is the encoding expected by the backends. If encoding is
+// empty data is expected to be hex encoded
+func decodeData(encoding, data string) (string, errors.Err) {
+	return decodeDataLimit(encoding, data, MaxDataSize)
+}
+
+// decodeDataLimit is like decodeData with the
+// limit for the decoded size of the data
+func decodeDataLimit(encoding, data string, limit int) (string, errors.Err) {
+	switch encoding {
+	case "", HexEncoding:
+		if err := validateDataLimit(data, limit); err != nil {
+			return "", err
+		}
+		return data, nil
+	case Base64Encoding:
+		p, err := base64.StdEncoding.DecodeString(data)
+		if err != nil {
+			return "", errors.New(errors.ErrDataNotBase64, err)
+		}
+		if len(p) > limit {
+			return "", errors.New(errors.ErrDataTooLarge,
+				fmt.Errorf("data has %d bytes which exceeds the limit of %d bytes",
+					len(p), limit))
+		}
+		return "0x" + hex.EncodeToString(p), nil
+	default:
+		return "", errors.New(errors.ErrUnknownEncoding,
+			fmt.Errorf("unknown encoding %q", encoding))
+	}
+}
+
+// encodeOutput encodes an output returned by the backend as a 0x
+// prefixed hex string in the requested encoding
+func encodeOutput(encoding, output string) string {
+	if encoding != Base64Encoding || !strings.HasPrefix(output, "0x") {
+		return output
+	}
+
+	p, err := hex.DecodeString(output[2:])
+	if err != nil {
+		// outputs are generated by the backend so this should not happen,
+		// in which case it is safer to return the output as is
+		return output
+	}
+
+	return base64.StdEncoding.EncodeToString(p)
+}
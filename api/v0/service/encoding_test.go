@@ -0,0 +1,38 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/oasislabs/oasis-gateway/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeDataHex(t *testing.T) {
+	data, err := decodeData("", "0x0102")
+	assert.Nil(t, err)
+	assert.Equal(t, "0x0102", data)
+
+	_, err = decodeData(HexEncoding, "0102")
+	assert.Equal(t, errors.ErrDataMissingHexPrefix, err.ErrorCode())
+}
+
+func TestDecodeDataBase64(t *testing.T) {
+	data, err := decodeData(Base64Encoding, "AQI=")
+	assert.Nil(t, err)
+	assert.Equal(t, "0x0102", data)
+
+	_, err = decodeData(Base64Encoding, "0x0102")
+	assert.Equal(t, errors.ErrDataNotBase64, err.ErrorCode())
+}
+
+func TestDecodeDataUnknownEncoding(t *testing.T) {
+	_, err := decodeData("base32", "AEBA====")
+	assert.Equal(t, errors.ErrUnknownEncoding, err.ErrorCode())
+}
+
+func TestEncodeOutput(t *testing.T) {
+	assert.Equal(t, "0x0102", encodeOutput("", "0x0102"))
+	assert.Equal(t, "0x0102", encodeOutput(HexEncoding, "0x0102"))
+	assert.Equal(t, "AQI=", encodeOutput(Base64Encoding, "0x0102"))
+	assert.Equal(t, "", encodeOutput(Base64Encoding, "0x"))
+}
@@ -1,6 +1,12 @@
 package service
 
-import "github.com/oasislabs/oasis-gateway/rpc"
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/oasislabs/oasis-gateway/rpc"
+)
 
 // RequestType defines the type of the request. May be
 // useful for serialization and deserialization
@@ -13,6 +19,10 @@ const (
 	GetCode      RequestType = 3
 	GetExpiry    RequestType = 4
 	GetPublicKey RequestType = 5
+	Deployments  RequestType = 6
+	Query        RequestType = 7
+	GetOutput    RequestType = 8
+	ExecuteBatch RequestType = 9
 )
 
 // Request is the type implemented by requests expected
@@ -40,8 +50,38 @@ type ExecuteServiceRequest struct {
 	// as argument
 	Data string `json:"data"`
 
-	// Address where the service can be found
+	// Address where the service can be found. An alias managed
+	// by the gateway or a name registered in the configured
+	// on-chain registry can be used instead of the address
 	Address string `json:"address"`
+
+	// Expiry is an optional block number after which the transaction is
+	// discarded if it has not been included in a block yet
+	Expiry uint64 `json:"expiry,omitempty"`
+
+	// GasLimit is an optional gas limit of the transaction. If not
+	// set the gas limit is chosen by the gateway
+	GasLimit uint64 `json:"gasLimit,omitempty"`
+
+	// GasPrice is an optional gas price in wei of the transaction. If
+	// not set the gas price is chosen by the gateway
+	GasPrice uint64 `json:"gasPrice,omitempty"`
+
+	// Encoding is the encoding used for Data and for the output
+	// returned to the client, either "hex" or "base64". If not set
+	// hex encoding is used
+	Encoding string `json:"encoding,omitempty"`
+
+	// IdempotencyKey if set is used to derive the ID of the event
+	// generated by the request, so that the client can compute it
+	// before the response is returned
+	IdempotencyKey string `json:"idempotencyKey,omitempty"`
+
+	// OutputKey if set is the hex encoded X25519 public key of the
+	// session of the client. The output of the execution is
+	// encrypted to it before it is stored, so that only the
+	// client can read it
+	OutputKey string `json:"outputKey,omitempty"`
 }
 
 // Type implementation of Request for ExecuteServiceRequest
@@ -53,6 +93,68 @@ func (r ExecuteServiceRequest) Type() RequestType {
 // using the polling mechanisms
 type ExecuteServiceResponse AsyncResponse
 
+// ExecuteServiceCall is a service execution of an
+// ExecuteServiceBatchRequest
+type ExecuteServiceCall struct {
+	// Data is a blob of data that the user wants to pass to the service
+	// as argument
+	Data string `json:"data"`
+
+	// Address where the service can be found. An alias managed
+	// by the gateway or a name registered in the configured
+	// on-chain registry can be used instead of the address
+	Address string `json:"address"`
+
+	// Expiry is an optional block number after which the transaction is
+	// discarded if it has not been included in a block yet
+	Expiry uint64 `json:"expiry,omitempty"`
+
+	// GasLimit is an optional gas limit of the transaction. If not
+	// set the gas limit is chosen by the gateway
+	GasLimit uint64 `json:"gasLimit,omitempty"`
+
+	// GasPrice is an optional gas price in wei of the transaction. If
+	// not set the gas price is chosen by the gateway
+	GasPrice uint64 `json:"gasPrice,omitempty"`
+
+	// Encoding is the encoding used for Data and for the output
+	// returned to the client, either "hex" or "base64". If not set
+	// hex encoding is used
+	Encoding string `json:"encoding,omitempty"`
+}
+
+// ExecuteServiceBatchRequest is used by the user to trigger several
+// service executions with a single request. Each call generates its
+// own event, which is polled like the event of an ExecuteServiceRequest
+type ExecuteServiceBatchRequest struct {
+	// Calls are the service executions of the batch. Their
+	// transactions are sent in the order of the calls
+	Calls []ExecuteServiceCall `json:"calls"`
+
+	// OutputKey if set is the hex encoded X25519 public key of the
+	// session of the client. The outputs of the executions are
+	// encrypted to it before they are stored, so that only the
+	// client can read them
+	OutputKey string `json:"outputKey,omitempty"`
+}
+
+// Type implementation of Request for ExecuteServiceBatchRequest
+func (r ExecuteServiceBatchRequest) Type() RequestType {
+	return ExecuteBatch
+}
+
+// ExecuteServiceBatchResponse is the response to an
+// ExecuteServiceBatchRequest
+type ExecuteServiceBatchResponse struct {
+	// IDs are the IDs of the events of the calls in the
+	// order of the calls
+	IDs []uint64 `json:"ids"`
+
+	// BatchID is the ID of the BatchCompletedEvent published
+	// once all the calls of the batch complete
+	BatchID uint64 `json:"batchId"`
+}
+
 // DeployServiceRequest is issued by the user to trigger a service
 // execution. A client is always subscribed to a subscription with
 // topic "service" from which the client can retrieve the asynchronous
@@ -61,6 +163,25 @@ type DeployServiceRequest struct {
 	// Data is a blob of data that the user wants to pass as argument for
 	// the deployment of a service
 	Data string `json:"data"`
+
+	// Expiry is an optional block number after which the transaction is
+	// discarded if it has not been included in a block yet
+	Expiry uint64 `json:"expiry,omitempty"`
+
+	// Encoding is the encoding used for Data and for the output
+	// returned to the client, either "hex" or "base64". If not set
+	// hex encoding is used
+	Encoding string `json:"encoding,omitempty"`
+
+	// IdempotencyKey if set is used to derive the ID of the event
+	// generated by the request, so that the client can compute it
+	// before the response is returned
+	IdempotencyKey string `json:"idempotencyKey,omitempty"`
+
+	// ContentType is the type of the payload in Data, either
+	// "application/evm" for EVM bytecode or "application/wasm"
+	// for a WASM module. If not set EVM bytecode is expected
+	ContentType string `json:"contentType,omitempty"`
 }
 
 // Type implementation of Request for DeployServiceRequest
@@ -156,6 +277,132 @@ type GetPublicKeyResponse struct {
 	Signature string `json:"signature"`
 }
 
+// QueryServiceRequest is a request to execute a read-only query on a
+// service. The query does not generate a transaction and the
+// response is returned synchronously
+type QueryServiceRequest struct {
+	// Address where the service can be found. An alias managed
+	// by the gateway or a name registered in the configured
+	// on-chain registry can be used instead of the address
+	Address string `json:"address"`
+
+	// Data is a blob of data that the user wants to pass to the service
+	// as argument
+	Data string `json:"data"`
+
+	// Block is an optional block number at which the query is executed.
+	// If not set the query is executed on the latest block
+	Block uint64 `json:"block,omitempty"`
+
+	// Encoding is the encoding used for Data and for the output
+	// returned to the client, either "hex" or "base64". If not set
+	// hex encoding is used
+	Encoding string `json:"encoding,omitempty"`
+}
+
+// Type implementation of Request for QueryServiceRequest
+func (r QueryServiceRequest) Type() RequestType {
+	return Query
+}
+
+// QueryServiceResponse is the response to a read-only query
+type QueryServiceResponse struct {
+	// Address where the service can be found
+	Address string `json:"address"`
+
+	// Output is the result of the query
+	Output string `json:"output"`
+
+	// Encoding of Output, which is the encoding requested
+	Encoding string `json:"encoding,omitempty"`
+
+	// maxAge is the time the response can be cached by the client
+	maxAge time.Duration
+}
+
+// HttpHeaders is the implementation of rpc.HttpHeaderProvider for
+// QueryServiceResponse, which sets the caching policy for the response
+func (r QueryServiceResponse) HttpHeaders() http.Header {
+	seconds := int64(r.maxAge / time.Second)
+	if seconds <= 0 {
+		return http.Header{"Cache-Control": []string{"no-cache"}}
+	}
+
+	return http.Header{"Cache-Control": []string{fmt.Sprintf("max-age=%d", seconds)}}
+}
+
+// ListDeploymentsRequest is a request to retrieve the services
+// deployed through the gateway by the issuer of the request
+type ListDeploymentsRequest struct {
+	// Offset at which deployments need to be provided. Deployments are
+	// ordered with sequence numbers by the time they completed
+	Offset uint64 `json:"offset"`
+
+	// Count for the number of deployments the client would prefer to
+	// receive at most from a single response
+	Count uint `json:"count"`
+
+	// CodeHash if set restricts the deployments returned to the ones
+	// of the bytecode with the provided keccak256 hash
+	CodeHash string `json:"codeHash,omitempty"`
+}
+
+// Type implementation of Request for ListDeploymentsRequest
+func (r ListDeploymentsRequest) Type() RequestType {
+	return Deployments
+}
+
+// Deployment describes a service deployed through the gateway
+type Deployment struct {
+	// ID is the sequence number of the deployment
+	ID uint64 `json:"id"`
+
+	// Address at which the service was deployed
+	Address string `json:"address"`
+
+	// CodeHash is the keccak256 hash of the deployed bytecode
+	CodeHash string `json:"codeHash"`
+
+	// Timestamp is the unix time in seconds at which the
+	// deployment completed
+	Timestamp uint64 `json:"timestamp"`
+}
+
+// ListDeploymentsResponse returns the list of deployments the
+// client requested
+type ListDeploymentsResponse struct {
+	// Offset is the base offset the deployments were got from
+	Offset uint64 `json:"offset"`
+
+	// Deployments starting from the provided Offset
+	Deployments []Deployment `json:"deployments"`
+}
+
+// GetServiceOutputRequest is a request to retrieve the full output
+// of an execution whose output was truncated in its event
+type GetServiceOutputRequest struct {
+	// ID of the execution as returned when it was submitted
+	ID uint64 `json:"id"`
+}
+
+// Type implementation of Request for GetServiceOutputRequest
+func (r GetServiceOutputRequest) Type() RequestType {
+	return GetOutput
+}
+
+// GetServiceOutputResponse is the full output of an execution
+type GetServiceOutputResponse struct {
+	// ID of the execution
+	ID uint64 `json:"id"`
+
+	// Output generated by the service at the end of its execution
+	Output string `json:"output"`
+
+	// Encoding of Output, which is the encoding requested when
+	// the service execution was submitted
+	Encoding string `json:"encoding,omitempty"`
+}
+
 // PollServiceRequest is a request that allows the user to
 // poll for events either from asynchronous responses
 type PollServiceRequest struct {
@@ -165,12 +412,27 @@ type PollServiceRequest struct {
 	Offset uint64 `json:"offset"`
 
 	// Count for the number of items the client would prefer to receive
-	// at most from a single response
+	// at most from a single response. If not set the default count of
+	// the gateway is used, and it is capped to the maximum count
 	Count uint `json:"count"`
 
 	// DiscardPrevious allows the client to define whether the server should
 	// discard all the events that have a sequence number lower than the offer
 	DiscardPrevious bool `json:"discardPrevious"`
+
+	// Types if set restricts the events returned to the ones of the
+	// provided types. Options are execute, deploy, error, warning,
+	// maintenance, reconnect and batch
+	Types []string `json:"types,omitempty"`
+
+	// Since if set retrieves the events produced at or after Since
+	// instead of the events starting at Offset. It is an RFC 3339
+	// timestamp such as 2024-05-01T00:00:00Z
+	Since string `json:"since,omitempty"`
+
+	// Until if set restricts the events retrieved by time to the
+	// ones produced before Until. It is an RFC 3339 timestamp
+	Until string `json:"until,omitempty"`
 }
 
 // Type implementation of Request for PollServiceRequest
@@ -192,6 +454,13 @@ type PollServiceResponse struct {
 	// Offset is the base offset the requests were got from
 	Offset uint64 `json:"offset"`
 
+	// Count is the number of events the server retrieved at most for
+	// the poll, after applying the default count and the maximum count
+	Count uint `json:"count"`
+
+	// MaxCount is the maximum number of events returned by a poll
+	MaxCount uint `json:"maxCount"`
+
 	// Events is the list of events that the server has starting from
 	// the provided Offset
 	Events []Event `json:"events"`
@@ -211,6 +480,26 @@ type ExecuteServiceEvent struct {
 
 	// Output generated by the service at the end of its execution
 	Output string `json:"output"`
+
+	// Encoding of Output, which is the encoding requested when
+	// the service execution was submitted
+	Encoding string `json:"encoding,omitempty"`
+
+	// Encrypted is true if Output is sealed to the OutputKey
+	// provided when the service execution was submitted
+	Encrypted bool `json:"encrypted,omitempty"`
+
+	// Truncated is true if Output is only a preview of the output
+	// of the execution. The full output can be retrieved from
+	// OutputURL until it expires
+	Truncated bool `json:"truncated,omitempty"`
+
+	// OutputSize is the size of the full output if it is Truncated
+	OutputSize int `json:"outputSize,omitempty"`
+
+	// OutputURL is the path from which the full output can be
+	// retrieved if it is Truncated
+	OutputURL string `json:"outputUrl,omitempty"`
 }
 
 // DeployServiceEvent is the event that can be polled by the user
@@ -237,6 +526,80 @@ type ErrorEvent struct {
 	Cause rpc.Error `json:"cause"`
 }
 
+// WarningEvent is the event that can be polled by the user when
+// the session approaches one of its quotas and its requests may
+// be rejected soon
+type WarningEvent struct {
+	// ID to identify the event itself within the sequence of events.
+	ID uint64 `json:"id"`
+
+	// Quota is the name of the quota the session is approaching
+	Quota string `json:"quota"`
+
+	// Used is the amount of the quota used by the session
+	Used uint64 `json:"used"`
+
+	// Limit is the amount of the quota at which the requests
+	// of the session are rejected
+	Limit uint64 `json:"limit"`
+}
+
+// MaintenanceEvent is the event that can be polled by the user when
+// the gateway enters or leaves maintenance
+type MaintenanceEvent struct {
+	// ID to identify the event itself within the sequence of events.
+	ID uint64 `json:"id"`
+
+	// Active is true if the gateway entered maintenance and false
+	// if it left it
+	Active bool `json:"active"`
+
+	// Message is the notice of the operator for the clients
+	Message string `json:"message"`
+
+	// RetryAfter is the time in seconds after which the clients
+	// are advised to retry their requests
+	RetryAfter uint64 `json:"retryAfter"`
+}
+
+// NodeReconnectedEvent is the event that can be polled by the user
+// when the gateway reconnects to the node after its connection failed.
+// The events that happened on the node between DisconnectedAt and
+// ReconnectedAt may have been missed, so the client is advised to
+// reconcile its state
+type NodeReconnectedEvent struct {
+	// ID to identify the event itself within the sequence of events.
+	ID uint64 `json:"id"`
+
+	// DisconnectedAt is the time in milliseconds since the epoch
+	// at which the connection to the node failed
+	DisconnectedAt int64 `json:"disconnectedAt"`
+
+	// ReconnectedAt is the time in milliseconds since the epoch
+	// at which the connection to the node was recreated
+	ReconnectedAt int64 `json:"reconnectedAt"`
+}
+
+// BatchCallStatus is the outcome of a call of a batch
+type BatchCallStatus struct {
+	// ID of the event of the call
+	ID uint64 `json:"id"`
+
+	// Cause is the error of the call if it failed
+	Cause *rpc.Error `json:"cause,omitempty"`
+}
+
+// BatchCompletedEvent is the event that can be polled by the user
+// once all the calls of a batch complete. It is published after the
+// events of the calls and summarizes their outcome
+type BatchCompletedEvent struct {
+	// ID to identify the event itself within the sequence of events.
+	ID uint64 `json:"id"`
+
+	// Calls is the outcome of each call in the order of the calls
+	Calls []BatchCallStatus `json:"calls"`
+}
+
 // EventID is the implementation of rpc.Event for ExecuteServiceEvent
 func (e ExecuteServiceEvent) EventID() uint64 {
 	return e.ID
@@ -251,3 +614,23 @@ func (e DeployServiceEvent) EventID() uint64 {
 func (e ErrorEvent) EventID() uint64 {
 	return e.ID
 }
+
+// EventID is the implementation of rpc.Event for WarningEvent
+func (e WarningEvent) EventID() uint64 {
+	return e.ID
+}
+
+// EventID is the implementation of rpc.Event for MaintenanceEvent
+func (e MaintenanceEvent) EventID() uint64 {
+	return e.ID
+}
+
+// EventID is the implementation of rpc.Event for NodeReconnectedEvent
+func (e NodeReconnectedEvent) EventID() uint64 {
+	return e.ID
+}
+
+// EventID is the implementation of rpc.Event for BatchCompletedEvent
+func (e BatchCompletedEvent) EventID() uint64 {
+	return e.ID
+}
@@ -5,6 +5,9 @@ import (
 	"encoding/binary"
 	"encoding/hex"
 	stderr "errors"
+	"fmt"
+	"strings"
+	"time"
 
 	auth "github.com/oasislabs/oasis-gateway/auth/core"
 	backend "github.com/oasislabs/oasis-gateway/backend/core"
@@ -13,6 +16,10 @@ import (
 	"github.com/oasislabs/oasis-gateway/rpc"
 )
 
+// OutputPath is the path from which the full output of an
+// execution can be retrieved when it is truncated in its event
+const OutputPath = "/v0/api/service/getOutput"
+
 // Client interface for the underlying operations needed for the API
 // implementation
 type Client interface {
@@ -24,6 +31,11 @@ type Client interface {
 	// the response can be later retrieved with a PollService request
 	ExecuteServiceAsync(context.Context, backend.ExecuteServiceRequest) (uint64, errors.Err)
 
+	// ExecuteServiceBatchAsync triggers the execute service operations of a batch and
+	// returns the IDs with which their responses and the summary of the batch can be
+	// later retrieved with a PollService request
+	ExecuteServiceBatchAsync(context.Context, backend.ExecuteServiceBatchRequest) (backend.ExecuteServiceBatchResponse, errors.Err)
+
 	// PollService allows the client to poll for asynchronous responses
 	PollService(context.Context, backend.PollServiceRequest) (backend.Events, errors.Err)
 
@@ -37,6 +49,17 @@ type Client interface {
 	// so that the client can encrypt and format the input data in a confidential
 	// and privacy preserving manner.
 	GetPublicKey(context.Context, backend.GetPublicKeyRequest) (backend.GetPublicKeyResponse, errors.Err)
+
+	// QueryService executes a read-only query on a service
+	QueryService(context.Context, backend.QueryServiceRequest) (backend.QueryServiceResponse, errors.Err)
+
+	// ListDeployments retrieves the services deployed through the gateway
+	// by an AAD
+	ListDeployments(context.Context, backend.ListDeploymentsRequest) (backend.DeployRecords, errors.Err)
+
+	// GetServiceOutput retrieves the full output of an execution whose
+	// output was truncated in its event
+	GetServiceOutput(context.Context, backend.GetServiceOutputRequest) (backend.GetServiceOutputResponse, errors.Err)
 }
 
 // Services required by the ServiceHandler execution
@@ -44,6 +67,46 @@ type Services struct {
 	Logger   log.Logger
 	Client   Client
 	Verifier auth.Auth
+
+	// WASM defines whether and how services can be
+	// deployed as WASM payloads
+	WASM WASMProps
+
+	// Poll are the limits on the number of events returned
+	// by a poll. If not set the default limits are used
+	Poll PollProps
+}
+
+// Default limits on the number of events returned by a poll
+// used when they are not set in PollProps
+const (
+	DefaultPollCount uint = 10
+	MaxPollCount     uint = 100
+)
+
+// PollProps are the limits on the number of events returned
+// to a client by a single poll
+type PollProps struct {
+	// DefaultCount is the number of events returned by a poll
+	// that does not set a count
+	DefaultCount uint
+
+	// MaxCount is the maximum number of events returned by a
+	// poll. Larger counts requested are capped to MaxCount
+	MaxCount uint
+}
+
+// count returns the number of events retrieved by a poll
+// for the count requested by the client
+func (p PollProps) count(requested uint) uint {
+	if requested == 0 {
+		return p.DefaultCount
+	}
+	if requested > p.MaxCount {
+		return p.MaxCount
+	}
+
+	return requested
 }
 
 // ServiceHandler implements the handlers for service management
@@ -51,6 +114,60 @@ type ServiceHandler struct {
 	logger   log.Logger
 	client   Client
 	verifier auth.Auth
+	wasm     WASMProps
+	poll     PollProps
+}
+
+// MaxIdempotencyKeySize is the maximum size in bytes of the
+// idempotency key a client can provide
+const MaxIdempotencyKeySize = 256
+
+func validateIdempotencyKey(key string) errors.Err {
+	if len(key) > MaxIdempotencyKeySize {
+		return errors.New(errors.ErrIdempotencyKeyTooLong,
+			stderr.New("idempotency key exceeds maximum size"))
+	}
+
+	return nil
+}
+
+// MinGasLimit is the minimum gas limit a client can set for a
+// transaction, which is the intrinsic gas of a transaction
+const MinGasLimit = 21000
+
+// validateGasLimit verifies that the gas limit set by the client, if
+// any, covers the intrinsic gas of a transaction. The node would
+// reject the transaction otherwise
+func validateGasLimit(gasLimit uint64) errors.Err {
+	if gasLimit > 0 && gasLimit < MinGasLimit {
+		return errors.New(errors.ErrGasLimitTooLow,
+			fmt.Errorf("gas limit %d is lower than the minimum %d", gasLimit, MinGasLimit))
+	}
+
+	return nil
+}
+
+// decodeOutputKey decodes the hex encoded X25519 public key to which
+// the client requests the output of an execution to be sealed. It
+// returns nil if the client did not provide a key
+func decodeOutputKey(key string) (*[32]byte, errors.Err) {
+	if len(key) == 0 {
+		return nil, nil
+	}
+
+	p, err := hex.DecodeString(strings.TrimPrefix(key, "0x"))
+	if err != nil {
+		return nil, errors.New(errors.ErrInvalidOutputKey, err)
+	}
+
+	if len(p) != 32 {
+		return nil, errors.New(errors.ErrInvalidOutputKey,
+			stderr.New("output key must have 32 bytes"))
+	}
+
+	var publicKey [32]byte
+	copy(publicKey[:], p)
+	return &publicKey, nil
 }
 
 // DeployService handles the deployment of new services
@@ -74,12 +191,31 @@ func (h ServiceHandler) DeployService(ctx context.Context, v interface{}) (inter
 		return nil, e
 	}
 
+	data, err := decodeDeployData(req, h.wasm)
+	if err != nil {
+		h.logger.Debug(ctx, "received invalid data", log.MapFields{
+			"call_type": "DeployServiceFailure",
+			"session":   session,
+		}, err)
+		return nil, err
+	}
+
+	if err := validateIdempotencyKey(req.IdempotencyKey); err != nil {
+		h.logger.Debug(ctx, "received invalid idempotency key", log.MapFields{
+			"call_type": "DeployServiceFailure",
+			"session":   session,
+		}, err)
+		return nil, err
+	}
+
 	// a context from an http request is cancelled after the response to the request is returned,
 	// so a new context is needed to handle the asynchronous request
 	id, err := h.client.DeployServiceAsync(context.Background(), backend.DeployServiceRequest{
-		AAD:        aad,
-		Data:       req.Data,
-		SessionKey: session,
+		AAD:            aad,
+		Data:           data,
+		Expiry:         req.Expiry,
+		IdempotencyKey: req.IdempotencyKey,
+		SessionKey:     session,
 	})
 	if err != nil {
 		h.logger.Debug(ctx, "failed to start request", log.MapFields{
@@ -149,13 +285,57 @@ func (h ServiceHandler) ExecuteService(ctx context.Context, v interface{}) (inte
 		return nil, e
 	}
 
+	data, err := decodeData(req.Encoding, req.Data)
+	if err != nil {
+		h.logger.Debug(ctx, "received invalid data", log.MapFields{
+			"call_type": "ExecuteServiceFailure",
+			"address":   req.Address,
+			"session":   session,
+		}, err)
+		return nil, err
+	}
+
+	if err := validateIdempotencyKey(req.IdempotencyKey); err != nil {
+		h.logger.Debug(ctx, "received invalid idempotency key", log.MapFields{
+			"call_type": "ExecuteServiceFailure",
+			"address":   req.Address,
+			"session":   session,
+		}, err)
+		return nil, err
+	}
+
+	if err := validateGasLimit(req.GasLimit); err != nil {
+		h.logger.Debug(ctx, "received invalid gas limit", log.MapFields{
+			"call_type": "ExecuteServiceFailure",
+			"address":   req.Address,
+			"session":   session,
+		}, err)
+		return nil, err
+	}
+
+	outputKey, err := decodeOutputKey(req.OutputKey)
+	if err != nil {
+		h.logger.Debug(ctx, "received invalid output key", log.MapFields{
+			"call_type": "ExecuteServiceFailure",
+			"address":   req.Address,
+			"session":   session,
+		}, err)
+		return nil, err
+	}
+
 	// a context from an http request is cancelled after the response to the request is returned,
 	// so a new context is needed to handle the asynchronous request
 	id, err := h.client.ExecuteServiceAsync(context.Background(), backend.ExecuteServiceRequest{
-		AAD:        aad,
-		Address:    req.Address,
-		Data:       req.Data,
-		SessionKey: session,
+		AAD:            aad,
+		Address:        req.Address,
+		Data:           data,
+		Expiry:         req.Expiry,
+		GasLimit:       req.GasLimit,
+		GasPrice:       req.GasPrice,
+		Encoding:       req.Encoding,
+		IdempotencyKey: req.IdempotencyKey,
+		OutputKey:      outputKey,
+		SessionKey:     session,
 	})
 	if err != nil {
 		h.logger.Debug(ctx, "failed to start request", log.MapFields{
@@ -169,6 +349,151 @@ func (h ServiceHandler) ExecuteService(ctx context.Context, v interface{}) (inte
 	return AsyncResponse{ID: id}, nil
 }
 
+// ExecuteServiceBatch handles the execution of a batch of calls to
+// deployed services. The batch is rejected if any of its calls is
+// invalid, so that either all the calls are executed or none is
+func (h ServiceHandler) ExecuteServiceBatch(ctx context.Context, v interface{}) (interface{}, error) {
+	aad := ctx.Value(auth.AAD{}).(string)
+	session := ctx.Value(auth.Session{}).(string)
+
+	req := v.(*ExecuteServiceBatchRequest)
+
+	if len(req.Calls) == 0 || len(req.Calls) > backend.MaxBatchSize {
+		err := errors.New(errors.ErrInvalidBatchSize,
+			fmt.Errorf("batch has %d calls and at most %d are allowed", len(req.Calls), backend.MaxBatchSize))
+		h.logger.Debug(ctx, "received invalid batch", log.MapFields{
+			"call_type": "ExecuteServiceBatchFailure",
+			"session":   session,
+		}, err)
+		return nil, err
+	}
+
+	outputKey, err := decodeOutputKey(req.OutputKey)
+	if err != nil {
+		h.logger.Debug(ctx, "received invalid output key", log.MapFields{
+			"call_type": "ExecuteServiceBatchFailure",
+			"session":   session,
+		}, err)
+		return nil, err
+	}
+
+	calls := make([]backend.ExecuteServiceRequest, 0, len(req.Calls))
+	for i, call := range req.Calls {
+		if len(call.Address) == 0 {
+			e := errors.New(errors.ErrInvalidAddress, fmt.Errorf("call %d has no address", i))
+			h.logger.Debug(ctx, "received empty address", log.MapFields{
+				"call_type": "ExecuteServiceBatchFailure",
+				"session":   session,
+				"call":      i,
+			}, e)
+			return nil, e
+		}
+
+		authReq := h.parseExecuteMessage(&ExecuteServiceRequest{Address: call.Address, Data: call.Data})
+		if err := h.verifier.Verify(ctx, authReq); err != nil {
+			e := errors.New(errors.ErrFailedAADVerification, err)
+			h.logger.Debug(ctx, "failed to verify AAD", log.MapFields{
+				"call_type": "ExecuteServiceBatchFailure",
+				"session":   session,
+				"call":      i,
+				"err":       e,
+			})
+			return nil, e
+		}
+
+		data, err := decodeData(call.Encoding, call.Data)
+		if err != nil {
+			h.logger.Debug(ctx, "received invalid data", log.MapFields{
+				"call_type": "ExecuteServiceBatchFailure",
+				"address":   call.Address,
+				"session":   session,
+				"call":      i,
+			}, err)
+			return nil, err
+		}
+
+		if err := validateGasLimit(call.GasLimit); err != nil {
+			h.logger.Debug(ctx, "received invalid gas limit", log.MapFields{
+				"call_type": "ExecuteServiceBatchFailure",
+				"address":   call.Address,
+				"session":   session,
+				"call":      i,
+			}, err)
+			return nil, err
+		}
+
+		calls = append(calls, backend.ExecuteServiceRequest{
+			Address:   call.Address,
+			Data:      data,
+			Expiry:    call.Expiry,
+			GasLimit:  call.GasLimit,
+			GasPrice:  call.GasPrice,
+			Encoding:  call.Encoding,
+			OutputKey: outputKey,
+		})
+	}
+
+	// a context from an http request is cancelled after the response to the request is returned,
+	// so a new context is needed to handle the asynchronous request
+	res, err := h.client.ExecuteServiceBatchAsync(context.Background(), backend.ExecuteServiceBatchRequest{
+		AAD:        aad,
+		Calls:      calls,
+		SessionKey: session,
+	})
+	if err != nil {
+		h.logger.Debug(ctx, "failed to start request", log.MapFields{
+			"call_type": "ExecuteServiceBatchFailure",
+			"session":   session,
+		}, err)
+		return nil, err
+	}
+
+	return ExecuteServiceBatchResponse{IDs: res.IDs, BatchID: res.BatchID}, nil
+}
+
+// QueryService executes a read-only query on a deployed service
+func (h ServiceHandler) QueryService(ctx context.Context, v interface{}) (interface{}, error) {
+	req := v.(*QueryServiceRequest)
+
+	if len(req.Address) == 0 {
+		err := errors.New(errors.ErrInvalidAddress, stderr.New("address field has not been set"))
+		h.logger.Debug(ctx, "failed to start request", log.MapFields{
+			"call_type": "QueryServiceFailure",
+			"address":   req.Address,
+		}, err)
+		return nil, err
+	}
+
+	data, err := decodeData(req.Encoding, req.Data)
+	if err != nil {
+		h.logger.Debug(ctx, "received invalid data", log.MapFields{
+			"call_type": "QueryServiceFailure",
+			"address":   req.Address,
+		}, err)
+		return nil, err
+	}
+
+	res, err := h.client.QueryService(ctx, backend.QueryServiceRequest{
+		Address: req.Address,
+		Data:    data,
+		Block:   req.Block,
+	})
+	if err != nil {
+		h.logger.Debug(ctx, "request failed", log.MapFields{
+			"call_type": "QueryServiceFailure",
+			"address":   req.Address,
+		}, err)
+		return nil, err
+	}
+
+	return QueryServiceResponse{
+		Address:  res.Address,
+		Output:   encodeOutput(req.Encoding, res.Output),
+		Encoding: req.Encoding,
+		maxAge:   res.MaxAge,
+	}, nil
+}
+
 func (h ServiceHandler) mapEvent(event backend.Event) Event {
 	switch r := event.(type) {
 	case backend.ErrorEvent:
@@ -177,16 +502,53 @@ func (h ServiceHandler) mapEvent(event backend.Event) Event {
 			Cause: r.Cause,
 		}
 	case backend.ExecuteServiceResponse:
-		return ExecuteServiceEvent{
-			ID:      r.ID,
-			Address: r.Address,
-			Output:  r.Output,
+		ev := ExecuteServiceEvent{
+			ID:        r.ID,
+			Address:   r.Address,
+			Output:    encodeOutput(r.Encoding, r.Output),
+			Encoding:  r.Encoding,
+			Encrypted: r.Encrypted,
+		}
+		if r.Truncated {
+			ev.Truncated = true
+			ev.OutputSize = r.OutputSize
+			ev.OutputURL = OutputPath
 		}
+		return ev
 	case backend.DeployServiceResponse:
 		return DeployServiceEvent{
 			ID:      r.ID,
 			Address: r.Address,
 		}
+	case backend.WarningEvent:
+		return WarningEvent{
+			ID:    r.ID,
+			Quota: r.Quota,
+			Used:  r.Used,
+			Limit: r.Limit,
+		}
+	case backend.MaintenanceEvent:
+		return MaintenanceEvent{
+			ID:         r.ID,
+			Active:     r.Active,
+			Message:    r.Message,
+			RetryAfter: r.RetryAfter,
+		}
+	case backend.NodeReconnectedEvent:
+		return NodeReconnectedEvent{
+			ID:             r.ID,
+			DisconnectedAt: r.DisconnectedAt,
+			ReconnectedAt:  r.ReconnectedAt,
+		}
+	case backend.BatchCompletedEvent:
+		calls := make([]BatchCallStatus, 0, len(r.Calls))
+		for _, call := range r.Calls {
+			calls = append(calls, BatchCallStatus{ID: call.ID, Cause: call.Cause})
+		}
+		return BatchCompletedEvent{
+			ID:    r.ID,
+			Calls: calls,
+		}
 	default:
 		panic("received unexpected event type from polling service")
 	}
@@ -196,14 +558,25 @@ func (h ServiceHandler) mapEvent(event backend.Event) Event {
 func (h ServiceHandler) PollService(ctx context.Context, v interface{}) (interface{}, error) {
 	session := ctx.Value(auth.Session{}).(string)
 	req := v.(*PollServiceRequest)
-	if req.Count == 0 {
-		req.Count = 10
+	count := h.poll.count(req.Count)
+
+	types, err := parseEventTypes(req.Types)
+	if err != nil {
+		return nil, err
+	}
+
+	since, until, err := parseTimeRange(req.Since, req.Until)
+	if err != nil {
+		return nil, err
 	}
 
 	res, err := h.client.PollService(ctx, backend.PollServiceRequest{
 		Offset:          req.Offset,
-		Count:           req.Count,
+		Count:           count,
 		DiscardPrevious: req.DiscardPrevious,
+		Types:           types,
+		Since:           since,
+		Until:           until,
 		SessionKey:      session,
 	})
 	if err != nil {
@@ -215,7 +588,12 @@ func (h ServiceHandler) PollService(ctx context.Context, v interface{}) (interfa
 		events = append(events, h.mapEvent(r))
 	}
 
-	return PollServiceResponse{Offset: res.Offset, Events: events}, nil
+	return PollServiceResponse{
+		Offset:   res.Offset,
+		Count:    count,
+		MaxCount: h.poll.MaxCount,
+		Events:   events,
+	}, nil
 }
 
 // GetCode retrieves the source code associated with a service.
@@ -315,6 +693,66 @@ func (h ServiceHandler) GetPublicKey(ctx context.Context, v interface{}) (interf
 	}, nil
 }
 
+// ListDeployments retrieves the services deployed through the gateway
+// by the issuer of the request
+func (h ServiceHandler) ListDeployments(ctx context.Context, v interface{}) (interface{}, error) {
+	aad := ctx.Value(auth.AAD{}).(string)
+	req := v.(*ListDeploymentsRequest)
+	if req.Count == 0 {
+		req.Count = 10
+	}
+
+	res, err := h.client.ListDeployments(ctx, backend.ListDeploymentsRequest{
+		AAD:      aad,
+		Offset:   req.Offset,
+		Count:    req.Count,
+		CodeHash: req.CodeHash,
+	})
+	if err != nil {
+		h.logger.Debug(ctx, "request failed", log.MapFields{
+			"call_type": "ListDeploymentsFailure",
+		}, err)
+		return nil, err
+	}
+
+	deployments := make([]Deployment, 0, len(res.Records))
+	for _, r := range res.Records {
+		deployments = append(deployments, Deployment{
+			ID:        r.ID,
+			Address:   r.Address,
+			CodeHash:  r.CodeHash,
+			Timestamp: r.Timestamp,
+		})
+	}
+
+	return ListDeploymentsResponse{Offset: res.Offset, Deployments: deployments}, nil
+}
+
+// GetServiceOutput retrieves the full output of an execution
+// whose output was truncated in its event
+func (h ServiceHandler) GetServiceOutput(ctx context.Context, v interface{}) (interface{}, error) {
+	session := ctx.Value(auth.Session{}).(string)
+	req := v.(*GetServiceOutputRequest)
+
+	res, err := h.client.GetServiceOutput(ctx, backend.GetServiceOutputRequest{
+		ID:         req.ID,
+		SessionKey: session,
+	})
+	if err != nil {
+		h.logger.Debug(ctx, "request failed", log.MapFields{
+			"call_type": "GetServiceOutputFailure",
+			"id":        req.ID,
+		}, err)
+		return nil, err
+	}
+
+	return GetServiceOutputResponse{
+		ID:       res.ID,
+		Output:   encodeOutput(res.Encoding, res.Output),
+		Encoding: res.Encoding,
+	}, nil
+}
+
 func NewServiceHandler(services Services) ServiceHandler {
 	if services.Client == nil {
 		panic("Request must be provided as a service")
@@ -323,13 +761,45 @@ func NewServiceHandler(services Services) ServiceHandler {
 		panic("Logger must be provided as a service")
 	}
 
+	poll := services.Poll
+	if poll.MaxCount == 0 {
+		poll.MaxCount = MaxPollCount
+	}
+	if poll.DefaultCount == 0 {
+		poll.DefaultCount = DefaultPollCount
+	}
+	if poll.DefaultCount > poll.MaxCount {
+		poll.DefaultCount = poll.MaxCount
+	}
+
 	return ServiceHandler{
 		logger:   services.Logger.ForClass("service", "handler"),
 		client:   services.Client,
 		verifier: services.Verifier,
+		wasm:     services.WASM,
+		poll:     poll,
 	}
 }
 
+// BindPollHandler binds only the handler to poll for the results
+// of asynchronous requests to the provided HandlerBinder
+func BindPollHandler(services Services, binder rpc.HandlerBinder) {
+	handler := NewServiceHandler(services)
+
+	binder.Bind("POST", "/v0/api/service/poll", rpc.HandlerFunc(handler.PollService),
+		rpc.EntityFactoryFunc(func() interface{} { return &PollServiceRequest{} }))
+	bindOutputHandler(handler, binder)
+}
+
+// bindOutputHandler binds the handler to retrieve truncated outputs,
+// which is served wherever the events that reference them are polled
+func bindOutputHandler(handler ServiceHandler, binder rpc.HandlerBinder) {
+	binder.Bind("GET", OutputPath, rpc.HandlerFunc(handler.GetServiceOutput),
+		rpc.EntityFactoryFunc(func() interface{} { return &GetServiceOutputRequest{} }))
+	binder.Bind("POST", OutputPath, rpc.HandlerFunc(handler.GetServiceOutput),
+		rpc.EntityFactoryFunc(func() interface{} { return &GetServiceOutputRequest{} }))
+}
+
 // BindHandler binds the service handler to the provided
 // HandlerBinder
 func BindHandler(services Services, binder rpc.HandlerBinder) {
@@ -339,6 +809,8 @@ func BindHandler(services Services, binder rpc.HandlerBinder) {
 		rpc.EntityFactoryFunc(func() interface{} { return &DeployServiceRequest{} }))
 	binder.Bind("POST", "/v0/api/service/execute", rpc.HandlerFunc(handler.ExecuteService),
 		rpc.EntityFactoryFunc(func() interface{} { return &ExecuteServiceRequest{} }))
+	binder.Bind("POST", "/v0/api/service/executeBatch", rpc.HandlerFunc(handler.ExecuteServiceBatch),
+		rpc.EntityFactoryFunc(func() interface{} { return &ExecuteServiceBatchRequest{} }))
 	binder.Bind("POST", "/v0/api/service/poll", rpc.HandlerFunc(handler.PollService),
 		rpc.EntityFactoryFunc(func() interface{} { return &PollServiceRequest{} }))
 	binder.Bind("GET", "/v0/api/service/getCode", rpc.HandlerFunc(handler.GetCode),
@@ -353,4 +825,83 @@ func BindHandler(services Services, binder rpc.HandlerBinder) {
 		rpc.EntityFactoryFunc(func() interface{} { return &GetExpiryRequest{} }))
 	binder.Bind("POST", "/v0/api/service/getPublicKey", rpc.HandlerFunc(handler.GetPublicKey),
 		rpc.EntityFactoryFunc(func() interface{} { return &GetPublicKeyRequest{} }))
+	binder.Bind("POST", "/v0/api/service/query", rpc.HandlerFunc(handler.QueryService),
+		rpc.EntityFactoryFunc(func() interface{} { return &QueryServiceRequest{} }))
+	binder.Bind("POST", "/v0/api/service/deployments", rpc.HandlerFunc(handler.ListDeployments),
+		rpc.EntityFactoryFunc(func() interface{} { return &ListDeploymentsRequest{} }))
+	bindOutputHandler(handler, binder)
+}
+
+// eventTypes maps the event types a client can filter
+// a poll by to the types of the backend events
+var eventTypes = map[string]backend.EventType{
+	"execute":     backend.ExecuteServiceEventType,
+	"deploy":      backend.DeployServiceEventType,
+	"error":       backend.ErrorEventType,
+	"warning":     backend.WarningEventType,
+	"maintenance": backend.MaintenanceEventType,
+	"reconnect":   backend.NodeReconnectedEventType,
+	"batch":       backend.BatchCompletedEventType,
+}
+
+// parseEventTypes maps the event types requested by the
+// client to the types of the backend events
+func parseEventTypes(types []string) ([]backend.EventType, errors.Err) {
+	if len(types) == 0 {
+		return nil, nil
+	}
+
+	parsed := make([]backend.EventType, 0, len(types))
+	for _, t := range types {
+		eventType, ok := eventTypes[t]
+		if !ok {
+			return nil, errors.New(errors.ErrInvalidEventType, fmt.Errorf("unknown event type %q", t))
+		}
+
+		parsed = append(parsed, eventType)
+	}
+
+	return parsed, nil
+}
+
+// timeLayouts are the layouts accepted for the time range of a
+// poll, RFC 3339 and RFC 3339 without seconds
+var timeLayouts = []string{time.RFC3339, "2006-01-02T15:04Z07:00"}
+
+// parseTime parses a timestamp of the time range of a poll
+func parseTime(s string) (time.Time, error) {
+	var err error
+	for _, layout := range timeLayouts {
+		var t time.Time
+		if t, err = time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, err
+}
+
+// parseTimeRange parses the time range of a poll. The
+// timestamps that are not set are returned as zero
+func parseTimeRange(since, until string) (time.Time, time.Time, errors.Err) {
+	var from, to time.Time
+	var err error
+	if len(since) > 0 {
+		if from, err = parseTime(since); err != nil {
+			return time.Time{}, time.Time{}, errors.New(errors.ErrInvalidTimeRange, err)
+		}
+	}
+
+	if len(until) > 0 {
+		if to, err = parseTime(until); err != nil {
+			return time.Time{}, time.Time{}, errors.New(errors.ErrInvalidTimeRange, err)
+		}
+	}
+
+	if !to.IsZero() && !to.After(from) {
+		return time.Time{}, time.Time{}, errors.New(errors.ErrInvalidTimeRange,
+			stderr.New("until must be after since"))
+	}
+
+	return from, to, nil
 }
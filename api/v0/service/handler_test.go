@@ -2,9 +2,11 @@ package service
 
 import (
 	"context"
+	"strings"
 	stderr "errors"
 	"io/ioutil"
 	"testing"
+	"time"
 
 	auth "github.com/oasislabs/oasis-gateway/auth/core"
 	insecureauth "github.com/oasislabs/oasis-gateway/auth/insecure"
@@ -60,6 +62,18 @@ func (c *MockClient) ExecuteServiceAsync(
 	return uint64(args.Int(0)), nil
 }
 
+func (c *MockClient) ExecuteServiceBatchAsync(
+	ctx context.Context,
+	req backend.ExecuteServiceBatchRequest,
+) (backend.ExecuteServiceBatchResponse, errors.Err) {
+	args := c.Mock.Called(ctx, req)
+	if args.Get(1) != nil {
+		return backend.ExecuteServiceBatchResponse{}, args.Get(1).(errors.Err)
+	}
+
+	return args.Get(0).(backend.ExecuteServiceBatchResponse), nil
+}
+
 func (c *MockClient) PollService(
 	ctx context.Context,
 	req backend.PollServiceRequest,
@@ -108,6 +122,42 @@ func (c *MockClient) GetPublicKey(
 	return args.Get(0).(backend.GetPublicKeyResponse), nil
 }
 
+func (c *MockClient) QueryService(
+	ctx context.Context,
+	req backend.QueryServiceRequest,
+) (backend.QueryServiceResponse, errors.Err) {
+	args := c.Mock.Called(ctx, req)
+	if args.Get(1) != nil {
+		return backend.QueryServiceResponse{}, args.Get(1).(errors.Err)
+	}
+
+	return args.Get(0).(backend.QueryServiceResponse), nil
+}
+
+func (c *MockClient) ListDeployments(
+	ctx context.Context,
+	req backend.ListDeploymentsRequest,
+) (backend.DeployRecords, errors.Err) {
+	args := c.Mock.Called(ctx, req)
+	if args.Get(1) != nil {
+		return backend.DeployRecords{}, args.Get(1).(errors.Err)
+	}
+
+	return args.Get(0).(backend.DeployRecords), nil
+}
+
+func (c *MockClient) GetServiceOutput(
+	ctx context.Context,
+	req backend.GetServiceOutputRequest,
+) (backend.GetServiceOutputResponse, errors.Err) {
+	args := c.Mock.Called(ctx, req)
+	if args.Get(1) != nil {
+		return backend.GetServiceOutputResponse{}, args.Get(1).(errors.Err)
+	}
+
+	return args.Get(0).(backend.GetServiceOutputResponse), nil
+}
+
 func createServiceHandler() ServiceHandler {
 	return NewServiceHandler(Services{
 		Logger:   Logger,
@@ -177,6 +227,33 @@ func TestDeployServiceOK(t *testing.T) {
 	assert.Equal(t, uint64(0), res.(AsyncResponse).ID)
 }
 
+func TestDeployServiceWASMOK(t *testing.T) {
+	ctx := context.WithValue(Context, auth.AAD{}, "aad")
+	ctx = context.WithValue(ctx, auth.Session{}, "sessionKey")
+
+	handler := NewServiceHandler(Services{
+		Logger:   Logger,
+		Client:   &MockClient{},
+		Verifier: insecureauth.InsecureAuth{},
+		WASM:     WASMProps{Enabled: true, MaxSize: 1024, Validate: true},
+	})
+
+	handler.client.(*MockClient).On("DeployServiceAsync",
+		mock.Anything,
+		backend.DeployServiceRequest{
+			AAD:        "aad",
+			Data:       "0x0061736d01000000",
+			SessionKey: "sessionKey",
+		}).Return(0, nil)
+
+	res, err := handler.DeployService(ctx, &DeployServiceRequest{
+		Data:        "0x0061736d01000000",
+		ContentType: WASMContentType,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0), res.(AsyncResponse).ID)
+}
+
 func TestExecuteServiceEmptyData(t *testing.T) {
 	ctx := context.WithValue(Context, auth.AAD{}, "aad")
 	ctx = context.WithValue(ctx, auth.Session{}, "sessionKey")
@@ -397,6 +474,81 @@ func TestPollServiceErrorOK(t *testing.T) {
 	}, evs.Events[0])
 }
 
+func TestPollServiceWarningOK(t *testing.T) {
+	ctx := context.WithValue(Context, auth.AAD{}, "aad")
+	ctx = context.WithValue(ctx, auth.Session{}, "sessionKey")
+
+	handler := createServiceHandler()
+
+	handler.client.(*MockClient).On("PollService",
+		mock.Anything,
+		backend.PollServiceRequest{
+			Offset:          0,
+			Count:           10,
+			DiscardPrevious: false,
+			SessionKey:      "sessionKey",
+		}).Return(backend.Events{
+		Offset: 0,
+		Events: []backend.Event{backend.WarningEvent{
+			ID:    1,
+			Quota: backend.QueueQuota,
+			Used:  8,
+			Limit: 10,
+		}}}, nil)
+
+	res, err := handler.PollService(ctx, &PollServiceRequest{
+		Offset:          0,
+		Count:           10,
+		DiscardPrevious: false,
+	})
+	assert.Nil(t, err)
+
+	evs := res.(PollServiceResponse)
+	assert.Equal(t, 1, len(evs.Events))
+	assert.Equal(t, WarningEvent{
+		ID:    1,
+		Quota: "queue",
+		Used:  8,
+		Limit: 10,
+	}, evs.Events[0])
+}
+
+func TestPollServiceBatchCompletedOK(t *testing.T) {
+	ctx := context.WithValue(Context, auth.AAD{}, "aad")
+	ctx = context.WithValue(ctx, auth.Session{}, "sessionKey")
+
+	handler := createServiceHandler()
+	cause := rpc.Error{ErrorCode: 1000, Description: "Internal Error. Please check the status of the service."}
+
+	handler.client.(*MockClient).On("PollService",
+		mock.Anything,
+		backend.PollServiceRequest{
+			Offset:          0,
+			Count:           10,
+			DiscardPrevious: false,
+			SessionKey:      "sessionKey",
+		}).Return(backend.Events{
+		Offset: 0,
+		Events: []backend.Event{backend.BatchCompletedEvent{
+			ID:    2,
+			Calls: []backend.BatchCallStatus{{ID: 0}, {ID: 1, Cause: &cause}},
+		}}}, nil)
+
+	res, err := handler.PollService(ctx, &PollServiceRequest{
+		Offset:          0,
+		Count:           10,
+		DiscardPrevious: false,
+	})
+	assert.Nil(t, err)
+
+	evs := res.(PollServiceResponse)
+	assert.Equal(t, 1, len(evs.Events))
+	assert.Equal(t, BatchCompletedEvent{
+		ID:    2,
+		Calls: []BatchCallStatus{{ID: 0}, {ID: 1, Cause: &cause}},
+	}, evs.Events[0])
+}
+
 func TestGetCodeEmptyAddress(t *testing.T) {
 	ctx := context.WithValue(Context, auth.AAD{}, "aad")
 	ctx = context.WithValue(ctx, auth.Session{}, "sessionKey")
@@ -676,3 +828,483 @@ func TestBindHandlerOK(t *testing.T) {
 	assert.True(t, router.HasHandler("/v0/api/service/getExpiry", "GET"))
 	assert.True(t, router.HasHandler("/v0/api/service/getPublicKey", "GET"))
 }
+
+func TestListDeploymentsOK(t *testing.T) {
+	ctx := context.WithValue(Context, auth.AAD{}, "aad")
+	ctx = context.WithValue(ctx, auth.Session{}, "sessionKey")
+
+	handler := createServiceHandler()
+
+	handler.client.(*MockClient).On("ListDeployments",
+		mock.Anything,
+		backend.ListDeploymentsRequest{
+			AAD:    "aad",
+			Offset: 0,
+			Count:  10,
+		}).Return(backend.DeployRecords{
+		Offset: 0,
+		Records: []backend.DeployRecord{{
+			ID:        0,
+			AAD:       "aad",
+			CodeHash:  "0x01",
+			Address:   "0x02",
+			Timestamp: 1,
+		}},
+	}, nil)
+
+	v, err := handler.ListDeployments(ctx, &ListDeploymentsRequest{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, ListDeploymentsResponse{
+		Offset: 0,
+		Deployments: []Deployment{{
+			ID:        0,
+			Address:   "0x02",
+			CodeHash:  "0x01",
+			Timestamp: 1,
+		}},
+	}, v)
+}
+
+func TestListDeploymentsErr(t *testing.T) {
+	ctx := context.WithValue(Context, auth.AAD{}, "aad")
+	ctx = context.WithValue(ctx, auth.Session{}, "sessionKey")
+
+	handler := createServiceHandler()
+
+	handler.client.(*MockClient).On("ListDeployments",
+		mock.Anything, mock.Anything).
+		Return(nil, errors.New(errors.ErrQueueRetrieve, stderr.New("made up error")))
+
+	_, err := handler.ListDeployments(ctx, &ListDeploymentsRequest{})
+
+	assert.Error(t, err)
+	assert.Equal(t, errors.ErrQueueRetrieve, err.(errors.Err).ErrorCode())
+}
+
+func TestQueryServiceOK(t *testing.T) {
+	handler := createServiceHandler()
+
+	handler.client.(*MockClient).On("QueryService",
+		mock.Anything,
+		backend.QueryServiceRequest{
+			Address: "address",
+			Data:    "0x01",
+			Block:   10,
+		}).Return(backend.QueryServiceResponse{
+		Address: "address",
+		Output:  "0x02",
+		MaxAge:  5 * time.Second,
+	}, nil)
+
+	v, err := handler.QueryService(Context, &QueryServiceRequest{
+		Address: "address",
+		Data:    "0x01",
+		Block:   10,
+	})
+
+	assert.Nil(t, err)
+	res := v.(QueryServiceResponse)
+	assert.Equal(t, "address", res.Address)
+	assert.Equal(t, "0x02", res.Output)
+	assert.Equal(t, "max-age=5", res.HttpHeaders().Get("Cache-Control"))
+}
+
+func TestQueryServiceNoCache(t *testing.T) {
+	handler := createServiceHandler()
+
+	handler.client.(*MockClient).On("QueryService", mock.Anything, mock.Anything).
+		Return(backend.QueryServiceResponse{Address: "address", Output: "0x02"}, nil)
+
+	v, err := handler.QueryService(Context, &QueryServiceRequest{Address: "address", Data: "0x"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "no-cache", v.(QueryServiceResponse).HttpHeaders().Get("Cache-Control"))
+}
+
+func TestQueryServiceErrNoAddress(t *testing.T) {
+	handler := createServiceHandler()
+
+	_, err := handler.QueryService(Context, &QueryServiceRequest{})
+
+	assert.Error(t, err)
+	assert.Equal(t, errors.ErrInvalidAddress, err.(errors.Err).ErrorCode())
+}
+
+func TestExecuteServiceInvalidData(t *testing.T) {
+	ctx := context.WithValue(Context, auth.AAD{}, "aad")
+	ctx = context.WithValue(ctx, auth.Session{}, "sessionKey")
+
+	handler := createServiceHandler()
+
+	_, err := handler.ExecuteService(ctx, &ExecuteServiceRequest{
+		Data:    "0x0",
+		Address: "0x00",
+	})
+
+	assert.Error(t, err)
+	assert.Equal(t, errors.ErrDataOddLength, err.(errors.Err).ErrorCode())
+	handler.client.(*MockClient).AssertNotCalled(t, "ExecuteServiceAsync", mock.Anything, mock.Anything)
+}
+
+func TestDeployServiceInvalidData(t *testing.T) {
+	ctx := context.WithValue(Context, auth.AAD{}, "aad")
+	ctx = context.WithValue(ctx, auth.Session{}, "sessionKey")
+
+	handler := createServiceHandler()
+
+	_, err := handler.DeployService(ctx, &DeployServiceRequest{Data: "6060"})
+
+	assert.Error(t, err)
+	assert.Equal(t, errors.ErrDataMissingHexPrefix, err.(errors.Err).ErrorCode())
+	handler.client.(*MockClient).AssertNotCalled(t, "DeployServiceAsync", mock.Anything, mock.Anything)
+}
+
+func TestExecuteServiceBase64OK(t *testing.T) {
+	ctx := context.WithValue(Context, auth.AAD{}, "aad")
+	ctx = context.WithValue(ctx, auth.Session{}, "sessionKey")
+
+	handler := createServiceHandler()
+
+	handler.client.(*MockClient).On("ExecuteServiceAsync",
+		mock.Anything,
+		backend.ExecuteServiceRequest{
+			AAD:        "aad",
+			Data:       "0x0102",
+			Address:    "0x00",
+			Encoding:   Base64Encoding,
+			SessionKey: "sessionKey",
+		}).Return(1, nil)
+
+	res, err := handler.ExecuteService(ctx, &ExecuteServiceRequest{
+		Data:     "AQI=",
+		Address:  "0x00",
+		Encoding: Base64Encoding,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1), res.(AsyncResponse).ID)
+}
+
+func TestPollServiceExecuteBase64OK(t *testing.T) {
+	ctx := context.WithValue(Context, auth.AAD{}, "aad")
+	ctx = context.WithValue(ctx, auth.Session{}, "sessionKey")
+
+	handler := createServiceHandler()
+
+	handler.client.(*MockClient).On("PollService", mock.Anything, mock.Anything).
+		Return(backend.Events{
+			Offset: 0,
+			Events: []backend.Event{backend.ExecuteServiceResponse{
+				ID:       0,
+				Address:  "0x00",
+				Output:   "0x0102",
+				Encoding: Base64Encoding,
+			}}}, nil)
+
+	res, err := handler.PollService(ctx, &PollServiceRequest{})
+	assert.Nil(t, err)
+
+	evs := res.(PollServiceResponse)
+	assert.Equal(t, ExecuteServiceEvent{
+		ID:       0,
+		Address:  "0x00",
+		Output:   "AQI=",
+		Encoding: Base64Encoding,
+	}, evs.Events[0])
+}
+
+func TestPollServiceTypes(t *testing.T) {
+	ctx := context.WithValue(Context, auth.AAD{}, "aad")
+	ctx = context.WithValue(ctx, auth.Session{}, "sessionKey")
+
+	handler := createServiceHandler()
+
+	handler.client.(*MockClient).On("PollService",
+		mock.Anything,
+		backend.PollServiceRequest{
+			Offset:     0,
+			Count:      10,
+			Types:      []backend.EventType{backend.ErrorEventType, backend.ExecuteServiceEventType},
+			SessionKey: "sessionKey",
+		}).Return(backend.Events{Offset: 0}, nil)
+
+	_, err := handler.PollService(ctx, &PollServiceRequest{Types: []string{"error", "execute"}})
+	assert.Nil(t, err)
+}
+
+func TestPollServiceInvalidType(t *testing.T) {
+	ctx := context.WithValue(Context, auth.AAD{}, "aad")
+	ctx = context.WithValue(ctx, auth.Session{}, "sessionKey")
+
+	handler := createServiceHandler()
+
+	_, err := handler.PollService(ctx, &PollServiceRequest{Types: []string{"data"}})
+	assert.Error(t, err)
+	assert.Equal(t, errors.ErrInvalidEventType, err.(errors.Err).ErrorCode())
+}
+
+func TestPollServiceSince(t *testing.T) {
+	ctx := context.WithValue(Context, auth.AAD{}, "aad")
+	ctx = context.WithValue(ctx, auth.Session{}, "sessionKey")
+
+	handler := createServiceHandler()
+
+	handler.client.(*MockClient).On("PollService",
+		mock.Anything,
+		backend.PollServiceRequest{
+			Offset:     0,
+			Count:      10,
+			Since:      time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+			Until:      time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC),
+			SessionKey: "sessionKey",
+		}).Return(backend.Events{Offset: 0}, nil)
+
+	_, err := handler.PollService(ctx, &PollServiceRequest{
+		Since: "2024-05-01T00:00Z",
+		Until: "2024-05-02T00:00:00Z",
+	})
+	assert.Nil(t, err)
+}
+
+func TestPollServiceInvalidTimeRange(t *testing.T) {
+	ctx := context.WithValue(Context, auth.AAD{}, "aad")
+	ctx = context.WithValue(ctx, auth.Session{}, "sessionKey")
+
+	handler := createServiceHandler()
+
+	for _, req := range []PollServiceRequest{
+		{Since: "2024-05-01"},
+		{Until: "yesterday"},
+		{Since: "2024-05-02T00:00Z", Until: "2024-05-01T00:00Z"},
+	} {
+		_, err := handler.PollService(ctx, &req)
+		assert.Error(t, err)
+		assert.Equal(t, errors.ErrInvalidTimeRange, err.(errors.Err).ErrorCode())
+	}
+}
+
+func TestPollServiceExecuteTruncatedOK(t *testing.T) {
+	ctx := context.WithValue(Context, auth.AAD{}, "aad")
+	ctx = context.WithValue(ctx, auth.Session{}, "sessionKey")
+
+	handler := createServiceHandler()
+
+	handler.client.(*MockClient).On("PollService", mock.Anything, mock.Anything).
+		Return(backend.Events{
+			Offset: 0,
+			Events: []backend.Event{backend.ExecuteServiceResponse{
+				ID:         0,
+				Address:    "0x00",
+				Output:     "0x01",
+				Truncated:  true,
+				OutputSize: 6,
+			}}}, nil)
+
+	res, err := handler.PollService(ctx, &PollServiceRequest{})
+	assert.Nil(t, err)
+
+	evs := res.(PollServiceResponse)
+	assert.Equal(t, ExecuteServiceEvent{
+		ID:         0,
+		Address:    "0x00",
+		Output:     "0x01",
+		Truncated:  true,
+		OutputSize: 6,
+		OutputURL:  OutputPath,
+	}, evs.Events[0])
+}
+
+func TestGetServiceOutputOK(t *testing.T) {
+	ctx := context.WithValue(Context, auth.AAD{}, "aad")
+	ctx = context.WithValue(ctx, auth.Session{}, "sessionKey")
+
+	handler := createServiceHandler()
+
+	handler.client.(*MockClient).On("GetServiceOutput",
+		mock.Anything,
+		backend.GetServiceOutputRequest{
+			ID:         1,
+			SessionKey: "sessionKey",
+		}).Return(backend.GetServiceOutputResponse{
+		ID:       1,
+		Output:   "0x0102",
+		Encoding: Base64Encoding,
+	}, nil)
+
+	res, err := handler.GetServiceOutput(ctx, &GetServiceOutputRequest{ID: 1})
+
+	assert.Nil(t, err)
+	assert.Equal(t, GetServiceOutputResponse{
+		ID:       1,
+		Output:   "AQI=",
+		Encoding: Base64Encoding,
+	}, res)
+}
+
+func TestGetServiceOutputErr(t *testing.T) {
+	ctx := context.WithValue(Context, auth.AAD{}, "aad")
+	ctx = context.WithValue(ctx, auth.Session{}, "sessionKey")
+
+	handler := createServiceHandler()
+
+	handler.client.(*MockClient).On("GetServiceOutput", mock.Anything, mock.Anything).
+		Return(nil, errors.New(errors.ErrOutputNotFound, nil))
+
+	_, err := handler.GetServiceOutput(ctx, &GetServiceOutputRequest{ID: 1})
+
+	assert.Error(t, err)
+	assert.Equal(t, errors.ErrOutputNotFound, err.(errors.Err).ErrorCode())
+}
+
+func TestExecuteServiceIdempotencyKeyTooLong(t *testing.T) {
+	ctx := context.WithValue(Context, auth.AAD{}, "aad")
+	ctx = context.WithValue(ctx, auth.Session{}, "sessionKey")
+
+	handler := createServiceHandler()
+
+	_, err := handler.ExecuteService(ctx, &ExecuteServiceRequest{
+		Data:           "0x00",
+		Address:        "0x00",
+		IdempotencyKey: strings.Repeat("k", MaxIdempotencyKeySize+1),
+	})
+
+	assert.Error(t, err)
+	assert.Equal(t, errors.ErrIdempotencyKeyTooLong, err.(errors.Err).ErrorCode())
+	handler.client.(*MockClient).AssertNotCalled(t, "ExecuteServiceAsync", mock.Anything, mock.Anything)
+}
+
+func TestExecuteServiceGasLimitTooLow(t *testing.T) {
+	ctx := context.WithValue(Context, auth.AAD{}, "aad")
+	ctx = context.WithValue(ctx, auth.Session{}, "sessionKey")
+
+	handler := createServiceHandler()
+
+	_, err := handler.ExecuteService(ctx, &ExecuteServiceRequest{
+		Data:     "0x00",
+		Address:  "0x00",
+		GasLimit: MinGasLimit - 1,
+	})
+
+	assert.Error(t, err)
+	assert.Equal(t, errors.ErrGasLimitTooLow, err.(errors.Err).ErrorCode())
+	handler.client.(*MockClient).AssertNotCalled(t, "ExecuteServiceAsync", mock.Anything, mock.Anything)
+}
+
+func TestExecuteServiceBatchGasLimitTooLow(t *testing.T) {
+	ctx := context.WithValue(Context, auth.AAD{}, "aad")
+	ctx = context.WithValue(ctx, auth.Session{}, "sessionKey")
+
+	handler := createServiceHandler()
+
+	_, err := handler.ExecuteServiceBatch(ctx, &ExecuteServiceBatchRequest{
+		Calls: []ExecuteServiceCall{
+			{Data: "0x00", Address: "0x00"},
+			{Data: "0x00", Address: "0x00", GasLimit: 1},
+		},
+	})
+
+	assert.Error(t, err)
+	assert.Equal(t, errors.ErrGasLimitTooLow, err.(errors.Err).ErrorCode())
+	handler.client.(*MockClient).AssertNotCalled(t, "ExecuteServiceBatchAsync", mock.Anything, mock.Anything)
+}
+
+func TestExecuteServiceInvalidOutputKey(t *testing.T) {
+	ctx := context.WithValue(Context, auth.AAD{}, "aad")
+	ctx = context.WithValue(ctx, auth.Session{}, "sessionKey")
+
+	handler := createServiceHandler()
+
+	_, err := handler.ExecuteService(ctx, &ExecuteServiceRequest{
+		Data:      "0x00",
+		Address:   "0x00",
+		OutputKey: "0x0102",
+	})
+	assert.Error(t, err)
+	baserr := err.(errors.Err)
+
+	assert.Equal(t, "output key must have 32 bytes", baserr.Cause().Error())
+	assert.Equal(t, errors.ErrInvalidOutputKey, baserr.ErrorCode())
+}
+
+func TestExecuteServiceOutputKey(t *testing.T) {
+	ctx := context.WithValue(Context, auth.AAD{}, "aad")
+	ctx = context.WithValue(ctx, auth.Session{}, "sessionKey")
+
+	handler := createServiceHandler()
+
+	var outputKey [32]byte
+	outputKey[0] = 1
+	handler.client.(*MockClient).On("ExecuteServiceAsync",
+		mock.Anything,
+		backend.ExecuteServiceRequest{
+			AAD:        "aad",
+			Data:       "0x00",
+			Address:    "0x00",
+			OutputKey:  &outputKey,
+			SessionKey: "sessionKey",
+		}).Return(1, nil)
+
+	res, err := handler.ExecuteService(ctx, &ExecuteServiceRequest{
+		Data:      "0x00",
+		Address:   "0x00",
+		OutputKey: "0x01" + strings.Repeat("00", 31),
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1), res.(AsyncResponse).ID)
+}
+
+func TestExecuteServiceBatchOK(t *testing.T) {
+	ctx := context.WithValue(Context, auth.AAD{}, "aad")
+	ctx = context.WithValue(ctx, auth.Session{}, "sessionKey")
+
+	handler := createServiceHandler()
+
+	handler.client.(*MockClient).On("ExecuteServiceBatchAsync",
+		mock.Anything,
+		backend.ExecuteServiceBatchRequest{
+			AAD: "aad",
+			Calls: []backend.ExecuteServiceRequest{
+				{Data: "0x00", Address: "0x00"},
+				{Data: "0x01", Address: "0x01", GasLimit: 100000, GasPrice: 2000000000},
+			},
+			SessionKey: "sessionKey",
+		}).Return(backend.ExecuteServiceBatchResponse{IDs: []uint64{0, 1}, BatchID: 2}, nil)
+
+	res, err := handler.ExecuteServiceBatch(ctx, &ExecuteServiceBatchRequest{
+		Calls: []ExecuteServiceCall{
+			{Data: "0x00", Address: "0x00"},
+			{Data: "0x01", Address: "0x01", GasLimit: 100000, GasPrice: 2000000000},
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, ExecuteServiceBatchResponse{IDs: []uint64{0, 1}, BatchID: 2}, res)
+}
+
+func TestExecuteServiceBatchEmpty(t *testing.T) {
+	ctx := context.WithValue(Context, auth.AAD{}, "aad")
+	ctx = context.WithValue(ctx, auth.Session{}, "sessionKey")
+
+	handler := createServiceHandler()
+
+	_, err := handler.ExecuteServiceBatch(ctx, &ExecuteServiceBatchRequest{})
+	assert.Error(t, err)
+	assert.Equal(t, errors.ErrInvalidBatchSize, err.(errors.Err).ErrorCode())
+	handler.client.(*MockClient).AssertNotCalled(t, "ExecuteServiceBatchAsync", mock.Anything, mock.Anything)
+}
+
+func TestExecuteServiceBatchEmptyAddress(t *testing.T) {
+	ctx := context.WithValue(Context, auth.AAD{}, "aad")
+	ctx = context.WithValue(ctx, auth.Session{}, "sessionKey")
+
+	handler := createServiceHandler()
+
+	_, err := handler.ExecuteServiceBatch(ctx, &ExecuteServiceBatchRequest{
+		Calls: []ExecuteServiceCall{
+			{Data: "0x00", Address: "0x00"},
+			{Data: "0x00"},
+		},
+	})
+	assert.Error(t, err)
+	assert.Equal(t, errors.ErrInvalidAddress, err.(errors.Err).ErrorCode())
+	handler.client.(*MockClient).AssertNotCalled(t, "ExecuteServiceBatchAsync", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/oasislabs/oasis-gateway/errors"
+)
+
+// MaxDataSize is the maximum size in bytes of the decoded data
+// that a client can submit in a single request
+const MaxDataSize = 512 * 1024
+
+// validateData verifies that the data provided by the client is a 0x
+// prefixed hex string of even length whose decoded size does not
+// exceed MaxDataSize
+func validateData(data string) errors.Err {
+	return validateDataLimit(data, MaxDataSize)
+}
+
+// validateDataLimit is like validateData with the
+// limit for the decoded size of the data
+func validateDataLimit(data string, limit int) errors.Err {
+	if !strings.HasPrefix(data, "0x") && !strings.HasPrefix(data, "0X") {
+		return errors.New(errors.ErrDataMissingHexPrefix, nil)
+	}
+
+	digits := data[2:]
+	if len(digits)%2 != 0 {
+		return errors.New(errors.ErrDataOddLength,
+			fmt.Errorf("data has %d hex digits", len(digits)))
+	}
+
+	if len(digits)/2 > limit {
+		return errors.New(errors.ErrDataTooLarge,
+			fmt.Errorf("data has %d bytes which exceeds the limit of %d bytes",
+				len(digits)/2, limit))
+	}
+
+	for i := 0; i < len(digits); i++ {
+		if !isHexDigit(digits[i]) {
+			return errors.New(errors.ErrDataNotHex,
+				fmt.Errorf("invalid character %q at position %d", digits[i], i+2))
+		}
+	}
+
+	return nil
+}
+
+func isHexDigit(c byte) bool {
+	return ('0' <= c && c <= '9') || ('a' <= c && c <= 'f') || ('A' <= c && c <= 'F')
+}
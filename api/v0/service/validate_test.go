@@ -0,0 +1,37 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/oasislabs/oasis-gateway/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateDataOK(t *testing.T) {
+	assert.Nil(t, validateData("0x"))
+	assert.Nil(t, validateData("0x00"))
+	assert.Nil(t, validateData("0xdeadBEEF"))
+}
+
+func TestValidateDataMissingPrefix(t *testing.T) {
+	err := validateData("0000")
+	assert.Equal(t, errors.ErrDataMissingHexPrefix, err.ErrorCode())
+}
+
+func TestValidateDataOddLength(t *testing.T) {
+	err := validateData("0x000")
+	assert.Equal(t, errors.ErrDataOddLength, err.ErrorCode())
+	assert.Equal(t, "data has 3 hex digits", err.Cause().Error())
+}
+
+func TestValidateDataNotHex(t *testing.T) {
+	err := validateData("0x00zz")
+	assert.Equal(t, errors.ErrDataNotHex, err.ErrorCode())
+	assert.Equal(t, "invalid character 'z' at position 4", err.Cause().Error())
+}
+
+func TestValidateDataTooLarge(t *testing.T) {
+	err := validateData("0x" + strings.Repeat("00", MaxDataSize+1))
+	assert.Equal(t, errors.ErrDataTooLarge, err.ErrorCode())
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/oasislabs/oasis-gateway/errors"
+)
+
+const (
+	// EVMContentType is the default content type of a deployment
+	// payload, EVM bytecode
+	EVMContentType = "application/evm"
+
+	// WASMContentType is the content type of a deployment payload
+	// that is a WASM module
+	WASMContentType = "application/wasm"
+)
+
+// wasmHeader is the magic number followed by the version
+// with which every WASM binary module starts
+var wasmHeader = hex.EncodeToString([]byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00})
+
+// WASMProps defines how the deployments of WASM payloads are handled
+type WASMProps struct {
+	// Enabled if set allows clients to deploy WASM payloads
+	Enabled bool
+
+	// MaxSize is the maximum size in bytes of a WASM payload
+	MaxSize int
+
+	// Validate if set rejects the WASM payloads that do
+	// not start with a valid WASM header
+	Validate bool
+}
+
+// decodeDeployData validates and decodes the payload of a deployment
+// depending on its content type. It returns the payload as a 0x
+// prefixed hex string
+func decodeDeployData(req *DeployServiceRequest, props WASMProps) (string, errors.Err) {
+	switch req.ContentType {
+	case "", EVMContentType:
+		return decodeData(req.Encoding, req.Data)
+	case WASMContentType:
+		if !props.Enabled {
+			return "", errors.New(errors.ErrUnsupportedContentType,
+				fmt.Errorf("deployment of %s payloads is not enabled", WASMContentType))
+		}
+
+		data, err := decodeDataLimit(req.Encoding, req.Data, props.MaxSize)
+		if err != nil {
+			return "", err
+		}
+
+		if props.Validate && !hasWASMHeader(data) {
+			return "", errors.New(errors.ErrInvalidWASMHeader, nil)
+		}
+
+		return data, nil
+	default:
+		return "", errors.New(errors.ErrUnsupportedContentType,
+			fmt.Errorf("unknown content type %q", req.ContentType))
+	}
+}
+
+// hasWASMHeader returns true if the 0x prefixed hex
+// data starts with the WASM header
+func hasWASMHeader(data string) bool {
+	digits := data[2:]
+	return len(digits) >= len(wasmHeader) &&
+		strings.EqualFold(digits[:len(wasmHeader)], wasmHeader)
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/oasislabs/oasis-gateway/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+var testWASMProps = WASMProps{
+	Enabled:  true,
+	MaxSize:  16,
+	Validate: true,
+}
+
+func TestDecodeDeployDataEVM(t *testing.T) {
+	for _, contentType := range []string{"", EVMContentType} {
+		data, err := decodeDeployData(&DeployServiceRequest{
+			Data:        "0x6060",
+			ContentType: contentType,
+		}, WASMProps{})
+		assert.Nil(t, err)
+		assert.Equal(t, "0x6060", data)
+	}
+}
+
+func TestDecodeDeployDataWASMOK(t *testing.T) {
+	data, err := decodeDeployData(&DeployServiceRequest{
+		Data:        "0x0061736D0100000001",
+		ContentType: WASMContentType,
+	}, testWASMProps)
+	assert.Nil(t, err)
+	assert.Equal(t, "0x0061736D0100000001", data)
+
+	data, err = decodeDeployData(&DeployServiceRequest{
+		Data:        "AGFzbQEAAAA=",
+		Encoding:    Base64Encoding,
+		ContentType: WASMContentType,
+	}, testWASMProps)
+	assert.Nil(t, err)
+	assert.Equal(t, "0x0061736d01000000", data)
+}
+
+func TestDecodeDeployDataWASMDisabled(t *testing.T) {
+	_, err := decodeDeployData(&DeployServiceRequest{
+		Data:        "0x0061736d01000000",
+		ContentType: WASMContentType,
+	}, WASMProps{MaxSize: 16})
+	assert.Equal(t, errors.ErrUnsupportedContentType, err.ErrorCode())
+}
+
+func TestDecodeDeployDataWASMInvalidHeader(t *testing.T) {
+	for _, data := range []string{"0x", "0x0061736d", "0x6060606060606060"} {
+		_, err := decodeDeployData(&DeployServiceRequest{
+			Data:        data,
+			ContentType: WASMContentType,
+		}, testWASMProps)
+		assert.Equal(t, errors.ErrInvalidWASMHeader, err.ErrorCode())
+	}
+
+	props := testWASMProps
+	props.Validate = false
+	_, err := decodeDeployData(&DeployServiceRequest{
+		Data:        "0x6060",
+		ContentType: WASMContentType,
+	}, props)
+	assert.Nil(t, err)
+}
+
+func TestDecodeDeployDataWASMTooLarge(t *testing.T) {
+	_, err := decodeDeployData(&DeployServiceRequest{
+		Data:        "0x0061736d01000000" + strings.Repeat("00", 9),
+		ContentType: WASMContentType,
+	}, testWASMProps)
+	assert.Equal(t, errors.ErrDataTooLarge, err.ErrorCode())
+}
+
+func TestDecodeDeployDataUnknownContentType(t *testing.T) {
+	_, err := decodeDeployData(&DeployServiceRequest{
+		Data:        "0x6060",
+		ContentType: "application/octet-stream",
+	}, testWASMProps)
+	assert.Equal(t, errors.ErrUnsupportedContentType, err.ErrorCode())
+}
@@ -0,0 +1,53 @@
+package session
+
+// SetMetadataRequest is a request to replace the metadata
+// attached to the session of the request
+type SetMetadataRequest struct {
+	// Metadata are the key/value pairs attached to the session,
+	// like the name and version of the client application
+	Metadata map[string]string `json:"metadata"`
+}
+
+// GetMetadataRequest is a request to retrieve the metadata
+// attached to the session of the request
+type GetMetadataRequest struct{}
+
+// GetMetadataResponse is the response to a SetMetadataRequest
+// or a GetMetadataRequest
+type GetMetadataResponse struct {
+	// Metadata attached to the session
+	Metadata map[string]string `json:"metadata"`
+}
+
+// HeartbeatRequest is a request to keep the session of
+// the request from expiring
+type HeartbeatRequest struct{}
+
+// HeartbeatResponse is the response to a HeartbeatRequest
+type HeartbeatResponse struct {
+	// Offset of the first event kept in the mailbox of the session
+	Offset uint64 `json:"offset"`
+
+	// Pending is the number of events kept in the mailbox of
+	// the session, which can be polled from Offset
+	Pending uint64 `json:"pending"`
+}
+
+// ListSessionsRequest is a request to retrieve the sessions
+// known to the gateway along with their metadata
+type ListSessionsRequest struct{}
+
+// Session is a session with the metadata attached to it
+type Session struct {
+	// Key is the session key
+	Key string `json:"key"`
+
+	// Metadata attached to the session
+	Metadata map[string]string `json:"metadata"`
+}
+
+// ListSessionsResponse is the response to a ListSessionsRequest
+type ListSessionsResponse struct {
+	// Sessions known to the gateway
+	Sessions []Session `json:"sessions"`
+}
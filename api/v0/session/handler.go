@@ -0,0 +1,158 @@
+package session
+
+import (
+	"context"
+
+	auth "github.com/oasislabs/oasis-gateway/auth/core"
+	backend "github.com/oasislabs/oasis-gateway/backend/core"
+	"github.com/oasislabs/oasis-gateway/errors"
+	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/oasislabs/oasis-gateway/rpc"
+)
+
+// Client interface for the underlying operations needed for the API
+// implementation
+type Client interface {
+	// SetSessionMetadata replaces the metadata attached to a session
+	SetSessionMetadata(context.Context, backend.SetSessionMetadataRequest) errors.Err
+
+	// GetSessionMetadata retrieves the metadata attached to a session
+	GetSessionMetadata(context.Context, backend.GetSessionMetadataRequest) (backend.SessionMetadata, errors.Err)
+
+	// LookupSessionMetadata returns the metadata attached to a
+	// session if it is known to the gateway
+	LookupSessionMetadata(session string) (backend.SessionMetadata, bool)
+
+	// ListSessions retrieves the sessions known to the gateway
+	ListSessions(context.Context) []backend.Session
+
+	// Heartbeat keeps a session from expiring
+	Heartbeat(context.Context, backend.HeartbeatRequest) (backend.HeartbeatResponse, errors.Err)
+}
+
+// Services required by the SessionHandler execution
+type Services struct {
+	Logger log.Logger
+	Client Client
+}
+
+// SessionHandler implements the handlers for the management
+// of the metadata of the sessions
+type SessionHandler struct {
+	logger log.Logger
+	client Client
+}
+
+// SetMetadata replaces the metadata attached to the session of the request
+func (h SessionHandler) SetMetadata(ctx context.Context, v interface{}) (interface{}, error) {
+	session := ctx.Value(auth.Session{}).(string)
+	req := v.(*SetMetadataRequest)
+
+	if err := h.client.SetSessionMetadata(ctx, backend.SetSessionMetadataRequest{
+		SessionKey: session,
+		Metadata:   req.Metadata,
+	}); err != nil {
+		h.logger.Debug(ctx, "request failed", log.MapFields{
+			"call_type": "SetSessionMetadataFailure",
+			"session":   session,
+		}, err)
+		return nil, err
+	}
+
+	h.logger.Info(log.PutFields(ctx, backend.SessionMetadata(req.Metadata).LogFields()),
+		"session metadata set", log.MapFields{
+			"call_type": "SetSessionMetadataSuccess",
+			"session":   session,
+		})
+
+	return GetMetadataResponse{Metadata: req.Metadata}, nil
+}
+
+// GetMetadata retrieves the metadata attached to the session of the request
+func (h SessionHandler) GetMetadata(ctx context.Context, v interface{}) (interface{}, error) {
+	session := ctx.Value(auth.Session{}).(string)
+	_ = v.(*GetMetadataRequest)
+
+	metadata, err := h.client.GetSessionMetadata(ctx, backend.GetSessionMetadataRequest{
+		SessionKey: session,
+	})
+	if err != nil {
+		h.logger.Debug(ctx, "request failed", log.MapFields{
+			"call_type": "GetSessionMetadataFailure",
+			"session":   session,
+		}, err)
+		return nil, err
+	}
+
+	return GetMetadataResponse{Metadata: metadata}, nil
+}
+
+// Heartbeat keeps the session of the request from expiring and
+// returns the number of events kept in its mailbox, so that clients
+// with sparse traffic do not need to poll to keep their session
+func (h SessionHandler) Heartbeat(ctx context.Context, v interface{}) (interface{}, error) {
+	session := ctx.Value(auth.Session{}).(string)
+	_ = v.(*HeartbeatRequest)
+
+	res, err := h.client.Heartbeat(ctx, backend.HeartbeatRequest{SessionKey: session})
+	if err != nil {
+		h.logger.Debug(ctx, "request failed", log.MapFields{
+			"call_type": "HeartbeatFailure",
+			"session":   session,
+		}, err)
+		return nil, err
+	}
+
+	return HeartbeatResponse{Offset: res.Offset, Pending: res.Pending}, nil
+}
+
+// ListSessions retrieves the sessions known to the gateway
+// along with their metadata
+func (h SessionHandler) ListSessions(ctx context.Context, v interface{}) (interface{}, error) {
+	_ = v.(*ListSessionsRequest)
+
+	sessions := h.client.ListSessions(ctx)
+	res := ListSessionsResponse{Sessions: make([]Session, 0, len(sessions))}
+	for _, session := range sessions {
+		res.Sessions = append(res.Sessions, Session{Key: session.Key, Metadata: session.Metadata})
+	}
+
+	return res, nil
+}
+
+func NewSessionHandler(services Services) SessionHandler {
+	if services.Client == nil {
+		panic("Client must be provided as a service")
+	}
+	if services.Logger == nil {
+		panic("Logger must be provided as a service")
+	}
+
+	return SessionHandler{
+		logger: services.Logger.ForClass("session", "handler"),
+		client: services.Client,
+	}
+}
+
+// BindHandler binds the handlers clients use to manage the
+// metadata and the lifetime of their sessions to the provided
+// HandlerBinder
+func BindHandler(services Services, binder rpc.HandlerBinder) {
+	handler := NewSessionHandler(services)
+
+	binder.Bind("POST", "/v0/api/session/setMetadata", rpc.HandlerFunc(handler.SetMetadata),
+		rpc.EntityFactoryFunc(func() interface{} { return &SetMetadataRequest{} }))
+	binder.Bind("POST", "/v0/api/session/getMetadata", rpc.HandlerFunc(handler.GetMetadata),
+		rpc.EntityFactoryFunc(func() interface{} { return &GetMetadataRequest{} }))
+	binder.Bind("POST", "/v0/api/session/heartbeat", rpc.HandlerFunc(handler.Heartbeat),
+		rpc.EntityFactoryFunc(func() interface{} { return &HeartbeatRequest{} }))
+}
+
+// BindPrivateHandler binds the handlers operators use to
+// list the sessions to the provided HandlerBinder
+func BindPrivateHandler(services Services, binder rpc.HandlerBinder) {
+	handler := NewSessionHandler(services)
+
+	binder.Bind("GET", "/v0/api/session/list", rpc.HandlerFunc(handler.ListSessions),
+		rpc.EntityFactoryFunc(func() interface{} { return &ListSessionsRequest{} }))
+}
@@ -0,0 +1,136 @@
+package session
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	auth "github.com/oasislabs/oasis-gateway/auth/core"
+	backend "github.com/oasislabs/oasis-gateway/backend/core"
+	"github.com/oasislabs/oasis-gateway/errors"
+	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/oasislabs/oasis-gateway/mqueue/mem"
+	"github.com/oasislabs/oasis-gateway/rpc"
+	"github.com/stretchr/testify/assert"
+)
+
+var Context = context.TODO()
+
+var Logger = log.NewLogrus(log.LogrusLoggerProperties{
+	Output: ioutil.Discard,
+})
+
+type registryClient struct {
+	registry *backend.SessionRegistry
+}
+
+func (c registryClient) SetSessionMetadata(ctx context.Context, req backend.SetSessionMetadataRequest) errors.Err {
+	return c.registry.Set(ctx, req.SessionKey, req.Metadata)
+}
+
+func (c registryClient) GetSessionMetadata(ctx context.Context, req backend.GetSessionMetadataRequest) (backend.SessionMetadata, errors.Err) {
+	return c.registry.Get(ctx, req.SessionKey)
+}
+
+func (c registryClient) LookupSessionMetadata(session string) (backend.SessionMetadata, bool) {
+	return c.registry.Lookup(session)
+}
+
+func (c registryClient) ListSessions(ctx context.Context) []backend.Session {
+	return c.registry.List()
+}
+
+func (c registryClient) Heartbeat(ctx context.Context, req backend.HeartbeatRequest) (backend.HeartbeatResponse, errors.Err) {
+	if req.SessionKey == "expired" {
+		return backend.HeartbeatResponse{}, errors.New(errors.ErrQueueRetrieve, nil)
+	}
+
+	return backend.HeartbeatResponse{Offset: 1, Pending: 2}, nil
+}
+
+func createSessionHandler() SessionHandler {
+	return NewSessionHandler(Services{
+		Logger: Logger,
+		Client: registryClient{registry: backend.NewSessionRegistry(
+			mem.NewServer(Context, mem.Services{Logger: Logger}), backend.SessionRegistryProps{})},
+	})
+}
+
+func sessionContext(session string) context.Context {
+	return context.WithValue(Context, auth.Session{}, session)
+}
+
+func TestSetMetadataOK(t *testing.T) {
+	h := createSessionHandler()
+	ctx := sessionContext("session")
+
+	v, err := h.SetMetadata(ctx, &SetMetadataRequest{Metadata: map[string]string{"app": "wallet"}})
+	assert.Nil(t, err)
+	assert.Equal(t, GetMetadataResponse{Metadata: map[string]string{"app": "wallet"}}, v)
+
+	v, err = h.GetMetadata(ctx, &GetMetadataRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, GetMetadataResponse{Metadata: map[string]string{"app": "wallet"}}, v)
+
+	v, err = h.ListSessions(Context, &ListSessionsRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, ListSessionsResponse{Sessions: []Session{
+		{Key: "session", Metadata: map[string]string{"app": "wallet"}},
+	}}, v)
+}
+
+func TestSetMetadataErrInvalid(t *testing.T) {
+	h := createSessionHandler()
+
+	_, err := h.SetMetadata(sessionContext("session"), &SetMetadataRequest{
+		Metadata: map[string]string{"app name": "wallet"}})
+	assert.Error(t, err)
+	assert.Equal(t, errors.ErrInvalidSessionMetadata, err.(errors.Err).ErrorCode())
+}
+
+func TestGetMetadataEmpty(t *testing.T) {
+	h := createSessionHandler()
+
+	v, err := h.GetMetadata(sessionContext("session"), &GetMetadataRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, GetMetadataResponse{Metadata: map[string]string{}}, v)
+}
+
+func TestHttpMiddlewareLogFields(t *testing.T) {
+	h := createSessionHandler()
+	ctx := sessionContext("session")
+	_, err := h.SetMetadata(ctx, &SetMetadataRequest{Metadata: map[string]string{"app": "wallet"}})
+	assert.Nil(t, err)
+
+	var fields map[string]string
+	m := NewHttpMiddleware(h.client, rpc.HttpMiddlewareFunc(func(req *http.Request) (interface{}, error) {
+		fields = log.GetFields(req.Context())
+		return nil, nil
+	}))
+
+	req, _ := http.NewRequest("POST", "/v0/api/service/execute", nil)
+	_, err = m.ServeHTTP(req.WithContext(ctx))
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"session.app": "wallet"}, fields)
+
+	_, err = m.ServeHTTP(req.WithContext(sessionContext("other")))
+	assert.Nil(t, err)
+	assert.Nil(t, fields)
+}
+
+func TestHeartbeatOK(t *testing.T) {
+	h := createSessionHandler()
+
+	v, err := h.Heartbeat(sessionContext("session"), &HeartbeatRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, HeartbeatResponse{Offset: 1, Pending: 2}, v)
+}
+
+func TestHeartbeatErr(t *testing.T) {
+	h := createSessionHandler()
+
+	_, err := h.Heartbeat(sessionContext("expired"), &HeartbeatRequest{})
+	assert.Error(t, err)
+	assert.Equal(t, errors.ErrQueueRetrieve, err.(errors.Err).ErrorCode())
+}
@@ -0,0 +1,36 @@
+package session
+
+import (
+	"net/http"
+
+	auth "github.com/oasislabs/oasis-gateway/auth/core"
+	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/oasislabs/oasis-gateway/rpc"
+)
+
+// HttpMiddleware adds the metadata of the session of a request to the
+// fields logged while the request is served. It must be placed after
+// the authentication middleware so that the session is set in the
+// context. Only the metadata already known to the gateway is logged,
+// so that requests do not need to wait for it to be loaded
+type HttpMiddleware struct {
+	client Client
+	next   rpc.HttpMiddleware
+}
+
+// NewHttpMiddleware creates a new middleware that logs the
+// metadata of the sessions of the requests served by next
+func NewHttpMiddleware(client Client, next rpc.HttpMiddleware) *HttpMiddleware {
+	return &HttpMiddleware{client: client, next: next}
+}
+
+// ServeHTTP is the implementation of rpc.HttpMiddleware for HttpMiddleware
+func (m *HttpMiddleware) ServeHTTP(req *http.Request) (interface{}, error) {
+	session, _ := req.Context().Value(auth.Session{}).(string)
+	metadata, ok := m.client.LookupSessionMetadata(session)
+	if ok && len(metadata) > 0 {
+		req = req.WithContext(log.PutFields(req.Context(), metadata.LogFields()))
+	}
+
+	return m.next.ServeHTTP(req)
+}
@@ -0,0 +1,41 @@
+package usage
+
+// ReportRequest is a request to retrieve the usage of the
+// gateway in a range of days
+type ReportRequest struct {
+	// AAD if set limits the report to the usage of the AAD
+	AAD string `json:"aad"`
+
+	// From is the first day of the report in the format YYYY-MM-DD
+	From string `json:"from"`
+
+	// To is the last day of the report in the format YYYY-MM-DD.
+	// If not set the report only includes the From day
+	To string `json:"to"`
+}
+
+// Usage are the counters of the usage of the gateway
+// by an AAD during a day
+type Usage struct {
+	// AAD is the identifier of the user of the gateway
+	AAD string `json:"aad"`
+
+	// Day is the day in which the usage was recorded
+	Day string `json:"day"`
+
+	// Requests is the number of requests issued to each API
+	Requests map[string]uint64 `json:"requests"`
+
+	// Events is the number of events generated for the AAD
+	Events uint64 `json:"events"`
+
+	// Errors is the number of failed requests and error events
+	Errors uint64 `json:"errors"`
+}
+
+// ReportResponse is the response to a ReportRequest
+type ReportResponse struct {
+	// Usage is the usage of each AAD and day, ordered
+	// by day and AAD
+	Usage []Usage `json:"usage"`
+}
@@ -0,0 +1,126 @@
+package usage
+
+import (
+	"context"
+	stderr "errors"
+	"time"
+
+	"github.com/oasislabs/oasis-gateway/errors"
+	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/oasislabs/oasis-gateway/rpc"
+	"github.com/oasislabs/oasis-gateway/usage"
+)
+
+// MaxReportDays is the maximum number of days that
+// can be included in a report
+const MaxReportDays = 366
+
+// Client interface for the underlying operations needed for the API
+// implementation
+type Client interface {
+	// Report retrieves the usage recorded in a range of days
+	Report(context.Context, usage.ReportRequest) ([]usage.Usage, error)
+}
+
+// Services required by the UsageHandler execution
+type Services struct {
+	Logger log.Logger
+	Client Client
+}
+
+// UsageHandler implements the handlers for usage reporting
+type UsageHandler struct {
+	logger log.Logger
+	client Client
+}
+
+// parseRange parses the range of days of the request
+func parseRange(req *ReportRequest) (time.Time, time.Time, errors.Err) {
+	from, err := time.Parse(usage.DayLayout, req.From)
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.New(errors.ErrInvalidUsageRange, err)
+	}
+
+	to := from
+	if len(req.To) > 0 {
+		to, err = time.Parse(usage.DayLayout, req.To)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.New(errors.ErrInvalidUsageRange, err)
+		}
+	}
+
+	if to.Before(from) {
+		return time.Time{}, time.Time{}, errors.New(errors.ErrInvalidUsageRange,
+			stderr.New("to cannot be before from"))
+	}
+
+	if to.Sub(from) >= MaxReportDays*24*time.Hour {
+		return time.Time{}, time.Time{}, errors.New(errors.ErrInvalidUsageRange,
+			stderr.New("range cannot exceed the maximum number of days"))
+	}
+
+	return from, to, nil
+}
+
+// Report retrieves the usage recorded in a range of days
+func (h UsageHandler) Report(ctx context.Context, v interface{}) (interface{}, error) {
+	req := v.(*ReportRequest)
+
+	from, to, err := parseRange(req)
+	if err != nil {
+		h.logger.Debug(ctx, "failed to handle request", log.MapFields{
+			"call_type": "ReportUsageFailure",
+		}, err)
+		return nil, err
+	}
+
+	usages, rerr := h.client.Report(ctx, usage.ReportRequest{
+		AAD:  req.AAD,
+		From: from,
+		To:   to,
+	})
+	if rerr != nil {
+		err := errors.New(errors.ErrUsageReport, rerr)
+		h.logger.Debug(ctx, "request failed", log.MapFields{
+			"call_type": "ReportUsageFailure",
+		}, err)
+		return nil, err
+	}
+
+	res := ReportResponse{Usage: make([]Usage, 0, len(usages))}
+	for _, u := range usages {
+		res.Usage = append(res.Usage, Usage{
+			AAD:      u.AAD,
+			Day:      u.Day,
+			Requests: u.Requests,
+			Events:   u.Events,
+			Errors:   u.Errors,
+		})
+	}
+
+	return res, nil
+}
+
+// NewUsageHandler creates a new instance of a usage handler
+func NewUsageHandler(services Services) UsageHandler {
+	if services.Logger == nil {
+		panic("Logger must be provided as a service")
+	}
+	if services.Client == nil {
+		panic("Client must be provided as a service")
+	}
+
+	return UsageHandler{
+		logger: services.Logger.ForClass("usage", "handler"),
+		client: services.Client,
+	}
+}
+
+// BindHandler binds the usage handler to the provided
+// HandlerBinder
+func BindHandler(services Services, binder rpc.HandlerBinder) {
+	handler := NewUsageHandler(services)
+
+	binder.Bind("POST", "/v0/api/usage/report", rpc.HandlerFunc(handler.Report),
+		rpc.EntityFactoryFunc(func() interface{} { return &ReportRequest{} }))
+}
@@ -0,0 +1,73 @@
+package usage
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/oasislabs/oasis-gateway/usage"
+	"github.com/stretchr/testify/assert"
+)
+
+var Context = context.TODO()
+
+var Logger = log.NewLogrus(log.LogrusLoggerProperties{
+	Output: ioutil.Discard,
+})
+
+func createUsageHandler() UsageHandler {
+	store := usage.NewMemStore()
+	_ = store.Add(Context, []usage.Usage{
+		{AAD: "aad", Day: "2020-06-01", Requests: map[string]uint64{"/v0/api/service/execute": 1}},
+		{AAD: "aad", Day: "2020-06-03", Events: 2},
+	})
+
+	return NewUsageHandler(Services{
+		Logger: Logger,
+		Client: usage.NewRecorder(Context, usage.Services{Logger: Logger, Store: store}, usage.Props{}),
+	})
+}
+
+func TestReportOK(t *testing.T) {
+	h := createUsageHandler()
+
+	v, err := h.Report(Context, &ReportRequest{From: "2020-06-01", To: "2020-06-03"})
+	assert.Nil(t, err)
+	assert.Equal(t, ReportResponse{Usage: []Usage{
+		{AAD: "aad", Day: "2020-06-01", Requests: map[string]uint64{"/v0/api/service/execute": 1}},
+		{AAD: "aad", Day: "2020-06-03", Requests: map[string]uint64{}, Events: 2},
+	}}, v)
+}
+
+func TestReportSingleDay(t *testing.T) {
+	h := createUsageHandler()
+
+	v, err := h.Report(Context, &ReportRequest{From: "2020-06-03"})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(v.(ReportResponse).Usage))
+}
+
+func TestReportErrInvalidDay(t *testing.T) {
+	h := createUsageHandler()
+
+	_, err := h.Report(Context, &ReportRequest{From: "06/01/2020"})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "[2025] error code InputError with desc Provided invalid range of days for the usage report.")
+}
+
+func TestReportErrToBeforeFrom(t *testing.T) {
+	h := createUsageHandler()
+
+	_, err := h.Report(Context, &ReportRequest{From: "2020-06-03", To: "2020-06-01"})
+	assert.Equal(t, "[2025] error code InputError with desc Provided invalid range of days for the usage report. with cause to cannot be before from", err.Error())
+}
+
+func TestReportErrRangeTooLong(t *testing.T) {
+	h := createUsageHandler()
+
+	to := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC).Add(MaxReportDays * 24 * time.Hour)
+	_, err := h.Report(Context, &ReportRequest{From: "2020-06-01", To: usage.Day(to)})
+	assert.Error(t, err)
+}
@@ -1,10 +1,13 @@
 package auth
 
 import (
+	"errors"
 	"plugin"
 	"strings"
+	"time"
 
 	"github.com/oasislabs/oasis-gateway/auth/core"
+	"github.com/oasislabs/oasis-gateway/auth/oauth"
 	"github.com/oasislabs/oasis-gateway/config"
 	"github.com/oasislabs/oasis-gateway/log"
 	"github.com/spf13/cobra"
@@ -16,12 +19,77 @@ type AuthProvider string
 const (
 	AuthInsecure = "insecure"
 	AuthOauth    = "oauth"
+	AuthOIDC     = "oidc"
 )
 
+const (
+	ReplayProviderMem   = "mem"
+	ReplayProviderRedis = "redis"
+)
+
+// ReplayConfig defines how the requests are
+// protected against replays
+type ReplayConfig struct {
+	// Window is the maximum difference between the timestamp of a
+	// request and the time it is received. If it is 0 requests
+	// are not protected against replays
+	Window time.Duration
+
+	// Provider is the store of the nonces
+	Provider string
+
+	// MaxEntries is the maximum number of nonces kept
+	// by the mem provider
+	MaxEntries int
+
+	// MaxEntriesPerAAD is the maximum number of nonces kept
+	// by the mem provider for the same AAD
+	MaxEntriesPerAAD int
+
+	// RedisAddrs are the addresses of the redis instance or
+	// cluster seeds used by the redis provider
+	RedisAddrs []string
+}
+
 // Config sets the configuration for the authentication
 // mechanism to use
 type Config struct {
 	Providers []core.Auth
+
+	// Cache defines how the results of verifying the tokens
+	// used for authentication are cached
+	Cache core.VerificationCacheProps
+
+	// OIDC defines the issuer of the ID tokens accepted
+	// by the oidc provider
+	OIDC oauth.OIDCProps
+
+	// Session defines how the session key of the
+	// authenticated requests is obtained
+	Session core.SessionProps
+
+	// Binding defines how long client supplied session keys
+	// stay bound to the AAD that used them
+	Binding core.SessionBindingProps
+
+	// Lockout defines when clients that fail to
+	// authenticate are locked out
+	Lockout core.LockoutProps
+
+	// Capability defines how capability tokens are signed. If
+	// the secret is not set capability tokens are not issued
+	Capability core.CapabilityProps
+
+	// Capabilities mints and verifies capability tokens if
+	// they are enabled
+	Capabilities *core.CapabilityIssuer
+
+	// Replay defines how the requests are protected against replays
+	Replay ReplayConfig
+
+	// ReplayGuard rejects stale and replayed requests if
+	// replay protection is enabled
+	ReplayGuard *core.ReplayGuard
 }
 
 func (c *Config) Log(fields log.Fields) {
@@ -32,6 +100,26 @@ func (c *Config) Log(fields log.Fields) {
 	}
 
 	fields.Add("auth.provider", strings.Join(names, ", "))
+	fields.Add("auth.cache.ttl_ms", int64(c.Cache.TTL/time.Millisecond))
+	fields.Add("auth.cache.max_entries", c.Cache.MaxEntries)
+	fields.Add("auth.oidc.issuer", c.OIDC.Issuer)
+	fields.Add("auth.oidc.audience", c.OIDC.Audience)
+	fields.Add("auth.oidc.jwks_url", c.OIDC.JWKSURL)
+	fields.Add("auth.oidc.aad_claim", c.OIDC.AADClaim)
+	fields.Add("auth.session.mode", c.Session.Mode)
+	fields.Add("auth.session.binding_ttl_ms", int64(c.Binding.TTL/time.Millisecond))
+	fields.Add("auth.session.binding_max_entries", c.Binding.MaxEntries)
+	fields.Add("auth.lockout.max_failures", c.Lockout.MaxFailures)
+	fields.Add("auth.lockout.duration_ms", int64(c.Lockout.Duration/time.Millisecond))
+	fields.Add("auth.lockout.max_entries", c.Lockout.MaxEntries)
+	fields.Add("auth.lockout.client_ip_header", c.Lockout.ClientIPHeader)
+	fields.Add("auth.capability.enabled", c.Capabilities != nil)
+	fields.Add("auth.capability.max_ttl_ms", int64(c.Capability.MaxTTL/time.Millisecond))
+	fields.Add("auth.replay.window_ms", int64(c.Replay.Window/time.Millisecond))
+	fields.Add("auth.replay.provider", c.Replay.Provider)
+	fields.Add("auth.replay.max_entries", c.Replay.MaxEntries)
+	fields.Add("auth.replay.max_entries_per_aad", c.Replay.MaxEntriesPerAAD)
+	fields.Add("auth.replay.redis.addrs", strings.Join(c.Replay.RedisAddrs, ","))
 }
 
 func (c *Config) Configure(v *viper.Viper) error {
@@ -39,9 +127,75 @@ func (c *Config) Configure(v *viper.Viper) error {
 		c.Providers = make([]core.Auth, 0)
 	}
 
+	ttl := v.GetInt64("auth.cache.ttl_ms")
+	if ttl < 0 {
+		return errors.New("auth.cache.ttl_ms cannot be negative")
+	}
+	c.Cache.TTL = time.Duration(ttl) * time.Millisecond
+
+	c.Cache.MaxEntries = v.GetInt("auth.cache.max_entries")
+	if c.Cache.MaxEntries < 0 {
+		return errors.New("auth.cache.max_entries cannot be negative")
+	}
+
+	c.Session.Mode = core.SessionMode(v.GetString("auth.session.mode"))
+	switch c.Session.Mode {
+	case core.SessionModeClient:
+	case core.SessionModeDerived:
+		c.Session.Secret = []byte(v.GetString("auth.session.secret"))
+		if len(c.Session.Secret) == 0 {
+			return errors.New("auth.session.secret must be set if " +
+				"auth.session.mode is " + core.SessionModeDerived.String())
+		}
+	default:
+		return config.ErrInvalidValue{
+			Key:          "auth.session.mode",
+			InvalidValue: c.Session.Mode.String(),
+			Values: []string{
+				core.SessionModeClient.String(),
+				core.SessionModeDerived.String(),
+			},
+		}
+	}
+
+	bindingTTL := v.GetInt64("auth.session.binding_ttl_ms")
+	if bindingTTL < 0 {
+		return errors.New("auth.session.binding_ttl_ms cannot be negative")
+	}
+	c.Binding.TTL = time.Duration(bindingTTL) * time.Millisecond
+
+	c.Binding.MaxEntries = v.GetInt("auth.session.binding_max_entries")
+	if c.Binding.MaxEntries < 0 {
+		return errors.New("auth.session.binding_max_entries cannot be negative")
+	}
+
+	c.Lockout.MaxFailures = v.GetInt("auth.lockout.max_failures")
+	if c.Lockout.MaxFailures < 0 {
+		return errors.New("auth.lockout.max_failures cannot be negative")
+	}
+
+	lockoutDuration := v.GetInt64("auth.lockout.duration_ms")
+	if lockoutDuration < 0 {
+		return errors.New("auth.lockout.duration_ms cannot be negative")
+	}
+	c.Lockout.Duration = time.Duration(lockoutDuration) * time.Millisecond
+
+	c.Lockout.MaxEntries = v.GetInt("auth.lockout.max_entries")
+	if c.Lockout.MaxEntries < 0 {
+		return errors.New("auth.lockout.max_entries cannot be negative")
+	}
+
+	c.Lockout.ClientIPHeader = v.GetString("auth.lockout.client_ip_header")
+
 	providers := v.GetStringSlice("auth.provider")
 	for _, provider := range providers {
-		auth := newAuthSingle(AuthProvider(provider))
+		if provider == AuthOIDC {
+			if err := c.configureOIDC(v); err != nil {
+				return err
+			}
+		}
+
+		auth := newAuthSingle(AuthProvider(provider), c)
 		if auth == nil {
 			return config.ErrKeyNotSet{Key: "auth.provider"}
 		}
@@ -65,11 +219,140 @@ func (c *Config) Configure(v *viper.Viper) error {
 		c.Providers = append(c.Providers, auth)
 	}
 
+	c.Capability.Secret = []byte(v.GetString("auth.capability.secret"))
+	maxTTL := v.GetInt64("auth.capability.max_ttl_ms")
+	if maxTTL < 1000 {
+		return errors.New("auth.capability.max_ttl_ms must be at least 1000")
+	}
+	c.Capability.MaxTTL = time.Duration(maxTTL) * time.Millisecond
+
+	if len(c.Capability.Secret) > 0 {
+		// capability tokens are checked first so that a request that
+		// presents one is not authenticated with other credentials
+		c.Capabilities = core.NewCapabilityIssuer(c.Capability)
+		c.Providers = append([]core.Auth{core.NewCapabilityAuth(c.Capabilities)}, c.Providers...)
+	}
+
+	return c.configureReplay(v)
+}
+
+func (c *Config) configureOIDC(v *viper.Viper) error {
+	c.OIDC.Issuer = v.GetString("auth.oidc.issuer")
+	if len(c.OIDC.Issuer) == 0 {
+		return config.ErrKeyNotSet{Key: "auth.oidc.issuer"}
+	}
+
+	c.OIDC.JWKSURL = v.GetString("auth.oidc.jwks_url")
+	if len(c.OIDC.JWKSURL) == 0 {
+		return config.ErrKeyNotSet{Key: "auth.oidc.jwks_url"}
+	}
+
+	c.OIDC.Audience = v.GetString("auth.oidc.audience")
+	c.OIDC.AADClaim = v.GetString("auth.oidc.aad_claim")
+	if len(c.OIDC.AADClaim) == 0 {
+		return config.ErrKeyNotSet{Key: "auth.oidc.aad_claim"}
+	}
+
+	return nil
+}
+
+func (c *Config) configureReplay(v *viper.Viper) error {
+	window := v.GetInt64("auth.replay.window_ms")
+	if window < 0 {
+		return errors.New("auth.replay.window_ms cannot be negative")
+	}
+	c.Replay.Window = time.Duration(window) * time.Millisecond
+	if c.Replay.Window == 0 {
+		return nil
+	}
+
+	var store core.NonceStore
+	c.Replay.Provider = v.GetString("auth.replay.provider")
+	switch c.Replay.Provider {
+	case ReplayProviderMem:
+		c.Replay.MaxEntries = v.GetInt("auth.replay.max_entries")
+		if c.Replay.MaxEntries <= 0 {
+			return errors.New("auth.replay.max_entries must be positive")
+		}
+		c.Replay.MaxEntriesPerAAD = v.GetInt("auth.replay.max_entries_per_aad")
+		if c.Replay.MaxEntriesPerAAD <= 0 {
+			return errors.New("auth.replay.max_entries_per_aad must be positive")
+		}
+		store = core.NewMemNonceStore(c.Replay.MaxEntries, c.Replay.MaxEntriesPerAAD)
+	case ReplayProviderRedis:
+		c.Replay.RedisAddrs = v.GetStringSlice("auth.replay.redis.addrs")
+		if len(c.Replay.RedisAddrs) == 0 {
+			return config.ErrKeyNotSet{Key: "auth.replay.redis.addrs"}
+		}
+		store = core.NewRedisNonceStore(c.Replay.RedisAddrs)
+	default:
+		return config.ErrInvalidValue{
+			Key:          "auth.replay.provider",
+			InvalidValue: c.Replay.Provider,
+			Values:       []string{ReplayProviderMem, ReplayProviderRedis},
+		}
+	}
+
+	c.ReplayGuard = core.NewReplayGuard(core.ReplayGuardProps{
+		Window: c.Replay.Window,
+		Store:  store,
+	})
 	return nil
 }
 
 func (c *Config) Bind(v *viper.Viper, cmd *cobra.Command) error {
 	cmd.PersistentFlags().StringSlice("auth.provider", []string{"insecure"}, "providers for request authentication")
 	cmd.PersistentFlags().StringSlice("auth.plugin", []string{}, "plugins for request authentication")
+	cmd.PersistentFlags().String("auth.oidc.issuer", "",
+		"URL of the issuer of the ID tokens accepted by the "+AuthOIDC+" provider. It must match their iss claim.")
+	cmd.PersistentFlags().String("auth.oidc.audience", "",
+		"audience that the ID tokens accepted by the "+AuthOIDC+" provider must have in their aud claim, "+
+			"usually the client ID of the application. If not set the audience is not checked.")
+	cmd.PersistentFlags().String("auth.oidc.jwks_url", "",
+		"URL of the key set with which the issuer signs the ID tokens accepted by the "+AuthOIDC+" provider.")
+	cmd.PersistentFlags().String("auth.oidc.aad_claim", oauth.DefaultAADClaim,
+		"claim of the ID tokens that identifies the user for the "+AuthOIDC+" provider.")
+	cmd.PersistentFlags().Int64("auth.cache.ttl_ms", 60000,
+		"time in milliseconds the result of verifying an authentication token is cached. "+
+			"It is capped to 5 minutes. If 0 results are not cached.")
+	cmd.PersistentFlags().Int("auth.cache.max_entries", 10000,
+		"maximum number of verified authentication tokens cached.")
+	cmd.PersistentFlags().String("auth.session.mode", core.SessionModeClient.String(),
+		"how the session key of a request is obtained. Options are "+
+			core.SessionModeClient.String()+" for the key provided by the client, "+
+			core.SessionModeDerived.String()+" for a key derived from the authenticated identity.")
+	cmd.PersistentFlags().String("auth.session.secret", "",
+		"secret used to derive the session keys if auth.session.mode is "+core.SessionModeDerived.String())
+	cmd.PersistentFlags().Int64("auth.session.binding_ttl_ms", 0,
+		"time in milliseconds a client supplied session key stays bound to the user that last used it. "+
+			"Requests from other users with a bound session key are rejected. Sessions are always scoped to the user, "+
+			"so binding only detects clients that share session keys. If 0 session keys are not bound.")
+	cmd.PersistentFlags().Int("auth.session.binding_max_entries", 100000,
+		"maximum number of session keys bound at the same time.")
+	cmd.PersistentFlags().Int("auth.lockout.max_failures", 10,
+		"number of consecutive authentication failures after which a client is locked out. If 0 clients are not locked out.")
+	cmd.PersistentFlags().Int64("auth.lockout.duration_ms", 60000,
+		"time in milliseconds a client that fails to authenticate too many times is locked out.")
+	cmd.PersistentFlags().Int("auth.lockout.max_entries", 100000,
+		"maximum number of clients for which authentication failures are tracked.")
+	cmd.PersistentFlags().String("auth.lockout.client_ip_header", "",
+		"header with the IP of the client when the gateway is behind a proxy, e.g. X-Forwarded-For. "+
+			"If not set the remote address of the connection is used.")
+	cmd.PersistentFlags().String("auth.capability.secret", "",
+		"secret used to sign the poll-only capability tokens issued to users. If not set tokens are not issued.")
+	cmd.PersistentFlags().Int64("auth.capability.max_ttl_ms", 300000,
+		"maximum time in milliseconds for which a capability token is valid.")
+	cmd.PersistentFlags().Int64("auth.replay.window_ms", 0,
+		"maximum difference in milliseconds between the timestamp of a request and the time it is received. "+
+			"If set requests must include a timestamp and a unique nonce. If 0 requests are not protected against replays.")
+	cmd.PersistentFlags().String("auth.replay.provider", ReplayProviderMem,
+		"store for the nonces used by the clients. Options are "+ReplayProviderMem+" and "+ReplayProviderRedis+".")
+	cmd.PersistentFlags().Int("auth.replay.max_entries", 1000000,
+		"maximum number of nonces kept by the "+ReplayProviderMem+" provider. New requests are rejected when it is full.")
+	cmd.PersistentFlags().Int("auth.replay.max_entries_per_aad", 10000,
+		"maximum number of nonces kept by the "+ReplayProviderMem+" provider for the same user. "+
+			"New requests of the user are rejected when it is reached.")
+	cmd.PersistentFlags().StringSlice("auth.replay.redis.addrs", []string{},
+		"addresses of the redis instance or cluster seeds that keep the nonces for the "+ReplayProviderRedis+" provider.")
 	return nil
 }
@@ -0,0 +1,125 @@
+package core
+
+import (
+	"crypto/sha256"
+	"sync"
+	"time"
+
+	"github.com/oasislabs/oasis-gateway/stats"
+)
+
+// SessionBindingProps are the properties used to create
+// a SessionBindings instance
+type SessionBindingProps struct {
+	// TTL is the time a session key stays bound to an AAD after
+	// the last request that used it. If it is 0 session keys are
+	// not bound
+	TTL time.Duration
+
+	// MaxEntries is the maximum number of session keys
+	// bound at the same time
+	MaxEntries int
+}
+
+type sessionBinding struct {
+	aad     string
+	expires time.Time
+}
+
+// SessionBindings keeps the AAD to which each client supplied session
+// key is bound so that a session key that is already in use by an AAD
+// cannot be used by a different one. Sessions are already scoped to the
+// AAD, so bindings do not isolate users further, they only reject the
+// session keys that clients share by mistake. Session keys are stored
+// hashed
+type SessionBindings struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	entries    map[[sha256.Size]byte]sessionBinding
+
+	conflicts stats.Counter
+	evictions stats.Counter
+}
+
+// NewSessionBindings creates a new SessionBindings instance
+func NewSessionBindings(props SessionBindingProps) *SessionBindings {
+	return &SessionBindings{
+		ttl:        props.TTL,
+		maxEntries: props.MaxEntries,
+		entries:    make(map[[sha256.Size]byte]sessionBinding),
+	}
+}
+
+// Enabled returns true if session keys are bound to their AAD
+func (b *SessionBindings) Enabled() bool {
+	return b != nil && b.ttl > 0 && b.maxEntries > 0
+}
+
+// Bind binds the session key to the AAD if it is not bound yet and
+// extends the binding otherwise. It returns false if the session key
+// is bound to a different AAD
+func (b *SessionBindings) Bind(sessionKey, aad string) bool {
+	if !b.Enabled() {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := clock()
+	key := sha256.Sum256([]byte(sessionKey))
+	binding, ok := b.entries[key]
+	if ok && now.Before(binding.expires) && binding.aad != aad {
+		b.conflicts.Incr()
+		return false
+	}
+
+	if !ok && len(b.entries) >= b.maxEntries {
+		b.evict(now)
+	}
+
+	b.entries[key] = sessionBinding{aad: aad, expires: now.Add(b.ttl)}
+	return true
+}
+
+func (b *SessionBindings) evict(now time.Time) {
+	var (
+		oldest    [sha256.Size]byte
+		oldestSet bool
+		expires   time.Time
+	)
+
+	for key, binding := range b.entries {
+		if !now.Before(binding.expires) {
+			delete(b.entries, key)
+			continue
+		}
+
+		if !oldestSet || binding.expires.Before(expires) {
+			oldest, expires, oldestSet = key, binding.expires, true
+		}
+	}
+
+	if len(b.entries) >= b.maxEntries && oldestSet {
+		delete(b.entries, oldest)
+		b.evictions.Incr()
+	}
+}
+
+// Stats returns the metrics of the session bindings
+func (b *SessionBindings) Stats() stats.Metrics {
+	if b == nil {
+		return nil
+	}
+
+	b.mu.Lock()
+	entries := len(b.entries)
+	b.mu.Unlock()
+
+	return stats.Metrics{
+		"entries":   entries,
+		"conflicts": b.conflicts.Value(),
+		"evictions": b.evictions.Value(),
+	}
+}
@@ -0,0 +1,74 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionBindingsDisabled(t *testing.T) {
+	var nilBindings *SessionBindings
+	assert.True(t, nilBindings.Bind("session", "aad1"))
+	assert.True(t, nilBindings.Bind("session", "aad2"))
+
+	bindings := NewSessionBindings(SessionBindingProps{MaxEntries: 10})
+	assert.True(t, bindings.Bind("session", "aad1"))
+	assert.True(t, bindings.Bind("session", "aad2"))
+}
+
+func TestSessionBindingsConflict(t *testing.T) {
+	useFakeClock(t)
+	bindings := NewSessionBindings(SessionBindingProps{
+		TTL:        time.Minute,
+		MaxEntries: 10,
+	})
+
+	assert.True(t, bindings.Bind("session", "aad1"))
+	assert.True(t, bindings.Bind("session", "aad1"))
+	assert.False(t, bindings.Bind("session", "aad2"))
+	assert.True(t, bindings.Bind("other", "aad2"))
+
+	assert.Equal(t, map[string]interface{}{
+		"entries":   2,
+		"conflicts": uint64(1),
+		"evictions": uint64(0),
+	}, map[string]interface{}(bindings.Stats()))
+}
+
+func TestSessionBindingsExpiry(t *testing.T) {
+	fake := useFakeClock(t)
+	bindings := NewSessionBindings(SessionBindingProps{
+		TTL:        time.Minute,
+		MaxEntries: 10,
+	})
+
+	assert.True(t, bindings.Bind("session", "aad1"))
+
+	// every request extends the binding
+	fake.Advance(30 * time.Second)
+	assert.True(t, bindings.Bind("session", "aad1"))
+	fake.Advance(45 * time.Second)
+	assert.False(t, bindings.Bind("session", "aad2"))
+
+	fake.Advance(time.Minute)
+	assert.True(t, bindings.Bind("session", "aad2"))
+}
+
+func TestSessionBindingsEviction(t *testing.T) {
+	fake := useFakeClock(t)
+	bindings := NewSessionBindings(SessionBindingProps{
+		TTL:        time.Minute,
+		MaxEntries: 2,
+	})
+
+	assert.True(t, bindings.Bind("session1", "aad1"))
+	fake.Advance(time.Second)
+	assert.True(t, bindings.Bind("session2", "aad2"))
+	fake.Advance(time.Second)
+	assert.True(t, bindings.Bind("session3", "aad3"))
+
+	// session1 was evicted so it can be bound again
+	assert.True(t, bindings.Bind("session1", "aad3"))
+	assert.Equal(t, uint64(2), bindings.Stats()["evictions"])
+}
@@ -0,0 +1,174 @@
+package core
+
+import (
+	"crypto/sha256"
+	"sync"
+	"time"
+
+	"github.com/oasislabs/oasis-gateway/stats"
+)
+
+// MaxVerificationCacheTTL is the maximum time the result of a token
+// verification is cached regardless of the configured TTL, so that a
+// token that is revoked stops being accepted after at most that time
+const MaxVerificationCacheTTL = 5 * time.Minute
+
+// VerificationCacheProps are the properties used to create
+// a VerificationCache
+type VerificationCacheProps struct {
+	// TTL is the time the result of a verification is cached. It
+	// is capped to MaxVerificationCacheTTL. If it is 0 the cache
+	// does not keep any entries
+	TTL time.Duration
+
+	// MaxEntries is the maximum number of entries the cache
+	// keeps at the same time
+	MaxEntries int
+}
+
+type verificationCacheEntry struct {
+	value   interface{}
+	expires time.Time
+}
+
+// VerificationCache caches the result of verifying a token so that
+// requests authenticated with the same token do not need to verify
+// it again. Entries are keyed by the hash of the token so that the
+// tokens themselves are not kept in memory
+type VerificationCache struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	entries    map[[sha256.Size]byte]verificationCacheEntry
+
+	hits      stats.Counter
+	misses    stats.Counter
+	evictions stats.Counter
+}
+
+// NewVerificationCache creates a new VerificationCache
+func NewVerificationCache(props VerificationCacheProps) *VerificationCache {
+	ttl := props.TTL
+	if ttl > MaxVerificationCacheTTL {
+		ttl = MaxVerificationCacheTTL
+	}
+
+	return &VerificationCache{
+		ttl:        ttl,
+		maxEntries: props.MaxEntries,
+		entries:    make(map[[sha256.Size]byte]verificationCacheEntry),
+	}
+}
+
+// Enabled returns true if the cache keeps entries
+func (c *VerificationCache) Enabled() bool {
+	return c != nil && c.ttl > 0 && c.maxEntries > 0
+}
+
+// Get returns the value cached for the token if it has
+// not expired yet
+func (c *VerificationCache) Get(token string) (interface{}, bool) {
+	if !c.Enabled() {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := sha256.Sum256([]byte(token))
+	entry, ok := c.entries[key]
+	if !ok {
+		c.misses.Incr()
+		return nil, false
+	}
+
+	if !clock().Before(entry.expires) {
+		delete(c.entries, key)
+		c.misses.Incr()
+		return nil, false
+	}
+
+	c.hits.Incr()
+	return entry.value, true
+}
+
+// Set caches the value resulting from verifying the token. If expiry
+// is not zero it is the time at which the token itself expires, and
+// the entry does not outlive it. If the cache is full the expired
+// entries are removed, and if it is still full the entry that
+// expires the soonest is evicted
+func (c *VerificationCache) Set(token string, value interface{}, expiry time.Time) {
+	if !c.Enabled() {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := clock()
+	expires := now.Add(c.ttl)
+	if !expiry.IsZero() && expiry.Before(expires) {
+		expires = expiry
+	}
+
+	if !now.Before(expires) {
+		return
+	}
+
+	key := sha256.Sum256([]byte(token))
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= c.maxEntries {
+		c.evict(now)
+	}
+
+	c.entries[key] = verificationCacheEntry{value: value, expires: expires}
+}
+
+func (c *VerificationCache) evict(now time.Time) {
+	var (
+		soonest    [sha256.Size]byte
+		soonestSet bool
+		expires    time.Time
+	)
+
+	for key, entry := range c.entries {
+		if !now.Before(entry.expires) {
+			delete(c.entries, key)
+			continue
+		}
+
+		if !soonestSet || entry.expires.Before(expires) {
+			soonest, expires, soonestSet = key, entry.expires, true
+		}
+	}
+
+	if len(c.entries) >= c.maxEntries && soonestSet {
+		delete(c.entries, soonest)
+		c.evictions.Incr()
+	}
+}
+
+// Stats returns the metrics of the cache usage
+func (c *VerificationCache) Stats() stats.Metrics {
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	entries := len(c.entries)
+	c.mu.Unlock()
+
+	hits := c.hits.Value()
+	misses := c.misses.Value()
+	hitRate := float64(0)
+	if hits+misses > 0 {
+		hitRate = float64(hits) / float64(hits+misses)
+	}
+
+	return stats.Metrics{
+		"entries":   entries,
+		"hits":      hits,
+		"misses":    misses,
+		"evictions": c.evictions.Value(),
+		"hitRate":   hitRate,
+	}
+}
@@ -0,0 +1,118 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerificationCacheDisabled(t *testing.T) {
+	var nilCache *VerificationCache
+	assert.False(t, nilCache.Enabled())
+	_, ok := nilCache.Get("token")
+	assert.False(t, ok)
+	nilCache.Set("token", "value", time.Time{})
+
+	cache := NewVerificationCache(VerificationCacheProps{MaxEntries: 10})
+	assert.False(t, cache.Enabled())
+	cache.Set("token", "value", time.Time{})
+	_, ok = cache.Get("token")
+	assert.False(t, ok)
+}
+
+func TestVerificationCacheHitMiss(t *testing.T) {
+	useFakeClock(t)
+	cache := NewVerificationCache(VerificationCacheProps{
+		TTL:        time.Minute,
+		MaxEntries: 10,
+	})
+
+	_, ok := cache.Get("token")
+	assert.False(t, ok)
+
+	cache.Set("token", "value", time.Time{})
+	value, ok := cache.Get("token")
+	assert.True(t, ok)
+	assert.Equal(t, "value", value)
+
+	assert.Equal(t, map[string]interface{}{
+		"entries":   1,
+		"hits":      uint64(1),
+		"misses":    uint64(1),
+		"evictions": uint64(0),
+		"hitRate":   float64(0.5),
+	}, map[string]interface{}(cache.Stats()))
+}
+
+func TestVerificationCacheExpiry(t *testing.T) {
+	fake := useFakeClock(t)
+	cache := NewVerificationCache(VerificationCacheProps{
+		TTL:        time.Minute,
+		MaxEntries: 10,
+	})
+
+	cache.Set("token", "value", time.Time{})
+	fake.Advance(time.Minute)
+
+	_, ok := cache.Get("token")
+	assert.False(t, ok)
+}
+
+func TestVerificationCacheTokenExpiry(t *testing.T) {
+	fake := useFakeClock(t)
+	cache := NewVerificationCache(VerificationCacheProps{
+		TTL:        time.Minute,
+		MaxEntries: 10,
+	})
+
+	cache.Set("token", "value", fake.Now().Add(10*time.Second))
+	fake.Advance(10 * time.Second)
+
+	_, ok := cache.Get("token")
+	assert.False(t, ok)
+
+	// tokens that have already expired are not cached
+	cache.Set("expired", "value", fake.Now().Add(-time.Second))
+	_, ok = cache.Get("expired")
+	assert.False(t, ok)
+}
+
+func TestVerificationCacheMaxTTL(t *testing.T) {
+	fake := useFakeClock(t)
+	cache := NewVerificationCache(VerificationCacheProps{
+		TTL:        time.Hour,
+		MaxEntries: 10,
+	})
+
+	cache.Set("token", "value", time.Time{})
+	fake.Advance(MaxVerificationCacheTTL - time.Second)
+	_, ok := cache.Get("token")
+	assert.True(t, ok)
+
+	fake.Advance(time.Second)
+	_, ok = cache.Get("token")
+	assert.False(t, ok)
+}
+
+func TestVerificationCacheEviction(t *testing.T) {
+	fake := useFakeClock(t)
+	cache := NewVerificationCache(VerificationCacheProps{
+		TTL:        time.Minute,
+		MaxEntries: 2,
+	})
+
+	cache.Set("token1", "value1", time.Time{})
+	fake.Advance(time.Second)
+	cache.Set("token2", "value2", time.Time{})
+	fake.Advance(time.Second)
+	cache.Set("token3", "value3", time.Time{})
+
+	_, ok := cache.Get("token1")
+	assert.False(t, ok)
+	_, ok = cache.Get("token2")
+	assert.True(t, ok)
+	_, ok = cache.Get("token3")
+	assert.True(t, ok)
+	assert.Equal(t, uint64(1), cache.Stats()["evictions"])
+}
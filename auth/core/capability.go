@@ -0,0 +1,211 @@
+package core
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	stderr "errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/oasislabs/oasis-gateway/stats"
+)
+
+// RequestHeaderCapabilityToken is the header in which a client
+// presents a capability token instead of its credentials
+const RequestHeaderCapabilityToken string = "X-OASIS-CAPABILITY-TOKEN"
+
+var (
+	ErrCapabilityTokenMissing   = stderr.New("capability token not provided")
+	ErrCapabilityTokenMalformed = stderr.New("capability token is malformed")
+	ErrCapabilityTokenSignature = stderr.New("capability token signature is not valid")
+	ErrCapabilityTokenExpired   = stderr.New("capability token has expired")
+)
+
+// CapabilityProps are the properties used to create
+// a CapabilityIssuer
+type CapabilityProps struct {
+	// Secret is the key used to sign the capability tokens
+	Secret []byte
+
+	// MaxTTL is the maximum time for which a capability
+	// token is valid
+	MaxTTL time.Duration
+}
+
+// capabilityClaims is the content of a capability token
+type capabilityClaims struct {
+	AAD      string  `json:"aad"`
+	Session  string  `json:"session"`
+	Scopes   []Scope `json:"scopes"`
+	Expiry   int64   `json:"exp"`
+	IssuedAt int64   `json:"iat"`
+}
+
+// CapabilityIssuer mints and verifies short-lived capability tokens
+// that grant a subset of the scopes on the session of the identity
+// that requested them, so that the primary credential of the
+// identity does not need to be shared
+type CapabilityIssuer struct {
+	secret []byte
+	maxTTL time.Duration
+
+	issued   stats.Counter
+	verified stats.Counter
+	rejected stats.Counter
+}
+
+// NewCapabilityIssuer creates a new CapabilityIssuer
+func NewCapabilityIssuer(props CapabilityProps) *CapabilityIssuer {
+	if len(props.Secret) == 0 {
+		panic("secret must be set")
+	}
+
+	if props.MaxTTL <= 0 {
+		panic("max ttl must be positive")
+	}
+
+	return &CapabilityIssuer{
+		secret: props.Secret,
+		maxTTL: props.MaxTTL,
+	}
+}
+
+// Issue mints a token that grants the scopes on the session of the AAD.
+// The token is valid for ttl, or for the maximum TTL if ttl is not set
+// or exceeds it. It returns the token and the time at which it expires
+func (c *CapabilityIssuer) Issue(aad, session string, ttl time.Duration, scopes ...Scope) (string, time.Time, error) {
+	if ttl <= 0 || ttl > c.maxTTL {
+		ttl = c.maxTTL
+	}
+
+	now := clock()
+	expiry := now.Add(ttl).Truncate(time.Second)
+	payload, err := json.Marshal(capabilityClaims{
+		AAD:      aad,
+		Session:  session,
+		Scopes:   scopes,
+		Expiry:   expiry.Unix(),
+		IssuedAt: now.Unix(),
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	c.issued.Incr()
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + c.sign(encoded), expiry, nil
+}
+
+func (c *CapabilityIssuer) sign(encoded string) string {
+	mac := hmac.New(sha256.New, c.secret)
+	_, _ = mac.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (c *CapabilityIssuer) verify(token string) (capabilityClaims, error) {
+	var claims capabilityClaims
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return claims, ErrCapabilityTokenMalformed
+	}
+
+	if !hmac.Equal([]byte(parts[1]), []byte(c.sign(parts[0]))) {
+		return claims, ErrCapabilityTokenSignature
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return claims, ErrCapabilityTokenMalformed
+	}
+
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return claims, ErrCapabilityTokenMalformed
+	}
+
+	if !clock().Before(time.Unix(claims.Expiry, 0)) {
+		return claims, ErrCapabilityTokenExpired
+	}
+
+	return claims, nil
+}
+
+// Stats returns the metrics of the issuer
+func (c *CapabilityIssuer) Stats() stats.Metrics {
+	return stats.Metrics{
+		"issued":   c.issued.Value(),
+		"verified": c.verified.Value(),
+		"rejected": c.rejected.Value(),
+	}
+}
+
+// CapabilityAuth authenticates the requests that present a
+// capability token minted by a CapabilityIssuer. The request is
+// authenticated as the AAD that requested the token, on its
+// session, and is only granted the scopes of the token
+type CapabilityAuth struct {
+	issuer *CapabilityIssuer
+}
+
+// NewCapabilityAuth creates a new CapabilityAuth that
+// accepts the tokens minted by the issuer
+func NewCapabilityAuth(issuer *CapabilityIssuer) *CapabilityAuth {
+	if issuer == nil {
+		panic("issuer must be set")
+	}
+
+	return &CapabilityAuth{issuer: issuer}
+}
+
+// Name is the implementation of Auth.Name for CapabilityAuth
+func (a *CapabilityAuth) Name() string {
+	return "auth.CapabilityAuth"
+}
+
+// Stats is the implementation of Auth.Stats for CapabilityAuth
+func (a *CapabilityAuth) Stats() stats.Metrics {
+	return stats.Metrics{"capability": a.issuer.Stats()}
+}
+
+// Authenticate is the implementation of Auth.Authenticate for CapabilityAuth
+func (a *CapabilityAuth) Authenticate(req *http.Request) (*http.Request, error) {
+	token := req.Header.Get(RequestHeaderCapabilityToken)
+	if len(token) == 0 {
+		return req, ErrCapabilityTokenMissing
+	}
+
+	claims, err := a.issuer.verify(token)
+	if err != nil {
+		a.issuer.rejected.Incr()
+		return req, err
+	}
+
+	a.issuer.verified.Incr()
+	ctx := context.WithValue(req.Context(), AAD{}, claims.AAD)
+	ctx = context.WithValue(ctx, SessionKey{}, claims.Session)
+	return WithScopes(req.WithContext(ctx), claims.Scopes...), nil
+}
+
+// ExemptsReplay is the implementation of ReplayExempter for CapabilityAuth.
+// A token is meant to be used to poll repeatedly until it expires, which
+// is soon after it is issued, and it only grants access to APIs that are
+// safe to repeat, so the requests it authenticates are not checked for
+// replays
+func (a *CapabilityAuth) ExemptsReplay() bool {
+	return true
+}
+
+// Verify is the implementation of Auth.Verify for CapabilityAuth. Capability
+// tokens do not grant access to the APIs that require verification
+func (a *CapabilityAuth) Verify(ctx context.Context, req AuthRequest) error {
+	return stderr.New("requests authenticated with a capability token cannot be verified")
+}
+
+// SetLogger is the implementation of Auth.SetLogger for CapabilityAuth
+func (a *CapabilityAuth) SetLogger(log.Logger) {
+}
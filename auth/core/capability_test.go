@@ -0,0 +1,118 @@
+package core
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/oasislabs/oasis-gateway/rpc"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestCapabilityIssuer() *CapabilityIssuer {
+	return NewCapabilityIssuer(CapabilityProps{
+		Secret: []byte("secret"),
+		MaxTTL: time.Minute,
+	})
+}
+
+func newCapabilityRequest(t *testing.T, token string) *http.Request {
+	req, err := http.NewRequest("POST", "/", nil)
+	assert.Nil(t, err)
+	req.Header.Add(RequestHeaderCapabilityToken, token)
+	return req
+}
+
+func TestCapabilityIssueMaxTTL(t *testing.T) {
+	fake := useFakeClock(t)
+	issuer := newTestCapabilityIssuer()
+
+	_, expiry, err := issuer.Issue("aad", "session", time.Hour, ScopeEventPoll)
+	assert.Nil(t, err)
+	assert.Equal(t, fake.Now().Add(time.Minute), expiry)
+
+	_, expiry, err = issuer.Issue("aad", "session", 0, ScopeEventPoll)
+	assert.Nil(t, err)
+	assert.Equal(t, fake.Now().Add(time.Minute), expiry)
+
+	_, expiry, err = issuer.Issue("aad", "session", 10*time.Second, ScopeEventPoll)
+	assert.Nil(t, err)
+	assert.Equal(t, fake.Now().Add(10*time.Second), expiry)
+}
+
+func TestCapabilityAuthAuthenticate(t *testing.T) {
+	useFakeClock(t)
+	issuer := newTestCapabilityIssuer()
+	token, _, err := issuer.Issue("aad", "session", 0, ScopeEventPoll)
+	assert.Nil(t, err)
+
+	req, err := NewCapabilityAuth(issuer).Authenticate(newCapabilityRequest(t, token))
+	assert.Nil(t, err)
+	assert.Equal(t, "aad", req.Context().Value(AAD{}))
+	assert.Equal(t, "session", req.Context().Value(SessionKey{}))
+	assert.True(t, HasScope(req.Context(), ScopeEventPoll))
+	assert.False(t, HasScope(req.Context(), ScopeServiceExecute))
+	assert.False(t, HasScope(req.Context(), ScopeTokenIssue))
+}
+
+func TestCapabilityAuthAuthenticateMissing(t *testing.T) {
+	useFakeClock(t)
+	issuer := newTestCapabilityIssuer()
+
+	_, err := NewCapabilityAuth(issuer).Authenticate(newCapabilityRequest(t, ""))
+	assert.Equal(t, ErrCapabilityTokenMissing, err)
+}
+
+func TestCapabilityAuthAuthenticateExpired(t *testing.T) {
+	fake := useFakeClock(t)
+	issuer := newTestCapabilityIssuer()
+	token, _, err := issuer.Issue("aad", "session", 0, ScopeEventPoll)
+	assert.Nil(t, err)
+
+	fake.Advance(time.Minute)
+	_, err = NewCapabilityAuth(issuer).Authenticate(newCapabilityRequest(t, token))
+	assert.Equal(t, ErrCapabilityTokenExpired, err)
+}
+
+func TestCapabilityAuthAuthenticateTampered(t *testing.T) {
+	useFakeClock(t)
+	issuer := newTestCapabilityIssuer()
+	token, _, err := issuer.Issue("aad", "session", 0, ScopeEventPoll)
+	assert.Nil(t, err)
+
+	other, _, err := issuer.Issue("other", "session", 0, ScopeEventPoll)
+	assert.Nil(t, err)
+
+	// the payload of a token with the signature of another one
+	_, err = NewCapabilityAuth(issuer).Authenticate(
+		newCapabilityRequest(t, token[:len(token)-43]+other[len(other)-43:]))
+	assert.Equal(t, ErrCapabilityTokenSignature, err)
+
+	_, err = NewCapabilityAuth(issuer).Authenticate(newCapabilityRequest(t, "token"))
+	assert.Equal(t, ErrCapabilityTokenMalformed, err)
+
+	assert.Equal(t, uint64(2), issuer.Stats()["rejected"])
+}
+
+func TestServeHTTPCapabilitySession(t *testing.T) {
+	useFakeClock(t)
+	issuer := newTestCapabilityIssuer()
+	token, _, err := issuer.Issue("nil", "session", 0, ScopeEventPoll)
+	assert.Nil(t, err)
+
+	var session interface{}
+	handler := NewHttpMiddlewareAuth(NewCapabilityAuth(issuer), Logger,
+		rpc.HttpMiddlewareFunc(func(req *http.Request) (interface{}, error) {
+			session = req.Context().Value(Session{})
+			return 0, nil
+		}))
+
+	// the session of the token is used even if the
+	// client provides a different one
+	req := newCapabilityRequest(t, token)
+	req.Header.Add(RequestHeaderSessionKey, "other")
+
+	_, err = handler.ServeHTTP(req)
+	assert.Nil(t, err)
+	assert.Equal(t, "5da3a4c7f117944275b4c8629c4916403625d5a4a6573a01ecb03f0e9d2edbe6:session", session)
+}
@@ -0,0 +1,9 @@
+package core
+
+import "time"
+
+// clock returns the current time to the types of the package that keep
+// state that expires, like the lockouts, the session bindings, the
+// verification cache, the capabilities and the replay guard, so that
+// the tests control the time of all of them in one place
+var clock = time.Now
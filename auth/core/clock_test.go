@@ -0,0 +1,38 @@
+package core
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is the clock of the package in the tests. It starts at a
+// fixed time and only moves forward when the test advances it
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// useFakeClock replaces the clock of the package with a fakeClock
+// until the test completes
+func useFakeClock(t *testing.T) *fakeClock {
+	fake := &fakeClock{now: time.Unix(1000, 0)}
+	prev := clock
+	clock = fake.Now
+	t.Cleanup(func() { clock = prev })
+	return fake
+}
+
+// Now returns the current time of the clock
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward by d
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
@@ -6,43 +6,96 @@ import (
 	"encoding/hex"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/oasislabs/oasis-gateway/errors"
 	"github.com/oasislabs/oasis-gateway/log"
 	"github.com/oasislabs/oasis-gateway/rpc"
+	"github.com/oasislabs/oasis-gateway/slo"
 )
 
 type AAD struct{}
 type Session struct{}
 
+// SessionKey is the key used to store in the context of a request the
+// session key of the request before it is scoped to the AAD. An Auth
+// that sets it in Authenticate overrides the session key of the request
+type SessionKey struct{}
+
 const (
 	sessionKeyFormat               = "%s:%s"
 	RequestHeaderSessionKey string = "X-OASIS-SESSION-KEY"
 )
 
 type HttpMiddlewareAuth struct {
-	auth   Auth
-	logger log.Logger
-	next   rpc.HttpMiddleware
+	auth     Auth
+	logger   log.Logger
+	sessions SessionProps
+	lockout  *Lockout
+	replay   *ReplayGuard
+	next     rpc.HttpMiddleware
+}
+
+// HttpMiddlewareAuthProps are the properties used to
+// create an HttpMiddlewareAuth
+type HttpMiddlewareAuthProps struct {
+	// Auth authenticates the requests
+	Auth Auth
+
+	// Logger is the logger used by the middleware
+	Logger log.Logger
+
+	// Sessions defines how the session key of the
+	// requests is obtained
+	Sessions SessionProps
+
+	// Lockout if set locks out the clients that fail to
+	// authenticate too many times
+	Lockout *Lockout
+
+	// Replay if set rejects the requests that are stale
+	// or that reuse a nonce
+	Replay *ReplayGuard
+
+	// Next is the middleware to which the authenticated
+	// requests are forwarded
+	Next rpc.HttpMiddleware
 }
 
 func NewHttpMiddlewareAuth(auth Auth, logger log.Logger, next rpc.HttpMiddleware) *HttpMiddlewareAuth {
-	if auth == nil {
+	return NewHttpMiddlewareAuthWithProps(HttpMiddlewareAuthProps{
+		Auth:     auth,
+		Logger:   logger,
+		Sessions: SessionProps{Mode: SessionModeClient},
+		Next:     next,
+	})
+}
+
+// NewHttpMiddlewareAuthWithProps creates a new HttpMiddlewareAuth
+func NewHttpMiddlewareAuthWithProps(props HttpMiddlewareAuthProps) *HttpMiddlewareAuth {
+	if props.Auth == nil {
 		panic("auth must be set")
 	}
 
-	if logger == nil {
+	if props.Logger == nil {
 		panic("log must be set")
 	}
 
-	if next == nil {
+	if props.Next == nil {
 		panic("next must be set")
 	}
 
+	if props.Sessions.Mode == SessionModeDerived && len(props.Sessions.Secret) == 0 {
+		panic("secret must be set to derive session keys")
+	}
+
 	return &HttpMiddlewareAuth{
-		auth:   auth,
-		logger: logger.ForClass("auth", "HttpMiddlewareAuth"),
-		next:   next,
+		auth:     props.Auth,
+		logger:   props.Logger.ForClass("auth", "HttpMiddlewareAuth"),
+		sessions: props.Sessions,
+		lockout:  props.Lockout,
+		replay:   props.Replay,
+		next:     props.Next,
 	}
 }
 
@@ -55,9 +108,37 @@ func MustGetAAD(ctx context.Context) string {
 	return value.(string)
 }
 
+func (m *HttpMiddlewareAuth) lockedOut(ctx context.Context, key string) *rpc.HttpError {
+	until, locked := m.lockout.Locked(key)
+	if !locked {
+		return nil
+	}
+
+	return rpc.HttpTooManyRequests(ctx, errors.New(errors.ErrAuthenticationLockout,
+		fmt.Errorf("client locked out until %s", until.UTC().Format(time.RFC3339))))
+}
+
+func (m *HttpMiddlewareAuth) fail(ctx context.Context, key string) {
+	if m.lockout.Fail(key) {
+		m.logger.Warn(ctx, "client locked out after consecutive authentication failures", log.MapFields{
+			"call_type": "AuthenticateLockout",
+			"client":    key,
+		})
+	}
+}
+
 func (m *HttpMiddlewareAuth) ServeHTTP(req *http.Request) (interface{}, error) {
+	ctx := req.Context()
+	clientKey := m.lockout.ClientKey(req)
+	if err := m.lockedOut(ctx, clientKey); err != nil {
+		return nil, err
+	}
+
+	authenticated := slo.GetStages(ctx).Track("auth")
 	req, err := m.auth.Authenticate(req)
+	authenticated()
 	if err != nil {
+		m.fail(ctx, clientKey)
 		newErr := errors.New(errors.ErrAuthenticateRequest, err)
 		return nil, &rpc.HttpError{
 			Cause:      &newErr,
@@ -65,7 +146,17 @@ func (m *HttpMiddlewareAuth) ServeHTTP(req *http.Request) (interface{}, error) {
 		}
 	}
 
-	sessionKey := req.Header.Get(RequestHeaderSessionKey)
+	expectedAAD := MustGetAAD(req.Context())
+
+	var sessionKey string
+	if value := req.Context().Value(SessionKey{}); value != nil {
+		sessionKey = value.(string)
+	} else if m.sessions.Mode == SessionModeDerived {
+		sessionKey = DeriveSessionKey(m.sessions.Secret, expectedAAD)
+	} else {
+		sessionKey = req.Header.Get(RequestHeaderSessionKey)
+	}
+
 	if len(sessionKey) == 0 {
 		newErr := errors.New(errors.ErrAuthenticateRequest, fmt.Errorf("no %s header provided", RequestHeaderSessionKey))
 		return nil, &rpc.HttpError{
@@ -74,14 +165,40 @@ func (m *HttpMiddlewareAuth) ServeHTTP(req *http.Request) (interface{}, error) {
 		}
 	}
 
-	expectedAAD := MustGetAAD(req.Context())
 	hasher := sha256.New()
 	if _, err = hasher.Write([]byte(expectedAAD)); err != nil {
 		return nil, rpc.HttpForbidden(context.TODO(), errors.New(errors.ErrInvalidAAD, err))
 	}
 
 	aadHash := hex.EncodeToString(hasher.Sum(nil))
+	m.lockout.Succeed(clientKey)
+
+	aadKey := "aad:" + aadHash
+	if err := m.lockedOut(req.Context(), aadKey); err != nil {
+		return nil, err
+	}
+
+	// whether the timestamp and nonce of the request are signed
+	// depends on the provider that authenticated it
+	if provider := AuthenticatedBy(m.auth, req); !exemptsReplay(provider) {
+		if err := m.replay.Verify(req, aadHash, signsReplay(provider)); err != nil {
+			if _, ok := err.(ErrNonceStore); ok {
+				return nil, rpc.HttpInternalServerError(req.Context(), errors.New(errors.ErrNonceStore, err))
+			}
+			return nil, rpc.HttpForbidden(req.Context(), errors.New(errors.ErrReplayedRequest, err))
+		}
+	}
+
+	// the session is scoped to the AAD, so a session key bound to another
+	// user gives no access to its session. The conflict is not counted as
+	// an authentication failure, otherwise a user who learns the session
+	// key of another could get that user locked out by binding it first
+	if m.sessions.Mode != SessionModeDerived && !m.sessions.Bindings.Bind(sessionKey, aadHash) {
+		return nil, rpc.HttpForbidden(req.Context(), errors.New(errors.ErrSessionKeyBound,
+			fmt.Errorf("session key is bound to a different AAD")))
+	}
 
-	req = req.WithContext(context.WithValue(req.Context(), Session{}, fmt.Sprintf(sessionKeyFormat, aadHash, sessionKey)))
+	ctx = context.WithValue(req.Context(), SessionKey{}, sessionKey)
+	req = req.WithContext(context.WithValue(ctx, Session{}, fmt.Sprintf(sessionKeyFormat, aadHash, sessionKey)))
 	return m.next.ServeHTTP(req)
 }
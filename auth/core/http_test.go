@@ -4,6 +4,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/oasislabs/oasis-gateway/log"
 	"github.com/oasislabs/oasis-gateway/rpc"
@@ -31,3 +32,107 @@ func TestServeHTTP(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, 0, res)
 }
+
+func TestServeHTTPDerivedSession(t *testing.T) {
+	auth := &NilAuth{}
+	sessions := SessionProps{Mode: SessionModeDerived, Secret: []byte("secret")}
+
+	var session interface{}
+	handler := NewHttpMiddlewareAuthWithProps(HttpMiddlewareAuthProps{
+		Auth:     auth,
+		Logger:   Logger,
+		Sessions: sessions,
+		Next: rpc.HttpMiddlewareFunc(func(req *http.Request) (interface{}, error) {
+			session = req.Context().Value(Session{})
+			return 0, nil
+		}),
+	})
+
+	// the session key provided by the client is ignored
+	for _, key := range []string{"", "session"} {
+		req, err := http.NewRequest("GET", "/", nil)
+		assert.Nil(t, err)
+		if len(key) > 0 {
+			req.Header.Add(RequestHeaderSessionKey, key)
+		}
+
+		res, err := handler.ServeHTTP(req)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, res)
+		assert.Equal(t, "5da3a4c7f117944275b4c8629c4916403625d5a4a6573a01ecb03f0e9d2edbe6:"+
+			"8ccacb16930bc115ed06dd900972a32a30bcf51612f22d3a2422ede3b2090b0f", session)
+	}
+}
+
+func TestDeriveSessionKey(t *testing.T) {
+	assert.Equal(t, DeriveSessionKey([]byte("secret"), "aad"), DeriveSessionKey([]byte("secret"), "aad"))
+	assert.NotEqual(t, DeriveSessionKey([]byte("secret"), "aad"), DeriveSessionKey([]byte("secret"), "other"))
+	assert.NotEqual(t, DeriveSessionKey([]byte("secret"), "aad"), DeriveSessionKey([]byte("other"), "aad"))
+}
+
+func TestServeHTTPSessionBound(t *testing.T) {
+	bindings := NewSessionBindings(SessionBindingProps{TTL: time.Minute, MaxEntries: 10})
+	assert.True(t, bindings.Bind("session", "otherAADHash"))
+
+	handler := NewHttpMiddlewareAuthWithProps(HttpMiddlewareAuthProps{
+		Auth:     &NilAuth{},
+		Logger:   Logger,
+		Sessions: SessionProps{Mode: SessionModeClient, Bindings: bindings},
+		Next: rpc.HttpMiddlewareFunc(func(req *http.Request) (interface{}, error) {
+			return 0, nil
+		}),
+	})
+
+	req, err := http.NewRequest("GET", "/", nil)
+	assert.Nil(t, err)
+	req.Header.Add(RequestHeaderSessionKey, "session")
+
+	res, err := handler.ServeHTTP(req)
+	assert.Nil(t, res)
+	assert.Equal(t, http.StatusForbidden, err.(*rpc.HttpError).StatusCode)
+	assert.Equal(t, 7007, err.(*rpc.HttpError).Cause.ErrorCode().Code())
+
+	req, err = http.NewRequest("GET", "/", nil)
+	assert.Nil(t, err)
+	req.Header.Add(RequestHeaderSessionKey, "session2")
+
+	res, err = handler.ServeHTTP(req)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, res)
+}
+
+func TestServeHTTPSessionBoundNotLockedOut(t *testing.T) {
+	bindings := NewSessionBindings(SessionBindingProps{TTL: time.Minute, MaxEntries: 10})
+	assert.True(t, bindings.Bind("session", "otherAADHash"))
+
+	lockout := NewLockout(LockoutProps{MaxFailures: 1, Duration: time.Minute, MaxEntries: 10})
+	handler := NewHttpMiddlewareAuthWithProps(HttpMiddlewareAuthProps{
+		Auth:     &NilAuth{},
+		Logger:   Logger,
+		Sessions: SessionProps{Mode: SessionModeClient, Bindings: bindings},
+		Lockout:  lockout,
+		Next: rpc.HttpMiddlewareFunc(func(req *http.Request) (interface{}, error) {
+			return 0, nil
+		}),
+	})
+
+	// a session key bound by another user is rejected without
+	// counting toward the lockout of the user
+	for i := 0; i < 2; i++ {
+		req, err := http.NewRequest("GET", "/", nil)
+		assert.Nil(t, err)
+		req.Header.Add(RequestHeaderSessionKey, "session")
+
+		_, err = handler.ServeHTTP(req)
+		assert.Equal(t, 7007, err.(*rpc.HttpError).Cause.ErrorCode().Code())
+	}
+
+	req, err := http.NewRequest("GET", "/", nil)
+	assert.Nil(t, err)
+	req.Header.Add(RequestHeaderSessionKey, "session2")
+
+	res, err := handler.ServeHTTP(req)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, res)
+	assert.Equal(t, uint64(0), lockout.Stats()["failures"])
+}
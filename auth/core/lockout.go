@@ -0,0 +1,198 @@
+package core
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oasislabs/oasis-gateway/stats"
+)
+
+// LockoutProps are the properties used to create a Lockout
+type LockoutProps struct {
+	// MaxFailures is the number of consecutive authentication
+	// failures after which a client is locked out. If it is 0
+	// clients are never locked out
+	MaxFailures int
+
+	// Duration is the time a client stays locked out. It is
+	// also the time after which the failures of a client that
+	// has not failed again are forgotten
+	Duration time.Duration
+
+	// MaxEntries is the maximum number of clients tracked
+	// at the same time
+	MaxEntries int
+
+	// ClientIPHeader if set is the header from which the IP of the
+	// client is read, for deployments behind a proxy. Otherwise
+	// the remote address of the connection is used
+	ClientIPHeader string
+}
+
+type lockoutEntry struct {
+	failures    int
+	lockedUntil time.Time
+	expires     time.Time
+}
+
+// Lockout tracks the consecutive authentication failures of the
+// clients and locks out the clients that fail too many times, so
+// that credentials cannot be guessed online
+type Lockout struct {
+	mu      sync.Mutex
+	props   LockoutProps
+	entries map[string]lockoutEntry
+
+	failures  stats.Counter
+	lockouts  stats.Counter
+	rejected  stats.Counter
+	evictions stats.Counter
+}
+
+// NewLockout creates a new Lockout instance
+func NewLockout(props LockoutProps) *Lockout {
+	return &Lockout{
+		props:   props,
+		entries: make(map[string]lockoutEntry),
+	}
+}
+
+// Enabled returns true if clients can be locked out
+func (l *Lockout) Enabled() bool {
+	return l != nil && l.props.MaxFailures > 0 && l.props.Duration > 0 && l.props.MaxEntries > 0
+}
+
+// ClientKey returns the key that identifies the client
+// that issued the request
+func (l *Lockout) ClientKey(req *http.Request) string {
+	if !l.Enabled() {
+		return ""
+	}
+
+	if len(l.props.ClientIPHeader) > 0 {
+		// proxies append the address of the client to the
+		// header, so the first address is the original client
+		value := req.Header.Get(l.props.ClientIPHeader)
+		if ip := strings.TrimSpace(strings.Split(value, ",")[0]); len(ip) > 0 {
+			return ip
+		}
+	}
+
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+
+	return host
+}
+
+// Locked returns the time until which the client is locked
+// out and true if it is currently locked out
+func (l *Lockout) Locked(key string) (time.Time, bool) {
+	if !l.Enabled() {
+		return time.Time{}, false
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry, ok := l.entries[key]
+	if !ok || !clock().Before(entry.lockedUntil) {
+		return time.Time{}, false
+	}
+
+	l.rejected.Incr()
+	return entry.lockedUntil, true
+}
+
+// Fail records an authentication failure for the client and
+// returns true if the client has been locked out as a result
+func (l *Lockout) Fail(key string) bool {
+	if !l.Enabled() {
+		return false
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := clock()
+	entry, ok := l.entries[key]
+	if !ok || !now.Before(entry.expires) {
+		if !ok && len(l.entries) >= l.props.MaxEntries {
+			l.evict(now)
+		}
+		entry = lockoutEntry{}
+	}
+
+	l.failures.Incr()
+	entry.failures++
+	entry.expires = now.Add(l.props.Duration)
+
+	locked := entry.failures >= l.props.MaxFailures
+	if locked {
+		l.lockouts.Incr()
+		entry.failures = 0
+		entry.lockedUntil = entry.expires
+	}
+
+	l.entries[key] = entry
+	return locked
+}
+
+// Succeed resets the failures of the client
+func (l *Lockout) Succeed(key string) {
+	if !l.Enabled() {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.entries, key)
+}
+
+func (l *Lockout) evict(now time.Time) {
+	var (
+		oldest    string
+		oldestSet bool
+		expires   time.Time
+	)
+
+	for key, entry := range l.entries {
+		if !now.Before(entry.expires) {
+			delete(l.entries, key)
+			continue
+		}
+
+		if !oldestSet || entry.expires.Before(expires) {
+			oldest, expires, oldestSet = key, entry.expires, true
+		}
+	}
+
+	if len(l.entries) >= l.props.MaxEntries && oldestSet {
+		delete(l.entries, oldest)
+		l.evictions.Incr()
+	}
+}
+
+// Stats returns the metrics of the lockout
+func (l *Lockout) Stats() stats.Metrics {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	entries := len(l.entries)
+	l.mu.Unlock()
+
+	return stats.Metrics{
+		"entries":   entries,
+		"failures":  l.failures.Value(),
+		"lockouts":  l.lockouts.Value(),
+		"rejected":  l.rejected.Value(),
+		"evictions": l.evictions.Value(),
+	}
+}
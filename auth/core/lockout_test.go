@@ -0,0 +1,123 @@
+package core
+
+import (
+	stderr "errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/oasislabs/oasis-gateway/rpc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLockoutDisabled(t *testing.T) {
+	var nilLockout *Lockout
+	assert.False(t, nilLockout.Fail("client"))
+	_, locked := nilLockout.Locked("client")
+	assert.False(t, locked)
+	nilLockout.Succeed("client")
+}
+
+func TestLockoutFail(t *testing.T) {
+	fake := useFakeClock(t)
+	lockout := NewLockout(LockoutProps{
+		MaxFailures: 3,
+		Duration:    time.Minute,
+		MaxEntries:  10,
+	})
+
+	assert.False(t, lockout.Fail("client"))
+	assert.False(t, lockout.Fail("client"))
+	assert.True(t, lockout.Fail("client"))
+
+	until, locked := lockout.Locked("client")
+	assert.True(t, locked)
+	assert.Equal(t, fake.Now().Add(time.Minute), until)
+
+	_, locked = lockout.Locked("other")
+	assert.False(t, locked)
+
+	fake.Advance(time.Minute)
+	_, locked = lockout.Locked("client")
+	assert.False(t, locked)
+
+	assert.Equal(t, map[string]interface{}{
+		"entries":   1,
+		"failures":  uint64(3),
+		"lockouts":  uint64(1),
+		"rejected":  uint64(1),
+		"evictions": uint64(0),
+	}, map[string]interface{}(lockout.Stats()))
+}
+
+func TestLockoutSucceedResets(t *testing.T) {
+	useFakeClock(t)
+	lockout := NewLockout(LockoutProps{
+		MaxFailures: 2,
+		Duration:    time.Minute,
+		MaxEntries:  10,
+	})
+
+	assert.False(t, lockout.Fail("client"))
+	lockout.Succeed("client")
+	assert.False(t, lockout.Fail("client"))
+}
+
+func TestLockoutFailuresExpire(t *testing.T) {
+	fake := useFakeClock(t)
+	lockout := NewLockout(LockoutProps{
+		MaxFailures: 2,
+		Duration:    time.Minute,
+		MaxEntries:  10,
+	})
+
+	assert.False(t, lockout.Fail("client"))
+	fake.Advance(time.Minute)
+	assert.False(t, lockout.Fail("client"))
+}
+
+func TestLockoutClientKey(t *testing.T) {
+	req, err := http.NewRequest("GET", "/", nil)
+	assert.Nil(t, err)
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.Header.Set("X-Forwarded-For", "192.168.0.1, 10.0.0.2")
+
+	lockout := NewLockout(LockoutProps{MaxFailures: 1, Duration: time.Minute, MaxEntries: 10})
+	assert.Equal(t, "10.0.0.1", lockout.ClientKey(req))
+
+	lockout = NewLockout(LockoutProps{
+		MaxFailures:    1,
+		Duration:       time.Minute,
+		MaxEntries:     10,
+		ClientIPHeader: "X-Forwarded-For",
+	})
+	assert.Equal(t, "192.168.0.1", lockout.ClientKey(req))
+}
+
+type failingAuth struct {
+	NilAuth
+}
+
+func (a *failingAuth) Authenticate(req *http.Request) (*http.Request, error) {
+	return req, stderr.New("authentication failed")
+}
+
+func TestServeHTTPLockout(t *testing.T) {
+	handler := NewHttpMiddlewareAuthWithProps(HttpMiddlewareAuthProps{
+		Auth:    &failingAuth{},
+		Logger:  Logger,
+		Lockout: NewLockout(LockoutProps{MaxFailures: 2, Duration: time.Minute, MaxEntries: 10}),
+		Next: rpc.HttpMiddlewareFunc(func(req *http.Request) (interface{}, error) {
+			return 0, nil
+		}),
+	})
+
+	for _, status := range []int{http.StatusForbidden, http.StatusForbidden, http.StatusTooManyRequests} {
+		req, err := http.NewRequest("GET", "/", nil)
+		assert.Nil(t, err)
+		req.RemoteAddr = "10.0.0.1:1234"
+
+		_, err = handler.ServeHTTP(req)
+		assert.Equal(t, status, err.(*rpc.HttpError).StatusCode)
+	}
+}
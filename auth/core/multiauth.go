@@ -48,6 +48,23 @@ func (m *MultiAuth) Authenticate(req *http.Request) (*http.Request, error) {
 	return req, MultiError{Errors: errs}
 }
 
+// AuthenticatedBy returns the Auth that authenticated the request. If
+// auth is a MultiAuth it is the Auth that accepted the credentials of
+// the request, or nil if none did
+func AuthenticatedBy(auth Auth, req *http.Request) Auth {
+	for {
+		multi, ok := auth.(*MultiAuth)
+		if !ok {
+			return auth
+		}
+
+		auth, ok = req.Context().Value(multi).(Auth)
+		if !ok {
+			return nil
+		}
+	}
+}
+
 func (m *MultiAuth) Verify(ctx context.Context, data AuthRequest) error {
 	auth := ctx.Value(m)
 	if auth == nil {
@@ -0,0 +1,330 @@
+package core
+
+import (
+	"context"
+	stderr "errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/oasislabs/oasis-gateway/stats"
+)
+
+const (
+	// RequestHeaderTimestamp is the header with the time in
+	// milliseconds since the epoch at which the client issued
+	// the request
+	RequestHeaderTimestamp string = "X-OASIS-TIMESTAMP"
+
+	// RequestHeaderNonce is the header with the value that the
+	// client generates to make each request unique
+	RequestHeaderNonce string = "X-OASIS-NONCE"
+
+	// MinNonceLength is the minimum length of a nonce
+	MinNonceLength = 16
+
+	// MaxNonceLength is the maximum length of a nonce
+	MaxNonceLength = 128
+)
+
+// ReplayTimestamp and ReplayNonce are the keys used to store in the
+// context of a request its timestamp and nonce. An Auth that sets them
+// in Authenticate overrides the headers, so that the values checked
+// are the ones covered by the signature of the credentials
+type ReplayTimestamp struct{}
+type ReplayNonce struct{}
+
+// ReplaySigner is implemented by the Auth implementations whose
+// credentials are signed and carry the timestamp and nonce of the
+// request, which they set in the context on Authenticate
+type ReplaySigner interface {
+	SignsReplay() bool
+}
+
+// ReplayExempter is implemented by the Auth implementations whose
+// credentials are short-lived and only grant access to APIs that
+// are safe to repeat, so the requests they authenticate are not
+// checked for replays
+type ReplayExempter interface {
+	ExemptsReplay() bool
+}
+
+// signsReplay returns true if the Auth signs the timestamp
+// and nonce of the requests it authenticates
+func signsReplay(auth Auth) bool {
+	signer, ok := auth.(ReplaySigner)
+	return ok && signer.SignsReplay()
+}
+
+// exemptsReplay returns true if the requests authenticated
+// by the Auth are not checked for replays
+func exemptsReplay(auth Auth) bool {
+	exempter, ok := auth.(ReplayExempter)
+	return ok && exempter.ExemptsReplay()
+}
+
+var (
+	ErrRequestTimestamp = stderr.New("request timestamp is outside the accepted window")
+	ErrRequestReplayed  = stderr.New("request nonce has already been used")
+	ErrRequestUnsigned  = stderr.New("request timestamp and nonce are not covered by the credentials")
+	ErrNonceStoreFull   = stderr.New("nonce store is full")
+	ErrNonceScopeFull   = stderr.New("too many nonces in use by the same AAD")
+)
+
+// ErrNonceStore is returned by the ReplayGuard when the NonceStore
+// fails, in which case the request cannot be verified
+type ErrNonceStore struct {
+	Cause error
+}
+
+// Error is the implementation of error for ErrNonceStore
+func (e ErrNonceStore) Error() string {
+	return "failed to store request nonce: " + e.Cause.Error()
+}
+
+// NonceStore keeps the nonces that have been used
+type NonceStore interface {
+	// Use marks the nonce as used within the scope for the ttl.
+	// It returns false if the nonce was already in use
+	Use(ctx context.Context, scope, nonce string, ttl time.Duration) (bool, error)
+}
+
+// nonceEntry is a nonce kept by the MemNonceStore
+type nonceEntry struct {
+	scope   string
+	expires time.Time
+}
+
+// MemNonceStore is a NonceStore that keeps the nonces in memory. It
+// only protects against replays to the same gateway instance
+type MemNonceStore struct {
+	mu              sync.Mutex
+	maxEntries      int
+	maxScopeEntries int
+	entries         map[string]nonceEntry
+	scopes          map[string]int
+}
+
+// NewMemNonceStore creates a new MemNonceStore that keeps at most
+// maxEntries nonces at the same time, and at most maxScopeEntries
+// for the same scope, so that a single user cannot fill the store
+func NewMemNonceStore(maxEntries, maxScopeEntries int) *MemNonceStore {
+	return &MemNonceStore{
+		maxEntries:      maxEntries,
+		maxScopeEntries: maxScopeEntries,
+		entries:         make(map[string]nonceEntry),
+		scopes:          make(map[string]int),
+	}
+}
+
+// Use is the implementation of NonceStore.Use for MemNonceStore
+func (s *MemNonceStore) Use(ctx context.Context, scope, nonce string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := scope + ":" + nonce
+	now := clock()
+	if entry, ok := s.entries[key]; ok && now.Before(entry.expires) {
+		return false, nil
+	}
+
+	if len(s.entries) >= s.maxEntries || s.scopes[scope] >= s.maxScopeEntries {
+		s.evict(now)
+	}
+
+	// evicting a nonce that has not expired would allow the
+	// request that used it to be replayed, so the store rejects
+	// new nonces instead
+	if len(s.entries) >= s.maxEntries {
+		return false, ErrNonceStoreFull
+	}
+	if s.scopes[scope] >= s.maxScopeEntries {
+		return false, ErrNonceScopeFull
+	}
+
+	if _, ok := s.entries[key]; !ok {
+		s.scopes[scope]++
+	}
+	s.entries[key] = nonceEntry{scope: scope, expires: now.Add(ttl)}
+	return true, nil
+}
+
+// evict removes the nonces that have expired
+func (s *MemNonceStore) evict(now time.Time) {
+	for key, entry := range s.entries {
+		if now.Before(entry.expires) {
+			continue
+		}
+
+		delete(s.entries, key)
+		if s.scopes[entry.scope] <= 1 {
+			delete(s.scopes, entry.scope)
+		} else {
+			s.scopes[entry.scope]--
+		}
+	}
+}
+
+// RedisNonceClient is the interface to the redis client
+// used by the RedisNonceStore
+type RedisNonceClient interface {
+	SetNX(key string, value interface{}, expiration time.Duration) *redis.BoolCmd
+}
+
+// RedisNonceStore is a NonceStore that keeps the nonces in redis
+// so that they are shared by all the gateways that use the same
+// redis deployment
+type RedisNonceStore struct {
+	client RedisNonceClient
+}
+
+// NewRedisNonceStore creates a new RedisNonceStore connected to
+// the redis instance or cluster seeds at the provided addresses
+func NewRedisNonceStore(addrs []string) *RedisNonceStore {
+	return NewRedisNonceStoreWithClient(redis.NewUniversalClient(&redis.UniversalOptions{
+		Addrs: addrs,
+	}))
+}
+
+// NewRedisNonceStoreWithClient creates a new RedisNonceStore
+// that uses the provided client
+func NewRedisNonceStoreWithClient(client RedisNonceClient) *RedisNonceStore {
+	return &RedisNonceStore{client: client}
+}
+
+// Use is the implementation of NonceStore.Use for RedisNonceStore
+func (s *RedisNonceStore) Use(ctx context.Context, scope, nonce string, ttl time.Duration) (bool, error) {
+	return s.client.SetNX("replay:"+scope+":"+nonce, 1, ttl).Result()
+}
+
+// ReplayGuardProps are the properties used to create a ReplayGuard
+type ReplayGuardProps struct {
+	// Window is the maximum difference between the timestamp of a
+	// request and the time at which it is received. If it is 0
+	// requests are not checked
+	Window time.Duration
+
+	// Store keeps the nonces used by the clients
+	Store NonceStore
+}
+
+// ReplayGuard rejects requests whose timestamp is stale and requests
+// that reuse a nonce, so that a captured request cannot be replayed.
+// Nonces only need to be kept for twice the window because older
+// requests are already rejected for their timestamp
+type ReplayGuard struct {
+	window time.Duration
+	store  NonceStore
+
+	accepted  stats.Counter
+	stale     stats.Counter
+	replayed  stats.Counter
+	unsigned  stats.Counter
+	throttled stats.Counter
+	failures  stats.Counter
+}
+
+// NewReplayGuard creates a new ReplayGuard instance
+func NewReplayGuard(props ReplayGuardProps) *ReplayGuard {
+	return &ReplayGuard{
+		window: props.Window,
+		store:  props.Store,
+	}
+}
+
+// Enabled returns true if requests are checked for replays
+func (g *ReplayGuard) Enabled() bool {
+	return g != nil && g.window > 0 && g.store != nil
+}
+
+// replayValue returns the value set by the Auth in the context
+// for the key or the header otherwise
+func replayValue(req *http.Request, key interface{}, header string) string {
+	if value := req.Context().Value(key); value != nil {
+		return value.(string)
+	}
+
+	return req.Header.Get(header)
+}
+
+// Verify checks that the request issued by the AAD, identified by its
+// hash, is fresh and has not been received before. If signed is set the
+// request was authenticated by an Auth that signs its timestamp and
+// nonce, and only the values it set in the context are accepted. A nil
+// error is returned if the guard is not enabled
+func (g *ReplayGuard) Verify(req *http.Request, aadHash string, signed bool) error {
+	if !g.Enabled() {
+		return nil
+	}
+
+	// the headers can be replaced by anyone that captured the
+	// credentials of a request, so only the values covered by
+	// the credentials are accepted
+	if signed && (req.Context().Value(ReplayTimestamp{}) == nil || req.Context().Value(ReplayNonce{}) == nil) {
+		g.unsigned.Incr()
+		return ErrRequestUnsigned
+	}
+
+	value := replayValue(req, ReplayTimestamp{}, RequestHeaderTimestamp)
+	if len(value) == 0 {
+		g.stale.Incr()
+		return fmt.Errorf("no %s header provided", RequestHeaderTimestamp)
+	}
+
+	millis, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		g.stale.Incr()
+		return fmt.Errorf("%s header must be the milliseconds since the epoch", RequestHeaderTimestamp)
+	}
+
+	skew := clock().Sub(time.Unix(0, millis*int64(time.Millisecond)))
+	if skew > g.window || skew < -g.window {
+		g.stale.Incr()
+		return ErrRequestTimestamp
+	}
+
+	nonce := replayValue(req, ReplayNonce{}, RequestHeaderNonce)
+	if len(nonce) < MinNonceLength || len(nonce) > MaxNonceLength {
+		g.replayed.Incr()
+		return fmt.Errorf("%s header must have between %d and %d characters",
+			RequestHeaderNonce, MinNonceLength, MaxNonceLength)
+	}
+
+	// nonces are scoped to the AAD so that a user cannot
+	// block the requests of others by guessing their nonces
+	ok, err := g.store.Use(req.Context(), aadHash, nonce, 2*g.window)
+	if err == ErrNonceScopeFull {
+		g.throttled.Incr()
+		return err
+	}
+	if err != nil {
+		g.failures.Incr()
+		return ErrNonceStore{Cause: err}
+	}
+	if !ok {
+		g.replayed.Incr()
+		return ErrRequestReplayed
+	}
+
+	g.accepted.Incr()
+	return nil
+}
+
+// Stats returns the metrics of the guard
+func (g *ReplayGuard) Stats() stats.Metrics {
+	if g == nil {
+		return nil
+	}
+
+	return stats.Metrics{
+		"accepted":  g.accepted.Value(),
+		"stale":     g.stale.Value(),
+		"replayed":  g.replayed.Value(),
+		"unsigned":  g.unsigned.Value(),
+		"throttled": g.throttled.Value(),
+		"failures":  g.failures.Value(),
+	}
+}
@@ -0,0 +1,278 @@
+package core
+
+import (
+	"context"
+	stderr "errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/oasislabs/oasis-gateway/rpc"
+	"github.com/stretchr/testify/assert"
+)
+
+const testNonce = "0123456789abcdef"
+
+type failingNonceStore struct{}
+
+func (s failingNonceStore) Use(ctx context.Context, scope, nonce string, ttl time.Duration) (bool, error) {
+	return false, stderr.New("store unavailable")
+}
+
+func newTestReplayGuard(store NonceStore) *ReplayGuard {
+	return NewReplayGuard(ReplayGuardProps{Window: time.Minute, Store: store})
+}
+
+func newReplayRequest(t *testing.T, timestamp time.Time, nonce string) *http.Request {
+	req, err := http.NewRequest("GET", "/", nil)
+	assert.Nil(t, err)
+	req.Header.Set(RequestHeaderTimestamp, strconv.FormatInt(timestamp.UnixNano()/int64(time.Millisecond), 10))
+	req.Header.Set(RequestHeaderNonce, nonce)
+	return req
+}
+
+func TestReplayGuardDisabled(t *testing.T) {
+	var nilGuard *ReplayGuard
+	req, err := http.NewRequest("GET", "/", nil)
+	assert.Nil(t, err)
+
+	assert.False(t, nilGuard.Enabled())
+	assert.Nil(t, nilGuard.Verify(req, "aad", false))
+}
+
+func TestReplayGuardVerify(t *testing.T) {
+	fake := useFakeClock(t)
+	guard := newTestReplayGuard(NewMemNonceStore(10, 10))
+
+	assert.Nil(t, guard.Verify(newReplayRequest(t, fake.Now(), testNonce), "aad", false))
+	assert.Equal(t, ErrRequestReplayed, guard.Verify(newReplayRequest(t, fake.Now(), testNonce), "aad", false))
+
+	// nonces are scoped to the AAD
+	assert.Nil(t, guard.Verify(newReplayRequest(t, fake.Now(), testNonce), "other", false))
+
+	assert.Equal(t, map[string]interface{}{
+		"accepted":  uint64(2),
+		"stale":     uint64(0),
+		"replayed":  uint64(1),
+		"unsigned":  uint64(0),
+		"throttled": uint64(0),
+		"failures":  uint64(0),
+	}, map[string]interface{}(guard.Stats()))
+}
+
+func TestReplayGuardVerifyTimestamp(t *testing.T) {
+	fake := useFakeClock(t)
+	guard := newTestReplayGuard(NewMemNonceStore(10, 10))
+
+	assert.Nil(t, guard.Verify(newReplayRequest(t, fake.Now().Add(-time.Minute), testNonce+"1"), "aad", false))
+	assert.Nil(t, guard.Verify(newReplayRequest(t, fake.Now().Add(time.Minute), testNonce+"2"), "aad", false))
+	assert.Equal(t, ErrRequestTimestamp,
+		guard.Verify(newReplayRequest(t, fake.Now().Add(-time.Minute-time.Millisecond), testNonce+"3"), "aad", false))
+	assert.Equal(t, ErrRequestTimestamp,
+		guard.Verify(newReplayRequest(t, fake.Now().Add(time.Minute+time.Millisecond), testNonce+"4"), "aad", false))
+
+	req := newReplayRequest(t, fake.Now(), testNonce+"5")
+	req.Header.Set(RequestHeaderTimestamp, "yesterday")
+	assert.Error(t, guard.Verify(req, "aad", false))
+
+	req.Header.Del(RequestHeaderTimestamp)
+	assert.Error(t, guard.Verify(req, "aad", false))
+}
+
+func TestReplayGuardVerifyNonceLength(t *testing.T) {
+	fake := useFakeClock(t)
+	guard := newTestReplayGuard(NewMemNonceStore(10, 10))
+
+	assert.Error(t, guard.Verify(newReplayRequest(t, fake.Now(), "short"), "aad", false))
+	assert.Error(t, guard.Verify(newReplayRequest(t, fake.Now(), string(make([]byte, MaxNonceLength+1))), "aad", false))
+}
+
+func TestReplayGuardVerifyContextOverridesHeaders(t *testing.T) {
+	fake := useFakeClock(t)
+	guard := newTestReplayGuard(NewMemNonceStore(10, 10))
+
+	req := newReplayRequest(t, fake.Now().Add(-time.Hour), "short")
+	ctx := context.WithValue(req.Context(), ReplayTimestamp{},
+		strconv.FormatInt(fake.Now().UnixNano()/int64(time.Millisecond), 10))
+	req = req.WithContext(context.WithValue(ctx, ReplayNonce{}, testNonce))
+
+	assert.Nil(t, guard.Verify(req, "aad", false))
+}
+
+func TestReplayGuardVerifySigned(t *testing.T) {
+	fake := useFakeClock(t)
+	guard := newTestReplayGuard(NewMemNonceStore(10, 10))
+
+	// the headers are not covered by the credentials
+	assert.Equal(t, ErrRequestUnsigned, guard.Verify(newReplayRequest(t, fake.Now(), testNonce), "aad", true))
+
+	req := newReplayRequest(t, fake.Now(), testNonce)
+	ctx := context.WithValue(req.Context(), ReplayTimestamp{},
+		strconv.FormatInt(fake.Now().UnixNano()/int64(time.Millisecond), 10))
+	req = req.WithContext(context.WithValue(ctx, ReplayNonce{}, testNonce))
+	assert.Nil(t, guard.Verify(req, "aad", true))
+}
+
+func TestReplayGuardVerifyScopeFull(t *testing.T) {
+	fake := useFakeClock(t)
+	guard := newTestReplayGuard(NewMemNonceStore(10, 1))
+
+	assert.Nil(t, guard.Verify(newReplayRequest(t, fake.Now(), testNonce+"1"), "aad", false))
+	assert.Equal(t, ErrNonceScopeFull, guard.Verify(newReplayRequest(t, fake.Now(), testNonce+"2"), "aad", false))
+
+	// other users are not affected
+	assert.Nil(t, guard.Verify(newReplayRequest(t, fake.Now(), testNonce+"2"), "other", false))
+}
+
+func TestReplayGuardVerifyStoreFailure(t *testing.T) {
+	fake := useFakeClock(t)
+	guard := newTestReplayGuard(failingNonceStore{})
+
+	err := guard.Verify(newReplayRequest(t, fake.Now(), testNonce), "aad", false)
+	assert.IsType(t, ErrNonceStore{}, err)
+}
+
+func TestMemNonceStoreFull(t *testing.T) {
+	store := NewMemNonceStore(1, 1)
+	fake := useFakeClock(t)
+
+	ok, err := store.Use(context.Background(), "aad", "a", time.Minute)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	_, err = store.Use(context.Background(), "other", "b", time.Minute)
+	assert.Equal(t, ErrNonceStoreFull, err)
+
+	// expired nonces are removed to make room
+	fake.Advance(time.Minute)
+	ok, err = store.Use(context.Background(), "other", "b", time.Minute)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+}
+
+func TestMemNonceStoreScopeFull(t *testing.T) {
+	store := NewMemNonceStore(10, 2)
+	fake := useFakeClock(t)
+
+	for _, nonce := range []string{"a", "b"} {
+		ok, err := store.Use(context.Background(), "aad", nonce, time.Minute)
+		assert.Nil(t, err)
+		assert.True(t, ok)
+	}
+
+	_, err := store.Use(context.Background(), "aad", "c", time.Minute)
+	assert.Equal(t, ErrNonceScopeFull, err)
+
+	ok, err := store.Use(context.Background(), "other", "c", time.Minute)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	// expired nonces of the scope are removed to make room
+	fake.Advance(time.Minute)
+	ok, err = store.Use(context.Background(), "aad", "c", time.Minute)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+}
+
+func TestServeHTTPReplay(t *testing.T) {
+	fake := useFakeClock(t)
+	guard := newTestReplayGuard(NewMemNonceStore(10, 10))
+	handler := NewHttpMiddlewareAuthWithProps(HttpMiddlewareAuthProps{
+		Auth:   &NilAuth{},
+		Logger: Logger,
+		Replay: guard,
+		Next: rpc.HttpMiddlewareFunc(func(req *http.Request) (interface{}, error) {
+			return 0, nil
+		}),
+	})
+
+	req := newReplayRequest(t, fake.Now(), testNonce)
+	req.Header.Set(RequestHeaderSessionKey, "session")
+	_, err := handler.ServeHTTP(req)
+	assert.Nil(t, err)
+
+	_, err = handler.ServeHTTP(req)
+	assert.Equal(t, http.StatusForbidden, err.(*rpc.HttpError).StatusCode)
+
+	guard.store = failingNonceStore{}
+	req = newReplayRequest(t, fake.Now(), testNonce+"1")
+	req.Header.Set(RequestHeaderSessionKey, "session")
+	_, err = handler.ServeHTTP(req)
+	assert.Equal(t, http.StatusInternalServerError, err.(*rpc.HttpError).StatusCode)
+}
+
+// signingAuth authenticates the requests that carry a signed
+// timestamp and nonce, emulated by the X-SIGNED header
+type signingAuth struct {
+	NilAuth
+}
+
+func (a *signingAuth) Authenticate(req *http.Request) (*http.Request, error) {
+	if len(req.Header.Get("X-SIGNED")) == 0 {
+		return req, stderr.New("request is not signed")
+	}
+
+	return a.NilAuth.Authenticate(req)
+}
+
+func (a *signingAuth) SignsReplay() bool {
+	return true
+}
+
+func TestServeHTTPReplaySignedPerProvider(t *testing.T) {
+	fake := useFakeClock(t)
+	guard := newTestReplayGuard(NewMemNonceStore(10, 10))
+	multi := &MultiAuth{}
+	multi.Add(&signingAuth{})
+	multi.Add(&NilAuth{})
+	handler := NewHttpMiddlewareAuthWithProps(HttpMiddlewareAuthProps{
+		Auth:   multi,
+		Logger: Logger,
+		Replay: guard,
+		Next: rpc.HttpMiddlewareFunc(func(req *http.Request) (interface{}, error) {
+			return 0, nil
+		}),
+	})
+
+	// the provider that does not sign accepts the headers
+	req := newReplayRequest(t, fake.Now(), testNonce)
+	req.Header.Set(RequestHeaderSessionKey, "session")
+	_, err := handler.ServeHTTP(req)
+	assert.Nil(t, err)
+
+	// the provider that signs only accepts the signed values
+	req = newReplayRequest(t, fake.Now(), testNonce+"1")
+	req.Header.Set(RequestHeaderSessionKey, "session")
+	req.Header.Set("X-SIGNED", "true")
+	_, err = handler.ServeHTTP(req)
+	assert.Equal(t, http.StatusForbidden, err.(*rpc.HttpError).StatusCode)
+	assert.Equal(t, uint64(1), guard.Stats()["unsigned"])
+}
+
+func TestServeHTTPReplayCapabilityExempt(t *testing.T) {
+	useFakeClock(t)
+	guard := newTestReplayGuard(NewMemNonceStore(10, 10))
+	issuer := newTestCapabilityIssuer()
+	multi := &MultiAuth{}
+	multi.Add(NewCapabilityAuth(issuer))
+	multi.Add(&signingAuth{})
+	handler := NewHttpMiddlewareAuthWithProps(HttpMiddlewareAuthProps{
+		Auth:   multi,
+		Logger: Logger,
+		Replay: guard,
+		Next: rpc.HttpMiddlewareFunc(func(req *http.Request) (interface{}, error) {
+			return 0, nil
+		}),
+	})
+
+	token, _, err := issuer.Issue("aad", "session", 0, ScopeEventPoll)
+	assert.Nil(t, err)
+
+	// a capability token can be used to poll repeatedly
+	for i := 0; i < 2; i++ {
+		_, err := handler.ServeHTTP(newCapabilityRequest(t, token))
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, uint64(0), guard.Stats()["accepted"])
+}
@@ -0,0 +1,110 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/oasislabs/oasis-gateway/errors"
+	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/oasislabs/oasis-gateway/rpc"
+)
+
+// Scope identifies a subset of the APIs that a request
+// is authorized to access
+type Scope string
+
+const (
+	// ScopeServiceExecute authorizes the execution of services
+	ScopeServiceExecute Scope = "service.execute"
+
+	// ScopeServiceDeploy authorizes the deployment of services
+	ScopeServiceDeploy Scope = "service.deploy"
+
+	// ScopeEventPoll authorizes polling for events
+	ScopeEventPoll Scope = "event.poll"
+
+	// ScopeSubscriptionManage authorizes the creation and
+	// removal of subscriptions
+	ScopeSubscriptionManage Scope = "subscription.manage"
+
+	// ScopeServiceRead authorizes the retrieval of information
+	// about services and the queries to services
+	ScopeServiceRead Scope = "service.read"
+
+	// ScopeTokenIssue authorizes minting capability tokens
+	ScopeTokenIssue Scope = "token.issue"
+)
+
+// Scopes is the key used to store the scopes granted
+// to a request in its context
+type Scopes struct{}
+
+// WithScopes attaches the scopes granted to the request. Auth plugins
+// that restrict the APIs a credential can access call it from
+// Authenticate. A request without scopes attached is granted all
+// of them
+func WithScopes(req *http.Request, scopes ...Scope) *http.Request {
+	granted := make(map[Scope]bool, len(scopes))
+	for _, scope := range scopes {
+		granted[scope] = true
+	}
+
+	return req.WithContext(context.WithValue(req.Context(), Scopes{}, granted))
+}
+
+// HasScope returns true if the context has been granted the scope
+func HasScope(ctx context.Context, scope Scope) bool {
+	value := ctx.Value(Scopes{})
+	if value == nil {
+		return true
+	}
+
+	return value.(map[Scope]bool)[scope]
+}
+
+// RouteScopes maps the path of a route to the scope
+// that is required to access it
+type RouteScopes map[string]Scope
+
+// HttpMiddlewareAuthorize rejects the requests to routes that
+// require a scope that has not been granted to the request
+type HttpMiddlewareAuthorize struct {
+	routes RouteScopes
+	logger log.Logger
+	next   rpc.HttpMiddleware
+}
+
+// NewHttpMiddlewareAuthorize creates a new instance of HttpMiddlewareAuthorize.
+// Routes that are not in routes do not require any scope
+func NewHttpMiddlewareAuthorize(routes RouteScopes, logger log.Logger, next rpc.HttpMiddleware) *HttpMiddlewareAuthorize {
+	if logger == nil {
+		panic("log must be set")
+	}
+
+	if next == nil {
+		panic("next must be set")
+	}
+
+	return &HttpMiddlewareAuthorize{
+		routes: routes,
+		logger: logger.ForClass("auth", "HttpMiddlewareAuthorize"),
+		next:   next,
+	}
+}
+
+// ServeHTTP is the implementation of rpc.HttpMiddleware for HttpMiddlewareAuthorize
+func (m *HttpMiddlewareAuthorize) ServeHTTP(req *http.Request) (interface{}, error) {
+	scope, ok := m.routes[req.URL.Path]
+	if ok && !HasScope(req.Context(), scope) {
+		err := errors.New(errors.ErrMissingScope, fmt.Errorf("scope %s required", scope))
+		m.logger.Debug(req.Context(), "request does not have the required scope", log.MapFields{
+			"call_type": "AuthorizeFailure",
+			"path":      req.URL.Path,
+			"scope":     string(scope),
+		}, err)
+		return nil, rpc.HttpForbidden(req.Context(), err)
+	}
+
+	return m.next.ServeHTTP(req)
+}
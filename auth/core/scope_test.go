@@ -0,0 +1,51 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/oasislabs/oasis-gateway/rpc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHasScopeNoScopes(t *testing.T) {
+	assert.True(t, HasScope(context.Background(), ScopeServiceDeploy))
+}
+
+func TestHasScope(t *testing.T) {
+	req, err := http.NewRequest("POST", "/", nil)
+	assert.Nil(t, err)
+
+	req = WithScopes(req, ScopeServiceExecute, ScopeEventPoll)
+	assert.True(t, HasScope(req.Context(), ScopeServiceExecute))
+	assert.True(t, HasScope(req.Context(), ScopeEventPoll))
+	assert.False(t, HasScope(req.Context(), ScopeServiceDeploy))
+	assert.False(t, HasScope(req.Context(), ScopeSubscriptionManage))
+}
+
+func TestHttpMiddlewareAuthorize(t *testing.T) {
+	handler := NewHttpMiddlewareAuthorize(RouteScopes{
+		"/deploy":  ScopeServiceDeploy,
+		"/execute": ScopeServiceExecute,
+	}, Logger, rpc.HttpMiddlewareFunc(func(req *http.Request) (interface{}, error) {
+		return 0, nil
+	}))
+
+	for _, path := range []string{"/execute", "/info"} {
+		req, err := http.NewRequest("POST", path, nil)
+		assert.Nil(t, err)
+
+		res, err := handler.ServeHTTP(WithScopes(req, ScopeServiceExecute))
+		assert.Nil(t, err)
+		assert.Equal(t, 0, res)
+	}
+
+	req, err := http.NewRequest("POST", "/deploy", nil)
+	assert.Nil(t, err)
+
+	res, err := handler.ServeHTTP(WithScopes(req, ScopeServiceExecute))
+	assert.Nil(t, res)
+	assert.Equal(t, http.StatusForbidden, err.(*rpc.HttpError).StatusCode)
+	assert.Equal(t, 7006, err.(*rpc.HttpError).Cause.ErrorCode().Code())
+}
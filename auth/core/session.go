@@ -0,0 +1,48 @@
+package core
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// SessionMode defines how the session key of a request is obtained
+type SessionMode string
+
+const (
+	// SessionModeClient uses the session key provided by the
+	// client in the RequestHeaderSessionKey header
+	SessionModeClient SessionMode = "client"
+
+	// SessionModeDerived derives the session key from the AAD
+	// of the request so that each identity has a single session
+	SessionModeDerived SessionMode = "derived"
+)
+
+func (m SessionMode) String() string {
+	return string(m)
+}
+
+// SessionProps defines how HttpMiddlewareAuth assigns a
+// session to the authenticated requests
+type SessionProps struct {
+	// Mode defines how the session key is obtained
+	Mode SessionMode
+
+	// Secret is the key used to derive the session key from
+	// the AAD when Mode is SessionModeDerived
+	Secret []byte
+
+	// Bindings if set rejects the requests that use a session
+	// key that is bound to a different AAD when Mode is
+	// SessionModeClient
+	Bindings *SessionBindings
+}
+
+// DeriveSessionKey derives the session key for an AAD as the
+// HMAC-SHA256 of the AAD keyed with the secret
+func DeriveSessionKey(secret []byte, aad string) string {
+	mac := hmac.New(sha256.New, secret)
+	_, _ = mac.Write([]byte(aad))
+	return hex.EncodeToString(mac.Sum(nil))
+}
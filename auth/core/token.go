@@ -0,0 +1,82 @@
+package core
+
+import (
+	"crypto/subtle"
+	stderr "errors"
+	"net/http"
+	"strings"
+
+	"github.com/oasislabs/oasis-gateway/errors"
+	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/oasislabs/oasis-gateway/rpc"
+)
+
+const bearerPrefix = "Bearer "
+
+// HttpMiddlewareTokenProps are the properties used to
+// create an HttpMiddlewareToken
+type HttpMiddlewareTokenProps struct {
+	// Token is the bearer token the requests must present. If
+	// empty no token is required
+	Token string
+
+	// Exempt are the paths of the routes that do not require the
+	// token because they authenticate the requests on their own
+	Exempt map[string]bool
+
+	// Logger is the logger used by the middleware
+	Logger log.Logger
+
+	// Next is the middleware to which the authenticated
+	// requests are forwarded
+	Next rpc.HttpMiddleware
+}
+
+// HttpMiddlewareToken rejects the requests that do not present the
+// static bearer token shared with the operators in the Authorization
+// header. It protects the APIs that are not served to the users of
+// the gateway, like the private API
+type HttpMiddlewareToken struct {
+	token  []byte
+	exempt map[string]bool
+	logger log.Logger
+	next   rpc.HttpMiddleware
+}
+
+// NewHttpMiddlewareToken creates a new HttpMiddlewareToken
+func NewHttpMiddlewareToken(props HttpMiddlewareTokenProps) *HttpMiddlewareToken {
+	if props.Logger == nil {
+		panic("log must be set")
+	}
+
+	if props.Next == nil {
+		panic("next must be set")
+	}
+
+	return &HttpMiddlewareToken{
+		token:  []byte(props.Token),
+		exempt: props.Exempt,
+		logger: props.Logger.ForClass("auth", "HttpMiddlewareToken"),
+		next:   props.Next,
+	}
+}
+
+// ServeHTTP is the implementation of rpc.HttpMiddleware for HttpMiddlewareToken
+func (m *HttpMiddlewareToken) ServeHTTP(req *http.Request) (interface{}, error) {
+	if len(m.token) == 0 || m.exempt[req.URL.Path] {
+		return m.next.ServeHTTP(req)
+	}
+
+	header := req.Header.Get("Authorization")
+	if !strings.HasPrefix(header, bearerPrefix) ||
+		subtle.ConstantTimeCompare([]byte(header[len(bearerPrefix):]), m.token) != 1 {
+		err := errors.New(errors.ErrPrivateAPIToken, stderr.New("missing or invalid bearer token"))
+		m.logger.Debug(req.Context(), "request does not have a valid token", log.MapFields{
+			"call_type": "TokenFailure",
+			"path":      req.URL.Path,
+		}, err)
+		return nil, rpc.HttpForbidden(req.Context(), err)
+	}
+
+	return m.next.ServeHTTP(req)
+}
@@ -0,0 +1,63 @@
+package core
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/oasislabs/oasis-gateway/rpc"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTokenHandler(token string) *HttpMiddlewareToken {
+	return NewHttpMiddlewareToken(HttpMiddlewareTokenProps{
+		Token:  token,
+		Exempt: map[string]bool{"/federation": true},
+		Logger: Logger,
+		Next: rpc.HttpMiddlewareFunc(func(req *http.Request) (interface{}, error) {
+			return 0, nil
+		}),
+	})
+}
+
+func TestHttpMiddlewareTokenNoToken(t *testing.T) {
+	req, err := http.NewRequest("GET", "/health", nil)
+	assert.Nil(t, err)
+
+	res, err := newTokenHandler("").ServeHTTP(req)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, res)
+}
+
+func TestHttpMiddlewareTokenValid(t *testing.T) {
+	req, err := http.NewRequest("GET", "/health", nil)
+	assert.Nil(t, err)
+	req.Header.Set("Authorization", "Bearer secret")
+
+	res, err := newTokenHandler("secret").ServeHTTP(req)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, res)
+}
+
+func TestHttpMiddlewareTokenInvalid(t *testing.T) {
+	handler := newTokenHandler("secret")
+
+	for _, header := range []string{"", "secret", "Bearer", "Bearer other", "Basic secret"} {
+		req, err := http.NewRequest("GET", "/health", nil)
+		assert.Nil(t, err)
+		req.Header.Set("Authorization", header)
+
+		res, err := handler.ServeHTTP(req)
+		assert.Nil(t, res, header)
+		assert.Equal(t, http.StatusForbidden, err.(*rpc.HttpError).StatusCode)
+		assert.Equal(t, 7010, err.(*rpc.HttpError).Cause.ErrorCode().Code())
+	}
+}
+
+func TestHttpMiddlewareTokenExempt(t *testing.T) {
+	req, err := http.NewRequest("POST", "/federation", nil)
+	assert.Nil(t, err)
+
+	res, err := newTokenHandler("secret").ServeHTTP(req)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, res)
+}
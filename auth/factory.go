@@ -29,10 +29,14 @@ var NewAuth = FactoryFunc(func(config *Config) (core.Auth, error) {
 	return multiAuth, nil
 })
 
-func newAuthSingle(provider AuthProvider) core.Auth {
+func newAuthSingle(provider AuthProvider, config *Config) core.Auth {
 	switch provider {
 	case AuthOauth:
-		return oauth.NewGoogleOauth(oauth.NewGoogleIDTokenVerifier())
+		return oauth.NewCachedGoogleOauth(oauth.NewGoogleIDTokenVerifier(),
+			core.NewVerificationCache(config.Cache))
+	case AuthOIDC:
+		return oauth.NewOIDCAuth(oauth.NewOIDCIDTokenVerifier(config.OIDC), config.OIDC.AADClaim,
+			core.NewVerificationCache(config.Cache))
 	case AuthInsecure:
 		return insecure.InsecureAuth{}
 	default:
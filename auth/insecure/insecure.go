@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"strings"
 
 	"github.com/oasislabs/oasis-gateway/auth/core"
 	"github.com/oasislabs/oasis-gateway/log"
@@ -12,6 +13,10 @@ import (
 
 const HeaderKey string = "X-OASIS-INSECURE-AUTH"
 
+// HeaderScopes is the header with the comma separated list of scopes
+// granted to the request. If it is not set all scopes are granted
+const HeaderScopes string = "X-OASIS-INSECURE-SCOPES"
+
 var ErrDataTooShort = errors.New("Payload data is too short")
 
 // InsecureAuth is an insecure authentication mechanism that may be
@@ -34,7 +39,17 @@ func (a InsecureAuth) Authenticate(req *http.Request) (*http.Request, error) {
 	}
 
 	ctx := context.WithValue(req.Context(), core.AAD{}, value)
-	return req.WithContext(ctx), nil
+	req = req.WithContext(ctx)
+
+	if scopes := req.Header.Get(HeaderScopes); len(scopes) > 0 {
+		var granted []core.Scope
+		for _, scope := range strings.Split(scopes, ",") {
+			granted = append(granted, core.Scope(strings.TrimSpace(scope)))
+		}
+		req = core.WithScopes(req, granted...)
+	}
+
+	return req, nil
 }
 
 func (InsecureAuth) Verify(ctx context.Context, req core.AuthRequest) error {
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
 	oidc "github.com/coreos/go-oidc"
 	"github.com/oasislabs/oasis-gateway/auth/core"
@@ -45,15 +46,25 @@ func (g *GoogleIDTokenVerifier) Verify(ctx context.Context, rawIDToken string) (
 type GoogleOauth struct {
 	logger   log.Logger
 	verifier IDTokenVerifier
+	cache    *core.VerificationCache
 }
 
 type OpenIDClaims struct {
 	Email         string `json:"email"`
 	EmailVerified bool   `json:"email_verified"`
+	Expiry        int64  `json:"exp"`
+	IssuedAt      int64  `json:"iat"`
+	Nonce         string `json:"nonce"`
 }
 
 func NewGoogleOauth(verifier IDTokenVerifier) GoogleOauth {
-	return GoogleOauth{verifier: verifier}
+	return NewCachedGoogleOauth(verifier, nil)
+}
+
+// NewCachedGoogleOauth creates a GoogleOauth that caches the claims
+// of the verified ID tokens in the provided cache
+func NewCachedGoogleOauth(verifier IDTokenVerifier, cache *core.VerificationCache) GoogleOauth {
+	return GoogleOauth{verifier: verifier, cache: cache}
 }
 
 func (g GoogleOauth) Name() string {
@@ -61,7 +72,13 @@ func (g GoogleOauth) Name() string {
 }
 
 func (g GoogleOauth) Stats() stats.Metrics {
-	return nil
+	if !g.cache.Enabled() {
+		return nil
+	}
+
+	return stats.Metrics{
+		"cache": g.cache.Stats(),
+	}
 }
 
 // Authenticates the user using the ID Token received from Google.
@@ -71,6 +88,10 @@ func (g GoogleOauth) Authenticate(req *http.Request) (*http.Request, error) {
 		return req, fmt.Errorf("%s header not set", GOOGLE_ID_TOKEN_KEY)
 	}
 
+	if claims, ok := g.cache.Get(rawIDToken); ok {
+		return withClaims(req, claims.(OpenIDClaims)), nil
+	}
+
 	idToken, err := g.verifier.Verify(req.Context(), rawIDToken)
 	if err != nil {
 		return req, err
@@ -84,8 +105,30 @@ func (g GoogleOauth) Authenticate(req *http.Request) (*http.Request, error) {
 		return req, errors.New("Email is unverified")
 	}
 
-	ctx := context.WithValue(req.Context(), core.AAD{}, claims.Email)
-	return req.WithContext(ctx), nil
+	var expiry time.Time
+	if claims.Expiry > 0 {
+		expiry = time.Unix(claims.Expiry, 0)
+	}
+	g.cache.Set(rawIDToken, claims, expiry)
+
+	return withClaims(req, claims), nil
+}
+
+// SignsReplay is the implementation of core.ReplaySigner for GoogleOauth.
+// The timestamp and nonce of a request are taken from the claims
+// of its ID token, so that they are covered by its signature
+func (g GoogleOauth) SignsReplay() bool {
+	return true
+}
+
+// withClaims sets the identity of the user and the timestamp and
+// nonce of the request from the verified claims of the ID token
+func withClaims(req *http.Request, claims OpenIDClaims) *http.Request {
+	return withIdentity(req, oidcIdentity{
+		AAD:      claims.Email,
+		IssuedAt: claims.IssuedAt,
+		Nonce:    claims.Nonce,
+	})
 }
 
 // Verify the provided AAD in the transaction data with the expected AAD
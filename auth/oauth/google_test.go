@@ -5,8 +5,10 @@ import (
 	"encoding/json"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/oasislabs/oasis-gateway/auth/core"
+	"github.com/oasislabs/oasis-gateway/stats"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -42,6 +44,30 @@ func TestAuthenticateSuccess(t *testing.T) {
 	assert.Equal(t, "test@email.com", req.Context().Value(core.AAD{}))
 }
 
+func TestAuthenticateReplayClaims(t *testing.T) {
+	claims := OpenIDClaims{
+		Email:         "test@email.com",
+		EmailVerified: true,
+		IssuedAt:      1000,
+		Nonce:         "0123456789abcdef",
+	}
+	jsonStr, err := json.Marshal(claims)
+	assert.Nil(t, err)
+
+	req, err := http.NewRequest("POST", "gateway.oasiscloud.io", nil)
+	assert.Nil(t, err)
+	req.Header.Add(GOOGLE_ID_TOKEN_KEY, string(jsonStr))
+	req.Header.Add(core.RequestHeaderTimestamp, "2000000")
+	req.Header.Add(core.RequestHeaderNonce, "fedcba9876543210")
+
+	auth := NewGoogleOauth(&MockIDTokenVerifier{})
+	req, err = auth.Authenticate(req)
+	assert.Nil(t, err)
+	assert.True(t, auth.SignsReplay())
+	assert.Equal(t, "1000000", req.Context().Value(core.ReplayTimestamp{}))
+	assert.Equal(t, "0123456789abcdef", req.Context().Value(core.ReplayNonce{}))
+}
+
 func TestAuthenticateUnverified(t *testing.T) {
 	claims := OpenIDClaims{
 		Email:         "test@email.com",
@@ -61,3 +87,41 @@ func TestAuthenticateUnverified(t *testing.T) {
 	assert.Equal(t, "Email is unverified", err.Error())
 	assert.Nil(t, req.Context().Value(core.AAD{}))
 }
+
+type CountingIDTokenVerifier struct {
+	MockIDTokenVerifier
+	Calls int
+}
+
+func (mock *CountingIDTokenVerifier) Verify(ctx context.Context, rawIDToken string) (IDToken, error) {
+	mock.Calls++
+	return mock.MockIDTokenVerifier.Verify(ctx, rawIDToken)
+}
+
+func TestAuthenticateCached(t *testing.T) {
+	claims := OpenIDClaims{
+		Email:         "test@email.com",
+		EmailVerified: true,
+	}
+	jsonStr, err := json.Marshal(claims)
+	assert.Nil(t, err)
+
+	verifier := &CountingIDTokenVerifier{}
+	auth := NewCachedGoogleOauth(verifier, core.NewVerificationCache(core.VerificationCacheProps{
+		TTL:        time.Minute,
+		MaxEntries: 10,
+	}))
+
+	for i := 0; i < 2; i++ {
+		req, err := http.NewRequest("POST", "gateway.oasiscloud.io", nil)
+		assert.Nil(t, err)
+		req.Header.Add(GOOGLE_ID_TOKEN_KEY, string(jsonStr))
+
+		req, err = auth.Authenticate(req)
+		assert.Nil(t, err)
+		assert.Equal(t, "test@email.com", req.Context().Value(core.AAD{}))
+	}
+
+	assert.Equal(t, 1, verifier.Calls)
+	assert.Equal(t, uint64(1), auth.Stats()["cache"].(stats.Metrics)["hits"])
+}
@@ -0,0 +1,207 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	oidc "github.com/coreos/go-oidc"
+	"github.com/oasislabs/oasis-gateway/auth/core"
+	auth "github.com/oasislabs/oasis-gateway/auth/core"
+	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/oasislabs/oasis-gateway/stats"
+)
+
+const (
+	// OIDCTokenHeader is the header that holds the ID token
+	// of the requests authenticated by OIDCAuth, as a bearer token
+	OIDCTokenHeader string = "Authorization"
+
+	// DefaultAADClaim is the claim used as AAD if none is configured
+	DefaultAADClaim string = "sub"
+
+	bearerPrefix string = "Bearer "
+)
+
+// OIDCProps defines the identity provider that issues
+// the ID tokens accepted by OIDCAuth
+type OIDCProps struct {
+	// Issuer is the URL of the issuer, which must match
+	// the iss claim of the ID tokens
+	Issuer string
+
+	// Audience if set must be one of the values of the aud
+	// claim of the ID tokens. It is usually the client ID
+	// of the application registered with the issuer
+	Audience string
+
+	// JWKSURL is the URL of the key set with which the
+	// issuer signs the ID tokens
+	JWKSURL string
+
+	// AADClaim is the claim of the ID tokens that identifies the
+	// user and is used as AAD. If empty DefaultAADClaim is used
+	AADClaim string
+}
+
+// OIDCIDTokenVerifier verifies the ID tokens issued by
+// an OpenID Connect provider
+type OIDCIDTokenVerifier struct {
+	verifier *oidc.IDTokenVerifier
+}
+
+// NewOIDCIDTokenVerifier creates a verifier for the ID tokens of the
+// issuer. The keys of the issuer are fetched when they are first needed
+func NewOIDCIDTokenVerifier(props OIDCProps) *OIDCIDTokenVerifier {
+	return newOIDCIDTokenVerifier(props, oidc.NewRemoteKeySet(context.Background(), props.JWKSURL))
+}
+
+func newOIDCIDTokenVerifier(props OIDCProps, keySet oidc.KeySet) *OIDCIDTokenVerifier {
+	return &OIDCIDTokenVerifier{
+		verifier: oidc.NewVerifier(props.Issuer, keySet, &oidc.Config{
+			ClientID:          props.Audience,
+			SkipClientIDCheck: len(props.Audience) == 0,
+		}),
+	}
+}
+
+func (v *OIDCIDTokenVerifier) Verify(ctx context.Context, rawIDToken string) (IDToken, error) {
+	return v.verifier.Verify(ctx, rawIDToken)
+}
+
+// oidcIdentity is what OIDCAuth keeps from the
+// claims of a verified ID token
+type oidcIdentity struct {
+	AAD      string
+	IssuedAt int64
+	Nonce    string
+}
+
+// OIDCAuth authenticates the requests with ID tokens issued by any
+// OpenID Connect provider, like Auth0, Okta or Keycloak
+type OIDCAuth struct {
+	logger   log.Logger
+	verifier IDTokenVerifier
+	cache    *core.VerificationCache
+	aadClaim string
+}
+
+// NewOIDCAuth creates an OIDCAuth that caches the identities of the
+// verified ID tokens in the provided cache
+func NewOIDCAuth(verifier IDTokenVerifier, aadClaim string, cache *core.VerificationCache) *OIDCAuth {
+	if len(aadClaim) == 0 {
+		aadClaim = DefaultAADClaim
+	}
+
+	return &OIDCAuth{verifier: verifier, cache: cache, aadClaim: aadClaim}
+}
+
+func (a *OIDCAuth) Name() string {
+	return "auth.oauth.OIDCAuth"
+}
+
+func (a *OIDCAuth) Stats() stats.Metrics {
+	if !a.cache.Enabled() {
+		return nil
+	}
+
+	return stats.Metrics{
+		"cache": a.cache.Stats(),
+	}
+}
+
+// Authenticate authenticates the user using the ID token sent as a
+// bearer token. The user is identified by the configured claim
+func (a *OIDCAuth) Authenticate(req *http.Request) (*http.Request, error) {
+	header := req.Header.Get(OIDCTokenHeader)
+	if !strings.HasPrefix(header, bearerPrefix) {
+		return req, fmt.Errorf("%s header not set with a bearer token", OIDCTokenHeader)
+	}
+	rawIDToken := strings.TrimSpace(header[len(bearerPrefix):])
+
+	if identity, ok := a.cache.Get(rawIDToken); ok {
+		return withIdentity(req, identity.(oidcIdentity)), nil
+	}
+
+	idToken, err := a.verifier.Verify(req.Context(), rawIDToken)
+	if err != nil {
+		return req, err
+	}
+
+	var claims map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil {
+		return req, err
+	}
+
+	aad, ok := claims[a.aadClaim].(string)
+	if !ok || len(aad) == 0 {
+		return req, fmt.Errorf("claim %s is not set in the ID token", a.aadClaim)
+	}
+
+	// an email is only a valid identity once the issuer
+	// has verified that it belongs to the user
+	if verified, _ := claims["email_verified"].(bool); a.aadClaim == "email" && !verified {
+		return req, errors.New("Email is unverified")
+	}
+
+	identity := oidcIdentity{AAD: aad}
+	identity.IssuedAt, _ = numericClaim(claims["iat"])
+	identity.Nonce, _ = claims["nonce"].(string)
+
+	var expiry time.Time
+	if exp, ok := numericClaim(claims["exp"]); ok && exp > 0 {
+		expiry = time.Unix(exp, 0)
+	}
+	a.cache.Set(rawIDToken, identity, expiry)
+
+	return withIdentity(req, identity), nil
+}
+
+// SignsReplay is the implementation of core.ReplaySigner for OIDCAuth.
+// The timestamp and nonce of a request are taken from the claims
+// of its ID token, so that they are covered by its signature
+func (a *OIDCAuth) SignsReplay() bool {
+	return true
+}
+
+// Verify the provided AAD in the transaction data with the expected AAD
+func (*OIDCAuth) Verify(ctx context.Context, data auth.AuthRequest) error {
+	if data.API == "Deploy" {
+		return errors.New("OIDCAuth cannot authorize a user to deploy a service")
+	}
+
+	expectedAAD := core.MustGetAAD(ctx)
+	if string(data.AAD) != expectedAAD {
+		return errors.New("AAD does not match")
+	}
+	return nil
+}
+
+func (a *OIDCAuth) SetLogger(l log.Logger) {
+	a.logger = l
+}
+
+// withIdentity sets the identity of the user and the timestamp and
+// nonce of the request from the verified claims of the ID token. The
+// timestamp and nonce are only set if the token has the claims, so
+// that the ones of tokens that lack them are not taken as empty
+func withIdentity(req *http.Request, identity oidcIdentity) *http.Request {
+	ctx := context.WithValue(req.Context(), core.AAD{}, identity.AAD)
+	if identity.IssuedAt > 0 {
+		ctx = context.WithValue(ctx, core.ReplayTimestamp{}, strconv.FormatInt(identity.IssuedAt*1000, 10))
+	}
+	if len(identity.Nonce) > 0 {
+		ctx = context.WithValue(ctx, core.ReplayNonce{}, identity.Nonce)
+	}
+	return req.WithContext(ctx)
+}
+
+// numericClaim returns the value of a claim that is a JSON number
+func numericClaim(v interface{}) (int64, bool) {
+	n, ok := v.(float64)
+	return int64(n), ok
+}
@@ -0,0 +1,215 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/oasislabs/oasis-gateway/auth/core"
+	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/stretchr/testify/assert"
+)
+
+// rsaKeySet is a key set with a single RSA key
+type rsaKeySet struct {
+	key *rsa.PublicKey
+}
+
+func (s rsaKeySet) VerifySignature(ctx context.Context, rawJWT string) ([]byte, error) {
+	if _, err := jwt.Parse(rawJWT, func(token *jwt.Token) (interface{}, error) {
+		return s.key, nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return base64.RawURLEncoding.DecodeString(strings.Split(rawJWT, ".")[1])
+}
+
+func newOIDCRequest(t *testing.T, token string) *http.Request {
+	req, err := http.NewRequest("POST", "gateway.oasiscloud.io", nil)
+	assert.Nil(t, err)
+	req.Header.Add(OIDCTokenHeader, "Bearer "+token)
+	return req
+}
+
+func newOIDCMockRequest(t *testing.T, claims map[string]interface{}) *http.Request {
+	p, err := json.Marshal(claims)
+	assert.Nil(t, err)
+	return newOIDCRequest(t, string(p))
+}
+
+func TestOIDCAuthenticateDefaultClaim(t *testing.T) {
+	auth := NewOIDCAuth(&MockIDTokenVerifier{}, "", nil)
+
+	req, err := auth.Authenticate(newOIDCMockRequest(t, map[string]interface{}{
+		"sub":   "auth0|1234",
+		"email": "test@email.com",
+	}))
+	assert.Nil(t, err)
+	assert.Equal(t, "auth0|1234", req.Context().Value(core.AAD{}))
+}
+
+func TestOIDCAuthenticateCustomClaim(t *testing.T) {
+	auth := NewOIDCAuth(&MockIDTokenVerifier{}, "preferred_username", nil)
+
+	req, err := auth.Authenticate(newOIDCMockRequest(t, map[string]interface{}{
+		"sub":                "1234",
+		"preferred_username": "test",
+	}))
+	assert.Nil(t, err)
+	assert.Equal(t, "test", req.Context().Value(core.AAD{}))
+}
+
+func TestOIDCAuthenticateMissingClaim(t *testing.T) {
+	auth := NewOIDCAuth(&MockIDTokenVerifier{}, "preferred_username", nil)
+
+	_, err := auth.Authenticate(newOIDCMockRequest(t, map[string]interface{}{
+		"sub": "1234",
+	}))
+	assert.Error(t, err)
+	assert.Equal(t, "claim preferred_username is not set in the ID token", err.Error())
+}
+
+func TestOIDCAuthenticateNoBearerToken(t *testing.T) {
+	auth := NewOIDCAuth(&MockIDTokenVerifier{}, "", nil)
+
+	req, err := http.NewRequest("POST", "gateway.oasiscloud.io", nil)
+	assert.Nil(t, err)
+	req.Header.Add(OIDCTokenHeader, "Basic dGVzdDp0ZXN0")
+
+	_, err = auth.Authenticate(req)
+	assert.Error(t, err)
+	assert.Nil(t, req.Context().Value(core.AAD{}))
+}
+
+func TestOIDCAuthenticateUnverifiedEmail(t *testing.T) {
+	auth := NewOIDCAuth(&MockIDTokenVerifier{}, "email", nil)
+
+	_, err := auth.Authenticate(newOIDCMockRequest(t, map[string]interface{}{
+		"email":          "test@email.com",
+		"email_verified": false,
+	}))
+	assert.Error(t, err)
+	assert.Equal(t, "Email is unverified", err.Error())
+}
+
+func TestOIDCAuthenticateEmailVerifiedMissing(t *testing.T) {
+	auth := NewOIDCAuth(&MockIDTokenVerifier{}, "email", nil)
+
+	_, err := auth.Authenticate(newOIDCMockRequest(t, map[string]interface{}{
+		"email": "test@email.com",
+	}))
+	assert.Error(t, err)
+	assert.Equal(t, "Email is unverified", err.Error())
+
+	req, err := auth.Authenticate(newOIDCMockRequest(t, map[string]interface{}{
+		"email":          "test@email.com",
+		"email_verified": true,
+	}))
+	assert.Nil(t, err)
+	assert.Equal(t, "test@email.com", req.Context().Value(core.AAD{}))
+}
+
+func TestOIDCAuthenticateNoReplayClaims(t *testing.T) {
+	auth := NewOIDCAuth(&MockIDTokenVerifier{}, "", nil)
+
+	// without the claims the timestamp and nonce are taken
+	// from the headers if the replay guard accepts them
+	req, err := auth.Authenticate(newOIDCMockRequest(t, map[string]interface{}{
+		"sub": "1234",
+	}))
+	assert.Nil(t, err)
+	assert.Nil(t, req.Context().Value(core.ReplayTimestamp{}))
+	assert.Nil(t, req.Context().Value(core.ReplayNonce{}))
+}
+
+func TestOIDCAuthenticateReplayClaims(t *testing.T) {
+	auth := NewOIDCAuth(&MockIDTokenVerifier{}, "", nil)
+
+	req, err := auth.Authenticate(newOIDCMockRequest(t, map[string]interface{}{
+		"sub":   "1234",
+		"iat":   1000,
+		"nonce": "0123456789abcdef",
+	}))
+	assert.Nil(t, err)
+	assert.True(t, auth.SignsReplay())
+	assert.Equal(t, "1000000", req.Context().Value(core.ReplayTimestamp{}))
+	assert.Equal(t, "0123456789abcdef", req.Context().Value(core.ReplayNonce{}))
+}
+
+func TestOIDCAuthenticateCached(t *testing.T) {
+	verifier := &CountingIDTokenVerifier{}
+	auth := NewOIDCAuth(verifier, "", core.NewVerificationCache(core.VerificationCacheProps{
+		TTL:        time.Minute,
+		MaxEntries: 10,
+	}))
+
+	for i := 0; i < 2; i++ {
+		req, err := auth.Authenticate(newOIDCMockRequest(t, map[string]interface{}{
+			"sub": "1234",
+		}))
+		assert.Nil(t, err)
+		assert.Equal(t, "1234", req.Context().Value(core.AAD{}))
+	}
+
+	assert.Equal(t, 1, verifier.Calls)
+}
+
+func TestOIDCIDTokenVerifier(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+
+	sign := func(claims jwt.MapClaims) string {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(key)
+		assert.Nil(t, err)
+		return token
+	}
+
+	props := OIDCProps{
+		Issuer:   "https://example.auth0.com/",
+		Audience: "gateway",
+	}
+	auth := NewOIDCAuth(newOIDCIDTokenVerifier(props, rsaKeySet{key: &key.PublicKey}), "", nil)
+	exp := time.Now().Add(time.Hour).Unix()
+
+	req, err := auth.Authenticate(newOIDCRequest(t, sign(jwt.MapClaims{
+		"iss": props.Issuer,
+		"aud": props.Audience,
+		"sub": "auth0|1234",
+		"exp": exp,
+	})))
+	assert.Nil(t, err)
+	assert.Equal(t, "auth0|1234", req.Context().Value(core.AAD{}))
+
+	_, err = auth.Authenticate(newOIDCRequest(t, sign(jwt.MapClaims{
+		"iss": "https://accounts.google.com",
+		"aud": props.Audience,
+		"sub": "auth0|1234",
+		"exp": exp,
+	})))
+	assert.Error(t, err)
+
+	_, err = auth.Authenticate(newOIDCRequest(t, sign(jwt.MapClaims{
+		"iss": props.Issuer,
+		"aud": "other",
+		"sub": "auth0|1234",
+		"exp": exp,
+	})))
+	assert.Error(t, err)
+}
+
+func TestOIDCAuthSetLogger(t *testing.T) {
+	auth := NewOIDCAuth(&MockIDTokenVerifier{}, "", nil)
+	logger := log.NewLogrus(log.LogrusLoggerProperties{Output: ioutil.Discard})
+
+	auth.SetLogger(logger)
+	assert.Equal(t, logger, auth.logger)
+}
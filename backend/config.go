@@ -2,9 +2,18 @@ package backend
 
 import (
 	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/oasislabs/oasis-gateway/config"
+	ethclient "github.com/oasislabs/oasis-gateway/eth"
 	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/oasislabs/oasis-gateway/proxy"
+	"github.com/oasislabs/oasis-gateway/tx"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -12,8 +21,9 @@ import (
 type BackendProvider string
 
 const (
-	BackendEthereum BackendProvider = "ethereum"
-	BackendEkiden   BackendProvider = "ekiden"
+	BackendEthereum  BackendProvider = "ethereum"
+	BackendEkiden    BackendProvider = "ekiden"
+	BackendSimulated BackendProvider = "simulated"
 )
 
 func (m BackendProvider) String() string {
@@ -21,12 +31,33 @@ func (m BackendProvider) String() string {
 }
 
 type Config struct {
-	Provider      BackendProvider
-	BackendConfig BackendConfig
+	Provider           BackendProvider
+	DeployDedup        bool
+	IdempotencyConfig  IdempotencyConfig
+	QueryCacheConfig   QueryCacheConfig
+	QuotaConfig        QuotaConfig
+	OutputConfig       OutputConfig
+	PollConfig         PollConfig
+	SubscriptionConfig SubscriptionConfig
+	WASMConfig         WASMConfig
+	BackendConfig      BackendConfig
+
+	// ReadOnly if set the backend client is created without a
+	// wallet. It is not bound to a flag, it is set by the gateway
+	// depending on the role of the instance
+	ReadOnly bool
 }
 
 func (c *Config) Log(fields log.Fields) {
 	fields.Add("backend.provider", c.Provider)
+	fields.Add("backend.deploy_dedup", c.DeployDedup)
+	c.IdempotencyConfig.Log(fields)
+	c.QueryCacheConfig.Log(fields)
+	c.QuotaConfig.Log(fields)
+	c.OutputConfig.Log(fields)
+	c.PollConfig.Log(fields)
+	c.SubscriptionConfig.Log(fields)
+	c.WASMConfig.Log(fields)
 
 	if c.BackendConfig != nil {
 		c.BackendConfig.Log(fields)
@@ -39,6 +70,29 @@ func (c *Config) Configure(v *viper.Viper) error {
 		return config.ErrKeyNotSet{Key: "backend.provider"}
 	}
 
+	c.DeployDedup = v.GetBool("backend.deploy_dedup")
+	if err := c.IdempotencyConfig.Configure(v); err != nil {
+		return err
+	}
+	if err := c.QueryCacheConfig.Configure(v); err != nil {
+		return err
+	}
+	if err := c.QuotaConfig.Configure(v); err != nil {
+		return err
+	}
+	if err := c.OutputConfig.Configure(v); err != nil {
+		return err
+	}
+	if err := c.PollConfig.Configure(v); err != nil {
+		return err
+	}
+	if err := c.SubscriptionConfig.Configure(v); err != nil {
+		return err
+	}
+	if err := c.WASMConfig.Configure(v); err != nil {
+		return err
+	}
+
 	switch c.Provider {
 	case BackendEthereum:
 		c.BackendConfig = &EthereumConfig{}
@@ -48,11 +102,18 @@ func (c *Config) Configure(v *viper.Viper) error {
 			Key:   "backend.provider",
 			Value: BackendEkiden.String(),
 		}
+	case BackendSimulated:
+		c.BackendConfig = &SimulatedConfig{}
+		return c.BackendConfig.(*SimulatedConfig).Configure(v)
 	default:
 		return config.ErrInvalidValue{
 			Key:          "backend.provider",
 			InvalidValue: c.Provider.String(),
-			Values:       []string{BackendEthereum.String(), BackendEkiden.String()},
+			Values: []string{
+				BackendEthereum.String(),
+				BackendEkiden.String(),
+				BackendSimulated.String(),
+			},
 		}
 	}
 }
@@ -61,12 +122,367 @@ func (c *Config) Bind(v *viper.Viper, cmd *cobra.Command) error {
 	cmd.PersistentFlags().String("backend.provider", "ethereum",
 		"provider for the mailbox service. "+
 			"Options are "+BackendEthereum.String()+
-			", "+BackendEkiden.String()+".")
+			", "+BackendEkiden.String()+
+			", "+BackendSimulated.String()+". The "+BackendSimulated.String()+
+			" backend keeps services in memory and is only meant for local development.")
+	cmd.PersistentFlags().Bool("backend.deploy_dedup", false,
+		"if set, a deployment of bytecode that has already been deployed "+
+			"by the same AAD returns the address of the existing service "+
+			"instead of deploying it again.")
+
+	if err := c.IdempotencyConfig.Bind(v, cmd); err != nil {
+		return err
+	}
+
+	if err := c.QueryCacheConfig.Bind(v, cmd); err != nil {
+		return err
+	}
+
+	if err := c.QuotaConfig.Bind(v, cmd); err != nil {
+		return err
+	}
+
+	if err := c.OutputConfig.Bind(v, cmd); err != nil {
+		return err
+	}
+
+	if err := c.PollConfig.Bind(v, cmd); err != nil {
+		return err
+	}
+
+	if err := c.SubscriptionConfig.Bind(v, cmd); err != nil {
+		return err
+	}
+
+	if err := c.WASMConfig.Bind(v, cmd); err != nil {
+		return err
+	}
 
 	if err := (&EthereumConfig{}).Bind(v, cmd); err != nil {
 		return err
 	}
 
+	if err := (&SimulatedConfig{}).Bind(v, cmd); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// QueryCacheConfig holds the configuration of the cache for the
+// results of read-only queries
+type QueryCacheConfig struct {
+	// TTL is the time a query result is cached. If 0 results
+	// are not cached
+	TTL time.Duration
+
+	// MaxEntries is the maximum number of results cached
+	MaxEntries int
+}
+
+func (c *QueryCacheConfig) Log(fields log.Fields) {
+	fields.Add("backend.query_cache.ttl_ms", int64(c.TTL/time.Millisecond))
+	fields.Add("backend.query_cache.max_entries", c.MaxEntries)
+}
+
+func (c *QueryCacheConfig) Configure(v *viper.Viper) error {
+	ttl := v.GetInt64("backend.query_cache.ttl_ms")
+	if ttl < 0 {
+		return errors.New("backend.query_cache.ttl_ms cannot be negative")
+	}
+
+	c.MaxEntries = v.GetInt("backend.query_cache.max_entries")
+	if c.MaxEntries < 0 {
+		return errors.New("backend.query_cache.max_entries cannot be negative")
+	}
+
+	c.TTL = time.Duration(ttl) * time.Millisecond
+	return nil
+}
+
+func (c *QueryCacheConfig) Bind(v *viper.Viper, cmd *cobra.Command) error {
+	cmd.PersistentFlags().Int64("backend.query_cache.ttl_ms", 0,
+		"time in milliseconds the result of a read-only query is cached. "+
+			"If 0 results are not cached.")
+	cmd.PersistentFlags().Int("backend.query_cache.max_entries", 1024,
+		"maximum number of read-only query results cached.")
+	return nil
+}
+
+// OutputConfig holds the configuration of the truncation of the
+// outputs of the executions that are too large to be included in
+// their events
+type OutputConfig struct {
+	// MaxSize is the maximum size of the output included in an
+	// execution event. If 0 outputs are never truncated
+	MaxSize int
+
+	// PreviewSize is the size of the output kept in the event
+	// when the output is truncated
+	PreviewSize int
+
+	// TTL is the time the full output is kept after the execution
+	TTL time.Duration
+}
+
+func (c *OutputConfig) Log(fields log.Fields) {
+	fields.Add("backend.output.max_size", c.MaxSize)
+	fields.Add("backend.output.preview_size", c.PreviewSize)
+	fields.Add("backend.output.ttl_ms", int64(c.TTL/time.Millisecond))
+}
+
+func (c *OutputConfig) Configure(v *viper.Viper) error {
+	c.MaxSize = v.GetInt("backend.output.max_size")
+	if c.MaxSize < 0 {
+		return errors.New("backend.output.max_size cannot be negative")
+	}
+
+	c.PreviewSize = v.GetInt("backend.output.preview_size")
+	if c.PreviewSize <= 0 {
+		return errors.New("backend.output.preview_size must be positive")
+	}
+
+	ttl := v.GetInt64("backend.output.ttl_ms")
+	if ttl <= 0 {
+		return errors.New("backend.output.ttl_ms must be positive")
+	}
+
+	c.TTL = time.Duration(ttl) * time.Millisecond
+	return nil
+}
+
+func (c *OutputConfig) Bind(v *viper.Viper, cmd *cobra.Command) error {
+	cmd.PersistentFlags().Int("backend.output.max_size", 0,
+		"maximum size in characters of the output included in an execution event. Larger outputs "+
+			"are replaced by a preview and can be retrieved in full from /v0/api/service/getOutput. "+
+			"If 0 outputs are never truncated.")
+	cmd.PersistentFlags().Int("backend.output.preview_size", 256,
+		"size in characters of the preview of a truncated output.")
+	cmd.PersistentFlags().Int64("backend.output.ttl_ms", 600000,
+		"time in milliseconds the full output of an execution is kept after it is truncated.")
+	return nil
+}
+
+// IdempotencyConfig holds the configuration of the records of the
+// idempotency keys provided by the clients
+type IdempotencyConfig struct {
+	// TTL is the time after which an idempotency key can be used
+	// again. If 0 the keys do not expire
+	TTL time.Duration
+}
+
+func (c *IdempotencyConfig) Log(fields log.Fields) {
+	fields.Add("backend.idempotency.ttl_ms", int64(c.TTL/time.Millisecond))
+}
+
+func (c *IdempotencyConfig) Configure(v *viper.Viper) error {
+	ttl := v.GetInt64("backend.idempotency.ttl_ms")
+	if ttl < 0 {
+		return errors.New("backend.idempotency.ttl_ms cannot be negative")
+	}
+
+	c.TTL = time.Duration(ttl) * time.Millisecond
+	return nil
+}
+
+func (c *IdempotencyConfig) Bind(v *viper.Viper, cmd *cobra.Command) error {
+	cmd.PersistentFlags().Int64("backend.idempotency.ttl_ms", 0,
+		"time in milliseconds after which an idempotency key provided by a client can be used again. "+
+			"If 0 the keys do not expire.")
+	return nil
+}
+
+// QuotaConfig holds the configuration of the quotas of the sessions,
+// of the warnings sent to sessions that approach them and of the
+// number of requests a session may have in flight
+type QuotaConfig struct {
+	// QueueSize is the number of events that can be kept in the
+	// queue of a session before its requests are rejected
+	QueueSize uint64
+
+	// SpendLimit is the amount of gas the transactions of an
+	// issuer may spend. If 0 the gas spent is not limited
+	SpendLimit uint64
+
+	// WarnPercent is the percentage of a quota at which a session
+	// is warned. If 0 sessions are not warned
+	WarnPercent uint64
+
+	// MaxInFlight is the maximum number of execute and deploy
+	// requests of a session that may be in flight. If 0 the
+	// requests in flight are not limited
+	MaxInFlight uint64
+}
+
+func (c *QuotaConfig) Log(fields log.Fields) {
+	fields.Add("backend.quota.queue_size", c.QueueSize)
+	fields.Add("backend.quota.spend_limit", c.SpendLimit)
+	fields.Add("backend.quota.warn_percent", c.WarnPercent)
+	fields.Add("backend.quota.max_in_flight", c.MaxInFlight)
+}
+
+func (c *QuotaConfig) Configure(v *viper.Viper) error {
+	c.QueueSize = v.GetUint64("backend.quota.queue_size")
+	c.SpendLimit = v.GetUint64("backend.quota.spend_limit")
+	c.MaxInFlight = v.GetUint64("backend.quota.max_in_flight")
+	c.WarnPercent = v.GetUint64("backend.quota.warn_percent")
+	if c.WarnPercent > 100 {
+		return errors.New("backend.quota.warn_percent cannot be greater than 100")
+	}
+
+	return nil
+}
+
+func (c *QuotaConfig) Bind(v *viper.Viper, cmd *cobra.Command) error {
+	cmd.PersistentFlags().Uint64("backend.quota.queue_size", 1024,
+		"number of events that can be kept in the queue of a session "+
+			"before its requests are rejected. If the mailbox keeps fewer "+
+			"events per queue its limit applies instead.")
+	cmd.PersistentFlags().Uint64("backend.quota.spend_limit", 0,
+		"amount of gas the transactions of an issuer can spend before its "+
+			"requests are rejected. If 0 the gas spent is not limited.")
+	cmd.PersistentFlags().Uint64("backend.quota.max_in_flight", 0,
+		"maximum number of execute and deploy requests of a session that can be "+
+			"in flight. Further requests are rejected until some complete. If 0 "+
+			"the requests in flight are not limited.")
+	cmd.PersistentFlags().Uint64("backend.quota.warn_percent", 0,
+		"percentage of a quota at which a warning event is inserted in the "+
+			"queue of the session. If 0 sessions are not warned.")
+	return nil
+}
+
+// PollConfig holds the limits on the number of events
+// returned to a client by a single poll
+type PollConfig struct {
+	// DefaultCount is the number of events returned by a poll
+	// that does not set a count
+	DefaultCount uint
+
+	// MaxCount is the maximum number of events returned by a
+	// poll. Larger counts requested are capped to MaxCount
+	MaxCount uint
+}
+
+func (c *PollConfig) Log(fields log.Fields) {
+	fields.Add("backend.poll.default_count", c.DefaultCount)
+	fields.Add("backend.poll.max_count", c.MaxCount)
+}
+
+func (c *PollConfig) Configure(v *viper.Viper) error {
+	c.DefaultCount = v.GetUint("backend.poll.default_count")
+	c.MaxCount = v.GetUint("backend.poll.max_count")
+	if c.MaxCount == 0 {
+		return errors.New("backend.poll.max_count must be positive")
+	}
+	if c.DefaultCount == 0 || c.DefaultCount > c.MaxCount {
+		return errors.New("backend.poll.default_count must be positive and not exceed backend.poll.max_count")
+	}
+
+	return nil
+}
+
+func (c *PollConfig) Bind(v *viper.Viper, cmd *cobra.Command) error {
+	cmd.PersistentFlags().Uint("backend.poll.default_count", 10,
+		"number of events returned by a poll that does not set a count.")
+	cmd.PersistentFlags().Uint("backend.poll.max_count", 100,
+		"maximum number of events returned by a poll. Larger counts are capped to it.")
+	return nil
+}
+
+// SubscriptionConfig holds the configuration of the checks on
+// the consumers of the subscriptions that lag behind
+type SubscriptionConfig struct {
+	// MaxLag is the number of events of a subscription that its
+	// consumer may not have acknowledged. If 0 the lag is not checked
+	MaxLag uint64
+
+	// MaxAge is the time the oldest event of a subscription that its
+	// consumer has not acknowledged may be kept. If 0 the age of
+	// the events is not checked
+	MaxAge time.Duration
+
+	// AutoUnsubscribe if set closes the subscriptions whose
+	// consumers lag behind instead of only warning them
+	AutoUnsubscribe bool
+}
+
+func (c *SubscriptionConfig) Log(fields log.Fields) {
+	fields.Add("backend.subscription.max_lag", c.MaxLag)
+	fields.Add("backend.subscription.max_age_ms", int64(c.MaxAge/time.Millisecond))
+	fields.Add("backend.subscription.auto_unsubscribe", c.AutoUnsubscribe)
+}
+
+func (c *SubscriptionConfig) Configure(v *viper.Viper) error {
+	c.MaxLag = v.GetUint64("backend.subscription.max_lag")
+
+	maxAge := v.GetInt64("backend.subscription.max_age_ms")
+	if maxAge < 0 {
+		return errors.New("backend.subscription.max_age_ms cannot be negative")
+	}
+	c.MaxAge = time.Duration(maxAge) * time.Millisecond
+
+	c.AutoUnsubscribe = v.GetBool("backend.subscription.auto_unsubscribe")
+	if c.AutoUnsubscribe && c.MaxLag == 0 && c.MaxAge == 0 {
+		return errors.New("backend.subscription.auto_unsubscribe requires " +
+			"backend.subscription.max_lag or backend.subscription.max_age_ms to be set")
+	}
+
+	return nil
+}
+
+func (c *SubscriptionConfig) Bind(v *viper.Viper, cmd *cobra.Command) error {
+	cmd.PersistentFlags().Uint64("backend.subscription.max_lag", 0,
+		"number of events of a subscription that its consumer may not have acknowledged "+
+			"before it is warned. If 0 the lag of the consumers is not checked.")
+	cmd.PersistentFlags().Int64("backend.subscription.max_age_ms", 0,
+		"time in milliseconds the oldest event of a subscription that its consumer has not "+
+			"acknowledged may be kept before it is warned. If 0 the age of the events is not checked.")
+	cmd.PersistentFlags().Bool("backend.subscription.auto_unsubscribe", false,
+		"if set, the subscriptions whose consumers exceed backend.subscription.max_lag or "+
+			"backend.subscription.max_age_ms are closed with a terminal error event.")
+	return nil
+}
+
+// WASMConfig holds the configuration of the deployment of
+// services as WASM payloads, for runtimes that accept them
+type WASMConfig struct {
+	// Enabled if set allows clients to deploy WASM payloads
+	Enabled bool
+
+	// MaxSize is the maximum size in bytes of a WASM payload
+	MaxSize int
+
+	// Validate if set rejects the WASM payloads that do
+	// not start with a valid WASM header
+	Validate bool
+}
+
+func (c *WASMConfig) Log(fields log.Fields) {
+	fields.Add("backend.wasm.enabled", c.Enabled)
+	fields.Add("backend.wasm.max_size", c.MaxSize)
+	fields.Add("backend.wasm.validate", c.Validate)
+}
+
+func (c *WASMConfig) Configure(v *viper.Viper) error {
+	c.Enabled = v.GetBool("backend.wasm.enabled")
+	c.MaxSize = v.GetInt("backend.wasm.max_size")
+	if c.MaxSize <= 0 {
+		return errors.New("backend.wasm.max_size must be positive")
+	}
+	c.Validate = v.GetBool("backend.wasm.validate")
+
+	return nil
+}
+
+func (c *WASMConfig) Bind(v *viper.Viper, cmd *cobra.Command) error {
+	cmd.PersistentFlags().Bool("backend.wasm.enabled", false,
+		"if set, clients can deploy services as WASM payloads. "+
+			"Only for runtimes that accept WASM rather than EVM bytecode.")
+	cmd.PersistentFlags().Int("backend.wasm.max_size", 2*1024*1024,
+		"maximum size in bytes of a WASM payload. Payloads are also "+
+			"limited by bind_public.max_body_bytes.")
+	cmd.PersistentFlags().Bool("backend.wasm.validate", true,
+		"if set, WASM payloads that do not start with a valid WASM header are rejected.")
 	return nil
 }
 
@@ -77,12 +493,41 @@ type BackendConfig interface {
 }
 
 type EthereumConfig struct {
-	URL          string
-	WalletConfig WalletConfig
+	URL              string
+	OutputMode       tx.OutputMode
+	ResolverRegistry string
+	WalletConfig     WalletConfig
+	TimeoutConfig    TimeoutConfig
+	SchedulingConfig SchedulingConfig
+	SelectionConfig  SelectionConfig
+	GasPriceConfig   GasPriceConfig
+	GasLimitConfig   GasLimitConfig
+
+	// Proxy used to reach the eth endpoint. If nil the
+	// proxies defined in the environment are used
+	Proxy *proxy.Proxy
+
+	// MaxTransactionLifetime is the maximum time a transaction
+	// can take before it fails with a timeout
+	MaxTransactionLifetime time.Duration
+
+	// LogPollInterval is the interval at which new logs are
+	// polled for on http endpoints
+	LogPollInterval time.Duration
 }
 
 func (c *EthereumConfig) Log(fields log.Fields) {
 	fields.Add("eth.url", c.URL)
+	fields.Add("eth.output_mode", c.OutputMode)
+	fields.Add("eth.resolver_registry", c.ResolverRegistry)
+	fields.Add("eth.proxy", c.Proxy.String())
+	fields.Add("eth.max_transaction_lifetime_ms", int64(c.MaxTransactionLifetime/time.Millisecond))
+	fields.Add("eth.log_poll_interval_ms", int64(c.LogPollInterval/time.Millisecond))
+	c.TimeoutConfig.Log(fields)
+	c.SchedulingConfig.Log(fields)
+	c.SelectionConfig.Log(fields)
+	c.GasPriceConfig.Log(fields)
+	c.GasLimitConfig.Log(fields)
 }
 
 func (c *EthereumConfig) Configure(v *viper.Viper) error {
@@ -91,6 +536,69 @@ func (c *EthereumConfig) Configure(v *viper.Viper) error {
 		return errors.New("eth.url must be set")
 	}
 
+	c.OutputMode = tx.OutputMode(v.GetString("eth.output_mode"))
+	switch c.OutputMode {
+	case "":
+		c.OutputMode = tx.OutputModeInvoke
+	case tx.OutputModeInvoke, tx.OutputModeTrace, tx.OutputModeCall:
+	default:
+		return config.ErrInvalidValue{
+			Key:          "eth.output_mode",
+			InvalidValue: c.OutputMode.String(),
+			Values: []string{
+				tx.OutputModeInvoke.String(),
+				tx.OutputModeTrace.String(),
+				tx.OutputModeCall.String(),
+			},
+		}
+	}
+
+	c.ResolverRegistry = v.GetString("eth.resolver_registry")
+	if len(c.ResolverRegistry) > 0 && !common.IsHexAddress(c.ResolverRegistry) {
+		return errors.New("eth.resolver_registry must be a valid address")
+	}
+
+	p, err := proxy.Parse(v.GetString("eth.proxy"))
+	if err != nil {
+		return err
+	}
+	if p.Explicit() && !strings.HasPrefix(c.URL, "http") {
+		return errors.New("eth.proxy can only be set for http and https eth.url")
+	}
+	c.Proxy = p
+
+	lifetime := v.GetInt64("eth.max_transaction_lifetime_ms")
+	if lifetime < 0 {
+		return errors.New("eth.max_transaction_lifetime_ms cannot be negative")
+	}
+	c.MaxTransactionLifetime = time.Duration(lifetime) * time.Millisecond
+
+	interval := v.GetInt64("eth.log_poll_interval_ms")
+	if interval <= 0 {
+		return errors.New("eth.log_poll_interval_ms must be positive")
+	}
+	c.LogPollInterval = time.Duration(interval) * time.Millisecond
+
+	if err := c.TimeoutConfig.Configure(v); err != nil {
+		return err
+	}
+
+	if err := c.SchedulingConfig.Configure(v); err != nil {
+		return err
+	}
+
+	if err := c.SelectionConfig.Configure(v); err != nil {
+		return err
+	}
+
+	if err := c.GasPriceConfig.Configure(v); err != nil {
+		return err
+	}
+
+	if err := c.GasLimitConfig.Configure(v); err != nil {
+		return err
+	}
+
 	return c.WalletConfig.Configure(v)
 }
 
@@ -99,38 +607,550 @@ func (c *EthereumConfig) ID() BackendProvider {
 }
 
 func (c *EthereumConfig) Bind(v *viper.Viper, cmd *cobra.Command) error {
-	cmd.PersistentFlags().String("eth.url", "", "url for the eth endpoint")
+	cmd.PersistentFlags().String("eth.url", "", "url for the eth endpoint. Supported schemes are ws, wss, http and https. On http endpoints subscriptions poll the node for new logs")
+	cmd.PersistentFlags().String("eth.output_mode", tx.OutputModeInvoke.String(),
+		"mechanism used to retrieve the output of a transaction. "+
+			"Options are "+tx.OutputModeInvoke.String()+
+			", "+tx.OutputModeTrace.String()+
+			", "+tx.OutputModeCall.String()+".")
+	cmd.PersistentFlags().String("eth.resolver_registry", "",
+		"address of the on-chain registry used to resolve service names "+
+			"to addresses. If not set names are not resolved on-chain.")
+	cmd.PersistentFlags().String("eth.proxy", proxy.Environment,
+		"url of the http, https or socks5 proxy used to reach an http eth.url, or "+
+			proxy.Direct+" to not use a proxy. If not set the proxy is taken from "+
+			"the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables.")
+	cmd.PersistentFlags().Int64("eth.max_transaction_lifetime_ms", 300000,
+		"maximum time in milliseconds a transaction can take to complete. Transactions that take "+
+			"longer fail with a timeout error event. If 0 transactions do not time out.")
+	cmd.PersistentFlags().Int64("eth.log_poll_interval_ms", int64(ethclient.LogPollInterval/time.Millisecond),
+		"time in milliseconds between polls for new logs on http and https eth.url, which do not support subscriptions.")
+	if err := c.TimeoutConfig.Bind(v, cmd); err != nil {
+		return err
+	}
+
+	if err := c.SchedulingConfig.Bind(v, cmd); err != nil {
+		return err
+	}
+
+	if err := c.SelectionConfig.Bind(v, cmd); err != nil {
+		return err
+	}
+
+	if err := c.GasPriceConfig.Bind(v, cmd); err != nil {
+		return err
+	}
+
+	if err := c.GasLimitConfig.Bind(v, cmd); err != nil {
+		return err
+	}
+
 	return c.WalletConfig.Bind(v, cmd)
 }
 
+// SimulatedConfig holds the configuration of the simulated backend
+type SimulatedConfig struct {
+	// Latency is the time simulated transactions take to complete
+	Latency time.Duration
+}
+
+func (c *SimulatedConfig) Log(fields log.Fields) {
+	fields.Add("simulated.latency_ms", int64(c.Latency/time.Millisecond))
+}
+
+func (c *SimulatedConfig) Configure(v *viper.Viper) error {
+	latency := v.GetInt64("simulated.latency_ms")
+	if latency < 0 {
+		return errors.New("simulated.latency_ms cannot be negative")
+	}
+	c.Latency = time.Duration(latency) * time.Millisecond
+
+	return nil
+}
+
+func (c *SimulatedConfig) ID() BackendProvider {
+	return BackendSimulated
+}
+
+func (c *SimulatedConfig) Bind(v *viper.Viper, cmd *cobra.Command) error {
+	cmd.PersistentFlags().Int64("simulated.latency_ms", 0,
+		"time in milliseconds that transactions take to complete on the simulated backend.")
+	return nil
+}
+
 // WalletConfig holds the configuration of a single wallet
 type WalletConfig struct {
 	// PrivateKeys for the wallet
 	PrivateKeys []string
+
+	// Accounts are the private keys of the wallets of each account,
+	// which only sign the transactions of the AADs mapped to them
+	Accounts map[string][]string
+
+	// AccountAADs maps AADs to the account whose wallets sign
+	// their transactions
+	AccountAADs map[string]string
+}
+
+// ClearKeys drops the references to the private keys once they
+// have been parsed. Go strings cannot be zeroized, so this only
+// lets the garbage collector reclaim them. The viper instance the
+// configuration was read from keeps its own copy of the keys, as
+// does the environment or file they were read from
+func (c *WalletConfig) ClearKeys() {
+	for i := range c.PrivateKeys {
+		c.PrivateKeys[i] = ""
+	}
+	c.PrivateKeys = nil
+
+	for name, keys := range c.Accounts {
+		for i := range keys {
+			keys[i] = ""
+		}
+		c.Accounts[name] = nil
+	}
 }
 
 func (c *WalletConfig) Log(fields log.Fields) {
 	// do not log the private keys themselves
 	fields.Add("eth.wallet.private_keys", len(c.PrivateKeys))
+
+	accounts := make([]string, 0, len(c.Accounts))
+	for name, keys := range c.Accounts {
+		accounts = append(accounts, fmt.Sprintf("%s=%d", name, len(keys)))
+	}
+	sort.Strings(accounts)
+	fields.Add("eth.wallet.accounts", strings.Join(accounts, ","))
+	fields.Add("eth.wallet.account_aads", len(c.AccountAADs))
 }
 
 func (c *WalletConfig) Configure(v *viper.Viper) error {
 	c.PrivateKeys = v.GetStringSlice("eth.wallet.private_keys")
 
-	if len(c.PrivateKeys) == 0 {
-		return errors.New("eth.wallet.private_keys must be set")
-	}
-
+	// an empty set of keys is validated when the client is created,
+	// since instances that do not submit transactions do not need them
 	for _, key := range c.PrivateKeys {
 		if len(key) == 0 {
 			return errors.New("eth.wallet.private_keys cannot have empty keys")
 		}
 	}
 
+	c.Accounts = make(map[string][]string)
+	for _, account := range v.GetStringSlice("eth.wallet.accounts") {
+		parts := strings.SplitN(account, ":", 2)
+		if len(parts) != 2 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+			// the value is not included in the error since it may
+			// contain a private key
+			return errors.New("eth.wallet.accounts must be of the form <account>:<private key>")
+		}
+		c.Accounts[parts[0]] = append(c.Accounts[parts[0]], parts[1])
+	}
+
+	c.AccountAADs = make(map[string]string)
+	for _, mapping := range v.GetStringSlice("eth.wallet.account_aads") {
+		// AADs may be base64 encoded, so the account name is
+		// split at the last '='
+		i := strings.LastIndex(mapping, "=")
+		if i <= 0 || i == len(mapping)-1 {
+			return fmt.Errorf("eth.wallet.account_aads %q must be of the form <aad>=<account>", mapping)
+		}
+
+		aad, account := mapping[:i], mapping[i+1:]
+		if _, ok := c.Accounts[account]; !ok {
+			return fmt.Errorf("eth.wallet.account_aads maps %s to account %s which is not in eth.wallet.accounts", aad, account)
+		}
+		c.AccountAADs[aad] = account
+	}
+
 	return nil
 }
 
 func (c *WalletConfig) Bind(v *viper.Viper, cmd *cobra.Command) error {
 	cmd.PersistentFlags().StringSlice("eth.wallet.private_keys", []string{}, "private keys for the wallet")
+	cmd.PersistentFlags().StringSlice("eth.wallet.accounts", nil,
+		"private keys of the wallets of separate accounts of the form <account>:<private key>. "+
+			"An account can have more than one wallet. The wallets of an account only sign the "+
+			"transactions of the AADs mapped to it in eth.wallet.account_aads.")
+	cmd.PersistentFlags().StringSlice("eth.wallet.account_aads", nil,
+		"AADs whose transactions are signed by the wallets of an account, of the form <aad>=<account>. "+
+			"The transactions of the AADs that are not mapped are signed by eth.wallet.private_keys.")
+	return nil
+}
+
+// TimeoutConfig holds the configuration of the timeouts of the calls
+// to the node, which are derived from the latencies of the previous
+// calls to the same method
+type TimeoutConfig struct {
+	// Percentile of the latencies used as the base of the timeouts
+	Percentile float64
+
+	// Factor multiplies the latency at Percentile to get the timeout
+	Factor float64
+
+	// Min is the lower bound of the timeouts
+	Min time.Duration
+
+	// Max is the upper bound of the timeouts. If it is 0 the
+	// calls to the node do not time out
+	Max time.Duration
+}
+
+func (c *TimeoutConfig) Log(fields log.Fields) {
+	fields.Add("eth.timeout.percentile", c.Percentile)
+	fields.Add("eth.timeout.factor", c.Factor)
+	fields.Add("eth.timeout.min_ms", int64(c.Min/time.Millisecond))
+	fields.Add("eth.timeout.max_ms", int64(c.Max/time.Millisecond))
+}
+
+func (c *TimeoutConfig) Configure(v *viper.Viper) error {
+	max := v.GetInt64("eth.timeout.max_ms")
+	if max < 0 {
+		return errors.New("eth.timeout.max_ms cannot be negative")
+	}
+	c.Max = time.Duration(max) * time.Millisecond
+	if c.Max == 0 {
+		return nil
+	}
+
+	min := v.GetInt64("eth.timeout.min_ms")
+	if min <= 0 {
+		return errors.New("eth.timeout.min_ms must be positive")
+	}
+	c.Min = time.Duration(min) * time.Millisecond
+	if c.Min > c.Max {
+		return errors.New("eth.timeout.min_ms cannot be greater than eth.timeout.max_ms")
+	}
+
+	c.Percentile = v.GetFloat64("eth.timeout.percentile")
+	if c.Percentile <= 0 || c.Percentile > 1 {
+		return errors.New("eth.timeout.percentile must be greater than 0 and at most 1")
+	}
+
+	c.Factor = v.GetFloat64("eth.timeout.factor")
+	if c.Factor < 1 {
+		return errors.New("eth.timeout.factor must be at least 1")
+	}
+
+	return nil
+}
+
+func (c *TimeoutConfig) Bind(v *viper.Viper, cmd *cobra.Command) error {
+	cmd.PersistentFlags().Float64("eth.timeout.percentile", 0.99,
+		"percentile of the latencies of the calls to the node used as the base of their timeout.")
+	cmd.PersistentFlags().Float64("eth.timeout.factor", 3,
+		"factor by which the latency at eth.timeout.percentile is multiplied to get the timeout of a call to the node.")
+	cmd.PersistentFlags().Int64("eth.timeout.min_ms", 1000,
+		"minimum timeout in milliseconds of a call to the node.")
+	cmd.PersistentFlags().Int64("eth.timeout.max_ms", 30000,
+		"maximum timeout in milliseconds of a call to the node, also used until enough latencies are known. "+
+			"If 0 the calls to the node do not time out.")
+	return nil
+}
+
+// SchedulingConfig holds the configuration of the order in which the
+// transactions of the AADs that share the same wallets are submitted
+type SchedulingConfig struct {
+	// Policy is the scheduling policy of the transactions
+	Policy tx.SchedulingPolicy
+
+	// Weights are the weights of the AADs for the weighted policy
+	Weights map[string]uint64
+}
+
+func (c *SchedulingConfig) Log(fields log.Fields) {
+	fields.Add("eth.scheduling.policy", c.Policy.String())
+	fields.Add("eth.scheduling.weights", len(c.Weights))
+}
+
+func (c *SchedulingConfig) Configure(v *viper.Viper) error {
+	c.Policy = tx.SchedulingPolicy(v.GetString("eth.scheduling.policy"))
+	switch c.Policy {
+	case "":
+		c.Policy = tx.SchedulingFIFO
+	case tx.SchedulingFIFO, tx.SchedulingFair, tx.SchedulingWeighted:
+	default:
+		return config.ErrInvalidValue{
+			Key:          "eth.scheduling.policy",
+			InvalidValue: c.Policy.String(),
+			Values: []string{
+				tx.SchedulingFIFO.String(),
+				tx.SchedulingFair.String(),
+				tx.SchedulingWeighted.String(),
+			},
+		}
+	}
+
+	c.Weights = make(map[string]uint64)
+	for _, weight := range v.GetStringSlice("eth.scheduling.weights") {
+		// AADs may be base64 encoded, so the weight is
+		// split at the last '='
+		i := strings.LastIndex(weight, "=")
+		if i <= 0 || i == len(weight)-1 {
+			return fmt.Errorf("eth.scheduling.weights %q must be of the form <aad>=<weight>", weight)
+		}
+
+		n, err := strconv.ParseUint(weight[i+1:], 10, 64)
+		if err != nil || n == 0 {
+			return fmt.Errorf("eth.scheduling.weights %q must have a positive integer weight", weight)
+		}
+		c.Weights[weight[:i]] = n
+	}
+
+	if len(c.Weights) > 0 && c.Policy != tx.SchedulingWeighted {
+		return fmt.Errorf("eth.scheduling.weights can only be set with the %s eth.scheduling.policy",
+			tx.SchedulingWeighted)
+	}
+
+	return nil
+}
+
+func (c *SchedulingConfig) Bind(v *viper.Viper, cmd *cobra.Command) error {
+	cmd.PersistentFlags().String("eth.scheduling.policy", tx.SchedulingFIFO.String(),
+		"order in which the transactions of the AADs that share the same wallets are submitted. "+
+			"Options are "+tx.SchedulingFIFO.String()+" to submit them in the order they arrive, "+
+			tx.SchedulingFair.String()+" to take turns amongst the AADs with transactions waiting and "+
+			tx.SchedulingWeighted.String()+" to take turns submitting as many transactions as the "+
+			"weight of each AAD in eth.scheduling.weights.")
+	cmd.PersistentFlags().StringSlice("eth.scheduling.weights", nil,
+		"weights of the AADs for the "+tx.SchedulingWeighted.String()+" eth.scheduling.policy, "+
+			"of the form <aad>=<weight>. The AADs without a weight have a weight of 1.")
+	return nil
+}
+
+// SelectionConfig holds the configuration of how the wallet
+// that submits a transaction is chosen
+type SelectionConfig struct {
+	// Selection defines how the wallet is chosen
+	Selection tx.WalletSelection
+
+	// DrainedRetry is the time a wallet that ran out of
+	// funds is skipped
+	DrainedRetry time.Duration
+}
+
+func (c *SelectionConfig) Log(fields log.Fields) {
+	fields.Add("eth.wallet.selection", c.Selection.String())
+	fields.Add("eth.wallet.drained_retry_ms", int64(c.DrainedRetry/time.Millisecond))
+}
+
+func (c *SelectionConfig) Configure(v *viper.Viper) error {
+	c.Selection = tx.WalletSelection(v.GetString("eth.wallet.selection"))
+	switch c.Selection {
+	case "":
+		c.Selection = tx.WalletSelectionShared
+	case tx.WalletSelectionShared, tx.WalletSelectionRoundRobin, tx.WalletSelectionLeastPending:
+	default:
+		return config.ErrInvalidValue{
+			Key:          "eth.wallet.selection",
+			InvalidValue: c.Selection.String(),
+			Values: []string{
+				tx.WalletSelectionShared.String(),
+				tx.WalletSelectionRoundRobin.String(),
+				tx.WalletSelectionLeastPending.String(),
+			},
+		}
+	}
+
+	retry := v.GetInt64("eth.wallet.drained_retry_ms")
+	if retry <= 0 {
+		return errors.New("eth.wallet.drained_retry_ms must be positive")
+	}
+	c.DrainedRetry = time.Duration(retry) * time.Millisecond
+
+	return nil
+}
+
+func (c *SelectionConfig) Bind(v *viper.Viper, cmd *cobra.Command) error {
+	cmd.PersistentFlags().String("eth.wallet.selection", tx.WalletSelectionShared.String(),
+		"how the wallet that submits a transaction is chosen. "+
+			"Options are "+tx.WalletSelectionShared.String()+" for the first wallet that is idle, "+
+			tx.WalletSelectionRoundRobin.String()+" to take turns amongst the wallets and "+
+			tx.WalletSelectionLeastPending.String()+" for the wallet with the fewest transactions pending. "+
+			"With "+tx.WalletSelectionRoundRobin.String()+" and "+tx.WalletSelectionLeastPending.String()+
+			" the transactions of a wallet that runs out of funds are submitted by the other wallets.")
+	cmd.PersistentFlags().Int64("eth.wallet.drained_retry_ms", int64(tx.DefaultDrainedRetry/time.Millisecond),
+		"time in milliseconds a wallet that runs out of funds is skipped before it is chosen again.")
+	return nil
+}
+
+// GasPriceConfig holds the configuration of how the gas price
+// of the transactions is chosen
+type GasPriceConfig struct {
+	// Mode defines how the gas price is chosen
+	Mode tx.GasPriceMode
+
+	// Price is the gas price of the fixed mode, also used by the
+	// percentile mode when the recent blocks have no transactions
+	Price uint64
+
+	// Percentile of the gas prices of the recent transactions
+	// used by the percentile mode
+	Percentile float64
+
+	// Blocks is the number of recent blocks used by the
+	// percentile mode
+	Blocks uint64
+
+	// EscalationInterval if not 0 is the time after which a
+	// transaction that has not been mined is replaced by one
+	// with an escalated gas price
+	EscalationInterval time.Duration
+
+	// EscalationFactor multiplies the gas price at each
+	// EscalationInterval
+	EscalationFactor float64
+
+	// Max is the upper bound of the gas price. If it is 0
+	// the gas price is not bounded
+	Max uint64
+
+	// DynamicFees if set submits EIP-1559 dynamic fee transactions
+	// on the chains that support them
+	DynamicFees bool
+
+	// PriorityFee is the maxPriorityFeePerGas of the
+	// dynamic fee transactions
+	PriorityFee uint64
+}
+
+func (c *GasPriceConfig) Log(fields log.Fields) {
+	fields.Add("eth.gas_price.mode", c.Mode.String())
+	fields.Add("eth.gas_price.price", c.Price)
+	fields.Add("eth.gas_price.percentile", c.Percentile)
+	fields.Add("eth.gas_price.blocks", c.Blocks)
+	fields.Add("eth.gas_price.escalation_interval_ms", int64(c.EscalationInterval/time.Millisecond))
+	fields.Add("eth.gas_price.escalation_factor", c.EscalationFactor)
+	fields.Add("eth.gas_price.max", c.Max)
+	fields.Add("eth.gas_price.dynamic_fees", c.DynamicFees)
+	fields.Add("eth.gas_price.priority_fee", c.PriorityFee)
+}
+
+func (c *GasPriceConfig) Configure(v *viper.Viper) error {
+	c.Mode = tx.GasPriceMode(v.GetString("eth.gas_price.mode"))
+	switch c.Mode {
+	case "":
+		c.Mode = tx.GasPriceModeFixed
+	case tx.GasPriceModeFixed, tx.GasPriceModeNode, tx.GasPriceModePercentile:
+	default:
+		return config.ErrInvalidValue{
+			Key:          "eth.gas_price.mode",
+			InvalidValue: c.Mode.String(),
+			Values: []string{
+				tx.GasPriceModeFixed.String(),
+				tx.GasPriceModeNode.String(),
+				tx.GasPriceModePercentile.String(),
+			},
+		}
+	}
+
+	c.Price = v.GetUint64("eth.gas_price.price")
+	if c.Price == 0 {
+		return errors.New("eth.gas_price.price must be positive")
+	}
+
+	c.Percentile = v.GetFloat64("eth.gas_price.percentile")
+	if c.Percentile <= 0 || c.Percentile > 1 {
+		return errors.New("eth.gas_price.percentile must be greater than 0 and at most 1")
+	}
+
+	c.Blocks = v.GetUint64("eth.gas_price.blocks")
+	if c.Blocks == 0 {
+		return errors.New("eth.gas_price.blocks must be positive")
+	}
+
+	interval := v.GetInt64("eth.gas_price.escalation_interval_ms")
+	if interval < 0 {
+		return errors.New("eth.gas_price.escalation_interval_ms cannot be negative")
+	}
+	c.EscalationInterval = time.Duration(interval) * time.Millisecond
+
+	c.EscalationFactor = v.GetFloat64("eth.gas_price.escalation_factor")
+	if c.EscalationFactor < 1 {
+		return errors.New("eth.gas_price.escalation_factor must be at least 1")
+	}
+
+	c.Max = v.GetUint64("eth.gas_price.max")
+	if c.Max > 0 && c.Mode == tx.GasPriceModeFixed && c.Max < c.Price {
+		return errors.New("eth.gas_price.max cannot be lower than eth.gas_price.price")
+	}
+
+	c.DynamicFees = v.GetBool("eth.gas_price.dynamic_fees")
+	c.PriorityFee = v.GetUint64("eth.gas_price.priority_fee")
+	if c.DynamicFees && c.PriorityFee == 0 {
+		return errors.New("eth.gas_price.priority_fee must be positive")
+	}
+
+	return nil
+}
+
+func (c *GasPriceConfig) Bind(v *viper.Viper, cmd *cobra.Command) error {
+	cmd.PersistentFlags().String("eth.gas_price.mode", tx.GasPriceModeFixed.String(),
+		"mechanism used to choose the gas price of the transactions. Options are "+
+			tx.GasPriceModeFixed.String()+" to use eth.gas_price.price, "+
+			tx.GasPriceModeNode.String()+" to use the gas price suggested by the node and "+
+			tx.GasPriceModePercentile.String()+" to use eth.gas_price.percentile of the gas prices "+
+			"of the transactions in the last eth.gas_price.blocks blocks.")
+	cmd.PersistentFlags().Uint64("eth.gas_price.price", tx.DefaultGasPrice,
+		"gas price in wei of the "+tx.GasPriceModeFixed.String()+" eth.gas_price.mode, also used by the "+
+			tx.GasPriceModePercentile.String()+" mode when the recent blocks have no transactions.")
+	cmd.PersistentFlags().Float64("eth.gas_price.percentile", 0.6,
+		"percentile of the gas prices of the recent transactions used by the "+
+			tx.GasPriceModePercentile.String()+" eth.gas_price.mode.")
+	cmd.PersistentFlags().Uint64("eth.gas_price.blocks", 20,
+		"number of recent blocks whose transactions are used by the "+
+			tx.GasPriceModePercentile.String()+" eth.gas_price.mode.")
+	cmd.PersistentFlags().Int64("eth.gas_price.escalation_interval_ms", 0,
+		"time in milliseconds after which a transaction that has not been mined is replaced by one with "+
+			"its gas price multiplied by eth.gas_price.escalation_factor. If 0 the gas price does not escalate.")
+	cmd.PersistentFlags().Float64("eth.gas_price.escalation_factor", 1.125,
+		"factor by which the gas price escalates at each eth.gas_price.escalation_interval_ms.")
+	cmd.PersistentFlags().Uint64("eth.gas_price.max", 0,
+		"maximum gas price in wei of a transaction. If 0 the gas price is not bounded.")
+	cmd.PersistentFlags().Bool("eth.gas_price.dynamic_fees", true,
+		"submit EIP-1559 dynamic fee transactions on the chains that support them, with the gas price "+
+			"chosen by eth.gas_price.mode as their maximum fee. Otherwise legacy transactions are submitted.")
+	cmd.PersistentFlags().Uint64("eth.gas_price.priority_fee", tx.DefaultPriorityFee,
+		"maximum priority fee in wei of the dynamic fee transactions, bounded by their maximum fee.")
+	return nil
+}
+
+// GasLimitConfig holds the configuration of the gas
+// limit of the transactions
+type GasLimitConfig struct {
+	// Confidential is the gas limit of the transactions to
+	// services, whose gas cannot be estimated
+	Confidential uint64
+
+	// Max is the upper bound of the gas limit. If it is 0
+	// the gas limit is not bounded
+	Max uint64
+}
+
+func (c *GasLimitConfig) Log(fields log.Fields) {
+	fields.Add("eth.gas_limit.confidential", c.Confidential)
+	fields.Add("eth.gas_limit.max", c.Max)
+}
+
+func (c *GasLimitConfig) Configure(v *viper.Viper) error {
+	c.Confidential = v.GetUint64("eth.gas_limit.confidential")
+	if c.Confidential == 0 {
+		return errors.New("eth.gas_limit.confidential must be positive")
+	}
+
+	c.Max = v.GetUint64("eth.gas_limit.max")
+	if c.Max > 0 && c.Max < c.Confidential {
+		return errors.New("eth.gas_limit.max cannot be lower than eth.gas_limit.confidential")
+	}
+
+	return nil
+}
+
+func (c *GasLimitConfig) Bind(v *viper.Viper, cmd *cobra.Command) error {
+	cmd.PersistentFlags().Uint64("eth.gas_limit.confidential", tx.DefaultConfidentialGasLimit,
+		"gas limit of the transactions to services, whose gas cannot be estimated because "+
+			"the services may be confidential. Requests can set a gas limit of their own instead.")
+	cmd.PersistentFlags().Uint64("eth.gas_limit.max", 0,
+		"maximum gas limit of a transaction, including the gas limits set by the requests. "+
+			"Transactions that require more are rejected. If 0 the gas limit is not bounded.")
 	return nil
 }
@@ -0,0 +1,123 @@
+package core
+
+import (
+	"context"
+	stderr "errors"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/oasislabs/oasis-gateway/errors"
+	mqueue "github.com/oasislabs/oasis-gateway/mqueue/core"
+)
+
+// Alias maps a human readable name to the address of a service
+type Alias struct {
+	// Name is the human readable identifier of the service
+	Name string
+
+	// Address of the service the alias points to
+	Address string
+}
+
+// aliasesKey is the key of the store that holds the aliases
+const aliasesKey = "aliases"
+
+// AliasRegistry keeps the aliases managed by the gateway so that
+// clients can refer to services by name, and operators can point
+// an alias to a new address after a redeploy. The aliases are kept
+// in the mailbox store, so they survive restarts and are shared by
+// the gateways that share the mailbox
+type AliasRegistry struct {
+	store mqueue.Store
+}
+
+// NewAliasRegistry creates a new registry backed by the provided store
+func NewAliasRegistry(store mqueue.Store) *AliasRegistry {
+	return &AliasRegistry{store: store}
+}
+
+// Set creates or updates an alias so that it points to the
+// provided address
+func (r *AliasRegistry) Set(ctx context.Context, alias Alias) errors.Err {
+	if len(alias.Name) == 0 {
+		return errors.New(errors.ErrInvalidAlias, stderr.New("alias name cannot be empty"))
+	}
+
+	// names that could be confused with an address would make
+	// resolution ambiguous
+	if common.IsHexAddress(alias.Name) || strings.HasPrefix(alias.Name, "0x") {
+		return errors.New(errors.ErrInvalidAlias, stderr.New("alias name cannot be an address"))
+	}
+
+	if !common.IsHexAddress(alias.Address) {
+		return errors.New(errors.ErrInvalidAddress, stderr.New("alias address is not a valid address"))
+	}
+
+	if _, _, err := r.store.SetField(ctx, mqueue.SetFieldRequest{
+		Key:   aliasesKey,
+		Field: alias.Name,
+		Value: alias.Address,
+	}); err != nil {
+		return queueError(errors.ErrStore, err)
+	}
+
+	return nil
+}
+
+// Get returns the alias with the provided name
+func (r *AliasRegistry) Get(ctx context.Context, name string) (Alias, errors.Err) {
+	address, ok, err := r.store.GetField(ctx, mqueue.FieldRequest{Key: aliasesKey, Field: name})
+	if err != nil {
+		return Alias{}, queueError(errors.ErrStore, err)
+	}
+
+	if !ok {
+		return Alias{}, errors.New(errors.ErrAliasNotFound, nil)
+	}
+
+	return Alias{Name: name, Address: address}, nil
+}
+
+// Remove deletes the alias with the provided name
+func (r *AliasRegistry) Remove(ctx context.Context, name string) errors.Err {
+	ok, err := r.store.DeleteField(ctx, mqueue.DeleteFieldRequest{Key: aliasesKey, Field: name})
+	if err != nil {
+		return queueError(errors.ErrStore, err)
+	}
+
+	if !ok {
+		return errors.New(errors.ErrAliasNotFound, nil)
+	}
+
+	return nil
+}
+
+// List returns all the aliases sorted by name
+func (r *AliasRegistry) List(ctx context.Context) ([]Alias, errors.Err) {
+	fields, err := r.store.Fields(ctx, mqueue.FieldsRequest{Key: aliasesKey})
+	if err != nil {
+		return nil, queueError(errors.ErrStore, err)
+	}
+
+	aliases := make([]Alias, 0, len(fields))
+	for name, address := range fields {
+		aliases = append(aliases, Alias{Name: name, Address: address})
+	}
+
+	sort.Slice(aliases, func(i, j int) bool {
+		return aliases[i].Name < aliases[j].Name
+	})
+
+	return aliases, nil
+}
+
+// Resolve is the implementation of AddressResolver for AliasRegistry
+func (r *AliasRegistry) Resolve(ctx context.Context, name string) (string, errors.Err) {
+	alias, err := r.Get(ctx, name)
+	if err != nil {
+		return "", err
+	}
+
+	return alias.Address, nil
+}
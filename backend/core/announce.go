@@ -0,0 +1,80 @@
+package core
+
+import (
+	"context"
+	stderr "errors"
+	"strings"
+
+	"github.com/oasislabs/oasis-gateway/errors"
+	"github.com/oasislabs/oasis-gateway/log"
+	mqueue "github.com/oasislabs/oasis-gateway/mqueue/core"
+)
+
+// isSessionKey returns true if the key identifies the queue of a
+// session rather than one of the queues the manager keeps for the
+// subscriptions of a session
+func isSessionKey(key string) bool {
+	return !strings.Contains(key, ":sub:") &&
+		!strings.HasSuffix(key, ":subinfo")
+}
+
+// Announce inserts an event in the queue of each session known to the
+// mqueue, so that the clients of all sessions receive it when they
+// poll. The event is created with the offset reserved for it in the
+// queue of each session. It returns the number of sessions in whose
+// queue the event was inserted. Only the mqueues that can list their
+// queues support announcements
+func (m *RequestManager) Announce(ctx context.Context, newEvent func(id uint64) Event) (int, errors.Err) {
+	compactor, ok := m.mqueue.(mqueue.Compactor)
+	if !ok {
+		return 0, errors.New(errors.ErrUnsupportedByBackend,
+			stderr.New("mqueue cannot list the queues of the sessions"))
+	}
+
+	var announced int
+	for _, key := range compactor.Keys() {
+		if !isSessionKey(key) {
+			continue
+		}
+
+		if ctx.Err() != nil {
+			return announced, errors.New(errors.ErrRequestCancelled, ctx.Err())
+		}
+
+		next, err := m.mqueue.Next(ctx, mqueue.NextRequest{Key: key})
+		if err != nil {
+			m.logger.Warn(ctx, "failed to reserve offset for announcement", log.MapFields{
+				"call_type": "AnnounceFailure",
+				"key":       key,
+				"err":       err.Error(),
+			})
+			continue
+		}
+
+		if err := m.bus.Publish(ctx, Publication{
+			Key:    key,
+			Offset: next,
+			Event:  newEvent(next),
+		}); err != nil {
+			m.logger.Warn(ctx, "failed to insert announcement", log.MapFields{
+				"call_type": "AnnounceFailure",
+				"key":       key,
+				"err":       err.Error(),
+			})
+
+			// the reserved offset is discarded so that it does not
+			// prevent the queue from discarding the events before it
+			_ = m.mqueue.Discard(ctx, mqueue.DiscardRequest{
+				Key:          key,
+				Offset:       next,
+				Count:        1,
+				KeepPrevious: true,
+			})
+			continue
+		}
+
+		announced++
+	}
+
+	return announced, nil
+}
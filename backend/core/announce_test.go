@@ -0,0 +1,39 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/oasislabs/oasis-gateway/mqueue/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnnounce(t *testing.T) {
+	manager := createQuotaRequestManager(QuotaProps{})
+
+	for _, key := range []string{"aad:session", SubinfoID("aad:session"), SubID("aad:session", 0)} {
+		offset, err := manager.mqueue.Next(Context, core.NextRequest{Key: key})
+		assert.Nil(t, err)
+		assert.Nil(t, manager.bus.Publish(Context, Publication{
+			Key:    key,
+			Offset: offset,
+			Event:  DataEvent{ID: offset},
+		}))
+	}
+
+	announced, err := manager.Announce(Context, func(id uint64) Event {
+		return MaintenanceEvent{ID: id, Active: true, Message: "upgrade", RetryAfter: 60}
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, announced)
+
+	evs, err := manager.PollService(Context, PollServiceRequest{
+		SessionKey: "aad:session",
+		Offset:     1,
+		Count:      10,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, Events{
+		Offset: 0,
+		Events: []Event{MaintenanceEvent{ID: 1, Active: true, Message: "upgrade", RetryAfter: 60}},
+	}, evs)
+}
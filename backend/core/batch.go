@@ -0,0 +1,211 @@
+package core
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/oasislabs/oasis-gateway/errors"
+	"github.com/oasislabs/oasis-gateway/log"
+	mqueue "github.com/oasislabs/oasis-gateway/mqueue/core"
+)
+
+// MaxBatchSize is the maximum number of calls of a batch
+const MaxBatchSize = 64
+
+// BatchExecutor is implemented by the clients that can execute the
+// calls of a batch together, like the transactions of a wallet with
+// consecutive nonces. The calls of a batch are executed one after the
+// other through ExecuteService if the client does not implement it
+type BatchExecutor interface {
+	// ExecuteServiceBatch executes the calls with the provided IDs
+	// and returns the outcome of each call in the same order. An
+	// error is returned only if none of the calls could be executed
+	ExecuteServiceBatch(ctx context.Context, ids []uint64, reqs []ExecuteServiceRequest) ([]ExecuteServiceBatchResult, errors.Err)
+}
+
+// ExecuteServiceBatchAsync starts the execution of the calls of the
+// batch and returns the IDs of the events they generate, in the order
+// of the calls, and the ID of the BatchCompletedEvent that summarizes
+// them. The batch is accepted or rejected as a whole, and each call
+// counts as a request against the quotas of the session
+func (m *RequestManager) ExecuteServiceBatchAsync(
+	ctx context.Context,
+	req ExecuteServiceBatchRequest,
+) (ExecuteServiceBatchResponse, errors.Err) {
+	if len(req.Calls) == 0 || len(req.Calls) > MaxBatchSize {
+		return ExecuteServiceBatchResponse{}, errors.New(errors.ErrInvalidBatchSize,
+			fmt.Errorf("batch has %d calls and at most %d are allowed", len(req.Calls), MaxBatchSize))
+	}
+
+	calls := make([]ExecuteServiceRequest, len(req.Calls))
+	for i, call := range req.Calls {
+		if len(call.Address) == 0 {
+			return ExecuteServiceBatchResponse{}, errors.New(errors.ErrInvalidAddress, nil)
+		}
+
+		address, err := resolveAddress(ctx, m.resolver, call.Address)
+		if err != nil {
+			return ExecuteServiceBatchResponse{}, err
+		}
+
+		outputKey, err := m.outputKeys.Bind(ctx, req.AAD, req.SessionKey, call.OutputKey)
+		if err != nil {
+			return ExecuteServiceBatchResponse{}, err
+		}
+
+		call.Address = address
+		call.OutputKey = outputKey
+		call.AAD = req.AAD
+		call.SessionKey = req.SessionKey
+		call.IdempotencyKey = ""
+		calls[i] = call
+	}
+
+	return m.startBatch(ctx, req.SessionKey, req.AAD, calls)
+}
+
+// startBatch reserves the offsets at which the events of the calls of
+// a batch and its BatchCompletedEvent are published and runs the batch
+// in the background
+func (m *RequestManager) startBatch(
+	ctx context.Context,
+	key, aad string,
+	calls []ExecuteServiceRequest,
+) (ExecuteServiceBatchResponse, errors.Err) {
+	if err := m.beginRequest(); err != nil {
+		return ExecuteServiceBatchResponse{}, err
+	}
+
+	offsets := make([]uint64, 0, len(calls))
+	reject := func(err errors.Err) (ExecuteServiceBatchResponse, errors.Err) {
+		// the offsets already reserved are filled with the error so
+		// that the queue does not keep a gap of events that are never
+		// published, which would block the client from discarding the
+		// events that follow them
+		for _, offset := range offsets {
+			if perr := m.bus.Publish(ctx, Publication{
+				Key:    key,
+				Offset: offset,
+				Event:  errorEvent(offset, err),
+				AAD:    aad,
+			}); perr != nil {
+				m.logger.Warn(ctx, "failed to publish event of rejected batch", log.MapFields{
+					"call_type": "PublishEventFailure",
+					"id":        offset,
+					"err":       perr.Error(),
+				})
+			}
+			m.inFlight.release(key)
+		}
+		m.requests.Done()
+		return ExecuteServiceBatchResponse{}, err
+	}
+
+	if err := m.checkSpendQuota(ctx, aad); err != nil {
+		return reject(err)
+	}
+
+	for range calls {
+		if err := m.inFlight.acquire(key); err != nil {
+			return reject(err)
+		}
+
+		offset, err := m.mqueue.Next(ctx, mqueue.NextRequest{Key: key})
+		if err != nil {
+			m.inFlight.release(key)
+			return reject(queueError(errors.ErrQueueNext, err))
+		}
+
+		offsets = append(offsets, offset)
+		if err := m.checkQueueQuota(ctx, key, aad, offset); err != nil {
+			return reject(err)
+		}
+	}
+
+	// the summary of the batch is not a request, so it does
+	// not count against the requests in flight
+	summary, err := m.mqueue.Next(ctx, mqueue.NextRequest{Key: key})
+	if err != nil {
+		return reject(queueError(errors.ErrQueueNext, err))
+	}
+
+	// the events of a batch are never derived from an idempotency
+	// key, so their IDs are the offsets at which they are published
+	ids := offsets
+	go func() {
+		defer m.requests.Done()
+		m.doBatch(ctx, key, aad, ids, summary, calls)
+	}()
+
+	return ExecuteServiceBatchResponse{IDs: ids, BatchID: summary}, nil
+}
+
+// doBatch executes the calls of a batch and publishes the event of
+// each call. Once the event of a call is published the call is no
+// longer in flight. Once all the calls complete the outcome of each
+// call is published in a BatchCompletedEvent at the summary offset
+func (m *RequestManager) doBatch(
+	ctx context.Context,
+	key, aad string,
+	ids []uint64,
+	summary uint64,
+	calls []ExecuteServiceRequest,
+) {
+	statuses := make([]BatchCallStatus, 0, len(ids))
+	results, err := m.executeServiceBatch(ctx, ids, calls)
+	for i, id := range ids {
+		var ev Event
+		var xerr errors.Err
+		switch {
+		case err != nil:
+			xerr = err
+		case results[i].Err != nil:
+			xerr = results[i].Err
+		default:
+			ev, xerr = m.executeServiceEvent(ctx, id, calls[i], results[i].Response)
+		}
+
+		m.publish(ctx, key, aad, id, id, ev, xerr)
+		m.inFlight.release(key)
+
+		status := BatchCallStatus{ID: id}
+		if xerr != nil {
+			cause := errorEvent(id, xerr).Cause
+			status.Cause = &cause
+		}
+		statuses = append(statuses, status)
+	}
+
+	m.publish(ctx, key, aad, summary, summary, BatchCompletedEvent{
+		ID:    summary,
+		Calls: statuses,
+	}, nil)
+}
+
+// executeServiceBatch executes the calls of a batch together if the
+// client supports it and one after the other otherwise
+func (m *RequestManager) executeServiceBatch(
+	ctx context.Context,
+	ids []uint64,
+	calls []ExecuteServiceRequest,
+) ([]ExecuteServiceBatchResult, errors.Err) {
+	if m.batcher != nil {
+		results, err := m.batcher.ExecuteServiceBatch(ctx, ids, calls)
+		if err != nil {
+			m.logger.Debug(ctx, "failed to execute batch", log.MapFields{
+				"call_type": "ExecuteServiceBatchFailure",
+				"id":        ids[0],
+				"calls":     len(calls),
+			}, err)
+		}
+		return results, err
+	}
+
+	results := make([]ExecuteServiceBatchResult, len(calls))
+	for i, call := range calls {
+		res, err := m.client.ExecuteService(ctx, ids[i], call)
+		results[i] = ExecuteServiceBatchResult{Response: res, Err: err}
+	}
+
+	return results, nil
+}
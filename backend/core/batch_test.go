@@ -0,0 +1,129 @@
+package core
+
+import (
+	"context"
+	"testing"
+
+	"github.com/oasislabs/oasis-gateway/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// batchClient is a MockClient that executes the calls
+// of a batch together
+type batchClient struct {
+	*MockClient
+	ids []uint64
+}
+
+func (c *batchClient) ExecuteServiceBatch(
+	ctx context.Context,
+	ids []uint64,
+	reqs []ExecuteServiceRequest,
+) ([]ExecuteServiceBatchResult, errors.Err) {
+	c.ids = ids
+	results := make([]ExecuteServiceBatchResult, len(reqs))
+	for i, req := range reqs {
+		results[i].Response = ExecuteServiceResponse{ID: ids[i], Address: req.Address}
+	}
+	results[len(results)-1].Err = errors.New(errors.ErrSendTransaction, nil)
+	return results, nil
+}
+
+func pollBatch(t *testing.T, manager *RequestManager, count uint) []Event {
+	assert.Nil(t, manager.Shutdown(Context))
+
+	evs, err := manager.PollService(Context, PollServiceRequest{
+		SessionKey: "session",
+		Offset:     0,
+		Count:      count,
+	})
+	assert.Nil(t, err)
+	return evs.Events
+}
+
+func TestExecuteServiceBatchAsyncSequential(t *testing.T) {
+	manager := createMemRequestManager(false)
+	address := "0x0a51514857B379A521C580a10822Fd8A7aC491A0"
+
+	for id := uint64(0); id < 2; id++ {
+		manager.client.(*MockClient).On("ExecuteService", mock.Anything, id, mock.Anything).
+			Return(ExecuteServiceResponse{ID: id, Address: address}, nil)
+	}
+
+	res, err := manager.ExecuteServiceBatchAsync(Context, ExecuteServiceBatchRequest{
+		AAD:        "aad",
+		SessionKey: "session",
+		Calls: []ExecuteServiceRequest{
+			{Address: address, Data: "0x01"},
+			{Address: address, Data: "0x02"},
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, ExecuteServiceBatchResponse{IDs: []uint64{0, 1}, BatchID: 2}, res)
+
+	evs := pollBatch(t, manager, 3)
+	assert.Equal(t, 3, len(evs))
+	for i, ev := range evs[:2] {
+		assert.Equal(t, uint64(i), ev.(ExecuteServiceResponse).ID)
+	}
+	assert.Equal(t, BatchCompletedEvent{
+		ID:    2,
+		Calls: []BatchCallStatus{{ID: 0}, {ID: 1}},
+	}, evs[2])
+	manager.client.(*MockClient).AssertNumberOfCalls(t, "ExecuteService", 2)
+}
+
+func TestExecuteServiceBatchAsyncBatchExecutor(t *testing.T) {
+	client := &batchClient{MockClient: &MockClient{}}
+	manager := createMemRequestManager(false)
+	manager.client = client
+	manager.batcher = client
+	address := "0x0a51514857B379A521C580a10822Fd8A7aC491A0"
+
+	res, err := manager.ExecuteServiceBatchAsync(Context, ExecuteServiceBatchRequest{
+		AAD:        "aad",
+		SessionKey: "session",
+		Calls: []ExecuteServiceRequest{
+			{Address: address, Data: "0x01"},
+			{Address: address, Data: "0x02"},
+		},
+	})
+	assert.Nil(t, err)
+
+	evs := pollBatch(t, manager, 3)
+	assert.Equal(t, res.IDs, client.ids)
+	assert.Equal(t, 3, len(evs))
+	assert.Equal(t, uint64(0), evs[0].(ExecuteServiceResponse).ID)
+	assert.Equal(t, uint64(1), evs[1].(ErrorEvent).ID)
+	assert.Equal(t, errors.ErrSendTransaction.Code(), evs[1].(ErrorEvent).Cause.ErrorCode)
+
+	// the summary reports the outcome of each call
+	summary := evs[2].(BatchCompletedEvent)
+	assert.Equal(t, res.BatchID, summary.ID)
+	assert.Equal(t, 2, len(summary.Calls))
+	assert.Nil(t, summary.Calls[0].Cause)
+	assert.Equal(t, uint64(1), summary.Calls[1].ID)
+	assert.Equal(t, errors.ErrSendTransaction.Code(), summary.Calls[1].Cause.ErrorCode)
+}
+
+func TestExecuteServiceBatchAsyncInvalidSize(t *testing.T) {
+	manager := createMemRequestManager(false)
+
+	_, err := manager.ExecuteServiceBatchAsync(Context, ExecuteServiceBatchRequest{
+		AAD:        "aad",
+		SessionKey: "session",
+	})
+	assert.Equal(t, errors.ErrInvalidBatchSize.Code(), err.ErrorCode().Code())
+
+	calls := make([]ExecuteServiceRequest, MaxBatchSize+1)
+	for i := range calls {
+		calls[i].Address = "0x0a51514857B379A521C580a10822Fd8A7aC491A0"
+	}
+	_, err = manager.ExecuteServiceBatchAsync(Context, ExecuteServiceBatchRequest{
+		AAD:        "aad",
+		SessionKey: "session",
+		Calls:      calls,
+	})
+	assert.Equal(t, errors.ErrInvalidBatchSize.Code(), err.ErrorCode().Code())
+}
@@ -0,0 +1,122 @@
+package core
+
+import (
+	"context"
+	"sync"
+
+	mqueue "github.com/oasislabs/oasis-gateway/mqueue/core"
+	"github.com/oasislabs/oasis-gateway/stats"
+)
+
+// Publication is an event published for the queue
+// identified by Key at the provided Offset
+type Publication struct {
+	Key    string
+	Offset uint64
+	Event  Event
+
+	// AAD is the identifier of the issuer of the request that
+	// generated the event. It may be empty if unknown
+	AAD string
+}
+
+// Consumer receives the events published on an EventBus
+type Consumer interface {
+	Consume(ctx context.Context, pub Publication)
+}
+
+// ConsumerFunc allows a function to be used as a Consumer
+type ConsumerFunc func(ctx context.Context, pub Publication)
+
+// Consume is the implementation of Consumer for ConsumerFunc
+func (f ConsumerFunc) Consume(ctx context.Context, pub Publication) {
+	f(ctx, pub)
+}
+
+// EventBus is the flow of events generated by the backend. Features
+// that need to react to the events subscribe a Consumer to the bus
+// instead of reading them from the mqueue
+type EventBus interface {
+	// Publish stores the event so that it can be polled by the
+	// client and notifies it to the subscribed consumers
+	Publish(ctx context.Context, pub Publication) error
+
+	// Subscribe adds a consumer to the bus. The returned function
+	// removes the consumer from the bus
+	Subscribe(consumer Consumer) func()
+}
+
+// QueueBus is an EventBus that stores the events in an mqueue.
+// Consumers are only notified of the events that have been
+// stored, and they are called synchronously by the publisher so
+// they must not block
+type QueueBus struct {
+	mqueue    mqueue.MQueue
+	lock      sync.RWMutex
+	consumers map[uint64]Consumer
+	nextID    uint64
+	published stats.Counter
+	failed    stats.Counter
+}
+
+// NewQueueBus creates a new bus that stores the events
+// in the provided mqueue
+func NewQueueBus(mqueue mqueue.MQueue) *QueueBus {
+	return &QueueBus{
+		mqueue:    mqueue,
+		consumers: make(map[uint64]Consumer),
+	}
+}
+
+// Publish is the implementation of EventBus.Publish for QueueBus
+func (b *QueueBus) Publish(ctx context.Context, pub Publication) error {
+	el, err := makeElement(pub.Event, pub.Offset)
+	if err != nil {
+		b.failed.Incr()
+		return err
+	}
+
+	if err := b.mqueue.Insert(ctx, mqueue.InsertRequest{Key: pub.Key, Element: el}); err != nil {
+		b.failed.Incr()
+		return err
+	}
+
+	b.published.Incr()
+
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	for _, consumer := range b.consumers {
+		consumer.Consume(ctx, pub)
+	}
+
+	return nil
+}
+
+// Subscribe is the implementation of EventBus.Subscribe for QueueBus
+func (b *QueueBus) Subscribe(consumer Consumer) func() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	id := b.nextID
+	b.nextID++
+	b.consumers[id] = consumer
+
+	return func() {
+		b.lock.Lock()
+		defer b.lock.Unlock()
+		delete(b.consumers, id)
+	}
+}
+
+// Stats is the implementation of stats.Collector for QueueBus
+func (b *QueueBus) Stats() stats.Metrics {
+	b.lock.RLock()
+	consumers := len(b.consumers)
+	b.lock.RUnlock()
+
+	return stats.Metrics{
+		"published": b.published.Value(),
+		"failed":    b.failed.Value(),
+		"consumers": consumers,
+	}
+}
@@ -0,0 +1,59 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	mqueue "github.com/oasislabs/oasis-gateway/mqueue/core"
+	"github.com/oasislabs/oasis-gateway/mqueue/mailboxtest"
+	"github.com/oasislabs/oasis-gateway/stats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestQueueBusPublish(t *testing.T) {
+	mailbox := &mailboxtest.Mailbox{}
+	bus := NewQueueBus(mailbox)
+
+	mailbox.On("Insert", mock.Anything, mock.MatchedBy(func(req mqueue.InsertRequest) bool {
+		return req.Key == "key" && req.Element.Offset == 1 &&
+			req.Element.Type == DataEventType.String()
+	})).Return(nil)
+
+	var pubs []Publication
+	unsubscribe := bus.Subscribe(ConsumerFunc(func(ctx context.Context, pub Publication) {
+		pubs = append(pubs, pub)
+	}))
+
+	pub := Publication{Key: "key", Offset: 1, Event: DataEvent{ID: 1}}
+	assert.Nil(t, bus.Publish(Context, pub))
+	assert.Equal(t, []Publication{pub}, pubs)
+
+	unsubscribe()
+	assert.Nil(t, bus.Publish(Context, pub))
+	assert.Equal(t, []Publication{pub}, pubs)
+
+	assert.Equal(t, stats.Metrics{
+		"published": uint64(2),
+		"failed":    uint64(0),
+		"consumers": 0,
+	}, bus.Stats())
+}
+
+func TestQueueBusPublishInsertErr(t *testing.T) {
+	mailbox := &mailboxtest.Mailbox{}
+	bus := NewQueueBus(mailbox)
+
+	mailbox.On("Insert", mock.Anything, mock.Anything).Return(errors.New("error"))
+
+	called := false
+	bus.Subscribe(ConsumerFunc(func(ctx context.Context, pub Publication) {
+		called = true
+	}))
+
+	err := bus.Publish(Context, Publication{Key: "key", Offset: 1, Event: DataEvent{ID: 1}})
+	assert.Equal(t, "error", err.Error())
+	assert.False(t, called)
+	assert.Equal(t, uint64(1), bus.Stats()["failed"])
+}
@@ -0,0 +1,110 @@
+package core
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// QueryCacheProps are the properties used to create a QueryCache
+type QueryCacheProps struct {
+	// TTL is the time an entry is kept in the cache. If it is
+	// 0 the cache does not keep any entries
+	TTL time.Duration
+
+	// MaxEntries is the maximum number of entries the cache
+	// keeps at the same time
+	MaxEntries int
+}
+
+type queryCacheEntry struct {
+	res     QueryServiceResponse
+	expires time.Time
+}
+
+// QueryCache caches the results of read-only queries so that
+// repeated queries for the same state do not reach the node
+type QueryCache struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	entries    map[string]queryCacheEntry
+	now        func() time.Time
+}
+
+// NewQueryCache creates a new QueryCache
+func NewQueryCache(props QueryCacheProps) *QueryCache {
+	return &QueryCache{
+		ttl:        props.TTL,
+		maxEntries: props.MaxEntries,
+		entries:    make(map[string]queryCacheEntry),
+		now:        time.Now,
+	}
+}
+
+// Enabled returns true if the cache keeps entries
+func (c *QueryCache) Enabled() bool {
+	return c.ttl > 0 && c.maxEntries > 0
+}
+
+func queryCacheKey(req QueryServiceRequest) string {
+	return fmt.Sprintf("%s:%s:%d", req.Address, req.Data, req.Block)
+}
+
+// Get returns the cached response for the request if there is
+// one that has not expired yet. The MaxAge of the returned
+// response is set to the time left before the entry expires
+func (c *QueryCache) Get(req QueryServiceRequest) (QueryServiceResponse, bool) {
+	if !c.Enabled() {
+		return QueryServiceResponse{}, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := queryCacheKey(req)
+	entry, ok := c.entries[key]
+	if !ok {
+		return QueryServiceResponse{}, false
+	}
+
+	now := c.now()
+	if !now.Before(entry.expires) {
+		delete(c.entries, key)
+		return QueryServiceResponse{}, false
+	}
+
+	res := entry.res
+	res.MaxAge = entry.expires.Sub(now)
+	return res, true
+}
+
+// Set adds the response for the request to the cache and returns the
+// response with MaxAge set to the cache TTL. If the cache is full the
+// expired entries are removed, and if it is still full the response
+// is not cached
+func (c *QueryCache) Set(req QueryServiceRequest, res QueryServiceResponse) QueryServiceResponse {
+	if !c.Enabled() {
+		return res
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if len(c.entries) >= c.maxEntries {
+		for key, entry := range c.entries {
+			if !now.Before(entry.expires) {
+				delete(c.entries, key)
+			}
+		}
+	}
+
+	if len(c.entries) >= c.maxEntries {
+		return res
+	}
+
+	c.entries[queryCacheKey(req)] = queryCacheEntry{res: res, expires: now.Add(c.ttl)}
+	res.MaxAge = c.ttl
+	return res
+}
@@ -0,0 +1,88 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	mqueue "github.com/oasislabs/oasis-gateway/mqueue/core"
+	"github.com/oasislabs/oasis-gateway/mqueue/mailboxtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestQueryCacheDisabled(t *testing.T) {
+	cache := NewQueryCache(QueryCacheProps{MaxEntries: 10})
+	req := QueryServiceRequest{Address: "0x01", Data: "0x02"}
+
+	res := cache.Set(req, QueryServiceResponse{Address: "0x01", Output: "0x03"})
+	assert.Equal(t, time.Duration(0), res.MaxAge)
+
+	_, ok := cache.Get(req)
+	assert.False(t, ok)
+}
+
+func TestQueryCacheGetSetExpiry(t *testing.T) {
+	now := time.Unix(0, 0)
+	cache := NewQueryCache(QueryCacheProps{TTL: 10 * time.Second, MaxEntries: 10})
+	cache.now = func() time.Time { return now }
+	req := QueryServiceRequest{Address: "0x01", Data: "0x02", Block: 1}
+
+	res := cache.Set(req, QueryServiceResponse{Address: "0x01", Output: "0x03"})
+	assert.Equal(t, 10*time.Second, res.MaxAge)
+
+	now = now.Add(4 * time.Second)
+	res, ok := cache.Get(req)
+	assert.True(t, ok)
+	assert.Equal(t, "0x03", res.Output)
+	assert.Equal(t, 6*time.Second, res.MaxAge)
+
+	_, ok = cache.Get(QueryServiceRequest{Address: "0x01", Data: "0x02", Block: 2})
+	assert.False(t, ok)
+
+	now = now.Add(6 * time.Second)
+	_, ok = cache.Get(req)
+	assert.False(t, ok)
+}
+
+func TestQueryCacheFull(t *testing.T) {
+	now := time.Unix(0, 0)
+	cache := NewQueryCache(QueryCacheProps{TTL: 10 * time.Second, MaxEntries: 1})
+	cache.now = func() time.Time { return now }
+
+	cache.Set(QueryServiceRequest{Address: "0x01"}, QueryServiceResponse{Output: "0x01"})
+	res := cache.Set(QueryServiceRequest{Address: "0x02"}, QueryServiceResponse{Output: "0x02"})
+	assert.Equal(t, time.Duration(0), res.MaxAge)
+
+	now = now.Add(10 * time.Second)
+	res = cache.Set(QueryServiceRequest{Address: "0x02"}, QueryServiceResponse{Output: "0x02"})
+	assert.Equal(t, 10*time.Second, res.MaxAge)
+}
+
+func TestRequestManagerQueryServiceCached(t *testing.T) {
+	client := &MockClient{}
+	manager := NewRequestManager(RequestManagerProperties{
+		MQueue:     &mailboxtest.Mailbox{},
+		Store:      mqueue.NewMemStore(),
+		Client:     client,
+		Logger:     Logger,
+		QueryCache: QueryCacheProps{TTL: time.Minute, MaxEntries: 10},
+	})
+	req := QueryServiceRequest{
+		Address: "0x0000000000000000000000000000000000000001",
+		Data:    "0x01",
+	}
+
+	client.On("QueryService", mock.Anything, req).
+		Return(QueryServiceResponse{Address: req.Address, Output: "0x02"}, nil)
+
+	res, err := manager.QueryService(Context, req)
+	assert.Nil(t, err)
+	assert.Equal(t, "0x02", res.Output)
+
+	res, err = manager.QueryService(Context, req)
+	assert.Nil(t, err)
+	assert.Equal(t, "0x02", res.Output)
+	assert.True(t, res.MaxAge > 0)
+
+	client.AssertNumberOfCalls(t, "QueryService", 1)
+}
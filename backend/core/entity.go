@@ -3,6 +3,7 @@ package core
 import (
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/oasislabs/oasis-gateway/errors"
 	mqueue "github.com/oasislabs/oasis-gateway/mqueue/core"
@@ -22,16 +23,36 @@ type Events struct {
 type EventType string
 
 const (
-	DeployServiceEventType  EventType = "deployServiceEventType"
-	ExecuteServiceEventType EventType = "executeServiceEventType"
-	ErrorEventType          EventType = "errorEventType"
-	DataEventType           EventType = "dataEventType"
+	DeployServiceEventType   EventType = "deployServiceEventType"
+	ExecuteServiceEventType  EventType = "executeServiceEventType"
+	ErrorEventType           EventType = "errorEventType"
+	DataEventType            EventType = "dataEventType"
+	LogRemovedEventType      EventType = "logRemovedEventType"
+	WarningEventType         EventType = "warningEventType"
+	MaintenanceEventType     EventType = "maintenanceEventType"
+	NodeReconnectedEventType EventType = "nodeReconnectedEventType"
+	BatchCompletedEventType  EventType = "batchCompletedEventType"
 )
 
 func (t EventType) String() string {
 	return string(t)
 }
 
+// eventTypeStrings returns the string representation
+// of each of the types
+func eventTypeStrings(types []EventType) []string {
+	if len(types) == 0 {
+		return nil
+	}
+
+	strs := make([]string, 0, len(types))
+	for _, t := range types {
+		strs = append(strs, t.String())
+	}
+
+	return strs
+}
+
 func makeElement(ev Event, offset uint64) (mqueue.Element, error) {
 	p, err := json.Marshal(ev)
 	if err != nil {
@@ -74,6 +95,41 @@ func deserializeElement(el mqueue.Element) (Event, errors.Err) {
 			return nil, errors.New(errors.ErrDeserializeEvent, err)
 		}
 
+		return ev, nil
+	case LogRemovedEventType:
+		var ev LogRemovedEvent
+		if err := json.Unmarshal([]byte(el.Value), &ev); err != nil {
+			return nil, errors.New(errors.ErrDeserializeEvent, err)
+		}
+
+		return ev, nil
+	case WarningEventType:
+		var ev WarningEvent
+		if err := json.Unmarshal([]byte(el.Value), &ev); err != nil {
+			return nil, errors.New(errors.ErrDeserializeEvent, err)
+		}
+
+		return ev, nil
+	case MaintenanceEventType:
+		var ev MaintenanceEvent
+		if err := json.Unmarshal([]byte(el.Value), &ev); err != nil {
+			return nil, errors.New(errors.ErrDeserializeEvent, err)
+		}
+
+		return ev, nil
+	case NodeReconnectedEventType:
+		var ev NodeReconnectedEvent
+		if err := json.Unmarshal([]byte(el.Value), &ev); err != nil {
+			return nil, errors.New(errors.ErrDeserializeEvent, err)
+		}
+
+		return ev, nil
+	case BatchCompletedEventType:
+		var ev BatchCompletedEvent
+		if err := json.Unmarshal([]byte(el.Value), &ev); err != nil {
+			return nil, errors.New(errors.ErrDeserializeEvent, err)
+		}
+
 		return ev, nil
 	default:
 		return nil, errors.New(errors.ErrUnkownEventType, nil)
@@ -107,10 +163,68 @@ type ExecuteServiceRequest struct {
 	// Address where the service can be found
 	Address string
 
+	// Expiry is the block number after which the transaction is discarded
+	// if it has not been included in a block. If 0 no expiry is set
+	Expiry uint64
+
+	// GasLimit if set is the gas limit of the transaction instead
+	// of the estimated one
+	GasLimit uint64
+
+	// GasPrice if set is the gas price of the transaction instead
+	// of the one chosen by the gateway
+	GasPrice uint64
+
+	// Encoding is the encoding requested by the client for the
+	// output of the execution
+	Encoding string
+
+	// IdempotencyKey if set is used to derive the ID of the
+	// event generated by the request
+	IdempotencyKey string
+
+	// OutputKey if set is the X25519 public key to which the
+	// output of the execution is sealed. It is bound to the
+	// session the first time the session uses it
+	OutputKey *[32]byte
+
+	// Key is the identifier of the session
+	SessionKey string
+}
+
+// ExecuteServiceBatchRequest is used by the user to trigger several
+// service executions at once. Each call generates its own event
+type ExecuteServiceBatchRequest struct {
+	// AAD is the identifier of the issuer of the transactions data
+	AAD string
+
+	// Calls are the executions of the batch. The AAD, SessionKey
+	// and IdempotencyKey of each call are ignored
+	Calls []ExecuteServiceRequest
+
 	// Key is the identifier of the session
 	SessionKey string
 }
 
+// ExecuteServiceBatchResult is the outcome of a call of a batch.
+// Only one of Response and Err is set
+type ExecuteServiceBatchResult struct {
+	Response ExecuteServiceResponse
+	Err      errors.Err
+}
+
+// ExecuteServiceBatchResponse holds the IDs of the events
+// generated by an ExecuteServiceBatchRequest
+type ExecuteServiceBatchResponse struct {
+	// IDs are the IDs of the events of the calls, in the
+	// order of the calls
+	IDs []uint64
+
+	// BatchID is the ID of the BatchCompletedEvent published
+	// once all the calls of the batch complete
+	BatchID uint64
+}
+
 // DeployServiceRequest is issued by the user to trigger a service
 // execution. A client is always subscribed to a subscription with
 // topic "service" from which the client can retrieve the asynchronous
@@ -123,10 +237,187 @@ type DeployServiceRequest struct {
 	// the deployment of a service
 	Data string
 
+	// Expiry is the block number after which the transaction is discarded
+	// if it has not been included in a block. If 0 no expiry is set
+	Expiry uint64
+
+	// IdempotencyKey if set is used to derive the ID of the
+	// event generated by the request
+	IdempotencyKey string
+
 	// Key is the identifier of the session
 	SessionKey string
 }
 
+// ListDeploymentsRequest is a request to retrieve the services
+// deployed through the gateway by an AAD
+type ListDeploymentsRequest struct {
+	// AAD is the identifier of the issuer of the deployments
+	AAD string
+
+	// Offset at which the records should start
+	Offset uint64
+
+	// Count is the maximum number of records returned
+	Count uint
+
+	// CodeHash if set restricts the records returned to the
+	// deployments of the bytecode with that hash
+	CodeHash string
+}
+
+// SetAliasRequest is a request to create or update an alias
+type SetAliasRequest struct {
+	// Name is the human readable identifier of the service
+	Name string
+
+	// Address of the service the alias points to
+	Address string
+}
+
+// GetAliasRequest is a request to retrieve an alias
+type GetAliasRequest struct {
+	// Name of the alias
+	Name string
+}
+
+// RemoveAliasRequest is a request to remove an alias
+type RemoveAliasRequest struct {
+	// Name of the alias
+	Name string
+}
+
+// GetIdempotencyRecordRequest is a request to retrieve the
+// record of an idempotency key
+type GetIdempotencyRecordRequest struct {
+	// AAD that used the idempotency key
+	AAD string
+
+	// Key is the idempotency key
+	Key string
+}
+
+// ListIdempotencyRecordsRequest is a request to retrieve the
+// records of all the idempotency keys used by an AAD
+type ListIdempotencyRecordsRequest struct {
+	// AAD that used the idempotency keys
+	AAD string
+}
+
+// PurgeIdempotencyRecordsRequest is a request to remove the records
+// of idempotency keys so that they can be used again
+type PurgeIdempotencyRecordsRequest struct {
+	// AAD that used the idempotency keys
+	AAD string
+
+	// Keys are the idempotency keys to purge. If empty all
+	// the keys of the AAD are purged
+	Keys []string
+}
+
+// SetIdempotencyExpiryRequest is a request to set the time at
+// which the record of an idempotency key expires
+type SetIdempotencyExpiryRequest struct {
+	// AAD that used the idempotency key
+	AAD string
+
+	// Key is the idempotency key
+	Key string
+
+	// ExpiresAt is the time at which the record expires. If
+	// it is zero the record does not expire
+	ExpiresAt time.Time
+}
+
+// SetSessionMetadataRequest is a request to replace the
+// metadata attached to a session
+type SetSessionMetadataRequest struct {
+	// SessionKey identifies the session
+	SessionKey string
+
+	// Metadata to attach to the session
+	Metadata SessionMetadata
+}
+
+// GetSessionMetadataRequest is a request to retrieve the
+// metadata attached to a session
+type GetSessionMetadataRequest struct {
+	// SessionKey identifies the session
+	SessionKey string
+}
+
+// HeartbeatRequest is a request to keep a session from expiring
+type HeartbeatRequest struct {
+	// SessionKey identifies the session
+	SessionKey string
+}
+
+// GetQuotaStateRequest is a request to retrieve the state
+// of the quotas of a session
+type GetQuotaStateRequest struct {
+	// SessionKey identifies the session
+	SessionKey string
+
+	// AAD identifies the issuer of the session
+	AAD string
+}
+
+// QuotaState is how much of the quotas that limit its
+// requests a session has used
+type QuotaState struct {
+	// MaxInFlight is the number of requests the session may have
+	// in flight. If 0 the number of requests is not limited
+	MaxInFlight uint64
+
+	// InFlight is the number of requests of the session in flight
+	InFlight uint64
+
+	// SpendLimit is the amount of gas the issuer of the session
+	// may spend. If 0 the gas spent is not limited
+	SpendLimit uint64
+
+	// Spent is the amount of gas spent by the issuer of the session
+	Spent uint64
+}
+
+// HeartbeatResponse describes the mailbox of the session
+// of a HeartbeatRequest
+type HeartbeatResponse struct {
+	// Offset of the first event kept in the mailbox
+	Offset uint64
+
+	// Pending is the number of events kept in the mailbox
+	Pending uint64
+}
+
+// QueryServiceRequest is a request to execute a read-only query
+// on a service without submitting a transaction
+type QueryServiceRequest struct {
+	// Address where the service can be found
+	Address string
+
+	// Data is a blob of data that the user wants to pass to the service
+	// as argument
+	Data string
+
+	// Block is the block number at which the query is executed. If 0
+	// the query is executed on the latest block
+	Block uint64
+}
+
+// QueryServiceResponse is the response to a read-only query
+type QueryServiceResponse struct {
+	// Address where the service can be found
+	Address string
+
+	// Output is the result of the query
+	Output string
+
+	// MaxAge is the time the response can be cached by the client. If
+	// 0 the response should not be cached
+	MaxAge time.Duration
+}
+
 // GetCodeRequest is a request to retrieve the code
 // associated with a specific service
 type GetCodeRequest struct {
@@ -169,6 +460,28 @@ type GetExpiryResponse struct {
 	Expiry uint64
 }
 
+// GetChainHeadResponse is the response in which the view the
+// backend has of the chain is provided
+type GetChainHeadResponse struct {
+	// BlockNumber is the number of the latest block known to the backend
+	BlockNumber uint64
+
+	// Timestamp is the time in seconds at which the latest block
+	// known to the backend was produced
+	Timestamp uint64
+
+	// ChainID is the identifier of the chain, or 0 if the backend
+	// does not report it
+	ChainID uint64
+
+	// Syncing is true if the backend is still catching up with the chain
+	Syncing bool
+
+	// HighestBlock is the number of the highest block the backend is
+	// aware of while it is syncing
+	HighestBlock uint64
+}
+
 // GetPublicKeyRequest is a request to retrieve the public key
 // associated with a specific service
 type GetPublicKeyRequest struct {
@@ -221,6 +534,46 @@ type ExecuteServiceResponse struct {
 
 	// Output generated by the service at the end of its execution
 	Output string
+
+	// Encoding is the encoding requested by the client for Output
+	Encoding string
+
+	// Encrypted is true if Output is sealed to the key
+	// provided by the client
+	Encrypted bool
+
+	// Truncated is true if Output is only a preview of the output,
+	// which can be retrieved in full from the OutputStore
+	Truncated bool
+
+	// OutputSize is the size of the full output if it is Truncated
+	OutputSize int
+
+	// GasUsed is the gas consumed by the execution, which is
+	// accounted for the spend quota of the issuer
+	GasUsed uint64
+}
+
+// GetServiceOutputRequest is the request to retrieve the full
+// output of an execution whose output was truncated
+type GetServiceOutputRequest struct {
+	// ID of the execution
+	ID uint64
+
+	// SessionKey identifies the session of the execution
+	SessionKey string
+}
+
+// GetServiceOutputResponse is the full output of an execution
+type GetServiceOutputResponse struct {
+	// ID of the execution
+	ID uint64
+
+	// Output generated by the service at the end of its execution
+	Output string
+
+	// Encoding is the encoding requested by the client for Output
+	Encoding string
 }
 
 // DeployServiceResponse is the event that can be polled by the user
@@ -234,6 +587,10 @@ type DeployServiceResponse struct {
 	// is generated when a service is deployed and it can be used
 	// for service execution
 	Address string
+
+	// GasUsed is the gas consumed by the deployment, which is
+	// accounted for the spend quota of the issuer
+	GasUsed uint64
 }
 
 // DataEvent is that event that can be polled by the user to poll
@@ -250,6 +607,94 @@ type DataEvent struct {
 	Topics []string
 }
 
+// LogRemovedEvent is the event inserted in the queue of a subscription
+// when a log delivered in a DataEvent is removed from the chain by a
+// reorganization, so that the client can invalidate the state
+// derived from it
+type LogRemovedEvent struct {
+	// ID to identify the event itself within the sequence of events.
+	ID uint64
+
+	// Offset is the ID of the DataEvent of the removed log
+	Offset uint64
+}
+
+// WarningEvent is the event inserted in the queue of a session to
+// warn the client that the session is approaching one of its quotas
+// and that its requests may be rejected soon
+type WarningEvent struct {
+	// ID to identify the event itself within the sequence of events.
+	ID uint64
+
+	// Quota is the name of the quota the session is approaching
+	Quota string
+
+	// Used is the amount of the quota used by the session
+	Used uint64
+
+	// Limit is the amount of the quota at which the requests
+	// of the session are rejected
+	Limit uint64
+}
+
+// MaintenanceEvent is the event inserted in the queue of each session
+// when the gateway enters or leaves maintenance, so that clients can
+// notify their users
+type MaintenanceEvent struct {
+	// ID to identify the event itself within the sequence of events.
+	ID uint64
+
+	// Active is true if the gateway entered maintenance and false
+	// if it left it
+	Active bool
+
+	// Message is the notice of the operator for the clients
+	Message string
+
+	// RetryAfter is the time in seconds after which the clients
+	// are advised to retry their requests
+	RetryAfter uint64
+}
+
+// NodeReconnectedEvent is the event inserted in the queue of each
+// session when the gateway reconnects to the node after its
+// connection failed. The events that happened on the node while the
+// gateway was disconnected may have been missed, so clients are
+// advised to reconcile their state
+type NodeReconnectedEvent struct {
+	// ID to identify the event itself within the sequence of events.
+	ID uint64
+
+	// DisconnectedAt is the time in milliseconds since the epoch
+	// at which the connection to the node failed
+	DisconnectedAt int64
+
+	// ReconnectedAt is the time in milliseconds since the epoch
+	// at which the connection to the node was recreated
+	ReconnectedAt int64
+}
+
+// BatchCallStatus is the outcome of a call of a batch
+// summarized in a BatchCompletedEvent
+type BatchCallStatus struct {
+	// ID of the event of the call
+	ID uint64
+
+	// Cause is the error of the call if it failed
+	Cause *rpc.Error
+}
+
+// BatchCompletedEvent is the event inserted in the queue of a session
+// once all the calls of a batch complete, after the events of the
+// calls, so that the client can track the batch with a single poll
+type BatchCompletedEvent struct {
+	// ID to identify the event itself within the sequence of events.
+	ID uint64
+
+	// Calls is the outcome of each call in the order of the calls
+	Calls []BatchCallStatus
+}
+
 // EventID is the implementation of Event for ExecuteServiceResponse
 func (e ExecuteServiceResponse) EventID() uint64 {
 	return e.ID
@@ -290,6 +735,56 @@ func (e DataEvent) EventType() EventType {
 	return DataEventType
 }
 
+// EventID is the implementation of rpc.Event for LogRemovedEvent
+func (e LogRemovedEvent) EventID() uint64 {
+	return e.ID
+}
+
+// EventType is the implementation of Event for LogRemovedEvent
+func (e LogRemovedEvent) EventType() EventType {
+	return LogRemovedEventType
+}
+
+// EventID is the implementation of rpc.Event for WarningEvent
+func (e WarningEvent) EventID() uint64 {
+	return e.ID
+}
+
+// EventType is the implementation of Event for WarningEvent
+func (e WarningEvent) EventType() EventType {
+	return WarningEventType
+}
+
+// EventID is the implementation of Event for MaintenanceEvent
+func (e MaintenanceEvent) EventID() uint64 {
+	return e.ID
+}
+
+// EventType is the implementation of Event for MaintenanceEvent
+func (e MaintenanceEvent) EventType() EventType {
+	return MaintenanceEventType
+}
+
+// EventID is the implementation of Event for NodeReconnectedEvent
+func (e NodeReconnectedEvent) EventID() uint64 {
+	return e.ID
+}
+
+// EventType is the implementation of Event for NodeReconnectedEvent
+func (e NodeReconnectedEvent) EventType() EventType {
+	return NodeReconnectedEventType
+}
+
+// EventID is the implementation of Event for BatchCompletedEvent
+func (e BatchCompletedEvent) EventID() uint64 {
+	return e.ID
+}
+
+// EventType is the implementation of Event for BatchCompletedEvent
+func (e BatchCompletedEvent) EventType() EventType {
+	return BatchCompletedEventType
+}
+
 // PollServiceRequest is a request issued by a client to
 // retrieve a window of responses generated by
 // asynchronous requests
@@ -307,6 +802,19 @@ type PollServiceRequest struct {
 	// discard all the events that have a sequence number lower than the offer
 	DiscardPrevious bool
 
+	// Types if set restricts the events returned to the ones
+	// of the provided types
+	Types []EventType
+
+	// Since if set retrieves the events by the time they were
+	// produced instead of by Offset. The events produced at or
+	// after Since and before Until are returned
+	Since time.Time
+
+	// Until if set restricts the events retrieved by time to
+	// the ones produced before Until
+	Until time.Time
+
 	// Key is the identifier of the request issuer
 	SessionKey string
 }
@@ -328,6 +836,13 @@ type SubscribeRequest struct {
 	// Topics is the list of topics the subscription client is
 	// interested in
 	Topics []string
+
+	// AAD is the identifier of the issuer of the subscription
+	AAD string
+
+	// FromBlock if set is the block from which the historical
+	// events are delivered before the live events
+	FromBlock *uint64
 }
 
 // PollEventRequest is a request issued by the client to
@@ -346,6 +861,19 @@ type PollEventRequest struct {
 	// discard all the events that have a sequence number lower than the offer
 	DiscardPrevious bool
 
+	// Types if set restricts the events returned to the ones
+	// of the provided types
+	Types []EventType
+
+	// Since if set retrieves the events by the time they were
+	// produced instead of by Offset. The events produced at or
+	// after Since and before Until are returned
+	Since time.Time
+
+	// Until if set restricts the events retrieved by time to
+	// the ones produced before Until
+	Until time.Time
+
 	// ID is the unique identifier for a subscription based on
 	// the user's key namespace
 	ID uint64
@@ -381,6 +909,10 @@ type CreateSubscriptionRequest struct {
 
 	// Topics is the list of topics the client is interested in
 	Topics []string
+
+	// FromBlock if set is the block from which the historical
+	// events are delivered before the live events
+	FromBlock *uint64
 }
 
 // UnsubscribeRequest is a request issued by the client to destroy
@@ -389,3 +921,50 @@ type DestroySubscriptionRequest struct {
 	// SubID is the unique subscription's identifier
 	SubID string
 }
+
+// TraceTransactionRequest is a request to re-execute a
+// transaction with tracing enabled
+type TraceTransactionRequest struct {
+	// Hash is the hex encoded hash of the transaction
+	Hash string
+}
+
+// TraceStep is a step of the execution of a traced transaction
+type TraceStep struct {
+	// Pc is the program counter of the step
+	Pc uint64
+
+	// Op is the name of the opcode executed
+	Op string
+
+	// Gas is the gas available before the step
+	Gas uint64
+
+	// GasCost is the gas consumed by the step
+	GasCost uint64
+
+	// Depth is the call depth of the step
+	Depth int
+
+	// Error if set is the error that stopped the execution
+	Error string
+}
+
+// TraceTransactionResponse is the trace of the
+// execution of a transaction
+type TraceTransactionResponse struct {
+	// Hash is the hash of the transaction traced
+	Hash string
+
+	// Failed is true if the execution of the transaction failed
+	Failed bool
+
+	// Gas is the gas used by the transaction
+	Gas uint64
+
+	// ReturnValue is the hex encoded data returned by the transaction
+	ReturnValue string
+
+	// Steps are the steps of the execution of the transaction
+	Steps []TraceStep
+}
@@ -0,0 +1,44 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+)
+
+const (
+	// derivedIDBit is set on all the event IDs derived from an
+	// idempotency key so they do not overlap with the IDs assigned
+	// from the queue offsets
+	derivedIDBit uint64 = 1 << 52
+
+	// derivedIDMask keeps the derived event IDs within the range of
+	// integers that can be represented exactly as a JSON number
+	derivedIDMask uint64 = derivedIDBit - 1
+)
+
+// DeriveEventID derives the ID of the event generated by a request from
+// the AAD of the issuer and the idempotency key provided by the client.
+// The ID is computed from the first 8 bytes of
+// sha256(aad || 0x00 || idempotencyKey) taken as a big endian integer,
+// keeping the lower 52 bits and setting bit 52, so that clients can
+// compute it before the request returns
+func DeriveEventID(aad, idempotencyKey string) uint64 {
+	h := sha256.New()
+	_, _ = h.Write([]byte(aad))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(idempotencyKey))
+	sum := h.Sum(nil)
+
+	return binary.BigEndian.Uint64(sum[:8])&derivedIDMask | derivedIDBit
+}
+
+// eventID returns the ID for the event stored at offset. If the
+// client provided an idempotency key the ID is derived from it,
+// otherwise the offset is used
+func eventID(aad, idempotencyKey string, offset uint64) uint64 {
+	if len(idempotencyKey) == 0 {
+		return offset
+	}
+
+	return DeriveEventID(aad, idempotencyKey)
+}
@@ -0,0 +1,214 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/oasislabs/oasis-gateway/errors"
+	mqueue "github.com/oasislabs/oasis-gateway/mqueue/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestDeriveEventIDDeterministic(t *testing.T) {
+	assert.Equal(t, DeriveEventID("aad", "key"), DeriveEventID("aad", "key"))
+	assert.NotEqual(t, DeriveEventID("aad", "key"), DeriveEventID("aad", "key2"))
+	assert.NotEqual(t, DeriveEventID("aad", "key"), DeriveEventID("aad2", "key"))
+	assert.NotEqual(t, DeriveEventID("a", "ad"), DeriveEventID("aa", "d"))
+}
+
+func TestDeriveEventIDRange(t *testing.T) {
+	id := DeriveEventID("aad", "key")
+
+	assert.True(t, id >= 1<<52)
+	assert.True(t, id < 1<<53)
+}
+
+func TestEventIDNoIdempotencyKey(t *testing.T) {
+	assert.Equal(t, uint64(3), eventID("aad", "", 3))
+	assert.Equal(t, DeriveEventID("aad", "key"), eventID("aad", "key", 3))
+}
+
+func TestExecuteServiceAsyncIdempotencyKey(t *testing.T) {
+	manager := createMemRequestManager(false)
+	address := "0x0a51514857B379A521C580a10822Fd8A7aC491A0"
+	id := DeriveEventID("aad", "key")
+
+	manager.client.(*MockClient).On("ExecuteService", mock.Anything, id, mock.Anything).
+		Return(nil, errors.New(errors.ErrInternalError, nil))
+
+	res, err := manager.ExecuteServiceAsync(Context, ExecuteServiceRequest{
+		AAD:            "aad",
+		Address:        address,
+		IdempotencyKey: "key",
+		SessionKey:     "session",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, id, res)
+
+	// the event is stored at the first offset of the queue but
+	// it is identified by the derived ID
+	var evs Events
+	for i := 0; i < 100 && len(evs.Events) == 0; i++ {
+		evs, err = manager.PollService(Context, PollServiceRequest{
+			SessionKey: "session",
+			Offset:     0,
+			Count:      1,
+		})
+		assert.Nil(t, err)
+		time.Sleep(time.Millisecond)
+	}
+
+	assert.Equal(t, 1, len(evs.Events))
+	assert.Equal(t, id, evs.Events[0].(ErrorEvent).ID)
+}
+
+func TestExecuteServiceAsyncIdempotencyKeyRepeated(t *testing.T) {
+	manager := createMemRequestManager(false)
+	address := "0x0a51514857B379A521C580a10822Fd8A7aC491A0"
+	id := DeriveEventID("aad", "key")
+	req := ExecuteServiceRequest{
+		AAD:            "aad",
+		Address:        address,
+		IdempotencyKey: "key",
+		SessionKey:     "session",
+	}
+
+	manager.client.(*MockClient).On("ExecuteService", mock.Anything, id, mock.Anything).
+		Return(nil, errors.New(errors.ErrInternalError, nil))
+
+	res, err := manager.ExecuteServiceAsync(Context, req)
+	assert.Nil(t, err)
+	assert.Equal(t, id, res)
+
+	res, err = manager.ExecuteServiceAsync(Context, req)
+	assert.Nil(t, err)
+	assert.Equal(t, id, res)
+
+	// the repeated request does not reserve an offset in the
+	// queue, so the next offset follows the first request
+	offset, qerr := manager.mqueue.Next(Context, mqueue.NextRequest{Key: "session"})
+	assert.Nil(t, qerr)
+	assert.Equal(t, uint64(1), offset)
+
+	assert.Eventually(t, func() bool {
+		evs, err := manager.PollService(Context, PollServiceRequest{
+			SessionKey: "session",
+			Offset:     0,
+			Count:      1,
+		})
+		return err == nil && len(evs.Events) == 1
+	}, time.Second, time.Millisecond)
+	manager.client.(*MockClient).AssertNumberOfCalls(t, "ExecuteService", 1)
+}
+
+func TestIdempotencyRegistryRelease(t *testing.T) {
+	registry := NewIdempotencyRegistry(mqueue.NewMemStore(), IdempotencyRegistryProps{})
+
+	id, ok, err := registry.Claim(Context, "aad", "key", 1)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(1), id)
+
+	// a release for another claim leaves the key claimed
+	assert.Nil(t, registry.Release(Context, "aad", "key", 2))
+	id, ok, err = registry.Claim(Context, "aad", "key", 2)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, uint64(1), id)
+
+	assert.Nil(t, registry.Release(Context, "aad", "key", 1))
+	_, ok, err = registry.Claim(Context, "aad", "key", 2)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+}
+
+func TestIdempotencyRegistryExpired(t *testing.T) {
+	registry := NewIdempotencyRegistry(mqueue.NewMemStore(), IdempotencyRegistryProps{
+		TTL: time.Millisecond,
+	})
+
+	_, ok, err := registry.Claim(Context, "aad", "key", 1)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	record, err := registry.Get(Context, "aad", "key")
+	assert.Nil(t, err)
+	assert.False(t, record.ExpiresAt.IsZero())
+
+	time.Sleep(2 * time.Millisecond)
+	id, ok, err := registry.Claim(Context, "aad", "key", 2)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(2), id)
+}
+
+func TestIdempotencyRegistryLegacyRecord(t *testing.T) {
+	store := mqueue.NewMemStore()
+	registry := NewIdempotencyRegistry(store, IdempotencyRegistryProps{TTL: time.Hour})
+
+	// records stored before keys could expire do not expire
+	_, _, serr := store.SetField(Context, mqueue.SetFieldRequest{
+		Key:   IdempotencyRegistryID("aad"),
+		Field: "key",
+		Value: "1",
+	})
+	assert.Nil(t, serr)
+
+	record, err := registry.Get(Context, "aad", "key")
+	assert.Nil(t, err)
+	assert.Equal(t, IdempotencyRecord{AAD: "aad", Key: "key", ID: 1}, record)
+
+	id, ok, err := registry.Claim(Context, "aad", "key", 2)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, uint64(1), id)
+}
+
+func TestIdempotencyRegistryPurge(t *testing.T) {
+	registry := NewIdempotencyRegistry(mqueue.NewMemStore(), IdempotencyRegistryProps{})
+	for i, key := range []string{"a", "b", "c"} {
+		_, _, err := registry.Claim(Context, "aad", key, uint64(i))
+		assert.Nil(t, err)
+	}
+
+	purged, err := registry.Purge(Context, "aad", []string{"a", "missing"})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, purged)
+
+	records, err := registry.List(Context, "aad")
+	assert.Nil(t, err)
+	assert.Equal(t, []IdempotencyRecord{
+		{AAD: "aad", Key: "b", ID: 1},
+		{AAD: "aad", Key: "c", ID: 2},
+	}, records)
+
+	purged, err = registry.Purge(Context, "aad", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, purged)
+
+	_, err = registry.Get(Context, "aad", "b")
+	assert.Equal(t, errors.ErrIdempotencyKeyNotFound, err.ErrorCode())
+}
+
+func TestIdempotencyRegistrySetExpiry(t *testing.T) {
+	registry := NewIdempotencyRegistry(mqueue.NewMemStore(), IdempotencyRegistryProps{})
+	_, _, err := registry.Claim(Context, "aad", "key", 1)
+	assert.Nil(t, err)
+
+	record, err := registry.SetExpiry(Context, "aad", "key", time.Now().Add(-time.Second))
+	assert.Nil(t, err)
+	assert.True(t, record.Expired(time.Now()))
+
+	// the expired key can be claimed again
+	_, ok, err := registry.Claim(Context, "aad", "key", 2)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	record, err = registry.SetExpiry(Context, "aad", "key", time.Time{})
+	assert.Nil(t, err)
+	assert.Equal(t, IdempotencyRecord{AAD: "aad", Key: "key", ID: 2}, record)
+
+	_, err = registry.SetExpiry(Context, "aad", "missing", time.Time{})
+	assert.Equal(t, errors.ErrIdempotencyKeyNotFound, err.ErrorCode())
+}
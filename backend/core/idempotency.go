@@ -0,0 +1,287 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/oasislabs/oasis-gateway/errors"
+	mqueue "github.com/oasislabs/oasis-gateway/mqueue/core"
+)
+
+// IdempotencyRegistryID generates the key of the store that
+// holds the idempotency keys used by an AAD
+func IdempotencyRegistryID(aad string) string {
+	return fmt.Sprintf("%s:idempotency", aad)
+}
+
+// IdempotencyRecord is the record of a request accepted
+// with an idempotency key
+type IdempotencyRecord struct {
+	// AAD of the issuer of the request
+	AAD string
+
+	// Key is the idempotency key provided by the client
+	Key string
+
+	// ID of the event of the request
+	ID uint64
+
+	// ExpiresAt is the time at which the key can be claimed
+	// again. If it is zero the key does not expire
+	ExpiresAt time.Time
+}
+
+// Expired returns true if the record has expired at the provided time
+func (r IdempotencyRecord) Expired(now time.Time) bool {
+	return !r.ExpiresAt.IsZero() && !now.Before(r.ExpiresAt)
+}
+
+// encodeIdempotencyRecord encodes the value stored for a record as
+// the event ID followed, if the record expires, by the time of
+// expiration in milliseconds since the epoch
+func encodeIdempotencyRecord(record IdempotencyRecord) string {
+	id := strconv.FormatUint(record.ID, 10)
+	if record.ExpiresAt.IsZero() {
+		return id
+	}
+
+	return fmt.Sprintf("%s:%d", id, record.ExpiresAt.UnixNano()/int64(time.Millisecond))
+}
+
+// decodeIdempotencyRecord decodes the value stored for a record. Values
+// stored before records could expire only hold the event ID
+func decodeIdempotencyRecord(aad, key, value string) (IdempotencyRecord, error) {
+	record := IdempotencyRecord{AAD: aad, Key: key}
+
+	parts := strings.SplitN(value, ":", 2)
+	id, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return IdempotencyRecord{}, err
+	}
+	record.ID = id
+
+	if len(parts) == 2 {
+		ms, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil {
+			return IdempotencyRecord{}, err
+		}
+		record.ExpiresAt = time.Unix(0, ms*int64(time.Millisecond))
+	}
+
+	return record, nil
+}
+
+// IdempotencyRegistryProps are the properties used to
+// create an IdempotencyRegistry
+type IdempotencyRegistryProps struct {
+	// TTL is the time after which an idempotency key can be
+	// claimed again. If it is 0 the keys do not expire
+	TTL time.Duration
+}
+
+// IdempotencyRegistry keeps track of the idempotency keys of the
+// requests accepted by the gateway, so that a request retried with the
+// same key is only executed once. The keys are kept in the mailbox
+// store and are shared by the gateways that share the mailbox. The
+// store does not expire them, an expired key is replaced when it is
+// claimed again
+type IdempotencyRegistry struct {
+	store mqueue.Store
+	ttl   time.Duration
+}
+
+// NewIdempotencyRegistry creates a new registry backed by the provided store
+func NewIdempotencyRegistry(store mqueue.Store, props IdempotencyRegistryProps) *IdempotencyRegistry {
+	return &IdempotencyRegistry{store: store, ttl: props.TTL}
+}
+
+// Claim atomically records that the request of the AAD with the
+// idempotency key has been accepted with the provided event ID. If a
+// request with the same key was accepted before, the ID of its event is
+// returned along with false, and the request must not be executed again
+func (r *IdempotencyRegistry) Claim(ctx context.Context, aad, key string, id uint64) (uint64, bool, errors.Err) {
+	record := IdempotencyRecord{AAD: aad, Key: key, ID: id}
+	if r.ttl > 0 {
+		record.ExpiresAt = time.Now().Add(r.ttl)
+	}
+
+	// an expired record is removed and the claim attempted again. If
+	// the claim fails a second time another request has claimed the
+	// key in the meantime
+	for attempt := 0; ; attempt++ {
+		value, ok, err := r.store.SetField(ctx, mqueue.SetFieldRequest{
+			Key:      IdempotencyRegistryID(aad),
+			Field:    key,
+			Value:    encodeIdempotencyRecord(record),
+			IfAbsent: true,
+		})
+		if err != nil {
+			return 0, false, queueError(errors.ErrStore, err)
+		}
+		if ok {
+			return id, true, nil
+		}
+
+		existing, derr := decodeIdempotencyRecord(aad, key, value)
+		if derr != nil {
+			return 0, false, errors.New(errors.ErrStore, derr)
+		}
+
+		if attempt > 0 || !existing.Expired(time.Now()) {
+			return existing.ID, false, nil
+		}
+
+		if _, err := r.store.DeleteField(ctx, mqueue.DeleteFieldRequest{
+			Key:     IdempotencyRegistryID(aad),
+			Field:   key,
+			IfValue: value,
+		}); err != nil {
+			return 0, false, queueError(errors.ErrStore, err)
+		}
+	}
+}
+
+// Release removes the claim on the idempotency key made for the
+// event ID, so that a request that could not be accepted after
+// the claim can be retried with the same key
+func (r *IdempotencyRegistry) Release(ctx context.Context, aad, key string, id uint64) errors.Err {
+	record, err := r.Get(ctx, aad, key)
+	if err != nil {
+		if err.ErrorCode() == errors.ErrIdempotencyKeyNotFound {
+			return nil
+		}
+		return err
+	}
+	if record.ID != id {
+		return nil
+	}
+
+	if _, err := r.store.DeleteField(ctx, mqueue.DeleteFieldRequest{
+		Key:     IdempotencyRegistryID(aad),
+		Field:   key,
+		IfValue: encodeIdempotencyRecord(record),
+	}); err != nil {
+		return queueError(errors.ErrStore, err)
+	}
+
+	return nil
+}
+
+// Get retrieves the record of the idempotency key of the AAD. Expired
+// records are returned until the key is claimed again
+func (r *IdempotencyRegistry) Get(ctx context.Context, aad, key string) (IdempotencyRecord, errors.Err) {
+	value, ok, err := r.store.GetField(ctx, mqueue.FieldRequest{
+		Key:   IdempotencyRegistryID(aad),
+		Field: key,
+	})
+	if err != nil {
+		return IdempotencyRecord{}, queueError(errors.ErrStore, err)
+	}
+	if !ok {
+		return IdempotencyRecord{}, errors.New(errors.ErrIdempotencyKeyNotFound, nil)
+	}
+
+	record, derr := decodeIdempotencyRecord(aad, key, value)
+	if derr != nil {
+		return IdempotencyRecord{}, errors.New(errors.ErrStore, derr)
+	}
+
+	return record, nil
+}
+
+// List retrieves the records of all the idempotency keys of the AAD
+// sorted by key
+func (r *IdempotencyRegistry) List(ctx context.Context, aad string) ([]IdempotencyRecord, errors.Err) {
+	fields, err := r.store.Fields(ctx, mqueue.FieldsRequest{Key: IdempotencyRegistryID(aad)})
+	if err != nil {
+		return nil, queueError(errors.ErrStore, err)
+	}
+
+	records := make([]IdempotencyRecord, 0, len(fields))
+	for key, value := range fields {
+		record, err := decodeIdempotencyRecord(aad, key, value)
+		if err != nil {
+			return nil, queueError(errors.ErrStore, err)
+		}
+		records = append(records, record)
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Key < records[j].Key
+	})
+
+	return records, nil
+}
+
+// Purge removes the records of the idempotency keys of the AAD, so
+// that the keys can be claimed again. If no keys are provided all the
+// keys of the AAD are purged. It returns the number of keys removed
+func (r *IdempotencyRegistry) Purge(ctx context.Context, aad string, keys []string) (int, errors.Err) {
+	if len(keys) == 0 {
+		fields, err := r.store.Fields(ctx, mqueue.FieldsRequest{Key: IdempotencyRegistryID(aad)})
+		if err != nil {
+			return 0, queueError(errors.ErrStore, err)
+		}
+
+		for key := range fields {
+			keys = append(keys, key)
+		}
+	}
+
+	purged := 0
+	for _, key := range keys {
+		ok, err := r.store.DeleteField(ctx, mqueue.DeleteFieldRequest{
+			Key:   IdempotencyRegistryID(aad),
+			Field: key,
+		})
+		if err != nil {
+			return purged, queueError(errors.ErrStore, err)
+		}
+		if ok {
+			purged++
+		}
+	}
+
+	return purged, nil
+}
+
+// SetExpiry sets the time at which the record of the idempotency key of
+// the AAD expires. If expiresAt is zero the record does not expire. The
+// record is replaced only if it has not changed since it was retrieved,
+// so a key purged or claimed again in the meantime is left as it is
+func (r *IdempotencyRegistry) SetExpiry(ctx context.Context, aad, key string, expiresAt time.Time) (IdempotencyRecord, errors.Err) {
+	record, err := r.Get(ctx, aad, key)
+	if err != nil {
+		return IdempotencyRecord{}, err
+	}
+
+	ok, derr := r.store.DeleteField(ctx, mqueue.DeleteFieldRequest{
+		Key:     IdempotencyRegistryID(aad),
+		Field:   key,
+		IfValue: encodeIdempotencyRecord(record),
+	})
+	if derr != nil {
+		return IdempotencyRecord{}, errors.New(errors.ErrStore, derr)
+	}
+	if !ok {
+		return IdempotencyRecord{}, errors.New(errors.ErrIdempotencyKeyNotFound, nil)
+	}
+
+	record.ExpiresAt = expiresAt
+	if _, ok, err := r.store.SetField(ctx, mqueue.SetFieldRequest{
+		Key:      IdempotencyRegistryID(aad),
+		Field:    key,
+		Value:    encodeIdempotencyRecord(record),
+		IfAbsent: true,
+	}); err != nil {
+		return IdempotencyRecord{}, queueError(errors.ErrStore, err)
+	} else if !ok {
+		return IdempotencyRecord{}, errors.New(errors.ErrIdempotencyKeyNotFound, nil)
+	}
+
+	return record, nil
+}
@@ -0,0 +1,140 @@
+package core
+
+import (
+	"time"
+
+	"github.com/oasislabs/oasis-gateway/errors"
+	"github.com/oasislabs/oasis-gateway/log"
+	mqueue "github.com/oasislabs/oasis-gateway/mqueue/core"
+	"github.com/oasislabs/oasis-gateway/rpc"
+)
+
+const (
+	// SubscriptionLagQuota is the name of the quota on the number of
+	// events of a subscription its consumer has not acknowledged
+	SubscriptionLagQuota = "subscriptionLag"
+
+	// SubscriptionAgeQuota is the name of the quota on the time in
+	// milliseconds the oldest event of a subscription that its
+	// consumer has not acknowledged has been kept
+	SubscriptionAgeQuota = "subscriptionAge"
+)
+
+// SubscriptionLagProps defines when the consumer of a subscription
+// is considered to be lagging behind and what is done about it
+type SubscriptionLagProps struct {
+	// MaxLag is the number of events of a subscription that its
+	// consumer may not have acknowledged. If 0 the lag is not checked
+	MaxLag uint64
+
+	// MaxAge is the time the oldest event of a subscription that its
+	// consumer has not acknowledged may be kept. If 0 the age of
+	// the events is not checked
+	MaxAge time.Duration
+
+	// AutoUnsubscribe if set closes the subscriptions whose consumers
+	// are lagging behind, instead of only warning them
+	AutoUnsubscribe bool
+}
+
+// enabled returns true if the lag of the consumers is checked
+func (p SubscriptionLagProps) enabled() bool {
+	return p.MaxLag > 0 || p.MaxAge > 0
+}
+
+// exceeded checks the lag of the consumer of a subscription once the
+// event at offset has been inserted. els are the first elements kept
+// for the subscription, which its consumer has not acknowledged. It
+// returns the name of the quota exceeded, with its usage and its limit,
+// or an empty name if the consumer is not lagging behind
+func (p SubscriptionLagProps) exceeded(offset uint64, els mqueue.Elements, now time.Time) (string, uint64, uint64) {
+	if p.MaxLag > 0 && offset >= els.Offset {
+		if lag := offset - els.Offset + 1; lag > p.MaxLag {
+			return SubscriptionLagQuota, lag, p.MaxLag
+		}
+	}
+
+	if p.MaxAge > 0 && len(els.Elements) > 0 && els.Elements[0].Timestamp > 0 {
+		age := mqueue.Timestamp(now) - els.Elements[0].Timestamp
+		limit := int64(p.MaxAge / time.Millisecond)
+		if age > limit {
+			return SubscriptionAgeQuota, uint64(age), uint64(limit)
+		}
+	}
+
+	return "", 0, 0
+}
+
+// checkLag checks whether the consumer of the subscription is lagging
+// behind once the event at offset has been inserted. The consumer is
+// warned once every time it falls behind. It returns true if the
+// subscription has been closed because of it
+func (s *subscription) checkLag(offset uint64) bool {
+	if !s.lag.enabled() {
+		return false
+	}
+
+	els, err := s.mqueue.Retrieve(s.ctx, mqueue.RetrieveRequest{Key: s.key, Offset: 0, Count: 1})
+	if err != nil {
+		s.logger.Warn(s.ctx, "failed to retrieve subscription offset", log.MapFields{
+			"call_type": "SubscriptionLagFailure",
+			"key":       s.key,
+			"err":       err.Error(),
+		})
+		return false
+	}
+
+	quota, used, limit := s.lag.exceeded(offset, els, time.Now())
+	if len(quota) == 0 {
+		s.warned = false
+		return false
+	}
+
+	// the events from keep onwards are kept if the subscription
+	// is closed, so that the consumer can find out why
+	var keep uint64
+	var warned bool
+	if !s.warned {
+		s.warned = true
+		keep, warned = s.insert(func(id uint64) Event {
+			return WarningEvent{ID: id, Quota: quota, Used: used, Limit: limit}
+		})
+	}
+
+	if !s.lag.AutoUnsubscribe {
+		return false
+	}
+
+	id, ok := s.insert(func(id uint64) Event {
+		return ErrorEvent{
+			ID: id,
+			Cause: rpc.Error{
+				ErrorCode:   errors.ErrSubscriptionLagging.Code(),
+				Description: errors.ErrSubscriptionLagging.Desc(),
+			},
+		}
+	})
+	if !ok {
+		return false
+	}
+	if !warned {
+		keep = id
+	}
+
+	if err := s.mqueue.Discard(s.ctx, mqueue.DiscardRequest{Key: s.key, Offset: keep}); err != nil {
+		s.logger.Warn(s.ctx, "failed to discard events of lagging subscription", log.MapFields{
+			"call_type": "SubscriptionLagFailure",
+			"key":       s.key,
+			"err":       err.Error(),
+		})
+	}
+
+	s.logger.Info(s.ctx, "subscription closed because its consumer is lagging behind", log.MapFields{
+		"call_type": "SubscriptionLagClose",
+		"key":       s.key,
+		"quota":     quota,
+		"used":      used,
+		"limit":     limit,
+	})
+	return true
+}
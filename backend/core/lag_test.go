@@ -0,0 +1,134 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/oasislabs/oasis-gateway/errors"
+	"github.com/oasislabs/oasis-gateway/mqueue/core"
+	"github.com/oasislabs/oasis-gateway/mqueue/mem"
+	"github.com/oasislabs/oasis-gateway/rpc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubscriptionLagPropsExceeded(t *testing.T) {
+	now := time.Now()
+	els := core.Elements{
+		Offset:   2,
+		Elements: []core.Element{{Offset: 2, Timestamp: core.Timestamp(now.Add(-time.Minute))}},
+	}
+
+	quota, used, limit := SubscriptionLagProps{MaxLag: 4}.exceeded(5, els, now)
+	assert.Equal(t, "", quota)
+
+	quota, used, limit = SubscriptionLagProps{MaxLag: 3}.exceeded(5, els, now)
+	assert.Equal(t, SubscriptionLagQuota, quota)
+	assert.Equal(t, uint64(4), used)
+	assert.Equal(t, uint64(3), limit)
+
+	quota, _, _ = SubscriptionLagProps{MaxAge: time.Hour}.exceeded(5, els, now)
+	assert.Equal(t, "", quota)
+
+	quota, used, limit = SubscriptionLagProps{MaxAge: time.Second}.exceeded(5, els, now)
+	assert.Equal(t, SubscriptionAgeQuota, quota)
+	assert.Equal(t, uint64(60000), used)
+	assert.Equal(t, uint64(1000), limit)
+}
+
+func createLagSubscription(
+	t *testing.T,
+	lag SubscriptionLagProps,
+	release func(context.Context, string) errors.Err,
+) (*SubscriptionManager, core.MQueue, chan interface{}) {
+	mq := mem.NewServer(Context, mem.Services{Logger: Logger})
+	manager := NewSubscriptionManager(SubscriptionManagerProps{
+		Context: Context,
+		Logger:  Logger,
+		MQueue:  mq,
+		Lag:     lag,
+		Release: release,
+	})
+
+	c := make(chan interface{})
+	assert.Nil(t, manager.Create(Context, "session:sub:0", "aad", c))
+	return manager, mq, c
+}
+
+func waitForEvents(t *testing.T, mq core.MQueue, count int) Events {
+	for {
+		els, err := mq.Retrieve(Context, core.RetrieveRequest{Key: "session:sub:0", Count: 10})
+		assert.Nil(t, err)
+		if len(els.Elements) >= count {
+			var events []Event
+			for _, el := range els.Elements {
+				ev, err := deserializeElement(el)
+				assert.Nil(t, err)
+				events = append(events, ev)
+			}
+			return Events{Offset: els.Offset, Events: events}
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSubscriptionLagWarn(t *testing.T) {
+	manager, mq, c := createLagSubscription(t, SubscriptionLagProps{MaxLag: 2}, nil)
+
+	for i := 0; i < 3; i++ {
+		c <- types.Log{Data: []byte{byte(i)}}
+	}
+
+	// the consumer is warned once, right after the event that
+	// made it fall behind
+	c <- types.Log{Data: []byte{3}}
+	evs := waitForEvents(t, mq, 5)
+	assert.Equal(t, uint64(0), evs.Offset)
+	assert.Equal(t, WarningEvent{ID: 3, Quota: SubscriptionLagQuota, Used: 3, Limit: 2}, evs.Events[3])
+	assert.Equal(t, DataEvent{ID: 4, Data: "0x03"}, evs.Events[4])
+	assert.True(t, manager.Exists(Context, "session:sub:0"))
+}
+
+func TestSubscriptionLagAutoUnsubscribe(t *testing.T) {
+	released := make(chan string, 1)
+	manager, mq, c := createLagSubscription(t, SubscriptionLagProps{
+		MaxLag:          1,
+		AutoUnsubscribe: true,
+	}, func(ctx context.Context, key string) errors.Err {
+		released <- key
+		return nil
+	})
+
+	c <- types.Log{Data: []byte{0}}
+	c <- types.Log{Data: []byte{1}}
+	assert.Equal(t, "session:sub:0", <-released)
+	assert.False(t, manager.Exists(Context, "session:sub:0"))
+
+	// only the warning and the terminal event are kept
+	evs := waitForEvents(t, mq, 2)
+	assert.Equal(t, Events{
+		Offset: 2,
+		Events: []Event{
+			WarningEvent{ID: 2, Quota: SubscriptionLagQuota, Used: 2, Limit: 1},
+			ErrorEvent{
+				ID: 3,
+				Cause: rpc.Error{
+					ErrorCode:   errors.ErrSubscriptionLagging.Code(),
+					Description: errors.ErrSubscriptionLagging.Desc(),
+				},
+			},
+		},
+	}, evs)
+}
+
+func TestSubscriptionManagerUnknownRequest(t *testing.T) {
+	manager, _, _ := createLagSubscription(t, SubscriptionLagProps{}, nil)
+
+	// an unknown request is counted and the manager keeps
+	// serving the requests that follow
+	manager.req <- struct{}{}
+	assert.True(t, manager.Exists(Context, "session:sub:0"))
+	assert.Equal(t, uint64(1), manager.Stats()["unknownRequestCount"])
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	stderr "errors"
 	"fmt"
+	"sync"
 
 	ethereum "github.com/ethereum/go-ethereum/common"
 	"github.com/oasislabs/oasis-gateway/errors"
@@ -13,17 +14,34 @@ import (
 	"github.com/oasislabs/oasis-gateway/stats"
 )
 
+// queueError returns the error for a failed mailbox operation. The
+// failures caused by the mailbox being unavailable are reported as
+// such so that clients know they can retry the request later
+func queueError(code errors.ErrorCode, err error) errors.Err {
+	if err == mqueue.ErrUnavailable {
+		return errors.New(errors.ErrMailboxUnavailable, err)
+	}
+
+	return errors.New(code, err)
+}
+
+// maxHeartbeatScan is the maximum number of events counted in
+// the mailbox of a session on a heartbeat
+const maxHeartbeatScan = 1024
+
 // Client is an interface for any type that sends requests and
 // receives responses
 type Client interface {
 	Name() string
 	Stats() stats.Metrics
 	Senders() []ethereum.Address
+	GetChainHead(context.Context) (GetChainHeadResponse, errors.Err)
 	GetCode(context.Context, GetCodeRequest) (GetCodeResponse, errors.Err)
 	GetExpiry(context.Context, GetExpiryRequest) (GetExpiryResponse, errors.Err)
 	GetPublicKey(context.Context, GetPublicKeyRequest) (GetPublicKeyResponse, errors.Err)
 	ExecuteService(context.Context, uint64, ExecuteServiceRequest) (ExecuteServiceResponse, errors.Err)
 	DeployService(context.Context, uint64, DeployServiceRequest) (DeployServiceResponse, errors.Err)
+	QueryService(context.Context, QueryServiceRequest) (QueryServiceResponse, errors.Err)
 	SubscribeRequest(context.Context, CreateSubscriptionRequest, chan<- interface{}) errors.Err
 	UnsubscribeRequest(context.Context, DestroySubscriptionRequest) errors.Err
 }
@@ -33,10 +51,31 @@ type Client interface {
 // that the caller can later on query to find out the outcome
 // of the request.
 type RequestManager struct {
-	mqueue mqueue.MQueue
-	client Client
-	logger log.Logger
-	subman *SubscriptionManager
+	mqueue      mqueue.MQueue
+	store       mqueue.Store
+	bus         EventBus
+	client      Client
+	logger      log.Logger
+	subman      *SubscriptionManager
+	registry    *DeployRegistry
+	aliases     *AliasRegistry
+	idempotency *IdempotencyRegistry
+	outputKeys  *OutputKeyRegistry
+	sessions    *SessionRegistry
+	outputs     *OutputStore
+	resolver    AddressResolver
+	batcher     BatchExecutor
+	queryCache  *QueryCache
+	quota       QuotaProps
+	inFlight    *inFlightTracker
+	deployDedup bool
+
+	// requests tracks the requests running in the background, so
+	// that they can complete before the manager shuts down. Once
+	// closed is set new requests are rejected
+	requests     sync.WaitGroup
+	shutdownLock sync.Mutex
+	closed       bool
 }
 
 func (m *RequestManager) Name() string {
@@ -44,15 +83,63 @@ func (m *RequestManager) Name() string {
 }
 
 func (m *RequestManager) Stats() stats.Metrics {
-	return stats.Metrics{
+	metrics := stats.Metrics{
 		"subscriptions": m.subman.Stats(),
+		"sessions":      m.sessions.Stats(),
+	}
+
+	if collector, ok := m.bus.(stats.Collector); ok {
+		metrics["bus"] = collector.Stats()
 	}
+
+	return metrics
 }
 
 type RequestManagerProperties struct {
 	MQueue mqueue.MQueue
 	Client Client
 	Logger log.Logger
+
+	// Store is the durable store in which the manager keeps the state
+	// that has to outlive the sessions, like the deploy records. If
+	// not set the store provided by MQueue is used
+	Store mqueue.Store
+
+	// Bus is the bus on which the events generated by the requests
+	// are published. If not set a QueueBus that stores the
+	// events in MQueue is used
+	Bus EventBus
+
+	// DeployDedup when set makes a deployment of bytecode that the
+	// same AAD has already deployed return the address of the
+	// existing service instead of deploying it again
+	DeployDedup bool
+
+	// Idempotency defines when the idempotency keys provided
+	// by the clients expire
+	Idempotency IdempotencyRegistryProps
+
+	// Resolver is an optional resolver used for the service addresses
+	// that are not hex addresses and do not match an alias
+	Resolver AddressResolver
+
+	// QueryCache defines how the results of read-only queries are
+	// cached. If the TTL is 0 results are not cached
+	QueryCache QueryCacheProps
+
+	// Quota defines the quotas of the sessions, when they are warned
+	// that they approach them and how many requests they may have
+	// in flight
+	Quota QuotaProps
+
+	// Output defines when the outputs of the executions are too
+	// large to be included in their events. If MaxSize is 0
+	// outputs are never truncated
+	Output OutputStoreProps
+
+	// Subscription defines when the consumers of the subscriptions
+	// are lagging behind and whether their subscriptions are closed
+	Subscription SubscriptionLagProps
 }
 
 // NewRequestManager creates a new instance of a request manager
@@ -69,22 +156,86 @@ func NewRequestManager(properties RequestManagerProperties) *RequestManager {
 		panic("Logger must be set")
 	}
 
-	return &RequestManager{
+	store := properties.Store
+	if store == nil {
+		s, ok := mqueue.StoreOf(properties.MQueue)
+		if !ok {
+			panic("Store must be set if MQueue does not provide one")
+		}
+		store = s
+	}
+
+	aliases := NewAliasRegistry(store)
+	resolvers := AddressResolvers{aliases}
+	if properties.Resolver != nil {
+		resolvers = append(resolvers, properties.Resolver)
+	}
+
+	bus := properties.Bus
+	if bus == nil {
+		bus = NewQueueBus(properties.MQueue)
+	}
+
+	m := &RequestManager{
 		mqueue: properties.MQueue,
-		logger: properties.Logger,
+		store:  store,
+		bus:    bus,
+		logger: properties.Logger.ForClass("backend/core", "RequestManager"),
 		client: properties.Client,
 		subman: NewSubscriptionManager(SubscriptionManagerProps{
 			Context: context.Background(),
 			Logger:  properties.Logger,
 			MQueue:  properties.MQueue,
+			Bus:     bus,
+			Lag:     properties.Subscription,
+			Release: func(ctx context.Context, key string) errors.Err {
+				return properties.Client.UnsubscribeRequest(ctx, DestroySubscriptionRequest{SubID: key})
+			},
 		}),
+		registry:    NewDeployRegistry(store),
+		aliases:     aliases,
+		idempotency: NewIdempotencyRegistry(store, properties.Idempotency),
+		outputKeys:  NewOutputKeyRegistry(store),
+		sessions:    NewSessionRegistry(properties.MQueue, SessionRegistryProps{}),
+		outputs:     NewOutputStore(properties.MQueue, properties.Output),
+		resolver:    resolvers,
+		queryCache:  NewQueryCache(properties.QueryCache),
+		quota:       properties.Quota.withQueueLimit(properties.MQueue),
+		inFlight:    newInFlightTracker(properties.Quota.MaxInFlight),
+		deployDedup: properties.DeployDedup,
 	}
+
+	// the calls of a batch are executed together
+	// if the client supports it
+	if batcher, ok := properties.Client.(BatchExecutor); ok {
+		m.batcher = batcher
+	}
+
+	// the sessions are notified when the client reconnects to the
+	// node, since events may have been missed while it was not
+	if notifier, ok := properties.Client.(ReconnectNotifier); ok {
+		notifier.NotifyReconnect(m.Reconnected)
+	}
+
+	return m
+}
+
+// Bus returns the bus on which the events generated
+// by the requests are published
+func (m *RequestManager) Bus() EventBus {
+	return m.bus
 }
 
 func (m *RequestManager) Senders() []ethereum.Address {
 	return m.client.Senders()
 }
 
+// GetChainHead retrieves the latest block known to the backend,
+// the chain it belongs to and whether the backend is syncing
+func (m *RequestManager) GetChainHead(ctx context.Context) (GetChainHeadResponse, errors.Err) {
+	return m.client.GetChainHead(ctx)
+}
+
 // GetCode retrieves the source code for a specific service
 func (m *RequestManager) GetCode(
 	ctx context.Context,
@@ -121,6 +272,34 @@ func (m *RequestManager) GetPublicKey(
 	return m.client.GetPublicKey(ctx, req)
 }
 
+// QueryService executes a read-only query on a service. The results
+// are cached if a query cache is configured
+func (m *RequestManager) QueryService(
+	ctx context.Context,
+	req QueryServiceRequest,
+) (QueryServiceResponse, errors.Err) {
+	if len(req.Address) == 0 {
+		return QueryServiceResponse{}, errors.New(errors.ErrInvalidAddress, nil)
+	}
+
+	address, err := resolveAddress(ctx, m.resolver, req.Address)
+	if err != nil {
+		return QueryServiceResponse{}, err
+	}
+	req.Address = address
+
+	if res, ok := m.queryCache.Get(req); ok {
+		return res, nil
+	}
+
+	res, err := m.client.QueryService(ctx, req)
+	if err != nil {
+		return QueryServiceResponse{}, err
+	}
+
+	return m.queryCache.Set(req, res), nil
+}
+
 // RequestManager starts a request and provides an identifier for the caller to
 // find the request later on. Executes an operation on a service
 func (m *RequestManager) ExecuteServiceAsync(
@@ -131,27 +310,376 @@ func (m *RequestManager) ExecuteServiceAsync(
 		return 0, errors.New(errors.ErrInvalidAddress, nil)
 	}
 
-	id, err := m.mqueue.Next(ctx, mqueue.NextRequest{Key: req.SessionKey})
+	address, err := resolveAddress(ctx, m.resolver, req.Address)
 	if err != nil {
-		return 0, errors.New(errors.ErrQueueNext, err)
+		return 0, err
 	}
+	req.Address = address
 
-	go m.doRequest(ctx, req.SessionKey, id, func() (Event, errors.Err) { return m.client.ExecuteService(ctx, id, req) })
+	outputKey, err := m.outputKeys.Bind(ctx, req.AAD, req.SessionKey, req.OutputKey)
+	if err != nil {
+		return 0, err
+	}
+	req.OutputKey = outputKey
+
+	return m.startRequest(ctx, req.SessionKey, req.AAD, req.IdempotencyKey, func(id uint64) (Event, errors.Err) {
+		return m.executeService(ctx, id, req)
+	})
+}
+
+// startRequest reserves the offset at which the event of a request is
+// published and runs the request in the background. If the client
+// provided an idempotency key and a request with the same key has
+// already been accepted, the ID of the event of that request is
+// returned and the request is not executed again
+func (m *RequestManager) startRequest(
+	ctx context.Context,
+	key, aad, idempotencyKey string,
+	fn func(id uint64) (Event, errors.Err),
+) (uint64, errors.Err) {
+	if err := m.beginRequest(); err != nil {
+		return 0, err
+	}
+	started := false
+	defer func() {
+		if !started {
+			m.requests.Done()
+		}
+	}()
+
+	if len(idempotencyKey) > 0 {
+		id, ok, err := m.idempotency.Claim(ctx, aad, idempotencyKey, DeriveEventID(aad, idempotencyKey))
+		if err != nil {
+			return 0, err
+		}
+
+		if !ok {
+			m.logger.Debug(ctx, "request with the same idempotency key already accepted", log.MapFields{
+				"call_type": "RequestDeduplicated",
+				"id":        id,
+			})
+			return id, nil
+		}
+	}
+
+	if err := m.checkSpendQuota(ctx, aad); err != nil {
+		m.releaseIdempotencyKey(ctx, aad, idempotencyKey)
+		return 0, err
+	}
+
+	if err := m.inFlight.acquire(key); err != nil {
+		m.releaseIdempotencyKey(ctx, aad, idempotencyKey)
+		return 0, err
+	}
+
+	offset, err := m.mqueue.Next(ctx, mqueue.NextRequest{Key: key})
+	if err != nil {
+		m.inFlight.release(key)
+		m.releaseIdempotencyKey(ctx, aad, idempotencyKey)
+		return 0, queueError(errors.ErrQueueNext, err)
+	}
+
+	if err := m.checkQueueQuota(ctx, key, aad, offset); err != nil {
+		m.inFlight.release(key)
+		m.releaseIdempotencyKey(ctx, aad, idempotencyKey)
+		return 0, err
+	}
+
+	id := eventID(aad, idempotencyKey, offset)
+	started = true
+	go func() {
+		defer m.requests.Done()
+		m.doRequest(ctx, key, aad, offset, id, func() (Event, errors.Err) { return fn(id) })
+	}()
 
 	return id, nil
 }
 
+// beginRequest accounts for a request that may run in the background.
+// It fails once the manager is shutting down
+func (m *RequestManager) beginRequest() errors.Err {
+	m.shutdownLock.Lock()
+	defer m.shutdownLock.Unlock()
+
+	if m.closed {
+		return errors.New(errors.ErrShuttingDown, nil)
+	}
+
+	m.requests.Add(1)
+	return nil
+}
+
+// Shutdown stops the manager from accepting new requests and waits
+// for the requests running in the background to publish their events,
+// or until the context is done
+func (m *RequestManager) Shutdown(ctx context.Context) error {
+	m.shutdownLock.Lock()
+	m.closed = true
+	m.shutdownLock.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		m.requests.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// releaseIdempotencyKey releases the claim on the idempotency key of a
+// request that could not be accepted, so that the client can retry it
+func (m *RequestManager) releaseIdempotencyKey(ctx context.Context, aad, idempotencyKey string) {
+	if len(idempotencyKey) == 0 {
+		return
+	}
+
+	if err := m.idempotency.Release(ctx, aad, idempotencyKey, DeriveEventID(aad, idempotencyKey)); err != nil {
+		m.logger.Warn(ctx, "failed to release idempotency key", log.MapFields{
+			"call_type": "IdempotencyKeyReleaseFailure",
+		}, err)
+	}
+}
+
+// executeService executes the service and keeps track of the encoding
+// the client requested for the output in the generated event. If the
+// session has an output key the output is sealed to it before the
+// event is published, so that it is not stored in the clear. Outputs that
+// are too large are truncated and kept in the OutputStore
+func (m *RequestManager) executeService(ctx context.Context, id uint64, req ExecuteServiceRequest) (Event, errors.Err) {
+	res, err := m.client.ExecuteService(ctx, id, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.executeServiceEvent(ctx, id, req, res)
+}
+
+// executeServiceEvent returns the event for the response of an
+// execution once its output is sealed and truncated if needed
+func (m *RequestManager) executeServiceEvent(
+	ctx context.Context,
+	id uint64,
+	req ExecuteServiceRequest,
+	res ExecuteServiceResponse,
+) (Event, errors.Err) {
+	m.recordSpend(ctx, req.SessionKey, req.AAD, res.GasUsed)
+
+	res.Encoding = req.Encoding
+	if req.OutputKey != nil {
+		output, err := sealOutput(res.Output, req.OutputKey)
+		if err != nil {
+			return nil, err
+		}
+
+		res.Output = output
+		res.Encrypted = true
+	}
+
+	truncated, err := m.outputs.Truncate(ctx, req.SessionKey, res)
+	if err != nil {
+		// the service has already been executed, so the output is
+		// kept in full rather than failing the request
+		m.logger.Warn(ctx, "failed to store the output of the execution", log.MapFields{
+			"call_type": "StoreOutputFailure",
+			"id":        id,
+		}, err)
+		return res, nil
+	}
+
+	return truncated, nil
+}
+
+// GetServiceOutput retrieves the full output of an execution
+// whose output was truncated in its event
+func (m *RequestManager) GetServiceOutput(
+	ctx context.Context,
+	req GetServiceOutputRequest,
+) (GetServiceOutputResponse, errors.Err) {
+	return m.outputs.Get(ctx, req.SessionKey, req.ID)
+}
+
 // RequestManager starts a request and provides an identifier for the caller to
 // find the request later on. Deploys a new service
 func (m *RequestManager) DeployServiceAsync(ctx context.Context, req DeployServiceRequest) (uint64, errors.Err) {
-	id, err := m.mqueue.Next(ctx, mqueue.NextRequest{Key: req.SessionKey})
+	return m.startRequest(ctx, req.SessionKey, req.AAD, req.IdempotencyKey, func(id uint64) (Event, errors.Err) {
+		return m.deployService(ctx, id, req)
+	})
+}
+
+// deployService deploys the service and keeps track of the deployment
+// in the DeployRegistry. If deployments are deduplicated the deployment
+// is claimed in the registry first, so that concurrent identical
+// deployments are only executed once
+func (m *RequestManager) deployService(ctx context.Context, id uint64, req DeployServiceRequest) (Event, errors.Err) {
+	codeHash := CodeHash(req.Data)
+
+	var claim string
+	if m.deployDedup {
+		record, c, err := m.registry.Claim(ctx, req.AAD, codeHash)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(c) == 0 {
+			m.logger.Debug(ctx, "service already deployed", log.MapFields{
+				"call_type": "DeployServiceDeduplicated",
+				"id":        id,
+				"address":   record.Address,
+				"code_hash": codeHash,
+			})
+			return DeployServiceResponse{ID: id, Address: record.Address}, nil
+		}
+		claim = c
+	}
+
+	res, err := m.client.DeployService(ctx, id, req)
 	if err != nil {
-		return 0, errors.New(errors.ErrQueueNext, err)
+		if len(claim) > 0 {
+			if err := m.registry.Release(ctx, req.AAD, codeHash, claim); err != nil {
+				m.logger.Warn(ctx, "failed to release service deployment claim", log.MapFields{
+					"call_type": "DeployClaimReleaseFailure",
+					"id":        id,
+					"code_hash": codeHash,
+				}, err)
+			}
+		}
+		return nil, err
 	}
 
-	go m.doRequest(ctx, req.SessionKey, id, func() (Event, errors.Err) { return m.client.DeployService(ctx, id, req) })
+	m.recordSpend(ctx, req.SessionKey, req.AAD, res.GasUsed)
 
-	return id, nil
+	// failing to record the deployment should not fail the
+	// deployment itself
+	if err := m.registry.Record(ctx, DeployRecord{
+		AAD:      req.AAD,
+		CodeHash: codeHash,
+		Address:  res.Address,
+	}); err != nil {
+		m.logger.Warn(ctx, "failed to record service deployment", log.MapFields{
+			"call_type": "DeployRecordFailure",
+			"id":        id,
+			"address":   res.Address,
+		}, err)
+	}
+
+	return res, nil
+}
+
+// ListDeployments retrieves the services deployed through the gateway
+// by the AAD of the request
+func (m *RequestManager) ListDeployments(ctx context.Context, req ListDeploymentsRequest) (DeployRecords, errors.Err) {
+	if len(req.AAD) == 0 {
+		return DeployRecords{}, errors.New(errors.ErrInvalidKey, stderr.New("aad cannot be empty"))
+	}
+
+	return m.registry.List(ctx, req.AAD, req.CodeHash, req.Offset, req.Count)
+}
+
+// SetAlias creates or updates an alias to point to a service address
+func (m *RequestManager) SetAlias(ctx context.Context, req SetAliasRequest) errors.Err {
+	return m.aliases.Set(ctx, Alias{Name: req.Name, Address: req.Address})
+}
+
+// GetAlias retrieves the address an alias points to
+func (m *RequestManager) GetAlias(ctx context.Context, req GetAliasRequest) (Alias, errors.Err) {
+	return m.aliases.Get(ctx, req.Name)
+}
+
+// RemoveAlias removes an existing alias
+func (m *RequestManager) RemoveAlias(ctx context.Context, req RemoveAliasRequest) errors.Err {
+	return m.aliases.Remove(ctx, req.Name)
+}
+
+// ListAliases retrieves all the aliases managed by the gateway
+func (m *RequestManager) ListAliases(ctx context.Context) ([]Alias, errors.Err) {
+	return m.aliases.List(ctx)
+}
+
+// GetIdempotencyRecord retrieves the record of an idempotency key
+func (m *RequestManager) GetIdempotencyRecord(ctx context.Context, req GetIdempotencyRecordRequest) (IdempotencyRecord, errors.Err) {
+	return m.idempotency.Get(ctx, req.AAD, req.Key)
+}
+
+// ListIdempotencyRecords retrieves the records of all the
+// idempotency keys used by an AAD
+func (m *RequestManager) ListIdempotencyRecords(ctx context.Context, req ListIdempotencyRecordsRequest) ([]IdempotencyRecord, errors.Err) {
+	return m.idempotency.List(ctx, req.AAD)
+}
+
+// PurgeIdempotencyRecords removes the records of idempotency keys
+// and returns the number of records removed
+func (m *RequestManager) PurgeIdempotencyRecords(ctx context.Context, req PurgeIdempotencyRecordsRequest) (int, errors.Err) {
+	return m.idempotency.Purge(ctx, req.AAD, req.Keys)
+}
+
+// SetIdempotencyExpiry sets the time at which the record
+// of an idempotency key expires
+func (m *RequestManager) SetIdempotencyExpiry(ctx context.Context, req SetIdempotencyExpiryRequest) (IdempotencyRecord, errors.Err) {
+	return m.idempotency.SetExpiry(ctx, req.AAD, req.Key, req.ExpiresAt)
+}
+
+// SetSessionMetadata replaces the metadata attached to a session
+func (m *RequestManager) SetSessionMetadata(ctx context.Context, req SetSessionMetadataRequest) errors.Err {
+	return m.sessions.Set(ctx, req.SessionKey, req.Metadata)
+}
+
+// GetSessionMetadata retrieves the metadata attached to a session
+func (m *RequestManager) GetSessionMetadata(ctx context.Context, req GetSessionMetadataRequest) (SessionMetadata, errors.Err) {
+	return m.sessions.Get(ctx, req.SessionKey)
+}
+
+// LookupSessionMetadata returns the metadata attached to a session
+// if it is known to the gateway
+func (m *RequestManager) LookupSessionMetadata(session string) (SessionMetadata, bool) {
+	return m.sessions.Lookup(session)
+}
+
+// ListSessions retrieves the sessions known to the gateway
+// along with their metadata
+func (m *RequestManager) ListSessions(ctx context.Context) []Session {
+	return m.sessions.List()
+}
+
+// Heartbeat keeps a session from expiring without polling it. The
+// queues that keep the state of a session expire when the session is
+// inactive, so retrieving their elements refreshes their expiration.
+// It returns the number of events kept in the mailbox of the session
+func (m *RequestManager) Heartbeat(ctx context.Context, req HeartbeatRequest) (HeartbeatResponse, errors.Err) {
+	if len(req.SessionKey) == 0 {
+		return HeartbeatResponse{}, errors.New(errors.ErrInvalidKey, stderr.New("key cannot be empty"))
+	}
+
+	els, err := m.mqueue.Retrieve(ctx, mqueue.RetrieveRequest{
+		Key:    req.SessionKey,
+		Offset: 0,
+		Count:  maxHeartbeatScan,
+	})
+	if err != nil {
+		return HeartbeatResponse{}, queueError(errors.ErrQueueRetrieve, err)
+	}
+
+	// the queues of the subscriptions and the metadata of the session
+	// are only refreshed if they exist, so that they are not created
+	for _, key := range []string{SubinfoID(req.SessionKey), SessionMetadataID(req.SessionKey)} {
+		ok, err := m.mqueue.Exists(ctx, mqueue.ExistsRequest{Key: key})
+		if err != nil {
+			return HeartbeatResponse{}, queueError(errors.ErrQueueExists, err)
+		}
+		if !ok {
+			continue
+		}
+
+		if _, err := m.mqueue.Retrieve(ctx, mqueue.RetrieveRequest{Key: key, Offset: 0, Count: 0}); err != nil {
+			return HeartbeatResponse{}, queueError(errors.ErrQueueRetrieve, err)
+		}
+	}
+
+	return HeartbeatResponse{Offset: els.Offset, Pending: uint64(len(els.Elements))}, nil
 }
 
 // Unsubscribe from an existing subscription freeing all the associated
@@ -170,7 +698,7 @@ func (m *RequestManager) Unsubscribe(ctx context.Context, req UnsubscribeRequest
 		Key:          key,
 	})
 	if err != nil {
-		return errors.New(errors.ErrQueueDiscard, err)
+		return queueError(errors.ErrQueueDiscard, err)
 	}
 
 	subID := SubID(req.SessionKey, req.ID)
@@ -199,7 +727,7 @@ func (m *RequestManager) Subscribe(ctx context.Context, req SubscribeRequest) (u
 	key := SubinfoID(req.SessionKey)
 	id, err := m.mqueue.Next(ctx, mqueue.NextRequest{Key: key})
 	if err != nil {
-		return 0, errors.New(errors.ErrQueueNext, err)
+		return 0, queueError(errors.ErrQueueNext, err)
 	}
 
 	if err := m.subscribe(ctx, id, req); err != nil {
@@ -214,15 +742,16 @@ func (m *RequestManager) subscribe(ctx context.Context, id uint64, req Subscribe
 	// TODO(stan): a request manager should have a context from which the subscription contexts
 	// should derive
 	c := make(chan interface{}, 64)
-	if err := m.subman.Create(ctx, subID, c); err != nil {
+	if err := m.subman.Create(ctx, subID, req.AAD, c); err != nil {
 		return err
 	}
 
 	if err := m.client.SubscribeRequest(ctx, CreateSubscriptionRequest{
-		Event:   req.Event,
-		Address: req.Address,
-		SubID:   subID,
-		Topics:  req.Topics,
+		Event:     req.Event,
+		Address:   req.Address,
+		SubID:     subID,
+		Topics:    req.Topics,
+		FromBlock: req.FromBlock,
 	}, c); err != nil {
 		return err
 	}
@@ -230,25 +759,46 @@ func (m *RequestManager) subscribe(ctx context.Context, id uint64, req Subscribe
 	return nil
 }
 
-func (m *RequestManager) doRequest(ctx context.Context, key string, id uint64, fn func() (Event, errors.Err)) {
+// doRequest runs fn and publishes the generated event at offset in the
+// queue identified by key. The event ID may differ from the offset
+// if it has been derived from an idempotency key. Once the event is
+// published the request is no longer in flight
+func (m *RequestManager) doRequest(ctx context.Context, key, aad string, offset, id uint64, fn func() (Event, errors.Err)) {
+	defer m.inFlight.release(key)
+
 	// TODO(stan): we should handle the case in which the request takes too long
 	ev, err := fn()
-	if err != nil {
-		ev = ErrorEvent{
-			ID: id,
-			Cause: rpc.Error{
-				ErrorCode:   err.ErrorCode().Code(),
-				Description: err.ErrorCode().Desc(),
-			},
-		}
+	m.publish(ctx, key, aad, offset, id, ev, err)
+}
+
+// errorEvent returns the event of a request that failed
+func errorEvent(id uint64, err errors.Err) ErrorEvent {
+	return ErrorEvent{
+		ID: id,
+		Cause: rpc.Error{
+			ErrorCode:   err.ErrorCode().Code(),
+			Description: err.ErrorCode().Desc(),
+		},
 	}
+}
 
-	el, derr := makeElement(ev, id)
-	if derr != nil {
-		panic(fmt.Sprintf("failed to marshal event %s", derr.Error()))
+// publish publishes the event generated by a request at offset in the
+// queue identified by key, or an error event if the request failed
+func (m *RequestManager) publish(ctx context.Context, key, aad string, offset, id uint64, ev Event, err errors.Err) {
+	if err != nil {
+		ev = errorEvent(id, err)
 	}
 
-	if err := m.mqueue.Insert(ctx, mqueue.InsertRequest{Key: key, Element: el}); err != nil {
+	if err := m.bus.Publish(ctx, Publication{Key: key, Offset: offset, Event: ev, AAD: aad}); err != nil {
+		if err == mqueue.ErrUnavailable {
+			// the mailbox rejects events while it is degraded, so the
+			// event is lost but the gateway keeps serving
+			m.logger.Error(ctx, "failed to publish event while mailbox is unavailable", log.MapFields{
+				"call_type": "PublishEventFailure",
+				"id":        id,
+			})
+			return
+		}
 		panic(fmt.Sprintf("failed to insert event %s", err.Error()))
 	}
 }
@@ -256,7 +806,14 @@ func (m *RequestManager) doRequest(ctx context.Context, key string, id uint64, f
 // PollService retrieves the responses the RequestManager already got
 // from the asynchronous requests.
 func (m *RequestManager) PollService(ctx context.Context, req PollServiceRequest) (Events, errors.Err) {
-	events, err := m.poll(ctx, req.SessionKey, req.Offset, req.Count, req.DiscardPrevious)
+	events, err := m.poll(ctx, mqueue.RetrieveRequest{
+		Key:    req.SessionKey,
+		Offset: req.Offset,
+		Count:  req.Count,
+		Types:  eventTypeStrings(req.Types),
+		Since:  req.Since,
+		Until:  req.Until,
+	}, req.DiscardPrevious)
 	return events, err
 }
 
@@ -266,7 +823,14 @@ func (m *RequestManager) PollEvent(ctx context.Context, req PollEventRequest) (E
 	subID := SubID(req.SessionKey, req.ID)
 	subinfoID := SubinfoID(req.SessionKey)
 
-	evs, err := m.poll(ctx, subID, req.Offset, req.Count, req.DiscardPrevious)
+	evs, err := m.poll(ctx, mqueue.RetrieveRequest{
+		Key:    subID,
+		Offset: req.Offset,
+		Count:  req.Count,
+		Types:  eventTypeStrings(req.Types),
+		Since:  req.Since,
+		Until:  req.Until,
+	}, req.DiscardPrevious)
 	if err != nil {
 		return Events{}, err
 	}
@@ -278,7 +842,7 @@ func (m *RequestManager) PollEvent(ctx context.Context, req PollEventRequest) (E
 			Key: subID,
 		})
 		if err != nil {
-			return Events{}, errors.New(errors.ErrQueueExists, err)
+			return Events{}, queueError(errors.ErrQueueExists, err)
 		}
 
 		if !ok {
@@ -289,7 +853,7 @@ func (m *RequestManager) PollEvent(ctx context.Context, req PollEventRequest) (E
 				Key:          subinfoID,
 			})
 			if err != nil {
-				return Events{}, errors.New(errors.ErrQueueDiscard, err)
+				return Events{}, queueError(errors.ErrQueueDiscard, err)
 			}
 		}
 	}
@@ -297,15 +861,15 @@ func (m *RequestManager) PollEvent(ctx context.Context, req PollEventRequest) (E
 	return evs, nil
 }
 
-func (m *RequestManager) poll(ctx context.Context, key string, offset uint64, count uint, discardPrevious bool) (Events, errors.Err) {
-	els, err := m.mqueue.Retrieve(ctx, mqueue.RetrieveRequest{Key: key, Offset: offset, Count: count})
+func (m *RequestManager) poll(ctx context.Context, req mqueue.RetrieveRequest, discardPrevious bool) (Events, errors.Err) {
+	els, err := m.mqueue.Retrieve(ctx, req)
 	if err != nil {
-		return Events{}, errors.New(errors.ErrQueueRetrieve, err)
+		return Events{}, queueError(errors.ErrQueueRetrieve, err)
 	}
 
 	if discardPrevious {
-		if err := m.mqueue.Discard(ctx, mqueue.DiscardRequest{Key: key, Offset: offset}); err != nil {
-			return Events{}, errors.New(errors.ErrQueueDiscard, err)
+		if err := m.mqueue.Discard(ctx, mqueue.DiscardRequest{Key: req.Key, Offset: req.Offset}); err != nil {
+			return Events{}, queueError(errors.ErrQueueDiscard, err)
 		}
 	}
 
@@ -4,6 +4,7 @@ import (
 	"context"
 	"io/ioutil"
 	"testing"
+	"time"
 
 	ethereum "github.com/ethereum/go-ethereum/common"
 	"github.com/oasislabs/oasis-gateway/errors"
@@ -41,6 +42,15 @@ func (c *MockClient) Senders() []ethereum.Address {
 	}
 }
 
+func (c *MockClient) GetChainHead(ctx context.Context) (GetChainHeadResponse, errors.Err) {
+	args := c.Called(ctx)
+	if args.Get(1) != nil {
+		return GetChainHeadResponse{}, args.Get(1).(errors.Err)
+	}
+
+	return args.Get(0).(GetChainHeadResponse), nil
+}
+
 func (c *MockClient) GetCode(
 	ctx context.Context,
 	req GetCodeRequest,
@@ -103,6 +113,18 @@ func (c *MockClient) DeployService(
 	return args.Get(0).(DeployServiceResponse), nil
 }
 
+func (c *MockClient) QueryService(
+	ctx context.Context,
+	req QueryServiceRequest,
+) (QueryServiceResponse, errors.Err) {
+	args := c.Called(ctx, req)
+	if args.Get(1) != nil {
+		return QueryServiceResponse{}, args.Get(1).(errors.Err)
+	}
+
+	return args.Get(0).(QueryServiceResponse), nil
+}
+
 func (c *MockClient) SubscribeRequest(
 	ctx context.Context,
 	req CreateSubscriptionRequest,
@@ -131,6 +153,7 @@ func (c *MockClient) UnsubscribeRequest(
 func createRequestManager() *RequestManager {
 	return NewRequestManager(RequestManagerProperties{
 		MQueue: &mailboxtest.Mailbox{},
+		Store:  mqueue.NewMemStore(),
 		Client: &MockClient{},
 		Logger: Logger,
 	})
@@ -259,3 +282,125 @@ func TestPollEventOKDiscardSubinfo(t *testing.T) {
 			Key:          "session:subinfo",
 		})
 }
+
+func TestHeartbeatErrNoSessionKey(t *testing.T) {
+	manager := createRequestManager()
+
+	_, err := manager.Heartbeat(Context, HeartbeatRequest{})
+
+	assert.Equal(t, errors.ErrInvalidKey, err.ErrorCode())
+}
+
+func TestHeartbeatOK(t *testing.T) {
+	manager := createRequestManager()
+
+	manager.mqueue.(*mailboxtest.Mailbox).On("Retrieve",
+		mock.Anything, mqueue.RetrieveRequest{
+			Key:    "session",
+			Offset: 0,
+			Count:  maxHeartbeatScan,
+		}).Return(mqueue.Elements{
+		Offset: 2,
+		Elements: []core.Element{
+			{Offset: 2, Type: DataEventType.String()},
+			{Offset: 3, Type: DataEventType.String()},
+		},
+	}, nil)
+	manager.mqueue.(*mailboxtest.Mailbox).On("Exists",
+		mock.Anything, mqueue.ExistsRequest{Key: "session:subinfo"}).
+		Return(true, nil)
+	manager.mqueue.(*mailboxtest.Mailbox).On("Exists",
+		mock.Anything, mqueue.ExistsRequest{Key: "session:metadata"}).
+		Return(false, nil)
+	manager.mqueue.(*mailboxtest.Mailbox).On("Retrieve",
+		mock.Anything, mqueue.RetrieveRequest{
+			Key:    "session:subinfo",
+			Offset: 0,
+			Count:  0,
+		}).Return(mqueue.Elements{}, nil)
+
+	res, err := manager.Heartbeat(Context, HeartbeatRequest{SessionKey: "session"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, HeartbeatResponse{Offset: 2, Pending: 2}, res)
+	manager.mqueue.(*mailboxtest.Mailbox).AssertNotCalled(t, "Retrieve",
+		mock.Anything, mqueue.RetrieveRequest{
+			Key:    "session:metadata",
+			Offset: 0,
+			Count:  0,
+		})
+}
+
+func TestShutdownWaitsForRequests(t *testing.T) {
+	manager := createMemRequestManager(false)
+	address := "0x0a51514857B379A521C580a10822Fd8A7aC491A0"
+	release := make(chan time.Time)
+
+	manager.client.(*MockClient).On("ExecuteService", mock.Anything, mock.Anything, mock.Anything).
+		WaitUntil(release).Return(ExecuteServiceResponse{ID: 0, Address: address}, nil)
+
+	_, err := manager.ExecuteServiceAsync(Context, ExecuteServiceRequest{
+		AAD:        "aad",
+		Address:    address,
+		SessionKey: "session",
+	})
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithTimeout(Context, 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, manager.Shutdown(ctx))
+
+	close(release)
+	assert.Nil(t, manager.Shutdown(Context))
+
+	evs, err := manager.PollService(Context, PollServiceRequest{
+		SessionKey: "session",
+		Offset:     0,
+		Count:      1,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(evs.Events))
+}
+
+func TestShutdownRejectsRequests(t *testing.T) {
+	manager := createMemRequestManager(false)
+
+	assert.Nil(t, manager.Shutdown(Context))
+
+	_, err := manager.ExecuteServiceAsync(Context, ExecuteServiceRequest{
+		AAD:        "aad",
+		Address:    "0x0a51514857B379A521C580a10822Fd8A7aC491A0",
+		SessionKey: "session",
+	})
+	assert.Equal(t, errors.ErrShuttingDown, err.ErrorCode())
+}
+
+// unavailableStore is a store whose mailbox is unavailable
+type unavailableStore struct {
+	mqueue.Store
+}
+
+func (unavailableStore) GetField(context.Context, mqueue.FieldRequest) (string, bool, error) {
+	return "", false, mqueue.ErrUnavailable
+}
+
+func TestGetAliasStoreUnavailable(t *testing.T) {
+	registry := NewAliasRegistry(unavailableStore{})
+
+	_, err := registry.Get(Context, "name")
+	assert.Equal(t, errors.ErrMailboxUnavailable, err.ErrorCode())
+}
+
+func TestExecuteServiceAsyncMailboxUnavailable(t *testing.T) {
+	manager := createRequestManager()
+
+	manager.mqueue.(*mailboxtest.Mailbox).On("Next",
+		mock.Anything, mock.Anything).Return(uint64(0), mqueue.ErrUnavailable)
+
+	_, err := manager.ExecuteServiceAsync(Context, ExecuteServiceRequest{
+		AAD:        "aad",
+		Address:    "0x0a51514857B379A521C580a10822Fd8A7aC491A0",
+		SessionKey: "session",
+	})
+	assert.Equal(t, errors.ErrMailboxUnavailable, err.ErrorCode())
+}
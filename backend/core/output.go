@@ -0,0 +1,194 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/oasislabs/oasis-gateway/errors"
+	mqueue "github.com/oasislabs/oasis-gateway/mqueue/core"
+)
+
+const (
+	// outputType is the type set on the mqueue elements that
+	// hold the full output of an execution
+	outputType = "executeOutput"
+
+	// DefaultOutputPreviewSize is the size of the preview of a
+	// truncated output when OutputStoreProps does not set it
+	DefaultOutputPreviewSize = 256
+
+	// DefaultOutputTTL is the time the full output of an execution
+	// is kept when OutputStoreProps does not set it
+	DefaultOutputTTL = 10 * time.Minute
+)
+
+// OutputID generates the ID of the queue that holds the full
+// output of an execution of a session
+func OutputID(session string, id uint64) string {
+	return fmt.Sprintf("%s:output:%d", session, id)
+}
+
+// OutputStoreProps are the properties used to create an OutputStore
+type OutputStoreProps struct {
+	// MaxSize is the maximum size of the output included in an
+	// execution event. Larger outputs are truncated. If 0 outputs
+	// are never truncated
+	MaxSize int
+
+	// PreviewSize is the size of the output kept in the event
+	// when the output is truncated
+	PreviewSize int
+
+	// TTL is the time the full output is kept after the execution
+	TTL time.Duration
+}
+
+// storedOutput is the full output of an execution as
+// it is stored in the mqueue
+type storedOutput struct {
+	Output    string `json:"output"`
+	Encoding  string `json:"encoding,omitempty"`
+	ExpiresAt int64  `json:"expiresAt"`
+}
+
+// OutputStore keeps the full output of the executions whose output is
+// too large to be included in their events, so that the events polled
+// by the clients stay small. The outputs are stored in the mqueue under
+// the session of the execution, so they are as persistent as the
+// configured mailbox and can only be retrieved by the same session.
+//
+// A nil OutputStore is valid and never truncates outputs
+type OutputStore struct {
+	mqueue      mqueue.MQueue
+	maxSize     int
+	previewSize int
+	ttl         time.Duration
+}
+
+// NewOutputStore creates a new OutputStore. If MaxSize is not set
+// it returns nil
+func NewOutputStore(mqueue mqueue.MQueue, props OutputStoreProps) *OutputStore {
+	if props.MaxSize <= 0 {
+		return nil
+	}
+
+	previewSize := props.PreviewSize
+	if previewSize <= 0 {
+		previewSize = DefaultOutputPreviewSize
+	}
+	if previewSize > props.MaxSize {
+		previewSize = props.MaxSize
+	}
+
+	ttl := props.TTL
+	if ttl <= 0 {
+		ttl = DefaultOutputTTL
+	}
+
+	return &OutputStore{
+		mqueue:      mqueue,
+		maxSize:     props.MaxSize,
+		previewSize: previewSize,
+		ttl:         ttl,
+	}
+}
+
+// Truncate stores the full output of the response and replaces it
+// with a preview if the output exceeds the maximum size
+func (s *OutputStore) Truncate(
+	ctx context.Context,
+	session string,
+	res ExecuteServiceResponse,
+) (ExecuteServiceResponse, errors.Err) {
+	if s == nil || len(res.Output) <= s.maxSize {
+		return res, nil
+	}
+
+	p, err := json.Marshal(storedOutput{
+		Output:    res.Output,
+		Encoding:  res.Encoding,
+		ExpiresAt: time.Now().Add(s.ttl).Unix(),
+	})
+	if err != nil {
+		return res, queueError(errors.ErrQueueInsert, err)
+	}
+
+	key := OutputID(session, res.ID)
+	offset, err := s.mqueue.Next(ctx, mqueue.NextRequest{Key: key})
+	if err != nil {
+		return res, queueError(errors.ErrQueueNext, err)
+	}
+
+	if err := s.mqueue.Insert(ctx, mqueue.InsertRequest{
+		Key: key,
+		Element: mqueue.Element{
+			Offset: offset,
+			Type:   outputType,
+			Value:  string(p),
+		},
+	}); err != nil {
+		return res, queueError(errors.ErrQueueInsert, err)
+	}
+
+	res.OutputSize = len(res.Output)
+	res.Output = s.preview(res)
+	res.Truncated = true
+	return res, nil
+}
+
+// preview returns the beginning of the output. An encrypted output
+// cannot be opened partially, so it has no preview
+func (s *OutputStore) preview(res ExecuteServiceResponse) string {
+	if res.Encrypted {
+		return ""
+	}
+
+	preview := res.Output[:s.previewSize]
+	if strings.HasPrefix(preview, "0x") && len(preview)%2 != 0 {
+		// keep whole bytes of a hex output so that the
+		// preview can be encoded as the client requested
+		preview = preview[:len(preview)-1]
+	}
+
+	return preview
+}
+
+// Get returns the full output of an execution of the session
+// and the encoding requested for it
+func (s *OutputStore) Get(ctx context.Context, session string, id uint64) (GetServiceOutputResponse, errors.Err) {
+	if s == nil {
+		return GetServiceOutputResponse{}, errors.New(errors.ErrOutputNotFound, nil)
+	}
+
+	key := OutputID(session, id)
+	els, err := s.mqueue.Retrieve(ctx, mqueue.RetrieveRequest{
+		Key:    key,
+		Offset: 0,
+		Count:  1,
+	})
+	if err != nil {
+		return GetServiceOutputResponse{}, queueError(errors.ErrQueueRetrieve, err)
+	}
+
+	if len(els.Elements) == 0 {
+		return GetServiceOutputResponse{}, errors.New(errors.ErrOutputNotFound, nil)
+	}
+
+	var stored storedOutput
+	if err := json.Unmarshal([]byte(els.Elements[0].Value), &stored); err != nil {
+		return GetServiceOutputResponse{}, errors.New(errors.ErrDeserializeEvent, err)
+	}
+
+	if time.Now().Unix() >= stored.ExpiresAt {
+		if err := s.mqueue.Remove(ctx, mqueue.RemoveRequest{Key: key}); err != nil {
+			return GetServiceOutputResponse{}, queueError(errors.ErrQueueRemove, err)
+		}
+
+		return GetServiceOutputResponse{}, errors.New(errors.ErrOutputNotFound, nil)
+	}
+
+	return GetServiceOutputResponse{ID: id, Output: stored.Output, Encoding: stored.Encoding}, nil
+}
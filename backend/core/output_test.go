@@ -0,0 +1,108 @@
+package core
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/oasislabs/oasis-gateway/errors"
+	mqueue "github.com/oasislabs/oasis-gateway/mqueue/core"
+	"github.com/oasislabs/oasis-gateway/mqueue/mem"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutputStoreNil(t *testing.T) {
+	mqueue := mem.NewServer(Context, mem.Services{Logger: Logger})
+	store := NewOutputStore(mqueue, OutputStoreProps{})
+	assert.Nil(t, store)
+
+	res := ExecuteServiceResponse{ID: 1, Output: "0x010203"}
+	truncated, err := store.Truncate(Context, "session", res)
+	assert.Nil(t, err)
+	assert.Equal(t, res, truncated)
+
+	_, err = store.Get(Context, "session", 1)
+	assert.Equal(t, errors.ErrOutputNotFound, err.ErrorCode())
+}
+
+func TestOutputStoreSmallOutput(t *testing.T) {
+	mqueue := mem.NewServer(Context, mem.Services{Logger: Logger})
+	store := NewOutputStore(mqueue, OutputStoreProps{MaxSize: 8})
+
+	res := ExecuteServiceResponse{ID: 1, Output: "0x010203"}
+	truncated, err := store.Truncate(Context, "session", res)
+	assert.Nil(t, err)
+	assert.Equal(t, res, truncated)
+
+	_, err = store.Get(Context, "session", 1)
+	assert.Equal(t, errors.ErrOutputNotFound, err.ErrorCode())
+}
+
+func TestOutputStoreTruncateGet(t *testing.T) {
+	mqueue := mem.NewServer(Context, mem.Services{Logger: Logger})
+	store := NewOutputStore(mqueue, OutputStoreProps{MaxSize: 8, PreviewSize: 5})
+
+	truncated, err := store.Truncate(Context, "session", ExecuteServiceResponse{
+		ID:       1,
+		Address:  "0x00",
+		Output:   "0x0102030405",
+		Encoding: "base64",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, ExecuteServiceResponse{
+		ID:         1,
+		Address:    "0x00",
+		Output:     "0x01",
+		Encoding:   "base64",
+		Truncated:  true,
+		OutputSize: 12,
+	}, truncated)
+
+	res, err := store.Get(Context, "session", 1)
+	assert.Nil(t, err)
+	assert.Equal(t, GetServiceOutputResponse{
+		ID:       1,
+		Output:   "0x0102030405",
+		Encoding: "base64",
+	}, res)
+
+	_, err = store.Get(Context, "other", 1)
+	assert.Equal(t, errors.ErrOutputNotFound, err.ErrorCode())
+}
+
+func TestOutputStoreTruncateEncrypted(t *testing.T) {
+	mqueue := mem.NewServer(Context, mem.Services{Logger: Logger})
+	store := NewOutputStore(mqueue, OutputStoreProps{MaxSize: 8})
+
+	truncated, err := store.Truncate(Context, "session", ExecuteServiceResponse{
+		ID:        1,
+		Output:    "0x0102030405",
+		Encrypted: true,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "", truncated.Output)
+	assert.True(t, truncated.Truncated)
+}
+
+func TestOutputStoreGetExpired(t *testing.T) {
+	mq := mem.NewServer(Context, mem.Services{Logger: Logger})
+	store := NewOutputStore(mq, OutputStoreProps{MaxSize: 8})
+
+	p, _ := json.Marshal(storedOutput{
+		Output:    "0x0102030405",
+		ExpiresAt: time.Now().Add(-time.Second).Unix(),
+	})
+	offset, err := mq.Next(Context, mqueue.NextRequest{Key: OutputID("session", 1)})
+	assert.Nil(t, err)
+	assert.Nil(t, mq.Insert(Context, mqueue.InsertRequest{
+		Key: OutputID("session", 1),
+		Element: mqueue.Element{
+			Offset: offset,
+			Type:   outputType,
+			Value:  string(p),
+		},
+	}))
+
+	_, derr := store.Get(Context, "session", 1)
+	assert.Equal(t, errors.ErrOutputNotFound, derr.ErrorCode())
+}
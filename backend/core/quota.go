@@ -0,0 +1,343 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/oasislabs/oasis-gateway/errors"
+	"github.com/oasislabs/oasis-gateway/log"
+	mqueue "github.com/oasislabs/oasis-gateway/mqueue/core"
+)
+
+const (
+	// QueueQuota is the name of the quota on the number of events
+	// kept in the queue of a session
+	QueueQuota = "queue"
+
+	// SpendQuota is the name of the quota on the gas spent by
+	// the transactions of an issuer
+	SpendQuota = "spend"
+)
+
+// spendField is the field of the spend hash of an issuer
+// that keeps the gas spent by its transactions
+const spendField = "gas"
+
+// SpendQuotaID returns the key of the hash in which the gas
+// spent by the transactions of the aad is kept
+func SpendQuotaID(aad string) string {
+	return fmt.Sprintf("%s:spend", aad)
+}
+
+// QuotaProps defines the quotas of a session and when the
+// session is warned that it approaches them
+type QuotaProps struct {
+	// QueueSize is the number of events that can be kept in the
+	// queue of a session before its requests are rejected. If the
+	// mailbox keeps fewer elements per queue its limit is used
+	// instead. If 0 only the limit of the mailbox applies
+	QueueSize uint64
+
+	// SpendLimit is the amount of gas the transactions of an issuer
+	// may spend before its requests are rejected. If 0 the gas
+	// spent is not limited
+	SpendLimit uint64
+
+	// WarnPercent is the percentage of a quota at which a session
+	// is warned. If 0 sessions are not warned
+	WarnPercent uint64
+
+	// MaxInFlight is the maximum number of execute and deploy requests
+	// of a session that may be in flight at the same time. If 0 the
+	// number of requests in flight is not limited
+	MaxInFlight uint64
+}
+
+// withQueueLimit returns the props with the QueueSize bounded by
+// the number of elements the mailbox keeps per queue, if the
+// mailbox limits it
+func (p QuotaProps) withQueueLimit(m mqueue.MQueue) QuotaProps {
+	if limit, ok := mqueue.LimitOf(m); ok && (p.QueueSize == 0 || limit < p.QueueSize) {
+		p.QueueSize = limit
+	}
+
+	return p
+}
+
+// threshold returns the amount of a quota with the given limit at
+// which a session is warned, or 0 if sessions should not be warned
+func (p QuotaProps) threshold(limit uint64) uint64 {
+	if p.WarnPercent == 0 || limit == 0 {
+		return 0
+	}
+
+	threshold := limit * p.WarnPercent / 100
+	if threshold == 0 {
+		threshold = 1
+	}
+
+	return threshold
+}
+
+// queueThreshold returns the number of events in the queue of
+// a session at which the session is warned, or 0 if sessions
+// should not be warned
+func (p QuotaProps) queueThreshold() uint64 {
+	return p.threshold(p.QueueSize)
+}
+
+// spendThreshold returns the gas spent by an issuer at which
+// its sessions are warned, or 0 if they should not be warned
+func (p QuotaProps) spendThreshold() uint64 {
+	return p.threshold(p.SpendLimit)
+}
+
+// checkQueueQuota verifies that the offset reserved for a request
+// does not exceed the queue quota of the session. If it does the
+// offset is discarded and the request is rejected. Otherwise, when
+// the number of events kept for the session reaches the warning
+// threshold, a WarningEvent is inserted in the queue of the session.
+// The warning is only inserted when the threshold is crossed, so that
+// a session is not warned on every request. A failure to warn does
+// not fail the request
+func (m *RequestManager) checkQueueQuota(ctx context.Context, key, aad string, offset uint64) errors.Err {
+	if m.quota.QueueSize == 0 {
+		return nil
+	}
+
+	// retrieving no elements from the beginning of the queue
+	// returns the offset of the first element kept
+	els, err := m.mqueue.Retrieve(ctx, mqueue.RetrieveRequest{Key: key, Offset: 0, Count: 0})
+	if err != nil {
+		m.discardOffset(ctx, key, offset)
+		return queueError(errors.ErrQueueRetrieve, err)
+	}
+
+	if offset < els.Offset {
+		return nil
+	}
+
+	used := offset - els.Offset + 1
+	if used > m.quota.QueueSize {
+		m.discardOffset(ctx, key, offset)
+		return errors.New(errors.ErrQueueLimitReached,
+			fmt.Errorf("session has %d events in its queue", used-1))
+	}
+
+	if threshold := m.quota.queueThreshold(); threshold > 0 && used == threshold {
+		m.warnQuota(ctx, key, aad, WarningEvent{
+			Quota: QueueQuota,
+			Used:  threshold,
+			Limit: m.quota.QueueSize,
+		})
+	}
+
+	return nil
+}
+
+// checkSpendQuota verifies that the issuer of a request has
+// not spent all the gas its quota allows
+func (m *RequestManager) checkSpendQuota(ctx context.Context, aad string) errors.Err {
+	if m.quota.SpendLimit == 0 {
+		return nil
+	}
+
+	spent, err := m.spent(ctx, aad)
+	if err != nil {
+		return err
+	}
+
+	if spent >= m.quota.SpendLimit {
+		return errors.New(errors.ErrSpendLimitReached,
+			fmt.Errorf("issuer has spent %d gas", spent))
+	}
+
+	return nil
+}
+
+// spent returns the gas spent by the transactions of the issuer
+func (m *RequestManager) spent(ctx context.Context, aad string) (uint64, errors.Err) {
+	value, ok, err := m.store.GetField(ctx, mqueue.FieldRequest{Key: SpendQuotaID(aad), Field: spendField})
+	if err != nil {
+		return 0, queueError(errors.ErrStore, err)
+	}
+	if !ok {
+		return 0, nil
+	}
+
+	spent, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, queueError(errors.ErrStore, err)
+	}
+
+	return spent, nil
+}
+
+// GetQuotaState returns how much of the quotas that limit the
+// requests of a session it has used. Only the quotas that are
+// enabled are retrieved
+func (m *RequestManager) GetQuotaState(ctx context.Context, req GetQuotaStateRequest) (QuotaState, errors.Err) {
+	state := QuotaState{
+		MaxInFlight: m.quota.MaxInFlight,
+		SpendLimit:  m.quota.SpendLimit,
+	}
+
+	if state.MaxInFlight > 0 {
+		state.InFlight = m.inFlight.inFlight(req.SessionKey)
+	}
+
+	if state.SpendLimit > 0 {
+		spent, err := m.spent(ctx, req.AAD)
+		if err != nil {
+			return QuotaState{}, err
+		}
+		state.Spent = spent
+	}
+
+	return state, nil
+}
+
+// recordSpend accounts the gas used by a transaction of the issuer
+// for its spend quota. When the gas spent crosses the warning
+// threshold a WarningEvent is inserted in the queue of the session
+// that issued the transaction. The transaction has already been
+// executed, so a failure to record the gas does not fail the request
+func (m *RequestManager) recordSpend(ctx context.Context, key, aad string, gas uint64) {
+	if m.quota.SpendLimit == 0 || gas == 0 {
+		return
+	}
+
+	spent, err := m.store.IncrField(ctx, mqueue.IncrFieldRequest{
+		Key:    SpendQuotaID(aad),
+		Field:  spendField,
+		Amount: gas,
+	})
+	if err != nil {
+		m.logger.Warn(ctx, "failed to record gas spent", log.MapFields{
+			"call_type": "SpendQuotaRecordFailure",
+			"err":       err.Error(),
+		})
+		return
+	}
+
+	threshold := m.quota.spendThreshold()
+	if threshold == 0 || spent < threshold || spent-gas >= threshold {
+		return
+	}
+
+	m.warnQuota(ctx, key, aad, WarningEvent{
+		Quota: SpendQuota,
+		Used:  spent,
+		Limit: m.quota.SpendLimit,
+	})
+}
+
+// warnQuota inserts the warning in the queue of the session at
+// the next offset available
+func (m *RequestManager) warnQuota(ctx context.Context, key, aad string, ev WarningEvent) {
+	next, err := m.mqueue.Next(ctx, mqueue.NextRequest{Key: key})
+	if err != nil {
+		m.logger.Warn(ctx, "failed to reserve offset for warning", log.MapFields{
+			"call_type": "QuotaWarningFailure",
+			"quota":     ev.Quota,
+			"err":       err.Error(),
+		})
+		return
+	}
+
+	ev.ID = next
+	err = m.bus.Publish(ctx, Publication{
+		Key:    key,
+		Offset: next,
+		AAD:    aad,
+		Event:  ev,
+	})
+	if err != nil {
+		m.logger.Warn(ctx, "failed to insert warning event", log.MapFields{
+			"call_type": "QuotaWarningFailure",
+			"quota":     ev.Quota,
+			"err":       err.Error(),
+		})
+
+		m.discardOffset(ctx, key, next)
+	}
+}
+
+// discardOffset discards an offset reserved in the queue of the
+// session that will not be used, so that it does not prevent the
+// queue from discarding the events before it
+func (m *RequestManager) discardOffset(ctx context.Context, key string, offset uint64) {
+	if err := m.mqueue.Discard(ctx, mqueue.DiscardRequest{
+		Key:          key,
+		Offset:       offset,
+		Count:        1,
+		KeepPrevious: true,
+	}); err != nil {
+		m.logger.Warn(ctx, "failed to discard reserved offset", log.MapFields{
+			"call_type": "DiscardOffsetFailure",
+			"offset":    offset,
+			"err":       err.Error(),
+		})
+	}
+}
+
+// inFlightTracker keeps the number of requests of each session that
+// are in flight. A request is in flight from the moment an offset is
+// reserved for it in the queue of the session until its event is
+// published at that offset
+type inFlightTracker struct {
+	lock    sync.Mutex
+	limit   uint64
+	pending map[string]uint64
+}
+
+func newInFlightTracker(limit uint64) *inFlightTracker {
+	return &inFlightTracker{limit: limit, pending: make(map[string]uint64)}
+}
+
+// acquire accounts for a new request of the session. It fails if
+// the session already has as many requests in flight as allowed
+func (t *inFlightTracker) acquire(key string) errors.Err {
+	if t.limit == 0 {
+		return nil
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.pending[key] >= t.limit {
+		return errors.New(errors.ErrInFlightLimitReached,
+			fmt.Errorf("session has %d requests in flight", t.pending[key]))
+	}
+
+	t.pending[key]++
+	return nil
+}
+
+// release accounts for a request of the session that is
+// no longer in flight
+func (t *inFlightTracker) release(key string) {
+	if t.limit == 0 {
+		return
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.pending[key] <= 1 {
+		delete(t.pending, key)
+		return
+	}
+
+	t.pending[key]--
+}
+
+// inFlight returns the number of requests of the session in flight
+func (t *inFlightTracker) inFlight(key string) uint64 {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	return t.pending[key]
+}
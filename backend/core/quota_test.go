@@ -0,0 +1,246 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/oasislabs/oasis-gateway/errors"
+	"github.com/oasislabs/oasis-gateway/mqueue/core"
+	"github.com/oasislabs/oasis-gateway/mqueue/mem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func createQuotaRequestManager(quota QuotaProps) *RequestManager {
+	return NewRequestManager(RequestManagerProperties{
+		MQueue: mem.NewServer(Context, mem.Services{Logger: Logger}),
+		Client: &MockClient{},
+		Logger: Logger,
+		Quota:  quota,
+	})
+}
+
+func TestQuotaPropsQueueThreshold(t *testing.T) {
+	assert.Equal(t, uint64(0), QuotaProps{QueueSize: 10}.queueThreshold())
+	assert.Equal(t, uint64(0), QuotaProps{WarnPercent: 10}.queueThreshold())
+	assert.Equal(t, uint64(8), QuotaProps{QueueSize: 10, WarnPercent: 80}.queueThreshold())
+	assert.Equal(t, uint64(1), QuotaProps{QueueSize: 1, WarnPercent: 10}.queueThreshold())
+}
+
+func TestQuotaPropsSpendThreshold(t *testing.T) {
+	assert.Equal(t, uint64(0), QuotaProps{SpendLimit: 100}.spendThreshold())
+	assert.Equal(t, uint64(75), QuotaProps{SpendLimit: 100, WarnPercent: 75}.spendThreshold())
+}
+
+func TestQuotaPropsWithQueueLimit(t *testing.T) {
+	m := mem.NewServer(Context, mem.Services{Logger: Logger})
+	limit, ok := core.LimitOf(m)
+	assert.True(t, ok)
+
+	assert.Equal(t, limit, QuotaProps{}.withQueueLimit(m).QueueSize)
+	assert.Equal(t, uint64(10), QuotaProps{QueueSize: 10}.withQueueLimit(m).QueueSize)
+	assert.Equal(t, limit, QuotaProps{QueueSize: limit + 1}.withQueueLimit(m).QueueSize)
+}
+
+func TestWarnQueueQuota(t *testing.T) {
+	manager := createQuotaRequestManager(QuotaProps{QueueSize: 4, WarnPercent: 50})
+
+	for i := 0; i < 3; i++ {
+		offset, err := manager.mqueue.Next(Context, core.NextRequest{Key: "key"})
+		assert.Nil(t, err)
+		assert.Nil(t, manager.checkQueueQuota(Context, "key", "aad", offset))
+	}
+
+	// the warning is inserted only once, right after the
+	// request that reached the threshold
+	evs, err := manager.PollService(Context, PollServiceRequest{
+		SessionKey: "key",
+		Offset:     0,
+		Count:      10,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, Events{
+		Offset: 0,
+		Events: []Event{WarningEvent{
+			ID:    2,
+			Quota: QueueQuota,
+			Used:  2,
+			Limit: 4,
+		}},
+	}, evs)
+}
+
+func TestWarnQueueQuotaDisabled(t *testing.T) {
+	manager := createQuotaRequestManager(QuotaProps{QueueSize: 1})
+
+	offset, err := manager.mqueue.Next(Context, core.NextRequest{Key: "key"})
+	assert.Nil(t, err)
+	assert.Nil(t, manager.checkQueueQuota(Context, "key", "aad", offset))
+
+	next, err := manager.mqueue.Next(Context, core.NextRequest{Key: "key"})
+	assert.Nil(t, err)
+	assert.Equal(t, offset+1, next)
+}
+
+func TestCheckQueueQuotaLimitReached(t *testing.T) {
+	manager := createQuotaRequestManager(QuotaProps{QueueSize: 2})
+
+	for i := 0; i < 2; i++ {
+		offset, err := manager.mqueue.Next(Context, core.NextRequest{Key: "key"})
+		assert.Nil(t, err)
+		assert.Nil(t, manager.checkQueueQuota(Context, "key", "aad", offset))
+	}
+
+	offset, err := manager.mqueue.Next(Context, core.NextRequest{Key: "key"})
+	assert.Nil(t, err)
+	assert.Equal(t, errors.ErrQueueLimitReached, manager.checkQueueQuota(Context, "key", "aad", offset).ErrorCode())
+}
+
+func TestSpendQuota(t *testing.T) {
+	manager := createQuotaRequestManager(QuotaProps{SpendLimit: 100, WarnPercent: 50})
+
+	assert.Nil(t, manager.checkSpendQuota(Context, "aad"))
+
+	manager.recordSpend(Context, "key", "aad", 40)
+	assert.Nil(t, manager.checkSpendQuota(Context, "aad"))
+
+	// the warning is inserted only once, when the
+	// gas spent crosses the threshold
+	manager.recordSpend(Context, "key", "aad", 20)
+	manager.recordSpend(Context, "key", "aad", 20)
+	assert.Nil(t, manager.checkSpendQuota(Context, "aad"))
+
+	manager.recordSpend(Context, "key", "aad", 20)
+	assert.Equal(t, errors.ErrSpendLimitReached, manager.checkSpendQuota(Context, "aad").ErrorCode())
+
+	// the quota applies to each issuer on its own
+	assert.Nil(t, manager.checkSpendQuota(Context, "other"))
+
+	evs, err := manager.PollService(Context, PollServiceRequest{
+		SessionKey: "key",
+		Offset:     0,
+		Count:      10,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, Events{
+		Offset: 0,
+		Events: []Event{WarningEvent{
+			ID:    0,
+			Quota: SpendQuota,
+			Used:  60,
+			Limit: 100,
+		}},
+	}, evs)
+}
+
+func TestExecuteServiceAsyncSpendLimit(t *testing.T) {
+	address := "0x0a51514857B379A521C580a10822Fd8A7aC491A0"
+	manager := createQuotaRequestManager(QuotaProps{SpendLimit: 100})
+	manager.client.(*MockClient).On("ExecuteService", mock.Anything, mock.Anything, mock.Anything).
+		Return(ExecuteServiceResponse{Address: address, GasUsed: 100}, nil)
+
+	_, err := manager.ExecuteServiceAsync(Context, ExecuteServiceRequest{
+		Address:    address,
+		SessionKey: "key",
+		AAD:        "aad",
+	})
+	assert.Nil(t, err)
+
+	assert.Eventually(t, func() bool {
+		return manager.checkSpendQuota(Context, "aad") != nil
+	}, time.Second, time.Millisecond)
+
+	_, err = manager.ExecuteServiceAsync(Context, ExecuteServiceRequest{
+		Address:    address,
+		SessionKey: "key",
+		AAD:        "aad",
+	})
+	assert.Equal(t, errors.ErrSpendLimitReached, err.ErrorCode())
+}
+
+func TestInFlightTracker(t *testing.T) {
+	tracker := newInFlightTracker(2)
+
+	assert.Nil(t, tracker.acquire("key"))
+	assert.Nil(t, tracker.acquire("key"))
+	assert.Equal(t, errors.ErrInFlightLimitReached, tracker.acquire("key").ErrorCode())
+
+	// the limit applies to each session on its own
+	assert.Nil(t, tracker.acquire("other"))
+
+	tracker.release("key")
+	assert.Equal(t, uint64(1), tracker.inFlight("key"))
+	assert.Nil(t, tracker.acquire("key"))
+}
+
+func TestInFlightTrackerUnlimited(t *testing.T) {
+	tracker := newInFlightTracker(0)
+
+	for i := 0; i < 10; i++ {
+		assert.Nil(t, tracker.acquire("key"))
+	}
+}
+
+func TestExecuteServiceAsyncInFlightLimit(t *testing.T) {
+	address := "0x0a51514857B379A521C580a10822Fd8A7aC491A0"
+	manager := createQuotaRequestManager(QuotaProps{MaxInFlight: 1})
+
+	release := make(chan struct{})
+	manager.client.(*MockClient).On("ExecuteService", mock.Anything, mock.Anything, mock.Anything).
+		Run(func(mock.Arguments) { <-release }).
+		Return(ExecuteServiceResponse{Address: address}, nil)
+
+	_, err := manager.ExecuteServiceAsync(Context, ExecuteServiceRequest{
+		Address:    address,
+		SessionKey: "key",
+	})
+	assert.Nil(t, err)
+
+	_, err = manager.ExecuteServiceAsync(Context, ExecuteServiceRequest{
+		Address:    address,
+		SessionKey: "key",
+	})
+	assert.Equal(t, errors.ErrInFlightLimitReached, err.ErrorCode())
+
+	_, err = manager.DeployServiceAsync(Context, DeployServiceRequest{
+		Data:       "0x0000",
+		SessionKey: "key",
+	})
+	assert.Equal(t, errors.ErrInFlightLimitReached, err.ErrorCode())
+
+	// once the event of the request is published the
+	// session can issue requests again
+	close(release)
+	for manager.inFlight.inFlight("key") > 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	_, err = manager.ExecuteServiceAsync(Context, ExecuteServiceRequest{
+		Address:    address,
+		SessionKey: "key",
+	})
+	assert.Nil(t, err)
+}
+
+func TestGetQuotaState(t *testing.T) {
+	manager := createQuotaRequestManager(QuotaProps{SpendLimit: 100, MaxInFlight: 2})
+
+	manager.recordSpend(Context, "key", "aad", 40)
+	assert.Nil(t, manager.inFlight.acquire("key"))
+
+	state, err := manager.GetQuotaState(Context, GetQuotaStateRequest{SessionKey: "key", AAD: "aad"})
+	assert.Nil(t, err)
+	assert.Equal(t, QuotaState{MaxInFlight: 2, InFlight: 1, SpendLimit: 100, Spent: 40}, state)
+
+	state, err = manager.GetQuotaState(Context, GetQuotaStateRequest{SessionKey: "other", AAD: "other"})
+	assert.Nil(t, err)
+	assert.Equal(t, QuotaState{MaxInFlight: 2, SpendLimit: 100}, state)
+}
+
+func TestGetQuotaStateDisabled(t *testing.T) {
+	manager := createQuotaRequestManager(QuotaProps{})
+
+	state, err := manager.GetQuotaState(Context, GetQuotaStateRequest{SessionKey: "key", AAD: "aad"})
+	assert.Nil(t, err)
+	assert.Equal(t, QuotaState{}, state)
+}
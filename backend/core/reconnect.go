@@ -0,0 +1,63 @@
+package core
+
+import (
+	"context"
+	"time"
+
+	"github.com/oasislabs/oasis-gateway/log"
+)
+
+// Reconnect describes a connection of a client to its node that was
+// recreated after it failed. The events that happened on the node
+// between DisconnectedAt and ReconnectedAt may have been missed
+type Reconnect struct {
+	// DisconnectedAt is the time at which the connection failed
+	DisconnectedAt time.Time
+
+	// ReconnectedAt is the time at which the connection was recreated
+	ReconnectedAt time.Time
+}
+
+// ReconnectNotifier is implemented by the clients that can notify
+// when their connection to the node is recreated after it failed
+type ReconnectNotifier interface {
+	// NotifyReconnect registers fn to be called every time the
+	// connection of the client to the node is recreated
+	NotifyReconnect(fn func(context.Context, Reconnect))
+}
+
+// Reconnected announces to all the sessions that the connection to
+// the node was recreated, so that their clients know that events
+// may have been missed in between and reconcile their state
+func (m *RequestManager) Reconnected(ctx context.Context, r Reconnect) {
+	announced, err := m.Announce(ctx, func(id uint64) Event {
+		return NodeReconnectedEvent{
+			ID:             id,
+			DisconnectedAt: unixMillis(r.DisconnectedAt),
+			ReconnectedAt:  unixMillis(r.ReconnectedAt),
+		}
+	})
+	if err != nil {
+		m.logger.Warn(ctx, "failed to announce reconnection to the node", log.MapFields{
+			"call_type": "AnnounceReconnectFailure",
+		}, err)
+		return
+	}
+
+	m.logger.Info(ctx, "announced reconnection to the node", log.MapFields{
+		"call_type":       "AnnounceReconnectSuccess",
+		"announced":       announced,
+		"disconnected_at": r.DisconnectedAt.String(),
+		"reconnected_at":  r.ReconnectedAt.String(),
+	})
+}
+
+// unixMillis returns the time in milliseconds since the epoch, or 0
+// if the time is not set
+func unixMillis(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+
+	return t.UnixNano() / int64(time.Millisecond)
+}
@@ -0,0 +1,56 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/oasislabs/oasis-gateway/mqueue/core"
+	"github.com/oasislabs/oasis-gateway/mqueue/mem"
+	"github.com/stretchr/testify/assert"
+)
+
+type reconnectClient struct {
+	MockClient
+	fn func(context.Context, Reconnect)
+}
+
+func (c *reconnectClient) NotifyReconnect(fn func(context.Context, Reconnect)) {
+	c.fn = fn
+}
+
+func TestReconnected(t *testing.T) {
+	client := &reconnectClient{}
+	manager := NewRequestManager(RequestManagerProperties{
+		MQueue: mem.NewServer(Context, mem.Services{Logger: Logger}),
+		Client: client,
+		Logger: Logger,
+	})
+
+	offset, err := manager.mqueue.Next(Context, core.NextRequest{Key: "aad:session"})
+	assert.Nil(t, err)
+	assert.Nil(t, manager.bus.Publish(Context, Publication{
+		Key:    "aad:session",
+		Offset: offset,
+		Event:  DataEvent{ID: offset},
+	}))
+
+	// the manager registers itself with the client so that the
+	// reconnections are announced to the sessions
+	assert.NotNil(t, client.fn)
+	client.fn(Context, Reconnect{
+		DisconnectedAt: time.Unix(1, 0),
+		ReconnectedAt:  time.Unix(5, 0),
+	})
+
+	evs, err := manager.PollService(Context, PollServiceRequest{
+		SessionKey: "aad:session",
+		Offset:     1,
+		Count:      10,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, Events{
+		Offset: 0,
+		Events: []Event{NodeReconnectedEvent{ID: 1, DisconnectedAt: 1000, ReconnectedAt: 5000}},
+	}, evs)
+}
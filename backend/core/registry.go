@@ -0,0 +1,282 @@
+package core
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/google/uuid"
+	"github.com/oasislabs/oasis-gateway/errors"
+	mqueue "github.com/oasislabs/oasis-gateway/mqueue/core"
+)
+
+const (
+	// deployRecordPrefix prefixes the fields of the store
+	// that hold a DeployRecord
+	deployRecordPrefix = "record:"
+
+	// deployCodePrefix prefixes the fields of the store that
+	// hold the address of the latest deployment of a code hash,
+	// or a claim on the deployment while it is in progress
+	deployCodePrefix = "code:"
+
+	// deployClaimPrefix prefixes the value of a claim
+	deployClaimPrefix = "claim:"
+
+	// deployNextField is the field of the store that holds the
+	// number of records of the AAD
+	deployNextField = "next"
+
+	// deployClaimTimeout is the time after which a claim on a
+	// deployment is considered abandoned, for instance because
+	// the gateway that made it stopped, and it can be taken over
+	deployClaimTimeout = 10 * time.Minute
+)
+
+// DeployRecord describes a service deployed through the gateway
+type DeployRecord struct {
+	// ID is the sequence number of the record within the
+	// records of the AAD
+	ID uint64
+
+	// AAD is the identifier of the issuer of the deployment
+	AAD string
+
+	// CodeHash is the keccak256 hash of the deployed bytecode
+	CodeHash string
+
+	// Address at which the service was deployed
+	Address string
+
+	// Timestamp is the unix time in seconds at which the
+	// deployment completed
+	Timestamp uint64
+}
+
+// DeployRecords is an ordered set of deploy records
+type DeployRecords struct {
+	// Offset is the base offset from which the records are taken
+	Offset uint64
+
+	// Records starting from Offset
+	Records []DeployRecord
+}
+
+// DeployRegistryID generates the key of the store that holds
+// the deploy records of an AAD
+func DeployRegistryID(aad string) string {
+	return fmt.Sprintf("%s:deploys", aad)
+}
+
+// CodeHash computes the hash used to identify the bytecode of a
+// deployment. The data is expected to be hex encoded, but if it is
+// not the raw data is used instead
+func CodeHash(data string) string {
+	p, err := hex.DecodeString(strings.TrimPrefix(data, "0x"))
+	if err != nil {
+		p = []byte(data)
+	}
+
+	return crypto.Keccak256Hash(p).Hex()
+}
+
+// DeployRegistry keeps track of the services deployed through the
+// gateway. The records are kept in the mailbox store, where they do not
+// expire and are not bounded in number, and are shared by the gateways
+// that share the mailbox
+type DeployRegistry struct {
+	store mqueue.Store
+}
+
+// NewDeployRegistry creates a new registry backed by the provided store
+func NewDeployRegistry(store mqueue.Store) *DeployRegistry {
+	return &DeployRegistry{store: store}
+}
+
+// Record adds a new record to the registry of the record's AAD, and
+// makes it the latest deployment of its code hash
+func (r *DeployRegistry) Record(ctx context.Context, record DeployRecord) errors.Err {
+	key := DeployRegistryID(record.AAD)
+	next, err := r.store.IncrField(ctx, mqueue.IncrFieldRequest{Key: key, Field: deployNextField, Amount: 1})
+	if err != nil {
+		return queueError(errors.ErrStore, err)
+	}
+
+	record.ID = next - 1
+	if record.Timestamp == 0 {
+		record.Timestamp = uint64(time.Now().Unix())
+	}
+
+	p, err := json.Marshal(record)
+	if err != nil {
+		return queueError(errors.ErrStore, err)
+	}
+
+	if _, _, err := r.store.SetField(ctx, mqueue.SetFieldRequest{
+		Key:   key,
+		Field: deployRecordPrefix + strconv.FormatUint(record.ID, 10),
+		Value: string(p),
+	}); err != nil {
+		return queueError(errors.ErrStore, err)
+	}
+
+	if _, _, err := r.store.SetField(ctx, mqueue.SetFieldRequest{
+		Key:   key,
+		Field: deployCodePrefix + record.CodeHash,
+		Value: record.Address,
+	}); err != nil {
+		return queueError(errors.ErrStore, err)
+	}
+
+	return nil
+}
+
+// List retrieves at most count records of the AAD with an ID of at
+// least offset. If codeHash is set only the records of the deployments
+// of that code hash are considered
+func (r *DeployRegistry) List(
+	ctx context.Context,
+	aad string,
+	codeHash string,
+	offset uint64,
+	count uint,
+) (DeployRecords, errors.Err) {
+	fields, err := r.store.Fields(ctx, mqueue.FieldsRequest{Key: DeployRegistryID(aad)})
+	if err != nil {
+		return DeployRecords{}, queueError(errors.ErrStore, err)
+	}
+
+	records := make([]DeployRecord, 0, len(fields))
+	for field, value := range fields {
+		if !strings.HasPrefix(field, deployRecordPrefix) {
+			continue
+		}
+
+		var record DeployRecord
+		if err := json.Unmarshal([]byte(value), &record); err != nil {
+			return DeployRecords{}, errors.New(errors.ErrDeserializeEvent, err)
+		}
+
+		// records are filtered before they are paginated, so that
+		// pages are only short when there are no more records
+		if record.ID < offset {
+			continue
+		}
+		if len(codeHash) > 0 && !strings.EqualFold(record.CodeHash, codeHash) {
+			continue
+		}
+
+		records = append(records, record)
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].ID < records[j].ID
+	})
+
+	if uint(len(records)) > count {
+		records = records[:count]
+	}
+
+	return DeployRecords{Offset: offset, Records: records}, nil
+}
+
+// Find looks for the most recent record of the AAD for the provided
+// code hash. It returns false if no such record exists
+func (r *DeployRegistry) Find(ctx context.Context, aad string, codeHash string) (DeployRecord, bool, errors.Err) {
+	value, ok, err := r.store.GetField(ctx, mqueue.FieldRequest{
+		Key:   DeployRegistryID(aad),
+		Field: deployCodePrefix + codeHash,
+	})
+	if err != nil {
+		return DeployRecord{}, false, queueError(errors.ErrStore, err)
+	}
+
+	if !ok || strings.HasPrefix(value, deployClaimPrefix) {
+		return DeployRecord{}, false, nil
+	}
+
+	return DeployRecord{AAD: aad, CodeHash: codeHash, Address: value}, true, nil
+}
+
+// Claim atomically reserves the deployment of the code hash for the
+// AAD, so that concurrent identical deployments are only executed
+// once. If the code hash has already been deployed it returns the
+// record of the deployment. Otherwise it returns the claim, which
+// has to be released if the deployment fails. If another deployment
+// of the code hash is in progress it fails with ErrDeployInProgress
+func (r *DeployRegistry) Claim(ctx context.Context, aad string, codeHash string) (DeployRecord, string, errors.Err) {
+	key := DeployRegistryID(aad)
+	field := deployCodePrefix + codeHash
+	claim := fmt.Sprintf("%s%d:%s", deployClaimPrefix, time.Now().Unix(), uuid.New().String())
+
+	// the loop runs at most twice, since an abandoned claim
+	// is removed before the claim is attempted again
+	for attempt := 0; attempt < 2; attempt++ {
+		value, ok, err := r.store.SetField(ctx, mqueue.SetFieldRequest{
+			Key:      key,
+			Field:    field,
+			Value:    claim,
+			IfAbsent: true,
+		})
+		if err != nil {
+			return DeployRecord{}, "", queueError(errors.ErrStore, err)
+		}
+
+		if ok {
+			return DeployRecord{}, claim, nil
+		}
+
+		if !strings.HasPrefix(value, deployClaimPrefix) {
+			return DeployRecord{AAD: aad, CodeHash: codeHash, Address: value}, "", nil
+		}
+
+		if !isAbandonedClaim(value) {
+			break
+		}
+
+		// the claim is only removed if it has not changed, so that a
+		// claim taken over by another gateway is not removed
+		if _, err := r.store.DeleteField(ctx, mqueue.DeleteFieldRequest{
+			Key:     key,
+			Field:   field,
+			IfValue: value,
+		}); err != nil {
+			return DeployRecord{}, "", queueError(errors.ErrStore, err)
+		}
+	}
+
+	return DeployRecord{}, "", errors.New(errors.ErrDeployInProgress,
+		fmt.Errorf("deployment of code hash %s is in progress", codeHash))
+}
+
+// Release removes a claim made with Claim whose deployment did not
+// complete. Claims of completed deployments are replaced by Record
+func (r *DeployRegistry) Release(ctx context.Context, aad string, codeHash string, claim string) errors.Err {
+	if _, err := r.store.DeleteField(ctx, mqueue.DeleteFieldRequest{
+		Key:     DeployRegistryID(aad),
+		Field:   deployCodePrefix + codeHash,
+		IfValue: claim,
+	}); err != nil {
+		return queueError(errors.ErrStore, err)
+	}
+
+	return nil
+}
+
+// isAbandonedClaim returns true if the claim was made more
+// than deployClaimTimeout ago
+func isAbandonedClaim(claim string) bool {
+	parts := strings.SplitN(strings.TrimPrefix(claim, deployClaimPrefix), ":", 2)
+	created, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return true
+	}
+
+	return time.Since(time.Unix(created, 0)) > deployClaimTimeout
+}
@@ -0,0 +1,271 @@
+package core
+
+import (
+	"context"
+	"testing"
+
+	"github.com/oasislabs/oasis-gateway/errors"
+	mqueue "github.com/oasislabs/oasis-gateway/mqueue/core"
+	"github.com/oasislabs/oasis-gateway/mqueue/mem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func createMemRequestManager(dedup bool) *RequestManager {
+	return NewRequestManager(RequestManagerProperties{
+		MQueue:      mem.NewServer(Context, mem.Services{Logger: Logger}),
+		Client:      &MockClient{},
+		Logger:      Logger,
+		DeployDedup: dedup,
+	})
+}
+
+func TestCodeHashHexAndRaw(t *testing.T) {
+	assert.Equal(t, CodeHash("0x0102"), CodeHash("0102"))
+	assert.NotEqual(t, CodeHash("0x0102"), CodeHash("0x0103"))
+	assert.NotEmpty(t, CodeHash("not hex"))
+}
+
+func TestDeployRegistryRecordAndList(t *testing.T) {
+	registry := NewDeployRegistry(mqueue.NewMemStore())
+
+	assert.Nil(t, registry.Record(Context, DeployRecord{AAD: "aad", CodeHash: "0x01", Address: "0x0a"}))
+	assert.Nil(t, registry.Record(Context, DeployRecord{AAD: "aad", CodeHash: "0x02", Address: "0x0b"}))
+	assert.Nil(t, registry.Record(Context, DeployRecord{AAD: "other", CodeHash: "0x01", Address: "0x0c"}))
+
+	records, err := registry.List(Context, "aad", "", 0, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(records.Records))
+	assert.Equal(t, uint64(0), records.Records[0].ID)
+	assert.Equal(t, "0x0a", records.Records[0].Address)
+	assert.Equal(t, uint64(1), records.Records[1].ID)
+	assert.Equal(t, "0x0b", records.Records[1].Address)
+	assert.NotZero(t, records.Records[0].Timestamp)
+
+	record, ok, err := registry.Find(Context, "other", "0x01")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "0x0c", record.Address)
+
+	_, ok, err = registry.Find(Context, "other", "0x02")
+	assert.Nil(t, err)
+	assert.False(t, ok)
+}
+
+func TestDeployRegistryListFiltersBeforePaging(t *testing.T) {
+	registry := NewDeployRegistry(mqueue.NewMemStore())
+
+	for i := 0; i < 4; i++ {
+		assert.Nil(t, registry.Record(Context, DeployRecord{AAD: "aad", CodeHash: "0x01", Address: "0x0a"}))
+	}
+	assert.Nil(t, registry.Record(Context, DeployRecord{AAD: "aad", CodeHash: "0x02", Address: "0x0b"}))
+
+	records, err := registry.List(Context, "aad", "0x02", 0, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(records.Records))
+	assert.Equal(t, uint64(4), records.Records[0].ID)
+
+	records, err = registry.List(Context, "aad", "0x01", 2, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(records.Records))
+	assert.Equal(t, uint64(2), records.Records[0].ID)
+}
+
+func TestDeployRegistryClaim(t *testing.T) {
+	registry := NewDeployRegistry(mqueue.NewMemStore())
+
+	_, claim, err := registry.Claim(Context, "aad", "0x01")
+	assert.Nil(t, err)
+	assert.NotEmpty(t, claim)
+
+	_, _, err = registry.Claim(Context, "aad", "0x01")
+	assert.Equal(t, errors.ErrDeployInProgress, err.ErrorCode())
+
+	assert.Nil(t, registry.Release(Context, "aad", "0x01", claim))
+	_, claim, err = registry.Claim(Context, "aad", "0x01")
+	assert.Nil(t, err)
+	assert.NotEmpty(t, claim)
+
+	assert.Nil(t, registry.Record(Context, DeployRecord{AAD: "aad", CodeHash: "0x01", Address: "0x0a"}))
+	record, claim, err := registry.Claim(Context, "aad", "0x01")
+	assert.Nil(t, err)
+	assert.Empty(t, claim)
+	assert.Equal(t, "0x0a", record.Address)
+}
+
+func TestDeployRegistryClaimAbandoned(t *testing.T) {
+	store := mqueue.NewMemStore()
+	registry := NewDeployRegistry(store)
+
+	_, _, _ = store.SetField(Context, mqueue.SetFieldRequest{
+		Key:   DeployRegistryID("aad"),
+		Field: deployCodePrefix + "0x01",
+		Value: deployClaimPrefix + "0:abandoned",
+	})
+
+	_, claim, err := registry.Claim(Context, "aad", "0x01")
+	assert.Nil(t, err)
+	assert.NotEmpty(t, claim)
+}
+
+func TestDeployServiceRecorded(t *testing.T) {
+	manager := createMemRequestManager(false)
+	req := DeployServiceRequest{AAD: "aad", Data: "0x0102", SessionKey: "session"}
+
+	manager.client.(*MockClient).On("DeployService", mock.Anything, mock.Anything, req).
+		Return(DeployServiceResponse{Address: "0x0a"}, nil)
+
+	_, err := manager.deployService(Context, 0, req)
+	assert.Nil(t, err)
+	_, err = manager.deployService(Context, 1, req)
+	assert.Nil(t, err)
+
+	records, err := manager.ListDeployments(Context, ListDeploymentsRequest{
+		AAD:      "aad",
+		Count:    10,
+		CodeHash: CodeHash("0x0102"),
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(records.Records))
+	manager.client.(*MockClient).AssertNumberOfCalls(t, "DeployService", 2)
+}
+
+func TestDeployServiceDedup(t *testing.T) {
+	manager := createMemRequestManager(true)
+	req := DeployServiceRequest{AAD: "aad", Data: "0x0102", SessionKey: "session"}
+
+	manager.client.(*MockClient).On("DeployService", mock.Anything, mock.Anything, req).
+		Return(DeployServiceResponse{Address: "0x0a"}, nil)
+
+	_, err := manager.deployService(Context, 0, req)
+	assert.Nil(t, err)
+	ev, err := manager.deployService(Context, 1, req)
+	assert.Nil(t, err)
+
+	assert.Equal(t, DeployServiceResponse{ID: 1, Address: "0x0a"}, ev)
+	manager.client.(*MockClient).AssertNumberOfCalls(t, "DeployService", 1)
+}
+
+func TestDeployServiceDedupConcurrent(t *testing.T) {
+	manager := createMemRequestManager(true)
+	req := DeployServiceRequest{AAD: "aad", Data: "0x0102", SessionKey: "session"}
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	manager.client.(*MockClient).On("DeployService", mock.Anything, mock.Anything, req).
+		Run(func(mock.Arguments) {
+			close(started)
+			<-release
+		}).
+		Return(DeployServiceResponse{Address: "0x0a"}, nil)
+
+	done := make(chan errors.Err)
+	go func() {
+		_, err := manager.deployService(Context, 0, req)
+		done <- err
+	}()
+
+	<-started
+	_, err := manager.deployService(Context, 1, req)
+	assert.Equal(t, errors.ErrDeployInProgress, err.ErrorCode())
+
+	close(release)
+	assert.Nil(t, <-done)
+	manager.client.(*MockClient).AssertNumberOfCalls(t, "DeployService", 1)
+}
+
+func TestListDeploymentsErrNoAAD(t *testing.T) {
+	manager := createMemRequestManager(false)
+
+	_, err := manager.ListDeployments(Context, ListDeploymentsRequest{Count: 10})
+	assert.Error(t, err)
+}
+
+func TestExecuteServiceAsyncResolvesAlias(t *testing.T) {
+	manager := createMemRequestManager(false)
+	address := "0x0a51514857B379A521C580a10822Fd8A7aC491A0"
+
+	assert.Nil(t, manager.SetAlias(Context, SetAliasRequest{Name: "token", Address: address}))
+
+	done := make(chan struct{})
+	manager.client.(*MockClient).On("ExecuteService", mock.Anything, mock.Anything,
+		ExecuteServiceRequest{Address: address, SessionKey: "session"}).
+		Run(func(mock.Arguments) { close(done) }).
+		Return(ExecuteServiceResponse{Address: address}, nil)
+
+	_, err := manager.ExecuteServiceAsync(Context, ExecuteServiceRequest{
+		Address:    "token",
+		SessionKey: "session",
+	})
+	assert.Nil(t, err)
+	<-done
+}
+
+func TestExecuteServiceAsyncErrNameNotResolved(t *testing.T) {
+	manager := createMemRequestManager(false)
+
+	_, err := manager.ExecuteServiceAsync(Context, ExecuteServiceRequest{
+		Address:    "token",
+		SessionKey: "session",
+	})
+	assert.Error(t, err)
+	assert.Equal(t, errors.ErrNameNotResolved, err.ErrorCode())
+}
+
+func TestExecuteServiceAsyncUsesResolver(t *testing.T) {
+	address := "0x0a51514857B379A521C580a10822Fd8A7aC491A0"
+	manager := NewRequestManager(RequestManagerProperties{
+		MQueue: mem.NewServer(Context, mem.Services{Logger: Logger}),
+		Client: &MockClient{},
+		Logger: Logger,
+		Resolver: AddressResolverFunc(func(ctx context.Context, name string) (string, errors.Err) {
+			if name == "token.oasis" {
+				return address, nil
+			}
+			return "", errors.New(errors.ErrNameNotResolved, nil)
+		}),
+	})
+
+	done := make(chan struct{})
+	manager.client.(*MockClient).On("ExecuteService", mock.Anything, mock.Anything,
+		ExecuteServiceRequest{Address: address, SessionKey: "session"}).
+		Run(func(mock.Arguments) { close(done) }).
+		Return(ExecuteServiceResponse{Address: address}, nil)
+
+	_, err := manager.ExecuteServiceAsync(Context, ExecuteServiceRequest{
+		Address:    "token.oasis",
+		SessionKey: "session",
+	})
+	assert.Nil(t, err)
+	<-done
+}
+
+func TestAddressResolversStopOnErr(t *testing.T) {
+	resolvers := AddressResolvers{
+		AddressResolverFunc(func(ctx context.Context, name string) (string, errors.Err) {
+			return "", errors.New(errors.ErrInternalError, nil)
+		}),
+		AddressResolverFunc(func(ctx context.Context, name string) (string, errors.Err) {
+			return "0x0a51514857B379A521C580a10822Fd8A7aC491A0", nil
+		}),
+	}
+
+	_, err := resolvers.Resolve(Context, "name")
+	assert.Error(t, err)
+	assert.Equal(t, errors.ErrInternalError, err.ErrorCode())
+}
+
+func TestAliasRegistrySharedStore(t *testing.T) {
+	store := mqueue.NewMemStore()
+	address := "0x0a51514857B379A521C580a10822Fd8A7aC491A0"
+
+	assert.Nil(t, NewAliasRegistry(store).Set(Context, Alias{Name: "token", Address: address}))
+
+	alias, err := NewAliasRegistry(store).Get(Context, "token")
+	assert.Nil(t, err)
+	assert.Equal(t, address, alias.Address)
+
+	assert.Nil(t, NewAliasRegistry(store).Remove(Context, "token"))
+	_, err = NewAliasRegistry(store).Get(Context, "token")
+	assert.Equal(t, errors.ErrAliasNotFound, err.ErrorCode())
+}
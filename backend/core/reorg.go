@@ -0,0 +1,88 @@
+package core
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/oasislabs/oasis-gateway/log"
+)
+
+// maxTrackedLogs is the number of the latest logs delivered by a
+// subscription whose removal can be reported. Reorganizations
+// deeper than that are not expected
+const maxTrackedLogs = 1024
+
+// logID identifies a log within the chain. A log removed by a
+// reorganization is notified with the same block hash and index
+// with which it was delivered
+type logID struct {
+	BlockHash common.Hash
+	Index     uint
+}
+
+// trackedLog is a log tracked at offset
+type trackedLog struct {
+	ID     logID
+	Offset uint64
+}
+
+// logTracker keeps the offsets of the events of the latest logs
+// delivered by a subscription
+type logTracker struct {
+	offsets map[logID]uint64
+	order   []trackedLog
+}
+
+func newLogTracker() *logTracker {
+	return &logTracker{offsets: make(map[logID]uint64)}
+}
+
+// Track records the offset of the event of a delivered log
+func (t *logTracker) Track(l types.Log, offset uint64) {
+	id := logID{BlockHash: l.BlockHash, Index: l.Index}
+	t.offsets[id] = offset
+	t.order = append(t.order, trackedLog{ID: id, Offset: offset})
+
+	if len(t.order) > maxTrackedLogs {
+		// the oldest log is only dropped if it has not been
+		// tracked again at a later offset
+		oldest := t.order[0]
+		if t.offsets[oldest.ID] == oldest.Offset {
+			delete(t.offsets, oldest.ID)
+		}
+		t.order = t.order[1:]
+	}
+}
+
+// Untrack returns the offset of the event of a removed log and
+// stops tracking it. It returns false if the log is not tracked
+func (t *logTracker) Untrack(l types.Log) (uint64, bool) {
+	id := logID{BlockHash: l.BlockHash, Index: l.Index}
+	offset, ok := t.offsets[id]
+	if !ok {
+		return 0, false
+	}
+
+	// the position of the log in order is left as it is, it
+	// is dropped once it becomes the oldest one
+	delete(t.offsets, id)
+	return offset, true
+}
+
+// removeLog inserts a LogRemovedEvent for a log delivered by
+// the subscription that a reorganization removed
+func (s *subscription) removeLog(l types.Log) {
+	offset, ok := s.logs.Untrack(l)
+	if !ok {
+		s.logger.Warn(s.ctx, "received removal of log that is not tracked", log.MapFields{
+			"call_type":  "RemoveSubscriptionLogFailure",
+			"key":        s.key,
+			"block_hash": l.BlockHash.Hex(),
+			"index":      l.Index,
+		})
+		return
+	}
+
+	s.insert(func(id uint64) Event {
+		return LogRemovedEvent{ID: id, Offset: offset}
+	})
+}
@@ -0,0 +1,57 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogTrackerUntrack(t *testing.T) {
+	tracker := newLogTracker()
+	l := types.Log{BlockHash: common.HexToHash("0x01"), Index: 1}
+
+	tracker.Track(l, 4)
+	offset, ok := tracker.Untrack(l)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(4), offset)
+
+	_, ok = tracker.Untrack(l)
+	assert.False(t, ok)
+}
+
+func TestLogTrackerPrune(t *testing.T) {
+	tracker := newLogTracker()
+	first := types.Log{BlockHash: common.HexToHash("0x01")}
+
+	tracker.Track(first, 0)
+	for i := 1; i <= maxTrackedLogs; i++ {
+		tracker.Track(types.Log{BlockHash: common.HexToHash("0x02"), Index: uint(i)}, uint64(i))
+	}
+
+	_, ok := tracker.Untrack(first)
+	assert.False(t, ok)
+	assert.Equal(t, maxTrackedLogs, len(tracker.offsets))
+}
+
+func TestSubscriptionLogRemoved(t *testing.T) {
+	_, mq, c := createLagSubscription(t, SubscriptionLagProps{}, nil)
+
+	l := types.Log{BlockHash: common.HexToHash("0x01"), Index: 2, Data: []byte{1}}
+	c <- l
+	c <- types.Log{BlockHash: common.HexToHash("0x02"), Index: 0, Data: []byte{2}}
+
+	l.Removed = true
+	c <- l
+
+	evs := waitForEvents(t, mq, 3)
+	assert.Equal(t, Events{
+		Offset: 0,
+		Events: []Event{
+			DataEvent{ID: 0, Data: "0x01"},
+			DataEvent{ID: 1, Data: "0x02"},
+			LogRemovedEvent{ID: 2, Offset: 0},
+		},
+	}, evs)
+}
@@ -0,0 +1,57 @@
+package core
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/oasislabs/oasis-gateway/errors"
+)
+
+// AddressResolver resolves a name to the address of a service. It is
+// invoked for the addresses provided by the client that are not
+// hex addresses
+type AddressResolver interface {
+	// Resolve returns the hex address for the provided name. If the
+	// name cannot be resolved errors.ErrNameNotResolved is returned
+	Resolve(ctx context.Context, name string) (string, errors.Err)
+}
+
+// AddressResolverFunc allows a function to act as an AddressResolver
+type AddressResolverFunc func(ctx context.Context, name string) (string, errors.Err)
+
+// Resolve is the implementation of AddressResolver for AddressResolverFunc
+func (f AddressResolverFunc) Resolve(ctx context.Context, name string) (string, errors.Err) {
+	return f(ctx, name)
+}
+
+// AddressResolvers tries each of the resolvers in order until one of
+// them resolves the name
+type AddressResolvers []AddressResolver
+
+// Resolve is the implementation of AddressResolver for AddressResolvers
+func (r AddressResolvers) Resolve(ctx context.Context, name string) (string, errors.Err) {
+	for _, resolver := range r {
+		address, err := resolver.Resolve(ctx, name)
+		if err == nil {
+			return address, nil
+		}
+
+		// a resolver that does not know about the name lets the
+		// next one attempt the resolution
+		if err.ErrorCode().Category() != errors.NotFound {
+			return "", err
+		}
+	}
+
+	return "", errors.New(errors.ErrNameNotResolved, nil)
+}
+
+// resolveAddress returns the address itself if it is a hex address,
+// otherwise it is resolved with the resolver
+func resolveAddress(ctx context.Context, resolver AddressResolver, address string) (string, errors.Err) {
+	if common.IsHexAddress(address) {
+		return address, nil
+	}
+
+	return resolver.Resolve(ctx, address)
+}
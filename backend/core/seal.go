@@ -0,0 +1,103 @@
+package core
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	stderr "errors"
+	"fmt"
+	"strings"
+
+	"github.com/oasislabs/oasis-gateway/errors"
+	mqueue "github.com/oasislabs/oasis-gateway/mqueue/core"
+	"golang.org/x/crypto/nacl/box"
+)
+
+// sealOutput seals the hex encoded output of a service to the X25519
+// public key of the client using an anonymous sealed box, which is
+// compatible with libsodium's crypto_box_seal. Only the holder of the
+// private key can open it, so the gateway and the mqueue cannot read
+// the output once it is sealed. The sealed box is returned hex encoded
+func sealOutput(output string, publicKey *[32]byte) (string, errors.Err) {
+	p, err := hex.DecodeString(strings.TrimPrefix(output, "0x"))
+	if err != nil {
+		return "", errors.New(errors.ErrSealOutput, err)
+	}
+
+	sealed, err := box.SealAnonymous(nil, p, publicKey, rand.Reader)
+	if err != nil {
+		return "", errors.New(errors.ErrSealOutput, err)
+	}
+
+	return "0x" + hex.EncodeToString(sealed), nil
+}
+
+// OutputKeyRegistryID generates the key of the store that holds
+// the output keys of the sessions of an AAD
+func OutputKeyRegistryID(aad string) string {
+	return fmt.Sprintf("%s:outputKeys", aad)
+}
+
+// OutputKeyRegistry binds an output key to the session that first
+// uses it. Once a session has an output key the outputs of all the
+// executions of the session are sealed to it, whether or not the
+// requests carry the key, and requests that carry a different key are
+// rejected. The bindings are kept in the mailbox store so that they
+// are shared by the gateways that share the mailbox
+type OutputKeyRegistry struct {
+	store mqueue.Store
+}
+
+// NewOutputKeyRegistry creates a new registry backed by the provided store
+func NewOutputKeyRegistry(store mqueue.Store) *OutputKeyRegistry {
+	return &OutputKeyRegistry{store: store}
+}
+
+// Bind binds the key to the session if the session has no output key
+// yet and returns the output key of the session, which is nil if the
+// session has none and no key is provided
+func (r *OutputKeyRegistry) Bind(
+	ctx context.Context,
+	aad, session string,
+	key *[32]byte,
+) (*[32]byte, errors.Err) {
+	req := mqueue.FieldRequest{Key: OutputKeyRegistryID(aad), Field: session}
+
+	var (
+		value string
+		err   error
+	)
+	if key == nil {
+		var ok bool
+		value, ok, err = r.store.GetField(ctx, req)
+		if err != nil {
+			return nil, queueError(errors.ErrStore, err)
+		}
+		if !ok {
+			return nil, nil
+		}
+	} else {
+		value, _, err = r.store.SetField(ctx, mqueue.SetFieldRequest{
+			Key:      req.Key,
+			Field:    req.Field,
+			Value:    hex.EncodeToString(key[:]),
+			IfAbsent: true,
+		})
+		if err != nil {
+			return nil, queueError(errors.ErrStore, err)
+		}
+	}
+
+	var bound [32]byte
+	p, err := hex.DecodeString(value)
+	if err != nil || len(p) != len(bound) {
+		return nil, errors.New(errors.ErrStore, stderr.New("invalid output key stored for session"))
+	}
+
+	copy(bound[:], p)
+	if key != nil && bound != *key {
+		return nil, errors.New(errors.ErrOutputKeyBound, nil)
+	}
+
+	return &bound, nil
+}
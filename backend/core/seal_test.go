@@ -0,0 +1,135 @@
+package core
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/oasislabs/oasis-gateway/errors"
+	mqueue "github.com/oasislabs/oasis-gateway/mqueue/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/nacl/box"
+)
+
+func TestSealOutput(t *testing.T) {
+	publicKey, privateKey, err := box.GenerateKey(rand.Reader)
+	assert.Nil(t, err)
+
+	sealed, serr := sealOutput("0x0102", publicKey)
+	assert.Nil(t, serr)
+
+	p, err := hex.DecodeString(strings.TrimPrefix(sealed, "0x"))
+	assert.Nil(t, err)
+	output, ok := box.OpenAnonymous(nil, p, publicKey, privateKey)
+	assert.True(t, ok)
+	assert.Equal(t, []byte{1, 2}, output)
+}
+
+func TestSealOutputInvalidOutput(t *testing.T) {
+	publicKey, _, err := box.GenerateKey(rand.Reader)
+	assert.Nil(t, err)
+
+	_, serr := sealOutput("0xzz", publicKey)
+	assert.Equal(t, "[1048] error code InternalError with desc Failed to encrypt the output. with cause encoding/hex: invalid byte: U+007A 'z'", serr.Error())
+}
+
+func TestExecuteServiceSealsOutput(t *testing.T) {
+	manager := createMemRequestManager(false)
+	publicKey, privateKey, err := box.GenerateKey(rand.Reader)
+	assert.Nil(t, err)
+
+	manager.client.(*MockClient).On("ExecuteService", mock.Anything, uint64(0), mock.Anything).
+		Return(ExecuteServiceResponse{ID: 0, Address: "address", Output: "0x0102"}, nil)
+
+	ev, eerr := manager.executeService(Context, 0, ExecuteServiceRequest{
+		Address:   "address",
+		OutputKey: publicKey,
+	})
+	assert.Nil(t, eerr)
+
+	res := ev.(ExecuteServiceResponse)
+	assert.True(t, res.Encrypted)
+
+	p, err := hex.DecodeString(strings.TrimPrefix(res.Output, "0x"))
+	assert.Nil(t, err)
+	output, ok := box.OpenAnonymous(nil, p, publicKey, privateKey)
+	assert.True(t, ok)
+	assert.Equal(t, []byte{1, 2}, output)
+}
+
+func TestOutputKeyRegistryBind(t *testing.T) {
+	registry := NewOutputKeyRegistry(mqueue.NewMemStore())
+	publicKey, _, err := box.GenerateKey(rand.Reader)
+	assert.Nil(t, err)
+	otherKey, _, err := box.GenerateKey(rand.Reader)
+	assert.Nil(t, err)
+
+	key, berr := registry.Bind(Context, "aad", "session", nil)
+	assert.Nil(t, berr)
+	assert.Nil(t, key)
+
+	key, berr = registry.Bind(Context, "aad", "session", publicKey)
+	assert.Nil(t, berr)
+	assert.Equal(t, publicKey, key)
+
+	// requests without a key are sealed to the key of the session
+	key, berr = registry.Bind(Context, "aad", "session", nil)
+	assert.Nil(t, berr)
+	assert.Equal(t, publicKey, key)
+
+	_, berr = registry.Bind(Context, "aad", "session", otherKey)
+	assert.Equal(t, errors.ErrOutputKeyBound, berr.ErrorCode())
+
+	// other sessions are not affected
+	key, berr = registry.Bind(Context, "aad", "other", otherKey)
+	assert.Nil(t, berr)
+	assert.Equal(t, otherKey, key)
+}
+
+func TestExecuteServiceAsyncOutputKeyBound(t *testing.T) {
+	manager := createMemRequestManager(false)
+	publicKey, _, err := box.GenerateKey(rand.Reader)
+	assert.Nil(t, err)
+	otherKey, _, err := box.GenerateKey(rand.Reader)
+	assert.Nil(t, err)
+
+	manager.client.(*MockClient).On("ExecuteService", mock.Anything, mock.Anything, mock.Anything).
+		Return(ExecuteServiceResponse{Address: "address", Output: "0x0102"}, nil)
+
+	_, eerr := manager.ExecuteServiceAsync(Context, ExecuteServiceRequest{
+		AAD:        "aad",
+		Address:    "0x0a51514857B379A521C580a10822Fd8A7aC491A0",
+		SessionKey: "session",
+		OutputKey:  publicKey,
+	})
+	assert.Nil(t, eerr)
+
+	_, eerr = manager.ExecuteServiceAsync(Context, ExecuteServiceRequest{
+		AAD:        "aad",
+		Address:    "0x0a51514857B379A521C580a10822Fd8A7aC491A0",
+		SessionKey: "session",
+		OutputKey:  otherKey,
+	})
+	assert.Equal(t, errors.ErrOutputKeyBound, eerr.ErrorCode())
+
+	_, eerr = manager.ExecuteServiceAsync(Context, ExecuteServiceRequest{
+		AAD:        "aad",
+		Address:    "0x0a51514857B379A521C580a10822Fd8A7aC491A0",
+		SessionKey: "session",
+	})
+	assert.Nil(t, eerr)
+	assert.Nil(t, manager.Shutdown(Context))
+
+	evs, eerr := manager.PollService(Context, PollServiceRequest{
+		SessionKey: "session",
+		Offset:     0,
+		Count:      2,
+	})
+	assert.Nil(t, eerr)
+	assert.Equal(t, 2, len(evs.Events))
+	for _, ev := range evs.Events {
+		assert.True(t, ev.(ExecuteServiceResponse).Encrypted)
+	}
+}
@@ -0,0 +1,270 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	stderr "errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+
+	"github.com/oasislabs/oasis-gateway/errors"
+	mqueue "github.com/oasislabs/oasis-gateway/mqueue/core"
+	"github.com/oasislabs/oasis-gateway/stats"
+)
+
+const (
+	// sessionMetadataType is the type set on the mqueue elements
+	// that hold the metadata of a session
+	sessionMetadataType = "sessionMetadata"
+
+	// maxSessionMetadataScan is the number of elements retrieved
+	// when the metadata of a session is loaded from the mqueue
+	maxSessionMetadataScan = 16
+
+	// MaxSessionMetadataEntries is the maximum number of entries
+	// of the metadata of a session
+	MaxSessionMetadataEntries = 16
+
+	// MaxSessionMetadataKeySize is the maximum size of a key of the
+	// metadata of a session
+	MaxSessionMetadataKeySize = 32
+
+	// MaxSessionMetadataValueSize is the maximum size of a value of
+	// the metadata of a session
+	MaxSessionMetadataValueSize = 128
+
+	// SessionMetadataApp is the metadata key clients use to
+	// identify their application. Sessions are aggregated by it
+	// in the metrics of the SessionRegistry
+	SessionMetadataApp = "app"
+
+	// DefaultSessionRegistryMaxSessions is the number of sessions
+	// kept in memory when SessionRegistryProps does not set it
+	DefaultSessionRegistryMaxSessions = 65536
+)
+
+var sessionMetadataKeyRegexp = regexp.MustCompile("^[a-zA-Z0-9_.-]+$")
+
+// SessionMetadata is a small set of key/value pairs a client attaches
+// to its session, like the name and version of its application, so
+// that operators can attribute the traffic of the session
+type SessionMetadata map[string]string
+
+// Validate returns an error if the metadata exceeds the limits
+// on the number or the size of its entries
+func (m SessionMetadata) Validate() errors.Err {
+	if len(m) > MaxSessionMetadataEntries {
+		return errors.New(errors.ErrInvalidSessionMetadata, fmt.Errorf(
+			"metadata cannot have more than %d entries", MaxSessionMetadataEntries))
+	}
+
+	for key, value := range m {
+		if len(key) > MaxSessionMetadataKeySize || !sessionMetadataKeyRegexp.MatchString(key) {
+			return errors.New(errors.ErrInvalidSessionMetadata, fmt.Errorf(
+				"metadata key %q must have at most %d alphanumeric characters, '_', '.' or '-'",
+				key, MaxSessionMetadataKeySize))
+		}
+
+		if len(value) > MaxSessionMetadataValueSize {
+			return errors.New(errors.ErrInvalidSessionMetadata, fmt.Errorf(
+				"metadata value of %q cannot be longer than %d bytes", key, MaxSessionMetadataValueSize))
+		}
+	}
+
+	return nil
+}
+
+// LogFields returns the metadata as log fields prefixed by session
+func (m SessionMetadata) LogFields() map[string]string {
+	fields := make(map[string]string, len(m))
+	for key, value := range m {
+		fields["session."+key] = value
+	}
+
+	return fields
+}
+
+// Session is a session with the metadata attached to it
+type Session struct {
+	// Key is the session key
+	Key string
+
+	// Metadata attached by the client to the session
+	Metadata SessionMetadata
+}
+
+// SessionMetadataID generates the ID of the queue that holds the
+// metadata of a session
+func SessionMetadataID(session string) string {
+	return fmt.Sprintf("%s:metadata", session)
+}
+
+// SessionRegistryProps are the properties used to create
+// a SessionRegistry
+type SessionRegistryProps struct {
+	// MaxSessions is the maximum number of sessions whose
+	// metadata is kept in memory
+	MaxSessions int
+}
+
+// SessionRegistry keeps the metadata of the sessions. The metadata is
+// stored in the mqueue alongside the queue of the session, so it is as
+// persistent as the configured mailbox. The registry also keeps in
+// memory the metadata of the sessions known to the gateway, which are
+// the ones reported in the listings and metrics of the gateway
+type SessionRegistry struct {
+	mqueue      mqueue.MQueue
+	maxSessions int
+
+	lock     sync.RWMutex
+	sessions map[string]SessionMetadata
+}
+
+// NewSessionRegistry creates a new registry backed by the provided mqueue
+func NewSessionRegistry(mqueue mqueue.MQueue, props SessionRegistryProps) *SessionRegistry {
+	maxSessions := props.MaxSessions
+	if maxSessions <= 0 {
+		maxSessions = DefaultSessionRegistryMaxSessions
+	}
+
+	return &SessionRegistry{
+		mqueue:      mqueue,
+		maxSessions: maxSessions,
+		sessions:    make(map[string]SessionMetadata),
+	}
+}
+
+// Set replaces the metadata of the session
+func (r *SessionRegistry) Set(ctx context.Context, session string, metadata SessionMetadata) errors.Err {
+	if len(session) == 0 {
+		return errors.New(errors.ErrInvalidSessionMetadata, stderr.New("session key cannot be empty"))
+	}
+
+	if err := metadata.Validate(); err != nil {
+		return err
+	}
+
+	p, err := json.Marshal(metadata)
+	if err != nil {
+		return queueError(errors.ErrQueueInsert, err)
+	}
+
+	key := SessionMetadataID(session)
+	id, err := r.mqueue.Next(ctx, mqueue.NextRequest{Key: key})
+	if err != nil {
+		return queueError(errors.ErrQueueNext, err)
+	}
+
+	if err := r.mqueue.Insert(ctx, mqueue.InsertRequest{
+		Key: key,
+		Element: mqueue.Element{
+			Offset: id,
+			Type:   sessionMetadataType,
+			Value:  string(p),
+		},
+	}); err != nil {
+		return queueError(errors.ErrQueueInsert, err)
+	}
+
+	// only the latest metadata of the session is needed
+	if err := r.mqueue.Discard(ctx, mqueue.DiscardRequest{Key: key, Offset: id}); err != nil {
+		return queueError(errors.ErrQueueDiscard, err)
+	}
+
+	r.cache(session, metadata)
+	return nil
+}
+
+// Get returns the metadata of the session. If the metadata is not
+// known to the gateway it is loaded from the mqueue
+func (r *SessionRegistry) Get(ctx context.Context, session string) (SessionMetadata, errors.Err) {
+	if metadata, ok := r.Lookup(session); ok {
+		return metadata, nil
+	}
+
+	els, err := r.mqueue.Retrieve(ctx, mqueue.RetrieveRequest{
+		Key:    SessionMetadataID(session),
+		Offset: 0,
+		Count:  maxSessionMetadataScan,
+	})
+	if err != nil {
+		return nil, queueError(errors.ErrQueueRetrieve, err)
+	}
+
+	if len(els.Elements) == 0 {
+		return SessionMetadata{}, nil
+	}
+
+	var metadata SessionMetadata
+	if err := json.Unmarshal([]byte(els.Elements[len(els.Elements)-1].Value), &metadata); err != nil {
+		return nil, errors.New(errors.ErrDeserializeEvent, err)
+	}
+
+	r.cache(session, metadata)
+	return metadata, nil
+}
+
+// Lookup returns the metadata of the session if it is known to
+// the gateway, without loading it from the mqueue
+func (r *SessionRegistry) Lookup(session string) (SessionMetadata, bool) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	metadata, ok := r.sessions[session]
+	return metadata, ok
+}
+
+// List returns the sessions known to the gateway sorted by key
+func (r *SessionRegistry) List() []Session {
+	r.lock.RLock()
+	sessions := make([]Session, 0, len(r.sessions))
+	for key, metadata := range r.sessions {
+		sessions = append(sessions, Session{Key: key, Metadata: metadata})
+	}
+	r.lock.RUnlock()
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].Key < sessions[j].Key
+	})
+
+	return sessions
+}
+
+func (r *SessionRegistry) cache(session string, metadata SessionMetadata) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if _, ok := r.sessions[session]; !ok && len(r.sessions) >= r.maxSessions {
+		// evict any session to make space, the sessions that are
+		// still in use are loaded again when they are needed
+		for evicted := range r.sessions {
+			delete(r.sessions, evicted)
+			break
+		}
+	}
+
+	r.sessions[session] = metadata
+}
+
+// Stats is the implementation of stats.Collector for SessionRegistry.
+// It reports the number of sessions known to the gateway and the
+// number of sessions of each application
+func (r *SessionRegistry) Stats() stats.Metrics {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	apps := make(stats.Metrics)
+	for _, metadata := range r.sessions {
+		if app, ok := metadata[SessionMetadataApp]; ok {
+			count, _ := apps[app].(int)
+			apps[app] = count + 1
+		}
+	}
+
+	return stats.Metrics{
+		"sessions": len(r.sessions),
+		"apps":     apps,
+	}
+}
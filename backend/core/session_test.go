@@ -0,0 +1,98 @@
+package core
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/oasislabs/oasis-gateway/errors"
+	"github.com/oasislabs/oasis-gateway/mqueue/mem"
+	"github.com/oasislabs/oasis-gateway/stats"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionMetadataValidate(t *testing.T) {
+	assert.Nil(t, SessionMetadata{"app": "app", "client.version": "1.0.0"}.Validate())
+
+	for _, metadata := range []SessionMetadata{
+		{"": "app"},
+		{"app name": "app"},
+		{strings.Repeat("k", MaxSessionMetadataKeySize+1): "app"},
+		{"app": strings.Repeat("v", MaxSessionMetadataValueSize+1)},
+	} {
+		err := metadata.Validate()
+		assert.Equal(t, errors.ErrInvalidSessionMetadata, err.ErrorCode())
+	}
+
+	metadata := make(SessionMetadata)
+	for i := 0; i <= MaxSessionMetadataEntries; i++ {
+		metadata[strings.Repeat("k", i+1)] = "v"
+	}
+	assert.Equal(t, errors.ErrInvalidSessionMetadata, metadata.Validate().ErrorCode())
+}
+
+func TestSessionMetadataLogFields(t *testing.T) {
+	assert.Equal(t, map[string]string{"session.app": "app"}, SessionMetadata{"app": "app"}.LogFields())
+}
+
+func TestSessionRegistrySetGet(t *testing.T) {
+	mqueue := mem.NewServer(Context, mem.Services{Logger: Logger})
+	registry := NewSessionRegistry(mqueue, SessionRegistryProps{})
+
+	assert.Nil(t, registry.Set(Context, "session", SessionMetadata{"app": "app", "version": "1"}))
+	assert.Nil(t, registry.Set(Context, "session", SessionMetadata{"app": "app", "version": "2"}))
+
+	metadata, err := registry.Get(Context, "session")
+	assert.Nil(t, err)
+	assert.Equal(t, SessionMetadata{"app": "app", "version": "2"}, metadata)
+
+	// the metadata is loaded from the mqueue by another gateway
+	other := NewSessionRegistry(mqueue, SessionRegistryProps{})
+	_, ok := other.Lookup("session")
+	assert.False(t, ok)
+
+	metadata, err = other.Get(Context, "session")
+	assert.Nil(t, err)
+	assert.Equal(t, SessionMetadata{"app": "app", "version": "2"}, metadata)
+
+	metadata, ok = other.Lookup("session")
+	assert.True(t, ok)
+	assert.Equal(t, SessionMetadata{"app": "app", "version": "2"}, metadata)
+
+	metadata, err = other.Get(Context, "unknown")
+	assert.Nil(t, err)
+	assert.Equal(t, SessionMetadata{}, metadata)
+}
+
+func TestSessionRegistrySetInvalid(t *testing.T) {
+	registry := NewSessionRegistry(mem.NewServer(Context, mem.Services{Logger: Logger}), SessionRegistryProps{})
+
+	err := registry.Set(Context, "", SessionMetadata{"app": "app"})
+	assert.Equal(t, errors.ErrInvalidSessionMetadata, err.ErrorCode())
+
+	err = registry.Set(Context, "session", SessionMetadata{"app name": "app"})
+	assert.Equal(t, errors.ErrInvalidSessionMetadata, err.ErrorCode())
+	assert.Equal(t, 0, len(registry.List()))
+}
+
+func TestSessionRegistryListStats(t *testing.T) {
+	registry := NewSessionRegistry(mem.NewServer(Context, mem.Services{Logger: Logger}),
+		SessionRegistryProps{MaxSessions: 3})
+
+	assert.Nil(t, registry.Set(Context, "b", SessionMetadata{"app": "wallet"}))
+	assert.Nil(t, registry.Set(Context, "a", SessionMetadata{"app": "wallet"}))
+	assert.Nil(t, registry.Set(Context, "c", SessionMetadata{"version": "1"}))
+
+	assert.Equal(t, []Session{
+		{Key: "a", Metadata: SessionMetadata{"app": "wallet"}},
+		{Key: "b", Metadata: SessionMetadata{"app": "wallet"}},
+		{Key: "c", Metadata: SessionMetadata{"version": "1"}},
+	}, registry.List())
+	assert.Equal(t, stats.Metrics{
+		"sessions": 3,
+		"apps":     stats.Metrics{"wallet": 2},
+	}, registry.Stats())
+
+	// a session is evicted to make space for a new one
+	assert.Nil(t, registry.Set(Context, "d", SessionMetadata{"app": "game"}))
+	assert.Equal(t, 3, len(registry.List()))
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/oasislabs/oasis-gateway/concurrent"
 	"github.com/oasislabs/oasis-gateway/errors"
 	"github.com/oasislabs/oasis-gateway/log"
 	mqueue "github.com/oasislabs/oasis-gateway/mqueue/core"
@@ -21,15 +22,30 @@ type subscription struct {
 	done   chan<- subscriptionEndEvent
 	stop   chan interface{}
 	key    string
+	aad    string
 	mqueue mqueue.MQueue
+	bus    EventBus
+	lag    SubscriptionLagProps
+	logs   *logTracker
 	wg     sync.WaitGroup
+
+	// warned is set while the consumer of the subscription is
+	// lagging behind and it has already been warned
+	warned bool
+
+	// closed is set when the subscription is closed because its
+	// consumer is lagging behind
+	closed bool
 }
 
 type subscriptionProps struct {
 	Context context.Context
 	Logger  log.Logger
 	MQueue  mqueue.MQueue
+	Bus     EventBus
+	Lag     SubscriptionLagProps
 	Key     string
+	AAD     string
 	Done    chan<- subscriptionEndEvent
 	C       <-chan interface{}
 }
@@ -50,6 +66,9 @@ func newSubscription(props subscriptionProps) *subscription {
 	if props.MQueue == nil {
 		panic("mqueue must be set")
 	}
+	if props.Bus == nil {
+		panic("bus must be set")
+	}
 
 	return &subscription{
 		ctx:    props.Context,
@@ -58,7 +77,11 @@ func newSubscription(props subscriptionProps) *subscription {
 		done:   props.Done,
 		stop:   make(chan interface{}),
 		key:    props.Key,
+		aad:    props.AAD,
 		mqueue: props.MQueue,
+		bus:    props.Bus,
+		lag:    props.Lag,
+		logs:   newLogTracker(),
 		wg:     sync.WaitGroup{},
 	}
 }
@@ -70,6 +93,23 @@ func (s *subscription) Stop() {
 
 func (s *subscription) Start() {
 	defer func() {
+		if s.closed {
+			// the queue of a closed subscription is kept so that its
+			// consumer can find out why, and the manager is notified
+			// so that it releases the subscription
+			select {
+			case s.done <- subscriptionEndEvent{
+				Key:   s.key,
+				Error: errors.New(errors.ErrSubscriptionLagging, nil),
+			}:
+			case <-s.stop:
+			case <-s.ctx.Done():
+			}
+
+			s.wg.Done()
+			return
+		}
+
 		err := s.mqueue.Remove(context.Background(), mqueue.RemoveRequest{Key: s.key})
 		if err != nil {
 			s.logger.Warn(s.ctx, "failed to remove messaging queue", log.MapFields{
@@ -99,6 +139,23 @@ func (s *subscription) Start() {
 				return
 			}
 
+			data, ok := ev.(types.Log)
+			if !ok {
+				s.logger.Warn(s.ctx, "received event of unexpected type", log.MapFields{
+					"call_type": "InsertSubscriptionEventFailure",
+					"key":       s.key,
+					"type":      fmt.Sprintf("%+v", ev),
+				})
+				continue
+			}
+
+			// a log removed by a reorganization is reported so that
+			// the client can invalidate the state derived from it
+			if data.Removed {
+				s.removeLog(data)
+				continue
+			}
+
 			// TODO(stan); when the subscription fails to insert elements into
 			// the queue, the subscription should be closed. In that case,
 			// we should define a mechanism to report the errors back to the client
@@ -113,47 +170,75 @@ func (s *subscription) Start() {
 				continue
 			}
 
-			data, ok := ev.(types.Log)
-			if !ok {
-				s.logger.Warn(s.ctx, "received event of unexpected type", log.MapFields{
-					"call_type": "InsertSubscriptionEventFailure",
-					"key":       s.key,
-					"type":      fmt.Sprintf("%+v", ev),
-				})
-				continue
-			}
-
 			var topics []string
 			for _, topic := range data.Topics {
 				topics = append(topics, topic.Hex())
 			}
 
-			el, err := makeElement(DataEvent{
-				ID:     id,
-				Data:   hexutil.Encode(data.Data),
-				Topics: topics,
-			}, id)
+			err = s.bus.Publish(s.ctx, Publication{
+				Key:    s.key,
+				Offset: id,
+				AAD:    s.aad,
+				Event: DataEvent{
+					ID:     id,
+					Data:   hexutil.Encode(data.Data),
+					Topics: topics,
+				},
+			})
 			if err != nil {
-				s.logger.Warn(s.ctx, "failed to serialize event", log.MapFields{
+				s.logger.Warn(s.ctx, "failed to insert event to resource", log.MapFields{
 					"call_type": "InsertSubscriptionEventFailure",
 					"key":       s.key,
-					"type":      fmt.Sprintf("%+v", ev),
 					"err":       err.Error(),
 				})
 				continue
 			}
 
-			if err := s.mqueue.Insert(s.ctx, mqueue.InsertRequest{Key: s.key, Element: el}); err != nil {
-				s.logger.Warn(s.ctx, "failed to insert event to resource", log.MapFields{
-					"call_type": "InsertSubscriptionEventFailure",
-					"key":       s.key,
-					"err":       err.Error(),
-				})
+			s.logs.Track(data, id)
+			if s.checkLag(id) {
+				s.closed = true
+				return
 			}
 		}
 	}
 }
 
+// insert inserts the event created by fn at the next offset of the
+// subscription. It returns the offset and whether the event has
+// been inserted
+func (s *subscription) insert(fn func(id uint64) Event) (uint64, bool) {
+	id, err := s.mqueue.Next(s.ctx, mqueue.NextRequest{Key: s.key})
+	if err != nil {
+		s.logger.Warn(s.ctx, "failed to reserve offset for event", log.MapFields{
+			"call_type": "InsertSubscriptionEventFailure",
+			"key":       s.key,
+			"err":       err.Error(),
+		})
+		return 0, false
+	}
+
+	err = s.bus.Publish(s.ctx, Publication{Key: s.key, Offset: id, AAD: s.aad, Event: fn(id)})
+	if err != nil {
+		s.logger.Warn(s.ctx, "failed to insert event to resource", log.MapFields{
+			"call_type": "InsertSubscriptionEventFailure",
+			"key":       s.key,
+			"err":       err.Error(),
+		})
+
+		// the reserved offset is discarded so that it does not
+		// prevent the queue from discarding the events before it
+		_ = s.mqueue.Discard(s.ctx, mqueue.DiscardRequest{
+			Key:          s.key,
+			Offset:       id,
+			Count:        1,
+			KeepPrevious: true,
+		})
+		return 0, false
+	}
+
+	return id, true
+}
+
 type subscriptionEndEvent struct {
 	Key   string
 	Error error
@@ -162,6 +247,7 @@ type subscriptionEndEvent struct {
 type createSubscriptionRequest struct {
 	Context context.Context
 	Key     string
+	AAD     string
 	Err     chan<- errors.Err
 	C       <-chan interface{}
 }
@@ -197,6 +283,19 @@ type SubscriptionManagerProps struct {
 	// stream of events so that the client can retrieve
 	// those events later on
 	MQueue mqueue.MQueue
+
+	// Bus is the bus on which the events of the subscriptions
+	// are published. If not set the events are only stored
+	// in MQueue
+	Bus EventBus
+
+	// Lag defines when the consumers of the subscriptions are
+	// lagging behind. If not set the lag is not checked
+	Lag SubscriptionLagProps
+
+	// Release if set is called to release the resources in the
+	// backend of a subscription closed by the manager
+	Release func(ctx context.Context, key string) errors.Err
 }
 
 // SubscriptionManager manages the lifetime
@@ -208,6 +307,9 @@ type SubscriptionManager struct {
 	req     chan interface{}
 	subs    map[string]*subscription
 	mqueue  mqueue.MQueue
+	bus     EventBus
+	lag     SubscriptionLagProps
+	release func(ctx context.Context, key string) errors.Err
 	metrics SubscriptionMetrics
 }
 
@@ -215,10 +317,16 @@ type SubscriptionMetrics struct {
 	SubscriptionCount      uint64
 	SubscriptionCurrent    uint64
 	TotalSubscriptionCount uint64
+	UnknownRequestCount    uint64
 }
 
 // NewSubscriptionManager creates a new subscription manager
 func NewSubscriptionManager(props SubscriptionManagerProps) *SubscriptionManager {
+	bus := props.Bus
+	if bus == nil {
+		bus = NewQueueBus(props.MQueue)
+	}
+
 	m := SubscriptionManager{
 		ctx:     props.Context,
 		logger:  props.Logger.ForClass("backend/core", "SubscriptionManager"),
@@ -226,6 +334,9 @@ func NewSubscriptionManager(props SubscriptionManagerProps) *SubscriptionManager
 		req:     make(chan interface{}),
 		subs:    make(map[string]*subscription),
 		mqueue:  props.MQueue,
+		bus:     bus,
+		lag:     props.Lag,
+		release: props.Release,
 		metrics: SubscriptionMetrics{},
 	}
 
@@ -257,7 +368,7 @@ func (m *SubscriptionManager) startLoop() {
 		case <-m.ctx.Done():
 			return
 		case ev := <-m.done:
-			m.remove(ev.Key)
+			m.end(ev)
 		case req := <-m.req:
 			m.handleRequest(req)
 		}
@@ -275,7 +386,12 @@ func (m *SubscriptionManager) handleRequest(req interface{}) {
 	case statsRequest:
 		m.stats(req)
 	default:
-		panic("received unknown request")
+		// the request cannot be answered since its type is not known,
+		// but that should not bring down the subscriptions
+		m.metrics.UnknownRequestCount++
+		m.logger.Warn(m.ctx, "received unexpected request", log.MapFields{
+			"call_type": "HandleRequestFailure",
+		}, errors.New(errors.ErrInternalError, concurrent.ErrUnexpectedRequest{Request: req}))
 	}
 }
 
@@ -286,6 +402,7 @@ func (m *SubscriptionManager) stats(req statsRequest) {
 		"subscriptionCount":      m.metrics.SubscriptionCount,
 		"totalSubscriptionCount": m.metrics.TotalSubscriptionCount,
 		"currentSubscriptions":   uint64(len(m.subs)),
+		"unknownRequestCount":    m.metrics.UnknownRequestCount,
 	}
 	close(req.Out)
 }
@@ -310,8 +427,11 @@ func (m *SubscriptionManager) create(req createSubscriptionRequest) {
 		Context: m.ctx,
 		Logger:  m.logger,
 		Key:     req.Key,
+		AAD:     req.AAD,
 		Done:    m.done,
 		MQueue:  m.mqueue,
+		Bus:     m.bus,
+		Lag:     m.lag,
 		C:       req.C,
 	})
 
@@ -336,6 +456,33 @@ func (m *SubscriptionManager) destroy(req destroySubscriptionRequest) {
 	req.Err <- nil
 }
 
+// end removes a subscription that ended on its own and
+// releases its resources in the backend
+func (m *SubscriptionManager) end(ev subscriptionEndEvent) {
+	if _, ok := m.subs[ev.Key]; !ok {
+		return
+	}
+
+	m.remove(ev.Key)
+	m.decrSubscriptions()
+
+	if m.release == nil {
+		return
+	}
+
+	// the backend is not called from the loop of the manager
+	// so that the loop is not blocked by it
+	go func() {
+		if err := m.release(m.ctx, ev.Key); err != nil {
+			m.logger.Warn(m.ctx, "failed to release subscription", log.MapFields{
+				"call_type": "ReleaseSubscriptionFailure",
+				"key":       ev.Key,
+				"err":       err.Error(),
+			})
+		}
+	}()
+}
+
 func (m *SubscriptionManager) remove(key string) {
 	_, ok := m.subs[key]
 	if !ok {
@@ -355,10 +502,12 @@ func (m *SubscriptionManager) Exists(
 	key string,
 ) bool {
 	out := make(chan bool)
-	m.req <- existsSubscriptionRequest{
+	if err := m.send(ctx, existsSubscriptionRequest{
 		Context: ctx,
 		Key:     key,
 		Out:     out,
+	}); err != nil {
+		return false
 	}
 	return <-out
 }
@@ -368,14 +517,18 @@ func (m *SubscriptionManager) Exists(
 func (m *SubscriptionManager) Create(
 	ctx context.Context,
 	key string,
+	aad string,
 	c chan interface{},
 ) errors.Err {
 	err := make(chan errors.Err)
-	m.req <- createSubscriptionRequest{
+	if err := m.send(ctx, createSubscriptionRequest{
 		Context: ctx,
 		Key:     key,
+		AAD:     aad,
 		C:       c,
 		Err:     err,
+	}); err != nil {
+		return err
 	}
 	return <-err
 }
@@ -387,10 +540,25 @@ func (m *SubscriptionManager) Destroy(
 	key string,
 ) errors.Err {
 	err := make(chan errors.Err)
-	m.req <- destroySubscriptionRequest{Context: ctx, Key: key, Err: err}
+	if err := m.send(ctx, destroySubscriptionRequest{Context: ctx, Key: key, Err: err}); err != nil {
+		return err
+	}
 	return <-err
 }
 
+// send passes a request to the loop of the manager. It fails if the
+// context of the request is done before the loop receives it. Once
+// received the request is always completed, so its response must
+// be waited for to know its outcome
+func (m *SubscriptionManager) send(ctx context.Context, req interface{}) errors.Err {
+	select {
+	case m.req <- req:
+		return nil
+	case <-ctx.Done():
+		return errors.New(errors.ErrRequestCancelled, ctx.Err())
+	}
+}
+
 func (m *SubscriptionManager) Stats() stats.Metrics {
 	out := make(chan stats.Metrics)
 	m.req <- statsRequest{Context: context.Background(), Out: out}
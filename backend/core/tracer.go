@@ -0,0 +1,17 @@
+package core
+
+import (
+	"context"
+
+	"github.com/oasislabs/oasis-gateway/errors"
+)
+
+// TransactionTracer is implemented by the clients of backends that can
+// re-execute a transaction to report how it was executed. It is meant
+// for developers to debug the transactions that failed
+type TransactionTracer interface {
+	// TraceTransaction re-executes the transaction with tracing enabled.
+	// If the node does not support tracing errors.ErrUnsupportedByBackend
+	// is returned
+	TraceTransaction(ctx context.Context, req TraceTransactionRequest) (TraceTransactionResponse, errors.Err)
+}
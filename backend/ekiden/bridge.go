@@ -0,0 +1,293 @@
+package ekiden
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/oasislabs/oasis-gateway/backend/core"
+	"github.com/oasislabs/oasis-gateway/concurrent"
+	"github.com/oasislabs/oasis-gateway/ekiden"
+	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/oasislabs/oasis-gateway/stats"
+)
+
+var watchRetryConfig = concurrent.RetryConfig{
+	Random:            true,
+	UnlimitedAttempts: true,
+	BaseExp:           2,
+	BaseTimeout:       100 * time.Millisecond,
+	MaxRetryTimeout:   10 * time.Second,
+}
+
+// BlockSource provides the blocks generated by a runtime and
+// the transactions of the batches they finalize
+type BlockSource interface {
+	WatchBlocks(ctx context.Context, req *ekiden.WatchBlocksRequest) (ekiden.BlockStream, error)
+	GetTransactions(ctx context.Context, req *ekiden.GetTransactionsRequest) (*ekiden.GetTransactionsResponse, error)
+}
+
+// BridgeServices are the services used by a Bridge
+type BridgeServices struct {
+	// Source is the runtime from which blocks are received
+	Source BlockSource
+
+	// Bus is where the events of the finalized transactions
+	// are published, so that they reach the session mqueues
+	Bus core.EventBus
+
+	// Epochs if set is notified of the epoch
+	// transitions of the runtime
+	Epochs EpochListener
+
+	Logger log.Logger
+}
+
+// BridgeProps are the properties used to create a Bridge
+type BridgeProps struct {
+	// RuntimeID is the ID of the runtime watched
+	RuntimeID []byte
+
+	// RetryConfig overrides the backoff used to open the block
+	// stream again after it fails. It is optional
+	RetryConfig *concurrent.RetryConfig
+}
+
+// Bridge consumes the stream of blocks of an ekiden runtime and
+// publishes an event for each tracked transaction once the batch
+// that contains it is finalized. Transactions are identified by the
+// hash of the raw payload submitted to the runtime
+type Bridge struct {
+	source      BlockSource
+	bus         core.EventBus
+	listener    EpochListener
+	logger      log.Logger
+	runtimeID   []byte
+	retryConfig concurrent.RetryConfig
+	supervisor  *concurrent.Supervisor
+
+	lock    sync.Mutex
+	pending map[common.Hash]core.Publication
+
+	// round is only accessed by the loop of the bridge
+	round    uint64
+	hasRound bool
+
+	blocks       stats.Counter
+	failedRounds stats.Counter
+	epochs       stats.Counter
+	published    stats.Counter
+	failed       stats.Counter
+}
+
+// NewBridge creates a new Bridge. The bridge does not
+// consume blocks until it is started
+func NewBridge(services BridgeServices, props BridgeProps) *Bridge {
+	config := watchRetryConfig
+	if props.RetryConfig != nil {
+		config = *props.RetryConfig
+	}
+
+	return &Bridge{
+		source:      services.Source,
+		bus:         services.Bus,
+		listener:    services.Epochs,
+		logger:      services.Logger.ForClass("backend/ekiden", "Bridge"),
+		runtimeID:   props.RuntimeID,
+		retryConfig: config,
+		supervisor:  concurrent.NewSupervisor(concurrent.DefaultSupervisorProps),
+		pending:     make(map[common.Hash]core.Publication),
+	}
+}
+
+// TransactionHash returns the hash that identifies a
+// raw transaction submitted to the runtime
+func TransactionHash(tx []byte) common.Hash {
+	return crypto.Keccak256Hash(tx)
+}
+
+func (b *Bridge) Name() string {
+	return "backend.ekiden.Bridge"
+}
+
+func (b *Bridge) Stats() stats.Metrics {
+	b.lock.Lock()
+	pending := len(b.pending)
+	b.lock.Unlock()
+
+	return stats.Metrics{
+		"pending":      pending,
+		"blocks":       b.blocks.Value(),
+		"failedRounds": b.failedRounds.Value(),
+		"epochs":       b.epochs.Value(),
+		"published":    b.published.Value(),
+		"failed":       b.failed.Value(),
+		"loopRestarts": b.supervisor.Restarts(),
+	}
+}
+
+// Track registers the publication for the raw transaction, which is
+// published once the transaction is part of a finalized batch
+func (b *Bridge) Track(tx []byte, pub core.Publication) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.pending[TransactionHash(tx)] = pub
+}
+
+// Untrack removes the publication of the raw transaction. It should
+// be called when the transaction could not be submitted
+func (b *Bridge) Untrack(tx []byte) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	delete(b.pending, TransactionHash(tx))
+}
+
+// take removes and returns the publication of the
+// transaction with the hash, if it is tracked
+func (b *Bridge) take(hash common.Hash) (core.Publication, bool) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	pub, ok := b.pending[hash]
+	if ok {
+		delete(b.pending, hash)
+	}
+
+	return pub, ok
+}
+
+// Start consumes the blocks of the runtime until the context is
+// cancelled. The stream is opened again if it fails
+func (b *Bridge) Start(ctx context.Context) {
+	b.supervisor.Go(ctx, b.loop, func(err error) {
+		if err != nil && ctx.Err() == nil {
+			b.logger.Error(ctx, "bridge stopped consuming blocks", log.MapFields{
+				"call_type": "BridgeLoopFailure",
+				"err":       err.Error(),
+			})
+		}
+	})
+}
+
+func (b *Bridge) loop(ctx context.Context) error {
+	for {
+		stream, err := b.watch(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		for {
+			ev, err := stream.Recv()
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+
+				b.logger.Warn(ctx, "block stream failed and is opened again", log.MapFields{
+					"call_type": "WatchBlocksFailure",
+					"err":       err.Error(),
+				})
+				break
+			}
+
+			b.handleBlock(ctx, ev)
+		}
+	}
+}
+
+// watch opens the block stream, retrying with backoff
+// until it succeeds or the context is cancelled
+func (b *Bridge) watch(ctx context.Context) (ekiden.BlockStream, error) {
+	v, err := concurrent.RetryWithConfig(ctx, concurrent.SupplierFunc(func() (interface{}, error) {
+		stream, err := b.source.WatchBlocks(ctx, &ekiden.WatchBlocksRequest{RuntimeID: b.runtimeID})
+		if err != nil {
+			b.logger.Warn(ctx, "failed to watch runtime blocks", log.MapFields{
+				"call_type": "WatchBlocksFailure",
+				"err":       err.Error(),
+			})
+			return nil, err
+		}
+
+		return stream, nil
+	}), b.retryConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return v.(ekiden.BlockStream), nil
+}
+
+func (b *Bridge) handleBlock(ctx context.Context, ev *ekiden.BlockEvent) {
+	header := ev.Block.Header
+	b.blocks.Incr()
+
+	// when the stream is opened again the last block
+	// received may be sent once more
+	if b.hasRound && header.Round <= b.round {
+		return
+	}
+	b.round = header.Round
+	b.hasRound = true
+
+	switch header.HeaderType {
+	case ekiden.HeaderTypeNormal:
+	case ekiden.HeaderTypeRoundFailed:
+		// the transactions of the batch stay pending, they may
+		// be included in the batch of a following round
+		b.failedRounds.Incr()
+		b.logger.Warn(ctx, "runtime round failed", log.MapFields{
+			"call_type": "RoundFailed",
+			"round":     header.Round,
+		})
+		return
+	case ekiden.HeaderTypeEpochTransition:
+		b.epochs.Incr()
+		if b.listener != nil {
+			b.listener.EpochTransition(ctx, header.Round)
+		}
+		return
+	default:
+		return
+	}
+
+	res, err := b.source.GetTransactions(ctx, &ekiden.GetTransactionsRequest{
+		RuntimeID: b.runtimeID,
+		Root:      header.IORoot,
+	})
+	if err != nil {
+		b.logger.Warn(ctx, "failed to retrieve the transactions of a block", log.MapFields{
+			"call_type": "GetTransactionsFailure",
+			"round":     header.Round,
+			"err":       err.Error(),
+		})
+		return
+	}
+
+	for _, tx := range res.Transactions {
+		pub, ok := b.take(TransactionHash(tx))
+		if !ok {
+			continue
+		}
+
+		if err := b.bus.Publish(ctx, pub); err != nil {
+			b.failed.Incr()
+			b.logger.Warn(ctx, "failed to publish event of finalized transaction", log.MapFields{
+				"call_type": "PublishFinalizedFailure",
+				"round":     header.Round,
+				"key":       pub.Key,
+				"offset":    pub.Offset,
+				"err":       err.Error(),
+			})
+			continue
+		}
+
+		b.published.Incr()
+	}
+}
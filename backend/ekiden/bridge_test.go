@@ -0,0 +1,190 @@
+package ekiden
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/oasislabs/oasis-gateway/backend/core"
+	"github.com/oasislabs/oasis-gateway/concurrent"
+	"github.com/oasislabs/oasis-gateway/ekiden"
+	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/stretchr/testify/assert"
+)
+
+var logger = log.NewLogrus(log.LogrusLoggerProperties{
+	Output: ioutil.Discard,
+})
+
+type chanStream chan *ekiden.BlockEvent
+
+func (s chanStream) Recv() (*ekiden.BlockEvent, error) {
+	ev, ok := <-s
+	if !ok {
+		return nil, errors.New("stream closed")
+	}
+	return ev, nil
+}
+
+type mockSource struct {
+	streams chan chanStream
+	batches map[string][][]byte
+}
+
+func (s *mockSource) WatchBlocks(ctx context.Context, req *ekiden.WatchBlocksRequest) (ekiden.BlockStream, error) {
+	select {
+	case stream := <-s.streams:
+		return stream, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (s *mockSource) GetTransactions(ctx context.Context, req *ekiden.GetTransactionsRequest) (*ekiden.GetTransactionsResponse, error) {
+	return &ekiden.GetTransactionsResponse{Transactions: s.batches[string(req.Root)]}, nil
+}
+
+type mockBus chan core.Publication
+
+func (b mockBus) Publish(ctx context.Context, pub core.Publication) error {
+	b <- pub
+	return nil
+}
+
+func (b mockBus) Subscribe(consumer core.Consumer) func() {
+	return func() {}
+}
+
+func newTestBridge(source *mockSource, bus mockBus) *Bridge {
+	return NewBridge(BridgeServices{
+		Source: source,
+		Bus:    bus,
+		Logger: logger,
+	}, BridgeProps{
+		RuntimeID: []byte("runtime"),
+		RetryConfig: &concurrent.RetryConfig{
+			UnlimitedAttempts: true,
+			BaseExp:           1,
+			BaseTimeout:       time.Millisecond,
+			MaxRetryTimeout:   time.Millisecond,
+		},
+	})
+}
+
+func block(round uint64, headerType ekiden.HeaderType, root string) *ekiden.BlockEvent {
+	return &ekiden.BlockEvent{Block: ekiden.Block{Header: ekiden.BlockHeader{
+		Round:      round,
+		HeaderType: headerType,
+		IORoot:     []byte(root),
+	}}}
+}
+
+func TestBridgePublishesFinalizedTransactions(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	source := &mockSource{
+		streams: make(chan chanStream, 1),
+		batches: map[string][][]byte{
+			"root1": {[]byte("tx1"), []byte("other")},
+			"root2": {[]byte("tx2")},
+		},
+	}
+	stream := make(chanStream, 4)
+	source.streams <- stream
+	bus := make(mockBus, 4)
+
+	bridge := newTestBridge(source, bus)
+	bridge.Track([]byte("tx1"), core.Publication{Key: "session1", Offset: 1,
+		Event: core.ExecuteServiceResponse{ID: 1}})
+	bridge.Track([]byte("tx2"), core.Publication{Key: "session2", Offset: 3,
+		Event: core.ExecuteServiceResponse{ID: 3}})
+	bridge.Start(ctx)
+
+	// the batch of a failed round is not finalized
+	stream <- block(1, ekiden.HeaderTypeRoundFailed, "root2")
+	stream <- block(2, ekiden.HeaderTypeNormal, "root1")
+	stream <- block(3, ekiden.HeaderTypeNormal, "root2")
+
+	assert.Equal(t, core.Publication{Key: "session1", Offset: 1,
+		Event: core.ExecuteServiceResponse{ID: 1}}, <-bus)
+	assert.Equal(t, core.Publication{Key: "session2", Offset: 3,
+		Event: core.ExecuteServiceResponse{ID: 3}}, <-bus)
+
+	metrics := bridge.Stats()
+	assert.Equal(t, 0, metrics["pending"])
+	assert.Equal(t, uint64(1), metrics["failedRounds"])
+}
+
+func TestBridgeReopensFailedStream(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	source := &mockSource{
+		streams: make(chan chanStream, 2),
+		batches: map[string][][]byte{"root": {[]byte("tx")}},
+	}
+	first := make(chanStream, 1)
+	second := make(chanStream, 2)
+	source.streams <- first
+	source.streams <- second
+	bus := make(mockBus, 2)
+
+	bridge := newTestBridge(source, bus)
+	bridge.Track([]byte("tx"), core.Publication{Key: "session", Offset: 1})
+	bridge.Start(ctx)
+
+	first <- block(1, ekiden.HeaderTypeNormal, "none")
+	close(first)
+
+	// the last block may be sent again when the stream is
+	// opened, in which case it is not handled twice
+	second <- block(1, ekiden.HeaderTypeNormal, "root")
+	second <- block(2, ekiden.HeaderTypeNormal, "root")
+
+	assert.Equal(t, core.Publication{Key: "session", Offset: 1}, <-bus)
+	assert.Equal(t, uint64(3), bridge.Stats()["blocks"])
+}
+
+func TestBridgeUntrack(t *testing.T) {
+	bridge := newTestBridge(&mockSource{}, make(mockBus))
+
+	bridge.Track([]byte("tx"), core.Publication{Key: "session"})
+	assert.Equal(t, 1, bridge.Stats()["pending"])
+
+	bridge.Untrack([]byte("tx"))
+	assert.Equal(t, 0, bridge.Stats()["pending"])
+}
+
+type epochListener chan uint64
+
+func (l epochListener) EpochTransition(ctx context.Context, round uint64) {
+	l <- round
+}
+
+func TestBridgeEpochTransition(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	source := &mockSource{streams: make(chan chanStream, 1)}
+	stream := make(chanStream, 2)
+	source.streams <- stream
+	listener := make(epochListener, 2)
+
+	bridge := NewBridge(BridgeServices{
+		Source: source,
+		Bus:    make(mockBus),
+		Epochs: listener,
+		Logger: logger,
+	}, BridgeProps{RuntimeID: []byte("runtime")})
+	bridge.Start(ctx)
+
+	stream <- block(1, ekiden.HeaderTypeEpochTransition, "")
+	stream <- block(2, ekiden.HeaderTypeEpochTransition, "")
+
+	assert.Equal(t, uint64(1), <-listener)
+	assert.Equal(t, uint64(2), <-listener)
+	assert.Equal(t, uint64(2), bridge.Stats()["epochs"])
+}
@@ -13,11 +13,16 @@ import (
 	"github.com/oasislabs/oasis-gateway/ekiden"
 	"github.com/oasislabs/oasis-gateway/errors"
 	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/oasislabs/oasis-gateway/proxy"
 	"github.com/oasislabs/oasis-gateway/stats"
 )
 
 type NodeProps struct {
 	URL string
+
+	// Proxy if set is used to reach the node. Otherwise the
+	// proxies defined in the environment are used
+	Proxy *proxy.Proxy
 }
 
 type ClientProps struct {
@@ -26,16 +31,25 @@ type ClientProps struct {
 	RuntimeProps    NodeProps
 	KeyManagerProps NodeProps
 	Logger          log.Logger
+
+	// KeyCache if set caches the public keys retrieved from the key
+	// manager. It needs to be notified of the epoch transitions of
+	// the runtime, such as by the Bridge
+	KeyCache *KeyCache
 }
 
 type Client struct {
 	runtime    *ekiden.Runtime
 	keyManager *ekiden.Enclave
+	keyCache   *KeyCache
 	runtimeID  []byte
 }
 
 func DialContext(ctx context.Context, props ClientProps) (*Client, errors.Err) {
-	runtime, err := ekiden.DialRuntimeContext(ctx, props.RuntimeProps.URL)
+	runtime, err := ekiden.DialRuntimeContext(ctx, &ekiden.RuntimeProps{
+		URL:   props.RuntimeProps.URL,
+		Proxy: props.RuntimeProps.Proxy,
+	})
 	if err != nil {
 		return nil, errors.New(errors.ErrEkidenDial, err)
 	}
@@ -43,6 +57,7 @@ func DialContext(ctx context.Context, props ClientProps) (*Client, errors.Err) {
 	keyManager, err := ekiden.DialEnclaveContext(ctx, &ekiden.EnclaveProps{
 		URL:      props.KeyManagerProps.URL,
 		Endpoint: "key-manager",
+		Proxy:    props.KeyManagerProps.Proxy,
 	})
 	if err != nil {
 		return nil, errors.New(errors.ErrEkidenDial, err)
@@ -51,6 +66,7 @@ func DialContext(ctx context.Context, props ClientProps) (*Client, errors.Err) {
 	return &Client{
 		runtime:    runtime,
 		keyManager: keyManager,
+		keyCache:   props.KeyCache,
 		runtimeID:  props.RuntimeID,
 	}, nil
 }
@@ -60,7 +76,13 @@ func (c *Client) Name() string {
 }
 
 func (c *Client) Stats() stats.Metrics {
-	return nil
+	if c.keyCache == nil {
+		return nil
+	}
+
+	return stats.Metrics{
+		"keyCache": c.keyCache.Stats(),
+	}
 }
 
 func (c *Client) GetCode(
@@ -86,14 +108,30 @@ func (c *Client) GetPublicKey(
 	var address ekiden.Address
 	copy(address[:], decoded)
 
-	_, err = c.keyManager.GetPublicKey(ctx, &ekiden.GetPublicKeyRequest{
+	if _, err := c.getPublicKey(ctx, address); err != nil {
+		return nil, errors.New(errors.ErrEkidenGetPublicKey, err)
+	}
+
+	return &core.GetPublicKeyResponse{}, nil
+}
+
+// getPublicKey retrieves the public key of the address from the
+// key cache or, if it is not cached, from the key manager
+func (c *Client) getPublicKey(ctx context.Context, address ekiden.Address) (*ekiden.GetPublicKeyResponse, error) {
+	res, epoch, ok := c.keyCache.Get(address)
+	if ok {
+		return res, nil
+	}
+
+	res, err := c.keyManager.GetPublicKey(ctx, &ekiden.GetPublicKeyRequest{
 		Address: address,
 	})
 	if err != nil {
-		return nil, errors.New(errors.ErrEkidenGetPublicKey, err)
+		return nil, err
 	}
 
-	return &core.GetPublicKeyResponse{}, nil
+	c.keyCache.Set(address, epoch, res)
+	return res, nil
 }
 
 func (c *Client) ExecuteService(
@@ -0,0 +1,137 @@
+package ekiden
+
+import (
+	"context"
+	"sync"
+
+	"github.com/oasislabs/oasis-gateway/ekiden"
+	"github.com/oasislabs/oasis-gateway/stats"
+)
+
+// DefaultKeyCacheMaxEntries is the number of public keys
+// cached when KeyCacheProps does not set it
+const DefaultKeyCacheMaxEntries = 4096
+
+// EpochListener is notified of the epoch transitions
+// observed in the blocks of the runtime
+type EpochListener interface {
+	EpochTransition(ctx context.Context, round uint64)
+}
+
+// KeyCacheProps are the properties used to create a KeyCache
+type KeyCacheProps struct {
+	// MaxEntries is the maximum number of public keys cached
+	MaxEntries int
+}
+
+// KeyCache caches the public keys retrieved from the key manager
+// by the address of the service. The keys of the services may be
+// rotated on an epoch transition, so the cache is cleared on each
+// transition observed from the runtime.
+//
+// A nil KeyCache is valid and does not cache any key
+type KeyCache struct {
+	maxEntries int
+
+	lock  sync.Mutex
+	epoch uint64
+	keys  map[ekiden.Address]*ekiden.GetPublicKeyResponse
+
+	hits          stats.Counter
+	misses        stats.Counter
+	invalidations stats.Counter
+}
+
+// NewKeyCache creates a new empty KeyCache
+func NewKeyCache(props KeyCacheProps) *KeyCache {
+	maxEntries := props.MaxEntries
+	if maxEntries <= 0 {
+		maxEntries = DefaultKeyCacheMaxEntries
+	}
+
+	return &KeyCache{
+		maxEntries: maxEntries,
+		keys:       make(map[ekiden.Address]*ekiden.GetPublicKeyResponse),
+	}
+}
+
+// Get returns the cached public key of the address, if any, along
+// with the current epoch of the cache. The epoch needs to be passed
+// to Set when the key is retrieved from the key manager
+func (c *KeyCache) Get(address ekiden.Address) (*ekiden.GetPublicKeyResponse, uint64, bool) {
+	if c == nil {
+		return nil, 0, false
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	res, ok := c.keys[address]
+	if ok {
+		c.hits.Incr()
+	} else {
+		c.misses.Incr()
+	}
+
+	return res, c.epoch, ok
+}
+
+// Set caches the public key of the address retrieved in the epoch.
+// If an epoch transition happened since, the key may have been
+// rotated and it is not cached
+func (c *KeyCache) Set(address ekiden.Address, epoch uint64, res *ekiden.GetPublicKeyResponse) {
+	if c == nil {
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if epoch != c.epoch {
+		return
+	}
+
+	if _, ok := c.keys[address]; !ok && len(c.keys) >= c.maxEntries {
+		// evict any key to make space, the cache only needs to
+		// keep the keys of the hot services
+		for evicted := range c.keys {
+			delete(c.keys, evicted)
+			break
+		}
+	}
+
+	c.keys[address] = res
+}
+
+// EpochTransition is the implementation of EpochListener for
+// KeyCache. It discards all the cached keys
+func (c *KeyCache) EpochTransition(ctx context.Context, round uint64) {
+	if c == nil {
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.epoch++
+	c.keys = make(map[ekiden.Address]*ekiden.GetPublicKeyResponse)
+	c.invalidations.Incr()
+}
+
+// Stats is the implementation of stats.Collector for KeyCache
+func (c *KeyCache) Stats() stats.Metrics {
+	if c == nil {
+		return nil
+	}
+
+	c.lock.Lock()
+	entries := len(c.keys)
+	c.lock.Unlock()
+
+	return stats.Metrics{
+		"entries":       entries,
+		"hits":          c.hits.Value(),
+		"misses":        c.misses.Value(),
+		"invalidations": c.invalidations.Value(),
+	}
+}
@@ -0,0 +1,65 @@
+package ekiden
+
+import (
+	"context"
+	"testing"
+
+	"github.com/oasislabs/oasis-gateway/ekiden"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyCacheNil(t *testing.T) {
+	var cache *KeyCache
+	cache.Set(ekiden.Address{1}, 0, &ekiden.GetPublicKeyResponse{})
+
+	_, _, ok := cache.Get(ekiden.Address{1})
+	assert.False(t, ok)
+}
+
+func TestKeyCacheGetSet(t *testing.T) {
+	cache := NewKeyCache(KeyCacheProps{})
+	res := &ekiden.GetPublicKeyResponse{Payload: []byte("key")}
+
+	_, epoch, ok := cache.Get(ekiden.Address{1})
+	assert.False(t, ok)
+
+	cache.Set(ekiden.Address{1}, epoch, res)
+	cached, _, ok := cache.Get(ekiden.Address{1})
+	assert.True(t, ok)
+	assert.Equal(t, res, cached)
+
+	metrics := cache.Stats()
+	assert.Equal(t, 1, metrics["entries"])
+	assert.Equal(t, uint64(1), metrics["hits"])
+	assert.Equal(t, uint64(1), metrics["misses"])
+}
+
+func TestKeyCacheEpochTransition(t *testing.T) {
+	cache := NewKeyCache(KeyCacheProps{})
+	res := &ekiden.GetPublicKeyResponse{Payload: []byte("key")}
+
+	_, epoch, _ := cache.Get(ekiden.Address{1})
+	cache.Set(ekiden.Address{1}, epoch, res)
+	cache.EpochTransition(context.Background(), 10)
+
+	_, _, ok := cache.Get(ekiden.Address{1})
+	assert.False(t, ok)
+
+	// a key retrieved before the transition may have been
+	// rotated so it is not cached
+	cache.Set(ekiden.Address{2}, epoch, res)
+	_, _, ok = cache.Get(ekiden.Address{2})
+	assert.False(t, ok)
+	assert.Equal(t, uint64(1), cache.Stats()["invalidations"])
+}
+
+func TestKeyCacheMaxEntries(t *testing.T) {
+	cache := NewKeyCache(KeyCacheProps{MaxEntries: 2})
+	res := &ekiden.GetPublicKeyResponse{}
+
+	for i := byte(0); i < 3; i++ {
+		cache.Set(ekiden.Address{i}, 0, res)
+	}
+
+	assert.Equal(t, 2, cache.Stats()["entries"])
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"crypto/ecdsa"
 	"fmt"
+	"math/big"
 	"net/url"
+	"time"
 
 	ethereum "github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
@@ -17,38 +19,102 @@ import (
 	"github.com/oasislabs/oasis-gateway/errors"
 	"github.com/oasislabs/oasis-gateway/eth"
 	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/oasislabs/oasis-gateway/proxy"
+	"github.com/oasislabs/oasis-gateway/slo"
 	"github.com/oasislabs/oasis-gateway/stats"
 	"github.com/oasislabs/oasis-gateway/tx"
 )
 
 const (
+	getChainHead       string = "GetChainHead"
 	getCode            string = "GetCode"
 	getExpiry          string = "GetExpiry"
 	getPublicKey       string = "GetPublicKey"
 	deployService      string = "DeployService"
 	executeService     string = "ExecuteService"
+	executeBatch       string = "ExecuteServiceBatch"
+	queryService       string = "QueryService"
+	resolve            string = "Resolve"
 	subscribeRequest   string = "SubscribeRequest"
+	traceTransaction   string = "TraceTransaction"
 	unsubscribeRequest string = "UnsubscribeRequest"
 )
 
 const StatusOK = 1
 
+// ErrReadOnly is returned when a transaction is requested from a
+// client that does not own a wallet
+var ErrReadOnly = stderr.New("client is read-only and cannot execute transactions")
+
 type executeTransactionRequest struct {
-	AAD     string
-	ID      uint64
-	Address string
-	Data    []byte
+	AAD      string
+	ID       uint64
+	Address  string
+	Data     []byte
+	Expiry   uint64
+	GasLimit uint64
+	GasPrice uint64
 }
 
 type executeTransactionResponse struct {
 	ID      uint64
 	Address string
 	Output  string
+	GasUsed uint64
 }
 
 type ClientProps struct {
 	PrivateKeys []*ecdsa.PrivateKey
+
+	// Accounts are the private keys of the wallets of each account,
+	// which only sign the transactions of the AADs in AccountAADs
+	Accounts    map[string][]*ecdsa.PrivateKey
+	AccountAADs map[string]string
 	URL         string
+	OutputMode  tx.OutputMode
+
+	// ResolverRegistry is the address of the on-chain registry used
+	// to resolve names to service addresses. If not set names
+	// are not resolved
+	ResolverRegistry string
+
+	// ReadOnly if set the client is created without an executor,
+	// so it can only be used for subscriptions and calls that do
+	// not require a transaction
+	ReadOnly bool
+
+	// Proxy used to reach http endpoints. If nil the proxies
+	// defined in the environment are used
+	Proxy *proxy.Proxy
+
+	// LogPollInterval is the interval at which new logs are polled
+	// for on http endpoints. If it is 0 eth.LogPollInterval is used
+	LogPollInterval time.Duration
+
+	// MaxTransactionLifetime is the maximum time a transaction can
+	// take to complete before it fails. If it is 0 transactions
+	// do not time out
+	MaxTransactionLifetime time.Duration
+
+	// CallTimeout defines how the timeouts of the calls to the node
+	// are derived from their latencies. If Max is 0 the calls do
+	// not time out
+	CallTimeout eth.AdaptiveTimeoutProps
+
+	// Scheduling defines the order in which the transactions of
+	// the AADs that share the same wallets are submitted
+	Scheduling tx.SchedulerProps
+
+	// Selection defines how the wallet that submits
+	// a transaction is chosen
+	Selection tx.WalletSelectorProps
+
+	// GasPrice defines how the gas price of the
+	// transactions is chosen
+	GasPrice tx.GasPriceProps
+
+	// GasLimit defines the gas limit of the transactions
+	GasLimit tx.GasLimitProps
 }
 
 type Client struct {
@@ -57,7 +123,16 @@ type Client struct {
 	client   eth.Client
 	executor *tx.Executor
 	subman   *eth.SubscriptionManager
+	resolver *RegistryResolver
 	tracker  *stats.MethodTracker
+
+	// reconnects notifies the reconnections of the client
+	// to the node
+	reconnects *reconnectNotifier
+
+	// capabilities are the features supported by the node. If
+	// nil they are unknown and all features are assumed supported
+	capabilities *eth.Capabilities
 }
 
 func (c *Client) Name() string {
@@ -65,15 +140,57 @@ func (c *Client) Name() string {
 }
 
 func (c *Client) Stats() stats.Metrics {
-	methodStats := c.tracker.Stats()
-	walletStats := c.executor.Stats()
-	return stats.Metrics{
-		"methods": methodStats,
-		"wallets": walletStats,
+	metrics := stats.Metrics{
+		"methods": c.tracker.Stats(),
 	}
+
+	if c.executor != nil {
+		metrics["wallets"] = c.executor.Stats()
+	}
+
+	if c.capabilities != nil {
+		metrics["capabilities"] = c.capabilities.Stats()
+	}
+
+	if collector, ok := c.client.(stats.Collector); ok {
+		metrics["node"] = collector.Stats()
+	}
+
+	metrics["subscriptions"] = c.subman.Stats()
+
+	return metrics
+}
+
+// checkSupported returns an error if the node is known not
+// to support the feature required by the API
+func (c *Client) checkSupported(api string, supported func(eth.Capabilities) bool) errors.Err {
+	if c.capabilities == nil || supported(*c.capabilities) {
+		return nil
+	}
+
+	return errors.New(errors.ErrUnsupportedByBackend,
+		fmt.Errorf("%s is not supported by the node", api))
+}
+
+func supportsPublicKeys(caps eth.Capabilities) bool { return caps.PublicKeys }
+func supportsExpiry(caps eth.Capabilities) bool     { return caps.Expiry }
+func supportsInvoke(caps eth.Capabilities) bool     { return caps.Invoke }
+func supportsTrace(caps eth.Capabilities) bool      { return caps.Trace }
+
+// Close stops the executor of the client and destroys the
+// private keys of its wallets
+func (c *Client) Close() error {
+	if c.executor == nil {
+		return nil
+	}
+
+	return c.executor.Close()
 }
 
 func (c *Client) Senders() []common.Address {
+	if c.executor == nil {
+		return nil
+	}
 	return c.executor.WalletAddresses
 }
 
@@ -126,6 +243,47 @@ func (c *Client) GetCode(
 	return v.(backend.GetCodeResponse), nil
 }
 
+func (c *Client) getChainHead(ctx context.Context) (backend.GetChainHeadResponse, errors.Err) {
+	c.logger.Debug(ctx, "", log.MapFields{
+		"call_type": "GetChainHeadAttempt",
+	})
+
+	head, err := c.client.ChainHead(ctx)
+	if err != nil {
+		err := errors.New(errors.ErrInternalError, stderr.Wrap(err, "failed to get chain head"))
+		c.logger.Debug(ctx, "client call failed", log.MapFields{
+			"call_type": "GetChainHeadFailure",
+		}, err)
+		return backend.GetChainHeadResponse{}, err
+	}
+
+	c.logger.Debug(ctx, "", log.MapFields{
+		"call_type":    "GetChainHeadSuccess",
+		"block_number": head.BlockNumber,
+		"syncing":      head.Syncing,
+	})
+
+	return backend.GetChainHeadResponse{
+		BlockNumber:  head.BlockNumber,
+		Timestamp:    head.Timestamp,
+		ChainID:      head.ChainID,
+		Syncing:      head.Syncing,
+		HighestBlock: head.HighestBlock,
+	}, nil
+}
+
+func (c *Client) GetChainHead(ctx context.Context) (backend.GetChainHeadResponse, errors.Err) {
+	v, err := c.tracker.Instrument(getChainHead, func() (interface{}, error) {
+		return c.getChainHead(ctx)
+	})
+
+	if err != nil {
+		return backend.GetChainHeadResponse{}, err.(errors.Err)
+	}
+
+	return v.(backend.GetChainHeadResponse), nil
+}
+
 func (c *Client) getExpiry(
 	ctx context.Context,
 	req backend.GetExpiryRequest,
@@ -139,6 +297,10 @@ func (c *Client) getExpiry(
 		return backend.GetExpiryResponse{}, err
 	}
 
+	if err := c.checkSupported("oasis_getExpiry", supportsExpiry); err != nil {
+		return backend.GetExpiryResponse{}, err
+	}
+
 	expiry, err := c.client.GetExpiry(ctx, common.HexToAddress(req.Address))
 	if err != nil {
 		err := errors.New(errors.ErrInternalError, stderr.Wrapf(err, "failed to get expiry for address %s", req.Address))
@@ -188,6 +350,10 @@ func (c *Client) getPublicKey(
 		return backend.GetPublicKeyResponse{}, err
 	}
 
+	if err := c.checkSupported("oasis_getPublicKey", supportsPublicKeys); err != nil {
+		return backend.GetPublicKeyResponse{}, err
+	}
+
 	pk, err := c.client.GetPublicKey(ctx, common.HexToAddress(req.Address))
 	if err != nil {
 		err := errors.New(errors.ErrInternalError, stderr.Wrapf(err, "failed to get public key for address %s", req.Address))
@@ -268,6 +434,7 @@ func (c *Client) deployService(
 		ID:      id,
 		Address: "",
 		Data:    data,
+		Expiry:  req.Expiry,
 	})
 	if err != nil {
 		return backend.DeployServiceResponse{}, err
@@ -276,6 +443,7 @@ func (c *Client) deployService(
 	return backend.DeployServiceResponse{
 		ID:      res.ID,
 		Address: res.Address,
+		GasUsed: res.GasUsed,
 	}, nil
 }
 
@@ -309,10 +477,13 @@ func (c *Client) executeService(
 	}
 
 	res, err := c.executeTransaction(ctx, executeTransactionRequest{
-		AAD:     req.AAD,
-		ID:      id,
-		Address: req.Address,
-		Data:    data,
+		AAD:      req.AAD,
+		ID:       id,
+		Address:  req.Address,
+		Data:     data,
+		Expiry:   req.Expiry,
+		GasLimit: req.GasLimit,
+		GasPrice: req.GasPrice,
 	})
 	if err != nil {
 		return backend.ExecuteServiceResponse{}, err
@@ -322,9 +493,161 @@ func (c *Client) executeService(
 		ID:      res.ID,
 		Address: res.Address,
 		Output:  res.Output,
+		GasUsed: res.GasUsed,
 	}, nil
 }
 
+func (c *Client) QueryService(
+	ctx context.Context,
+	req backend.QueryServiceRequest,
+) (backend.QueryServiceResponse, errors.Err) {
+	v, err := c.tracker.Instrument(queryService, func() (interface{}, error) {
+		return c.queryService(ctx, req)
+	})
+	if err != nil {
+		return backend.QueryServiceResponse{}, err.(errors.Err)
+	}
+
+	return v.(backend.QueryServiceResponse), nil
+}
+
+func (c *Client) queryService(
+	ctx context.Context,
+	req backend.QueryServiceRequest,
+) (backend.QueryServiceResponse, errors.Err) {
+	c.logger.Debug(ctx, "", log.MapFields{
+		"call_type": "QueryServiceAttempt",
+		"address":   req.Address,
+		"block":     req.Block,
+	})
+
+	if err := c.verifyAddress(req.Address); err != nil {
+		return backend.QueryServiceResponse{}, err
+	}
+
+	data, err := c.decodeBytes(req.Data)
+	if err != nil {
+		return backend.QueryServiceResponse{}, err
+	}
+
+	var block *big.Int
+	if req.Block > 0 {
+		block = new(big.Int).SetUint64(req.Block)
+	}
+
+	to := common.HexToAddress(req.Address)
+	p, derr := c.client.CallContract(ctx, ethereum.CallMsg{
+		To:   &to,
+		Data: data,
+	}, block)
+	if derr != nil {
+		err := errors.New(errors.ErrInternalError, stderr.Wrapf(derr, "failed to query service %s", req.Address))
+		c.logger.Debug(ctx, "client call failed", log.MapFields{
+			"call_type": "QueryServiceFailure",
+			"address":   req.Address,
+		}, err)
+		return backend.QueryServiceResponse{}, err
+	}
+
+	c.logger.Debug(ctx, "", log.MapFields{
+		"call_type": "QueryServiceSuccess",
+		"address":   req.Address,
+	})
+
+	return backend.QueryServiceResponse{
+		Address: req.Address,
+		Output:  hexutil.Encode(p),
+	}, nil
+}
+
+// Resolve resolves a name to a service address using the on-chain
+// registry, if one is configured
+func (c *Client) Resolve(ctx context.Context, name string) (string, errors.Err) {
+	if c.resolver == nil {
+		return "", errors.New(errors.ErrNameNotResolved, nil)
+	}
+
+	v, err := c.tracker.Instrument(resolve, func() (interface{}, error) {
+		return c.resolver.Resolve(ctx, name)
+	})
+	if err != nil {
+		return "", err.(errors.Err)
+	}
+
+	return v.(string), nil
+}
+
+func (c *Client) traceTransaction(
+	ctx context.Context,
+	req backend.TraceTransactionRequest,
+) (backend.TraceTransactionResponse, errors.Err) {
+	c.logger.Debug(ctx, "", log.MapFields{
+		"call_type": "TraceTransactionAttempt",
+		"hash":      req.Hash,
+	})
+
+	if err := c.checkSupported("debug_traceTransaction", supportsTrace); err != nil {
+		return backend.TraceTransactionResponse{}, err
+	}
+
+	p, derr := hexutil.Decode(req.Hash)
+	if derr != nil || len(p) != common.HashLength {
+		return backend.TraceTransactionResponse{}, errors.New(errors.ErrInvalidTransactionHash, derr)
+	}
+
+	trace, err := c.client.TraceTransaction(ctx, common.BytesToHash(p))
+	if err != nil {
+		err := errors.New(errors.ErrTraceTransaction, stderr.Wrapf(err, "failed to trace transaction %s", req.Hash))
+		c.logger.Debug(ctx, "client call failed", log.MapFields{
+			"call_type": "TraceTransactionFailure",
+			"hash":      req.Hash,
+		}, err)
+		return backend.TraceTransactionResponse{}, err
+	}
+
+	steps := make([]backend.TraceStep, 0, len(trace.StructLogs))
+	for _, l := range trace.StructLogs {
+		steps = append(steps, backend.TraceStep{
+			Pc:      l.Pc,
+			Op:      l.Op,
+			Gas:     l.Gas,
+			GasCost: l.GasCost,
+			Depth:   l.Depth,
+			Error:   l.Error,
+		})
+	}
+
+	c.logger.Debug(ctx, "", log.MapFields{
+		"call_type": "TraceTransactionSuccess",
+		"hash":      req.Hash,
+	})
+
+	return backend.TraceTransactionResponse{
+		Hash:        req.Hash,
+		Failed:      trace.Failed,
+		Gas:         trace.Gas,
+		ReturnValue: trace.ReturnValue,
+		Steps:       steps,
+	}, nil
+}
+
+// TraceTransaction re-executes a transaction with the debug_traceTransaction
+// API of the node, which is only available on nodes that expose it
+func (c *Client) TraceTransaction(
+	ctx context.Context,
+	req backend.TraceTransactionRequest,
+) (backend.TraceTransactionResponse, errors.Err) {
+	v, err := c.tracker.Instrument(traceTransaction, func() (interface{}, error) {
+		return c.traceTransaction(ctx, req)
+	})
+
+	if err != nil {
+		return backend.TraceTransactionResponse{}, err.(errors.Err)
+	}
+
+	return v.(backend.TraceTransactionResponse), nil
+}
+
 func (c *Client) SubscribeRequest(
 	ctx context.Context,
 	req backend.CreateSubscriptionRequest,
@@ -359,11 +682,18 @@ func (c *Client) subscribeRequest(
 		addresses = []common.Address{common.HexToAddress(req.Address)}
 	}
 
+	var backfill *big.Int
+	if req.FromBlock != nil {
+		backfill = new(big.Int).SetUint64(*req.FromBlock)
+	}
+
 	if err := c.subman.Create(ctx, req.SubID, &eth.LogSubscriber{
 		FilterQuery: ethereum.FilterQuery{
 			Addresses: addresses,
 			Topics:    topics,
 		},
+		Backfill: backfill,
+		Metrics:  c.subman.Metrics(),
 	}, ch); err != nil {
 		err := errors.New(errors.ErrInternalError, err)
 		c.logger.Debug(ctx, "failed to create subscription", log.MapFields{
@@ -405,6 +735,112 @@ func (c *Client) unsubscribeRequest(
 	return nil
 }
 
+// ExecuteServiceBatch is the implementation of core.BatchExecutor for
+// Client. The calls are executed by the same wallet with consecutive
+// nonces, so that they do not wait for each other to be mined
+func (c *Client) ExecuteServiceBatch(
+	ctx context.Context,
+	ids []uint64,
+	reqs []backend.ExecuteServiceRequest,
+) ([]backend.ExecuteServiceBatchResult, errors.Err) {
+	v, err := c.tracker.Instrument(executeBatch, func() (interface{}, error) {
+		return c.executeServiceBatch(ctx, ids, reqs)
+	})
+	if err != nil {
+		return nil, err.(errors.Err)
+	}
+
+	return v.([]backend.ExecuteServiceBatchResult), nil
+}
+
+func (c *Client) executeServiceBatch(
+	ctx context.Context,
+	ids []uint64,
+	reqs []backend.ExecuteServiceRequest,
+) ([]backend.ExecuteServiceBatchResult, errors.Err) {
+	if c.executor == nil {
+		return nil, errors.New(errors.ErrAPINotImplemented, ErrReadOnly)
+	}
+
+	if err := c.checkSupported("oasis_invoke", supportsInvoke); err != nil {
+		return nil, err
+	}
+
+	// the calls that are invalid fail on their own
+	// without failing the rest of the batch
+	results := make([]backend.ExecuteServiceBatchResult, len(reqs))
+	batch := tx.ExecuteBatchRequest{Requests: make([]tx.ExecuteRequest, 0, len(reqs))}
+	indexes := make([]int, 0, len(reqs))
+	for i, req := range reqs {
+		batch.AAD = req.AAD
+		if err := c.verifyAddress(req.Address); err != nil {
+			results[i].Err = err
+			continue
+		}
+
+		data, err := c.decodeBytes(req.Data)
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+
+		if req.Expiry > 0 {
+			if err := c.checkSupported("transaction expiry", supportsExpiry); err != nil {
+				results[i].Err = err
+				continue
+			}
+		}
+
+		batch.Requests = append(batch.Requests, tx.ExecuteRequest{
+			AAD:      req.AAD,
+			ID:       ids[i],
+			Address:  req.Address,
+			Data:     data,
+			Expiry:   req.Expiry,
+			GasLimit: req.GasLimit,
+			GasPrice: req.GasPrice,
+		})
+		indexes = append(indexes, i)
+	}
+
+	if len(batch.Requests) == 0 {
+		return results, nil
+	}
+
+	res, err := c.executor.ExecuteBatch(ctx, batch)
+	if err != nil {
+		c.logger.Debug(ctx, "failure to execute transaction batch", log.MapFields{
+			"call_type": "ExecuteTransactionBatchFailure",
+			"id":        ids[0],
+			"calls":     len(reqs),
+		}, err)
+
+		return nil, err
+	}
+
+	for k, i := range indexes {
+		if res[k].Err != nil {
+			results[i].Err = res[k].Err
+			continue
+		}
+
+		results[i].Response = backend.ExecuteServiceResponse{
+			ID:      ids[i],
+			Address: res[k].Response.Address,
+			Output:  res[k].Response.Output,
+			GasUsed: res[k].Response.GasUsed,
+		}
+	}
+
+	c.logger.Debug(ctx, "transaction batch executed", log.MapFields{
+		"call_type": "ExecuteTransactionBatchSuccess",
+		"id":        ids[0],
+		"calls":     len(reqs),
+	})
+
+	return results, nil
+}
+
 func (c *Client) executeTransaction(
 	ctx context.Context,
 	req executeTransactionRequest,
@@ -415,11 +851,28 @@ func (c *Client) executeTransaction(
 		"executeAddress": req.Address,
 	})
 
+	if c.executor == nil {
+		return nil, errors.New(errors.ErrAPINotImplemented, ErrReadOnly)
+	}
+
+	if err := c.checkSupported("oasis_invoke", supportsInvoke); err != nil {
+		return nil, err
+	}
+
+	if req.Expiry > 0 {
+		if err := c.checkSupported("transaction expiry", supportsExpiry); err != nil {
+			return nil, err
+		}
+	}
+
 	res, err := c.executor.Execute(ctx, tx.ExecuteRequest{
-		AAD:     req.AAD,
-		ID:      req.ID,
-		Address: req.Address,
-		Data:    req.Data,
+		AAD:      req.AAD,
+		ID:       req.ID,
+		Address:  req.Address,
+		Data:     req.Data,
+		Expiry:   req.Expiry,
+		GasLimit: req.GasLimit,
+		GasPrice: req.GasPrice,
 	})
 	if err != nil {
 		c.logger.Debug(ctx, "failure to retrieve transaction receipt", log.MapFields{
@@ -442,6 +895,7 @@ func (c *Client) executeTransaction(
 		ID:      req.ID,
 		Address: res.Address,
 		Output:  res.Output,
+		GasUsed: res.GasUsed,
 	}, nil
 }
 
@@ -458,24 +912,62 @@ type ClientDeps struct {
 	Logger   log.Logger
 	Client   eth.Client
 	Executor *tx.Executor
+
+	// Resolver is an optional resolver for names to
+	// service addresses
+	Resolver *RegistryResolver
+
+	// Capabilities if set are the features supported by the
+	// node. APIs that require a feature that is not supported
+	// fail without calling the node
+	Capabilities *eth.Capabilities
+
+	// Callbacks if set are used to notify the operator when the
+	// client reconnects to the node
+	Callbacks callback.Calls
+
+	// reconnects if set is the notifier the dialer of the
+	// client reports its reconnections to
+	reconnects *reconnectNotifier
 }
 
 type ClientServices struct {
 	Logger    log.Logger
 	Callbacks callback.Calls
+
+	// Registry if set is where the client registers the
+	// collectors of the components it constructs
+	Registry *stats.Registry
+
+	// Slow are the thresholds above which the transactions are
+	// logged with the time taken by each of their stages
+	Slow slo.SlowProps
 }
 
 func NewClientWithDeps(ctx context.Context, deps *ClientDeps) *Client {
+	reconnects := deps.reconnects
+	if reconnects == nil {
+		reconnects = newReconnectNotifier(ctx, deps.Logger, deps.Callbacks)
+	}
 
 	return &Client{
-		ctx:      ctx,
-		logger:   deps.Logger.ForClass("eth", "Client"),
-		client:   deps.Client,
-		executor: deps.Executor,
-		tracker: stats.NewMethodTracker(getPublicKey,
+		ctx:          ctx,
+		logger:       deps.Logger.ForClass("eth", "Client"),
+		client:       deps.Client,
+		executor:     deps.Executor,
+		resolver:     deps.Resolver,
+		capabilities: deps.Capabilities,
+		reconnects:   reconnects,
+		tracker: stats.NewLabeledMethodTracker(stats.Labels{"backend": "eth"},
+			getChainHead,
+			getPublicKey,
 			deployService,
 			executeService,
+			executeBatch,
+			queryService,
+			resolve,
 			subscribeRequest,
+			traceTransaction,
 			unsubscribeRequest),
 		subman: eth.NewSubscriptionManager(eth.SubscriptionManagerProps{
 			Context: ctx,
@@ -485,6 +977,42 @@ func NewClientWithDeps(ctx context.Context, deps *ClientDeps) *Client {
 	}
 }
 
+// capabilitiesProbeTimeout is the maximum time the client waits
+// for the node to report its capabilities at startup
+const capabilitiesProbeTimeout = 10 * time.Second
+
+// probeCapabilities queries the node for the features it supports.
+// If the node cannot be reached the capabilities are unknown and
+// nil is returned, so that the client assumes all are supported
+func probeCapabilities(ctx context.Context, logger log.Logger, client *eth.PooledClient) *eth.Capabilities {
+	ctx, cancel := context.WithTimeout(ctx, capabilitiesProbeTimeout)
+	defer cancel()
+
+	caps, err := client.ProbeCapabilities(ctx)
+	if err != nil {
+		logger.Warn(ctx, "failed to probe node capabilities, assuming all are supported", log.MapFields{
+			"call_type": "ProbeCapabilitiesFailure",
+			"err":       err.Error(),
+		})
+		return nil
+	}
+
+	logger.Info(ctx, "probed node capabilities", log.MapFields{
+		"call_type": "ProbeCapabilitiesSuccess",
+	}, caps)
+	return &caps
+}
+
+const (
+	// defaultTimeoutWindowSize is the number of latest latencies
+	// of each method used to derive its timeout
+	defaultTimeoutWindowSize = 256
+
+	// defaultTimeoutMinSamples is the number of latencies a method
+	// needs before its timeout is derived from them
+	defaultTimeoutMinSamples = 32
+)
+
 func DialContext(ctx context.Context, services *ClientServices, props *ClientProps) (*Client, error) {
 	if len(props.URL) == 0 {
 		return nil, stderr.New("no url provided for eth client")
@@ -495,28 +1023,83 @@ func DialContext(ctx context.Context, services *ClientServices, props *ClientPro
 		return nil, stderr.New(fmt.Sprintf("Failed to parse url %s", err.Error()))
 	}
 
-	if url.Scheme != "wss" && url.Scheme != "ws" {
-		return nil, stderr.New("Only schemes supported are ws and wss")
+	switch url.Scheme {
+	case "ws", "wss", "http", "https":
+	default:
+		return nil, stderr.New("Only schemes supported are ws, wss, http and https")
+	}
+
+	reconnects := newReconnectNotifier(ctx, services.Logger, services.Callbacks)
+	dialer := eth.NewUniDialerWithProps(ctx, eth.UniDialerProps{
+		URL:          props.URL,
+		Proxy:        props.Proxy,
+		PollInterval: props.LogPollInterval,
+		OnReconnect:  reconnects.notify,
+	})
+	var timeout *eth.AdaptiveTimeout
+	if props.CallTimeout.Max > 0 {
+		timeoutProps := props.CallTimeout
+		timeoutProps.WindowSize = defaultTimeoutWindowSize
+		timeoutProps.MinSamples = defaultTimeoutMinSamples
+		timeout = eth.NewAdaptiveTimeout(timeoutProps)
 	}
 
-	dialer := eth.NewUniDialer(ctx, props.URL)
 	client := eth.NewPooledClient(eth.PooledClientProps{
 		Pool:        dialer,
 		RetryConfig: concurrent.RandomConfig,
+		Timeout:     timeout,
 	})
 
-	executor, err := tx.NewExecutor(ctx, &tx.ExecutorServices{
-		Logger:    services.Logger,
-		Client:    client,
-		Callbacks: services.Callbacks,
-	}, &tx.ExecutorProps{PrivateKeys: props.PrivateKeys})
-	if err != nil {
-		return nil, err
+	// the capabilities are probed before the executor is created,
+	// since they decide whether it submits dynamic fee transactions
+	caps := probeCapabilities(ctx, services.Logger, client)
+	var dynamicFeeChainID uint64
+	if caps != nil && caps.DynamicFees {
+		dynamicFeeChainID = caps.ChainID
+	}
+
+	var executor *tx.Executor
+	if !props.ReadOnly {
+		executor, err = tx.NewExecutor(ctx, &tx.ExecutorServices{
+			Logger:    services.Logger,
+			Client:    client,
+			Callbacks: services.Callbacks,
+		}, &tx.ExecutorProps{
+			PrivateKeys:       props.PrivateKeys,
+			Accounts:          props.Accounts,
+			AccountAADs:       props.AccountAADs,
+			OutputMode:        props.OutputMode,
+			MaxLifetime:       props.MaxTransactionLifetime,
+			Scheduling:        props.Scheduling,
+			Selection:         props.Selection,
+			GasPrice:          props.GasPrice,
+			GasLimit:          props.GasLimit,
+			Slow:              services.Slow,
+			DynamicFeeChainID: dynamicFeeChainID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := services.Registry.Register(executor.Name(), executor); err != nil {
+			_ = executor.Close()
+			return nil, err
+		}
+	}
+
+	var resolver *RegistryResolver
+	if len(props.ResolverRegistry) > 0 {
+		resolver = NewRegistryResolver(client, services.Logger, RegistryResolverProps{
+			Registry: common.HexToAddress(props.ResolverRegistry),
+		})
 	}
 
 	return NewClientWithDeps(ctx, &ClientDeps{
-		Logger:   services.Logger,
-		Client:   client,
-		Executor: executor,
+		Logger:       services.Logger,
+		Client:       client,
+		Executor:     executor,
+		Resolver:     resolver,
+		Capabilities: caps,
+		Callbacks:    services.Callbacks,
+		reconnects:   reconnects,
 	}), nil
 }
@@ -9,12 +9,14 @@ import (
 	"math/big"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/oasislabs/oasis-gateway/backend/core"
 	backend "github.com/oasislabs/oasis-gateway/backend/core"
 	"github.com/oasislabs/oasis-gateway/callback/callbacktest"
+	callback "github.com/oasislabs/oasis-gateway/callback/client"
 	"github.com/oasislabs/oasis-gateway/eth"
 	"github.com/oasislabs/oasis-gateway/eth/ethtest"
 	"github.com/oasislabs/oasis-gateway/log"
@@ -48,7 +50,7 @@ func NewClient() (*Client, error) {
 
 	callbacktest.ImplementMock(mockcallbacks)
 	mockclient.On("BalanceAt", mock.Anything, mock.Anything, mock.Anything).
-		Return(big.NewInt(1), nil)
+		Return(big.NewInt(1000000000000000000), nil)
 	mockclient.On("NonceAt", mock.Anything, mock.Anything).Return(uint64(0), nil)
 
 	executor, err := tx.NewExecutor(Context, &tx.ExecutorServices{
@@ -115,6 +117,38 @@ func TestGetCodeOK(t *testing.T) {
 	}, pk)
 }
 
+func TestGetChainHeadErr(t *testing.T) {
+	client, err := NewClient()
+	assert.Nil(t, err)
+
+	ethtest.ImplementMockWithOverwrite(client.client.(*ethtest.MockClient),
+		ethtest.MockMethods{
+			"ChainHead": ethtest.MockMethod{
+				Arguments: []interface{}{mock.Anything},
+				Return:    []interface{}{eth.ChainHead{}, errors.New("error")},
+			},
+		})
+
+	_, err = client.GetChainHead(Context)
+	assert.Error(t, err)
+	assert.Equal(t, "[1000] error code InternalError with desc Internal Error. Please check the status of the service. with cause failed to get chain head: error", err.Error())
+}
+
+func TestGetChainHeadOK(t *testing.T) {
+	client, err := NewClient()
+	assert.Nil(t, err)
+
+	ethtest.ImplementMock(client.client.(*ethtest.MockClient))
+
+	head, err := client.GetChainHead(Context)
+	assert.Nil(t, err)
+	assert.Equal(t, backend.GetChainHeadResponse{
+		BlockNumber: 1,
+		Timestamp:   1,
+		ChainID:     42261,
+	}, head)
+}
+
 func TestGetExpiryInvalidAddress(t *testing.T) {
 	client, err := NewClient()
 	assert.Nil(t, err)
@@ -287,6 +321,54 @@ func TestExecuteServiceOK(t *testing.T) {
 	}, res)
 }
 
+func TestExecuteServiceReadOnlyErr(t *testing.T) {
+	mockclient := &ethtest.MockClient{}
+	ethtest.ImplementMock(mockclient)
+	client := NewClientWithDeps(Context, &ClientDeps{
+		Logger: Logger,
+		Client: mockclient,
+	})
+
+	_, err := client.ExecuteService(Context, 1, backend.ExecuteServiceRequest{
+		Address: "0x5d352cf2160f79CBF3554534cF25A4b42C43D502",
+		Data:    "0x0000000000000000000000000000000000000000",
+	})
+
+	assert.Error(t, err)
+	assert.Equal(t, ErrReadOnly, err.Cause())
+	assert.Nil(t, client.Senders())
+}
+
+func TestGetPublicKeyUnsupportedErr(t *testing.T) {
+	client, err := NewClient()
+	assert.Nil(t, err)
+	client.capabilities = &eth.Capabilities{Invoke: true, Expiry: true}
+
+	_, err = client.GetPublicKey(Context, backend.GetPublicKeyRequest{
+		Address: "0x0000000000000000000000000000000000000000",
+	})
+
+	assert.Error(t, err)
+	assert.Equal(t, "[5002] error code Not Implemented with desc API is not supported by the backend node. with cause oasis_getPublicKey is not supported by the node", err.Error())
+	client.client.(*ethtest.MockClient).AssertNotCalled(t, "GetPublicKey", mock.Anything, mock.Anything)
+}
+
+func TestExecuteServiceExpiryUnsupportedErr(t *testing.T) {
+	client, err := NewClient()
+	assert.Nil(t, err)
+	client.capabilities = &eth.Capabilities{Invoke: true, PublicKeys: true}
+	ethtest.ImplementMock(client.client.(*ethtest.MockClient))
+
+	_, err = client.ExecuteService(Context, 1, backend.ExecuteServiceRequest{
+		Address: "0x5d352cf2160f79CBF3554534cF25A4b42C43D502",
+		Data:    "0x0000000000000000000000000000000000000000",
+		Expiry:  10,
+	})
+
+	assert.Error(t, err)
+	assert.Equal(t, "[5002] error code Not Implemented with desc API is not supported by the backend node. with cause transaction expiry is not supported by the node", err.Error())
+}
+
 func TestExecuteServiceEmptyAddressErr(t *testing.T) {
 	client, err := NewClient()
 	assert.Nil(t, err)
@@ -420,3 +502,108 @@ func TestSubscribeSubscriptionErr(t *testing.T) {
 	close(c)
 	client.client.(*ethtest.MockClient).AssertNumberOfCalls(t, "SubscribeFilterLogs", 2)
 }
+
+func TestQueryServiceOK(t *testing.T) {
+	client, err := NewClient()
+	assert.Nil(t, err)
+
+	ethtest.ImplementMockWithOverwrite(client.client.(*ethtest.MockClient),
+		ethtest.MockMethods{
+			"CallContract": ethtest.MockMethod{
+				Arguments: []interface{}{mock.Anything, mock.Anything, mock.Anything},
+				Return:    []interface{}{[]byte{0x02}, nil},
+			},
+		})
+
+	res, err := client.QueryService(Context, backend.QueryServiceRequest{
+		Address: "0x0000000000000000000000000000000000000001",
+		Data:    "0x01",
+		Block:   10,
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "0x0000000000000000000000000000000000000001", res.Address)
+	assert.Equal(t, "0x02", res.Output)
+	client.client.(*ethtest.MockClient).AssertCalled(t, "CallContract",
+		mock.Anything, mock.Anything, big.NewInt(10))
+}
+
+func TestQueryServiceInvalidAddress(t *testing.T) {
+	client, err := NewClient()
+	assert.Nil(t, err)
+
+	_, err = client.QueryService(Context, backend.QueryServiceRequest{
+		Address: "0x",
+		Data:    "0x01",
+	})
+	assert.Error(t, err)
+}
+
+func TestTraceTransactionOK(t *testing.T) {
+	client, err := NewClient()
+	assert.Nil(t, err)
+	ethtest.ImplementMock(client.client.(*ethtest.MockClient))
+
+	hash := "0x0000000000000000000000000000000000000000000000000000000000000001"
+	res, err := client.TraceTransaction(Context, backend.TraceTransactionRequest{Hash: hash})
+
+	assert.Nil(t, err)
+	assert.Equal(t, backend.TraceTransactionResponse{
+		Hash:        hash,
+		ReturnValue: "73756363657373",
+		Steps:       []backend.TraceStep{},
+	}, res)
+}
+
+func TestTraceTransactionUnsupportedErr(t *testing.T) {
+	client, err := NewClient()
+	assert.Nil(t, err)
+	client.capabilities = &eth.Capabilities{Invoke: true}
+
+	_, err = client.TraceTransaction(Context, backend.TraceTransactionRequest{
+		Hash: "0x0000000000000000000000000000000000000000000000000000000000000001",
+	})
+
+	assert.Error(t, err)
+	assert.Equal(t, "[5002] error code Not Implemented with desc API is not supported by the backend node. with cause debug_traceTransaction is not supported by the node", err.Error())
+	client.client.(*ethtest.MockClient).AssertNotCalled(t, "TraceTransaction", mock.Anything, mock.Anything)
+}
+
+func TestTraceTransactionInvalidHashErr(t *testing.T) {
+	client, err := NewClient()
+	assert.Nil(t, err)
+
+	_, err = client.TraceTransaction(Context, backend.TraceTransactionRequest{Hash: "0x01"})
+
+	assert.Error(t, err)
+	assert.Equal(t, "[2026] error code InputError with desc Provided invalid transaction hash.", err.Error())
+}
+
+func TestNotifyReconnect(t *testing.T) {
+	mockcallbacks := &callbacktest.MockClient{}
+	callbacktest.ImplementMock(mockcallbacks)
+	client := NewClientWithDeps(Context, &ClientDeps{
+		Logger:    Logger,
+		Client:    &ethtest.MockClient{},
+		Callbacks: mockcallbacks,
+	})
+
+	var reconnects []core.Reconnect
+	client.NotifyReconnect(func(ctx context.Context, r core.Reconnect) {
+		reconnects = append(reconnects, r)
+	})
+
+	client.reconnects.notify(eth.Reconnect{
+		DisconnectedAt: time.Unix(1, 0),
+		ReconnectedAt:  time.Unix(5, 0),
+	})
+
+	mockcallbacks.AssertCalled(t, "NodeReconnected", mock.Anything, callback.NodeReconnectedBody{
+		DisconnectedAt: 1000,
+		ReconnectedAt:  5000,
+	})
+	assert.Equal(t, []core.Reconnect{{
+		DisconnectedAt: time.Unix(1, 0),
+		ReconnectedAt:  time.Unix(5, 0),
+	}}, reconnects)
+}
@@ -0,0 +1,80 @@
+package eth
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	backend "github.com/oasislabs/oasis-gateway/backend/core"
+	callback "github.com/oasislabs/oasis-gateway/callback/client"
+	"github.com/oasislabs/oasis-gateway/eth"
+	"github.com/oasislabs/oasis-gateway/log"
+)
+
+// reconnectNotifier notifies the reconnections of the client to the
+// node to the operator with a callback and to the listeners
+// registered with the client
+type reconnectNotifier struct {
+	ctx       context.Context
+	logger    log.Logger
+	callbacks callback.Calls
+
+	lock      sync.Mutex
+	listeners []func(context.Context, backend.Reconnect)
+}
+
+func newReconnectNotifier(ctx context.Context, logger log.Logger, callbacks callback.Calls) *reconnectNotifier {
+	return &reconnectNotifier{
+		ctx:       ctx,
+		logger:    logger.ForClass("eth", "reconnectNotifier"),
+		callbacks: callbacks,
+	}
+}
+
+// add registers fn to be called on every reconnection
+func (n *reconnectNotifier) add(fn func(context.Context, backend.Reconnect)) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.listeners = append(n.listeners, fn)
+}
+
+// notify is called by the dialer when it reconnects to the node
+func (n *reconnectNotifier) notify(r eth.Reconnect) {
+	n.logger.Warn(n.ctx, "reconnected to the node, events may have been missed", log.MapFields{
+		"call_type":       "NodeReconnected",
+		"disconnected_at": r.DisconnectedAt.String(),
+		"reconnected_at":  r.ReconnectedAt.String(),
+	})
+
+	reconnect := backend.Reconnect{
+		DisconnectedAt: r.DisconnectedAt,
+		ReconnectedAt:  r.ReconnectedAt,
+	}
+
+	if n.callbacks != nil {
+		n.callbacks.NodeReconnected(n.ctx, callback.NodeReconnectedBody{
+			DisconnectedAt: unixMillis(r.DisconnectedAt),
+			ReconnectedAt:  unixMillis(r.ReconnectedAt),
+		})
+	}
+
+	n.lock.Lock()
+	listeners := make([]func(context.Context, backend.Reconnect), len(n.listeners))
+	copy(listeners, n.listeners)
+	n.lock.Unlock()
+
+	for _, fn := range listeners {
+		fn(n.ctx, reconnect)
+	}
+}
+
+// NotifyReconnect is the implementation of backend.ReconnectNotifier
+// for Client
+func (c *Client) NotifyReconnect(fn func(context.Context, backend.Reconnect)) {
+	c.reconnects.add(fn)
+}
+
+// unixMillis returns the time in milliseconds since the epoch
+func unixMillis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
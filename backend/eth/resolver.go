@@ -0,0 +1,101 @@
+package eth
+
+import (
+	"context"
+	"strings"
+
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	stderr "github.com/pkg/errors"
+
+	"github.com/oasislabs/oasis-gateway/errors"
+	"github.com/oasislabs/oasis-gateway/eth"
+	"github.com/oasislabs/oasis-gateway/log"
+)
+
+// addrSelector is the function selector for addr(bytes32) which
+// returns the address a node of the registry points to
+var addrSelector = crypto.Keccak256([]byte("addr(bytes32)"))[:4]
+
+// NameHash computes the ENS namehash of the provided name, which is the
+// node identifier used by the registry for that name
+func NameHash(name string) common.Hash {
+	var node common.Hash
+	if len(name) == 0 {
+		return node
+	}
+
+	labels := strings.Split(strings.ToLower(name), ".")
+	for i := len(labels) - 1; i >= 0; i-- {
+		label := crypto.Keccak256([]byte(labels[i]))
+		node = crypto.Keccak256Hash(node.Bytes(), label)
+	}
+
+	return node
+}
+
+// RegistryResolverProps are the properties used to create
+// a RegistryResolver
+type RegistryResolverProps struct {
+	// Registry is the address of the on-chain registry. The registry
+	// must implement addr(bytes32) returning the address of a node
+	Registry common.Address
+}
+
+// RegistryResolver resolves names to service addresses by querying
+// an ENS-style on-chain registry
+type RegistryResolver struct {
+	client   eth.Client
+	logger   log.Logger
+	registry common.Address
+}
+
+// NewRegistryResolver creates a new RegistryResolver
+func NewRegistryResolver(client eth.Client, logger log.Logger, props RegistryResolverProps) *RegistryResolver {
+	return &RegistryResolver{
+		client:   client,
+		logger:   logger.ForClass("eth", "RegistryResolver"),
+		registry: props.Registry,
+	}
+}
+
+// Resolve is the implementation of backend.AddressResolver for
+// RegistryResolver
+func (r *RegistryResolver) Resolve(ctx context.Context, name string) (string, errors.Err) {
+	node := NameHash(name)
+	data := make([]byte, 0, len(addrSelector)+common.HashLength)
+	data = append(data, addrSelector...)
+	data = append(data, node.Bytes()...)
+
+	p, err := r.client.CallContract(ctx, ethereum.CallMsg{
+		To:   &r.registry,
+		Data: data,
+	}, nil)
+	if err != nil {
+		err := errors.New(errors.ErrInternalError, stderr.Wrapf(err, "failed to resolve name %s", name))
+		r.logger.Debug(ctx, "registry call failed", log.MapFields{
+			"call_type": "ResolveFailure",
+			"name":      name,
+		}, err)
+		return "", err
+	}
+
+	if len(p) < common.HashLength {
+		return "", errors.New(errors.ErrNameNotResolved,
+			stderr.Errorf("registry returned %d bytes for name %s", len(p), name))
+	}
+
+	address := common.BytesToAddress(p[:common.HashLength])
+	if address == (common.Address{}) {
+		return "", errors.New(errors.ErrNameNotResolved, nil)
+	}
+
+	r.logger.Debug(ctx, "", log.MapFields{
+		"call_type": "ResolveSuccess",
+		"name":      name,
+		"address":   address.Hex(),
+	})
+
+	return address.Hex(), nil
+}
@@ -0,0 +1,67 @@
+package eth
+
+import (
+	"bytes"
+	"testing"
+
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/oasislabs/oasis-gateway/errors"
+	"github.com/oasislabs/oasis-gateway/eth/ethtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newRegistryResolver(p []byte, err error) (*RegistryResolver, *ethtest.MockClient) {
+	mockclient := &ethtest.MockClient{}
+	ethtest.ImplementMockWithOverwrite(mockclient, ethtest.MockMethods{
+		"CallContract": {
+			Arguments: []interface{}{mock.Anything, mock.Anything, mock.Anything},
+			Return:    []interface{}{p, err},
+		},
+	})
+
+	return NewRegistryResolver(mockclient, Logger, RegistryResolverProps{
+		Registry: common.HexToAddress("0x0000000000000000000000000000000000000001"),
+	}), mockclient
+}
+
+func TestNameHash(t *testing.T) {
+	assert.Equal(t, common.Hash{}, NameHash(""))
+	assert.Equal(t, "0x93cdeb708b7545dc668eb9280176169d1c33cfd8ed6f04690a0bcc88a93fc4ae", NameHash("eth").Hex())
+	assert.Equal(t, "0xde9b09fd7c5f901e23a3f19fecc54828e9c848539801e86591bd9801b019f84f", NameHash("foo.eth").Hex())
+}
+
+func TestRegistryResolverResolveOK(t *testing.T) {
+	address := common.HexToAddress("0x0a51514857B379A521C580a10822Fd8A7aC491A0")
+	resolver, mockclient := newRegistryResolver(common.LeftPadBytes(address.Bytes(), 32), nil)
+
+	res, err := resolver.Resolve(Context, "foo.eth")
+	assert.Nil(t, err)
+	assert.Equal(t, address.Hex(), res)
+
+	node := NameHash("foo.eth")
+	mockclient.AssertCalled(t, "CallContract", mock.Anything,
+		mock.MatchedBy(func(msg ethereum.CallMsg) bool {
+			return *msg.To == resolver.registry &&
+				bytes.Equal(msg.Data[:4], addrSelector) &&
+				bytes.Equal(msg.Data[4:], node.Bytes())
+		}), mock.Anything)
+}
+
+func TestRegistryResolverResolveErrZeroAddress(t *testing.T) {
+	resolver, _ := newRegistryResolver(make([]byte, 32), nil)
+
+	_, err := resolver.Resolve(Context, "foo.eth")
+	assert.Error(t, err)
+	assert.Equal(t, errors.ErrNameNotResolved, err.ErrorCode())
+}
+
+func TestClientResolveErrNoRegistry(t *testing.T) {
+	client, err := NewClient()
+	assert.Nil(t, err)
+
+	_, xerr := client.Resolve(Context, "foo.eth")
+	assert.Error(t, xerr)
+	assert.Equal(t, errors.ErrNameNotResolved, xerr.ErrorCode())
+}
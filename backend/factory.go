@@ -5,23 +5,63 @@ import (
 	"crypto/ecdsa"
 	"fmt"
 
-	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/oasislabs/oasis-gateway/backend/core"
 	"github.com/oasislabs/oasis-gateway/backend/eth"
+	"github.com/oasislabs/oasis-gateway/backend/sim"
 	callback "github.com/oasislabs/oasis-gateway/callback/client"
+	ethclient "github.com/oasislabs/oasis-gateway/eth"
 	"github.com/oasislabs/oasis-gateway/log"
 	mqueue "github.com/oasislabs/oasis-gateway/mqueue/core"
+	"github.com/oasislabs/oasis-gateway/slo"
+	"github.com/oasislabs/oasis-gateway/stats"
+	"github.com/oasislabs/oasis-gateway/tx"
 )
 
 type Deps struct {
 	Logger log.Logger
 	MQueue mqueue.MQueue
 	Client core.Client
+
+	// DeployDedup enables the deduplication of identical
+	// deployments issued by the same AAD
+	DeployDedup bool
+
+	// Idempotency defines when the idempotency keys
+	// provided by the clients expire
+	Idempotency core.IdempotencyRegistryProps
+
+	// Resolver is an optional resolver for names used as
+	// service addresses
+	Resolver core.AddressResolver
+
+	// QueryCache defines how the results of read-only
+	// queries are cached
+	QueryCache core.QueryCacheProps
+
+	// Quota defines when sessions are warned that they
+	// approach their quotas
+	Quota core.QuotaProps
+
+	// Output defines when the outputs of the executions
+	// are truncated
+	Output core.OutputStoreProps
+
+	// Subscription defines when the consumers of the
+	// subscriptions are lagging behind
+	Subscription core.SubscriptionLagProps
 }
 
 type ClientServices struct {
 	Logger    log.Logger
 	Callbacks callback.Calls
+
+	// Registry if set is where the backend registers the
+	// collectors of its internal components
+	Registry *stats.Registry
+
+	// Slow are the thresholds above which the transactions are
+	// logged with the time taken by each of their stages
+	Slow slo.SlowProps
 }
 
 type ClientFactory interface {
@@ -46,9 +86,16 @@ func (f RequestManagerFactoryFunc) New(ctx context.Context, deps *Deps) (*core.R
 
 var NewRequestManagerWithDeps = RequestManagerFactoryFunc(func(ctx context.Context, deps *Deps) (*core.RequestManager, error) {
 	return core.NewRequestManager(core.RequestManagerProperties{
-		MQueue: deps.MQueue,
-		Client: deps.Client,
-		Logger: deps.Logger,
+		MQueue:       deps.MQueue,
+		Client:       deps.Client,
+		Logger:       deps.Logger,
+		DeployDedup:  deps.DeployDedup,
+		Idempotency:  deps.Idempotency,
+		Resolver:     deps.Resolver,
+		QueryCache:   deps.QueryCache,
+		Quota:        deps.Quota,
+		Output:       deps.Output,
+		Subscription: deps.Subscription,
 	}), nil
 })
 
@@ -58,9 +105,15 @@ var NewBackendClient = ClientFactoryFunc(func(ctx context.Context, services *Cli
 		return NewEthClient(ctx, &eth.ClientServices{
 			Logger:    services.Logger,
 			Callbacks: services.Callbacks,
-		}, config.BackendConfig.(*EthereumConfig))
+			Registry:  services.Registry,
+			Slow:      services.Slow,
+		}, config.BackendConfig.(*EthereumConfig), config.ReadOnly)
 	case BackendEkiden:
 		return nil, ErrEkidenBackendNotImplemented
+	case BackendSimulated:
+		return sim.NewClient(services.Logger, sim.ClientProps{
+			Latency: config.BackendConfig.(*SimulatedConfig).Latency,
+		}), nil
 	default:
 		return nil, ErrUnknownBackend{Backend: config.Provider.String()}
 	}
@@ -70,11 +123,23 @@ func NewEthClientWithDeps(ctx context.Context, deps *eth.ClientDeps) (*eth.Clien
 	return eth.NewClientWithDeps(ctx, deps), nil
 }
 
-func NewEthClient(ctx context.Context, services *eth.ClientServices, config *EthereumConfig) (*eth.Client, error) {
+func NewEthClient(
+	ctx context.Context,
+	services *eth.ClientServices,
+	config *EthereumConfig,
+	readOnly bool,
+) (*eth.Client, error) {
+	if !readOnly && len(config.WalletConfig.PrivateKeys) == 0 {
+		return nil, fmt.Errorf("eth.wallet.private_keys must be set")
+	}
+
+	// the keys are only kept by the executor once parsed
+	defer config.WalletConfig.ClearKeys()
+
 	var privateKeys []*ecdsa.PrivateKey
 
 	for _, key := range config.WalletConfig.PrivateKeys {
-		privateKey, err := crypto.HexToECDSA(key)
+		privateKey, err := tx.ParsePrivateKey(key)
 		if err != nil {
 			return nil, fmt.Errorf("failed to read private key with error %s", err.Error())
 		}
@@ -82,9 +147,59 @@ func NewEthClient(ctx context.Context, services *eth.ClientServices, config *Eth
 		privateKeys = append(privateKeys, privateKey)
 	}
 
+	accounts := make(map[string][]*ecdsa.PrivateKey, len(config.WalletConfig.Accounts))
+	for name, keys := range config.WalletConfig.Accounts {
+		for _, key := range keys {
+			privateKey, err := tx.ParsePrivateKey(key)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read private key of account %s with error %s", name, err.Error())
+			}
+
+			accounts[name] = append(accounts[name], privateKey)
+		}
+	}
+
 	client, err := eth.DialContext(ctx, services, &eth.ClientProps{
-		PrivateKeys: privateKeys,
-		URL:         config.URL,
+		PrivateKeys:      privateKeys,
+		Accounts:         accounts,
+		AccountAADs:      config.WalletConfig.AccountAADs,
+		URL:              config.URL,
+		OutputMode:       config.OutputMode,
+		ResolverRegistry: config.ResolverRegistry,
+		ReadOnly:         readOnly,
+		Proxy:            config.Proxy,
+		LogPollInterval:  config.LogPollInterval,
+
+		MaxTransactionLifetime: config.MaxTransactionLifetime,
+		CallTimeout: ethclient.AdaptiveTimeoutProps{
+			Percentile: config.TimeoutConfig.Percentile,
+			Factor:     config.TimeoutConfig.Factor,
+			Min:        config.TimeoutConfig.Min,
+			Max:        config.TimeoutConfig.Max,
+		},
+		Scheduling: tx.SchedulerProps{
+			Policy:  config.SchedulingConfig.Policy,
+			Weights: config.SchedulingConfig.Weights,
+		},
+		Selection: tx.WalletSelectorProps{
+			Selection:    config.SelectionConfig.Selection,
+			DrainedRetry: config.SelectionConfig.DrainedRetry,
+		},
+		GasPrice: tx.GasPriceProps{
+			Mode:               config.GasPriceConfig.Mode,
+			Price:              config.GasPriceConfig.Price,
+			Percentile:         config.GasPriceConfig.Percentile,
+			Blocks:             config.GasPriceConfig.Blocks,
+			EscalationInterval: config.GasPriceConfig.EscalationInterval,
+			EscalationFactor:   config.GasPriceConfig.EscalationFactor,
+			Max:                config.GasPriceConfig.Max,
+			DynamicFees:        config.GasPriceConfig.DynamicFees,
+			PriorityFee:        config.GasPriceConfig.PriorityFee,
+		},
+		GasLimit: tx.GasLimitProps{
+			Confidential: config.GasLimitConfig.Confidential,
+			Max:          config.GasLimitConfig.Max,
+		},
 	})
 
 	if err != nil {
@@ -0,0 +1,286 @@
+// Package sim implements a backend that simulates the execution of
+// services in memory. It is only meant for local development, so that
+// the API of the gateway can be tried without a node
+package sim
+
+import (
+	"context"
+	"encoding/binary"
+	stderr "errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	backend "github.com/oasislabs/oasis-gateway/backend/core"
+	"github.com/oasislabs/oasis-gateway/errors"
+	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/oasislabs/oasis-gateway/stats"
+)
+
+// ErrServiceNotDeployed is returned when a request targets
+// a service that has not been deployed to the simulation
+var ErrServiceNotDeployed = stderr.New("service has not been deployed")
+
+// ClientProps are the properties used to create a Client
+type ClientProps struct {
+	// Latency is the time the simulated transactions take to
+	// complete, so that clients experience asynchronous results
+	Latency time.Duration
+}
+
+type subscription struct {
+	address string
+	c       chan<- interface{}
+}
+
+// Client is a backend.Client that keeps the deployed services in
+// memory. Executing or querying a service returns the data of the
+// request as output, and each execution emits a log with the same
+// data to the subscriptions for the address of the service
+type Client struct {
+	logger  log.Logger
+	latency time.Duration
+
+	lock          sync.Mutex
+	nonce         uint64
+	services      map[string]string
+	subscriptions map[string]subscription
+
+	deploys    stats.Counter
+	executions stats.Counter
+	queries    stats.Counter
+}
+
+// NewClient creates a new Client with no services deployed
+func NewClient(logger log.Logger, props ClientProps) *Client {
+	return &Client{
+		logger:        logger.ForClass("backend/sim", "Client"),
+		latency:       props.Latency,
+		services:      make(map[string]string),
+		subscriptions: make(map[string]subscription),
+	}
+}
+
+func (c *Client) Name() string {
+	return "backend.sim.Client"
+}
+
+func (c *Client) Stats() stats.Metrics {
+	c.lock.Lock()
+	services := len(c.services)
+	subscriptions := len(c.subscriptions)
+	c.lock.Unlock()
+
+	return stats.Metrics{
+		"services":      services,
+		"subscriptions": subscriptions,
+		"deploys":       c.deploys.Value(),
+		"executions":    c.executions.Value(),
+		"queries":       c.queries.Value(),
+	}
+}
+
+// Senders is the implementation of backend.Client for Client. The
+// simulation does not sign transactions so it has no senders
+func (c *Client) Senders() []common.Address {
+	return nil
+}
+
+// wait simulates the time a transaction takes to complete
+func (c *Client) wait(ctx context.Context) {
+	if c.latency <= 0 {
+		return
+	}
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(c.latency):
+	}
+}
+
+func (c *Client) code(address string) (string, errors.Err) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	code, ok := c.services[common.HexToAddress(address).Hex()]
+	if !ok {
+		return "", errors.New(errors.ErrInvalidAddress, ErrServiceNotDeployed)
+	}
+
+	return code, nil
+}
+
+func decode(data string) ([]byte, errors.Err) {
+	p, err := hexutil.Decode(data)
+	if err != nil {
+		return nil, errors.New(errors.ErrStringNotHex, err)
+	}
+
+	return p, nil
+}
+
+// GetChainHead is the implementation of backend.Client for Client. The
+// simulation produces a block for each deployment and execution of
+// a service, and it is always in sync
+func (c *Client) GetChainHead(ctx context.Context) (backend.GetChainHeadResponse, errors.Err) {
+	return backend.GetChainHeadResponse{
+		BlockNumber: c.deploys.Value() + c.executions.Value(),
+		Timestamp:   uint64(time.Now().Unix()),
+	}, nil
+}
+
+func (c *Client) GetCode(ctx context.Context, req backend.GetCodeRequest) (backend.GetCodeResponse, errors.Err) {
+	code, err := c.code(req.Address)
+	if err != nil {
+		return backend.GetCodeResponse{}, err
+	}
+
+	return backend.GetCodeResponse{Address: req.Address, Code: code}, nil
+}
+
+// GetExpiry is the implementation of backend.Client for Client.
+// Simulated services never expire
+func (c *Client) GetExpiry(ctx context.Context, req backend.GetExpiryRequest) (backend.GetExpiryResponse, errors.Err) {
+	if _, err := c.code(req.Address); err != nil {
+		return backend.GetExpiryResponse{}, err
+	}
+
+	return backend.GetExpiryResponse{Address: req.Address}, nil
+}
+
+// GetPublicKey is the implementation of backend.Client for Client. The
+// public key is derived from the address of the service and it is not
+// signed, so it must not be used to encrypt any real data
+func (c *Client) GetPublicKey(ctx context.Context, req backend.GetPublicKeyRequest) (backend.GetPublicKeyResponse, errors.Err) {
+	if _, err := c.code(req.Address); err != nil {
+		return backend.GetPublicKeyResponse{}, err
+	}
+
+	return backend.GetPublicKeyResponse{
+		Timestamp: uint64(time.Now().Unix()),
+		Address:   req.Address,
+		PublicKey: hexutil.Encode(crypto.Keccak256(common.HexToAddress(req.Address).Bytes())),
+	}, nil
+}
+
+func (c *Client) ExecuteService(ctx context.Context, id uint64, req backend.ExecuteServiceRequest) (backend.ExecuteServiceResponse, errors.Err) {
+	data, err := decode(req.Data)
+	if err != nil {
+		return backend.ExecuteServiceResponse{}, err
+	}
+
+	if _, err := c.code(req.Address); err != nil {
+		return backend.ExecuteServiceResponse{}, err
+	}
+
+	c.wait(ctx)
+	c.executions.Incr()
+	c.publish(types.Log{
+		Address: common.HexToAddress(req.Address),
+		Data:    data,
+	})
+
+	c.logger.Debug(ctx, "simulated service execution", log.MapFields{
+		"call_type": "ExecuteServiceSuccess",
+		"id":        id,
+		"address":   req.Address,
+	})
+
+	return backend.ExecuteServiceResponse{
+		ID:      id,
+		Address: req.Address,
+		Output:  req.Data,
+	}, nil
+}
+
+func (c *Client) DeployService(ctx context.Context, id uint64, req backend.DeployServiceRequest) (backend.DeployServiceResponse, errors.Err) {
+	data, err := decode(req.Data)
+	if err != nil {
+		return backend.DeployServiceResponse{}, err
+	}
+
+	c.wait(ctx)
+
+	c.lock.Lock()
+	var nonce [8]byte
+	binary.BigEndian.PutUint64(nonce[:], c.nonce)
+	c.nonce++
+	address := common.BytesToAddress(crypto.Keccak256(data, nonce[:])).Hex()
+	c.services[address] = req.Data
+	c.lock.Unlock()
+
+	c.deploys.Incr()
+	c.logger.Debug(ctx, "simulated service deployment", log.MapFields{
+		"call_type": "DeployServiceSuccess",
+		"id":        id,
+		"address":   address,
+	})
+
+	return backend.DeployServiceResponse{ID: id, Address: address}, nil
+}
+
+func (c *Client) QueryService(ctx context.Context, req backend.QueryServiceRequest) (backend.QueryServiceResponse, errors.Err) {
+	if _, err := decode(req.Data); err != nil {
+		return backend.QueryServiceResponse{}, err
+	}
+
+	if _, err := c.code(req.Address); err != nil {
+		return backend.QueryServiceResponse{}, err
+	}
+
+	c.queries.Incr()
+	return backend.QueryServiceResponse{Address: req.Address, Output: req.Data}, nil
+}
+
+// publish sends the log to the subscriptions for its address. Logs
+// are dropped for subscriptions that are not keeping up
+func (c *Client) publish(ev types.Log) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for _, sub := range c.subscriptions {
+		if len(sub.address) > 0 && common.HexToAddress(sub.address) != ev.Address {
+			continue
+		}
+
+		select {
+		case sub.c <- ev:
+		default:
+		}
+	}
+}
+
+func (c *Client) SubscribeRequest(ctx context.Context, req backend.CreateSubscriptionRequest, ch chan<- interface{}) errors.Err {
+	if req.Event != "logs" {
+		return errors.New(errors.ErrTopicLogsSupported, nil)
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if _, ok := c.subscriptions[req.SubID]; ok {
+		return errors.New(errors.ErrInternalError,
+			fmt.Errorf("subscription %s already exists", req.SubID))
+	}
+
+	c.subscriptions[req.SubID] = subscription{address: req.Address, c: ch}
+	return nil
+}
+
+func (c *Client) UnsubscribeRequest(ctx context.Context, req backend.DestroySubscriptionRequest) errors.Err {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	sub, ok := c.subscriptions[req.SubID]
+	if !ok {
+		return errors.New(errors.ErrSubscriptionNotFound, nil)
+	}
+
+	close(sub.c)
+	delete(c.subscriptions, req.SubID)
+	return nil
+}
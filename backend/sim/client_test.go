@@ -0,0 +1,133 @@
+package sim
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	backend "github.com/oasislabs/oasis-gateway/backend/core"
+	"github.com/oasislabs/oasis-gateway/errors"
+	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/stretchr/testify/assert"
+)
+
+var Context = context.TODO()
+
+var Logger = log.NewLogrus(log.LogrusLoggerProperties{
+	Output: ioutil.Discard,
+})
+
+func deploy(t *testing.T, c *Client) string {
+	res, err := c.DeployService(Context, 1, backend.DeployServiceRequest{Data: "0x0061736d01000000"})
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1), res.ID)
+	assert.True(t, common.IsHexAddress(res.Address))
+	return res.Address
+}
+
+func TestDeployService(t *testing.T) {
+	c := NewClient(Logger, ClientProps{})
+
+	address := deploy(t, c)
+	code, err := c.GetCode(Context, backend.GetCodeRequest{Address: address})
+	assert.Nil(t, err)
+	assert.Equal(t, "0x0061736d01000000", code.Code)
+
+	// the same code deployed twice is a different service
+	assert.NotEqual(t, address, deploy(t, c))
+}
+
+func TestGetChainHead(t *testing.T) {
+	c := NewClient(Logger, ClientProps{})
+	address := deploy(t, c)
+
+	_, err := c.ExecuteService(Context, 2, backend.ExecuteServiceRequest{Address: address, Data: "0x0102"})
+	assert.Nil(t, err)
+
+	head, err := c.GetChainHead(Context)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(2), head.BlockNumber)
+	assert.False(t, head.Syncing)
+}
+
+func TestDeployServiceNotHex(t *testing.T) {
+	c := NewClient(Logger, ClientProps{})
+
+	_, err := c.DeployService(Context, 1, backend.DeployServiceRequest{Data: "code"})
+	assert.Equal(t, errors.ErrStringNotHex.Code(), err.ErrorCode().Code())
+}
+
+func TestExecuteService(t *testing.T) {
+	c := NewClient(Logger, ClientProps{})
+	address := deploy(t, c)
+
+	res, err := c.ExecuteService(Context, 2, backend.ExecuteServiceRequest{Address: address, Data: "0x0102"})
+	assert.Nil(t, err)
+	assert.Equal(t, backend.ExecuteServiceResponse{ID: 2, Address: address, Output: "0x0102"}, res)
+}
+
+func TestExecuteServiceNotDeployed(t *testing.T) {
+	c := NewClient(Logger, ClientProps{})
+
+	_, err := c.ExecuteService(Context, 2, backend.ExecuteServiceRequest{
+		Address: "0x0000000000000000000000000000000000000001",
+		Data:    "0x0102",
+	})
+	assert.Equal(t, errors.ErrInvalidAddress.Code(), err.ErrorCode().Code())
+}
+
+func TestQueryService(t *testing.T) {
+	c := NewClient(Logger, ClientProps{})
+	address := deploy(t, c)
+
+	res, err := c.QueryService(Context, backend.QueryServiceRequest{Address: address, Data: "0x0102"})
+	assert.Nil(t, err)
+	assert.Equal(t, "0x0102", res.Output)
+}
+
+func TestSubscribeRequest(t *testing.T) {
+	c := NewClient(Logger, ClientProps{})
+	address := deploy(t, c)
+	other := deploy(t, c)
+
+	ch := make(chan interface{}, 2)
+	err := c.SubscribeRequest(Context, backend.CreateSubscriptionRequest{
+		Event:   "logs",
+		Address: address,
+		SubID:   "sub",
+	}, ch)
+	assert.Nil(t, err)
+
+	_, err = c.ExecuteService(Context, 2, backend.ExecuteServiceRequest{Address: other, Data: "0x01"})
+	assert.Nil(t, err)
+	_, err = c.ExecuteService(Context, 3, backend.ExecuteServiceRequest{Address: address, Data: "0x02"})
+	assert.Nil(t, err)
+
+	ev := <-ch
+	assert.Equal(t, types.Log{Address: common.HexToAddress(address), Data: []byte{2}}, ev)
+
+	err = c.UnsubscribeRequest(Context, backend.DestroySubscriptionRequest{SubID: "sub"})
+	assert.Nil(t, err)
+
+	_, ok := <-ch
+	assert.False(t, ok)
+}
+
+func TestSubscribeRequestNotLogs(t *testing.T) {
+	c := NewClient(Logger, ClientProps{})
+
+	err := c.SubscribeRequest(Context, backend.CreateSubscriptionRequest{
+		Event: "blocks",
+		SubID: "sub",
+	}, make(chan interface{}))
+	assert.Equal(t, errors.ErrTopicLogsSupported.Code(), err.ErrorCode().Code())
+}
+
+func TestUnsubscribeRequestNotFound(t *testing.T) {
+	c := NewClient(Logger, ClientProps{})
+
+	err := c.UnsubscribeRequest(Context, backend.DestroySubscriptionRequest{SubID: "sub"})
+	assert.Equal(t, errors.ErrSubscriptionNotFound.Code(), err.ErrorCode().Code())
+}
@@ -4,16 +4,41 @@ import (
 	"context"
 
 	callback "github.com/oasislabs/oasis-gateway/callback/client"
+	"github.com/oasislabs/oasis-gateway/tests/scenario"
 	"github.com/stretchr/testify/mock"
 )
 
+// MockClient is a mock of the callback client. If Scenario is set,
+// each call first plays the scenario, and the callbacks for which
+// the scenario scripts an error are dropped, as the callback client
+// does when it fails to deliver a callback
 type MockClient struct {
 	mock.Mock
+	Scenario *scenario.Player
 }
 
 func (c *MockClient) TransactionCommitted(
 	ctx context.Context,
 	body callback.TransactionCommittedBody,
+) {
+	if err := c.Scenario.Play(ctx, "TransactionCommitted"); err != nil {
+		return
+	}
+
+	if err := c.Scenario.Play(ctx, "WalletOutOfFunds"); err != nil {
+		return
+	}
+
+	if err := c.Scenario.Play(ctx, "WalletReachedFundsThreshold"); err != nil {
+		return
+	}
+
+	_ = c.Called(ctx, body)
+}
+
+func (c *MockClient) NodeReconnected(
+	ctx context.Context,
+	body callback.NodeReconnectedBody,
 ) {
 	_ = c.Called(ctx, body)
 }
@@ -33,7 +58,15 @@ func (c *MockClient) WalletReachedFundsThreshold(
 }
 
 func ImplementMock(client *MockClient) {
+	client.On("NodeReconnected", mock.Anything, mock.Anything).Return()
 	client.On("TransactionCommitted", mock.Anything, mock.Anything).Return()
 	client.On("WalletOutOfFunds", mock.Anything, mock.Anything).Return()
 	client.On("WalletReachedFundsThreshold", mock.Anything, mock.Anything).Return()
 }
+
+// ImplementMockWithScenario implements the mock and plays
+// the scenario on its calls
+func ImplementMockWithScenario(client *MockClient, player *scenario.Player) {
+	ImplementMock(client)
+	client.Scenario = player
+}
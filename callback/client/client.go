@@ -4,9 +4,12 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"math/big"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/oasislabs/oasis-gateway/concurrent"
@@ -28,6 +31,7 @@ type CallbackProps struct {
 
 // Calls are all the callbacks that the client implements
 type Calls interface {
+	NodeReconnected(ctx context.Context, body NodeReconnectedBody)
 	TransactionCommitted(ctx context.Context, body TransactionCommittedBody)
 	WalletOutOfFunds(ctx context.Context, body WalletOutOfFundsBody)
 	WalletReachedFundsThreshold(ctx context.Context, body WalletReachedFundsThresholdBody)
@@ -48,6 +52,7 @@ type WalletReachedFundsThresholdCallback struct {
 // client supports and the behaviour that the client
 // should have on those callbacks
 type Callbacks struct {
+	NodeReconnected             Callback
 	TransactionCommitted        Callback
 	WalletOutOfFunds            Callback
 	WalletReachedFundsThreshold WalletReachedFundsThresholdCallback
@@ -58,11 +63,28 @@ type Services struct {
 	Logger log.Logger
 }
 
+// DispatchProps define the pool of workers that deliver the
+// callbacks that are not sent synchronously
+type DispatchProps struct {
+	// Workers is the number of callbacks delivered concurrently
+	Workers uint8
+
+	// QueueSize is the number of callbacks each worker can have
+	// queued. Callbacks dispatched when the queue is full are
+	// dropped so that the caller is never blocked
+	QueueSize int
+}
+
 // Props are the properties that define
 // the behaviour of the client to send callbacks
 type Props struct {
 	Callbacks   Callbacks
 	RetryConfig concurrent.RetryConfig
+	Dispatch    DispatchProps
+
+	// Tenants route the callbacks triggered on behalf of
+	// their AADs to their own destinations
+	Tenants []Tenant
 }
 
 // Deps are the required instantiated dependencies
@@ -83,12 +105,20 @@ func NewClient(services *Services, props *Props) *Client {
 // NewClientWithDeps creates a new client using the external
 // dependencies provided
 func NewClientWithDeps(deps *Deps, props *Props) *Client {
+	tenants := make([]Tenant, len(props.Tenants))
+	copy(tenants, props.Tenants)
+
 	return &Client{
 		callbacks:   props.Callbacks,
+		tenants:     newTenants(tenants),
 		retryConfig: props.RetryConfig,
 		client:      deps.Client,
 		logger:      deps.Logger,
 		tracker:     stats.NewMethodTracker(walletOutOfFunds),
+		dispatcher: concurrent.NewPoolRunnerWithConfig(context.Background(), concurrent.PoolConfig{
+			Concurrency: props.Dispatch.Workers,
+			QueueSize:   props.Dispatch.QueueSize,
+		}),
 	}
 }
 
@@ -96,10 +126,18 @@ func NewClientWithDeps(deps *Deps, props *Props) *Client {
 // callbacks when events are triggered
 type Client struct {
 	callbacks   Callbacks
+	tenants     map[string]*Tenant
 	client      HttpClient
 	retryConfig concurrent.RetryConfig
 	logger      log.Logger
 	tracker     *stats.MethodTracker
+	dispatcher  *concurrent.PoolRunner
+	dispatched  stats.Counter
+	dropped     stats.Counter
+
+	// lock protects the state of the callbacks, which are
+	// triggered concurrently
+	lock sync.Mutex
 }
 
 func (c *Client) Name() string {
@@ -107,12 +145,17 @@ func (c *Client) Name() string {
 }
 
 func (c *Client) Stats() stats.Metrics {
-	return c.tracker.Stats()
+	metrics := c.tracker.Stats()
+	metrics["dispatch"] = stats.Metrics{
+		"dispatched": c.dispatched.Value(),
+		"dropped":    c.dropped.Value(),
+	}
+	return metrics
 }
 
-func (c *Client) instrumentedRequest(ctx context.Context, method string, req *http.Request) (int, error) {
-	code, err := c.tracker.Instrument(method, func() (interface{}, error) {
-		return c.request(ctx, req)
+func (c *Client) instrumentedRequest(ctx context.Context, callback *Callback, req *http.Request) (int, error) {
+	code, err := c.tracker.Instrument(callback.Method, func() (interface{}, error) {
+		return c.request(ctx, callback, req)
 	})
 
 	if err != nil {
@@ -122,20 +165,22 @@ func (c *Client) instrumentedRequest(ctx context.Context, method string, req *ht
 	return code.(int), err
 }
 
-// request sends an http request
-func (c *Client) request(ctx context.Context, req *http.Request) (int, error) {
-	code, err := concurrent.RetryWithConfig(ctx, concurrent.SupplierFunc(func() (interface{}, error) {
-		res, err := c.client.Do(req)
-		if err != nil {
-			return 0, err
-		}
+// request sends an http request retrying it as defined
+// by the callback
+func (c *Client) request(ctx context.Context, callback *Callback, req *http.Request) (int, error) {
+	client := c.client
+	if callback.Client != nil {
+		client = callback.Client
+	}
 
-		if res.StatusCode >= 500 {
-			return 0, fmt.Errorf("http request failed with status %d", res.StatusCode)
-		}
+	retryConfig := c.retryConfig
+	if callback.RetryConfig.Attempts > 0 {
+		retryConfig = callback.RetryConfig
+	}
 
-		return res.StatusCode, nil
-	}), c.retryConfig)
+	code, err := concurrent.RetryWithConfig(ctx, concurrent.SupplierFunc(func() (interface{}, error) {
+		return c.attempt(ctx, client, callback.Timeout, req)
+	}), retryConfig)
 
 	if err != nil {
 		return 0, err
@@ -144,6 +189,47 @@ func (c *Client) request(ctx context.Context, req *http.Request) (int, error) {
 	return code.(int), err
 }
 
+// attempt sends a single http request bounded by the timeout
+func (c *Client) attempt(
+	ctx context.Context,
+	client HttpClient,
+	timeout time.Duration,
+	req *http.Request,
+) (int, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	// the body of the request is consumed on each attempt,
+	// so a new one is needed for retries
+	attempt := req.WithContext(ctx)
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return 0, concurrent.ErrCannotRecover{Cause: err}
+		}
+		attempt.Body = body
+	}
+
+	res, err := client.Do(attempt)
+	if err != nil {
+		return 0, err
+	}
+
+	if res.Body != nil {
+		_, _ = io.Copy(ioutil.Discard, io.LimitReader(res.Body, 1<<16))
+		_ = res.Body.Close()
+	}
+
+	if res.StatusCode >= 500 {
+		return 0, fmt.Errorf("http request failed with status %d", res.StatusCode)
+	}
+
+	return res.StatusCode, nil
+}
+
 func (c *Client) createRequest(
 	ctx context.Context,
 	callback *Callback,
@@ -152,12 +238,11 @@ func (c *Client) createRequest(
 	url := callback.URL
 
 	if callback.QueryURLFormat != nil && props.Body != nil {
-		buffer := bytes.NewBuffer([]byte{})
-		if err := callback.QueryURLFormat.Execute(buffer, props.Body); err != nil {
-			return nil, err
+		queryURL, err := executeTemplate(callback.QueryURLFormat, props.Body)
+		if err != nil {
+			return nil, ErrTemplateErr{Cause: err, Param: "query url"}
 		}
 
-		queryURL := buffer.String()
 		if !strings.HasPrefix(queryURL, "?") {
 			queryURL = "?" + queryURL
 		}
@@ -165,15 +250,13 @@ func (c *Client) createRequest(
 	}
 
 	if callback.BodyFormat != nil && props.Body != nil {
-		buffer := bytes.NewBuffer([]byte{})
-		if err := callback.BodyFormat.Execute(buffer, props.Body); err != nil {
-			return nil, err
+		body, err := executeTemplate(callback.BodyFormat, props.Body)
+		if err != nil {
+			return nil, ErrTemplateErr{Cause: err, Param: "body"}
 		}
 
-		s := strings.Replace(buffer.String(), "'", "\"", -1)
-		buffer.Reset()
-		buffer.WriteString(s)
-		return http.NewRequest(callback.Method, url, buffer)
+		body = strings.Replace(body, "'", "\"", -1)
+		return http.NewRequest(callback.Method, url, bytes.NewBufferString(body))
 	}
 
 	return http.NewRequest(callback.Method, url, nil)
@@ -188,8 +271,7 @@ func (c *Client) Callback(
 		return nil
 	}
 
-	now := time.Now().Unix()
-	if now-callback.LastAttempt < int64(callback.PeriodLimit.Seconds()) {
+	if !c.attemptAllowed(callback) {
 		return nil
 	}
 
@@ -225,15 +307,40 @@ func (c *Client) Callback(
 		return c.deliver(ctx, callback, req)
 	}
 
-	go func() {
-		_ = c.deliver(ctx, callback, req)
-	}()
+	if !c.dispatcher.TryRunAndDiscard(concurrent.SupplierFunc(func() (interface{}, error) {
+		return nil, c.deliver(ctx, callback, req)
+	})) {
+		c.dropped.Incr()
+		c.logger.Warn(ctx, "callback dispatch queue is full, dropping callback", log.MapFields{
+			"call_type": "SendCallbackFailure",
+			"method":    callback.Method,
+			"url":       callback.URL,
+			"callback":  callback.Name,
+		})
+		return ErrDispatchQueueFull
+	}
 
+	c.dispatched.Incr()
 	return nil
 }
 
+// attemptAllowed returns true and records the attempt if the
+// period limit of the callback has passed since its last attempt
+func (c *Client) attemptAllowed(callback *Callback) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	now := time.Now().Unix()
+	if now-callback.LastAttempt < int64(callback.PeriodLimit.Seconds()) {
+		return false
+	}
+
+	callback.LastAttempt = now
+	return true
+}
+
 func (c *Client) deliver(ctx context.Context, callback *Callback, req *http.Request) error {
-	code, err := c.instrumentedRequest(ctx, callback.Method, req)
+	code, err := c.instrumentedRequest(ctx, callback, req)
 	if err != nil {
 		c.logger.Warn(ctx, "failed to deliver http callback", log.MapFields{
 			"call_type":  "SendCallbackFailure",
@@ -258,10 +365,26 @@ func (c *Client) deliver(ctx context.Context, callback *Callback, req *http.Requ
 	return err
 }
 
+// tenant returns the tenant of the AAD on whose behalf
+// a callback is triggered, or nil if it has none
+func (c *Client) tenant(ctx context.Context) *Tenant {
+	aad, ok := aadFromContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	return c.tenants[aad]
+}
+
 // WalletOutOfFunds sends a callback that is triggered when a wallet
 // is out of funds
 func (c *Client) WalletOutOfFunds(ctx context.Context, body WalletOutOfFundsBody) {
-	_ = c.Callback(ctx, &c.callbacks.WalletOutOfFunds, &CallbackProps{
+	callback := &c.callbacks.WalletOutOfFunds
+	if tenant := c.tenant(ctx); tenant != nil && tenant.Callbacks.WalletOutOfFunds.Enabled {
+		callback = &tenant.Callbacks.WalletOutOfFunds
+	}
+
+	_ = c.Callback(ctx, callback, &CallbackProps{
 		Body: body,
 	})
 }
@@ -269,24 +392,29 @@ func (c *Client) WalletOutOfFunds(ctx context.Context, body WalletOutOfFundsBody
 // WalletReachedFundsThreshold sends a callback that is triggered when a wallet
 // reaches a specific threshold of funds
 func (c *Client) WalletReachedFundsThreshold(ctx context.Context, body WalletReachedFundsThresholdBody) {
-	isSet := c.callbacks.WalletReachedFundsThreshold.Threshold != nil &&
-		c.callbacks.WalletReachedFundsThreshold.Threshold.Cmp(new(big.Int).SetInt64(0)) > 0
+	callback := &c.callbacks.WalletReachedFundsThreshold
+	if tenant := c.tenant(ctx); tenant != nil && tenant.Callbacks.WalletReachedFundsThreshold.Enabled {
+		callback = &tenant.Callbacks.WalletReachedFundsThreshold
+	}
+
+	isSet := callback.Threshold != nil &&
+		callback.Threshold.Cmp(new(big.Int).SetInt64(0)) > 0
 
 	if isSet &&
 		(body.Before == nil ||
-			c.callbacks.WalletReachedFundsThreshold.Threshold.Cmp(body.Before) < 0) &&
+			callback.Threshold.Cmp(body.Before) < 0) &&
 		body.After != nil &&
-		c.callbacks.WalletReachedFundsThreshold.Threshold.Cmp(body.After) > 0 {
+		callback.Threshold.Cmp(body.After) > 0 {
 		if body.Before == nil {
 			body.Before = new(big.Int).SetInt64(0)
 		}
 
-		_ = c.Callback(ctx, &c.callbacks.WalletReachedFundsThreshold.Callback, &CallbackProps{
+		_ = c.Callback(ctx, &callback.Callback, &CallbackProps{
 			Body: WalletReachedFundsThresholdRequest{
 				Address:   body.Address,
 				Before:    fmt.Sprintf("0x%x", body.Before),
 				After:     fmt.Sprintf("0x%x", body.After),
-				Threshold: fmt.Sprintf("0x%x", c.callbacks.WalletReachedFundsThreshold.Threshold),
+				Threshold: fmt.Sprintf("0x%x", callback.Threshold),
 			},
 		})
 	}
@@ -295,7 +423,21 @@ func (c *Client) WalletReachedFundsThreshold(ctx context.Context, body WalletRea
 // TransactionCommitted sends a callback that is triggered when a
 // transaction has been committed to the blockchain
 func (c *Client) TransactionCommitted(ctx context.Context, body TransactionCommittedBody) {
-	_ = c.Callback(ctx, &c.callbacks.TransactionCommitted, &CallbackProps{
+	callback := &c.callbacks.TransactionCommitted
+	if tenant := c.tenant(ctx); tenant != nil && tenant.Callbacks.TransactionCommitted.Enabled {
+		callback = &tenant.Callbacks.TransactionCommitted
+	}
+
+	_ = c.Callback(ctx, callback, &CallbackProps{
+		Body: body,
+	})
+}
+
+// NodeReconnected sends a callback that is triggered when the gateway
+// reconnects to the node after its connection failed. It is not
+// triggered on behalf of an AAD so it is never routed to a tenant
+func (c *Client) NodeReconnected(ctx context.Context, body NodeReconnectedBody) {
+	_ = c.Callback(ctx, &c.callbacks.NodeReconnected, &CallbackProps{
 		Body: body,
 	})
 }
@@ -6,11 +6,13 @@ import (
 	"io/ioutil"
 	"math/big"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
 	"github.com/oasislabs/oasis-gateway/concurrent"
 	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/oasislabs/oasis-gateway/stats"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -150,6 +152,46 @@ func TestClientWalletOutOfFundsOK(t *testing.T) {
 	}))
 }
 
+func TestClientNodeReconnectedOK(t *testing.T) {
+	bodyTmpl, err := template.New("NodeReconnectedBody").
+		Parse("{\"from\": {{.DisconnectedAt}}, \"to\": {{.ReconnectedAt}}}")
+	assert.Nil(t, err)
+
+	client := NewClientWithDeps(&Deps{
+		Client: &MockHttpClient{},
+		Logger: Logger,
+	}, &Props{
+		Callbacks: Callbacks{
+			NodeReconnected: Callback{
+				Enabled:    true,
+				Method:     http.MethodPost,
+				URL:        "http://localhost:1234/",
+				BodyFormat: bodyTmpl,
+				Sync:       true,
+			},
+		},
+		RetryConfig: TestRetryConfig,
+	})
+	mockclient := client.client.(*MockHttpClient)
+
+	mockclient.On("Do", mock.Anything).
+		Return(&http.Response{StatusCode: http.StatusOK}, nil)
+
+	client.NodeReconnected(Context, NodeReconnectedBody{
+		DisconnectedAt: 1000,
+		ReconnectedAt:  2000,
+	})
+
+	mockclient.AssertCalled(t, "Do", mock.MatchedBy(func(req *http.Request) bool {
+		v, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			return false
+		}
+
+		return string(v) == "{\"from\": 1000, \"to\": 2000}"
+	}))
+}
+
 func TestClientWalletReachedFundsThresholdOKCalledBeforeSet(t *testing.T) {
 	bodyTmplFmt := `{
   "address": "{{.Address}}",
@@ -311,3 +353,248 @@ func TestClientWalletReachedFundsThresholdOKNoCalled(t *testing.T) {
 
 	mockclient.AssertNotCalled(t, "Do", mock.Anything)
 }
+
+func TestClientCallbackRetryResendsBody(t *testing.T) {
+	bodyTmpl, err := template.New("Body").Parse("{\"address\": \"{{.Address}}\"}")
+	assert.Nil(t, err)
+
+	client := newClient()
+	mockclient := client.client.(*MockHttpClient)
+
+	var bodies []string
+	readBody := func(args mock.Arguments) {
+		v, _ := ioutil.ReadAll(args.Get(0).(*http.Request).Body)
+		bodies = append(bodies, string(v))
+	}
+	mockclient.On("Do", mock.Anything).Run(readBody).
+		Return(&http.Response{StatusCode: http.StatusInternalServerError}, nil).Once()
+	mockclient.On("Do", mock.Anything).Run(readBody).
+		Return(&http.Response{StatusCode: http.StatusOK}, nil)
+
+	err = client.Callback(Context, &Callback{
+		Enabled:    true,
+		Method:     http.MethodPost,
+		URL:        "http://localhost:1234/",
+		BodyFormat: bodyTmpl,
+		Sync:       true,
+	}, &CallbackProps{Body: WalletOutOfFundsBody{Address: "myAddress"}})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"{\"address\": \"myAddress\"}",
+		"{\"address\": \"myAddress\"}",
+	}, bodies)
+}
+
+func TestClientCallbackUsesCallbackClientAndRetries(t *testing.T) {
+	client := newClient()
+	defaultclient := client.client.(*MockHttpClient)
+	callbackclient := &MockHttpClient{}
+
+	callbackclient.On("Do", mock.Anything).
+		Return(&http.Response{StatusCode: http.StatusInternalServerError}, nil)
+
+	err := client.Callback(Context, &Callback{
+		Enabled: true,
+		Method:  http.MethodPost,
+		URL:     "http://localhost:1234/",
+		Sync:    true,
+		Client:  callbackclient,
+		RetryConfig: concurrent.RetryConfig{
+			BaseTimeout:     1,
+			BaseExp:         1,
+			MaxRetryTimeout: time.Millisecond,
+			Attempts:        2,
+		},
+	}, &CallbackProps{})
+
+	_, ok := err.(concurrent.ErrMaxAttemptsReached)
+	assert.True(t, ok)
+	callbackclient.AssertNumberOfCalls(t, "Do", 2)
+	defaultclient.AssertNotCalled(t, "Do", mock.Anything)
+}
+
+func TestClientCallbackTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		select {
+		case <-req.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer server.Close()
+
+	client := newClient()
+
+	start := time.Now()
+	err := client.Callback(Context, &Callback{
+		Enabled: true,
+		Method:  http.MethodPost,
+		URL:     server.URL,
+		Sync:    true,
+		Client:  server.Client(),
+		Timeout: 10 * time.Millisecond,
+		RetryConfig: concurrent.RetryConfig{
+			BaseTimeout:     1,
+			BaseExp:         1,
+			MaxRetryTimeout: time.Millisecond,
+			Attempts:        2,
+		},
+	}, &CallbackProps{})
+
+	_, ok := err.(concurrent.ErrMaxAttemptsReached)
+	assert.True(t, ok)
+	assert.True(t, time.Since(start) < 500*time.Millisecond)
+}
+
+func TestClientCallbackPeriodLimit(t *testing.T) {
+	client := newClient()
+	mockclient := client.client.(*MockHttpClient)
+
+	mockclient.On("Do", mock.Anything).Return(&http.Response{StatusCode: http.StatusOK}, nil)
+
+	callback := &Callback{
+		Enabled:     true,
+		Method:      http.MethodPost,
+		URL:         "http://localhost:1234/",
+		PeriodLimit: time.Minute,
+		Sync:        true,
+	}
+	for i := 0; i < 2; i++ {
+		assert.Nil(t, client.Callback(Context, callback, &CallbackProps{}))
+	}
+
+	mockclient.AssertNumberOfCalls(t, "Do", 1)
+}
+
+func TestClientCallbackAsyncDispatch(t *testing.T) {
+	client := newClient()
+	mockclient := client.client.(*MockHttpClient)
+
+	delivered := make(chan struct{})
+	mockclient.On("Do", mock.Anything).Run(func(mock.Arguments) {
+		close(delivered)
+	}).Return(&http.Response{StatusCode: http.StatusOK}, nil)
+
+	err := client.Callback(Context, &Callback{
+		Enabled: true,
+		Method:  http.MethodPost,
+		URL:     "http://localhost:1234/",
+	}, &CallbackProps{})
+	assert.Nil(t, err)
+
+	select {
+	case <-delivered:
+	case <-time.After(time.Second):
+		assert.Fail(t, "callback was not delivered")
+	}
+	assert.Equal(t, uint64(1), client.Stats()["dispatch"].(stats.Metrics)["dispatched"])
+}
+
+func TestClientCallbackAsyncDispatchQueueFull(t *testing.T) {
+	client := NewClientWithDeps(&Deps{
+		Client: &MockHttpClient{},
+		Logger: Logger,
+	}, &Props{
+		RetryConfig: TestRetryConfig,
+		Dispatch:    DispatchProps{Workers: 1, QueueSize: 1},
+	})
+	mockclient := client.client.(*MockHttpClient)
+
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	mockclient.On("Do", mock.Anything).Run(func(mock.Arguments) {
+		started <- struct{}{}
+		<-release
+	}).Return(&http.Response{StatusCode: http.StatusOK}, nil)
+	defer close(release)
+
+	callback := &Callback{
+		Enabled: true,
+		Method:  http.MethodPost,
+		URL:     "http://localhost:1234/",
+	}
+
+	// the first callback keeps the worker busy, the second one
+	// is queued and the third one is dropped
+	assert.Nil(t, client.Callback(Context, callback, &CallbackProps{}))
+	<-started
+	assert.Nil(t, client.Callback(Context, callback, &CallbackProps{}))
+	assert.Equal(t, ErrDispatchQueueFull, client.Callback(Context, callback, &CallbackProps{}))
+
+	metrics := client.Stats()["dispatch"].(stats.Metrics)
+	assert.Equal(t, uint64(2), metrics["dispatched"])
+	assert.Equal(t, uint64(1), metrics["dropped"])
+}
+
+func TestClientWalletOutOfFundsTenantRoute(t *testing.T) {
+	client := NewClientWithDeps(&Deps{
+		Client: &MockHttpClient{},
+		Logger: Logger,
+	}, &Props{
+		Callbacks: Callbacks{
+			WalletOutOfFunds: Callback{
+				Enabled: true,
+				Method:  http.MethodPost,
+				URL:     "http://localhost:1234/default",
+				Sync:    true,
+			},
+		},
+		RetryConfig: TestRetryConfig,
+		Tenants: []Tenant{{
+			Name: "team",
+			AADs: []string{"team@example.com"},
+			Callbacks: Callbacks{
+				WalletOutOfFunds: Callback{
+					Enabled: true,
+					Method:  http.MethodPost,
+					URL:     "http://localhost:1234/team",
+					Sync:    true,
+				},
+			},
+		}},
+	})
+	mockclient := client.client.(*MockHttpClient)
+
+	var urls []string
+	mockclient.On("Do", mock.Anything).Run(func(args mock.Arguments) {
+		urls = append(urls, args.Get(0).(*http.Request).URL.String())
+	}).Return(&http.Response{StatusCode: http.StatusOK}, nil)
+
+	client.WalletOutOfFunds(WithAAD(Context, "team@example.com"), WalletOutOfFundsBody{})
+	client.WalletOutOfFunds(WithAAD(Context, "other@example.com"), WalletOutOfFundsBody{})
+
+	assert.Equal(t, []string{
+		"http://localhost:1234/team",
+		"http://localhost:1234/default",
+	}, urls)
+}
+
+func TestClientTransactionCommittedTenantFallback(t *testing.T) {
+	client := NewClientWithDeps(&Deps{
+		Client: &MockHttpClient{},
+		Logger: Logger,
+	}, &Props{
+		Callbacks: Callbacks{
+			TransactionCommitted: Callback{
+				Enabled: true,
+				Method:  http.MethodPost,
+				URL:     "http://localhost:1234/default",
+				Sync:    true,
+			},
+		},
+		RetryConfig: TestRetryConfig,
+		Tenants: []Tenant{{
+			Name: "team",
+			AADs: []string{"team@example.com"},
+		}},
+	})
+	mockclient := client.client.(*MockHttpClient)
+
+	mockclient.On("Do", mock.MatchedBy(func(req *http.Request) bool {
+		return req.URL.String() == "http://localhost:1234/default"
+	})).Return(&http.Response{StatusCode: http.StatusOK}, nil)
+
+	client.TransactionCommitted(WithAAD(Context, "team@example.com"), TransactionCommittedBody{})
+
+	mockclient.AssertNumberOfCalls(t, "Do", 1)
+}
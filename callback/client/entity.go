@@ -4,6 +4,8 @@ import (
 	"html/template"
 	"math/big"
 	"time"
+
+	"github.com/oasislabs/oasis-gateway/concurrent"
 )
 
 // Callback is the definition of how a callback
@@ -45,6 +47,18 @@ type Callback struct {
 
 	// Sync if true the callback will be sent synchronously
 	Sync bool
+
+	// Client if set is the http client used to deliver this
+	// callback instead of the default client
+	Client HttpClient
+
+	// Timeout if set bounds each attempt to deliver the
+	// callback, including reading the response
+	Timeout time.Duration
+
+	// RetryConfig if it sets Attempts defines how the delivery of
+	// the callback is retried instead of the default configuration
+	RetryConfig concurrent.RetryConfig
 }
 
 // WalletOutOfFundsBody is the body sent on a WalletOutOfFunds
@@ -89,3 +103,15 @@ type TransactionCommittedBody struct {
 	// Hash is the hash of the transaction that was committed
 	Hash string
 }
+
+// NodeReconnectedBody is the body sent on a NodeReconnected
+// callback to the required endpoint
+type NodeReconnectedBody struct {
+	// DisconnectedAt is the time in milliseconds since the epoch at
+	// which the connection to the node was reported as failed
+	DisconnectedAt int64
+
+	// ReconnectedAt is the time in milliseconds since the epoch at
+	// which the connection to the node was recreated
+	ReconnectedAt int64
+}
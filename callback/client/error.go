@@ -1,6 +1,13 @@
 package client
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrDispatchQueueFull is returned when an asynchronous callback
+// is dropped because the dispatch queue is full
+var ErrDispatchQueueFull = errors.New("[callback] dispatch queue is full")
 
 type ErrNewHttpRequest struct {
 	Cause error
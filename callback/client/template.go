@@ -0,0 +1,52 @@
+package client
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+)
+
+// MaxTemplateOutput is the maximum number of bytes that a callback
+// template can generate when executed. Executions that exceed it fail
+// so that a malformed template cannot produce unbounded requests
+const MaxTemplateOutput = 1 << 16
+
+// limitedBuffer is a buffer that fails writes once the
+// template output exceeds MaxTemplateOutput
+type limitedBuffer struct {
+	bytes.Buffer
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > MaxTemplateOutput {
+		return 0, fmt.Errorf("template output exceeds %d bytes", MaxTemplateOutput)
+	}
+
+	return b.Buffer.Write(p)
+}
+
+// executeTemplate executes the template against data and returns
+// the generated output
+func executeTemplate(tmpl *template.Template, data interface{}) (string, error) {
+	var buffer limitedBuffer
+	if err := tmpl.Execute(&buffer, data); err != nil {
+		return "", err
+	}
+
+	return buffer.String(), nil
+}
+
+// ValidateTemplate executes the template for param against a
+// sample of the data it will receive when the callback is
+// delivered, so that errors are found before a callback is sent
+func ValidateTemplate(param string, tmpl *template.Template, sample interface{}) error {
+	if tmpl == nil {
+		return nil
+	}
+
+	if _, err := executeTemplate(tmpl, sample); err != nil {
+		return ErrTemplateErr{Cause: err, Param: param}
+	}
+
+	return nil
+}
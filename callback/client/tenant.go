@@ -0,0 +1,49 @@
+package client
+
+import "context"
+
+// aadKey is the key of the context value that holds the AAD
+// on whose behalf a callback is triggered
+type aadKey struct{}
+
+// WithAAD returns a context that routes the callbacks triggered
+// with it to the tenant the AAD belongs to
+func WithAAD(ctx context.Context, aad string) context.Context {
+	return context.WithValue(ctx, aadKey{}, aad)
+}
+
+// aadFromContext returns the AAD set in the context, if any
+func aadFromContext(ctx context.Context) (string, bool) {
+	aad, ok := ctx.Value(aadKey{}).(string)
+	return aad, ok
+}
+
+// Tenant defines the callbacks sent on behalf of a set of AADs.
+// Callbacks that are not enabled for the tenant are sent as
+// defined by the default callbacks of the client
+type Tenant struct {
+	// Name is a human readable name to identify the tenant
+	Name string
+
+	// AADs are the AADs that belong to the tenant
+	AADs []string
+
+	// Callbacks are the callbacks of the tenant
+	Callbacks Callbacks
+}
+
+// newTenants creates the lookup of the tenant each AAD belongs
+// to. If an AAD belongs to more than one tenant the first one is used
+func newTenants(tenants []Tenant) map[string]*Tenant {
+	lookup := make(map[string]*Tenant)
+	for i := range tenants {
+		tenant := &tenants[i]
+		for _, aad := range tenant.AADs {
+			if _, ok := lookup[aad]; !ok {
+				lookup[aad] = tenant
+			}
+		}
+	}
+
+	return lookup
+}
@@ -1,11 +1,15 @@
 package callback
 
 import (
+	"errors"
 	"fmt"
+	"math"
 	"strings"
+	"time"
 
 	"github.com/oasislabs/oasis-gateway/config"
 	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/oasislabs/oasis-gateway/proxy"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -34,6 +38,9 @@ func (c *WalletOutOfFunds) Configure(v *viper.Viper) error {
 	c.QueryURL = v.GetString("callback.wallet_out_of_funds.queryurl")
 	c.Headers = v.GetStringSlice("callback.wallet_out_of_funds.headers")
 	c.Sync = v.GetBool("callback.wallet_out_of_funds.sync")
+	if err := c.Delivery.Configure("callback.wallet_out_of_funds", v); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -54,7 +61,7 @@ func (c *WalletOutOfFunds) Bind(v *viper.Viper, cmd *cobra.Command) error {
 	cmd.PersistentFlags().Bool("callback.wallet_out_of_funds.sync", false,
 		"whether to send the callback synchronously.")
 
-	return nil
+	return c.Delivery.Bind("callback.wallet_out_of_funds", cmd)
 }
 
 func (c *WalletOutOfFunds) Log(fields log.Fields) {
@@ -65,6 +72,69 @@ func (c *WalletOutOfFunds) Log(fields log.Fields) {
 	fields.Add("callback.wallet_out_of_funds.queryurl", c.QueryURL)
 	fields.Add("callback.wallet_out_of_funds.headers", strings.Join(c.Headers, ","))
 	fields.Add("callback.wallet_out_of_funds.sync", c.Sync)
+	c.Delivery.Log("callback.wallet_out_of_funds", fields)
+}
+
+type NodeReconnected struct {
+	Callback
+}
+
+func (c *NodeReconnected) Configure(v *viper.Viper) error {
+	c.Enabled = v.GetBool("callback.node_reconnected.enabled")
+	if !c.Enabled {
+		return nil
+	}
+
+	c.Method = v.GetString("callback.node_reconnected.method")
+	if len(c.Method) == 0 {
+		return config.ErrKeyNotSet{Key: "callback.node_reconnected.method"}
+	}
+
+	c.URL = v.GetString("callback.node_reconnected.url")
+	if len(c.URL) == 0 {
+		return config.ErrKeyNotSet{Key: "callback.node_reconnected.url"}
+	}
+
+	c.Body = v.GetString("callback.node_reconnected.body")
+	c.QueryURL = v.GetString("callback.node_reconnected.queryurl")
+	c.Headers = v.GetStringSlice("callback.node_reconnected.headers")
+	c.Sync = v.GetBool("callback.node_reconnected.sync")
+	if err := c.Delivery.Configure("callback.node_reconnected", v); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *NodeReconnected) Bind(v *viper.Viper, cmd *cobra.Command) error {
+	cmd.PersistentFlags().Bool("callback.node_reconnected.enabled", false,
+		"enables the node_reconnected callback. This callback will be sent by the "+
+			"gateway when it reconnects to the node after its connection failed, since "+
+			"events may have been missed while it was disconnected.")
+	cmd.PersistentFlags().String("callback.node_reconnected.method", "",
+		"http method on the request for the callback.")
+	cmd.PersistentFlags().String("callback.node_reconnected.url", "",
+		"http url for the callback.")
+	cmd.PersistentFlags().String("callback.node_reconnected.body", "",
+		"http body for the callback.")
+	cmd.PersistentFlags().String("callback.node_reconnected.queryurl", "",
+		"http query url for the callback.")
+	cmd.PersistentFlags().StringSlice("callback.node_reconnected.headers", nil,
+		"http headers for the callback.")
+	cmd.PersistentFlags().Bool("callback.node_reconnected.sync", false,
+		"whether to send the callback synchronously.")
+
+	return c.Delivery.Bind("callback.node_reconnected", cmd)
+}
+
+func (c *NodeReconnected) Log(fields log.Fields) {
+	fields.Add("callback.node_reconnected.enabled", c.Enabled)
+	fields.Add("callback.node_reconnected.method", c.Method)
+	fields.Add("callback.node_reconnected.url", c.URL)
+	fields.Add("callback.node_reconnected.body", c.Body)
+	fields.Add("callback.node_reconnected.queryurl", c.QueryURL)
+	fields.Add("callback.node_reconnected.headers", strings.Join(c.Headers, ","))
+	fields.Add("callback.node_reconnected.sync", c.Sync)
+	c.Delivery.Log("callback.node_reconnected", fields)
 }
 
 type TransactionCommitted struct {
@@ -91,6 +161,9 @@ func (c *TransactionCommitted) Configure(v *viper.Viper) error {
 	c.QueryURL = v.GetString("callback.transaction_committed.queryurl")
 	c.Headers = v.GetStringSlice("callback.transaction_committed.headers")
 	c.Sync = v.GetBool("callback.transaction_committed.sync")
+	if err := c.Delivery.Configure("callback.transaction_committed", v); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -111,7 +184,7 @@ func (c *TransactionCommitted) Bind(v *viper.Viper, cmd *cobra.Command) error {
 	cmd.PersistentFlags().Bool("callback.transaction_committed.sync", false,
 		"whether to send the callback synchronously.")
 
-	return nil
+	return c.Delivery.Bind("callback.transaction_committed", cmd)
 }
 
 func (c *TransactionCommitted) Log(fields log.Fields) {
@@ -122,6 +195,7 @@ func (c *TransactionCommitted) Log(fields log.Fields) {
 	fields.Add("callback.transaction_committed.queryurl", c.QueryURL)
 	fields.Add("callback.transaction_committed.headers", strings.Join(c.Headers, ","))
 	fields.Add("callback.transaction_committed.sync", c.Sync)
+	c.Delivery.Log("callback.transaction_committed", fields)
 }
 
 type WalletReachedFundsThreshold struct {
@@ -133,6 +207,7 @@ type WalletReachedFundsThreshold struct {
 	QueryURL  string
 	Headers   []string
 	Threshold uint64
+	Delivery
 }
 
 func (c *WalletReachedFundsThreshold) Configure(v *viper.Viper) error {
@@ -155,6 +230,9 @@ func (c *WalletReachedFundsThreshold) Configure(v *viper.Viper) error {
 	c.QueryURL = v.GetString("callback.wallet_reached_funds_threshold.queryurl")
 	c.Headers = v.GetStringSlice("callback.wallet_reached_funds_threshold.headers")
 	c.Sync = v.GetBool("callback.wallet_reached_funds_threshold.sync")
+	if err := c.Delivery.Configure("callback.wallet_reached_funds_threshold", v); err != nil {
+		return err
+	}
 	i := v.GetInt64("callback.wallet_reached_funds_threshold.threshold")
 	if i < 0 {
 		return config.ErrInvalidValue{
@@ -169,6 +247,21 @@ func (c *WalletReachedFundsThreshold) Configure(v *viper.Viper) error {
 	return nil
 }
 
+// callback returns the configuration of the WalletReachedFundsThreshold
+// callback without its threshold
+func (c *WalletReachedFundsThreshold) callback() Callback {
+	return Callback{
+		Enabled:  c.Enabled,
+		Sync:     c.Sync,
+		Method:   c.Method,
+		URL:      c.URL,
+		Body:     c.Body,
+		QueryURL: c.QueryURL,
+		Headers:  c.Headers,
+		Delivery: c.Delivery,
+	}
+}
+
 func (c *WalletReachedFundsThreshold) Bind(v *viper.Viper, cmd *cobra.Command) error {
 	cmd.PersistentFlags().Bool("callback.wallet_reached_funds_threshold.enabled", false,
 		"enables the wallet_reached_funds_threshold callback. This callback will be sent by the"+
@@ -188,7 +281,7 @@ func (c *WalletReachedFundsThreshold) Bind(v *viper.Viper, cmd *cobra.Command) e
 	cmd.PersistentFlags().Uint64("callback.wallet_reached_funds_threshold.threshold", 0,
 		"sets the lower threshold to trigger the callback")
 
-	return nil
+	return c.Delivery.Bind("callback.wallet_reached_funds_threshold", cmd)
 }
 
 func (c *WalletReachedFundsThreshold) Log(fields log.Fields) {
@@ -199,6 +292,7 @@ func (c *WalletReachedFundsThreshold) Log(fields log.Fields) {
 	fields.Add("callback.wallet_reached_funds_threshold.queryurl", c.QueryURL)
 	fields.Add("callback.wallet_reached_funds_threshold.headers", strings.Join(c.Headers, ","))
 	fields.Add("callback.wallet_reached_funds_threshold.sync", c.Sync)
+	c.Delivery.Log("callback.wallet_reached_funds_threshold", fields)
 }
 
 type Callback struct {
@@ -209,15 +303,109 @@ type Callback struct {
 	Body     string
 	QueryURL string
 	Headers  []string
+	Delivery
+}
+
+// Delivery defines how a callback is delivered to its endpoint
+// so that slow endpoints cannot stall the callers
+type Delivery struct {
+	// ConnectTimeout is the maximum time to establish the
+	// connection to the endpoint
+	ConnectTimeout time.Duration
+
+	// RequestTimeout is the maximum time of each attempt to
+	// deliver the callback, including reading the response
+	RequestTimeout time.Duration
+
+	// MaxAttempts is the maximum number of attempts to
+	// deliver the callback
+	MaxAttempts uint8
+
+	// TLSCAPath is the path to a PEM file with the certificate
+	// authorities trusted to verify the endpoint. If not set the
+	// system ones are used
+	TLSCAPath string
+
+	// TLSInsecureSkipVerify if set the certificate of the endpoint
+	// is not verified. Only meant for development
+	TLSInsecureSkipVerify bool
+}
+
+func (d *Delivery) Configure(prefix string, v *viper.Viper) error {
+	connectTimeout := v.GetInt64(prefix + ".connect_timeout_ms")
+	if connectTimeout <= 0 {
+		return errors.New(prefix + ".connect_timeout_ms must be positive")
+	}
+	d.ConnectTimeout = time.Duration(connectTimeout) * time.Millisecond
+
+	requestTimeout := v.GetInt64(prefix + ".request_timeout_ms")
+	if requestTimeout <= 0 {
+		return errors.New(prefix + ".request_timeout_ms must be positive")
+	}
+	d.RequestTimeout = time.Duration(requestTimeout) * time.Millisecond
+
+	maxAttempts := v.GetInt(prefix + ".max_attempts")
+	if maxAttempts < 1 || maxAttempts > math.MaxUint8 {
+		return errors.New(prefix + ".max_attempts must be between 1 and 255")
+	}
+	d.MaxAttempts = uint8(maxAttempts)
+
+	d.TLSCAPath = v.GetString(prefix + ".tls_ca_path")
+	d.TLSInsecureSkipVerify = v.GetBool(prefix + ".tls_insecure_skip_verify")
+	return nil
+}
+
+func (d *Delivery) Bind(prefix string, cmd *cobra.Command) error {
+	cmd.PersistentFlags().Int64(prefix+".connect_timeout_ms", 5000,
+		"maximum time in milliseconds to connect to the endpoint of the callback.")
+	cmd.PersistentFlags().Int64(prefix+".request_timeout_ms", 10000,
+		"maximum time in milliseconds of each attempt to deliver the callback.")
+	cmd.PersistentFlags().Int(prefix+".max_attempts", 3,
+		"maximum number of attempts to deliver the callback.")
+	cmd.PersistentFlags().String(prefix+".tls_ca_path", "",
+		"path to a PEM file with the certificate authorities used to verify "+
+			"the endpoint of the callback. If not set the system ones are used.")
+	cmd.PersistentFlags().Bool(prefix+".tls_insecure_skip_verify", false,
+		"if set the certificate of the endpoint of the callback is not verified. "+
+			"Only meant for development.")
+	return nil
+}
+
+func (d *Delivery) Log(prefix string, fields log.Fields) {
+	fields.Add(prefix+".connect_timeout_ms", int64(d.ConnectTimeout/time.Millisecond))
+	fields.Add(prefix+".request_timeout_ms", int64(d.RequestTimeout/time.Millisecond))
+	fields.Add(prefix+".max_attempts", d.MaxAttempts)
+	fields.Add(prefix+".tls_ca_path", d.TLSCAPath)
+	fields.Add(prefix+".tls_insecure_skip_verify", d.TLSInsecureSkipVerify)
 }
 
 type Config struct {
+	NodeReconnected             NodeReconnected
 	TransactionCommitted        TransactionCommitted
 	WalletOutOfFunds            WalletOutOfFunds
 	WalletReachedFundsThreshold WalletReachedFundsThreshold
+
+	// Proxy used to send the callbacks. If nil the proxies
+	// defined in the environment are used
+	Proxy *proxy.Proxy
+
+	// DispatchWorkers is the number of asynchronous callbacks
+	// delivered concurrently
+	DispatchWorkers uint8
+
+	// DispatchQueueSize is the number of asynchronous callbacks
+	// each worker can have queued before new ones are dropped
+	DispatchQueueSize int
+
+	// Tenants route the callbacks triggered on behalf of
+	// their AADs to their own destinations
+	Tenants Tenants
 }
 
 func (c *Config) Configure(v *viper.Viper) error {
+	if err := c.NodeReconnected.Configure(v); err != nil {
+		return err
+	}
 	if err := c.TransactionCommitted.Configure(v); err != nil {
 		return err
 	}
@@ -227,10 +415,52 @@ func (c *Config) Configure(v *viper.Viper) error {
 	if err := c.WalletReachedFundsThreshold.Configure(v); err != nil {
 		return err
 	}
+
+	p, err := proxy.Parse(v.GetString("callback.proxy"))
+	if err != nil {
+		return err
+	}
+	c.Proxy = p
+
+	workers := v.GetInt("callback.dispatch.workers")
+	if workers < 1 || workers > math.MaxUint8 {
+		return errors.New("callback.dispatch.workers must be between 1 and 255")
+	}
+	c.DispatchWorkers = uint8(workers)
+
+	c.DispatchQueueSize = v.GetInt("callback.dispatch.queue_size")
+	if c.DispatchQueueSize < 1 {
+		return errors.New("callback.dispatch.queue_size must be positive")
+	}
+
+	if err := c.Tenants.Configure(v); err != nil {
+		return err
+	}
+
+	for _, tenant := range c.Tenants {
+		for _, route := range []struct {
+			name    string
+			route   Route
+			enabled bool
+		}{
+			{"transaction_committed", tenant.TransactionCommitted, c.TransactionCommitted.Enabled},
+			{"wallet_out_of_funds", tenant.WalletOutOfFunds, c.WalletOutOfFunds.Enabled},
+			{"wallet_reached_funds_threshold", tenant.WalletReachedFundsThreshold, c.WalletReachedFundsThreshold.Enabled},
+		} {
+			if route.route.IsSet() && !route.enabled {
+				return fmt.Errorf("%s.%s is set but callback.%s is not enabled",
+					tenant.prefix(), route.name, route.name)
+			}
+		}
+	}
+
 	return nil
 }
 
 func (c *Config) Bind(v *viper.Viper, cmd *cobra.Command) error {
+	if err := c.NodeReconnected.Bind(v, cmd); err != nil {
+		return err
+	}
 	if err := c.TransactionCommitted.Bind(v, cmd); err != nil {
 		return err
 	}
@@ -240,11 +470,26 @@ func (c *Config) Bind(v *viper.Viper, cmd *cobra.Command) error {
 	if err := c.WalletReachedFundsThreshold.Bind(v, cmd); err != nil {
 		return err
 	}
+
+	cmd.PersistentFlags().String("callback.proxy", proxy.Environment,
+		"url of the http, https or socks5 proxy used to send the callbacks, or "+
+			proxy.Direct+" to not use a proxy. If not set the proxy is taken from "+
+			"the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables.")
+	cmd.PersistentFlags().Int("callback.dispatch.workers", 4,
+		"number of asynchronous callbacks delivered concurrently.")
+	cmd.PersistentFlags().Int("callback.dispatch.queue_size", 128,
+		"number of asynchronous callbacks each worker can have queued. "+
+			"Callbacks triggered when the queue is full are dropped.")
 	return nil
 }
 
 func (c *Config) Log(fields log.Fields) {
+	c.NodeReconnected.Log(fields)
 	c.TransactionCommitted.Log(fields)
 	c.WalletOutOfFunds.Log(fields)
 	c.WalletReachedFundsThreshold.Log(fields)
+	fields.Add("callback.proxy", c.Proxy.String())
+	fields.Add("callback.dispatch.workers", c.DispatchWorkers)
+	fields.Add("callback.dispatch.queue_size", c.DispatchQueueSize)
+	c.Tenants.Log(fields)
 }
@@ -2,13 +2,20 @@ package callback
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
 	"html/template"
+	"io/ioutil"
 	"math/big"
+	"net"
 	"net/http"
 	"time"
 
 	"github.com/oasislabs/oasis-gateway/callback/client"
+	"github.com/oasislabs/oasis-gateway/concurrent"
 	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/oasislabs/oasis-gateway/proxy"
 )
 
 type ClientDeps struct {
@@ -30,27 +37,107 @@ func (f CallbacksFactoryFunc) New(ctx context.Context, services *ClientServices,
 	return f(ctx, services, config)
 }
 
-func parseCallback(name string, callback Callback) (client.Callback, error) {
-	var (
-		bodyFormat     *template.Template
-		queryURLFormat *template.Template
-	)
-	if len(callback.Body) > 0 {
-		tmpl, err := template.New(name).Parse(callback.Body)
-		if err != nil {
-			return client.Callback{}, err
-		}
+// sampleAddress is the address used in the sample payloads
+// against which the callback templates are validated
+const sampleAddress = "0x0000000000000000000000000000000000000000"
+
+var (
+	sampleTransactionCommitted = client.TransactionCommittedBody{
+		AAD:     sampleAddress,
+		Address: sampleAddress,
+		Hash:    "0x0000000000000000000000000000000000000000000000000000000000000000",
+	}
+
+	sampleWalletOutOfFunds = client.WalletOutOfFundsBody{Address: sampleAddress}
+
+	sampleNodeReconnected = client.NodeReconnectedBody{
+		DisconnectedAt: 1574000000000,
+		ReconnectedAt:  1574000005000,
+	}
+
+	sampleWalletReachedFundsThreshold = client.WalletReachedFundsThresholdRequest{
+		Address:   sampleAddress,
+		Before:    "0x3e8",
+		After:     "0x1f4",
+		Threshold: "0x3e8",
+	}
+)
 
-		bodyFormat = tmpl
+// deliveryRetryConfig returns the retry configuration used to
+// deliver a callback with up to attempts attempts
+func deliveryRetryConfig(attempts uint8) concurrent.RetryConfig {
+	return concurrent.RetryConfig{
+		Random:          true,
+		Attempts:        attempts,
+		BaseExp:         2,
+		BaseTimeout:     500 * time.Millisecond,
+		MaxRetryTimeout: 5 * time.Second,
 	}
+}
+
+// newHttpClient creates the http client used to deliver a
+// callback as defined by its delivery configuration
+func newHttpClient(p *proxy.Proxy, delivery Delivery) (*http.Client, error) {
+	transport := p.Transport()
+	transport.DialContext = (&net.Dialer{
+		Timeout:   delivery.ConnectTimeout,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
 
-	if len(callback.QueryURL) > 0 {
-		tmpl, err := template.New(name).Parse(callback.QueryURL)
-		if err != nil {
-			return client.Callback{}, err
+	if len(delivery.TLSCAPath) > 0 || delivery.TLSInsecureSkipVerify {
+		config := &tls.Config{InsecureSkipVerify: delivery.TLSInsecureSkipVerify}
+
+		if len(delivery.TLSCAPath) > 0 {
+			pem, err := ioutil.ReadFile(delivery.TLSCAPath)
+			if err != nil {
+				return nil, err
+			}
+
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificates found in %s", delivery.TLSCAPath)
+			}
+			config.RootCAs = pool
 		}
 
-		queryURLFormat = tmpl
+		transport.TLSClientConfig = config
+	}
+
+	return &http.Client{Transport: transport}, nil
+}
+
+// parseTemplate parses the template for param of the callback and
+// validates it by executing it against a sample of the data the
+// callback is delivered with
+func parseTemplate(name, param, text string, sample interface{}) (*template.Template, error) {
+	if len(text) == 0 {
+		return nil, nil
+	}
+
+	tmpl, err := template.New(name + param).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("callback %s has an invalid %s template: %s", name, param, err.Error())
+	}
+
+	if err := client.ValidateTemplate(param, tmpl, sample); err != nil {
+		return nil, fmt.Errorf("callback %s has an invalid %s template: %s", name, param, err.Error())
+	}
+
+	return tmpl, nil
+}
+
+// parseCallback parses the callback configuration. The templates of
+// the callback are validated against sample, which must be of the
+// same type as the body the callback is delivered with
+func parseCallback(name string, callback Callback, sample interface{}) (client.Callback, error) {
+	bodyFormat, err := parseTemplate(name, "Body", callback.Body, sample)
+	if err != nil {
+		return client.Callback{}, err
+	}
+
+	queryURLFormat, err := parseTemplate(name, "QueryURL", callback.QueryURL, sample)
+	if err != nil {
+		return client.Callback{}, err
 	}
 
 	return client.Callback{
@@ -63,19 +150,13 @@ func parseCallback(name string, callback Callback) (client.Callback, error) {
 		Headers:        callback.Headers,
 		Sync:           callback.Sync,
 		PeriodLimit:    1 * time.Minute,
+		Timeout:        callback.RequestTimeout,
+		RetryConfig:    deliveryRetryConfig(callback.MaxAttempts),
 	}, nil
 }
 
-func parseWalletReachedFundsThresholdCallback(config WalletReachedFundsThreshold) (client.WalletReachedFundsThresholdCallback, error) {
-	callback, err := parseCallback("WalletReachedFundsThreshold", Callback{
-		Enabled:  config.Enabled,
-		Sync:     config.Sync,
-		Method:   config.Method,
-		URL:      config.URL,
-		Body:     config.Body,
-		QueryURL: config.QueryURL,
-		Headers:  config.Headers,
-	})
+func parseWalletReachedFundsThresholdCallback(name string, config WalletReachedFundsThreshold, route Route) (client.WalletReachedFundsThresholdCallback, error) {
+	callback, err := parseCallback(name, route.Apply(config.callback()), sampleWalletReachedFundsThreshold)
 	if err != nil {
 		return client.WalletReachedFundsThresholdCallback{}, err
 	}
@@ -86,28 +167,129 @@ func parseWalletReachedFundsThresholdCallback(config WalletReachedFundsThreshold
 	}, nil
 }
 
+// parseTenants parses the callbacks of the tenants. The callbacks
+// that a tenant routes are the default ones with the fields set
+// in its routes overridden, and they are delivered with the same
+// http client as the default ones
+func parseTenants(config *Config, defaults client.Callbacks) ([]client.Tenant, error) {
+	var tenants []client.Tenant
+	for _, tenant := range config.Tenants {
+		callbacks := client.Callbacks{}
+
+		if tenant.TransactionCommitted.IsSet() {
+			callback, err := parseCallback(tenant.Name+"/TransactionCommitted",
+				tenant.TransactionCommitted.Apply(config.TransactionCommitted.Callback),
+				sampleTransactionCommitted)
+			if err != nil {
+				return nil, err
+			}
+			callback.Client = defaults.TransactionCommitted.Client
+			callbacks.TransactionCommitted = callback
+		}
+
+		if tenant.WalletOutOfFunds.IsSet() {
+			callback, err := parseCallback(tenant.Name+"/WalletOutOfFunds",
+				tenant.WalletOutOfFunds.Apply(config.WalletOutOfFunds.Callback),
+				sampleWalletOutOfFunds)
+			if err != nil {
+				return nil, err
+			}
+			callback.Client = defaults.WalletOutOfFunds.Client
+			callbacks.WalletOutOfFunds = callback
+		}
+
+		if tenant.WalletReachedFundsThreshold.IsSet() {
+			callback, err := parseWalletReachedFundsThresholdCallback(
+				tenant.Name+"/WalletReachedFundsThreshold",
+				config.WalletReachedFundsThreshold, tenant.WalletReachedFundsThreshold)
+			if err != nil {
+				return nil, err
+			}
+			callback.Client = defaults.WalletReachedFundsThreshold.Client
+			callbacks.WalletReachedFundsThreshold = callback
+		}
+
+		tenants = append(tenants, client.Tenant{
+			Name:      tenant.Name,
+			AADs:      tenant.AADs,
+			Callbacks: callbacks,
+		})
+	}
+
+	return tenants, nil
+}
+
+// NewClientWithDeps creates a new instance of the client with the
+// provided dependencies. If deps.Client is nil each enabled callback
+// is delivered with an http client created from its configuration
 func NewClientWithDeps(ctx context.Context, deps *client.Deps, config *Config) (*client.Client, error) {
-	transactionCommitted, err := parseCallback("TransactionCommitted", config.TransactionCommitted.Callback)
+	nodeReconnected, err := parseCallback("NodeReconnected",
+		config.NodeReconnected.Callback, sampleNodeReconnected)
 	if err != nil {
 		return nil, err
 	}
 
-	walletOutOfFunds, err := parseCallback("WalletOutOfFundsBody", config.WalletOutOfFunds.Callback)
+	transactionCommitted, err := parseCallback("TransactionCommitted",
+		config.TransactionCommitted.Callback, sampleTransactionCommitted)
 	if err != nil {
 		return nil, err
 	}
 
-	walletReachedFundsThreshold, err := parseWalletReachedFundsThresholdCallback(config.WalletReachedFundsThreshold)
+	walletOutOfFunds, err := parseCallback("WalletOutOfFundsBody",
+		config.WalletOutOfFunds.Callback, sampleWalletOutOfFunds)
+	if err != nil {
+		return nil, err
+	}
+
+	walletReachedFundsThreshold, err := parseWalletReachedFundsThresholdCallback(
+		"WalletReachedFundsThreshold", config.WalletReachedFundsThreshold, Route{})
+	if err != nil {
+		return nil, err
+	}
+
+	if deps.Client == nil {
+		for _, callback := range []struct {
+			callback *client.Callback
+			delivery Delivery
+		}{
+			{&nodeReconnected, config.NodeReconnected.Delivery},
+			{&transactionCommitted, config.TransactionCommitted.Delivery},
+			{&walletOutOfFunds, config.WalletOutOfFunds.Delivery},
+			{&walletReachedFundsThreshold.Callback, config.WalletReachedFundsThreshold.Delivery},
+		} {
+			if !callback.callback.Enabled {
+				continue
+			}
+
+			httpClient, err := newHttpClient(config.Proxy, callback.delivery)
+			if err != nil {
+				return nil, err
+			}
+			callback.callback.Client = httpClient
+		}
+	}
+
+	tenants, err := parseTenants(config, client.Callbacks{
+		TransactionCommitted:        transactionCommitted,
+		WalletOutOfFunds:            walletOutOfFunds,
+		WalletReachedFundsThreshold: walletReachedFundsThreshold,
+	})
 	if err != nil {
 		return nil, err
 	}
 
 	return client.NewClientWithDeps(deps, &client.Props{
 		Callbacks: client.Callbacks{
+			NodeReconnected:             nodeReconnected,
 			TransactionCommitted:        transactionCommitted,
 			WalletOutOfFunds:            walletOutOfFunds,
 			WalletReachedFundsThreshold: walletReachedFundsThreshold,
 		},
+		Dispatch: client.DispatchProps{
+			Workers:   config.DispatchWorkers,
+			QueueSize: config.DispatchQueueSize,
+		},
+		Tenants: tenants,
 	}), nil
 }
 
@@ -116,6 +298,5 @@ func NewClientWithDeps(ctx context.Context, deps *client.Deps, config *Config) (
 var NewClient = CallbacksFactoryFunc(func(ctx context.Context, services *ClientServices, config *Config) (*client.Client, error) {
 	return NewClientWithDeps(ctx, &client.Deps{
 		Logger: services.Logger,
-		Client: &http.Client{},
 	}, config)
 })
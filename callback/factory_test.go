@@ -0,0 +1,89 @@
+package callback
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/oasislabs/oasis-gateway/callback/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewHttpClientInsecureSkipVerify(t *testing.T) {
+	client, err := newHttpClient(nil, Delivery{
+		ConnectTimeout:        time.Second,
+		TLSInsecureSkipVerify: true,
+	})
+	assert.Nil(t, err)
+
+	transport := client.Transport.(*http.Transport)
+	assert.True(t, transport.TLSClientConfig.InsecureSkipVerify)
+}
+
+func TestNewHttpClientInvalidCA(t *testing.T) {
+	dir, err := ioutil.TempDir("", "callback")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "ca.pem")
+	assert.Nil(t, ioutil.WriteFile(path, []byte("not a certificate"), 0600))
+
+	_, err = newHttpClient(nil, Delivery{TLSCAPath: path})
+	assert.Equal(t, "no certificates found in "+path, err.Error())
+}
+
+func TestNewHttpClientMissingCA(t *testing.T) {
+	_, err := newHttpClient(nil, Delivery{TLSCAPath: "/does/not/exist.pem"})
+	assert.Error(t, err)
+}
+
+func TestParseCallbackValidTemplates(t *testing.T) {
+	callback, err := parseCallback("WalletOutOfFunds", Callback{
+		Body:     "{'address': '{{.Address}}'}",
+		QueryURL: "address={{.Address}}",
+	}, client.WalletOutOfFundsBody{Address: sampleAddress})
+	assert.Nil(t, err)
+	assert.NotNil(t, callback.BodyFormat)
+	assert.NotNil(t, callback.QueryURLFormat)
+}
+
+func TestParseCallbackParseError(t *testing.T) {
+	_, err := parseCallback("WalletOutOfFunds", Callback{
+		Body: "{\n'address': '{{.Address}'\n}",
+	}, client.WalletOutOfFundsBody{Address: sampleAddress})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "callback WalletOutOfFunds has an invalid Body template: "+
+		"template: WalletOutOfFundsBody:2:")
+}
+
+func TestParseCallbackUnknownField(t *testing.T) {
+	_, err := parseCallback("WalletOutOfFunds", Callback{
+		QueryURL: "address={{.Adress}}",
+	}, client.WalletOutOfFundsBody{Address: sampleAddress})
+	assert.Equal(t, "callback WalletOutOfFunds has an invalid QueryURL template: "+
+		"[callback] failed to generate QueryURL for http request: "+
+		"template: WalletOutOfFundsQueryURL:1:10: executing \"WalletOutOfFundsQueryURL\" "+
+		"at <.Adress>: can't evaluate field Adress in type client.WalletOutOfFundsBody", err.Error())
+}
+
+func TestParseCallbackOutputTooLarge(t *testing.T) {
+	_, err := parseCallback("WalletOutOfFunds", Callback{
+		Body: "{{range $i := .}}{{$i}}{{end}}",
+	}, make([]int, client.MaxTemplateOutput+1))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "template output exceeds 65536 bytes")
+}
+
+func TestNewClientWithDepsInvalidTemplate(t *testing.T) {
+	config := &Config{}
+	config.TransactionCommitted.Enabled = true
+	config.TransactionCommitted.Body = "{'hash': '{{.TxHash}}'}"
+
+	_, err := NewClientWithDeps(context.Background(), &client.Deps{}, config)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "callback TransactionCommitted has an invalid Body template")
+}
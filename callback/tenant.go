@@ -0,0 +1,144 @@
+package callback
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/oasislabs/oasis-gateway/config"
+	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/spf13/viper"
+)
+
+// Route overrides the destination of a callback for a tenant.
+// The fields that are not set are taken from the default callback
+type Route struct {
+	Method   string
+	URL      string
+	Body     string
+	QueryURL string
+	Headers  []string
+}
+
+// IsSet returns true if the route overrides any field
+// of the default callback
+func (r *Route) IsSet() bool {
+	return len(r.Method) > 0 || len(r.URL) > 0 || len(r.Body) > 0 ||
+		len(r.QueryURL) > 0 || len(r.Headers) > 0
+}
+
+// Apply returns the callback with the fields set in
+// the route overridden
+func (r *Route) Apply(callback Callback) Callback {
+	if len(r.Method) > 0 {
+		callback.Method = r.Method
+	}
+	if len(r.URL) > 0 {
+		callback.URL = r.URL
+	}
+	if len(r.Body) > 0 {
+		callback.Body = r.Body
+	}
+	if len(r.QueryURL) > 0 {
+		callback.QueryURL = r.QueryURL
+	}
+	if len(r.Headers) > 0 {
+		callback.Headers = r.Headers
+	}
+
+	return callback
+}
+
+func (r *Route) Configure(prefix string, v *viper.Viper) {
+	r.Method = v.GetString(prefix + ".method")
+	r.URL = v.GetString(prefix + ".url")
+	r.Body = v.GetString(prefix + ".body")
+	r.QueryURL = v.GetString(prefix + ".queryurl")
+	r.Headers = v.GetStringSlice(prefix + ".headers")
+}
+
+func (r *Route) Log(prefix string, fields log.Fields) {
+	if !r.IsSet() {
+		return
+	}
+
+	fields.Add(prefix+".method", r.Method)
+	fields.Add(prefix+".url", r.URL)
+	fields.Add(prefix+".body", r.Body)
+	fields.Add(prefix+".queryurl", r.QueryURL)
+	fields.Add(prefix+".headers", strings.Join(r.Headers, ","))
+}
+
+// Tenant routes the callbacks triggered on behalf of its
+// AADs to its own destinations
+type Tenant struct {
+	Name                        string
+	AADs                        []string
+	TransactionCommitted        Route
+	WalletOutOfFunds            Route
+	WalletReachedFundsThreshold Route
+}
+
+func (t *Tenant) prefix() string {
+	return "callback.tenants." + t.Name
+}
+
+func (t *Tenant) Configure(v *viper.Viper) error {
+	prefix := t.prefix()
+	t.AADs = v.GetStringSlice(prefix + ".aads")
+	if len(t.AADs) == 0 {
+		return config.ErrKeyNotSet{Key: prefix + ".aads"}
+	}
+
+	t.TransactionCommitted.Configure(prefix+".transaction_committed", v)
+	t.WalletOutOfFunds.Configure(prefix+".wallet_out_of_funds", v)
+	t.WalletReachedFundsThreshold.Configure(prefix+".wallet_reached_funds_threshold", v)
+	return nil
+}
+
+func (t *Tenant) Log(fields log.Fields) {
+	prefix := t.prefix()
+	fields.Add(prefix+".aads", strings.Join(t.AADs, ","))
+	t.TransactionCommitted.Log(prefix+".transaction_committed", fields)
+	t.WalletOutOfFunds.Log(prefix+".wallet_out_of_funds", fields)
+	t.WalletReachedFundsThreshold.Log(prefix+".wallet_reached_funds_threshold", fields)
+}
+
+// Tenants are the tenants defined in the callback.tenants table
+// of the configuration file. They cannot be set with flags
+type Tenants []Tenant
+
+func (t *Tenants) Configure(v *viper.Viper) error {
+	var names []string
+	for name := range v.GetStringMap("callback.tenants") {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	tenants := make(Tenants, 0, len(names))
+	owners := make(map[string]string)
+	for _, name := range names {
+		tenant := Tenant{Name: name}
+		if err := tenant.Configure(v); err != nil {
+			return err
+		}
+
+		for _, aad := range tenant.AADs {
+			if owner, ok := owners[aad]; ok {
+				return fmt.Errorf("aad %s belongs to tenants %s and %s", aad, owner, name)
+			}
+			owners[aad] = name
+		}
+
+		tenants = append(tenants, tenant)
+	}
+
+	*t = tenants
+	return nil
+}
+
+func (t Tenants) Log(fields log.Fields) {
+	for i := range t {
+		t[i].Log(fields)
+	}
+}
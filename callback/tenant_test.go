@@ -0,0 +1,86 @@
+package callback
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func readTenants(t *testing.T, config string) (Tenants, error) {
+	v := viper.New()
+	v.SetConfigType("yaml")
+	assert.Nil(t, v.ReadConfig(strings.NewReader(config)))
+
+	var tenants Tenants
+	err := tenants.Configure(v)
+	return tenants, err
+}
+
+func TestTenantsConfigure(t *testing.T) {
+	tenants, err := readTenants(t, `
+callback:
+  tenants:
+    team_b:
+      aads: ["b@example.com"]
+    team_a:
+      aads: ["a1@example.com", "a2@example.com"]
+      wallet_out_of_funds:
+        url: "https://hooks.example.com/team_a"
+`)
+	assert.Nil(t, err)
+	assert.Equal(t, Tenants{
+		{
+			Name: "team_a",
+			AADs: []string{"a1@example.com", "a2@example.com"},
+			WalletOutOfFunds: Route{
+				URL: "https://hooks.example.com/team_a",
+			},
+		},
+		{
+			Name: "team_b",
+			AADs: []string{"b@example.com"},
+		},
+	}, tenants)
+}
+
+func TestTenantsConfigureDuplicateAAD(t *testing.T) {
+	_, err := readTenants(t, `
+callback:
+  tenants:
+    team_a:
+      aads: ["a@example.com"]
+    team_b:
+      aads: ["a@example.com"]
+`)
+	assert.Equal(t, "aad a@example.com belongs to tenants team_a and team_b", err.Error())
+}
+
+func TestTenantsConfigureNoAADs(t *testing.T) {
+	_, err := readTenants(t, `
+callback:
+  tenants:
+    team_a:
+      wallet_out_of_funds:
+        url: "https://hooks.example.com/team_a"
+`)
+	assert.Error(t, err)
+}
+
+func TestRouteApply(t *testing.T) {
+	route := Route{URL: "https://hooks.example.com/team_a"}
+	callback := route.Apply(Callback{
+		Enabled: true,
+		Method:  "POST",
+		URL:     "https://hooks.example.com/default",
+		Body:    "{}",
+	})
+
+	assert.Equal(t, Callback{
+		Enabled: true,
+		Method:  "POST",
+		URL:     "https://hooks.example.com/team_a",
+		Body:    "{}",
+	}, callback)
+}
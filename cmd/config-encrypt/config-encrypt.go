@@ -0,0 +1,124 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/oasislabs/oasis-gateway/config"
+	"github.com/spf13/cobra"
+)
+
+type CryptProps struct {
+	KeyPath string
+	Output  string
+}
+
+func readKey(props CryptProps) ([]byte, error) {
+	if len(props.KeyPath) > 0 {
+		return config.ReadKey(props.KeyPath)
+	}
+
+	key := os.Getenv("OASIS_DG_CONFIG_KEY")
+	if len(key) == 0 {
+		return nil, fmt.Errorf("the key must be set with --key_path or OASIS_DG_CONFIG_KEY")
+	}
+
+	return config.ParseKey(key)
+}
+
+func runCrypt(props CryptProps, path string, crypt func(key, p []byte) ([]byte, error)) error {
+	key, err := readKey(props)
+	if err != nil {
+		return err
+	}
+
+	p, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	out, err := crypt(key, p)
+	if err != nil {
+		return err
+	}
+
+	if len(props.Output) == 0 {
+		_, err := os.Stdout.Write(out)
+		return err
+	}
+
+	return ioutil.WriteFile(props.Output, out, 0600)
+}
+
+func runGenerateKey() error {
+	key := make([]byte, config.KeySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return err
+	}
+
+	fmt.Println(base64.StdEncoding.EncodeToString(key))
+	return nil
+}
+
+func exitOnError(err error) {
+	if err != nil {
+		fmt.Println("ERROR: ", err)
+		os.Exit(1)
+	}
+}
+
+func main() {
+	var props CryptProps
+
+	var rootCmd = &cobra.Command{
+		Use:   "config-encrypt",
+		Short: "encrypt and decrypt configuration files of the gateway",
+		Long: "Encrypts configuration files so that they can be stored in " +
+			"configuration management systems. The gateway decrypts the " +
+			"files whose path ends with .enc in memory when it starts, with " +
+			"the key set in config.key or config.key_path.",
+	}
+
+	var encryptCmd = &cobra.Command{
+		Use:   "encrypt [path]",
+		Short: "encrypt a configuration file",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			exitOnError(runCrypt(props, args[0], config.Encrypt))
+		},
+	}
+
+	var decryptCmd = &cobra.Command{
+		Use:   "decrypt [path]",
+		Short: "decrypt a configuration file",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			exitOnError(runCrypt(props, args[0], config.Decrypt))
+		},
+	}
+
+	var keyCmd = &cobra.Command{
+		Use:   "genkey",
+		Short: "generate a new base64 encoded key",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			exitOnError(runGenerateKey())
+		},
+	}
+
+	rootCmd.PersistentFlags().StringVar(
+		&props.KeyPath, "key_path", "",
+		"path to a file with the base64 encoded key. If not set the key is read from OASIS_DG_CONFIG_KEY")
+	rootCmd.PersistentFlags().StringVar(
+		&props.Output, "output", "", "path of the output file. If not set the output is written to stdout")
+	rootCmd.AddCommand(encryptCmd, decryptCmd, keyCmd)
+
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Println("failed to parse command line arguments ", err.Error())
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/oasislabs/oasis-gateway/auth/core"
+	"github.com/oasislabs/oasis-gateway/auth/insecure"
+	"github.com/oasislabs/oasis-gateway/backend"
+	"github.com/oasislabs/oasis-gateway/config"
+	"github.com/oasislabs/oasis-gateway/gateway"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// DevConfig enables the development mode, in which the gateway runs
+// on its own with the insecure authentication, in memory state and a
+// simulated backend, so that the API can be tried in one command
+type DevConfig struct {
+	Enabled bool
+}
+
+func (c *DevConfig) Bind(v *viper.Viper, cmd *cobra.Command) error {
+	cmd.PersistentFlags().Bool("dev", false,
+		"if set, the gateway runs in development mode with the "+config.PresetLocalDev+
+			" preset and the "+backend.BackendSimulated.String()+" backend, listening on "+
+			"all interfaces so that it can be reached from outside a container. "+
+			"Values that are set explicitly override the development mode.")
+	return nil
+}
+
+func (c *DevConfig) Configure(v *viper.Viper) error {
+	c.Enabled = v.GetBool("dev")
+	if !c.Enabled {
+		return nil
+	}
+
+	for key, value := range config.Presets[config.PresetLocalDev].Values {
+		v.SetDefault(key, value)
+	}
+	v.SetDefault("backend.provider", backend.BackendSimulated.String())
+	v.SetDefault("bind_public.http_interface", "0.0.0.0")
+	return nil
+}
+
+// devConfig is the configuration of the gateway with the
+// development mode. The development mode is configured first
+// so that its defaults are in place for the other binders
+type devConfig struct {
+	gateway.Config
+	Dev DevConfig
+}
+
+func (c *devConfig) Binders() []config.Binder {
+	return append([]config.Binder{&c.Dev}, c.Config.Binders()...)
+}
+
+// printDevExamples writes example requests that can be
+// copied to try the API of a gateway in development mode
+func printDevExamples(w io.Writer, config *gateway.BindPublicConfig) {
+	url := fmt.Sprintf("http://localhost:%d", config.HttpPort)
+	headers := fmt.Sprintf("-H '%s: dev-user' -H '%s: dev-session' -H 'Content-Type: application/json'",
+		insecure.HeaderKey, core.RequestHeaderSessionKey)
+
+	fmt.Fprintf(w, "\nthe gateway is running in development mode, try it with\n\n")
+	fmt.Fprintf(w, "  # deploy a service\n")
+	fmt.Fprintf(w, "  curl -X POST %s %s/v0/api/service/deploy -d '{\"data\":\"0x0061736d01000000\"}'\n\n",
+		headers, url)
+	fmt.Fprintf(w, "  # poll for the result of the deployment\n")
+	fmt.Fprintf(w, "  curl -X POST %s %s/v0/api/service/poll -d '{\"offset\":0}'\n\n", headers, url)
+}
@@ -1,104 +1,158 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/oasislabs/oasis-gateway/config"
 	"github.com/oasislabs/oasis-gateway/gateway"
 	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/oasislabs/oasis-gateway/restart"
 	"github.com/oasislabs/oasis-gateway/rpc"
 )
 
-func publicServer(config *gateway.BindPublicConfig, router *rpc.HttpRouter) {
-	httpInterface := config.HttpInterface
-	httpPort := config.HttpPort
+// server serves a router on a listener that is inherited from
+// the previous process when the gateway is restarted
+type server struct {
+	name   string
+	config *gateway.BindConfig
+	http   *http.Server
+}
 
-	s := &http.Server{
-		Addr:           fmt.Sprintf("%s:%d", httpInterface, httpPort),
-		Handler:        router,
-		ReadTimeout:    time.Duration(config.HttpReadTimeoutMs) * time.Millisecond,
-		WriteTimeout:   time.Duration(config.HttpWriteTimeoutMs) * time.Millisecond,
-		MaxHeaderBytes: int(config.HttpMaxHeaderBytes),
+func newServer(name string, config *gateway.BindConfig, router *rpc.HttpRouter) *server {
+	return &server{
+		name:   name,
+		config: config,
+		http: &http.Server{
+			Addr:           fmt.Sprintf("%s:%d", config.HttpInterface, config.HttpPort),
+			Handler:        router,
+			ReadTimeout:    time.Duration(config.HttpReadTimeoutMs) * time.Millisecond,
+			WriteTimeout:   time.Duration(config.HttpWriteTimeoutMs) * time.Millisecond,
+			MaxHeaderBytes: int(config.HttpMaxHeaderBytes),
+		},
 	}
+}
 
+func (s *server) listen(listeners *restart.Listeners) net.Listener {
 	gateway.RootLogger.Info(gateway.RootContext, "listening to port", log.MapFields{
-		"call_type": "HttpPublicListenAttempt",
-		"port":      httpPort,
-		"interface": httpInterface,
+		"call_type": "Http" + s.name + "ListenAttempt",
+		"port":      s.config.HttpPort,
+		"interface": s.config.HttpInterface,
+		"inherited": listeners.Inherited(),
 	})
 
-	if config.HttpsEnabled {
-		if err := s.ListenAndServeTLS(config.TlsCertificatePath, config.TlsPrivateKeyPath); err != nil {
-			gateway.RootLogger.Fatal(gateway.RootContext, "http server failed to listen", log.MapFields{
-				"call_type": "HttpPublicListenFailure",
-				"port":      httpPort,
-				"interface": httpInterface,
-				"err":       err.Error(),
-			})
-			os.Exit(1)
-		}
+	l, err := listeners.Listen(strings.ToLower(s.name), "tcp", s.http.Addr)
+	if err != nil {
+		s.fatal(err)
+	}
+
+	return l
+}
 
+func (s *server) serve(l net.Listener) {
+	var err error
+	if s.config.HttpsEnabled {
+		err = s.http.ServeTLS(l, s.config.TlsCertificatePath, s.config.TlsPrivateKeyPath)
 	} else {
-		if err := s.ListenAndServe(); err != nil {
-			gateway.RootLogger.Fatal(gateway.RootContext, "http server failed to listen", log.MapFields{
-				"call_type": "HttpPublicListenFailure",
-				"port":      httpPort,
-				"interface": httpInterface,
-				"err":       err.Error(),
-			})
-			os.Exit(1)
-		}
+		err = s.http.Serve(l)
+	}
+
+	if err != http.ErrServerClosed {
+		s.fatal(err)
 	}
 }
 
-func privateServer(config *gateway.BindPrivateConfig, router *rpc.HttpRouter) {
-	httpInterface := config.HttpInterface
-	httpPort := config.HttpPort
+func (s *server) fatal(err error) {
+	gateway.RootLogger.Fatal(gateway.RootContext, "http server failed to listen", log.MapFields{
+		"call_type": "Http" + s.name + "ListenFailure",
+		"port":      s.config.HttpPort,
+		"interface": s.config.HttpInterface,
+		"err":       err.Error(),
+	})
+	os.Exit(1)
+}
+
+// shutdown stops the servers from accepting new connections and
+// waits for the requests in flight up to the drain timeout
+func shutdown(timeout time.Duration, servers ...*server) {
+	ctx, cancel := context.WithTimeout(gateway.RootContext, timeout)
+	defer cancel()
 
-	s := &http.Server{
-		Addr:           fmt.Sprintf("%s:%d", httpInterface, httpPort),
-		Handler:        router,
-		ReadTimeout:    time.Duration(config.HttpReadTimeoutMs) * time.Millisecond,
-		WriteTimeout:   time.Duration(config.HttpWriteTimeoutMs) * time.Millisecond,
-		MaxHeaderBytes: int(config.HttpMaxHeaderBytes),
+	var wg sync.WaitGroup
+	wg.Add(len(servers))
+	for _, s := range servers {
+		go func(s *server) {
+			defer wg.Done()
+			if err := s.http.Shutdown(ctx); err != nil {
+				gateway.RootLogger.Warn(gateway.RootContext, "http server did not drain in time", log.MapFields{
+					"call_type": "Http" + s.name + "ShutdownFailure",
+					"err":       err.Error(),
+				})
+				s.http.Close()
+			}
+		}(s)
 	}
+	wg.Wait()
+}
 
-	gateway.RootLogger.Info(gateway.RootContext, "listening to port", log.MapFields{
-		"call_type": "HttpPrivateListenAttempt",
-		"port":      httpPort,
-		"interface": httpInterface,
-	})
+// handleSignals shuts down the servers on a SIGINT or SIGTERM and, if
+// restarts are enabled, starts a new process that takes over the
+// listeners on a SIGUSR2. The new process sends a SIGTERM once ready.
+// On shutdown stopDiscovery is called before the servers are drained,
+// so that the instance stops receiving new requests
+func handleSignals(
+	config *restart.Config,
+	listeners *restart.Listeners,
+	stopDiscovery context.CancelFunc,
+	servers ...*server,
+) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM, syscall.SIGUSR2)
 
-	if config.HttpsEnabled {
-		if err := s.ListenAndServeTLS(config.TlsCertificatePath, config.TlsPrivateKeyPath); err != nil {
-			gateway.RootLogger.Fatal(gateway.RootContext, "http server failed to listen", log.MapFields{
-				"call_type": "HttpPrivateListenFailure",
-				"port":      httpPort,
-				"interface": httpInterface,
-				"err":       err.Error(),
+	for sig := range signals {
+		if sig != syscall.SIGUSR2 {
+			gateway.RootLogger.Info(gateway.RootContext, "shutting down", log.MapFields{
+				"call_type": "ShutdownAttempt",
+				"signal":    sig.String(),
 			})
-			os.Exit(1)
+			stopDiscovery()
+			shutdown(config.DrainTimeout, servers...)
+			return
 		}
 
-	} else {
-		if err := s.ListenAndServe(); err != nil {
-			gateway.RootLogger.Fatal(gateway.RootContext, "http server failed to listen", log.MapFields{
-				"call_type": "HttpPrivateListenFailure",
-				"port":      httpPort,
-				"interface": httpInterface,
+		if !config.Enabled {
+			gateway.RootLogger.Info(gateway.RootContext, "restart requested but restarts are not enabled", log.MapFields{
+				"call_type": "RestartDisabled",
+			})
+			continue
+		}
+
+		process, err := listeners.Start()
+		if err != nil {
+			gateway.RootLogger.Warn(gateway.RootContext, "failed to start new process", log.MapFields{
+				"call_type": "RestartFailure",
 				"err":       err.Error(),
 			})
-			os.Exit(1)
+			continue
 		}
+
+		gateway.RootLogger.Info(gateway.RootContext, "started new process to take over the listeners", log.MapFields{
+			"call_type": "RestartSuccess",
+			"pid":       process.Pid,
+		})
 	}
 }
 
 func main() {
-	parser, err := config.Generate(&gateway.Config{})
+	parser, err := config.Generate(&devConfig{})
 	if err != nil {
 		fmt.Println("Failed to generate configurations: ", err.Error())
 		os.Exit(1)
@@ -113,7 +167,8 @@ func main() {
 		os.Exit(1)
 	}
 
-	config := parser.Config.(*gateway.Config)
+	dev := parser.Config.(*devConfig)
+	config := &dev.Config
 	gateway.InitLogger(&config.LoggingConfig)
 
 	gateway.RootLogger.Info(gateway.RootContext, "bind public configuration parsed", log.MapFields{
@@ -134,9 +189,31 @@ func main() {
 	gateway.RootLogger.Info(gateway.RootContext, "callback config configuration parsed", log.MapFields{
 		"callType": "CallbackConfigParseSuccess",
 	}, &config.CallbackConfig)
+	gateway.RootLogger.Info(gateway.RootContext, "discovery config configuration parsed", log.MapFields{
+		"callType": "DiscoveryConfigParseSuccess",
+	}, &config.DiscoveryConfig)
 
-	var wg sync.WaitGroup
-	wg.Add(2)
+	gateway.RootLogger.Info(gateway.RootContext, "startup configuration parsed", log.MapFields{
+		"callType": "StartupConfigParseSuccess",
+	}, &config.StartupConfig)
+	gateway.RootLogger.Info(gateway.RootContext, "restart configuration parsed", log.MapFields{
+		"callType": "RestartConfigParseSuccess",
+	}, &config.RestartConfig)
+	gateway.RootLogger.Info(gateway.RootContext, "statsd configuration parsed", log.MapFields{
+		"callType": "StatsDConfigParseSuccess",
+	}, &config.StatsDConfig)
+	gateway.RootLogger.Info(gateway.RootContext, "dev configuration parsed", log.MapFields{
+		"callType": "DevConfigParseSuccess",
+		"dev":      dev.Dev.Enabled,
+	})
+
+	if err := gateway.WaitForDependencies(gateway.RootContext, config); err != nil {
+		gateway.RootLogger.Fatal(gateway.RootContext, "failed to wait for dependencies", log.MapFields{
+			"call_type": "DependencyWaitFailure",
+			"err":       err.Error(),
+		})
+		os.Exit(1)
+	}
 
 	group, err := gateway.NewServiceGroup(gateway.RootContext, config)
 	if err != nil {
@@ -147,17 +224,67 @@ func main() {
 		os.Exit(1)
 	}
 
-	routers := gateway.NewRouters(config, group)
+	routers, err := gateway.NewRouters(config, group)
+	if err != nil {
+		gateway.RootLogger.Fatal(gateway.RootContext, "failed to initialize routers", log.MapFields{
+			"call_type": "RoutersInitFailure",
+			"err":       err.Error(),
+		})
+		os.Exit(1)
+	}
 
-	go func() {
-		publicServer(&config.BindPublicConfig, routers.Public)
-		wg.Done()
-	}()
+	discoveryCtx, stopDiscovery := context.WithCancel(gateway.RootContext)
+	registration, err := gateway.StartDiscovery(discoveryCtx, config, group)
+	if err != nil {
+		gateway.RootLogger.Fatal(gateway.RootContext, "failed to start service discovery registration", log.MapFields{
+			"call_type": "DiscoveryRegisterFailure",
+			"err":       err.Error(),
+		})
+		os.Exit(1)
+	}
 
-	go func() {
-		privateServer(&config.BindPrivateConfig, routers.Private)
-		wg.Done()
-	}()
+	if dev.Dev.Enabled {
+		printDevExamples(os.Stdout, &config.BindPublicConfig)
+	}
 
-	wg.Wait()
+	listeners, err := restart.Inherit()
+	if err != nil {
+		gateway.RootLogger.Fatal(gateway.RootContext, "failed to inherit listeners", log.MapFields{
+			"call_type": "RestartInheritFailure",
+			"err":       err.Error(),
+		})
+		os.Exit(1)
+	}
+
+	public := newServer("Public", &config.BindPublicConfig.BindConfig, routers.Public)
+	private := newServer("Private", &config.BindPrivateConfig.BindConfig, routers.Private)
+	publicListener := public.listen(listeners)
+	privateListener := private.listen(listeners)
+
+	go public.serve(publicListener)
+	go private.serve(privateListener)
+
+	if err := listeners.Ready(); err != nil {
+		gateway.RootLogger.Warn(gateway.RootContext, "failed to notify the previous process", log.MapFields{
+			"call_type": "RestartReadyFailure",
+			"err":       err.Error(),
+		})
+	}
+
+	handleSignals(&config.RestartConfig, listeners, stopDiscovery, public, private)
+
+	if registration != nil {
+		registration.Wait()
+	}
+
+	// the servers are drained, so the requests still running can
+	// complete before the wallet keys are destroyed
+	ctx, cancel := context.WithTimeout(gateway.RootContext, config.RestartConfig.DrainTimeout)
+	defer cancel()
+	if err := group.Shutdown(ctx); err != nil {
+		gateway.RootLogger.Warn(gateway.RootContext, "failed to shut down services", log.MapFields{
+			"call_type": "ServiceShutdownFailure",
+			"err":       err.Error(),
+		})
+	}
 }
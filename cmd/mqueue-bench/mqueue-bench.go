@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/oasislabs/oasis-gateway/mqueue"
+	"github.com/oasislabs/oasis-gateway/mqueue/core"
+	"github.com/oasislabs/oasis-gateway/mqueue/mem"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+type BenchProps struct {
+	Provider string
+	Addr     string
+	Addrs    []string
+	Workload WorkloadProps
+}
+
+func dialMQueue(ctx context.Context, props BenchProps) (core.MQueue, error) {
+	logger := log.NewLogrus(log.LogrusLoggerProperties{Level: logrus.WarnLevel})
+	services := mqueue.Services{Logger: logger}
+
+	switch mqueue.MailboxProvider(props.Provider) {
+	case mqueue.MailboxMem:
+		return mem.NewServer(ctx, mem.Services{Logger: logger}), nil
+	case mqueue.MailboxRedisSingle:
+		return mqueue.NewRedisSingleMailbox(ctx, services,
+			&mqueue.MailboxRedisSingleConfig{Addr: props.Addr})
+	case mqueue.MailboxRedisCluster:
+		return mqueue.NewRedisClusterMailbox(ctx, services,
+			&mqueue.MailboxRedisClusterConfig{Addrs: props.Addrs})
+	default:
+		return nil, fmt.Errorf("unknown provider %s", props.Provider)
+	}
+}
+
+func runBench(props BenchProps) error {
+	ctx := context.Background()
+
+	m, err := dialMQueue(ctx, props)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("running workload against %s with %d workers for %s\n",
+		m.Name(), props.Workload.Workers, props.Workload.Duration)
+
+	report, err := runWorkload(ctx, m, props.Workload)
+	if err != nil {
+		return err
+	}
+
+	report.Print(os.Stdout)
+	return nil
+}
+
+func main() {
+	var props BenchProps
+
+	var rootCmd = &cobra.Command{
+		Use:   "mqueue-bench",
+		Short: "load test an mqueue backend",
+		Long: "Drives an insert, retrieve and discard workload against an mqueue " +
+			"backend and reports the throughput and latency distribution of " +
+			"each operation.",
+		Args: cobra.MinimumNArgs(0),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := runBench(props); err != nil {
+				fmt.Println("ERROR: ", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	rootCmd.PersistentFlags().StringVar(
+		&props.Provider, "provider", "mem", "mqueue backend to benchmark. Options are "+
+			mqueue.MailboxMem.String()+", "+mqueue.MailboxRedisSingle.String()+
+			", "+mqueue.MailboxRedisCluster.String()+".")
+	rootCmd.PersistentFlags().StringVar(
+		&props.Addr, "redis_single.addr", "127.0.0.1:6379", "redis instance address")
+	rootCmd.PersistentFlags().StringArrayVar(
+		&props.Addrs, "redis_cluster.addrs", []string{"127.0.0.1:6379"},
+		"array of addresses for bootstrap redis instances in the cluster")
+	rootCmd.PersistentFlags().UintVar(
+		&props.Workload.Workers, "workers", 16, "number of concurrent workers, each with its own queue")
+	rootCmd.PersistentFlags().DurationVar(
+		&props.Workload.Duration, "duration", 10*time.Second, "duration of the workload")
+	rootCmd.PersistentFlags().UintVar(
+		&props.Workload.Batch, "batch", 10, "number of elements inserted before they are retrieved")
+	rootCmd.PersistentFlags().UintVar(
+		&props.Workload.ValueSize, "valueSize", 256, "size in bytes of the value of each element")
+	rootCmd.PersistentFlags().BoolVar(
+		&props.Workload.Discard, "discard", true, "discard the elements after they are retrieved")
+
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Println("failed to parse command line arguments ", err.Error())
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	nextOp     = "Next"
+	insertOp   = "Insert"
+	retrieveOp = "Retrieve"
+	discardOp  = "Discard"
+)
+
+var operations = []string{nextOp, insertOp, retrieveOp, discardOp}
+
+// Recorder keeps track of the latencies and errors of the
+// operations issued by the workers
+type Recorder struct {
+	lock      sync.Mutex
+	latencies map[string][]time.Duration
+	errors    map[string]uint64
+}
+
+// NewRecorder creates a new empty recorder
+func NewRecorder() *Recorder {
+	return &Recorder{
+		latencies: make(map[string][]time.Duration),
+		errors:    make(map[string]uint64),
+	}
+}
+
+// Record records the completion of an operation
+func (r *Recorder) Record(op string, latency time.Duration, err error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if err != nil {
+		r.errors[op]++
+		return
+	}
+
+	r.latencies[op] = append(r.latencies[op], latency)
+}
+
+// Report generates the report from the recorded operations for
+// a workload that took elapsed time
+func (r *Recorder) Report(elapsed time.Duration) *Report {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	report := &Report{Elapsed: elapsed}
+	for _, op := range operations {
+		latencies := r.latencies[op]
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+		stats := OperationStats{
+			Operation: op,
+			Count:     uint64(len(latencies)),
+			Errors:    r.errors[op],
+		}
+
+		if len(latencies) > 0 {
+			stats.Throughput = float64(len(latencies)) / elapsed.Seconds()
+			stats.P50 = percentile(latencies, 0.50)
+			stats.P90 = percentile(latencies, 0.90)
+			stats.P99 = percentile(latencies, 0.99)
+			stats.Max = latencies[len(latencies)-1]
+		}
+
+		report.Operations = append(report.Operations, stats)
+	}
+
+	return report
+}
+
+// percentile returns the latency at the p percentile of
+// the sorted latencies
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	index := int(float64(len(sorted)-1) * p)
+	return sorted[index]
+}
+
+// OperationStats are the collected statistics for a
+// single operation
+type OperationStats struct {
+	Operation  string
+	Count      uint64
+	Errors     uint64
+	Throughput float64
+	P50        time.Duration
+	P90        time.Duration
+	P99        time.Duration
+	Max        time.Duration
+}
+
+// Report is the result of running a workload
+type Report struct {
+	Elapsed    time.Duration
+	Operations []OperationStats
+}
+
+// Print writes the report in a human readable table
+func (r *Report) Print(w io.Writer) {
+	fmt.Fprintf(w, "elapsed %s\n", r.Elapsed)
+	fmt.Fprintf(w, "%-10s %10s %8s %12s %12s %12s %12s %12s\n",
+		"op", "count", "errors", "ops/s", "p50", "p90", "p99", "max")
+	for _, s := range r.Operations {
+		fmt.Fprintf(w, "%-10s %10d %8d %12.1f %12s %12s %12s %12s\n",
+			s.Operation, s.Count, s.Errors, s.Throughput, s.P50, s.P90, s.P99, s.Max)
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oasislabs/oasis-gateway/mqueue/core"
+)
+
+type WorkloadProps struct {
+	// Workers is the number of concurrent workers issuing requests
+	Workers uint
+
+	// Duration of the workload
+	Duration time.Duration
+
+	// Batch is the number of elements a worker inserts before
+	// retrieving them
+	Batch uint
+
+	// ValueSize is the size in bytes of the value of each element
+	ValueSize uint
+
+	// Discard if set discards the elements after they are retrieved
+	Discard bool
+}
+
+// worker drives the workload against its own queue
+type worker struct {
+	mqueue   core.MQueue
+	key      string
+	value    string
+	props    WorkloadProps
+	recorder *Recorder
+}
+
+func (w *worker) run(ctx context.Context) error {
+	offsets := make([]uint64, 0, w.props.Batch)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		offsets = offsets[:0]
+		for i := uint(0); i < w.props.Batch; i++ {
+			start := time.Now()
+			offset, err := w.mqueue.Next(ctx, core.NextRequest{Key: w.key})
+			w.recorder.Record(nextOp, time.Since(start), err)
+			if err != nil {
+				// if elements are not discarded the queue is expected to
+				// eventually reach its capacity, at which point the worker
+				// has nothing else to do
+				if ctx.Err() != nil || !w.props.Discard {
+					return nil
+				}
+				return fmt.Errorf("failed to get next offset for %s: %s", w.key, err)
+			}
+
+			start = time.Now()
+			err = w.mqueue.Insert(ctx, core.InsertRequest{
+				Key: w.key,
+				Element: core.Element{
+					Offset: offset,
+					Value:  w.value,
+					Type:   "bench",
+				},
+			})
+			w.recorder.Record(insertOp, time.Since(start), err)
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return fmt.Errorf("failed to insert element for %s: %s", w.key, err)
+			}
+
+			offsets = append(offsets, offset)
+		}
+
+		start := time.Now()
+		_, err := w.mqueue.Retrieve(ctx, core.RetrieveRequest{
+			Key:    w.key,
+			Offset: offsets[0],
+			Count:  w.props.Batch,
+		})
+		w.recorder.Record(retrieveOp, time.Since(start), err)
+		if err != nil && ctx.Err() != nil {
+			return nil
+		}
+
+		if !w.props.Discard {
+			continue
+		}
+
+		// discard the batch the same way clients do when polling
+		// with discardPrevious set
+		start = time.Now()
+		err = w.mqueue.Discard(ctx, core.DiscardRequest{
+			Key:    w.key,
+			Offset: offsets[len(offsets)-1] + 1,
+		})
+		w.recorder.Record(discardOp, time.Since(start), err)
+		if err != nil && ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+// runWorkload runs the workload described by props against the
+// provided mqueue and returns the collected report
+func runWorkload(ctx context.Context, m core.MQueue, props WorkloadProps) (*Report, error) {
+	if props.Workers == 0 || props.Batch == 0 {
+		return nil, fmt.Errorf("workers and batch must be greater than 0")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, props.Duration)
+	defer cancel()
+
+	recorder := NewRecorder()
+	value := strings.Repeat("x", int(props.ValueSize))
+	prefix := fmt.Sprintf("mqueue-bench-%d", time.Now().UnixNano())
+
+	var wg sync.WaitGroup
+	errs := make(chan error, props.Workers)
+	start := time.Now()
+
+	for i := uint(0); i < props.Workers; i++ {
+		w := &worker{
+			mqueue:   m,
+			key:      fmt.Sprintf("%s-%d", prefix, i),
+			value:    value,
+			props:    props,
+			recorder: recorder,
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := w.run(ctx); err != nil {
+				errs <- err
+				cancel()
+			}
+		}()
+	}
+
+	wg.Wait()
+	elapsed := time.Since(start)
+	close(errs)
+
+	for i := uint(0); i < props.Workers; i++ {
+		_ = m.Remove(context.Background(), core.RemoveRequest{
+			Key: fmt.Sprintf("%s-%d", prefix, i),
+		})
+	}
+
+	if err, ok := <-errs; ok {
+		return nil, err
+	}
+
+	return recorder.Report(elapsed), nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/oasislabs/oasis-gateway/mqueue"
+	"github.com/oasislabs/oasis-gateway/mqueue/redis"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+type ReencryptProps struct {
+	Provider string
+	Addr     string
+	Addrs    []string
+	Keys     []string
+	Primary  string
+	Match    string
+}
+
+func dialMQueue(props ReencryptProps) (*redis.MQueue, error) {
+	encryption := mqueue.EncryptionConfig{
+		Keys:    make(map[string][]byte),
+		Primary: props.Primary,
+	}
+	for _, k := range props.Keys {
+		id, key, err := redis.ParseKey(k)
+		if err != nil {
+			return nil, err
+		}
+		encryption.Keys[id] = key
+	}
+
+	if len(encryption.Keys) == 0 {
+		return nil, fmt.Errorf("at least one key needs to be set")
+	}
+
+	keyring, err := encryption.Keyring()
+	if err != nil {
+		return nil, err
+	}
+
+	redisProps := redis.Props{
+		Context: context.Background(),
+		Logger:  log.NewLogrus(log.LogrusLoggerProperties{Level: logrus.WarnLevel}),
+		Keyring: keyring,
+	}
+
+	switch mqueue.MailboxProvider(props.Provider) {
+	case mqueue.MailboxRedisSingle:
+		return redis.NewSingleMQueue(redis.SingleInstanceProps{
+			Props: redisProps,
+			Addr:  props.Addr,
+		})
+	case mqueue.MailboxRedisCluster:
+		return redis.NewClusterMQueue(redis.ClusterProps{
+			Props: redisProps,
+			Addrs: props.Addrs,
+		})
+	default:
+		return nil, fmt.Errorf("unknown provider %s", props.Provider)
+	}
+}
+
+func runReencrypt(props ReencryptProps) error {
+	m, err := dialMQueue(props)
+	if err != nil {
+		return err
+	}
+
+	res, err := m.ReencryptAll(context.Background(), props.Match)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("reencrypted %d elements in %d queues, %d queues failed\n",
+		res.Elements, res.Queues, res.Failed)
+	if res.Failed > 0 {
+		return fmt.Errorf("%d queues failed to be reencrypted", res.Failed)
+	}
+
+	return nil
+}
+
+func main() {
+	var props ReencryptProps
+
+	var rootCmd = &cobra.Command{
+		Use:   "mqueue-reencrypt",
+		Short: "reencrypt the queues of a redis mailbox with the primary key",
+		Long: "Rewrites the values of the elements of the queues of a redis " +
+			"mailbox that are stored in plaintext or encrypted with a key " +
+			"that is not the primary key. It is meant to be run after " +
+			"enabling encryption or rotating the primary key, before the " +
+			"previous keys are removed from the gateway configuration.",
+		Args: cobra.MinimumNArgs(0),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := runReencrypt(props); err != nil {
+				fmt.Println("ERROR: ", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	rootCmd.PersistentFlags().StringVar(
+		&props.Provider, "provider", mqueue.MailboxRedisSingle.String(),
+		"mqueue backend to reencrypt. Options are "+mqueue.MailboxRedisSingle.String()+
+			", "+mqueue.MailboxRedisCluster.String()+".")
+	rootCmd.PersistentFlags().StringVar(
+		&props.Addr, "redis_single.addr", "127.0.0.1:6379", "redis instance address")
+	rootCmd.PersistentFlags().StringArrayVar(
+		&props.Addrs, "redis_cluster.addrs", []string{"127.0.0.1:6379"},
+		"array of addresses for bootstrap redis instances in the cluster")
+	rootCmd.PersistentFlags().StringArrayVar(
+		&props.Keys, "keys", nil,
+		"array of keys of the form <id>:<base64 key>, as set in mailbox.encryption.keys")
+	rootCmd.PersistentFlags().StringVar(
+		&props.Primary, "primary", "", "ID of the key the values are reencrypted with")
+	rootCmd.PersistentFlags().StringVar(
+		&props.Match, "match", "*", "pattern of the keys of the queues to reencrypt")
+
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Println("failed to parse command line arguments ", err.Error())
+		os.Exit(1)
+	}
+}
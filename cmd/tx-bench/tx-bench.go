@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/oasislabs/oasis-gateway/tx/txbench"
+	"github.com/spf13/cobra"
+)
+
+type BenchProps struct {
+	Scenarios   []string
+	BudgetScale float64
+}
+
+func selectScenarios(names []string) ([]txbench.Scenario, error) {
+	if len(names) == 0 {
+		return txbench.Scenarios, nil
+	}
+
+	var scenarios []txbench.Scenario
+	for _, name := range names {
+		found := false
+		for _, scenario := range txbench.Scenarios {
+			if scenario.Name == name {
+				scenarios = append(scenarios, scenario)
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return nil, fmt.Errorf("unknown scenario %s", name)
+		}
+	}
+
+	return scenarios, nil
+}
+
+func runBench(props BenchProps) error {
+	scenarios, err := selectScenarios(props.Scenarios)
+	if err != nil {
+		return err
+	}
+
+	var exceeded []string
+	fmt.Printf("%-15s %10s %15s %15s %8s\n", "scenario", "ops", "time/op", "budget/op", "status")
+	for _, scenario := range scenarios {
+		res := testing.Benchmark(txbench.Benchmark(scenario))
+		if res.N == 0 {
+			return fmt.Errorf("scenario %s failed to run", scenario.Name)
+		}
+
+		perOp := time.Duration(res.NsPerOp())
+		budget := time.Duration(float64(scenario.Budget) * props.BudgetScale)
+		status := "ok"
+		if perOp > budget {
+			status = "FAIL"
+			exceeded = append(exceeded, scenario.Name)
+		}
+
+		fmt.Printf("%-15s %10d %15s %15s %8s\n", scenario.Name, res.N, perOp, budget, status)
+	}
+
+	if len(exceeded) > 0 {
+		return fmt.Errorf("scenarios %v exceeded their performance budget", exceeded)
+	}
+
+	return nil
+}
+
+func main() {
+	var props BenchProps
+
+	var rootCmd = &cobra.Command{
+		Use:   "tx-bench",
+		Short: "benchmark the transaction executor",
+		Long: "Runs the transaction executor benchmark scenarios against a mock " +
+			"client and fails if any of them exceeds its performance budget.",
+		Args: cobra.MinimumNArgs(0),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := runBench(props); err != nil {
+				fmt.Println("ERROR: ", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	rootCmd.PersistentFlags().StringSliceVar(
+		&props.Scenarios, "scenario", nil, "scenarios to run. If not set all scenarios are run")
+	rootCmd.PersistentFlags().Float64Var(
+		&props.BudgetScale, "budgetScale", 1.0, "factor applied to the performance budget of each scenario")
+
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Println("failed to parse command line arguments ", err.Error())
+		os.Exit(1)
+	}
+}
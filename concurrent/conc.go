@@ -41,8 +41,31 @@ func (e ErrMaxAttemptsReached) Error() string {
 	return fmt.Sprintf("maximum number of attempts %d reached", len(e.Causes))
 }
 
+// ErrUnexpectedRequest is returned by handlers that receive
+// a request of a type that they do not handle
+type ErrUnexpectedRequest struct {
+	Request interface{}
+}
+
+// Error implementation of error for ErrUnexpectedRequest
+func (e ErrUnexpectedRequest) Error() string {
+	return fmt.Sprintf("unexpected request of type %T", e.Request)
+}
+
+// ErrUnexpectedEvent is returned by handlers that receive
+// an event of a type that they do not handle
+type ErrUnexpectedEvent struct {
+	Event interface{}
+}
+
+// Error implementation of error for ErrUnexpectedEvent
+func (e ErrUnexpectedEvent) Error() string {
+	return fmt.Sprintf("unexpected event of type %T", e.Event)
+}
+
 const (
 	defaultConcurrency     uint8         = 2
+	defaultQueueSize       int           = 128
 	defaultBaseTimeout     time.Duration = 100 * time.Millisecond
 	defaultBaseExp         uint8         = 2
 	defaultMaxRetryTimeout time.Duration = 10 * time.Second
@@ -132,9 +155,15 @@ func RetryWithConfig(
 		select {
 		case <-ctx.Done():
 			timer.Stop()
-			return nil, stderr.WithStack(context.Canceled)
+			return nil, stderr.WithStack(ctx.Err())
 
 		case <-timer.C:
+			// the timer and the context may be ready at the same
+			// time, in which case the operation is not attempted
+			if err := ctx.Err(); err != nil {
+				return nil, stderr.WithStack(err)
+			}
+
 			v, err := supplier.Supply()
 			if err == nil {
 				return v, nil
@@ -188,6 +217,10 @@ type PoolConfig struct {
 	// Concurrency is the number of Suppliers that the pool can
 	// run in parallel at most
 	Concurrency uint8
+
+	// QueueSize is the number of Suppliers that each goroutine of
+	// the pool can have queued before submitting more blocks
+	QueueSize int
 }
 
 // PoolRunner has a fixed number of goroutines that are used to run
@@ -215,6 +248,9 @@ func NewPoolRunnerWithConfig(ctx context.Context, config PoolConfig) *PoolRunner
 	if config.Concurrency == 0 {
 		config.Concurrency = defaultConcurrency
 	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = defaultQueueSize
+	}
 
 	runner := PoolRunner{
 		config: config,
@@ -223,7 +259,7 @@ func NewPoolRunnerWithConfig(ctx context.Context, config PoolConfig) *PoolRunner
 
 	runner.wg.Add(int(config.Concurrency))
 	for i := 0; i < int(config.Concurrency); i++ {
-		runner.argCh[i] = make(chan argument, 128)
+		runner.argCh[i] = make(chan argument, config.QueueSize)
 		go runner.run(ctx, runner.argCh[i])
 	}
 
@@ -293,6 +329,25 @@ func (r *PoolRunner) RunAndDiscard(supplier Supplier) {
 	}
 }
 
+// TryRunAndDiscard is the same as RunAndDiscard but it does not block if
+// the queue of the goroutine that would run the supplier is full. In that
+// case the supplier is not run and false is returned
+func (r *PoolRunner) TryRunAndDiscard(supplier Supplier) bool {
+	current := atomic.AddUint64(&r.counter, 1)
+	index := current % uint64(r.config.Concurrency)
+	select {
+	case r.argCh[index] <- argument{
+		Out:           nil,
+		Supplier:      supplier,
+		Index:         current,
+		TimeSubmitted: time.Now().UnixNano(),
+	}:
+		return true
+	default:
+		return false
+	}
+}
+
 // Stop orderly stops all the goroutines in the PoolRunner
 // and returns once all the goroutines have exited
 func (r *PoolRunner) Stop() {
@@ -125,6 +125,33 @@ func TestPoolRun(t *testing.T) {
 	assert.Equal(t, counter, len)
 }
 
+func TestPoolTryRunAndDiscardQueueFull(t *testing.T) {
+	pool := NewPoolRunnerWithConfig(context.Background(), PoolConfig{
+		Concurrency: 1,
+		QueueSize:   1,
+	})
+	defer pool.Stop()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	assert.True(t, pool.TryRunAndDiscard(SupplierFunc(func() (interface{}, error) {
+		close(started)
+		<-release
+		return nil, nil
+	})))
+	<-started
+
+	// the goroutine is busy so only one more supplier can be queued
+	assert.True(t, pool.TryRunAndDiscard(SupplierFunc(func() (interface{}, error) {
+		return nil, nil
+	})))
+	assert.False(t, pool.TryRunAndDiscard(SupplierFunc(func() (interface{}, error) {
+		return nil, nil
+	})))
+
+	close(release)
+}
+
 func BenchmarkPoolRunner(b *testing.B) {
 	res := make(chan Result, 64)
 	supplier := SupplierFunc(func() (interface{}, error) {
@@ -6,6 +6,7 @@ import (
 	"sync"
 	"sync/atomic"
 
+	"github.com/oasislabs/oasis-gateway/stats"
 	stderr "github.com/pkg/errors"
 )
 
@@ -131,6 +132,31 @@ type Master struct {
 	// of its Start-Stop span
 	ctx context.Context
 
+	// onWorkerPanic is called when the loop of a worker panics
+	onWorkerPanic func(key string, err error)
+
+	// supervisor restarts the loop of the master if it panics
+	supervisor *Supervisor
+
+	// panics is the number of times the loop of a worker panicked
+	panics uint64
+
+	// exits is the number of workers that exited on their own
+	// with an error
+	exits uint64
+
+	// active is the number of workers currently running
+	active int64
+
+	// pending tracks the requests whose delivery was handed off
+	// to a separate goroutine because the channel they are sent
+	// through was full
+	pending sync.WaitGroup
+
+	// loopDone is closed when the loop of the master has exited,
+	// so no more requests are handed off
+	loopDone chan struct{}
+
 	// Error is set in case of exiting with an error
 	Error error
 }
@@ -161,6 +187,10 @@ type MasterProps struct {
 	// used if a worker does not need a specific request passed on to the
 	// CreateWorkerEvent handler
 	CreateWorkerOnRequest bool
+
+	// OnWorkerPanic if set is called from the goroutine of a worker
+	// when its loop panics, before the loop is restarted
+	OnWorkerPanic func(key string, err error)
 }
 
 // NewMaster creates a new master
@@ -168,12 +198,27 @@ func NewMaster(props MasterProps) *Master {
 	return &Master{
 		createWorkerOnRequest: props.CreateWorkerOnRequest,
 		handler:               props.MasterHandler,
+		onWorkerPanic:         props.OnWorkerPanic,
+		supervisor:            NewSupervisor(DefaultSupervisorProps),
 		workers:               make(map[string]*Worker),
 		shutdownWorkers:       make(map[string]*Worker),
 		state:                 stopped,
 	}
 }
 
+// Stats returns the number of times the loop of the master was
+// restarted, the number of times the loops of the workers panicked,
+// the number of workers that exited with an error and the number
+// of workers currently running
+func (m *Master) Stats() stats.Metrics {
+	return stats.Metrics{
+		"active":       atomic.LoadInt64(&m.active),
+		"loopRestarts": m.supervisor.Restarts(),
+		"workerPanics": atomic.LoadUint64(&m.panics),
+		"workerExits":  atomic.LoadUint64(&m.exits),
+	}
+}
+
 // IsStopped returns true if the master is not running
 func (m *Master) IsStopped() bool {
 	return atomic.LoadUint32(&m.state) == stopped
@@ -190,6 +235,7 @@ func (m *Master) Start(ctx context.Context) error {
 	m.doneCh = make(chan workerDestroyed, 64)
 	m.shutdownCh = make(chan interface{})
 	m.inCh = make(chan request)
+	m.loopDone = make(chan struct{})
 
 	go m.startLoop(ctx)
 	return nil
@@ -204,7 +250,9 @@ func (m *Master) Stop() error {
 	}
 
 	close(m.shutdownCh)
+	<-m.loopDone
 	m.workerCount.Wait()
+	m.pending.Wait()
 
 	close(m.sharedCh)
 	close(m.inCh)
@@ -231,8 +279,13 @@ func (m *Master) Create(ctx context.Context, key string, value interface{}) erro
 		return stderr.New("master is not started")
 	}
 
+	// once the loop receives the request the worker may be created,
+	// so the response is always waited for to report the outcome
 	out := make(chan error)
-	m.inCh <- createRequest{Context: ctx, Key: key, Out: out, Value: value}
+	if err := m.send(ctx, createRequest{Context: ctx, Key: key, Out: out, Value: value}); err != nil {
+		return err
+	}
+
 	return <-out
 }
 
@@ -244,7 +297,10 @@ func (m *Master) Destroy(ctx context.Context, key string) error {
 	}
 
 	out := make(chan Response)
-	m.inCh <- destroyRequest{Context: ctx, Key: key, Out: out}
+	if err := m.send(ctx, destroyRequest{Context: ctx, Key: key, Out: out}); err != nil {
+		return err
+	}
+
 	res := <-out
 	if res.Error != nil {
 		return res.Error
@@ -252,12 +308,15 @@ func (m *Master) Destroy(ctx context.Context, key string) error {
 
 	// wait for the worker to destroy
 	c := res.Value.(<-chan error)
-	err, ok := <-c
-	if ok && err != nil {
-		return err
+	select {
+	case err, ok := <-c:
+		if ok && err != nil {
+			return err
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
-
-	return nil
 }
 
 // Exists returns true if the worker exists, false otherwise
@@ -268,7 +327,10 @@ func (m *Master) Exists(ctx context.Context, key string) (bool, error) {
 	}
 
 	out := make(chan bool)
-	m.inCh <- existsRequest{Context: ctx, Key: key, Out: out}
+	if err := m.send(ctx, existsRequest{Context: ctx, Key: key, Out: out}); err != nil {
+		return false, err
+	}
+
 	return <-out, nil
 }
 
@@ -280,17 +342,19 @@ func (m *Master) Request(ctx context.Context, key string, req interface{}) (inte
 		return nil, stderr.New("master is not started")
 	}
 
-	out := make(chan Response)
+	out := make(chan Response, 1)
 	count := int32(1)
-	m.inCh <- workerRequest{
+	if err := m.send(ctx, workerRequest{
 		Context: ctx,
 		Key:     key,
 		Value:   req,
 		Out:     out,
 		Count:   &count,
+	}); err != nil {
+		return nil, err
 	}
-	res := <-out
-	return res.Value, res.Error
+
+	return receive(ctx, out)
 }
 
 // Broadcast sends the same request to all workers and waits until
@@ -301,14 +365,28 @@ func (m *Master) Broadcast(ctx context.Context, req interface{}) ([]Response, er
 		return nil, stderr.New("master is not started")
 	}
 
-	out := make(chan Response)
-	m.inCh <- broadcastRequest{Context: ctx, Value: req, Out: out}
-	var responses []Response
-	for res := range out {
-		responses = append(responses, res)
+	out := make(chan Response, 1)
+	if err := m.send(ctx, broadcastRequest{Context: ctx, Value: req, Out: out}); err != nil {
+		return nil, err
 	}
 
-	return responses, nil
+	var responses []Response
+	for {
+		select {
+		case res, ok := <-out:
+			if !ok {
+				// responses may have been dropped if the
+				// context of the request is done
+				if err := ctx.Err(); err != nil {
+					return nil, err
+				}
+				return responses, nil
+			}
+			responses = append(responses, res)
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
 }
 
 // Execute sends a request that will be caught by any worker which
@@ -319,10 +397,46 @@ func (m *Master) Execute(ctx context.Context, req interface{}) (interface{}, err
 		return nil, stderr.New("master is not started")
 	}
 
-	out := make(chan Response)
-	m.inCh <- executeRequest{Context: ctx, Value: req, Out: out}
-	res := <-out
-	return res.Value, res.Error
+	out := make(chan Response, 1)
+	if err := m.send(ctx, executeRequest{Context: ctx, Value: req, Out: out}); err != nil {
+		return nil, err
+	}
+
+	return receive(ctx, out)
+}
+
+// send passes a request to the loop of the master. It fails if the
+// context of the request is done before the loop receives it
+func (m *Master) send(ctx context.Context, req request) error {
+	// select picks any of the ready cases, so a request whose context
+	// is already done could otherwise still reach the loop
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	select {
+	case m.inCh <- req:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// receive waits for the response to a request until the context of
+// the request is done. The channel must be buffered so that the loop
+// of the master does not block on a response nobody waits for
+func receive(ctx context.Context, out <-chan Response) (interface{}, error) {
+	select {
+	case res, ok := <-out:
+		if !ok {
+			// the worker closes the channel without a response
+			// when it finds out the context of the request is done
+			return nil, ctx.Err()
+		}
+		return res.Value, res.Error
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
 }
 
 // shutdown closes all the workers and frees the resources
@@ -358,35 +472,43 @@ func (m *Master) shutdownWorker(key string) (<-chan error, bool) {
 	// set of workers which are being shutdown
 	delete(m.workers, key)
 	m.shutdownWorkers[key] = w
+	w.close()
 	close(w.C)
+	w.lock.Unlock()
 	return w.ShutdownC, true
 }
 
 func (m *Master) startLoop(ctx context.Context) {
-	defer func() {
-		if r := recover(); r != nil {
-			panic(errorFromPanic(r))
-		}
+	m.ctx = ctx
 
-		m.shutdown()
-	}()
+	// the state of the loop is kept in the master, so the loop can be
+	// restarted after a panic. If it keeps panicking there is no
+	// loop to serve the requests and the master cannot recover
+	err := m.supervisor.Run(ctx, m.loop)
+	if _, ok := err.(ErrMaxRestartsReached); ok {
+		panic(err)
+	}
 
-	m.ctx = ctx
+	close(m.loopDone)
+	m.shutdown()
+}
+
+func (m *Master) loop(ctx context.Context) error {
 	for {
 		select {
 		case <-ctx.Done():
-			return
+			return nil
 		case <-m.shutdownCh:
-			return
+			return nil
 		case ev, ok := <-m.doneCh:
 			if !ok {
-				return
+				return nil
 			}
 
 			m.removeWorker(ev)
 		case req, ok := <-m.inCh:
 			if !ok {
-				return
+				return nil
 			}
 
 			m.handleRequest(req)
@@ -433,7 +555,46 @@ func (m *Master) handleWorkerRequest(req workerRequest) {
 		}
 	}
 
-	w.C <- req
+	m.deliver(w, req)
+}
+
+// deliver passes a request to a worker without blocking the loop. A
+// worker that is busy or stalls stops reading its requests, and
+// waiting for it in the loop would hold up the requests for all the
+// other workers as well. Once its channel is full the request waits
+// for the worker in a separate goroutine until the worker takes it,
+// the context of the request is done or the worker is closed
+func (m *Master) deliver(w *Worker, req workerRequest) {
+	select {
+	case w.C <- req:
+		return
+	default:
+	}
+
+	m.pending.Add(1)
+	go func() {
+		defer m.pending.Done()
+
+		// the lock ensures that the channel of the worker is not
+		// closed while the request is sent through it
+		w.lock.RLock()
+		defer w.lock.RUnlock()
+
+		select {
+		case <-w.closed:
+			respond(req, Response{Key: w.key, Error: ErrWorkerExited{Key: w.key}})
+			return
+		default:
+		}
+
+		select {
+		case w.C <- req:
+		case <-w.closed:
+			respond(req, Response{Key: w.key, Error: ErrWorkerExited{Key: w.key}})
+		case <-req.Context.Done():
+			respond(req, Response{Key: w.key, Error: req.Context.Err()})
+		}
+	}()
 }
 
 func (m *Master) handleBroadcastRequest(req broadcastRequest) {
@@ -444,13 +605,13 @@ func (m *Master) handleBroadcastRequest(req broadcastRequest) {
 
 	count := int32(len(m.workers))
 	for _, w := range m.workers {
-		w.C <- workerRequest{
+		m.deliver(w, workerRequest{
 			Context: req.Context,
 			Key:     w.key,
 			Value:   req.Value,
 			Out:     req.Out,
 			Count:   &count,
-		}
+		})
 	}
 }
 
@@ -461,7 +622,31 @@ func (m *Master) handleExecuteRequest(req executeRequest) {
 		return
 	}
 
-	m.sharedCh <- req
+	select {
+	case m.sharedCh <- req:
+		return
+	default:
+	}
+
+	// the request waits for a worker in a separate goroutine, so
+	// that workers that are all busy do not block the loop
+	m.pending.Add(1)
+	go func() {
+		defer m.pending.Done()
+
+		var err error
+		select {
+		case m.sharedCh <- req:
+			return
+		case <-req.Context.Done():
+			err = req.Context.Err()
+		case <-m.shutdownCh:
+			err = stderr.New("master is stopping")
+		}
+
+		req.Out <- Response{Value: nil, Error: err}
+		close(req.Out)
+	}()
 }
 
 func (m *Master) createWorker(ctx context.Context, key string, value interface{}) (err error) {
@@ -497,9 +682,16 @@ func (m *Master) createWorker(ctx context.Context, key string, value interface{}
 		ErrC:          props.ErrC,
 		C:             ch,
 		SharedC:       m.sharedCh,
+		OnPanic: func(err error) {
+			atomic.AddUint64(&m.panics, 1)
+			if m.onWorkerPanic != nil {
+				m.onWorkerPanic(key, err)
+			}
+		},
 	})
 
 	m.workerCount.Add(1)
+	atomic.AddInt64(&m.active, 1)
 	return nil
 }
 
@@ -535,6 +727,56 @@ func (m *Master) handleExistsRequest(req existsRequest) {
 }
 
 func (m *Master) removeWorker(ev workerDestroyed) {
+	if w, ok := m.workers[ev.Key]; ok && w == ev.Worker {
+		m.removeExitedWorker(w, ev.Cause)
+		return
+	}
+
+	m.removeShutdownWorker(ev)
+}
+
+// removeExitedWorker removes a worker that exited without being
+// shutdown by the master, so that requests for its key are not sent
+// to a worker that does not serve them. The requests it had not
+// handled fail with ErrWorkerExited
+func (m *Master) removeExitedWorker(w *Worker, cause error) {
+	delete(m.workers, w.key)
+	m.workerCount.Done()
+	atomic.AddInt64(&m.active, -1)
+	if cause != nil {
+		atomic.AddUint64(&m.exits, 1)
+	}
+
+	// the requests waiting to be sent to the worker fail as well
+	w.close()
+	w.lock.Unlock()
+
+	for pending := true; pending; {
+		select {
+		case req := <-w.C:
+			respond(req, Response{Key: w.key, Error: ErrWorkerExited{Key: w.key, Cause: cause}})
+		default:
+			pending = false
+		}
+	}
+
+	func() {
+		// nobody waits for the destruction of the worker
+		// so a failure of the handler can only be ignored
+		defer func() {
+			_ = recover()
+		}()
+
+		_ = m.handler.Handle(context.Background(), DestroyWorkerEvent{
+			Worker: w,
+			Key:    w.key,
+		})
+	}()
+
+	close(w.ShutdownC)
+}
+
+func (m *Master) removeShutdownWorker(ev workerDestroyed) {
 	var (
 		ok  bool
 		err error
@@ -568,6 +810,7 @@ func (m *Master) removeWorker(ev workerDestroyed) {
 
 	delete(m.shutdownWorkers, ev.Key)
 	m.workerCount.Done()
+	atomic.AddInt64(&m.active, -1)
 
 	err = m.handler.Handle(context.Background(), DestroyWorkerEvent{
 		Worker: w,
@@ -4,8 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -470,3 +472,275 @@ func BenchmarkMasterRequestMultipleWorkers(b *testing.B) {
 		}
 	})
 }
+
+func TestMasterWorkerExitsOnError(t *testing.T) {
+	ctx := context.Background()
+	errC := make(chan error)
+	destroyed := make(chan string, 1)
+	handler := MasterHandlerFunc(func(ctx context.Context, ev MasterEvent) error {
+		switch req := ev.(type) {
+		case CreateWorkerEvent:
+			req.Props.ErrC = errC
+			req.Props.UserData = nil
+			req.Props.WorkerHandler = &MockWorkerHandler{}
+		case DestroyWorkerEvent:
+			destroyed <- req.Key
+		default:
+			panic("received unknown master event")
+		}
+
+		return nil
+	})
+	master := NewMaster(MasterProps{
+		MasterHandler: handler,
+	})
+
+	err := master.Start(ctx)
+	assert.Nil(t, err)
+
+	err = master.Create(ctx, "1", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), master.Stats()["active"])
+
+	// the worker exits when its handler fails to handle the error
+	errC <- errors.New("error")
+	assert.Equal(t, "1", <-destroyed)
+
+	ok, err := master.Exists(ctx, "1")
+	assert.Nil(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, uint64(1), master.Stats()["workerExits"])
+	assert.Equal(t, int64(0), master.Stats()["active"])
+
+	_, err = master.Request(ctx, "1", 1)
+	assert.Error(t, err)
+
+	err = master.Stop()
+	assert.Nil(t, err)
+}
+
+func TestMasterWorkerRequestCancelled(t *testing.T) {
+	ctx := context.Background()
+	handled := make(chan int, 2)
+	handler := MasterHandlerFunc(func(ctx context.Context, ev MasterEvent) error {
+		if ev, ok := ev.(CreateWorkerEvent); ok {
+			ev.Props.WorkerHandler = WorkerHandlerFunc(func(ctx context.Context, ev WorkerEvent) (interface{}, error) {
+				req := ev.(RequestWorkerEvent)
+				handled <- req.Value.(int)
+				if req.Value.(int) == 0 {
+					// the request blocks until its caller gives up
+					<-ctx.Done()
+					return nil, ctx.Err()
+				}
+				return req.Value, nil
+			})
+		}
+
+		return nil
+	})
+	master := NewMaster(MasterProps{
+		MasterHandler: handler,
+	})
+
+	err := master.Start(ctx)
+	assert.Nil(t, err)
+
+	err = master.Create(ctx, "1", nil)
+	assert.Nil(t, err)
+
+	reqCtx, cancel := context.WithCancel(ctx)
+	errC := make(chan error, 1)
+	go func() {
+		_, err := master.Request(reqCtx, "1", 0)
+		errC <- err
+	}()
+
+	assert.Equal(t, 0, <-handled)
+	cancel()
+	assert.Equal(t, context.Canceled, <-errC)
+
+	// the worker is free to serve the following requests
+	v, err := master.Request(ctx, "1", 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, v)
+	assert.Equal(t, 1, <-handled)
+
+	err = master.Stop()
+	assert.Nil(t, err)
+}
+
+func TestMasterWorkerRequestContextDone(t *testing.T) {
+	ScopedMaster(t, func(ctx context.Context, m *Master) {
+		err := m.Create(ctx, "1", nil)
+		assert.Nil(t, err)
+
+		reqCtx, cancel := context.WithCancel(ctx)
+		cancel()
+
+		// a request whose context is already done is not handled
+		_, err = m.Request(reqCtx, "1", 1)
+		assert.Equal(t, context.Canceled, err)
+
+		v, err := m.Request(ctx, "1", 1)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, v)
+	})
+}
+
+// newStallingMaster creates a master whose worker with key stalled
+// does not handle requests until release is closed. The worker is
+// sent to stalled when it handles its first request
+func newStallingMaster(release <-chan struct{}, stalled chan<- *Worker) *Master {
+	handler := MasterHandlerFunc(func(ctx context.Context, ev MasterEvent) error {
+		if ev, ok := ev.(CreateWorkerEvent); ok {
+			key := ev.Key
+			ev.Props.WorkerHandler = WorkerHandlerFunc(func(ctx context.Context, ev WorkerEvent) (interface{}, error) {
+				if key == "stalled" {
+					select {
+					case stalled <- ev.GetWorker():
+					default:
+					}
+					<-release
+				}
+				return ev.(RequestWorkerEvent).Value, nil
+			})
+		}
+
+		return nil
+	})
+
+	return NewMaster(MasterProps{
+		MasterHandler:         handler,
+		CreateWorkerOnRequest: true,
+	})
+}
+
+func TestMasterWorkerStalledDoesNotBlockOtherWorkers(t *testing.T) {
+	ctx := context.Background()
+	release := make(chan struct{})
+	master := newStallingMaster(release, nil)
+
+	err := master.Start(ctx)
+	assert.Nil(t, err)
+
+	// the stalled worker handles one request and queues more
+	// than its channel holds
+	var wg sync.WaitGroup
+	failed := int32(0)
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if v, err := master.Request(ctx, "stalled", i); err != nil || v != i {
+				atomic.AddInt32(&failed, 1)
+			}
+		}(i)
+	}
+
+	// the loop of the master still serves the other workers
+	reqCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	v, err := master.Request(reqCtx, "other", 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, v)
+
+	// a request for the stalled worker fails once its context is done
+	reqCtx, cancel = context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, err = master.Request(reqCtx, "stalled", 100)
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	// the requests that were queued are all handled once
+	// the worker resumes
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(0), atomic.LoadInt32(&failed))
+
+	err = master.Stop()
+	assert.Nil(t, err)
+}
+
+func TestMasterExecuteWorkersBusyDoesNotBlockLoop(t *testing.T) {
+	ctx := context.Background()
+	release := make(chan struct{})
+	master := newStallingMaster(release, nil)
+
+	err := master.Start(ctx)
+	assert.Nil(t, err)
+	assert.Nil(t, master.Create(ctx, "stalled", nil))
+
+	// the only worker stalls so the executions fill the shared channel
+	var wg sync.WaitGroup
+	failed := int32(0)
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if v, err := master.Execute(ctx, i); err != nil || v != i {
+				atomic.AddInt32(&failed, 1)
+			}
+		}(i)
+	}
+
+	reqCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	ok, err := master.Exists(reqCtx, "stalled")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(0), atomic.LoadInt32(&failed))
+
+	err = master.Stop()
+	assert.Nil(t, err)
+}
+
+func TestMasterDestroyWorkerFailsQueuedRequests(t *testing.T) {
+	ctx := context.Background()
+	release := make(chan struct{})
+	stalled := make(chan *Worker, 1)
+	master := newStallingMaster(release, stalled)
+
+	err := master.Start(ctx)
+	assert.Nil(t, err)
+
+	var wg sync.WaitGroup
+	exited := int32(0)
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if _, err := master.Request(ctx, "stalled", i); err == (ErrWorkerExited{Key: "stalled"}) {
+				atomic.AddInt32(&exited, 1)
+			}
+		}(i)
+	}
+
+	// wait until the channel of the worker is full, so that
+	// the remaining requests wait to be sent to it
+	w := <-stalled
+	for len(w.C) < cap(w.C) {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	destroyed := make(chan error, 1)
+	go func() {
+		destroyed <- master.Destroy(ctx, "stalled")
+	}()
+
+	// the requests that did not fit in the channel of the worker
+	// fail, the ones in it are handled before the worker exits
+	deadline := time.Now().Add(time.Second)
+	for atomic.LoadInt32(&exited) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	assert.True(t, atomic.LoadInt32(&exited) > 0)
+	close(release)
+	wg.Wait()
+	assert.Nil(t, <-destroyed)
+
+	err = master.Stop()
+	assert.Nil(t, err)
+}
@@ -0,0 +1,164 @@
+package concurrent
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultMaxRestarts   = 10
+	defaultRestartPeriod = time.Minute
+	defaultBaseBackoff   = 10 * time.Millisecond
+	defaultMaxBackoff    = time.Second
+)
+
+// DefaultSupervisorProps allow a loop to restart up to 10 times
+// within a minute, waiting from 10ms up to 1s between restarts
+var DefaultSupervisorProps = SupervisorProps{
+	MaxRestarts: defaultMaxRestarts,
+	Period:      defaultRestartPeriod,
+	BaseBackoff: defaultBaseBackoff,
+	MaxBackoff:  defaultMaxBackoff,
+}
+
+// ErrMaxRestartsReached is returned by a Supervisor when the loop
+// it supervises panics more times than it is allowed to restart
+type ErrMaxRestartsReached struct {
+	Restarts int
+	Cause    error
+}
+
+// Error implementation of error for ErrMaxRestartsReached
+func (e ErrMaxRestartsReached) Error() string {
+	return fmt.Sprintf("loop stopped after %d restarts with error %s", e.Restarts, e.Cause.Error())
+}
+
+// SupervisorProps are the properties used to create a Supervisor.
+// They define the restart policy of the loops it supervises
+type SupervisorProps struct {
+	// MaxRestarts is the maximum number of times a loop is restarted
+	// after a panic within Period. If 0 the loop is not restarted
+	MaxRestarts int
+
+	// Period is the window of time in which the restarts of a loop
+	// are counted against MaxRestarts. If 0 all the restarts of the
+	// loop are counted
+	Period time.Duration
+
+	// BaseBackoff is the time waited before the first restart of
+	// a loop. It doubles with each restart within Period
+	BaseBackoff time.Duration
+
+	// MaxBackoff is the maximum time waited before a restart
+	MaxBackoff time.Duration
+
+	// OnPanic if set is called with the error recovered
+	// from each panic of a loop
+	OnPanic func(err error)
+}
+
+// Supervisor runs loops and restarts them when they panic, so that a
+// crash in a loop does not leave its component without a goroutine
+// to serve it. Loops are supervised one for one, a crash only
+// restarts the loop that crashed and counts against its own restarts
+type Supervisor struct {
+	props    SupervisorProps
+	restarts uint64
+}
+
+// NewSupervisor creates a new Supervisor
+func NewSupervisor(props SupervisorProps) *Supervisor {
+	return &Supervisor{props: props}
+}
+
+// Restarts returns the number of times the
+// supervisor restarted a loop
+func (s *Supervisor) Restarts() uint64 {
+	return atomic.LoadUint64(&s.restarts)
+}
+
+// runOnce runs the loop and recovers from a panic, in
+// which case it returns the error and true
+func (s *Supervisor) runOnce(ctx context.Context, loop func(ctx context.Context) error) (err error, panicked bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errorFromPanic(r)
+			panicked = true
+		}
+	}()
+
+	return loop(ctx), false
+}
+
+// backoff returns the time to wait before restarting a
+// loop that has been restarted the number of times
+func (s *Supervisor) backoff(restarts int) time.Duration {
+	backoff := s.props.BaseBackoff
+	for i := 0; i < restarts && backoff < s.props.MaxBackoff; i++ {
+		backoff *= 2
+	}
+
+	if backoff > s.props.MaxBackoff {
+		backoff = s.props.MaxBackoff
+	}
+
+	return backoff
+}
+
+// Run runs the loop until it returns. If the loop panics it is
+// restarted after a backoff, unless it has already been restarted
+// MaxRestarts times within Period, in which case ErrMaxRestartsReached
+// is returned. The state that the loop keeps outside of its own stack
+// is preserved across restarts
+func (s *Supervisor) Run(ctx context.Context, loop func(ctx context.Context) error) error {
+	var restarts []time.Time
+	for {
+		err, panicked := s.runOnce(ctx, loop)
+		if !panicked {
+			return err
+		}
+
+		if s.props.OnPanic != nil {
+			s.props.OnPanic(err)
+		}
+
+		now := time.Now()
+		if s.props.Period > 0 {
+			recent := restarts[:0]
+			for _, t := range restarts {
+				if now.Sub(t) < s.props.Period {
+					recent = append(recent, t)
+				}
+			}
+			restarts = recent
+		}
+
+		if len(restarts) >= s.props.MaxRestarts {
+			return ErrMaxRestartsReached{Restarts: len(restarts), Cause: err}
+		}
+
+		timer := time.NewTimer(s.backoff(len(restarts)))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		restarts = append(restarts, now)
+		atomic.AddUint64(&s.restarts, 1)
+	}
+}
+
+// Go runs the loop in a new goroutine with Run. The done
+// function, if set, is called with the error Run returns
+func (s *Supervisor) Go(ctx context.Context, loop func(ctx context.Context) error, done func(err error)) {
+	go func() {
+		err := s.Run(ctx, loop)
+		if done != nil {
+			done(err)
+		}
+	}()
+}
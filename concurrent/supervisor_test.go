@@ -0,0 +1,128 @@
+package concurrent
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSupervisorRunReturns(t *testing.T) {
+	s := NewSupervisor(SupervisorProps{MaxRestarts: 1})
+
+	err := s.Run(context.Background(), func(ctx context.Context) error {
+		return errors.New("error")
+	})
+
+	assert.Equal(t, "error", err.Error())
+	assert.Equal(t, uint64(0), s.Restarts())
+}
+
+func TestSupervisorRunRestarts(t *testing.T) {
+	var panics []error
+	s := NewSupervisor(SupervisorProps{
+		MaxRestarts: 3,
+		OnPanic:     func(err error) { panics = append(panics, err) },
+	})
+
+	// state kept outside of the loop is preserved across restarts
+	runs := 0
+	err := s.Run(context.Background(), func(ctx context.Context) error {
+		runs++
+		if runs < 3 {
+			panic("crash")
+		}
+		return nil
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, 3, runs)
+	assert.Equal(t, uint64(2), s.Restarts())
+	assert.Equal(t, 2, len(panics))
+}
+
+func TestSupervisorRunMaxRestarts(t *testing.T) {
+	s := NewSupervisor(SupervisorProps{MaxRestarts: 2})
+
+	runs := 0
+	err := s.Run(context.Background(), func(ctx context.Context) error {
+		runs++
+		panic("crash")
+	})
+
+	assert.IsType(t, ErrMaxRestartsReached{}, err)
+	assert.Equal(t, 3, runs)
+	assert.Equal(t, uint64(2), s.Restarts())
+}
+
+func TestSupervisorRunRestartsWithinPeriod(t *testing.T) {
+	s := NewSupervisor(SupervisorProps{
+		MaxRestarts: 1,
+		Period:      time.Millisecond,
+		BaseBackoff: 2 * time.Millisecond,
+		MaxBackoff:  2 * time.Millisecond,
+	})
+
+	// restarts older than the period are not counted, so the
+	// loop can restart more than MaxRestarts times
+	runs := 0
+	err := s.Run(context.Background(), func(ctx context.Context) error {
+		runs++
+		if runs < 4 {
+			panic("crash")
+		}
+		return nil
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(3), s.Restarts())
+}
+
+func TestSupervisorBackoff(t *testing.T) {
+	s := NewSupervisor(SupervisorProps{
+		BaseBackoff: 10 * time.Millisecond,
+		MaxBackoff:  50 * time.Millisecond,
+	})
+
+	assert.Equal(t, 10*time.Millisecond, s.backoff(0))
+	assert.Equal(t, 20*time.Millisecond, s.backoff(1))
+	assert.Equal(t, 40*time.Millisecond, s.backoff(2))
+	assert.Equal(t, 50*time.Millisecond, s.backoff(3))
+	assert.Equal(t, 50*time.Millisecond, s.backoff(100))
+}
+
+func TestSupervisorRunCancelledDuringBackoff(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := NewSupervisor(SupervisorProps{
+		MaxRestarts: 1,
+		BaseBackoff: time.Hour,
+		MaxBackoff:  time.Hour,
+		OnPanic:     func(err error) { cancel() },
+	})
+
+	err := s.Run(ctx, func(ctx context.Context) error {
+		panic("crash")
+	})
+
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, uint64(0), s.Restarts())
+}
+
+func TestSupervisorGo(t *testing.T) {
+	s := NewSupervisor(SupervisorProps{MaxRestarts: 1})
+	done := make(chan error)
+
+	runs := 0
+	s.Go(context.Background(), func(ctx context.Context) error {
+		runs++
+		if runs == 1 {
+			panic("crash")
+		}
+		return errors.New("error")
+	}, func(err error) { done <- err })
+
+	assert.Equal(t, "error", (<-done).Error())
+	assert.Equal(t, uint64(1), s.Restarts())
+}
@@ -2,10 +2,27 @@ package concurrent
 
 import (
 	"context"
+	"fmt"
+	"sync"
 	"sync/atomic"
 	"time"
 )
 
+// ErrWorkerExited is returned for the requests that a worker
+// had not handled when it exited on its own
+type ErrWorkerExited struct {
+	Key   string
+	Cause error
+}
+
+// Error implementation of error for ErrWorkerExited
+func (e ErrWorkerExited) Error() string {
+	if e.Cause == nil {
+		return fmt.Sprintf("worker %s exited", e.Key)
+	}
+	return fmt.Sprintf("worker %s exited with error %s", e.Key, e.Cause.Error())
+}
+
 // Worker handles requests issued by the master in a separate
 // goroutine and gives back results. Its lifetime is managed
 // by the Master
@@ -33,6 +50,14 @@ type Worker struct {
 	// C is the channel the worker only reads from
 	C chan workerRequest
 
+	// closed is closed by the master when it stops sending requests
+	// to the worker, so that the requests waiting to be sent fail
+	closed chan struct{}
+
+	// lock is held for reading while a request waits to be sent
+	// through C, and for writing by the master while it closes C
+	lock sync.RWMutex
+
 	// ShutdownC is a channel used by the worker to signal that it
 	// has been completely shutdown and removed
 	ShutdownC chan error
@@ -45,6 +70,9 @@ type Worker struct {
 	// that the worker has exited
 	doneC chan<- workerDestroyed
 
+	// supervisor restarts the loop of the worker if it panics
+	supervisor *Supervisor
+
 	// UserData is data that the user can attach to the worker in case any
 	// external context is required
 	UserData interface{}
@@ -171,6 +199,11 @@ type WorkerProps struct {
 	// without serving any request. When this time expires the worker
 	// should destroy itself
 	MaxInactivity time.Duration
+
+	// OnPanic if set is called with the error recovered from each
+	// panic of the loop of the worker. The loop is restarted with
+	// DefaultSupervisorProps
+	OnPanic func(err error)
 }
 
 // workerDestroyed is the event sent by a worker to the
@@ -182,6 +215,9 @@ type workerDestroyed struct {
 	// Key uniquely identifies a worker
 	Key string
 
+	// Worker is the worker that exited
+	Worker *Worker
+
 	// Cause may be set by the worker if the conditions in
 	// which it terminated were abnormal
 	Cause error
@@ -238,6 +274,7 @@ func NewWorker(ctx context.Context, props WorkerProps) *Worker {
 		handler:            props.WorkerHandler,
 		SharedC:            props.SharedC,
 		C:                  props.C,
+		closed:             make(chan struct{}),
 
 		// ShutdownC may be closed with an error if there are no listeners
 		// for it. In that case we should not block
@@ -247,32 +284,39 @@ func NewWorker(ctx context.Context, props WorkerProps) *Worker {
 		UserData:  props.UserData,
 	}
 
+	supervisorProps := DefaultSupervisorProps
+	supervisorProps.OnPanic = props.OnPanic
+	w.supervisor = NewSupervisor(supervisorProps)
+
 	go w.startLoop(ctx)
 	return w
 }
 
-func (w *Worker) startLoop(ctx context.Context) {
-	timer := time.NewTimer(w.maxInactivity)
-	var err error
-
-	defer func() {
-		timer.Stop()
+// close stops the requests waiting to be sent to the worker. It
+// returns holding the lock for writing, so that no request is sent
+// to the worker until the lock is released
+func (w *Worker) close() {
+	close(w.closed)
+	w.lock.Lock()
+}
 
-		if r := recover(); r != nil {
-			err = errorFromPanic(r)
-		}
+func (w *Worker) startLoop(ctx context.Context) {
+	err := w.supervisor.Run(ctx, w.loop)
+	w.doneC <- workerDestroyed{Context: ctx, Key: w.key, Worker: w, Cause: err}
+}
 
-		w.doneC <- workerDestroyed{Context: ctx, Key: w.key, Cause: err}
-	}()
+func (w *Worker) loop(ctx context.Context) error {
+	timer := time.NewTimer(w.maxInactivity)
+	defer timer.Stop()
 
 	for {
 		select {
 		case <-ctx.Done():
-			return
+			return nil
 		case <-timer.C:
 			current := time.Now().Unix()
 			if time.Duration(current-w.lastEventTimestamp) > w.maxInactivity {
-				return
+				return nil
 
 			} else {
 				if ok := timer.Reset(w.maxInactivity); ok {
@@ -281,23 +325,22 @@ func (w *Worker) startLoop(ctx context.Context) {
 			}
 		case err, ok := <-w.ErrC:
 			if !ok {
-				return
+				return nil
 			}
 
-			err = w.handleError(err)
-			if err != nil {
-				return
+			if err := w.handleError(err); err != nil {
+				return err
 			}
 
 		case req, ok := <-w.SharedC:
 			if !ok {
-				return
+				return nil
 			}
 
 			w.handleExecute(req)
 		case req, ok := <-w.C:
 			if !ok {
-				return
+				return nil
 			}
 
 			w.handleRequest(req)
@@ -347,18 +390,31 @@ func (w *Worker) processRequest(req workerRequest) Response {
 }
 
 func (w *Worker) handleRequest(req workerRequest) {
+	// the caller is no longer waiting for the request, so it is
+	// not handled to leave the worker free for the next one
+	if err := req.Context.Err(); err != nil {
+		respond(req, Response{Value: nil, Key: w.key, Error: err})
+		return
+	}
+
 	defer func() {
-		var err error
 		if r := recover(); r != nil {
-			err = errorFromPanic(r)
-			req.Out <- Response{Value: nil, Key: w.key, Error: err}
-			if value := atomic.AddInt32(req.Count, -1); value == 0 {
-				close(req.Out)
-			}
+			respond(req, Response{Value: nil, Key: w.key, Error: errorFromPanic(r)})
 		}
 	}()
 
-	req.Out <- w.processRequest(req)
+	respond(req, w.processRequest(req))
+}
+
+// respond sends the response to the caller of the request, unless the
+// context of the request is done, in which case the caller may have
+// stopped waiting for it
+func respond(req workerRequest, res Response) {
+	select {
+	case req.Out <- res:
+	case <-req.Context.Done():
+	}
+
 	if value := atomic.AddInt32(req.Count, -1); value == 0 {
 		close(req.Out)
 	}
@@ -0,0 +1,113 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+const (
+	// KeySize is the size in bytes of the keys used to encrypt
+	// configuration files
+	KeySize = 32
+
+	// EncryptedExt is the extension appended to the path of an
+	// encrypted configuration file, e.g. gateway.toml.enc
+	EncryptedExt = "enc"
+
+	// encryptedPrefix identifies the contents of an encrypted
+	// configuration file and the version of its format
+	encryptedPrefix = "oasis-config:v1:"
+)
+
+var (
+	ErrMalformedEncrypted = errors.New("encrypted configuration is malformed")
+	ErrDecrypt            = errors.New("failed to decrypt configuration, the key may be wrong")
+)
+
+// ParseKey decodes a base64 encoded configuration key
+func ParseKey(s string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode configuration key %s", err.Error())
+	}
+
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("configuration key must be %d bytes long", KeySize)
+	}
+
+	return key, nil
+}
+
+// ReadKey reads a base64 encoded configuration key from a file, like
+// the ones mounted by secret managers or written by a KMS agent
+func ReadKey(path string) ([]byte, error) {
+	p, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read configuration key %s", err.Error())
+	}
+
+	return ParseKey(string(p))
+}
+
+// Encrypt encrypts the contents of a configuration file with
+// AES-256-GCM so that it can be read with Decrypt
+func Encrypt(key, plaintext []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	sealed := aead.Seal(nonce, nonce, plaintext, []byte(encryptedPrefix))
+	return []byte(encryptedPrefix + base64.StdEncoding.EncodeToString(sealed) + "\n"), nil
+}
+
+// Decrypt decrypts the contents of a configuration file returned
+// by Encrypt
+func Decrypt(key, ciphertext []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	s := strings.TrimSpace(string(ciphertext))
+	if !strings.HasPrefix(s, encryptedPrefix) {
+		return nil, ErrMalformedEncrypted
+	}
+
+	p, err := base64.StdEncoding.DecodeString(s[len(encryptedPrefix):])
+	if err != nil || len(p) < aead.NonceSize() {
+		return nil, ErrMalformedEncrypted
+	}
+
+	plaintext, err := aead.Open(nil, p[:aead.NonceSize()], p[aead.NonceSize():], []byte(encryptedPrefix))
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+
+	return plaintext, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("configuration key must be %d bytes long", KeySize)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
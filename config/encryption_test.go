@@ -0,0 +1,96 @@
+package config
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+var configKey = bytes.Repeat([]byte{1}, KeySize)
+
+func TestEncryptDecrypt(t *testing.T) {
+	ciphertext, err := Encrypt(configKey, []byte("[eth]\nurl = \"wss://node\"\n"))
+	assert.Nil(t, err)
+	assert.NotContains(t, string(ciphertext), "wss://node")
+
+	plaintext, err := Decrypt(configKey, ciphertext)
+	assert.Nil(t, err)
+	assert.Equal(t, "[eth]\nurl = \"wss://node\"\n", string(plaintext))
+}
+
+func TestDecryptWrongKey(t *testing.T) {
+	ciphertext, err := Encrypt(configKey, []byte("secret"))
+	assert.Nil(t, err)
+
+	_, err = Decrypt(bytes.Repeat([]byte{2}, KeySize), ciphertext)
+	assert.Equal(t, ErrDecrypt, err)
+}
+
+func TestDecryptMalformed(t *testing.T) {
+	_, err := Decrypt(configKey, []byte("[eth]\nurl = \"wss://node\"\n"))
+	assert.Equal(t, ErrMalformedEncrypted, err)
+
+	_, err = Decrypt(configKey, []byte(encryptedPrefix+"AAAA"))
+	assert.Equal(t, ErrMalformedEncrypted, err)
+}
+
+func TestParseKey(t *testing.T) {
+	key, err := ParseKey(base64.StdEncoding.EncodeToString(configKey) + "\n")
+	assert.Nil(t, err)
+	assert.Equal(t, configKey, key)
+
+	_, err = ParseKey(base64.StdEncoding.EncodeToString([]byte("short")))
+	assert.Error(t, err)
+}
+
+func newFileViper(t *testing.T) (*viper.Viper, *cobra.Command) {
+	v := viper.New()
+	cmd := &cobra.Command{}
+	assert.Nil(t, (&ConfigFile{}).Bind(v, cmd))
+	assert.Nil(t, v.BindPFlags(cmd.PersistentFlags()))
+	return v, cmd
+}
+
+func writeEncryptedConfig(t *testing.T, dir string) string {
+	ciphertext, err := Encrypt(configKey, []byte("[eth]\nurl = \"wss://node\"\n"))
+	assert.Nil(t, err)
+
+	path := filepath.Join(dir, "gateway.toml.enc")
+	assert.Nil(t, ioutil.WriteFile(path, ciphertext, 0600))
+	return path
+}
+
+func TestConfigFileEncrypted(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	assert.Nil(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	keyPath := filepath.Join(dir, "key")
+	assert.Nil(t, ioutil.WriteFile(keyPath, []byte(base64.StdEncoding.EncodeToString(configKey)), 0600))
+
+	v, cmd := newFileViper(t)
+	assert.Nil(t, cmd.PersistentFlags().Set("config.path", writeEncryptedConfig(t, dir)))
+	assert.Nil(t, cmd.PersistentFlags().Set("config.key_path", keyPath))
+
+	assert.Nil(t, (&ConfigFile{}).Configure(v))
+	assert.Equal(t, "wss://node", v.GetString("eth.url"))
+}
+
+func TestConfigFileEncryptedNoKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	assert.Nil(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	v, cmd := newFileViper(t)
+	assert.Nil(t, cmd.PersistentFlags().Set("config.path", writeEncryptedConfig(t, dir)))
+
+	assert.Error(t, (&ConfigFile{}).Configure(v))
+	assert.Equal(t, "", v.GetString("eth.url"))
+}
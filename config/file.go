@@ -1,8 +1,10 @@
 package config
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
-	"os"
+	"io/ioutil"
 	"path"
 	"strings"
 
@@ -16,6 +18,12 @@ type ConfigFile struct {
 
 func (f *ConfigFile) Bind(v *viper.Viper, cmd *cobra.Command) error {
 	cmd.PersistentFlags().String("config.path", "", "sets the configuration file")
+	cmd.PersistentFlags().String("config.key", "",
+		"base64 encoded key used to decrypt the configuration file if its path ends with .enc. "+
+			"It is recommended to set it through the environment rather than the command line.")
+	cmd.PersistentFlags().String("config.key_path", "",
+		"path to a file with the base64 encoded key used to decrypt the configuration file "+
+			"if its path ends with .enc. Takes precedence over config.key.")
 	return nil
 }
 
@@ -27,21 +35,52 @@ func (f *ConfigFile) Configure(v *viper.Viper) error {
 		return nil
 	}
 
-	ext := strings.TrimPrefix(path.Ext(f.Path), ".")
+	// an encrypted file keeps the extension of the format of
+	// its contents before the encrypted extension
+	name := f.Path
+	encrypted := strings.TrimPrefix(path.Ext(name), ".") == EncryptedExt
+	if encrypted {
+		name = strings.TrimSuffix(name, "."+EncryptedExt)
+	}
+
+	ext := strings.TrimPrefix(path.Ext(name), ".")
 	if ext != "toml" && ext != "yaml" {
 		return fmt.Errorf("config file extension must be .toml or .yaml")
 	}
 
-	file, err := os.Open(f.Path)
+	p, err := ioutil.ReadFile(f.Path)
 	if err != nil {
 		return fmt.Errorf("failed to open config file %s", err.Error())
 	}
 
-	defer func() { _ = file.Close() }()
+	if encrypted {
+		key, err := f.key(v)
+		if err != nil {
+			return err
+		}
+
+		// the decrypted configuration is only kept in memory
+		if p, err = Decrypt(key, p); err != nil {
+			return fmt.Errorf("failed to decrypt config file %s", err.Error())
+		}
+	}
+
 	v.SetConfigType(ext)
-	if err := v.ReadConfig(file); err != nil {
+	if err := v.ReadConfig(bytes.NewReader(p)); err != nil {
 		return fmt.Errorf("failed to read config file %s", err.Error())
 	}
 
 	return nil
 }
+
+func (f *ConfigFile) key(v *viper.Viper) ([]byte, error) {
+	if keyPath := v.GetString("config.key_path"); len(keyPath) > 0 {
+		return ReadKey(keyPath)
+	}
+
+	if key := v.GetString("config.key"); len(key) > 0 {
+		return ParseKey(key)
+	}
+
+	return nil, errors.New("config.key or config.key_path must be set to read an encrypted config file")
+}
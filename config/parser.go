@@ -18,7 +18,8 @@ type Config interface {
 type Parser struct {
 	Config Config
 
-	file *ConfigFile
+	file   *ConfigFile
+	preset *PresetConfig
 
 	cmd *cobra.Command
 	v   *viper.Viper
@@ -34,9 +35,10 @@ func (p *Parser) Parse() error {
 	}
 
 	// keep file first so that any parameters read from the file are used
-	// as defaults for the other flags
+	// as defaults for the other flags. The preset goes next so that it
+	// can be selected from the file
 	var binders []Binder
-	binders = append(binders, p.file)
+	binders = append(binders, p.file, p.preset)
 	binders = append(binders, p.Config.Binders()...)
 
 	for _, c := range binders {
@@ -64,8 +66,9 @@ func Generate(config Config) (*Parser, error) {
 
 	cmd := &cobra.Command{Use: "oasis-gateway"}
 	file := ConfigFile{}
+	preset := PresetConfig{}
 	var binders []Binder
-	binders = append(binders, &file)
+	binders = append(binders, &file, &preset)
 	binders = append(binders, config.Binders()...)
 
 	for _, c := range binders {
@@ -78,5 +81,5 @@ func Generate(config Config) (*Parser, error) {
 		return nil, fmt.Errorf("failed to bind flags %s", err.Error())
 	}
 
-	return &Parser{file: &file, Config: config, cmd: cmd, v: v}, nil
+	return &Parser{file: &file, preset: &preset, Config: config, cmd: cmd, v: v}, nil
 }
@@ -0,0 +1,132 @@
+package config
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	PresetLocalDev        = "local-dev"
+	PresetSingleNodeRedis = "single-node-redis"
+	PresetClustered       = "clustered"
+)
+
+// Preset is a named set of values for the configuration keys
+// that suit a common deployment
+type Preset struct {
+	// Name is the name used to select the preset
+	Name string
+
+	// Description summarises the deployment the preset is for
+	Description string
+
+	// Values are the values of the configuration keys. They
+	// replace the defaults of the keys, so they are overridden
+	// by any value that is set explicitly
+	Values map[string]interface{}
+}
+
+// Presets are the presets that can be selected
+// with config.preset
+var Presets = map[string]Preset{
+	PresetLocalDev: {
+		Name:        PresetLocalDev,
+		Description: "single gateway on the local machine with in memory state and insecure authentication",
+		Values: map[string]interface{}{
+			"bind_public.http_interface":            "127.0.0.1",
+			"bind_public.http_port":                 1234,
+			"bind_private.http_interface":           "127.0.0.1",
+			"bind_private.http_port":                1235,
+			"bind_public.http_cors.enabled":         true,
+			"bind_public.http_cors.allowed_origins": []string{"*"},
+			"mailbox.provider":                      "mem",
+			"auth.provider":                         []string{"insecure"},
+			"usage.provider":                        "mem",
+			"logging.level":                         "debug",
+		},
+	},
+	PresetSingleNodeRedis: {
+		Name:        PresetSingleNodeRedis,
+		Description: "single gateway that keeps its state in a redis instance so that it survives restarts",
+		Values: map[string]interface{}{
+			"bind_public.http_interface":  "0.0.0.0",
+			"bind_private.http_interface": "127.0.0.1",
+			"bind_private.http_port":      1235,
+			"mailbox.provider":            "redis-single",
+			"mailbox.redis_single.addr":   "127.0.0.1:6379",
+			"auth.provider":               []string{"oauth"},
+			"usage.provider":              "redis",
+			"usage.redis.addrs":           []string{"127.0.0.1:6379"},
+			"logging.level":               "info",
+		},
+	},
+	PresetClustered: {
+		Name:        PresetClustered,
+		Description: "gateways behind a load balancer that share their state through a redis cluster",
+		Values: map[string]interface{}{
+			"bind_public.http_interface":  "0.0.0.0",
+			"bind_private.http_interface": "0.0.0.0",
+			"bind_private.http_port":      1235,
+			"mailbox.provider":            "redis-cluster",
+			"mailbox.redis_cluster.addrs": []string{"127.0.0.1:6379"},
+			"auth.provider":               []string{"oauth"},
+			"auth.replay.provider":        "redis",
+			"auth.replay.redis.addrs":     []string{"127.0.0.1:6379"},
+			"usage.provider":              "redis",
+			"usage.redis.addrs":           []string{"127.0.0.1:6379"},
+			"logging.level":               "info",
+		},
+	},
+}
+
+// PresetNames returns the names of the presets sorted
+func PresetNames() []string {
+	names := make([]string, 0, len(Presets))
+	for name := range Presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// PresetConfig selects the preset used as the defaults of the
+// configuration. Values set through flags, environment variables
+// or the configuration file take precedence over the preset
+type PresetConfig struct {
+	Name string
+}
+
+func (p *PresetConfig) Bind(v *viper.Viper, cmd *cobra.Command) error {
+	cmd.PersistentFlags().String("config.preset", "",
+		"preset used as the defaults of the configuration. Options are "+strings.Join(PresetNames(), ", ")+
+			". Values that are set explicitly override the preset.")
+	return nil
+}
+
+func (p *PresetConfig) Configure(v *viper.Viper) error {
+	p.Name = v.GetString("config.preset")
+	if len(p.Name) == 0 {
+		return nil
+	}
+
+	preset, ok := Presets[p.Name]
+	if !ok {
+		return ErrInvalidValue{
+			Key:          "config.preset",
+			InvalidValue: p.Name,
+			Values:       PresetNames(),
+		}
+	}
+
+	// viper only falls back to the defaults of the flags when no
+	// other value is set, so setting the preset values as defaults
+	// keeps the values that are set explicitly
+	for key, value := range preset.Values {
+		v.SetDefault(key, value)
+	}
+
+	return nil
+}
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func newPresetViper(t *testing.T) (*viper.Viper, *cobra.Command) {
+	v := viper.New()
+	v.SetEnvPrefix("OASIS_DG_TEST")
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
+
+	cmd := &cobra.Command{}
+	assert.Nil(t, (&PresetConfig{}).Bind(v, cmd))
+	cmd.PersistentFlags().String("mailbox.provider", "mem", "")
+	cmd.PersistentFlags().String("logging.level", "debug", "")
+	cmd.PersistentFlags().StringSlice("auth.provider", []string{"insecure"}, "")
+	assert.Nil(t, v.BindPFlags(cmd.PersistentFlags()))
+	return v, cmd
+}
+
+func TestPresetNotSet(t *testing.T) {
+	v, _ := newPresetViper(t)
+
+	preset := PresetConfig{}
+	assert.Nil(t, preset.Configure(v))
+	assert.Equal(t, "mem", v.GetString("mailbox.provider"))
+}
+
+func TestPresetSetsDefaults(t *testing.T) {
+	v, cmd := newPresetViper(t)
+	assert.Nil(t, cmd.PersistentFlags().Set("config.preset", PresetClustered))
+
+	preset := PresetConfig{}
+	assert.Nil(t, preset.Configure(v))
+	assert.Equal(t, PresetClustered, preset.Name)
+	assert.Equal(t, "redis-cluster", v.GetString("mailbox.provider"))
+	assert.Equal(t, "info", v.GetString("logging.level"))
+	assert.Equal(t, []string{"oauth"}, v.GetStringSlice("auth.provider"))
+}
+
+func TestPresetExplicitValuesOverride(t *testing.T) {
+	v, cmd := newPresetViper(t)
+	assert.Nil(t, cmd.PersistentFlags().Set("config.preset", PresetClustered))
+	assert.Nil(t, cmd.PersistentFlags().Set("mailbox.provider", "redis-single"))
+	assert.Nil(t, os.Setenv("OASIS_DG_TEST_LOGGING_LEVEL", "warn"))
+	defer func() { _ = os.Unsetenv("OASIS_DG_TEST_LOGGING_LEVEL") }()
+
+	preset := PresetConfig{}
+	assert.Nil(t, preset.Configure(v))
+	assert.Equal(t, "redis-single", v.GetString("mailbox.provider"))
+	assert.Equal(t, "warn", v.GetString("logging.level"))
+}
+
+func TestPresetUnknown(t *testing.T) {
+	v, cmd := newPresetViper(t)
+	assert.Nil(t, cmd.PersistentFlags().Set("config.preset", "unknown"))
+
+	preset := PresetConfig{}
+	err := preset.Configure(v)
+	assert.Equal(t, ErrInvalidValue{
+		Key:          "config.preset",
+		InvalidValue: "unknown",
+		Values:       []string{PresetClustered, PresetLocalDev, PresetSingleNodeRedis},
+	}, err)
+}
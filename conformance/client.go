@@ -0,0 +1,149 @@
+package conformance
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	auth "github.com/oasislabs/oasis-gateway/auth/core"
+	"github.com/oasislabs/oasis-gateway/auth/insecure"
+	"github.com/oasislabs/oasis-gateway/rpc"
+)
+
+// Props define how the conformance tests reach the
+// implementation of the gateway API under test
+type Props struct {
+	// BaseURL is the URL of the public API under test,
+	// like http://127.0.0.1:1234
+	BaseURL string
+
+	// Client is the client used to send the requests. If not
+	// set http.DefaultClient is used
+	Client *http.Client
+
+	// Headers are the headers that authenticate the requests. If
+	// not set, the headers of the insecure authentication provider
+	// are used
+	Headers map[string]string
+}
+
+// Response is the response of the gateway to a request
+type Response struct {
+	// Code is the HTTP status code of the response
+	Code int
+
+	// Body is the content of the response
+	Body []byte
+}
+
+// Decode deserializes the body of the response into v
+func (r Response) Decode(v interface{}) error {
+	return json.Unmarshal(r.Body, v)
+}
+
+// Error deserializes the body of the response as an rpc.Error
+func (r Response) Error() (rpc.Error, error) {
+	var rpcError rpc.Error
+	err := json.Unmarshal(r.Body, &rpcError)
+	return rpcError, err
+}
+
+// Client sends requests to the gateway under test
+type Client struct {
+	baseURL string
+	client  *http.Client
+	headers map[string]string
+}
+
+// NewClient creates a new client for the gateway under test
+func NewClient(props Props) *Client {
+	client := props.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	headers := props.Headers
+	if headers == nil {
+		headers = map[string]string{insecure.HeaderKey: "conformance"}
+	}
+
+	return &Client{
+		baseURL: strings.TrimSuffix(props.BaseURL, "/"),
+		client:  client,
+		headers: headers,
+	}
+}
+
+// NewSession generates a new session key, so that the
+// requests of a test do not observe the events of others
+func NewSession() string {
+	p := make([]byte, 16)
+	if _, err := rand.Read(p); err != nil {
+		panic(fmt.Sprintf("failed to generate session key %s", err.Error()))
+	}
+
+	return hex.EncodeToString(p)
+}
+
+// Request sends an authenticated request with the session key. The
+// body is serialized as JSON if it is set
+func (c *Client) Request(method, path, session string, body interface{}) (Response, error) {
+	headers := c.AuthHeaders(session)
+
+	var p []byte
+	if body != nil {
+		var err error
+		if p, err = json.Marshal(body); err != nil {
+			return Response{}, err
+		}
+		headers["Content-type"] = "application/json"
+	}
+
+	return c.RequestRaw(method, path, headers, p)
+}
+
+// AuthHeaders returns the headers that authenticate a
+// request with the session key
+func (c *Client) AuthHeaders(session string) map[string]string {
+	headers := make(map[string]string, len(c.headers)+2)
+	for key, value := range c.headers {
+		headers[key] = value
+	}
+
+	if len(session) > 0 {
+		headers[auth.RequestHeaderSessionKey] = session
+	}
+
+	return headers
+}
+
+// RequestRaw sends a request with the provided headers and body
+// as they are, without authenticating it
+func (c *Client) RequestRaw(method, path string, headers map[string]string, body []byte) (Response, error) {
+	req, err := http.NewRequest(method, c.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return Response{}, err
+	}
+
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return Response{}, err
+	}
+	defer res.Body.Close()
+
+	p, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return Response{}, err
+	}
+
+	return Response{Code: res.StatusCode, Body: p}, nil
+}
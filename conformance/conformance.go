@@ -0,0 +1,190 @@
+// Package conformance implements a suite of tests that validates
+// that a server implements the public API of the gateway, so that
+// alternative implementations and proxies in front of the gateway
+// can verify that they are compatible with its clients. The suite
+// only relies on the behaviour of the API that does not depend on
+// the backend, so that it can run against any deployment.
+package conformance
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Case is a conformance test
+type Case struct {
+	// Name identifies the test
+	Name string
+
+	// Run runs the test against the gateway reached with the client
+	Run func(t *testing.T, c *Client)
+}
+
+// Run runs all the conformance tests against the gateway
+// described by props
+func Run(t *testing.T, props Props) {
+	RunCases(t, props, Cases)
+}
+
+// RunCases runs the provided conformance tests against the
+// gateway described by props
+func RunCases(t *testing.T, props Props, cases []Case) {
+	client := NewClient(props)
+	for _, c := range cases {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			c.Run(t, client)
+		})
+	}
+}
+
+// Cases are the conformance tests of the public API
+var Cases = []Case{
+	{Name: "Version", Run: testVersion},
+	{Name: "NotAuthenticated", Run: testNotAuthenticated},
+	{Name: "NoSession", Run: testNoSession},
+	{Name: "UnknownPath", Run: testUnknownPath},
+	{Name: "InvalidMethod", Run: testInvalidMethod},
+	{Name: "NoContentType", Run: testNoContentType},
+	{Name: "PollServiceEmpty", Run: testPollServiceEmpty},
+	{Name: "PollEventEmpty", Run: testPollEventEmpty},
+	{Name: "GetCodeEmptyAddress", Run: testGetCodeEmptyAddress},
+	{Name: "SubscribeNoEvents", Run: testSubscribeNoEvents},
+	{Name: "SessionMetadata", Run: testSessionMetadata},
+	{Name: "SessionMetadataInvalid", Run: testSessionMetadataInvalid},
+}
+
+// requireError asserts that the response is an error with the
+// provided status code and error code
+func requireError(t *testing.T, res Response, status int, code int) {
+	require.Equal(t, status, res.Code, string(res.Body))
+
+	rpcError, err := res.Error()
+	require.Nil(t, err, string(res.Body))
+	assert.Equal(t, code, rpcError.ErrorCode)
+	assert.NotEmpty(t, rpcError.Description)
+}
+
+func testVersion(t *testing.T, c *Client) {
+	res, err := c.Request("GET", "/v0/api/version", NewSession(), nil)
+	require.Nil(t, err)
+	require.Equal(t, http.StatusOK, res.Code, string(res.Body))
+
+	var version struct {
+		Version *int `json:"version"`
+	}
+	require.Nil(t, res.Decode(&version))
+	require.NotNil(t, version.Version)
+	assert.Equal(t, 0, *version.Version)
+}
+
+func testNotAuthenticated(t *testing.T, c *Client) {
+	res, err := c.RequestRaw("POST", "/v0/api/service/poll", nil, nil)
+	require.Nil(t, err)
+	requireError(t, res, http.StatusForbidden, 7003)
+}
+
+func testNoSession(t *testing.T, c *Client) {
+	res, err := c.Request("POST", "/v0/api/service/poll", "", struct{}{})
+	require.Nil(t, err)
+	requireError(t, res, http.StatusForbidden, 7003)
+}
+
+func testUnknownPath(t *testing.T, c *Client) {
+	res, err := c.Request("POST", "/v0/api/service/unknown", NewSession(), struct{}{})
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusNotFound, res.Code)
+}
+
+func testInvalidMethod(t *testing.T, c *Client) {
+	res, err := c.Request("GET", "/v0/api/service/deploy", NewSession(), nil)
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusMethodNotAllowed, res.Code)
+}
+
+func testNoContentType(t *testing.T, c *Client) {
+	res, err := c.RequestRaw("POST", "/v0/api/service/poll", c.AuthHeaders(NewSession()), []byte("{}"))
+	require.Nil(t, err)
+	requireError(t, res, http.StatusBadRequest, 2004)
+}
+
+func testPollServiceEmpty(t *testing.T, c *Client) {
+	res, err := c.Request("POST", "/v0/api/service/poll", NewSession(), map[string]interface{}{
+		"offset": 0,
+		"count":  10,
+	})
+	require.Nil(t, err)
+	require.Equal(t, http.StatusOK, res.Code, string(res.Body))
+
+	var poll struct {
+		Offset uint64        `json:"offset"`
+		Events []interface{} `json:"events"`
+	}
+	require.Nil(t, res.Decode(&poll))
+	assert.Equal(t, uint64(0), poll.Offset)
+	assert.Empty(t, poll.Events)
+}
+
+func testPollEventEmpty(t *testing.T, c *Client) {
+	res, err := c.Request("POST", "/v0/api/event/poll", NewSession(), map[string]interface{}{
+		"id":     0,
+		"offset": 0,
+		"count":  10,
+	})
+	require.Nil(t, err)
+	require.Equal(t, http.StatusOK, res.Code, string(res.Body))
+
+	var poll struct {
+		Offset uint64        `json:"offset"`
+		Events []interface{} `json:"events"`
+	}
+	require.Nil(t, res.Decode(&poll))
+	assert.Equal(t, uint64(0), poll.Offset)
+	assert.Empty(t, poll.Events)
+}
+
+func testGetCodeEmptyAddress(t *testing.T, c *Client) {
+	res, err := c.Request("POST", "/v0/api/service/getCode", NewSession(), map[string]interface{}{})
+	require.Nil(t, err)
+	requireError(t, res, http.StatusBadRequest, 2006)
+}
+
+func testSubscribeNoEvents(t *testing.T, c *Client) {
+	res, err := c.Request("POST", "/v0/api/event/subscribe", NewSession(), map[string]interface{}{
+		"events": []string{},
+	})
+	require.Nil(t, err)
+	requireError(t, res, http.StatusBadRequest, 2007)
+}
+
+func testSessionMetadata(t *testing.T, c *Client) {
+	session := NewSession()
+	metadata := map[string]string{"app": "conformance", "version": "1.0.0"}
+
+	res, err := c.Request("POST", "/v0/api/session/setMetadata", session, map[string]interface{}{
+		"metadata": metadata,
+	})
+	require.Nil(t, err)
+	require.Equal(t, http.StatusOK, res.Code, string(res.Body))
+
+	res, err = c.Request("POST", "/v0/api/session/getMetadata", session, struct{}{})
+	require.Nil(t, err)
+	require.Equal(t, http.StatusOK, res.Code, string(res.Body))
+
+	var get struct {
+		Metadata map[string]string `json:"metadata"`
+	}
+	require.Nil(t, res.Decode(&get))
+	assert.Equal(t, metadata, get.Metadata)
+}
+
+func testSessionMetadataInvalid(t *testing.T, c *Client) {
+	res, err := c.Request("POST", "/v0/api/session/setMetadata", NewSession(), map[string]interface{}{
+		"metadata": map[string]string{"app name": "conformance"},
+	})
+	require.Nil(t, err)
+	requireError(t, res, http.StatusBadRequest, 2029)
+}
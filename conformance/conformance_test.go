@@ -0,0 +1,17 @@
+package conformance
+
+import (
+	"os"
+	"testing"
+)
+
+// TestGateway runs the conformance suite against the gateway at
+// OASIS_DG_CONFORMANCE_URL. It is skipped if the variable is not set
+func TestGateway(t *testing.T) {
+	url := os.Getenv("OASIS_DG_CONFORMANCE_URL")
+	if len(url) == 0 {
+		t.Skip("OASIS_DG_CONFORMANCE_URL not set")
+	}
+
+	Run(t, Props{BaseURL: url})
+}
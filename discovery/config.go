@@ -0,0 +1,131 @@
+package discovery
+
+import (
+	"errors"
+	"time"
+
+	"github.com/oasislabs/oasis-gateway/config"
+	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+type Provider string
+
+const (
+	ProviderNone   Provider = "none"
+	ProviderConsul Provider = "consul"
+	ProviderEtcd   Provider = "etcd"
+)
+
+func (p Provider) String() string {
+	return string(p)
+}
+
+// Config defines how the gateway instance registers itself
+// with a service discovery backend
+type Config struct {
+	// Provider is the discovery backend. If ProviderNone the
+	// instance is not registered
+	Provider Provider
+
+	// URL is the url of the http API of the discovery backend
+	URL string
+
+	// ServiceName is the name of the service under which
+	// the instance is registered
+	ServiceName string
+
+	// InstanceID uniquely identifies the instance. If not set
+	// it is derived from the service name and the address
+	InstanceID string
+
+	// AdvertiseAddr is the host:port at which the public API of the
+	// instance is reachable by others. If not set the address the
+	// public API is bound to is used
+	AdvertiseAddr string
+
+	// AdvertiseHealthAddr is the host:port at which the private API,
+	// which serves the health endpoint, is reachable by others. If
+	// not set the address the private API is bound to is used
+	AdvertiseHealthAddr string
+
+	// TTL is the time after which the registration expires
+	// if it is not renewed
+	TTL time.Duration
+}
+
+func (c *Config) Log(fields log.Fields) {
+	fields.Add("discovery.provider", c.Provider)
+	fields.Add("discovery.url", c.URL)
+	fields.Add("discovery.service_name", c.ServiceName)
+	fields.Add("discovery.instance_id", c.InstanceID)
+	fields.Add("discovery.advertise_addr", c.AdvertiseAddr)
+	fields.Add("discovery.advertise_health_addr", c.AdvertiseHealthAddr)
+	fields.Add("discovery.ttl_ms", int64(c.TTL/time.Millisecond))
+}
+
+func (c *Config) Configure(v *viper.Viper) error {
+	c.Provider = Provider(v.GetString("discovery.provider"))
+	switch c.Provider {
+	case ProviderNone:
+		return nil
+	case ProviderConsul, ProviderEtcd:
+	default:
+		return config.ErrInvalidValue{
+			Key:          "discovery.provider",
+			InvalidValue: c.Provider.String(),
+			Values: []string{
+				ProviderNone.String(),
+				ProviderConsul.String(),
+				ProviderEtcd.String(),
+			},
+		}
+	}
+
+	c.URL = v.GetString("discovery.url")
+	if len(c.URL) == 0 {
+		return config.ErrKeyNotSet{Key: "discovery.url"}
+	}
+
+	c.ServiceName = v.GetString("discovery.service_name")
+	if len(c.ServiceName) == 0 {
+		return config.ErrKeyNotSet{Key: "discovery.service_name"}
+	}
+
+	c.InstanceID = v.GetString("discovery.instance_id")
+	c.AdvertiseAddr = v.GetString("discovery.advertise_addr")
+	c.AdvertiseHealthAddr = v.GetString("discovery.advertise_health_addr")
+
+	ttl := v.GetInt64("discovery.ttl_ms")
+	if ttl < 1000 {
+		return errors.New("discovery.ttl_ms must be at least 1000")
+	}
+	c.TTL = time.Duration(ttl) * time.Millisecond
+
+	return nil
+}
+
+func (c *Config) Bind(v *viper.Viper, cmd *cobra.Command) error {
+	cmd.PersistentFlags().String("discovery.provider", ProviderNone.String(),
+		"service discovery backend the instance registers with. "+
+			"Options are "+ProviderNone.String()+
+			", "+ProviderConsul.String()+
+			", "+ProviderEtcd.String()+".")
+	cmd.PersistentFlags().String("discovery.url", "",
+		"url of the http API of the consul agent or etcd endpoint")
+	cmd.PersistentFlags().String("discovery.service_name", "oasis-gateway",
+		"name of the service under which the instance is registered")
+	cmd.PersistentFlags().String("discovery.instance_id", "",
+		"unique identifier of the instance. If not set it is derived "+
+			"from the service name and the advertised address")
+	cmd.PersistentFlags().String("discovery.advertise_addr", "",
+		"host:port at which the public API of the instance is reachable. "+
+			"If not set the public http interface and port are used")
+	cmd.PersistentFlags().String("discovery.advertise_health_addr", "",
+		"host:port at which the private API, which serves the health endpoint, "+
+			"is reachable. If not set the private http interface and port are used")
+	cmd.PersistentFlags().Int64("discovery.ttl_ms", 30000,
+		"time in milliseconds after which the registration expires if it is not renewed")
+	return nil
+}
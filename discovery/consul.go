@@ -0,0 +1,87 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+)
+
+// consulService is the registration of a service in the
+// Consul agent API
+type consulService struct {
+	ID      string
+	Name    string
+	Address string
+	Port    int
+	Tags    []string
+	Meta    map[string]string
+	Check   consulCheck
+}
+
+// consulCheck is a TTL check that keeps the service healthy
+// while it is renewed
+type consulCheck struct {
+	CheckID                        string
+	TTL                            string
+	DeregisterCriticalServiceAfter string
+}
+
+// ConsulRegistrar registers instances as services of the local
+// Consul agent with a TTL check that is renewed by the gateway
+type ConsulRegistrar struct {
+	api httpAPI
+}
+
+// NewConsulRegistrar creates a registrar for the Consul agent at url.
+// If client is nil a default http client is used
+func NewConsulRegistrar(client HttpClient, url string) *ConsulRegistrar {
+	return &ConsulRegistrar{api: newHttpAPI(client, url)}
+}
+
+// Register is the implementation of Registrar.Register for ConsulRegistrar
+func (r *ConsulRegistrar) Register(ctx context.Context, instance Instance, ttl time.Duration) error {
+	host, portStr, err := net.SplitHostPort(instance.Address)
+	if err != nil {
+		return err
+	}
+
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return err
+	}
+
+	return r.api.do(ctx, "PUT", "/v1/agent/service/register", consulService{
+		ID:      instance.ID,
+		Name:    instance.Name,
+		Address: host,
+		Port:    port,
+		Tags:    []string{instance.Role},
+		Meta: map[string]string{
+			"role":       instance.Role,
+			"health_url": instance.HealthURL,
+		},
+		Check: consulCheck{
+			CheckID: consulCheckID(instance),
+			TTL:     ttl.String(),
+			// instances that stop renewing their registration are
+			// removed so that the catalog does not grow unbounded
+			DeregisterCriticalServiceAfter: (10 * ttl).String(),
+		},
+	}, nil)
+}
+
+// Renew is the implementation of Registrar.Renew for ConsulRegistrar
+func (r *ConsulRegistrar) Renew(ctx context.Context, instance Instance) error {
+	return r.api.do(ctx, "PUT", "/v1/agent/check/pass/"+consulCheckID(instance), nil, nil)
+}
+
+// Deregister is the implementation of Registrar.Deregister for ConsulRegistrar
+func (r *ConsulRegistrar) Deregister(ctx context.Context, instance Instance) error {
+	return r.api.do(ctx, "PUT", "/v1/agent/service/deregister/"+instance.ID, nil, nil)
+}
+
+func consulCheckID(instance Instance) string {
+	return fmt.Sprintf("service:%s", instance.ID)
+}
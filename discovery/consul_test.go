@@ -0,0 +1,62 @@
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConsulRegistrarRegister(t *testing.T) {
+	var service consulService
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "PUT", req.Method)
+		assert.Equal(t, "/v1/agent/service/register", req.URL.Path)
+		assert.Nil(t, json.NewDecoder(req.Body).Decode(&service))
+	}))
+	defer server.Close()
+
+	err := NewConsulRegistrar(nil, server.URL).Register(context.Background(), instance, 30*time.Second)
+	assert.Nil(t, err)
+	assert.Equal(t, consulService{
+		ID:      "gateway-1",
+		Name:    "oasis-gateway",
+		Address: "10.0.0.1",
+		Port:    1234,
+		Tags:    []string{"full"},
+		Meta: map[string]string{
+			"role":       "full",
+			"health_url": "http://10.0.0.1:1235/v0/api/health",
+		},
+		Check: consulCheck{
+			CheckID:                        "service:gateway-1",
+			TTL:                            "30s",
+			DeregisterCriticalServiceAfter: "5m0s",
+		},
+	}, service)
+}
+
+func TestConsulRegistrarRenew(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "/v1/agent/check/pass/service:gateway-1", req.URL.Path)
+	}))
+	defer server.Close()
+
+	err := NewConsulRegistrar(nil, server.URL).Renew(context.Background(), instance)
+	assert.Nil(t, err)
+}
+
+func TestConsulRegistrarDeregisterErr(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "/v1/agent/service/deregister/gateway-1", req.URL.Path)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	err := NewConsulRegistrar(nil, server.URL).Deregister(context.Background(), instance)
+	assert.Error(t, err)
+}
@@ -0,0 +1,210 @@
+// Package discovery registers the gateway instance with a service
+// discovery backend, so that load balancers and other gateways can
+// find the instances of a clustered deployment
+package discovery
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/oasislabs/oasis-gateway/stats"
+)
+
+// deregisterTimeout is the maximum time spent deregistering the
+// instance once the registration is stopped
+const deregisterTimeout = 5 * time.Second
+
+// Instance describes a gateway instance
+type Instance struct {
+	// ID uniquely identifies the instance amongst the
+	// instances of the service
+	ID string `json:"id"`
+
+	// Name is the name of the service the instance belongs to
+	Name string `json:"name"`
+
+	// Address is the host:port at which the public API
+	// of the instance is reachable
+	Address string `json:"address"`
+
+	// Role is the role of the instance
+	Role string `json:"role"`
+
+	// HealthURL is the url of the health endpoint of the instance
+	HealthURL string `json:"healthUrl"`
+}
+
+// Registrar registers instances with a discovery backend. A registration
+// expires unless it is renewed before its TTL elapses
+type Registrar interface {
+	// Register registers the instance with the provided TTL
+	Register(context.Context, Instance, time.Duration) error
+
+	// Renew renews the registration of the instance
+	Renew(context.Context, Instance) error
+
+	// Deregister removes the registration of the instance
+	Deregister(context.Context, Instance) error
+}
+
+// Services required by the Registration
+type Services struct {
+	Logger    log.Logger
+	Registrar Registrar
+}
+
+// Props define the behaviour of the Registration
+type Props struct {
+	// Instance is the instance that is registered
+	Instance Instance
+
+	// TTL is the time after which the registration expires
+	// if it is not renewed
+	TTL time.Duration
+}
+
+// Registration keeps the instance registered with the discovery
+// backend while the gateway is running
+type Registration struct {
+	logger    log.Logger
+	registrar Registrar
+	instance  Instance
+	ttl       time.Duration
+	counters  *stats.CounterGroup
+
+	mu         sync.Mutex
+	registered bool
+	done       chan struct{}
+}
+
+// NewRegistration creates a new registration for the instance
+func NewRegistration(services Services, props Props) *Registration {
+	if services.Logger == nil {
+		panic("Logger must be set")
+	}
+
+	if services.Registrar == nil {
+		panic("Registrar must be set")
+	}
+
+	if props.TTL <= 0 {
+		panic("TTL must be greater than 0")
+	}
+
+	return &Registration{
+		logger:    services.Logger.ForClass("discovery", "Registration"),
+		registrar: services.Registrar,
+		instance:  props.Instance,
+		ttl:       props.TTL,
+		counters:  stats.NewCounterGroup("registrations", "renewals", "errors"),
+		done:      make(chan struct{}),
+	}
+}
+
+// Name is the implementation of stats.Collector.Name for Registration
+func (r *Registration) Name() string {
+	return "discovery.Registration"
+}
+
+// Stats is the implementation of stats.Collector.Stats for Registration
+func (r *Registration) Stats() stats.Metrics {
+	r.mu.Lock()
+	registered := r.registered
+	r.mu.Unlock()
+
+	return stats.Metrics{
+		"registered": registered,
+		"counters":   r.counters.Stats(),
+	}
+}
+
+// Start registers the instance and renews the registration a few
+// times per TTL until the context is cancelled, at which point the
+// instance is deregistered. If the registration or a renewal fails,
+// the instance is registered again on the next attempt
+func (r *Registration) Start(ctx context.Context) {
+	r.Refresh(ctx)
+
+	go func() {
+		ticker := time.NewTicker(r.ttl / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				r.deregister()
+				close(r.done)
+				return
+			case <-ticker.C:
+				r.Refresh(ctx)
+			}
+		}
+	}()
+}
+
+// Wait blocks until the registration started with Start has
+// stopped and the instance has been deregistered
+func (r *Registration) Wait() {
+	<-r.done
+}
+
+// Refresh registers the instance if it is not registered yet, or
+// renews its registration otherwise
+func (r *Registration) Refresh(ctx context.Context) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.registered {
+		err := r.registrar.Renew(ctx, r.instance)
+		if err == nil {
+			r.counters.Incr("renewals")
+			return
+		}
+
+		r.fail(ctx, "failed to renew instance registration", err)
+	}
+
+	if err := r.registrar.Register(ctx, r.instance, r.ttl); err != nil {
+		r.registered = false
+		r.fail(ctx, "failed to register instance", err)
+		return
+	}
+
+	r.registered = true
+	r.counters.Incr("registrations")
+	r.logger.Info(ctx, "instance registered", log.MapFields{
+		"call_type": "DiscoveryRegisterSuccess",
+		"id":        r.instance.ID,
+		"address":   r.instance.Address,
+	})
+}
+
+func (r *Registration) fail(ctx context.Context, msg string, err error) {
+	r.counters.Incr("errors")
+	r.logger.Warn(ctx, msg, log.MapFields{
+		"call_type": "DiscoveryRegisterFailure",
+		"id":        r.instance.ID,
+		"err":       err.Error(),
+	})
+}
+
+func (r *Registration) deregister() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.registered {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), deregisterTimeout)
+	defer cancel()
+
+	if err := r.registrar.Deregister(ctx, r.instance); err != nil {
+		r.fail(ctx, "failed to deregister instance", err)
+		return
+	}
+
+	r.registered = false
+}
@@ -0,0 +1,106 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var logger = log.NewLogrus(log.LogrusLoggerProperties{Output: ioutil.Discard})
+
+var instance = Instance{
+	ID:        "gateway-1",
+	Name:      "oasis-gateway",
+	Address:   "10.0.0.1:1234",
+	Role:      "full",
+	HealthURL: "http://10.0.0.1:1235/v0/api/health",
+}
+
+type MockRegistrar struct {
+	mock.Mock
+}
+
+func (r *MockRegistrar) Register(ctx context.Context, instance Instance, ttl time.Duration) error {
+	args := r.Called(ctx, instance, ttl)
+	return args.Error(0)
+}
+
+func (r *MockRegistrar) Renew(ctx context.Context, instance Instance) error {
+	args := r.Called(ctx, instance)
+	return args.Error(0)
+}
+
+func (r *MockRegistrar) Deregister(ctx context.Context, instance Instance) error {
+	args := r.Called(ctx, instance)
+	return args.Error(0)
+}
+
+func newRegistration(registrar Registrar) *Registration {
+	return NewRegistration(Services{
+		Logger:    logger,
+		Registrar: registrar,
+	}, Props{
+		Instance: instance,
+		TTL:      30 * time.Second,
+	})
+}
+
+func TestRegistrationRefreshRegisters(t *testing.T) {
+	registrar := &MockRegistrar{}
+	registrar.On("Register", mock.Anything, instance, 30*time.Second).Return(nil)
+	registrar.On("Renew", mock.Anything, instance).Return(nil)
+
+	r := newRegistration(registrar)
+	r.Refresh(context.Background())
+	r.Refresh(context.Background())
+
+	registrar.AssertNumberOfCalls(t, "Register", 1)
+	registrar.AssertNumberOfCalls(t, "Renew", 1)
+	assert.Equal(t, true, r.Stats()["registered"])
+}
+
+func TestRegistrationRefreshRenewErr(t *testing.T) {
+	registrar := &MockRegistrar{}
+	registrar.On("Register", mock.Anything, instance, 30*time.Second).Return(nil)
+	registrar.On("Renew", mock.Anything, instance).Return(errors.New("expired"))
+
+	r := newRegistration(registrar)
+	r.Refresh(context.Background())
+	r.Refresh(context.Background())
+
+	// a failed renewal registers the instance again
+	registrar.AssertNumberOfCalls(t, "Register", 2)
+	registrar.AssertNumberOfCalls(t, "Renew", 1)
+	assert.Equal(t, true, r.Stats()["registered"])
+}
+
+func TestRegistrationRefreshRegisterErr(t *testing.T) {
+	registrar := &MockRegistrar{}
+	registrar.On("Register", mock.Anything, instance, 30*time.Second).Return(errors.New("unavailable"))
+
+	r := newRegistration(registrar)
+	r.Refresh(context.Background())
+
+	assert.Equal(t, false, r.Stats()["registered"])
+}
+
+func TestRegistrationStartDeregisters(t *testing.T) {
+	registrar := &MockRegistrar{}
+	registrar.On("Register", mock.Anything, instance, 30*time.Second).Return(nil)
+	registrar.On("Deregister", mock.Anything, instance).Return(nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r := newRegistration(registrar)
+	r.Start(ctx)
+	cancel()
+	r.Wait()
+
+	registrar.AssertCalled(t, "Deregister", mock.Anything, instance)
+	assert.Equal(t, false, r.Stats()["registered"])
+}
@@ -0,0 +1,129 @@
+package discovery
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	stderr "github.com/pkg/errors"
+)
+
+// EtcdKeyPrefix is the prefix of the keys under which
+// instances are registered in etcd. The instance is stored
+// at EtcdKeyPrefix/<name>/<id>
+const EtcdKeyPrefix = "/services"
+
+var (
+	ErrLeaseNotGranted = stderr.New("etcd did not grant a lease for the registration")
+	ErrLeaseExpired    = stderr.New("lease of the registration has expired")
+)
+
+type etcdLeaseRequest struct {
+	TTL int64  `json:"TTL,omitempty"`
+	ID  string `json:"ID,omitempty"`
+}
+
+type etcdLeaseResponse struct {
+	ID  string `json:"ID"`
+	TTL string `json:"TTL"`
+}
+
+type etcdKeepAliveResponse struct {
+	Result etcdLeaseResponse `json:"result"`
+}
+
+type etcdPutRequest struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	Lease string `json:"lease"`
+}
+
+// EtcdRegistrar registers instances as keys in etcd attached to
+// a lease that is kept alive by the gateway. It uses the json
+// gateway of the etcd v3 API
+type EtcdRegistrar struct {
+	api httpAPI
+
+	mu    sync.Mutex
+	lease string
+}
+
+// NewEtcdRegistrar creates a registrar for the etcd endpoint at url.
+// If client is nil a default http client is used
+func NewEtcdRegistrar(client HttpClient, url string) *EtcdRegistrar {
+	return &EtcdRegistrar{api: newHttpAPI(client, url)}
+}
+
+// EtcdKey returns the key under which the instance is registered
+func EtcdKey(instance Instance) string {
+	return fmt.Sprintf("%s/%s/%s", EtcdKeyPrefix, instance.Name, instance.ID)
+}
+
+// Register is the implementation of Registrar.Register for EtcdRegistrar
+func (r *EtcdRegistrar) Register(ctx context.Context, instance Instance, ttl time.Duration) error {
+	var lease etcdLeaseResponse
+	if err := r.api.do(ctx, "POST", "/v3/lease/grant", etcdLeaseRequest{
+		TTL: int64(ttl / time.Second),
+	}, &lease); err != nil {
+		return err
+	}
+
+	if len(lease.ID) == 0 {
+		return ErrLeaseNotGranted
+	}
+
+	value, err := json.Marshal(instance)
+	if err != nil {
+		return err
+	}
+
+	if err := r.api.do(ctx, "POST", "/v3/kv/put", etcdPutRequest{
+		Key:   base64.StdEncoding.EncodeToString([]byte(EtcdKey(instance))),
+		Value: base64.StdEncoding.EncodeToString(value),
+		Lease: lease.ID,
+	}, nil); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.lease = lease.ID
+	r.mu.Unlock()
+
+	return nil
+}
+
+// Renew is the implementation of Registrar.Renew for EtcdRegistrar
+func (r *EtcdRegistrar) Renew(ctx context.Context, instance Instance) error {
+	r.mu.Lock()
+	lease := r.lease
+	r.mu.Unlock()
+
+	var res etcdKeepAliveResponse
+	if err := r.api.do(ctx, "POST", "/v3/lease/keepalive", etcdLeaseRequest{
+		ID: lease,
+	}, &res); err != nil {
+		return err
+	}
+
+	// etcd omits the TTL of a lease that does not exist anymore
+	if len(res.Result.TTL) == 0 || res.Result.TTL == "0" {
+		return ErrLeaseExpired
+	}
+
+	return nil
+}
+
+// Deregister is the implementation of Registrar.Deregister for EtcdRegistrar.
+// Revoking the lease removes the key attached to it
+func (r *EtcdRegistrar) Deregister(ctx context.Context, instance Instance) error {
+	r.mu.Lock()
+	lease := r.lease
+	r.mu.Unlock()
+
+	return r.api.do(ctx, "POST", "/v3/lease/revoke", etcdLeaseRequest{
+		ID: lease,
+	}, nil)
+}
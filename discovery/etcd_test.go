@@ -0,0 +1,50 @@
+package discovery
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEtcdRegistrar(t *testing.T) {
+	var put etcdPutRequest
+	ttl := "30"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/v3/lease/grant":
+			_, _ = w.Write([]byte(`{"ID":"7587848"}`))
+		case "/v3/kv/put":
+			assert.Nil(t, json.NewDecoder(req.Body).Decode(&put))
+			_, _ = w.Write([]byte(`{}`))
+		case "/v3/lease/keepalive":
+			_, _ = w.Write([]byte(`{"result":{"ID":"7587848","TTL":"` + ttl + `"}}`))
+		default:
+			t.Fatalf("unexpected request to %s", req.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	r := NewEtcdRegistrar(nil, server.URL)
+	err := r.Register(context.Background(), instance, 30*time.Second)
+	assert.Nil(t, err)
+
+	key, _ := base64.StdEncoding.DecodeString(put.Key)
+	assert.Equal(t, "/services/oasis-gateway/gateway-1", string(key))
+	assert.Equal(t, "7587848", put.Lease)
+
+	var registered Instance
+	value, _ := base64.StdEncoding.DecodeString(put.Value)
+	assert.Nil(t, json.Unmarshal(value, &registered))
+	assert.Equal(t, instance, registered)
+
+	assert.Nil(t, r.Renew(context.Background(), instance))
+
+	ttl = "0"
+	assert.Equal(t, ErrLeaseExpired, r.Renew(context.Background(), instance))
+}
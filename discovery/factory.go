@@ -0,0 +1,17 @@
+package discovery
+
+import (
+	"fmt"
+)
+
+// NewRegistrar creates the registrar for the configured provider
+func NewRegistrar(client HttpClient, config *Config) (Registrar, error) {
+	switch config.Provider {
+	case ProviderConsul:
+		return NewConsulRegistrar(client, config.URL), nil
+	case ProviderEtcd:
+		return NewEtcdRegistrar(client, config.URL), nil
+	default:
+		return nil, fmt.Errorf("discovery provider %s does not have a registrar", config.Provider)
+	}
+}
@@ -0,0 +1,68 @@
+package discovery
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// HttpClient is the basic interface for the
+// underlying http client used by the registrars
+type HttpClient interface {
+	Do(*http.Request) (*http.Response, error)
+}
+
+// httpAPI issues json requests to the http API of a
+// discovery backend
+type httpAPI struct {
+	url    string
+	client HttpClient
+}
+
+func newHttpAPI(client HttpClient, url string) httpAPI {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return httpAPI{url: strings.TrimSuffix(url, "/"), client: client}
+}
+
+// do sends the request with body serialized as json if set, and
+// deserializes the response into out if set
+func (a httpAPI) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var payload []byte
+	if body != nil {
+		p, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		payload = p
+	}
+
+	req, err := http.NewRequest(method, a.url+path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("request to %s failed with status %d", path, res.StatusCode)
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	return json.NewDecoder(res.Body).Decode(out)
+}
@@ -0,0 +1,106 @@
+package ekiden
+
+import (
+	"bytes"
+
+	"github.com/ugorji/go/codec"
+)
+
+// HeaderType is the type of a roothash block
+type HeaderType uint8
+
+const (
+	// HeaderTypeNormal is the type of a block that finalizes
+	// a batch of transactions
+	HeaderTypeNormal HeaderType = 1
+
+	// HeaderTypeRoundFailed is the type of a block of a round
+	// that failed, so its batch was not finalized
+	HeaderTypeRoundFailed HeaderType = 2
+
+	// HeaderTypeEpochTransition is the type of a block
+	// generated on an epoch transition
+	HeaderTypeEpochTransition HeaderType = 3
+)
+
+// BlockHeader is the header of a roothash block
+type BlockHeader struct {
+	// Round is the round of the runtime that generated the block
+	Round uint64 `codec:"round"`
+
+	// Timestamp is the time at which the block was generated
+	Timestamp uint64 `codec:"timestamp"`
+
+	// HeaderType defines the kind of block
+	HeaderType HeaderType `codec:"header_type"`
+
+	// PreviousHash is the hash of the previous block
+	PreviousHash []byte `codec:"previous_hash"`
+
+	// IORoot is the root of the inputs and outputs of
+	// the batch finalized by the block
+	IORoot []byte `codec:"io_root"`
+
+	// StateRoot is the root of the state of the runtime
+	// after the batch is applied
+	StateRoot []byte `codec:"state_root"`
+}
+
+// Block is a roothash block of a runtime
+type Block struct {
+	Header BlockHeader `codec:"header"`
+}
+
+// UnmarshalBlock deserializes a CBOR encoded block
+func UnmarshalBlock(p []byte, block *Block) error {
+	return codec.NewDecoder(bytes.NewBuffer(p), &codec.CborHandle{}).Decode(block)
+}
+
+// MarshalBlock serializes a block to CBOR
+func MarshalBlock(block *Block) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := codec.NewEncoder(&buf, &codec.CborHandle{}).Encode(block); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// WatchBlocksRequest is the request to watch the
+// blocks generated by a runtime
+type WatchBlocksRequest struct {
+	// RuntimeID is the ID of the runtime to watch
+	RuntimeID []byte
+}
+
+// BlockEvent is a block received from a runtime
+type BlockEvent struct {
+	// Block is the deserialized block
+	Block Block
+
+	// Hash is the hash of the block header
+	Hash []byte
+}
+
+// BlockStream is the stream of the blocks of a runtime
+type BlockStream interface {
+	// Recv blocks until the next block is received
+	// or the stream fails
+	Recv() (*BlockEvent, error)
+}
+
+// GetTransactionsRequest is the request to retrieve the
+// transactions of a batch identified by its IO root
+type GetTransactionsRequest struct {
+	// RuntimeID is the ID of the runtime that finalized the batch
+	RuntimeID []byte
+
+	// Root is the IO root of the batch
+	Root []byte
+}
+
+// GetTransactionsResponse contains the raw transactions
+// of a batch
+type GetTransactionsResponse struct {
+	Transactions [][]byte
+}
@@ -6,9 +6,11 @@ import (
 	"crypto/tls"
 	"errors"
 	"io"
+	"net"
 
 	api "github.com/oasislabs/oasis-gateway/ekiden/grpc"
 	"github.com/oasislabs/oasis-gateway/noise"
+	"github.com/oasislabs/oasis-gateway/proxy"
 	"github.com/oasislabs/oasis-gateway/rw"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
@@ -17,6 +19,10 @@ import (
 type EnclaveProps struct {
 	Endpoint string
 	URL      string
+
+	// Proxy if set is used to reach the enclave. Otherwise
+	// the proxies defined in the environment are used
+	Proxy *proxy.Proxy
 }
 
 type Enclave struct {
@@ -27,8 +33,14 @@ type Enclave struct {
 
 func DialEnclaveContext(ctx context.Context, props *EnclaveProps) (*Enclave, error) {
 	cred := credentials.NewTLS(&tls.Config{InsecureSkipVerify: true})
-	transport := grpc.WithTransportCredentials(cred)
-	conn, err := grpc.DialContext(ctx, props.URL, transport)
+	opts := []grpc.DialOption{grpc.WithTransportCredentials(cred)}
+	if props.Proxy.Explicit() {
+		opts = append(opts, grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+			return props.Proxy.DialContext(ctx, "tcp", addr)
+		}))
+	}
+
+	conn, err := grpc.DialContext(ctx, props.URL, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -39,7 +51,8 @@ func DialEnclaveContext(ctx context.Context, props *EnclaveProps) (*Enclave, err
 		Conns:  1,
 		Client: noise.ClientFunc(enclave.request),
 		SessionProps: noise.SessionProps{
-			Initiator: true,
+			Initiator:      true,
+			MaxMessageSize: noise.MaxMessageSize,
 		},
 	})
 	if err != nil {
@@ -3,8 +3,10 @@ package ekiden
 import (
 	"context"
 	"errors"
+	"net"
 
 	api "github.com/oasislabs/oasis-gateway/ekiden/grpc"
+	"github.com/oasislabs/oasis-gateway/proxy"
 	"google.golang.org/grpc"
 )
 
@@ -12,9 +14,24 @@ type Runtime struct {
 	conn *grpc.ClientConn
 }
 
-func DialRuntimeContext(ctx context.Context, url string) (*Runtime, error) {
-	transport := grpc.WithInsecure()
-	conn, err := grpc.DialContext(ctx, url, transport)
+// RuntimeProps are the properties used to dial a runtime
+type RuntimeProps struct {
+	URL string
+
+	// Proxy if set is used to reach the runtime. Otherwise
+	// the proxies defined in the environment are used
+	Proxy *proxy.Proxy
+}
+
+func DialRuntimeContext(ctx context.Context, props *RuntimeProps) (*Runtime, error) {
+	opts := []grpc.DialOption{grpc.WithInsecure()}
+	if props.Proxy.Explicit() {
+		opts = append(opts, grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+			return props.Proxy.DialContext(ctx, "tcp", addr)
+		}))
+	}
+
+	conn, err := grpc.DialContext(ctx, props.URL, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -70,3 +87,54 @@ func (r *Runtime) EthereumTransaction(
 
 	return &EthereumTransactionResponse{Result: res.Result}, nil
 }
+
+type blockStream struct {
+	stream api.Runtime_WatchBlocksClient
+}
+
+// Recv implementation of BlockStream for blockStream
+func (s blockStream) Recv() (*BlockEvent, error) {
+	res, err := s.stream.Recv()
+	if err != nil {
+		return nil, err
+	}
+
+	var block Block
+	if err := UnmarshalBlock(res.Block, &block); err != nil {
+		return nil, err
+	}
+
+	return &BlockEvent{Block: block, Hash: res.BlockHash}, nil
+}
+
+// WatchBlocks subscribes to the roothash blocks of the runtime. The
+// stream is closed when the context is cancelled
+func (r *Runtime) WatchBlocks(ctx context.Context, req *WatchBlocksRequest) (BlockStream, error) {
+	runtime := api.NewRuntimeClient(r.conn)
+	stream, err := runtime.WatchBlocks(ctx, &api.WatchBlocksRequest{
+		RuntimeId: req.RuntimeID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return blockStream{stream: stream}, nil
+}
+
+// GetTransactions retrieves the raw transactions of the
+// batch finalized with the IO root
+func (r *Runtime) GetTransactions(
+	ctx context.Context,
+	req *GetTransactionsRequest,
+) (*GetTransactionsResponse, error) {
+	runtime := api.NewRuntimeClient(r.conn)
+	res, err := runtime.GetTransactions(ctx, &api.GetTransactionsRequest{
+		RuntimeId: req.RuntimeID,
+		Root:      req.Root,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &GetTransactionsResponse{Transactions: res.Txns}, nil
+}
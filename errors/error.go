@@ -286,6 +286,48 @@ var (
 		desc:     "Internal Error. Please check the status of the service.",
 	}
 
+	ErrUsageReport = ErrorCode{
+		category: InternalError,
+		code:     1045,
+		desc:     "Failed to retrieve the usage report.",
+	}
+
+	ErrNonceStore = ErrorCode{
+		category: InternalError,
+		code:     1046,
+		desc:     "Failed to verify the request nonce.",
+	}
+
+	ErrTraceTransaction = ErrorCode{
+		category: InternalError,
+		code:     1047,
+		desc:     "Failed to trace the transaction.",
+	}
+
+	ErrSealOutput = ErrorCode{
+		category: InternalError,
+		code:     1048,
+		desc:     "Failed to encrypt the output.",
+	}
+
+	ErrStore = ErrorCode{
+		category: InternalError,
+		code:     1049,
+		desc:     "Failed to access the mailbox store.",
+	}
+
+	ErrGasPrice = ErrorCode{
+		category: InternalError,
+		code:     1050,
+		desc:     "Internal Error. Please check the status of the service.",
+	}
+
+	ErrInsufficientGatewayFunds = ErrorCode{
+		category: InternalError,
+		code:     1051,
+		desc:     "The wallet of the gateway does not have enough funds for the transaction.",
+	}
+
 	ErrOutOfRange = ErrorCode{
 		category: InputError,
 		code:     2001,
@@ -364,6 +406,150 @@ var (
 		desc:     "Provided string is not a valid hex encoding.",
 	}
 
+	ErrTransactionRejected = ErrorCode{
+		category: InputError,
+		code:     2014,
+		desc:     "Transaction rejected by gateway policy.",
+	}
+
+	ErrInvalidAlias = ErrorCode{
+		category: InputError,
+		code:     2015,
+		desc:     "Provided invalid alias.",
+	}
+
+	ErrDataMissingHexPrefix = ErrorCode{
+		category: InputError,
+		code:     2016,
+		desc:     "Data field must be a 0x prefixed hex string.",
+	}
+
+	ErrDataOddLength = ErrorCode{
+		category: InputError,
+		code:     2017,
+		desc:     "Data field must be a hex string of even length.",
+	}
+
+	ErrDataNotHex = ErrorCode{
+		category: InputError,
+		code:     2018,
+		desc:     "Data field contains characters that are not valid hex.",
+	}
+
+	ErrDataTooLarge = ErrorCode{
+		category: InputError,
+		code:     2019,
+		desc:     "Data field exceeds the maximum allowed payload size.",
+	}
+
+	ErrUnknownEncoding = ErrorCode{
+		category: InputError,
+		code:     2020,
+		desc:     "Encoding field must be either hex or base64.",
+	}
+
+	ErrDataNotBase64 = ErrorCode{
+		category: InputError,
+		code:     2021,
+		desc:     "Data field is not a valid base64 encoding.",
+	}
+
+	ErrIdempotencyKeyTooLong = ErrorCode{
+		category: InputError,
+		code:     2022,
+		desc:     "Idempotency key exceeds the maximum allowed size.",
+	}
+
+	ErrUnsupportedContentType = ErrorCode{
+		category: InputError,
+		code:     2023,
+		desc:     "Content type of the deployment payload is not supported.",
+	}
+
+	ErrInvalidWASMHeader = ErrorCode{
+		category: InputError,
+		code:     2024,
+		desc:     "Data field does not start with a valid WASM header.",
+	}
+
+	ErrInvalidUsageRange = ErrorCode{
+		category: InputError,
+		code:     2025,
+		desc:     "Provided invalid range of days for the usage report.",
+	}
+
+	ErrInvalidTransactionHash = ErrorCode{
+		category: InputError,
+		code:     2026,
+		desc:     "Provided invalid transaction hash.",
+	}
+
+	ErrUnknownFeature = ErrorCode{
+		category: InputError,
+		code:     2027,
+		desc:     "Provided unknown feature.",
+	}
+
+	ErrInvalidOutputKey = ErrorCode{
+		category: InputError,
+		code:     2028,
+		desc:     "Provided invalid output key.",
+	}
+
+	ErrInvalidSessionMetadata = ErrorCode{
+		category: InputError,
+		code:     2029,
+		desc:     "Provided invalid session metadata.",
+	}
+
+	ErrInvalidEventType = ErrorCode{
+		category: InputError,
+		code:     2030,
+		desc:     "Provided invalid event type.",
+	}
+
+	ErrInvalidTimeRange = ErrorCode{
+		category: InputError,
+		code:     2031,
+		desc:     "Provided invalid time range.",
+	}
+
+	ErrInvalidLogLevel = ErrorCode{
+		category: InputError,
+		code:     2032,
+		desc:     "Provided invalid log level.",
+	}
+
+	ErrGasLimitExceeded = ErrorCode{
+		category: InputError,
+		code:     2033,
+		desc:     "Transaction requires more gas than the maximum allowed.",
+	}
+
+	ErrInvalidBatchSize = ErrorCode{
+		category: InputError,
+		code:     2034,
+		desc:     "Provided batch has no calls or more calls than allowed.",
+	}
+
+	ErrGasLimitTooLow = ErrorCode{
+		category: InputError,
+		code:     2035,
+		desc:     "Provided gas limit is lower than the intrinsic gas of a transaction.",
+	}
+
+	ErrGasPriceExceeded = ErrorCode{
+		category: InputError,
+		code:     2036,
+		desc:     "Provided gas price is higher than the maximum allowed.",
+	}
+
+	ErrOutputKeyBound = ErrorCode{
+		category: InputError,
+		code:     2037,
+		desc:     "Provided output key is different from the output key of the session.",
+	}
+
 	ErrQueueLimitReached = ErrorCode{
 		category: ResourceLimitReached,
 		code:     3001,
@@ -371,6 +557,27 @@ var (
 			"No further requests can be processed until requests are confirmed.",
 	}
 
+	ErrInFlightLimitReached = ErrorCode{
+		category: ResourceLimitReached,
+		code:     3002,
+		desc: "The number of requests in flight has reached its limit. " +
+			"No further requests can be processed until requests complete.",
+	}
+
+	ErrSubscriptionLagging = ErrorCode{
+		category: ResourceLimitReached,
+		code:     3003,
+		desc: "The consumer of the subscription fell too far behind. " +
+			"The subscription has been closed.",
+	}
+
+	ErrSpendLimitReached = ErrorCode{
+		category: ResourceLimitReached,
+		code:     3004,
+		desc: "The gas spent by the issuer has reached its limit. " +
+			"No further transactions can be processed.",
+	}
+
 	ErrQueueDiscardNotExists = ErrorCode{
 		category: StateConflict,
 		code:     4001,
@@ -383,12 +590,42 @@ var (
 		desc:     "Attempt to create a subscription that already exists.",
 	}
 
+	ErrTransactionExpired = ErrorCode{
+		category: StateConflict,
+		code:     4003,
+		desc:     "Transaction expired before it could be included in a block.",
+	}
+
+	ErrTransactionTimeout = ErrorCode{
+		category: StateConflict,
+		code:     4004,
+		desc:     "Transaction did not complete within the maximum transaction lifetime.",
+	}
+
+	ErrRequestCancelled = ErrorCode{
+		category: StateConflict,
+		code:     4005,
+		desc:     "Request was cancelled before it could be completed.",
+	}
+
+	ErrDeployInProgress = ErrorCode{
+		category: StateConflict,
+		code:     4006,
+		desc:     "An identical deployment is already in progress.",
+	}
+
 	ErrAPINotImplemented = ErrorCode{
 		category: NotImplemented,
 		code:     5001,
 		desc:     "API not Implemented.",
 	}
 
+	ErrUnsupportedByBackend = ErrorCode{
+		category: NotImplemented,
+		code:     5002,
+		desc:     "API is not supported by the backend node.",
+	}
+
 	ErrQueueNotFound = ErrorCode{
 		category: NotFound,
 		code:     6001,
@@ -401,6 +638,42 @@ var (
 		desc:     "Subscription not found.",
 	}
 
+	ErrAliasNotFound = ErrorCode{
+		category: NotFound,
+		code:     6003,
+		desc:     "Alias not found.",
+	}
+
+	ErrNameNotResolved = ErrorCode{
+		category: NotFound,
+		code:     6004,
+		desc:     "Name could not be resolved to a service address.",
+	}
+
+	ErrSnapshotNotFound = ErrorCode{
+		category: NotFound,
+		code:     6005,
+		desc:     "Snapshot not found.",
+	}
+
+	ErrFeatureDisabled = ErrorCode{
+		category: NotFound,
+		code:     6006,
+		desc:     "API is disabled in this deployment.",
+	}
+
+	ErrOutputNotFound = ErrorCode{
+		category: NotFound,
+		code:     6007,
+		desc:     "Output not found or expired.",
+	}
+
+	ErrIdempotencyKeyNotFound = ErrorCode{
+		category: NotFound,
+		code:     6008,
+		desc:     "Idempotency key not found.",
+	}
+
 	ErrInvalidAAD = ErrorCode{
 		category: AuthenticationError,
 		code:     7001,
@@ -424,6 +697,60 @@ var (
 		code:     7004,
 		desc:     "Failed to verify request.",
 	}
+
+	ErrFederationSignature = ErrorCode{
+		category: AuthenticationError,
+		code:     7005,
+		desc:     "Failed to verify the signature of the forwarded request.",
+	}
+
+	ErrMissingScope = ErrorCode{
+		category: AuthenticationError,
+		code:     7006,
+		desc:     "Request is not authorized to access the API.",
+	}
+
+	ErrSessionKeyBound = ErrorCode{
+		category: AuthenticationError,
+		code:     7007,
+		desc:     "Session key is already in use by a different user.",
+	}
+
+	ErrAuthenticationLockout = ErrorCode{
+		category: AuthenticationError,
+		code:     7008,
+		desc:     "Too many failed authentication attempts. Try again later.",
+	}
+
+	ErrReplayedRequest = ErrorCode{
+		category: AuthenticationError,
+		code:     7009,
+		desc:     "Request is stale or has already been served.",
+	}
+
+	ErrPrivateAPIToken = ErrorCode{
+		category: AuthenticationError,
+		code:     7010,
+		desc:     "Request to the private API does not have a valid token.",
+	}
+
+	ErrUnderMaintenance = ErrorCode{
+		category: Unavailable,
+		code:     8001,
+		desc:     "Service is under maintenance.",
+	}
+
+	ErrShuttingDown = ErrorCode{
+		category: Unavailable,
+		code:     8002,
+		desc:     "Service is shutting down.",
+	}
+
+	ErrMailboxUnavailable = ErrorCode{
+		category: Unavailable,
+		code:     8003,
+		desc:     "Mailbox is unavailable. Please try again later.",
+	}
 )
 
 // Category defines error categories that logically group them. This classification
@@ -465,6 +792,10 @@ const (
 	// AuthenticationError refers to errors in which the client
 	// cannot be authenticated
 	AuthenticationError Category = "AuthenticationError"
+
+	// Unavailable refers to errors in which the service cannot serve
+	// the request temporarily, and the client should retry later
+	Unavailable Category = "Unavailable"
 )
 
 // We have to redefine this interface here because it is private,
@@ -0,0 +1,122 @@
+package eth
+
+import (
+	"context"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	rpc "github.com/ethereum/go-ethereum/rpc"
+	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/oasislabs/oasis-gateway/stats"
+)
+
+// codeMethodNotFound is the JSON-RPC error code returned by a
+// node for a method that it does not implement
+const codeMethodNotFound = -32601
+
+// Capabilities are the features supported by the node and
+// runtime the gateway is connected to
+type Capabilities struct {
+	// ChainID is the chain ID reported by the node, or 0
+	// if the node does not report it
+	ChainID uint64
+
+	// PublicKeys is true if the node supports oasis_getPublicKey
+	PublicKeys bool
+
+	// Expiry is true if the node supports oasis_getExpiry and
+	// transactions with an expiry
+	Expiry bool
+
+	// Invoke is true if the node supports oasis_invoke
+	Invoke bool
+
+	// Trace is true if the node supports debug_traceTransaction
+	Trace bool
+
+	// DynamicFees is true if the blocks of the chain have a base
+	// fee, so that it accepts EIP-1559 dynamic fee transactions
+	DynamicFees bool
+}
+
+// Log is the implementation of log.Loggable for Capabilities
+func (c Capabilities) Log(fields log.Fields) {
+	fields.Add("chain_id", c.ChainID)
+	fields.Add("public_keys", c.PublicKeys)
+	fields.Add("expiry", c.Expiry)
+	fields.Add("invoke", c.Invoke)
+	fields.Add("trace", c.Trace)
+	fields.Add("dynamic_fees", c.DynamicFees)
+}
+
+// Stats returns the capabilities as metrics
+func (c Capabilities) Stats() stats.Metrics {
+	return stats.Metrics{
+		"chainId":     c.ChainID,
+		"publicKeys":  c.PublicKeys,
+		"expiry":      c.Expiry,
+		"invoke":      c.Invoke,
+		"trace":       c.Trace,
+		"dynamicFees": c.DynamicFees,
+	}
+}
+
+// isMethodNotFound returns true if the error returned by a
+// node signals that the method called is not implemented
+func isMethodNotFound(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if rerr, ok := err.(rpc.Error); ok && rerr.ErrorCode() == codeMethodNotFound {
+		return true
+	}
+
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "method") &&
+		(strings.Contains(msg, "not found") || strings.Contains(msg, "does not exist"))
+}
+
+// supportsMethod calls a method with the provided parameters and
+// returns false only if the node reports the method does not exist.
+// Any other error, like invalid parameters, means the method exists
+func supportsMethod(ctx context.Context, client rpcClient, method string, params ...interface{}) bool {
+	var res interface{}
+	return !isMethodNotFound(client.CallContext(ctx, &res, method, params...))
+}
+
+// ProbeCapabilities queries the node for the features it supports. The
+// calls are not retried, so an error is returned if the node cannot be
+// reached
+func (c *PooledClient) ProbeCapabilities(ctx context.Context) (Capabilities, error) {
+	var caps Capabilities
+
+	conn, err := c.pool.Conn(ctx)
+	if err != nil {
+		return caps, err
+	}
+
+	var chainID hexutil.Uint64
+	err = conn.rclient.CallContext(ctx, &chainID, "eth_chainId")
+	switch {
+	case err == nil:
+		caps.ChainID = uint64(chainID)
+	case !isMethodNotFound(err):
+		return caps, err
+	}
+
+	var zero common.Address
+	caps.PublicKeys = supportsMethod(ctx, conn.rclient, "oasis_getPublicKey", zero)
+	caps.Expiry = supportsMethod(ctx, conn.rclient, "oasis_getExpiry", zero)
+	caps.Invoke = supportsMethod(ctx, conn.rclient, "oasis_invoke")
+	caps.Trace = supportsMethod(ctx, conn.rclient, "debug_traceTransaction", common.Hash{})
+
+	// a failure to fetch the latest block is not an error of the
+	// probe, the chain is then assumed to only take legacy fees
+	var header *blockBaseFee
+	err = conn.rclient.CallContext(ctx, &header, "eth_getBlockByNumber", "latest", false)
+	caps.DynamicFees = err == nil && header != nil && header.BaseFee != nil
+
+	return caps, nil
+}
@@ -0,0 +1,101 @@
+package eth
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type methodNotFoundError struct{}
+
+func (methodNotFoundError) Error() string  { return "the method does not exist/is not available" }
+func (methodNotFoundError) ErrorCode() int { return -32601 }
+
+func TestIsMethodNotFound(t *testing.T) {
+	assert.False(t, isMethodNotFound(nil))
+	assert.False(t, isMethodNotFound(errors.New("invalid params")))
+	assert.True(t, isMethodNotFound(methodNotFoundError{}))
+	assert.True(t, isMethodNotFound(errors.New("Method not found")))
+}
+
+func TestPooledClientProbeCapabilities(t *testing.T) {
+	pool := mockPool{conn: &Conn{eclient: &mockEthClient{}, rclient: &mockRpcClient{}}}
+	c := NewPooledClient(PooledClientProps{
+		Pool:        pool,
+		RetryConfig: TestRetryConfig,
+	})
+
+	rclient := pool.conn.rclient.(*mockRpcClient)
+	rclient.On("CallContext", mock.Anything, mock.Anything, "eth_chainId", mock.Anything).
+		Run(func(args mock.Arguments) {
+			*args[1].(*hexutil.Uint64) = 42261
+		}).
+		Return(nil)
+	rclient.On("CallContext", mock.Anything, mock.Anything, "oasis_getPublicKey", mock.Anything).
+		Return(nil)
+	rclient.On("CallContext", mock.Anything, mock.Anything, "oasis_getExpiry", mock.Anything).
+		Return(methodNotFoundError{})
+	rclient.On("CallContext", mock.Anything, mock.Anything, "oasis_invoke", mock.Anything).
+		Return(errors.New("missing value for required argument 0"))
+	rclient.On("CallContext", mock.Anything, mock.Anything, "debug_traceTransaction", mock.Anything).
+		Return(methodNotFoundError{})
+	rclient.On("CallContext", mock.Anything, mock.Anything, "eth_getBlockByNumber", mock.Anything).
+		Run(func(args mock.Arguments) {
+			*args[1].(**blockBaseFee) = &blockBaseFee{}
+		}).
+		Return(nil)
+
+	caps, err := c.ProbeCapabilities(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, Capabilities{
+		ChainID:     42261,
+		PublicKeys:  true,
+		Expiry:      false,
+		Invoke:      true,
+		Trace:       false,
+		DynamicFees: false,
+	}, caps)
+}
+
+func TestPooledClientProbeCapabilitiesDynamicFees(t *testing.T) {
+	pool := mockPool{conn: &Conn{eclient: &mockEthClient{}, rclient: &mockRpcClient{}}}
+	c := NewPooledClient(PooledClientProps{
+		Pool:        pool,
+		RetryConfig: TestRetryConfig,
+	})
+
+	rclient := pool.conn.rclient.(*mockRpcClient)
+	rclient.On("CallContext", mock.Anything, mock.Anything, "eth_chainId", mock.Anything).
+		Return(methodNotFoundError{})
+	rclient.On("CallContext", mock.Anything, mock.Anything, "eth_getBlockByNumber", mock.Anything).
+		Run(func(args mock.Arguments) {
+			*args[1].(**blockBaseFee) = &blockBaseFee{BaseFee: (*hexutil.Big)(big.NewInt(7))}
+		}).
+		Return(nil)
+	rclient.On("CallContext", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(methodNotFoundError{})
+
+	caps, err := c.ProbeCapabilities(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, Capabilities{DynamicFees: true}, caps)
+}
+
+func TestPooledClientProbeCapabilitiesErr(t *testing.T) {
+	pool := mockPool{conn: &Conn{eclient: &mockEthClient{}, rclient: &mockRpcClient{}}}
+	c := NewPooledClient(PooledClientProps{
+		Pool:        pool,
+		RetryConfig: TestRetryConfig,
+	})
+
+	pool.conn.rclient.(*mockRpcClient).
+		On("CallContext", mock.Anything, mock.Anything, "eth_chainId", mock.Anything).
+		Return(errors.New("connection refused"))
+
+	_, err := c.ProbeCapabilities(context.Background())
+	assert.Error(t, err)
+}
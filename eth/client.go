@@ -2,9 +2,12 @@ package eth
 
 import (
 	"context"
+	"encoding/json"
 	"math/big"
+	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	ethereum "github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
@@ -16,31 +19,46 @@ import (
 	stderr "github.com/pkg/errors"
 
 	"github.com/oasislabs/oasis-gateway/concurrent"
+	"github.com/oasislabs/oasis-gateway/proxy"
+	"github.com/oasislabs/oasis-gateway/stats"
 )
 
 var (
-	ErrExceedsBalance    = stderr.New("cost of transaction exceeds sender balance")
-	ErrExceedsBlockLimit = stderr.New("requested gas greater than block gas limit")
-	ErrInvalidNonce      = stderr.New("invalid transaction nonce")
+	ErrExceedsBalance     = stderr.New("cost of transaction exceeds sender balance")
+	ErrExceedsBlockLimit  = stderr.New("requested gas greater than block gas limit")
+	ErrInvalidNonce       = stderr.New("invalid transaction nonce")
+	ErrTransactionExpired = stderr.New("transaction expired")
+	ErrCallTimeout        = stderr.New("call to the node timed out")
 )
 
 type Client interface {
+	CallContract(context.Context, ethereum.CallMsg, *big.Int) ([]byte, error)
 	EstimateGas(context.Context, ethereum.CallMsg) (uint64, error)
 	GetExpiry(context.Context, common.Address) (uint64, error)
 	GetPublicKey(context.Context, common.Address) (PublicKey, error)
 	NonceAt(context.Context, common.Address) (uint64, error)
 	SendTransaction(context.Context, *types.Transaction) (SendTransactionResponse, error)
+	SendTransactionWithExpiry(context.Context, *types.Transaction, uint64) (SendTransactionResponse, error)
+	SendDynamicFeeTransaction(context.Context, *DynamicFeeTransaction) (SendTransactionResponse, error)
 	SubscribeFilterLogs(context.Context, ethereum.FilterQuery, chan<- types.Log) (ethereum.Subscription, error)
+	FilterLogs(context.Context, ethereum.FilterQuery) ([]types.Log, error)
 	TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
+	TransactionBlockNumber(ctx context.Context, txHash common.Hash) (*big.Int, error)
+	TraceTransaction(ctx context.Context, txHash common.Hash) (TransactionTrace, error)
 	BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error)
 	GetCode(ctx context.Context, addr common.Address) (string, error)
+	ChainHead(ctx context.Context) (ChainHead, error)
+	SuggestGasPrice(ctx context.Context) (*big.Int, error)
+	BlockGasPrices(ctx context.Context, blockNumber *big.Int) (BlockGasPrices, error)
 }
 
 type ethClient interface {
+	CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error)
 	EstimateGas(ctx context.Context, msg ethereum.CallMsg) (uint64, error)
 	NonceAt(ctx context.Context, account common.Address, n *big.Int) (uint64, error)
 	TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
 	SubscribeFilterLogs(ctx context.Context, q ethereum.FilterQuery, c chan<- types.Log) (ethereum.Subscription, error)
+	FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error)
 	BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error)
 	CodeAt(ctx context.Context, addr common.Address, blockNumber *big.Int) ([]byte, error)
 	Close()
@@ -59,18 +77,62 @@ type Pool interface {
 type PooledClientProps struct {
 	Pool        Pool
 	RetryConfig concurrent.RetryConfig
+
+	// Timeout if set derives the timeout of each call to the node
+	// from the latencies of the previous calls to the same method
+	Timeout *AdaptiveTimeout
 }
 
 func NewPooledClient(props PooledClientProps) *PooledClient {
 	return &PooledClient{
 		pool:        props.Pool,
 		retryConfig: props.RetryConfig,
+		timeout:     props.Timeout,
+		tracker: stats.NewMethodTracker(
+			"eth_call",
+			"eth_chainId",
+			"eth_estimateGas",
+			"eth_gasPrice",
+			"eth_getBalance",
+			"eth_getBlockByNumber",
+			"eth_getCode",
+			"eth_getLogs",
+			"eth_getTransactionByHash",
+			"eth_getTransactionCount",
+			"eth_getTransactionReceipt",
+			"eth_subscribe",
+			"eth_syncing",
+			"oasis_getExpiry",
+			"oasis_getPublicKey",
+			"oasis_invoke",
+			"debug_traceTransaction"),
 	}
 }
 
 type PooledClient struct {
 	pool        Pool
 	retryConfig concurrent.RetryConfig
+	timeout     *AdaptiveTimeout
+	tracker     *stats.MethodTracker
+}
+
+// Stats is the implementation of stats.Collector for PooledClient.
+// It reports the latency of the RPC calls issued to the node and,
+// if the pool reports them, the metrics of the connection
+func (c *PooledClient) Stats() stats.Metrics {
+	metrics := stats.Metrics{
+		"rpc": c.tracker.Stats(),
+	}
+
+	if collector, ok := c.pool.(stats.Collector); ok {
+		metrics["connection"] = collector.Stats()
+	}
+
+	if c.timeout != nil {
+		metrics["timeout"] = c.timeout.Stats()
+	}
+
+	return metrics
 }
 
 func (c *PooledClient) inferError(err error) error {
@@ -84,19 +146,27 @@ func (c *PooledClient) inferError(err error) error {
 		return concurrent.ErrCannotRecover{Cause: stderr.Wrap(ErrExceedsBlockLimit, err.Error())}
 	case strings.Contains(err.Error(), "Invalid transaction nonce"):
 		return concurrent.ErrCannotRecover{Cause: stderr.Wrap(ErrInvalidNonce, err.Error())}
+	case strings.Contains(err.Error(), "Transaction expired"):
+		return concurrent.ErrCannotRecover{Cause: stderr.Wrap(ErrTransactionExpired, err.Error())}
 	default:
 		return err
 	}
 }
 
-func (c *PooledClient) request(ctx context.Context, fn func(conn *Conn) (interface{}, error)) (interface{}, error) {
+func (c *PooledClient) request(
+	ctx context.Context,
+	method string,
+	fn func(ctx context.Context, conn *Conn) (interface{}, error),
+) (interface{}, error) {
 	v, err := concurrent.RetryWithConfig(ctx, concurrent.SupplierFunc(func() (interface{}, error) {
 		conn, err := c.pool.Conn(ctx)
 		if err != nil {
 			return nil, err
 		}
 
-		v, err := fn(conn)
+		v, err := c.tracker.Instrument(method, func() (interface{}, error) {
+			return c.call(ctx, method, conn, fn)
+		})
 		if err != nil {
 			return nil, c.inferError(err)
 		}
@@ -118,8 +188,52 @@ func (c *PooledClient) request(ctx context.Context, fn func(conn *Conn) (interfa
 	return v, nil
 }
 
+// call issues a single call to the node bounded by the timeout
+// derived for the method. A call that times out is not retried, so
+// that a slow node sheds the load instead of accumulating it
+func (c *PooledClient) call(
+	ctx context.Context,
+	method string,
+	conn *Conn,
+	fn func(ctx context.Context, conn *Conn) (interface{}, error),
+) (interface{}, error) {
+	// the context of a subscription lasts as long as the subscription
+	// so it cannot be bounded by the latency of the calls
+	timeout := c.timeout.Timeout(method)
+	if timeout == 0 || method == "eth_subscribe" {
+		return fn(ctx, conn)
+	}
+
+	callCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	v, err := fn(callCtx, conn)
+	if err != nil && ctx.Err() == nil && callCtx.Err() == context.DeadlineExceeded {
+		c.timeout.TimedOut(method)
+		return nil, concurrent.ErrCannotRecover{
+			Cause: stderr.Wrapf(ErrCallTimeout, "%s did not complete within %s", method, timeout),
+		}
+	}
+
+	c.timeout.Observe(method, time.Since(start))
+	return v, err
+}
+
+func (c *PooledClient) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	v, err := c.request(ctx, "eth_call", func(ctx context.Context, conn *Conn) (interface{}, error) {
+		return conn.eclient.CallContract(ctx, msg, blockNumber)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return v.([]byte), nil
+}
+
 func (c *PooledClient) EstimateGas(ctx context.Context, msg ethereum.CallMsg) (uint64, error) {
-	v, err := c.request(ctx, func(conn *Conn) (interface{}, error) {
+	v, err := c.request(ctx, "eth_estimateGas", func(ctx context.Context, conn *Conn) (interface{}, error) {
 		return conn.eclient.EstimateGas(ctx, msg)
 	})
 
@@ -131,7 +245,7 @@ func (c *PooledClient) EstimateGas(ctx context.Context, msg ethereum.CallMsg) (u
 }
 
 func (c *PooledClient) BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error) {
-	v, err := c.request(ctx, func(conn *Conn) (interface{}, error) {
+	v, err := c.request(ctx, "eth_getBalance", func(ctx context.Context, conn *Conn) (interface{}, error) {
 		return conn.eclient.BalanceAt(ctx, account, blockNumber)
 	})
 
@@ -143,7 +257,7 @@ func (c *PooledClient) BalanceAt(ctx context.Context, account common.Address, bl
 }
 
 func (c *PooledClient) GetExpiry(ctx context.Context, address common.Address) (uint64, error) {
-	v, err := c.request(ctx, func(conn *Conn) (interface{}, error) {
+	v, err := c.request(ctx, "oasis_getExpiry", func(ctx context.Context, conn *Conn) (interface{}, error) {
 		var exp uint64
 		err := conn.rclient.CallContext(ctx, &exp, "oasis_getExpiry", address)
 		return exp, err
@@ -157,7 +271,7 @@ func (c *PooledClient) GetExpiry(ctx context.Context, address common.Address) (u
 }
 
 func (c *PooledClient) GetPublicKey(ctx context.Context, address common.Address) (PublicKey, error) {
-	v, err := c.request(ctx, func(conn *Conn) (interface{}, error) {
+	v, err := c.request(ctx, "oasis_getPublicKey", func(ctx context.Context, conn *Conn) (interface{}, error) {
 		var pk PublicKey
 		err := conn.rclient.CallContext(ctx, &pk, "oasis_getPublicKey", address)
 		return pk, err
@@ -171,7 +285,7 @@ func (c *PooledClient) GetPublicKey(ctx context.Context, address common.Address)
 }
 
 func (c *PooledClient) NonceAt(ctx context.Context, account common.Address) (uint64, error) {
-	v, err := c.request(ctx, func(conn *Conn) (interface{}, error) {
+	v, err := c.request(ctx, "eth_getTransactionCount", func(ctx context.Context, conn *Conn) (interface{}, error) {
 		return conn.eclient.NonceAt(ctx, account, nil)
 	})
 
@@ -188,9 +302,45 @@ func (c *PooledClient) SendTransaction(ctx context.Context, tx *types.Transactio
 		return SendTransactionResponse{}, stderr.Wrap(err, "Failed to encode transaction")
 	}
 
-	v, err := c.request(ctx, func(conn *Conn) (interface{}, error) {
+	return c.invoke(ctx, data)
+}
+
+// SendTransactionWithExpiry submits a transaction through oasis_invoke along
+// with the block number after which the runtime should discard the
+// transaction if it has not been included in a block yet
+func (c *PooledClient) SendTransactionWithExpiry(
+	ctx context.Context,
+	tx *types.Transaction,
+	expiry uint64,
+) (SendTransactionResponse, error) {
+	data, err := rlp.EncodeToBytes(tx)
+	if err != nil {
+		return SendTransactionResponse{}, stderr.Wrap(err, "Failed to encode transaction")
+	}
+
+	return c.invoke(ctx, data, hexutil.Uint64(expiry))
+}
+
+// SendDynamicFeeTransaction submits an EIP-1559 dynamic fee transaction
+// through oasis_invoke. The transaction is submitted in its EIP-2718
+// envelope, so the node must support typed transactions
+func (c *PooledClient) SendDynamicFeeTransaction(
+	ctx context.Context,
+	tx *DynamicFeeTransaction,
+) (SendTransactionResponse, error) {
+	data, err := tx.MarshalBinary()
+	if err != nil {
+		return SendTransactionResponse{}, stderr.Wrap(err, "Failed to encode transaction")
+	}
+
+	return c.invoke(ctx, data)
+}
+
+func (c *PooledClient) invoke(ctx context.Context, data []byte, params ...interface{}) (SendTransactionResponse, error) {
+	args := append([]interface{}{hexutil.Encode(data)}, params...)
+	v, err := c.request(ctx, "oasis_invoke", func(ctx context.Context, conn *Conn) (interface{}, error) {
 		var res sendTransactionResponseDeserialize
-		if err := conn.rclient.CallContext(ctx, &res, "oasis_invoke", hexutil.Encode(data)); err != nil {
+		if err := conn.rclient.CallContext(ctx, &res, "oasis_invoke", args...); err != nil {
 			return nil, err
 		}
 
@@ -216,7 +366,7 @@ func (c *PooledClient) SendTransaction(ctx context.Context, tx *types.Transactio
 }
 
 func (c *PooledClient) GetCode(ctx context.Context, addr common.Address) (string, error) {
-	v, err := c.request(ctx, func(conn *Conn) (interface{}, error) {
+	v, err := c.request(ctx, "eth_getCode", func(ctx context.Context, conn *Conn) (interface{}, error) {
 		return conn.eclient.CodeAt(ctx, addr, nil)
 	})
 
@@ -228,7 +378,7 @@ func (c *PooledClient) GetCode(ctx context.Context, addr common.Address) (string
 }
 
 func (c *PooledClient) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
-	v, err := c.request(ctx, func(conn *Conn) (interface{}, error) {
+	v, err := c.request(ctx, "eth_getTransactionReceipt", func(ctx context.Context, conn *Conn) (interface{}, error) {
 		return conn.eclient.TransactionReceipt(ctx, txHash)
 	})
 
@@ -239,12 +389,52 @@ func (c *PooledClient) TransactionReceipt(ctx context.Context, txHash common.Has
 	return v.(*types.Receipt), nil
 }
 
+// TransactionBlockNumber returns the number of the block in which
+// the transaction was mined. The receipts returned by TransactionReceipt
+// do not carry the block number, so it is taken from the transaction
+func (c *PooledClient) TransactionBlockNumber(ctx context.Context, txHash common.Hash) (*big.Int, error) {
+	v, err := c.request(ctx, "eth_getTransactionByHash", func(ctx context.Context, conn *Conn) (interface{}, error) {
+		var tx *transactionBlock
+		err := conn.rclient.CallContext(ctx, &tx, "eth_getTransactionByHash", txHash)
+		if err == nil && (tx == nil || tx.BlockNumber == nil) {
+			err = stderr.New("transaction has not been mined")
+		}
+		return tx, err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return v.(*transactionBlock).BlockNumber.ToInt(), nil
+}
+
+// TraceTransaction replays a transaction using the debug_traceTransaction
+// API. Only nodes that expose the debug namespace support this call
+func (c *PooledClient) TraceTransaction(ctx context.Context, txHash common.Hash) (TransactionTrace, error) {
+	v, err := c.request(ctx, "debug_traceTransaction", func(ctx context.Context, conn *Conn) (interface{}, error) {
+		var trace TransactionTrace
+		err := conn.rclient.CallContext(ctx, &trace, "debug_traceTransaction", txHash, map[string]interface{}{
+			"disableStorage": true,
+			"disableMemory":  true,
+			"disableStack":   true,
+		})
+		return trace, err
+	})
+
+	if err != nil {
+		return TransactionTrace{}, err
+	}
+
+	return v.(TransactionTrace), nil
+}
+
 func (c *PooledClient) SubscribeFilterLogs(
 	ctx context.Context,
 	q ethereum.FilterQuery,
 	ch chan<- types.Log,
 ) (ethereum.Subscription, error) {
-	v, err := c.request(ctx, func(conn *Conn) (interface{}, error) {
+	v, err := c.request(ctx, "eth_subscribe", func(ctx context.Context, conn *Conn) (interface{}, error) {
 		return conn.eclient.SubscribeFilterLogs(ctx, q, ch)
 	})
 
@@ -255,6 +445,131 @@ func (c *PooledClient) SubscribeFilterLogs(
 	return v.(ethereum.Subscription), nil
 }
 
+func (c *PooledClient) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	v, err := c.request(ctx, "eth_getLogs", func(ctx context.Context, conn *Conn) (interface{}, error) {
+		return conn.eclient.FilterLogs(ctx, q)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return v.([]types.Log), nil
+}
+
+// ChainHead returns the latest block known to the node, the chain
+// it belongs to and whether the node is still syncing
+func (c *PooledClient) ChainHead(ctx context.Context) (ChainHead, error) {
+	v, err := c.request(ctx, "eth_getBlockByNumber", func(ctx context.Context, conn *Conn) (interface{}, error) {
+		var header *blockHeader
+		err := conn.rclient.CallContext(ctx, &header, "eth_getBlockByNumber", "latest", false)
+		if err == nil && header == nil {
+			err = stderr.New("node returned no latest block")
+		}
+		return header, err
+	})
+	if err != nil {
+		return ChainHead{}, err
+	}
+
+	header := v.(*blockHeader)
+	head := ChainHead{
+		BlockNumber: uint64(header.Number),
+		Timestamp:   uint64(header.Timestamp),
+	}
+
+	v, err = c.request(ctx, "eth_chainId", func(ctx context.Context, conn *Conn) (interface{}, error) {
+		var chainID hexutil.Uint64
+		err := conn.rclient.CallContext(ctx, &chainID, "eth_chainId")
+		if err != nil && isMethodNotFound(err) {
+			return hexutil.Uint64(0), nil
+		}
+		return chainID, err
+	})
+	if err != nil {
+		return ChainHead{}, err
+	}
+	head.ChainID = uint64(v.(hexutil.Uint64))
+
+	v, err = c.request(ctx, "eth_syncing", func(ctx context.Context, conn *Conn) (interface{}, error) {
+		// the node returns false if it is not syncing
+		var raw json.RawMessage
+		if err := conn.rclient.CallContext(ctx, &raw, "eth_syncing"); err != nil {
+			return nil, err
+		}
+
+		var syncing bool
+		if err := json.Unmarshal(raw, &syncing); err == nil {
+			return (*syncProgress)(nil), nil
+		}
+
+		var progress syncProgress
+		if err := json.Unmarshal(raw, &progress); err != nil {
+			return nil, stderr.Wrap(err, "failed to decode sync progress")
+		}
+		return &progress, nil
+	})
+	if err != nil {
+		return ChainHead{}, err
+	}
+
+	if progress := v.(*syncProgress); progress != nil {
+		head.Syncing = true
+		head.HighestBlock = uint64(progress.HighestBlock)
+	}
+
+	return head, nil
+}
+
+// SuggestGasPrice returns the gas price the node
+// suggests for new transactions
+func (c *PooledClient) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	v, err := c.request(ctx, "eth_gasPrice", func(ctx context.Context, conn *Conn) (interface{}, error) {
+		var price hexutil.Big
+		err := conn.rclient.CallContext(ctx, &price, "eth_gasPrice")
+		return &price, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return (*big.Int)(v.(*hexutil.Big)), nil
+}
+
+// BlockGasPrices returns the gas prices of the transactions included
+// in the block. If blockNumber is nil the latest block is used
+func (c *PooledClient) BlockGasPrices(ctx context.Context, blockNumber *big.Int) (BlockGasPrices, error) {
+	number := "latest"
+	if blockNumber != nil {
+		number = hexutil.EncodeBig(blockNumber)
+	}
+
+	v, err := c.request(ctx, "eth_getBlockByNumber", func(ctx context.Context, conn *Conn) (interface{}, error) {
+		var block *blockTransactions
+		err := conn.rclient.CallContext(ctx, &block, "eth_getBlockByNumber", number, true)
+		if err == nil && block == nil {
+			err = stderr.Errorf("node returned no block %s", number)
+		}
+		return block, err
+	})
+	if err != nil {
+		return BlockGasPrices{}, err
+	}
+
+	block := v.(*blockTransactions)
+	prices := BlockGasPrices{
+		BlockNumber: uint64(block.Number),
+		GasPrices:   make([]*big.Int, 0, len(block.Transactions)),
+	}
+	for _, tx := range block.Transactions {
+		if tx.GasPrice != nil {
+			prices.GasPrices = append(prices.GasPrices, (*big.Int)(tx.GasPrice))
+		}
+	}
+
+	return prices, nil
+}
+
 type Conn struct {
 	eclient ethClient
 	rclient rpcClient
@@ -282,32 +597,69 @@ type returnResponse struct {
 type UniDialerProps struct {
 	URL         string
 	RetryConfig concurrent.RetryConfig
+
+	// Proxy used to reach http endpoints. If nil the proxies
+	// defined in the environment are used
+	Proxy *proxy.Proxy
+
+	// PollInterval is the interval at which new logs are polled for
+	// on http endpoints. If it is 0 LogPollInterval is used
+	PollInterval time.Duration
+
+	// OnReconnect if set is called when the dialer connects to the
+	// node again after its connection was reported as failed. It is
+	// called on its own goroutine so it does not block the dialer
+	OnReconnect func(Reconnect)
 }
 
 // UniDialer implements the Dialer interface and it provides
 // a connection to a specific URL. If a different URL is attempted
 // the FixedDialer will return an error
 type UniDialer struct {
-	ctx  context.Context
-	conn *Conn
-	url  string
-	req  chan interface{}
+	ctx         context.Context
+	conn        *Conn
+	url         string
+	req         chan interface{}
+	proxy       *proxy.Proxy
+	interval    time.Duration
+	metrics     connMetrics
+	onReconnect func(Reconnect)
+
+	// disconnectedAt is the time at which the last connection was
+	// reported as failed. It is only accessed by the loop
+	disconnectedAt time.Time
 }
 
 // NewUniDialer keeps a connection open to an endpoint. If the
 // connection needs to be recreated a client can signal the pool
-// to recreate the connection. Only websocket endpoints are
-// supported because only websocket endpoints support
-// the subscribe API
+// to recreate the connection. Websocket and http endpoints are
+// supported. Since http endpoints do not support the subscribe
+// API, subscriptions on them poll the node for new logs
 func NewUniDialer(ctx context.Context, url string) *UniDialer {
-	p := UniDialer{ctx: ctx, conn: nil, url: url, req: make(chan interface{})}
+	return NewUniDialerWithProps(ctx, UniDialerProps{URL: url})
+}
+
+// NewUniDialerWithProps creates a UniDialer with the
+// provided properties
+func NewUniDialerWithProps(ctx context.Context, props UniDialerProps) *UniDialer {
+	p := UniDialer{
+		ctx:         ctx,
+		conn:        nil,
+		url:         props.URL,
+		proxy:       props.Proxy,
+		interval:    props.PollInterval,
+		req:         make(chan interface{}),
+		onReconnect: props.OnReconnect,
+	}
 	go p.startLoop()
 	return &p
 }
 
 func (p *UniDialer) startLoop() {
 	defer func() {
-		p.conn.rclient.Close()
+		if p.conn != nil {
+			p.conn.rclient.Close()
+		}
 	}()
 
 	for {
@@ -334,6 +686,8 @@ func (p *UniDialer) request(req interface{}) {
 func (p *UniDialer) returnClient(req returnRequest) {
 	if p.conn == req.Conn {
 		p.conn = nil
+		p.metrics.reported()
+		p.disconnectedAt = time.Now()
 	}
 
 	req.C <- returnResponse{Error: nil}
@@ -345,34 +699,125 @@ func (p *UniDialer) dial(req dialRequest) {
 		return
 	}
 
-	c, err := rpc.DialWebsocket(req.Context, p.url, "")
+	conn, err := dialConn(req.Context, p.url, p.proxy)
+	reconnected := err == nil && p.metrics.connectedOnce
+	p.metrics.dialed(err)
 	if err != nil {
-		req.C <- dialResponse{Conn: nil, Error: stderr.Wrapf(err, "Failed to dial websocket at URL %s", p.url)}
+		req.C <- dialResponse{Conn: nil, Error: err}
 		return
 	}
 
-	p.conn = &Conn{
-		eclient: ethclient.NewClient(c),
-		rclient: c,
+	if polling, ok := conn.eclient.(*pollingEthClient); ok && p.interval > 0 {
+		polling.interval = p.interval
 	}
 
+	p.conn = conn
+	if reconnected && p.onReconnect != nil {
+		go p.onReconnect(Reconnect{
+			DisconnectedAt: p.disconnectedAt,
+			ReconnectedAt:  time.Now(),
+		})
+	}
 	req.C <- dialResponse{Conn: p.conn, Error: nil}
 }
 
+// dialConn creates a connection to the endpoint based on the
+// scheme of the url. The websocket client does not support
+// proxies, so websocket endpoints are always dialed directly
+func dialConn(ctx context.Context, rawurl string, p *proxy.Proxy) (*Conn, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, stderr.Wrapf(err, "Failed to parse URL %s", rawurl)
+	}
+
+	switch u.Scheme {
+	case "ws", "wss":
+		c, err := rpc.DialWebsocket(ctx, rawurl, "")
+		if err != nil {
+			return nil, stderr.Wrapf(err, "Failed to dial websocket at URL %s", rawurl)
+		}
+
+		return &Conn{eclient: ethclient.NewClient(c), rclient: c}, nil
+	case "http", "https":
+		c, err := rpc.DialHTTPWithClient(rawurl, p.HttpClient())
+		if err != nil {
+			return nil, stderr.Wrapf(err, "Failed to dial http at URL %s", rawurl)
+		}
+
+		return &Conn{
+			eclient: &pollingEthClient{Client: ethclient.NewClient(c), interval: LogPollInterval},
+			rclient: c,
+		}, nil
+	default:
+		return nil, stderr.Errorf("Unsupported scheme %s for URL %s", u.Scheme, rawurl)
+	}
+}
+
+// Ping checks that the endpoint at the url is reachable and that
+// it serves json-rpc requests. Http endpoints are not contacted
+// when dialed, so a request is needed to know they are available
+func Ping(ctx context.Context, rawurl string, p *proxy.Proxy) error {
+	conn, err := dialConn(ctx, rawurl, p)
+	if err != nil {
+		return err
+	}
+	defer conn.rclient.Close()
+
+	var version string
+	if err := conn.rclient.CallContext(ctx, &version, "net_version"); err != nil {
+		return stderr.Wrapf(err, "Failed to request net_version at URL %s", rawurl)
+	}
+
+	return nil
+}
+
 // Report returns a failed Client connection. In this
 // case, the pool we create a new Client connection on the
 // next DialContext
 func (p *UniDialer) Report(ctx context.Context, conn *Conn) error {
-	c := make(chan returnResponse)
-	p.req <- returnRequest{C: c, Conn: conn}
-	res := <-c
-	return res.Error
+	c := make(chan returnResponse, 1)
+	if err := p.send(ctx, returnRequest{C: c, Conn: conn}); err != nil {
+		return err
+	}
+
+	select {
+	case res := <-c:
+		return res.Error
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// send passes a request to the loop of the dialer. It fails if the
+// context of the request is done or the dialer has stopped before
+// the loop receives it
+func (p *UniDialer) send(ctx context.Context, req interface{}) error {
+	select {
+	case p.req <- req:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-p.ctx.Done():
+		return stderr.New("dialer is stopped")
+	}
+}
+
+// Stats is the implementation of stats.Collector for UniDialer
+func (p *UniDialer) Stats() stats.Metrics {
+	return p.metrics.Stats()
 }
 
 // DialContext implementation of Dialer for FixedDialer
 func (p *UniDialer) Conn(ctx context.Context) (*Conn, error) {
-	c := make(chan dialResponse)
-	p.req <- dialRequest{Context: ctx, C: c}
-	res := <-c
-	return res.Conn, res.Error
+	c := make(chan dialResponse, 1)
+	if err := p.send(ctx, dialRequest{Context: ctx, C: c}); err != nil {
+		return nil, err
+	}
+
+	select {
+	case res := <-c:
+		return res.Conn, res.Error
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
 }
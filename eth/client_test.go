@@ -2,6 +2,7 @@ package eth
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"math/big"
 	"testing"
@@ -14,6 +15,7 @@ import (
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/oasislabs/oasis-gateway/concurrent"
+	stderr "github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -55,6 +57,15 @@ func (c *mockEthClient) BalanceAt(ctx context.Context, address common.Address, b
 	return args.Get(0).(*big.Int), nil
 }
 
+func (c *mockEthClient) CallContract(ctx context.Context, msg ethereum.CallMsg, block *big.Int) ([]byte, error) {
+	args := c.Called(ctx, msg, block)
+	if args.Get(1) != nil {
+		return nil, args.Error(1)
+	}
+
+	return args.Get(0).([]byte), nil
+}
+
 func (c *mockEthClient) CodeAt(ctx context.Context, address common.Address, block *big.Int) ([]byte, error) {
 	args := c.Called(ctx, address, block)
 	if args.Get(1) != nil {
@@ -100,6 +111,15 @@ func (c *mockEthClient) SubscribeFilterLogs(ctx context.Context, q ethereum.Filt
 	return args.Get(0).(ethereum.Subscription), nil
 }
 
+func (c *mockEthClient) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	args := c.Called(ctx, q)
+	if args.Get(1) != nil {
+		return nil, args.Error(1)
+	}
+
+	return args.Get(0).([]types.Log), nil
+}
+
 func (c *mockEthClient) Close() {
 	c.Called()
 }
@@ -191,6 +211,95 @@ func TestPooledClientSendTransactionStatusErr(t *testing.T) {
 	}, res)
 }
 
+func TestPooledClientSendTransactionWithExpiryOK(t *testing.T) {
+	pool := mockPool{conn: &Conn{eclient: &mockEthClient{}, rclient: &mockRpcClient{}}}
+	c := NewPooledClient(PooledClientProps{
+		Pool:        pool,
+		RetryConfig: TestRetryConfig,
+	})
+
+	tx, err := getSignedTransaction()
+	assert.Nil(t, err)
+	data, err := rlp.EncodeToBytes(tx)
+	assert.Nil(t, err)
+
+	pool.conn.rclient.(*mockRpcClient).
+		On("CallContext", mock.Anything, mock.Anything, "oasis_invoke",
+			[]interface{}{hexutil.Encode(data), hexutil.Uint64(1024)}).
+		Run(func(args mock.Arguments) {
+			res := args[1].(*sendTransactionResponseDeserialize)
+			res.Hash = tx.Hash().Hex()
+			res.Output = "0x00"
+			res.Status = "0x1"
+		}).
+		Return(nil)
+
+	res, err := c.SendTransactionWithExpiry(context.Background(), tx, 1024)
+	assert.Nil(t, err)
+	assert.Equal(t, SendTransactionResponse{
+		Output: "0x00",
+		Status: 1,
+		Hash:   tx.Hash().Hex(),
+	}, res)
+}
+
+func TestPooledClientSendDynamicFeeTransactionOK(t *testing.T) {
+	pool := mockPool{conn: &Conn{eclient: &mockEthClient{}, rclient: &mockRpcClient{}}}
+	c := NewPooledClient(PooledClientProps{
+		Pool:        pool,
+		RetryConfig: TestRetryConfig,
+	})
+
+	pk, err := crypto.HexToECDSA(privateKey)
+	assert.Nil(t, err)
+	tx := NewDynamicFeeTransaction(big.NewInt(1), 0, &common.Address{}, 1024,
+		big.NewInt(1), big.NewInt(2), []byte("0x00"))
+	hash := tx.SigningHash()
+	sig, err := crypto.Sign(hash[:], pk)
+	assert.Nil(t, err)
+	tx, err = tx.WithSignature(sig)
+	assert.Nil(t, err)
+	data, err := tx.MarshalBinary()
+	assert.Nil(t, err)
+
+	pool.conn.rclient.(*mockRpcClient).
+		On("CallContext", mock.Anything, mock.Anything, "oasis_invoke", []interface{}{hexutil.Encode(data)}).
+		Run(func(args mock.Arguments) {
+			res := args[1].(*sendTransactionResponseDeserialize)
+			res.Hash = tx.Hash().Hex()
+			res.Output = "0x00"
+			res.Status = "0x1"
+		}).
+		Return(nil)
+
+	res, err := c.SendDynamicFeeTransaction(context.Background(), tx)
+	assert.Nil(t, err)
+	assert.Equal(t, SendTransactionResponse{
+		Output: "0x00",
+		Status: 1,
+		Hash:   tx.Hash().Hex(),
+	}, res)
+}
+
+func TestPooledClientSendTransactionExpiredErr(t *testing.T) {
+	pool := mockPool{conn: &Conn{eclient: &mockEthClient{}, rclient: &mockRpcClient{}}}
+	c := NewPooledClient(PooledClientProps{
+		Pool:        pool,
+		RetryConfig: TestRetryConfig,
+	})
+
+	tx, err := getSignedTransaction()
+	assert.Nil(t, err)
+
+	pool.conn.rclient.(*mockRpcClient).
+		On("CallContext", mock.Anything, mock.Anything, "oasis_invoke", mock.Anything).
+		Return(errors.New("Transaction expired"))
+
+	_, err = c.SendTransactionWithExpiry(context.Background(), tx, 1024)
+	assert.Error(t, err)
+	assert.True(t, stderr.Is(err, ErrTransactionExpired))
+}
+
 func TestPooledClientSendTransactionCallErr(t *testing.T) {
 	pool := mockPool{conn: &Conn{eclient: &mockEthClient{}, rclient: &mockRpcClient{}}}
 	c := NewPooledClient(PooledClientProps{
@@ -209,3 +318,127 @@ func TestPooledClientSendTransactionCallErr(t *testing.T) {
 	assert.Error(t, err)
 	assert.Equal(t, "maximum number of attempts 10 reached; see cause for last error: error", err.Error())
 }
+
+func TestPooledClientChainHeadSyncing(t *testing.T) {
+	pool := mockPool{conn: &Conn{eclient: &mockEthClient{}, rclient: &mockRpcClient{}}}
+	c := NewPooledClient(PooledClientProps{
+		Pool:        pool,
+		RetryConfig: TestRetryConfig,
+	})
+
+	rclient := pool.conn.rclient.(*mockRpcClient)
+	rclient.On("CallContext", mock.Anything, mock.Anything, "eth_getBlockByNumber", []interface{}{"latest", false}).
+		Run(func(args mock.Arguments) {
+			*args[1].(**blockHeader) = &blockHeader{Number: 16, Timestamp: 1024}
+		}).
+		Return(nil)
+	rclient.On("CallContext", mock.Anything, mock.Anything, "eth_chainId", mock.Anything).
+		Run(func(args mock.Arguments) {
+			*args[1].(*hexutil.Uint64) = 42261
+		}).
+		Return(nil)
+	rclient.On("CallContext", mock.Anything, mock.Anything, "eth_syncing", mock.Anything).
+		Run(func(args mock.Arguments) {
+			*args[1].(*json.RawMessage) = json.RawMessage(`{"currentBlock":"0x10","highestBlock":"0x20"}`)
+		}).
+		Return(nil)
+
+	head, err := c.ChainHead(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, ChainHead{
+		BlockNumber:  16,
+		Timestamp:    1024,
+		ChainID:      42261,
+		Syncing:      true,
+		HighestBlock: 32,
+	}, head)
+}
+
+func TestPooledClientChainHeadNotSyncing(t *testing.T) {
+	pool := mockPool{conn: &Conn{eclient: &mockEthClient{}, rclient: &mockRpcClient{}}}
+	c := NewPooledClient(PooledClientProps{
+		Pool:        pool,
+		RetryConfig: TestRetryConfig,
+	})
+
+	rclient := pool.conn.rclient.(*mockRpcClient)
+	rclient.On("CallContext", mock.Anything, mock.Anything, "eth_getBlockByNumber", []interface{}{"latest", false}).
+		Run(func(args mock.Arguments) {
+			*args[1].(**blockHeader) = &blockHeader{Number: 16, Timestamp: 1024}
+		}).
+		Return(nil)
+	rclient.On("CallContext", mock.Anything, mock.Anything, "eth_chainId", mock.Anything).
+		Return(methodNotFoundError{})
+	rclient.On("CallContext", mock.Anything, mock.Anything, "eth_syncing", mock.Anything).
+		Run(func(args mock.Arguments) {
+			*args[1].(*json.RawMessage) = json.RawMessage(`false`)
+		}).
+		Return(nil)
+
+	head, err := c.ChainHead(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, ChainHead{BlockNumber: 16, Timestamp: 1024}, head)
+}
+
+func TestPooledClientTransactionBlockNumber(t *testing.T) {
+	pool := mockPool{conn: &Conn{eclient: &mockEthClient{}, rclient: &mockRpcClient{}}}
+	c := NewPooledClient(PooledClientProps{
+		Pool:        pool,
+		RetryConfig: TestRetryConfig,
+	})
+
+	rclient := pool.conn.rclient.(*mockRpcClient)
+	rclient.On("CallContext", mock.Anything, mock.Anything, "eth_getTransactionByHash", mock.Anything).
+		Run(func(args mock.Arguments) {
+			*args[1].(**transactionBlock) = &transactionBlock{BlockNumber: (*hexutil.Big)(big.NewInt(16))}
+		}).
+		Return(nil)
+
+	number, err := c.TransactionBlockNumber(context.Background(), common.Hash{})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(16), number.Int64())
+}
+
+func TestPooledClientBlockGasPrices(t *testing.T) {
+	pool := mockPool{conn: &Conn{eclient: &mockEthClient{}, rclient: &mockRpcClient{}}}
+	c := NewPooledClient(PooledClientProps{
+		Pool:        pool,
+		RetryConfig: TestRetryConfig,
+	})
+
+	rclient := pool.conn.rclient.(*mockRpcClient)
+	rclient.On("CallContext", mock.Anything, mock.Anything, "eth_getBlockByNumber", []interface{}{"0x10", true}).
+		Run(func(args mock.Arguments) {
+			block := &blockTransactions{}
+			err := json.Unmarshal([]byte(`{"number":"0x10","transactions":[{"gasPrice":"0x3b9aca00"},{"gasPrice":"0x1"}]}`), block)
+			assert.Nil(t, err)
+			*args[1].(**blockTransactions) = block
+		}).
+		Return(nil)
+
+	prices, err := c.BlockGasPrices(context.Background(), big.NewInt(16))
+	assert.Nil(t, err)
+	assert.Equal(t, BlockGasPrices{
+		BlockNumber: 16,
+		GasPrices:   []*big.Int{big.NewInt(1000000000), big.NewInt(1)},
+	}, prices)
+}
+
+func TestPooledClientSuggestGasPrice(t *testing.T) {
+	pool := mockPool{conn: &Conn{eclient: &mockEthClient{}, rclient: &mockRpcClient{}}}
+	c := NewPooledClient(PooledClientProps{
+		Pool:        pool,
+		RetryConfig: TestRetryConfig,
+	})
+
+	rclient := pool.conn.rclient.(*mockRpcClient)
+	rclient.On("CallContext", mock.Anything, mock.Anything, "eth_gasPrice", mock.Anything).
+		Run(func(args mock.Arguments) {
+			*args[1].(*hexutil.Big) = hexutil.Big(*big.NewInt(42))
+		}).
+		Return(nil)
+
+	price, err := c.SuggestGasPrice(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, big.NewInt(42), price)
+}
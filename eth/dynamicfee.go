@@ -0,0 +1,168 @@
+package eth
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	stderr "github.com/pkg/errors"
+)
+
+// DynamicFeeTxType is the EIP-2718 type of the EIP-1559
+// dynamic fee transactions
+const DynamicFeeTxType = 0x02
+
+// signatureLength is the length of a signature in the
+// [R || S || V] format returned by crypto.Sign
+const signatureLength = 65
+
+// Transaction is a signed transaction that can be submitted to the
+// node. It is either a legacy *types.Transaction or a
+// *DynamicFeeTransaction
+type Transaction interface {
+	Hash() common.Hash
+	Nonce() uint64
+	Gas() uint64
+}
+
+// DynamicFeeTransaction is an EIP-1559 dynamic fee transaction. The
+// version of go-ethereum the gateway depends on predates typed
+// transactions, so the envelope is encoded here. The gateway does
+// not use access lists, so the access list is always empty
+type DynamicFeeTransaction struct {
+	chainID   *big.Int
+	nonce     uint64
+	gasTipCap *big.Int
+	gasFeeCap *big.Int
+	gas       uint64
+	to        *common.Address
+	value     *big.Int
+	data      []byte
+
+	// v, r and s are the signature of the transaction. They are
+	// nil until the transaction is signed
+	v, r, s *big.Int
+}
+
+// NewDynamicFeeTransaction creates a new unsigned dynamic fee
+// transaction. If to is nil the transaction deploys a contract
+func NewDynamicFeeTransaction(
+	chainID *big.Int,
+	nonce uint64,
+	to *common.Address,
+	gas uint64,
+	gasTipCap *big.Int,
+	gasFeeCap *big.Int,
+	data []byte,
+) *DynamicFeeTransaction {
+	return &DynamicFeeTransaction{
+		chainID:   new(big.Int).Set(chainID),
+		nonce:     nonce,
+		gasTipCap: new(big.Int).Set(gasTipCap),
+		gasFeeCap: new(big.Int).Set(gasFeeCap),
+		gas:       gas,
+		to:        to,
+		value:     big.NewInt(0),
+		data:      common.CopyBytes(data),
+	}
+}
+
+// ChainID returns the chain the transaction is signed for
+func (tx *DynamicFeeTransaction) ChainID() *big.Int { return new(big.Int).Set(tx.chainID) }
+
+// Nonce returns the nonce of the transaction
+func (tx *DynamicFeeTransaction) Nonce() uint64 { return tx.nonce }
+
+// GasTipCap returns the maxPriorityFeePerGas of the transaction
+func (tx *DynamicFeeTransaction) GasTipCap() *big.Int { return new(big.Int).Set(tx.gasTipCap) }
+
+// GasFeeCap returns the maxFeePerGas of the transaction
+func (tx *DynamicFeeTransaction) GasFeeCap() *big.Int { return new(big.Int).Set(tx.gasFeeCap) }
+
+// Gas returns the gas limit of the transaction
+func (tx *DynamicFeeTransaction) Gas() uint64 { return tx.gas }
+
+// To returns the recipient of the transaction, or nil
+// for a contract deployment
+func (tx *DynamicFeeTransaction) To() *common.Address { return tx.to }
+
+// Data returns the payload of the transaction
+func (tx *DynamicFeeTransaction) Data() []byte { return common.CopyBytes(tx.data) }
+
+// fields returns the fields of the transaction covered by its
+// signature in the order in which they are encoded
+func (tx *DynamicFeeTransaction) fields() []interface{} {
+	var to []byte
+	if tx.to != nil {
+		to = tx.to.Bytes()
+	}
+
+	return []interface{}{
+		tx.chainID,
+		tx.nonce,
+		tx.gasTipCap,
+		tx.gasFeeCap,
+		tx.gas,
+		to,
+		tx.value,
+		tx.data,
+		[]interface{}{},
+	}
+}
+
+// encodeTyped returns the type of the transaction followed by
+// the RLP encoding of the fields
+func encodeTyped(fields []interface{}) ([]byte, error) {
+	p, err := rlp.EncodeToBytes(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte{DynamicFeeTxType}, p...), nil
+}
+
+// SigningHash returns the hash signed by the sender of the transaction
+func (tx *DynamicFeeTransaction) SigningHash() common.Hash {
+	p, err := encodeTyped(tx.fields())
+	if err != nil {
+		// the fields are all of types that rlp can encode
+		panic(err)
+	}
+
+	return crypto.Keccak256Hash(p)
+}
+
+// WithSignature returns a copy of the transaction with the signature
+// set. The signature is in the [R || S || V] format returned by
+// crypto.Sign, where V is the y parity of the signature
+func (tx *DynamicFeeTransaction) WithSignature(sig []byte) (*DynamicFeeTransaction, error) {
+	if len(sig) != signatureLength {
+		return nil, stderr.Errorf("wrong size for signature: got %d, want %d", len(sig), signatureLength)
+	}
+
+	signed := *tx
+	signed.r = new(big.Int).SetBytes(sig[:32])
+	signed.s = new(big.Int).SetBytes(sig[32:64])
+	signed.v = new(big.Int).SetBytes([]byte{sig[64]})
+	return &signed, nil
+}
+
+// MarshalBinary returns the EIP-2718 envelope of the signed transaction
+func (tx *DynamicFeeTransaction) MarshalBinary() ([]byte, error) {
+	if tx.v == nil {
+		return nil, stderr.New("transaction is not signed")
+	}
+
+	return encodeTyped(append(tx.fields(), tx.v, tx.r, tx.s))
+}
+
+// Hash returns the hash of the signed transaction
+func (tx *DynamicFeeTransaction) Hash() common.Hash {
+	p, err := tx.MarshalBinary()
+	if err != nil {
+		return common.Hash{}
+	}
+
+	return crypto.Keccak256Hash(p)
+}
@@ -0,0 +1,84 @@
+package eth
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/stretchr/testify/assert"
+)
+
+// signedDynamicFeeTransaction mirrors the fields of the
+// envelope of a signed dynamic fee transaction
+type signedDynamicFeeTransaction struct {
+	ChainID    *big.Int
+	Nonce      uint64
+	GasTipCap  *big.Int
+	GasFeeCap  *big.Int
+	Gas        uint64
+	To         []byte
+	Value      *big.Int
+	Data       []byte
+	AccessList []interface{}
+	V, R, S    *big.Int
+}
+
+func TestDynamicFeeTransactionSigningPayload(t *testing.T) {
+	tx := NewDynamicFeeTransaction(big.NewInt(1), 0, nil, 21000, big.NewInt(1), big.NewInt(2), nil)
+
+	// 0x02 || rlp([1, 0, 1, 2, 21000, "", 0, "", []])
+	payload := []byte{0x02, 0xcb, 0x01, 0x80, 0x01, 0x02, 0x82, 0x52, 0x08, 0x80, 0x80, 0x80, 0xc0}
+	assert.Equal(t, crypto.Keccak256Hash(payload), tx.SigningHash())
+
+	_, err := tx.MarshalBinary()
+	assert.Error(t, err)
+}
+
+func TestDynamicFeeTransactionSigned(t *testing.T) {
+	pk, err := crypto.HexToECDSA(privateKey)
+	assert.Nil(t, err)
+
+	to := common.HexToAddress("0x6f6704e5a10332af6672e50b3d9754dc460dfa4d")
+	tx := NewDynamicFeeTransaction(big.NewInt(42261), 7, &to, 1024,
+		big.NewInt(1000000000), big.NewInt(2000000000), []byte("data"))
+
+	hash := tx.SigningHash()
+	sig, err := crypto.Sign(hash[:], pk)
+	assert.Nil(t, err)
+	tx, err = tx.WithSignature(sig)
+	assert.Nil(t, err)
+
+	data, err := tx.MarshalBinary()
+	assert.Nil(t, err)
+	assert.Equal(t, byte(DynamicFeeTxType), data[0])
+	assert.Equal(t, crypto.Keccak256Hash(data), tx.Hash())
+
+	var decoded signedDynamicFeeTransaction
+	assert.Nil(t, rlp.DecodeBytes(data[1:], &decoded))
+	assert.Equal(t, big.NewInt(42261), decoded.ChainID)
+	assert.Equal(t, uint64(7), decoded.Nonce)
+	assert.Equal(t, big.NewInt(1000000000), decoded.GasTipCap)
+	assert.Equal(t, big.NewInt(2000000000), decoded.GasFeeCap)
+	assert.Equal(t, uint64(1024), decoded.Gas)
+	assert.Equal(t, to.Bytes(), decoded.To)
+	assert.Equal(t, []byte("data"), decoded.Data)
+	assert.Empty(t, decoded.AccessList)
+
+	// the signature recovers the sender from the signing hash
+	recovered := make([]byte, 65)
+	copy(recovered[32-len(decoded.R.Bytes()):32], decoded.R.Bytes())
+	copy(recovered[64-len(decoded.S.Bytes()):64], decoded.S.Bytes())
+	recovered[64] = byte(decoded.V.Uint64())
+	pub, err := crypto.SigToPub(hash[:], recovered)
+	assert.Nil(t, err)
+	assert.Equal(t, crypto.PubkeyToAddress(pk.PublicKey), crypto.PubkeyToAddress(*pub))
+}
+
+func TestDynamicFeeTransactionWrongSignature(t *testing.T) {
+	tx := NewDynamicFeeTransaction(big.NewInt(1), 0, nil, 21000, big.NewInt(1), big.NewInt(2), nil)
+
+	_, err := tx.WithSignature(make([]byte, 64))
+	assert.Error(t, err)
+}
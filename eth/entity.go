@@ -1,5 +1,12 @@
 package eth
 
+import (
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
 type PublicKey struct {
 	Timestamp uint64 `json:"timestamp"`
 	PublicKey string `json:"public_key"`
@@ -17,3 +24,103 @@ type sendTransactionResponseDeserialize struct {
 	Status string `json:"status"`
 	Hash   string `json:"transactionHash"`
 }
+
+// TransactionTrace is the subset of the result of a debug_traceTransaction
+// call that the gateway makes use of
+type TransactionTrace struct {
+	Failed      bool        `json:"failed"`
+	Gas         uint64      `json:"gas"`
+	ReturnValue string      `json:"returnValue"`
+	StructLogs  []StructLog `json:"structLogs"`
+}
+
+// StructLog is a step of the execution of a traced transaction. The
+// gateway traces transactions without their stack, memory and storage
+type StructLog struct {
+	Pc      uint64 `json:"pc"`
+	Op      string `json:"op"`
+	Gas     uint64 `json:"gas"`
+	GasCost uint64 `json:"gasCost"`
+	Depth   int    `json:"depth"`
+	Error   string `json:"error,omitempty"`
+}
+
+// ChainHead is the view the node has of the chain
+type ChainHead struct {
+	// BlockNumber is the number of the latest block known to the node
+	BlockNumber uint64
+
+	// Timestamp is the time in seconds at which the latest block
+	// known to the node was produced
+	Timestamp uint64
+
+	// ChainID is the identifier of the chain. It is 0 if the node
+	// does not report it
+	ChainID uint64
+
+	// Syncing is true if the node is still catching up with the chain
+	Syncing bool
+
+	// HighestBlock is the number of the highest block the node is
+	// aware of while it is syncing. It is 0 if the node is not syncing
+	HighestBlock uint64
+}
+
+// BlockGasPrices are the gas prices of the transactions
+// included in a block
+type BlockGasPrices struct {
+	// BlockNumber is the number of the block
+	BlockNumber uint64
+
+	// GasPrices are the gas prices of the transactions in the block
+	GasPrices []*big.Int
+}
+
+// blockHeader is the subset of the result of a eth_getBlockByNumber
+// call that the gateway makes use of
+type blockHeader struct {
+	Number    hexutil.Uint64 `json:"number"`
+	Timestamp hexutil.Uint64 `json:"timestamp"`
+}
+
+// blockBaseFee is the base fee of the result of a eth_getBlockByNumber
+// call, which is only set on chains with EIP-1559 dynamic fees
+type blockBaseFee struct {
+	BaseFee *hexutil.Big `json:"baseFeePerGas"`
+}
+
+// blockTransactions is the subset of the result of a
+// eth_getBlockByNumber call with the full transactions that
+// the gateway makes use of
+type blockTransactions struct {
+	Number       hexutil.Uint64 `json:"number"`
+	Transactions []struct {
+		GasPrice *hexutil.Big `json:"gasPrice"`
+	} `json:"transactions"`
+}
+
+// transactionBlock is the subset of the result of a
+// eth_getTransactionByHash call that the gateway makes use of
+type transactionBlock struct {
+	BlockNumber *hexutil.Big `json:"blockNumber"`
+}
+
+// syncProgress is the result of a eth_syncing call when the node
+// is syncing
+type syncProgress struct {
+	CurrentBlock hexutil.Uint64 `json:"currentBlock"`
+	HighestBlock hexutil.Uint64 `json:"highestBlock"`
+}
+
+// Reconnect describes a connection to the node that was recreated
+// after it failed. The events that happened on the node between
+// DisconnectedAt and ReconnectedAt may not have been observed
+type Reconnect struct {
+	// DisconnectedAt is the time at which the connection was
+	// reported as failed
+	DisconnectedAt time.Time
+
+	// ReconnectedAt is the time at which the connection
+	// was recreated
+	ReconnectedAt time.Time
+}
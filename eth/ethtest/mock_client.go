@@ -8,6 +8,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/oasislabs/oasis-gateway/eth"
+	"github.com/oasislabs/oasis-gateway/tests/scenario"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -20,6 +21,10 @@ type MockMethod struct {
 type MockMethods map[string]MockMethod
 
 var DefaultMockMethods = map[string]MockMethod{
+	"CallContract": {
+		Arguments: []interface{}{mock.Anything, mock.Anything, mock.Anything},
+		Return:    []interface{}{[]byte("success"), nil},
+	},
 	"EstimateGas": {
 		Arguments: []interface{}{mock.Anything, mock.Anything},
 		Return:    []interface{}{uint64(0), nil},
@@ -32,9 +37,21 @@ var DefaultMockMethods = map[string]MockMethod{
 		Arguments: []interface{}{mock.Anything, mock.Anything},
 		Return:    []interface{}{"0x0000000000000000000000000000000000000000", nil},
 	},
+	"ChainHead": {
+		Arguments: []interface{}{mock.Anything},
+		Return:    []interface{}{eth.ChainHead{BlockNumber: 1, Timestamp: 1, ChainID: 42261}, nil},
+	},
 	"BalanceAt": {
 		Arguments: []interface{}{mock.Anything, mock.Anything, mock.Anything},
-		Return:    []interface{}{big.NewInt(1), nil},
+		Return:    []interface{}{big.NewInt(1000000000000000000), nil},
+	},
+	"SuggestGasPrice": {
+		Arguments: []interface{}{mock.Anything},
+		Return:    []interface{}{big.NewInt(1000000000), nil},
+	},
+	"BlockGasPrices": {
+		Arguments: []interface{}{mock.Anything, mock.Anything},
+		Return:    []interface{}{eth.BlockGasPrices{BlockNumber: 1}, nil},
 	},
 	"TransactionReceipt": {
 		Arguments: []interface{}{mock.Anything, mock.Anything},
@@ -45,6 +62,19 @@ var DefaultMockMethods = map[string]MockMethod{
 			}, nil,
 		},
 	},
+	"TransactionBlockNumber": {
+		Arguments: []interface{}{mock.Anything, mock.Anything},
+		Return:    []interface{}{big.NewInt(10), nil},
+	},
+	"TraceTransaction": {
+		Arguments: []interface{}{mock.Anything, mock.Anything},
+		Return: []interface{}{
+			eth.TransactionTrace{
+				Failed:      false,
+				ReturnValue: "73756363657373",
+			}, nil,
+		},
+	},
 	"GetExpiry": {
 		Arguments: []interface{}{mock.Anything, mock.Anything},
 		Return:    []interface{}{uint64(123456789), nil},
@@ -69,6 +99,26 @@ var DefaultMockMethods = map[string]MockMethod{
 			}, nil,
 		},
 	},
+	"SendTransactionWithExpiry": {
+		Arguments: []interface{}{mock.Anything, mock.Anything, mock.Anything},
+		Return: []interface{}{
+			eth.SendTransactionResponse{
+				Status: 1,
+				Output: "0x73756363657373",
+				Hash:   "0x00000000000000000000000000000000000000000000000000000000000000000",
+			}, nil,
+		},
+	},
+	"SendDynamicFeeTransaction": {
+		Arguments: []interface{}{mock.Anything, mock.Anything},
+		Return: []interface{}{
+			eth.SendTransactionResponse{
+				Status: 1,
+				Output: "0x73756363657373",
+				Hash:   "0x00000000000000000000000000000000000000000000000000000000000000000",
+			}, nil,
+		},
+	},
 	"SubscribeFilterLogs": {
 		Arguments: []interface{}{mock.Anything, mock.Anything, mock.Anything},
 		Return: []interface{}{
@@ -111,8 +161,19 @@ func ImplementMock(client *MockClient) {
 	ImplementMockWithMethods(client, DefaultMockMethods)
 }
 
+// ImplementMockWithScenario implements the mock with the default
+// methods and plays the scenario on its calls
+func ImplementMockWithScenario(client *MockClient, player *scenario.Player) {
+	ImplementMock(client)
+	client.Scenario = player
+}
+
+// MockClient is a mock of eth.Client. If Scenario is set, each
+// call first plays the scenario and fails with the error it
+// scripts, if any, before the mocked method is called
 type MockClient struct {
 	mock.Mock
+	Scenario *scenario.Player
 }
 
 func (c *MockClient) BalanceAt(
@@ -120,6 +181,10 @@ func (c *MockClient) BalanceAt(
 	address common.Address,
 	block *big.Int,
 ) (*big.Int, error) {
+	if err := c.Scenario.Play(ctx, "BalanceAt"); err != nil {
+		return nil, err
+	}
+
 	args := c.Called(ctx, address, block)
 	if args.Get(1) != nil {
 		return nil, args.Error(1)
@@ -128,10 +193,31 @@ func (c *MockClient) BalanceAt(
 	return args.Get(0).(*big.Int), nil
 }
 
+func (m *MockClient) CallContract(
+	ctx context.Context,
+	msg ethereum.CallMsg,
+	block *big.Int,
+) ([]byte, error) {
+	if err := m.Scenario.Play(ctx, "CallContract"); err != nil {
+		return nil, err
+	}
+
+	args := m.Called(ctx, msg, block)
+	if args.Get(1) != nil {
+		return nil, args.Error(1)
+	}
+
+	return args.Get(0).([]byte), nil
+}
+
 func (m *MockClient) EstimateGas(
 	ctx context.Context,
 	msg ethereum.CallMsg,
 ) (uint64, error) {
+	if err := m.Scenario.Play(ctx, "EstimateGas"); err != nil {
+		return 0, err
+	}
+
 	args := m.Called(ctx, msg)
 	return args.Get(0).(uint64), args.Error(1)
 }
@@ -140,6 +226,10 @@ func (m *MockClient) GetExpiry(
 	ctx context.Context,
 	addr common.Address,
 ) (uint64, error) {
+	if err := m.Scenario.Play(ctx, "GetExpiry"); err != nil {
+		return 0, err
+	}
+
 	args := m.Called(ctx, addr)
 	return args.Get(0).(uint64), args.Error(1)
 }
@@ -148,6 +238,10 @@ func (m *MockClient) GetPublicKey(
 	ctx context.Context,
 	addr common.Address,
 ) (eth.PublicKey, error) {
+	if err := m.Scenario.Play(ctx, "GetPublicKey"); err != nil {
+		return eth.PublicKey{}, err
+	}
+
 	args := m.Called(ctx, addr)
 	return args.Get(0).(eth.PublicKey), args.Error(1)
 }
@@ -156,6 +250,10 @@ func (m *MockClient) NonceAt(
 	ctx context.Context,
 	addr common.Address,
 ) (uint64, error) {
+	if err := m.Scenario.Play(ctx, "NonceAt"); err != nil {
+		return 0, err
+	}
+
 	args := m.Called(ctx, addr)
 	return args.Get(0).(uint64), args.Error(1)
 }
@@ -164,14 +262,81 @@ func (m *MockClient) GetCode(
 	ctx context.Context,
 	addr common.Address,
 ) (string, error) {
+	if err := m.Scenario.Play(ctx, "GetCode"); err != nil {
+		return "", err
+	}
+
 	args := m.Called(ctx, addr)
 	return args.Get(0).(string), args.Error(1)
 }
 
+func (m *MockClient) ChainHead(ctx context.Context) (eth.ChainHead, error) {
+	if err := m.Scenario.Play(ctx, "ChainHead"); err != nil {
+		return eth.ChainHead{}, err
+	}
+
+	args := m.Called(ctx)
+	return args.Get(0).(eth.ChainHead), args.Error(1)
+}
+
+func (m *MockClient) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	if err := m.Scenario.Play(ctx, "SuggestGasPrice"); err != nil {
+		return nil, err
+	}
+
+	args := m.Called(ctx)
+	if args.Get(1) != nil {
+		return nil, args.Error(1)
+	}
+
+	return args.Get(0).(*big.Int), nil
+}
+
+func (m *MockClient) BlockGasPrices(
+	ctx context.Context,
+	blockNumber *big.Int,
+) (eth.BlockGasPrices, error) {
+	if err := m.Scenario.Play(ctx, "BlockGasPrices"); err != nil {
+		return eth.BlockGasPrices{}, err
+	}
+
+	args := m.Called(ctx, blockNumber)
+	return args.Get(0).(eth.BlockGasPrices), args.Error(1)
+}
+
 func (m *MockClient) SendTransaction(
 	ctx context.Context,
 	tx *types.Transaction,
 ) (eth.SendTransactionResponse, error) {
+	if err := m.Scenario.Play(ctx, "SendTransaction"); err != nil {
+		return eth.SendTransactionResponse{}, err
+	}
+
+	args := m.Called(ctx, tx)
+	return args.Get(0).(eth.SendTransactionResponse), args.Error(1)
+}
+
+func (m *MockClient) SendTransactionWithExpiry(
+	ctx context.Context,
+	tx *types.Transaction,
+	expiry uint64,
+) (eth.SendTransactionResponse, error) {
+	if err := m.Scenario.Play(ctx, "SendTransactionWithExpiry"); err != nil {
+		return eth.SendTransactionResponse{}, err
+	}
+
+	args := m.Called(ctx, tx, expiry)
+	return args.Get(0).(eth.SendTransactionResponse), args.Error(1)
+}
+
+func (m *MockClient) SendDynamicFeeTransaction(
+	ctx context.Context,
+	tx *eth.DynamicFeeTransaction,
+) (eth.SendTransactionResponse, error) {
+	if err := m.Scenario.Play(ctx, "SendDynamicFeeTransaction"); err != nil {
+		return eth.SendTransactionResponse{}, err
+	}
+
 	args := m.Called(ctx, tx)
 	return args.Get(0).(eth.SendTransactionResponse), args.Error(1)
 }
@@ -181,6 +346,10 @@ func (m *MockClient) SubscribeFilterLogs(
 	q ethereum.FilterQuery,
 	c chan<- types.Log,
 ) (ethereum.Subscription, error) {
+	if err := m.Scenario.Play(ctx, "SubscribeFilterLogs"); err != nil {
+		return nil, err
+	}
+
 	args := m.Called(ctx, q, c)
 	if args.Get(1) != nil {
 		return nil, args.Error(1)
@@ -188,7 +357,44 @@ func (m *MockClient) SubscribeFilterLogs(
 	return args.Get(0).(*MockSubscription), nil
 }
 
+func (m *MockClient) FilterLogs(
+	ctx context.Context,
+	q ethereum.FilterQuery,
+) ([]types.Log, error) {
+	if err := m.Scenario.Play(ctx, "FilterLogs"); err != nil {
+		return nil, err
+	}
+
+	args := m.Called(ctx, q)
+	if args.Get(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]types.Log), nil
+}
+
 func (m *MockClient) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	if err := m.Scenario.Play(ctx, "TransactionReceipt"); err != nil {
+		return nil, err
+	}
+
 	args := m.Called(ctx, txHash)
 	return args.Get(0).(*types.Receipt), args.Error(1)
 }
+
+func (m *MockClient) TransactionBlockNumber(ctx context.Context, txHash common.Hash) (*big.Int, error) {
+	if err := m.Scenario.Play(ctx, "TransactionBlockNumber"); err != nil {
+		return nil, err
+	}
+
+	args := m.Called(ctx, txHash)
+	return args.Get(0).(*big.Int), args.Error(1)
+}
+
+func (m *MockClient) TraceTransaction(ctx context.Context, txHash common.Hash) (eth.TransactionTrace, error) {
+	if err := m.Scenario.Play(ctx, "TraceTransaction"); err != nil {
+		return eth.TransactionTrace{}, err
+	}
+
+	args := m.Called(ctx, txHash)
+	return args.Get(0).(eth.TransactionTrace), args.Error(1)
+}
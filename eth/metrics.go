@@ -0,0 +1,153 @@
+package eth
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/oasislabs/oasis-gateway/stats"
+)
+
+// ConnState is the state of the connection to the node
+type ConnState int32
+
+const (
+	// ConnStateDisconnected the dialer does not hold a connection
+	// and will dial one on the next request
+	ConnStateDisconnected ConnState = 0
+
+	// ConnStateConnected the dialer holds a connection that
+	// is used for requests
+	ConnStateConnected ConnState = 1
+)
+
+func (s ConnState) String() string {
+	switch s {
+	case ConnStateConnected:
+		return "connected"
+	default:
+		return "disconnected"
+	}
+}
+
+// connMetrics tracks the state of the connection held
+// by a dialer and how often it needs to be recreated
+type connMetrics struct {
+	state      int32
+	dials      stats.Counter
+	failures   stats.Counter
+	reconnects stats.Counter
+	reports    stats.Counter
+
+	// connectedOnce is only accessed by the loop of the
+	// dialer so it does not need to be synchronized
+	connectedOnce bool
+}
+
+func (m *connMetrics) setState(state ConnState) {
+	atomic.StoreInt32(&m.state, int32(state))
+}
+
+func (m *connMetrics) State() ConnState {
+	return ConnState(atomic.LoadInt32(&m.state))
+}
+
+func (m *connMetrics) dialed(err error) {
+	m.dials.Incr()
+	if err != nil {
+		m.failures.Incr()
+		return
+	}
+
+	if m.connectedOnce {
+		m.reconnects.Incr()
+	}
+	m.connectedOnce = true
+	m.setState(ConnStateConnected)
+}
+
+func (m *connMetrics) reported() {
+	m.reports.Incr()
+	m.setState(ConnStateDisconnected)
+}
+
+func (m *connMetrics) Stats() stats.Metrics {
+	return stats.Metrics{
+		"state":        m.State().String(),
+		"dials":        m.dials.Value(),
+		"dialFailures": m.failures.Value(),
+		"reconnects":   m.reconnects.Value(),
+		"reports":      m.reports.Value(),
+	}
+}
+
+// SubscriptionMetrics tracks the events delivered by the
+// subscriptions and how long they take to be delivered
+type SubscriptionMetrics struct {
+	lock   sync.Mutex
+	lag    *stats.IntWindow
+	active int64
+
+	events          stats.Counter
+	resubscriptions stats.Counter
+	lastBlock       uint64
+}
+
+// NewSubscriptionMetrics creates a new empty SubscriptionMetrics
+func NewSubscriptionMetrics() *SubscriptionMetrics {
+	return &SubscriptionMetrics{lag: stats.NewIntWindow(64)}
+}
+
+// delivered records an event for the block that was received
+// from the node at received and forwarded to the consumer now
+func (m *SubscriptionMetrics) delivered(block uint64, received time.Time) {
+	if m == nil {
+		return
+	}
+
+	lag := time.Since(received)
+	m.events.Incr()
+	atomic.StoreUint64(&m.lastBlock, block)
+
+	m.lock.Lock()
+	m.lag.Add(int64(lag))
+	m.lock.Unlock()
+}
+
+func (m *SubscriptionMetrics) resubscribed() {
+	if m == nil {
+		return
+	}
+
+	m.resubscriptions.Incr()
+}
+
+func (m *SubscriptionMetrics) addActive(delta int64) {
+	if m == nil {
+		return
+	}
+
+	atomic.AddInt64(&m.active, delta)
+}
+
+// Stats is the implementation of stats.Collector for SubscriptionMetrics.
+// The lag is the average time in nanoseconds that the last events
+// waited to be accepted by the consumer after being received from
+// the node
+func (m *SubscriptionMetrics) Stats() stats.Metrics {
+	if m == nil {
+		return nil
+	}
+
+	m.lock.Lock()
+	lag := m.lag.Stats()
+	m.lock.Unlock()
+
+	return stats.Metrics{
+		"active":          atomic.LoadInt64(&m.active),
+		"events":          m.events.Value(),
+		"resubscriptions": m.resubscriptions.Value(),
+		"lastBlock":       atomic.LoadUint64(&m.lastBlock),
+		"lag":             lag,
+	}
+}
@@ -0,0 +1,134 @@
+package eth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/oasislabs/oasis-gateway/stats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestUniDialerStatsReconnect(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dialer := NewUniDialer(ctx, "http://localhost:8545")
+	assert.Equal(t, "disconnected", dialer.Stats()["state"])
+
+	conn, err := dialer.Conn(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, stats.Metrics{
+		"state":        "connected",
+		"dials":        uint64(1),
+		"dialFailures": uint64(0),
+		"reconnects":   uint64(0),
+		"reports":      uint64(0),
+	}, dialer.Stats())
+
+	assert.Nil(t, dialer.Report(ctx, conn))
+	assert.Equal(t, "disconnected", dialer.Stats()["state"])
+
+	_, err = dialer.Conn(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, stats.Metrics{
+		"state":        "connected",
+		"dials":        uint64(2),
+		"dialFailures": uint64(0),
+		"reconnects":   uint64(1),
+		"reports":      uint64(1),
+	}, dialer.Stats())
+}
+
+func TestUniDialerOnReconnect(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	reconnects := make(chan Reconnect, 1)
+	dialer := NewUniDialerWithProps(ctx, UniDialerProps{
+		URL:         "http://localhost:8545",
+		OnReconnect: func(r Reconnect) { reconnects <- r },
+	})
+
+	// the first connection is not a reconnection
+	conn, err := dialer.Conn(ctx)
+	assert.Nil(t, err)
+	assert.Nil(t, dialer.Report(ctx, conn))
+
+	_, err = dialer.Conn(ctx)
+	assert.Nil(t, err)
+
+	select {
+	case r := <-reconnects:
+		assert.False(t, r.DisconnectedAt.IsZero())
+		assert.False(t, r.ReconnectedAt.Before(r.DisconnectedAt))
+	case <-time.After(time.Second):
+		assert.Fail(t, "timed out waiting for reconnection")
+	}
+	assert.Empty(t, reconnects)
+}
+
+func TestUniDialerStatsDialFailure(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dialer := NewUniDialer(ctx, "ftp://localhost:8545")
+	_, err := dialer.Conn(ctx)
+	assert.Error(t, err)
+
+	assert.Equal(t, "disconnected", dialer.Stats()["state"])
+	assert.Equal(t, uint64(1), dialer.Stats()["dialFailures"])
+}
+
+func TestPooledClientStats(t *testing.T) {
+	pool := mockPool{conn: &Conn{eclient: &mockEthClient{}, rclient: &mockRpcClient{}}}
+	c := NewPooledClient(PooledClientProps{
+		Pool:        pool,
+		RetryConfig: TestRetryConfig,
+	})
+
+	rclient := pool.conn.rclient.(*mockRpcClient)
+	rclient.On("CallContext", mock.Anything, mock.Anything, "oasis_getExpiry", mock.Anything).
+		Return(nil).Once()
+	rclient.On("CallContext", mock.Anything, mock.Anything, "oasis_getExpiry", mock.Anything).
+		Return(errors.New("error"))
+
+	_, err := c.GetExpiry(context.Background(), common.Address{})
+	assert.Nil(t, err)
+
+	metrics := c.Stats()
+	_, ok := metrics["connection"]
+	assert.False(t, ok)
+
+	count := metrics["rpc"].(stats.Metrics)["oasis_getExpiry"].(stats.Metrics)["count"]
+	assert.Equal(t, uint64(1), count.(map[string]interface{})["ok"])
+	assert.Equal(t, uint64(0), count.(map[string]interface{})["error"])
+}
+
+func TestSubscriptionMetricsDelivered(t *testing.T) {
+	metrics := NewSubscriptionMetrics()
+
+	metrics.addActive(1)
+	metrics.delivered(10, time.Now())
+	metrics.delivered(12, time.Now())
+	metrics.resubscribed()
+
+	s := metrics.Stats()
+	assert.Equal(t, int64(1), s["active"])
+	assert.Equal(t, uint64(2), s["events"])
+	assert.Equal(t, uint64(1), s["resubscriptions"])
+	assert.Equal(t, uint64(12), s["lastBlock"])
+}
+
+func TestSubscriptionMetricsNil(t *testing.T) {
+	var metrics *SubscriptionMetrics
+
+	metrics.addActive(1)
+	metrics.delivered(10, time.Now())
+	metrics.resubscribed()
+
+	assert.Nil(t, metrics.Stats())
+}
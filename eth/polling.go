@@ -0,0 +1,114 @@
+package eth
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/event"
+)
+
+// LogPollInterval is the interval at which new logs are polled
+// for on connections that do not support subscriptions
+const LogPollInterval = 2 * time.Second
+
+// logFilterer is the subset of the ethclient API required
+// to poll for logs
+type logFilterer interface {
+	FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error)
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+}
+
+// pollingEthClient is an ethClient for http endpoints, which
+// do not support the subscribe API. Log subscriptions are
+// emulated by polling the node for new logs
+type pollingEthClient struct {
+	*ethclient.Client
+	interval time.Duration
+}
+
+// SubscribeFilterLogs implementation of ethClient for pollingEthClient
+func (c *pollingEthClient) SubscribeFilterLogs(
+	ctx context.Context,
+	q ethereum.FilterQuery,
+	ch chan<- types.Log,
+) (ethereum.Subscription, error) {
+	return subscribeFilterLogsPolling(ctx, c.Client, c.interval, q, ch)
+}
+
+// subscribeFilterLogsPolling creates a subscription that polls the node
+// for logs matching the query every interval. If the query does not
+// set FromBlock only logs from blocks after the current one are
+// delivered, which matches the behaviour of a websocket subscription
+func subscribeFilterLogsPolling(
+	ctx context.Context,
+	client logFilterer,
+	interval time.Duration,
+	q ethereum.FilterQuery,
+	ch chan<- types.Log,
+) (ethereum.Subscription, error) {
+	var next uint64
+	if q.FromBlock != nil {
+		next = q.FromBlock.Uint64()
+	} else {
+		header, err := client.HeaderByNumber(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+		next = header.Number.Uint64() + 1
+	}
+
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-quit:
+				return nil
+			case <-ticker.C:
+			}
+
+			header, err := client.HeaderByNumber(ctx, nil)
+			if err != nil {
+				return err
+			}
+
+			head := header.Number.Uint64()
+			if q.ToBlock != nil && q.ToBlock.Uint64() < head {
+				head = q.ToBlock.Uint64()
+			}
+			if head < next {
+				if q.ToBlock != nil && q.ToBlock.Uint64() < next {
+					return nil
+				}
+				continue
+			}
+
+			query := q
+			query.FromBlock = new(big.Int).SetUint64(next)
+			query.ToBlock = new(big.Int).SetUint64(head)
+			logs, err := client.FilterLogs(ctx, query)
+			if err != nil {
+				return err
+			}
+
+			for _, log := range logs {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-quit:
+					return nil
+				case ch <- log:
+				}
+			}
+
+			next = head + 1
+		}
+	}), nil
+}
@@ -0,0 +1,163 @@
+package eth
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeLogFilterer struct {
+	lock    sync.Mutex
+	head    uint64
+	logs    []types.Log
+	err     error
+	queries []ethereum.FilterQuery
+}
+
+func (f *fakeLogFilterer) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &types.Header{Number: new(big.Int).SetUint64(f.head)}, nil
+}
+
+func (f *fakeLogFilterer) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.queries = append(f.queries, q)
+
+	var logs []types.Log
+	for _, log := range f.logs {
+		if log.BlockNumber >= q.FromBlock.Uint64() && log.BlockNumber <= q.ToBlock.Uint64() {
+			logs = append(logs, log)
+		}
+	}
+	return logs, nil
+}
+
+func (f *fakeLogFilterer) setHead(head uint64, logs ...types.Log) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.head = head
+	f.logs = append(f.logs, logs...)
+}
+
+func receiveLog(t *testing.T, ch <-chan types.Log) types.Log {
+	select {
+	case log := <-ch:
+		return log
+	case <-time.After(time.Second):
+		assert.Fail(t, "timed out waiting for log")
+		return types.Log{}
+	}
+}
+
+func TestSubscribeFilterLogsPollingNewLogs(t *testing.T) {
+	client := &fakeLogFilterer{head: 10, logs: []types.Log{{BlockNumber: 10}}}
+	ch := make(chan types.Log, 16)
+
+	sub, err := subscribeFilterLogsPolling(context.Background(), client, time.Millisecond, ethereum.FilterQuery{}, ch)
+	assert.Nil(t, err)
+	defer sub.Unsubscribe()
+
+	client.setHead(12, types.Log{BlockNumber: 11, Index: 0}, types.Log{BlockNumber: 12, Index: 1})
+
+	assert.Equal(t, types.Log{BlockNumber: 11, Index: 0}, receiveLog(t, ch))
+	assert.Equal(t, types.Log{BlockNumber: 12, Index: 1}, receiveLog(t, ch))
+}
+
+func TestSubscribeFilterLogsPollingFromBlock(t *testing.T) {
+	client := &fakeLogFilterer{head: 10, logs: []types.Log{{BlockNumber: 9}, {BlockNumber: 10}}}
+	ch := make(chan types.Log, 16)
+
+	sub, err := subscribeFilterLogsPolling(context.Background(), client, time.Millisecond, ethereum.FilterQuery{
+		FromBlock: big.NewInt(9),
+	}, ch)
+	assert.Nil(t, err)
+	defer sub.Unsubscribe()
+
+	assert.Equal(t, types.Log{BlockNumber: 9}, receiveLog(t, ch))
+	assert.Equal(t, types.Log{BlockNumber: 10}, receiveLog(t, ch))
+}
+
+func TestSubscribeFilterLogsPollingToBlock(t *testing.T) {
+	client := &fakeLogFilterer{head: 20, logs: []types.Log{{BlockNumber: 5}, {BlockNumber: 15}}}
+	ch := make(chan types.Log, 16)
+
+	sub, err := subscribeFilterLogsPolling(context.Background(), client, time.Millisecond, ethereum.FilterQuery{
+		FromBlock: big.NewInt(1),
+		ToBlock:   big.NewInt(10),
+	}, ch)
+	assert.Nil(t, err)
+
+	assert.Equal(t, types.Log{BlockNumber: 5}, receiveLog(t, ch))
+
+	select {
+	case err, ok := <-sub.Err():
+		assert.False(t, ok)
+		assert.Nil(t, err)
+	case <-time.After(time.Second):
+		assert.Fail(t, "subscription did not end")
+	}
+}
+
+func TestSubscribeFilterLogsPollingErr(t *testing.T) {
+	client := &fakeLogFilterer{head: 10}
+	ch := make(chan types.Log, 16)
+
+	sub, err := subscribeFilterLogsPolling(context.Background(), client, time.Millisecond, ethereum.FilterQuery{}, ch)
+	assert.Nil(t, err)
+
+	client.lock.Lock()
+	client.err = errors.New("connection refused")
+	client.lock.Unlock()
+
+	select {
+	case err := <-sub.Err():
+		assert.Equal(t, "connection refused", err.Error())
+	case <-time.After(time.Second):
+		assert.Fail(t, "subscription did not fail")
+	}
+}
+
+func TestSubscribeFilterLogsPollingHeaderErr(t *testing.T) {
+	client := &fakeLogFilterer{err: errors.New("connection refused")}
+
+	_, err := subscribeFilterLogsPolling(context.Background(), client, time.Millisecond, ethereum.FilterQuery{}, make(chan types.Log))
+	assert.Equal(t, "connection refused", err.Error())
+}
+
+func TestDialConnUnsupportedScheme(t *testing.T) {
+	_, err := dialConn(context.Background(), "ftp://localhost:8545", nil)
+	assert.Equal(t, "Unsupported scheme ftp for URL ftp://localhost:8545", err.Error())
+}
+
+func TestDialConnHTTP(t *testing.T) {
+	conn, err := dialConn(context.Background(), "http://localhost:8545", nil)
+	assert.Nil(t, err)
+	_, ok := conn.eclient.(*pollingEthClient)
+	assert.True(t, ok)
+	conn.rclient.Close()
+}
+
+func TestUniDialerPollInterval(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	dialer := NewUniDialerWithProps(ctx, UniDialerProps{
+		URL:          "http://localhost:8545",
+		PollInterval: time.Millisecond,
+	})
+
+	conn, err := dialer.Conn(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, time.Millisecond, conn.eclient.(*pollingEthClient).interval)
+}
@@ -5,11 +5,13 @@ import (
 	"fmt"
 	"math/big"
 	"sync"
+	"time"
 
 	ethereum "github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/oasislabs/oasis-gateway/concurrent"
 	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/oasislabs/oasis-gateway/stats"
 )
 
 // EthSubscription abstracts an ethereum.Subscription to be
@@ -41,6 +43,14 @@ type LogSubscriber struct {
 	FilterQuery ethereum.FilterQuery
 	BlockNumber uint64
 	Index       uint
+
+	// Backfill if set is the block from which the historical logs
+	// matching FilterQuery are delivered before the live logs. It
+	// is cleared once the historical logs have been delivered
+	Backfill *big.Int
+
+	// Metrics if set tracks the events delivered by the subscriber
+	Metrics *SubscriptionMetrics
 }
 
 func (s *LogSubscriber) createSubscription(
@@ -77,6 +87,18 @@ func (s *LogSubscriber) Subscribe(
 			close(cerr)
 		}()
 
+		// the live subscription is created before the historical logs
+		// are retrieved so that no logs are missed in between. The
+		// logs received by both are delivered only once
+		if err := s.backfill(ctx, client, c); err != nil {
+			sub.Unsubscribe()
+			select {
+			case <-ctx.Done():
+			case cerr <- err:
+			}
+			return
+		}
+
 		for {
 			select {
 			case <-ctx.Done():
@@ -86,6 +108,24 @@ func (s *LogSubscriber) Subscribe(
 					return
 				}
 
+				received := time.Now()
+
+				// a log removed by a reorganization is always forwarded, and
+				// the offsets tracked are moved back to the beginning of its
+				// block so that the logs of the new chain from that block
+				// on are delivered
+				if ev.Removed {
+					s.lock.Lock()
+					if ev.BlockNumber <= s.BlockNumber {
+						s.BlockNumber = ev.BlockNumber
+						s.Index = 0
+					}
+					s.lock.Unlock()
+
+					c <- ev
+					continue
+				}
+
 				// in case events are received that are previous to the offsets
 				// tracked by the subscriber, the events are discarded
 				if ev.BlockNumber < s.BlockNumber ||
@@ -99,6 +139,7 @@ func (s *LogSubscriber) Subscribe(
 				s.lock.Unlock()
 
 				c <- ev
+				s.Metrics.delivered(ev.BlockNumber, received)
 			case err, ok := <-sub.Err():
 				if !ok {
 					return
@@ -113,6 +154,66 @@ func (s *LogSubscriber) Subscribe(
 	return &EthSubscription{sub: sub, err: cerr}, nil
 }
 
+// backfill delivers the historical logs matching the filter
+// query from the Backfill block up to the latest block
+func (s *LogSubscriber) backfill(
+	ctx context.Context,
+	client Client,
+	c chan<- interface{},
+) error {
+	s.lock.Lock()
+	query := s.FilterQuery
+	query.FromBlock = s.Backfill
+	query.ToBlock = nil
+	s.lock.Unlock()
+
+	if query.FromBlock == nil {
+		return nil
+	}
+
+	logs, err := client.FilterLogs(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	var delivered bool
+	for _, ev := range logs {
+		received := time.Now()
+
+		// on a resubscription the logs already delivered before
+		// the subscription failed are discarded
+		if ev.BlockNumber < s.BlockNumber ||
+			(ev.BlockNumber == s.BlockNumber && ev.Index < s.Index) {
+			continue
+		}
+
+		s.lock.Lock()
+		s.BlockNumber = ev.BlockNumber
+		s.Index = ev.Index
+		s.lock.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case c <- ev:
+		}
+
+		delivered = true
+		s.Metrics.delivered(ev.BlockNumber, received)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	// the last historical log is also received by the live
+	// subscription, so it is skipped there
+	if delivered {
+		s.Index++
+	}
+	s.Backfill = nil
+	return nil
+}
+
 // Subscriber is an interface for types that creates subscriptions
 // against an ethereum-like backend
 type Subscriber interface {
@@ -141,8 +242,8 @@ type SubscriptionProps struct {
 	// Client to make requests
 	Client Client
 
-	// URL to dial to for the subscription. Must be a ws URL since
-	// other provides may not support creating subscriptions
+	// URL to dial to for the subscription. On http URLs the
+	// subscription polls the node for new logs
 	URL string
 
 	// Key uniquely identifies a subscription and it can be used to
@@ -154,6 +255,9 @@ type SubscriptionProps struct {
 
 	// C channel to receive the events for a subscription
 	C chan<- interface{}
+
+	// Metrics if set tracks the resubscriptions
+	Metrics *SubscriptionMetrics
 }
 
 // Subscription abstracts an ethereum subscription into a type
@@ -166,6 +270,7 @@ type Subscription struct {
 	url        string
 	key        string
 	c          chan<- interface{}
+	metrics    *SubscriptionMetrics
 }
 
 // NewSubscription creates a new subscription with the
@@ -191,6 +296,7 @@ func NewSubscription(props SubscriptionProps) *Subscription {
 		subscriber: props.Subscriber,
 		key:        props.Key,
 		c:          props.C,
+		metrics:    props.Metrics,
 	}
 
 	return s
@@ -234,6 +340,7 @@ func (s *Subscription) handleError(ctx context.Context, ev concurrent.ErrorWorke
 		"err":       ev.Error.Error(),
 	})
 
+	s.metrics.resubscribed()
 	return s.subscribe(ctx)
 }
 
@@ -259,10 +366,11 @@ type SubscriptionManagerProps struct {
 // SubscriptionManager manages the lifetime
 // of a group of subscriptions
 type SubscriptionManager struct {
-	ctx    context.Context
-	logger log.Logger
-	client Client
-	master *concurrent.Master
+	ctx     context.Context
+	logger  log.Logger
+	client  Client
+	master  *concurrent.Master
+	metrics *SubscriptionMetrics
 }
 
 // NewSubscriptionManager creates a new subscription manager
@@ -270,9 +378,10 @@ func NewSubscriptionManager(
 	props SubscriptionManagerProps,
 ) *SubscriptionManager {
 	m := SubscriptionManager{
-		ctx:    props.Context,
-		logger: props.Logger.ForClass("eth", "SubscriptionManager"),
-		client: props.Client,
+		ctx:     props.Context,
+		logger:  props.Logger.ForClass("eth", "SubscriptionManager"),
+		client:  props.Client,
+		metrics: NewSubscriptionMetrics(),
 	}
 
 	m.master = concurrent.NewMaster(concurrent.MasterProps{
@@ -305,6 +414,7 @@ func (m *SubscriptionManager) create(ctx context.Context, ev concurrent.CreateWo
 		Key:        ev.Key,
 		Subscriber: req.Subscriber,
 		C:          req.C,
+		Metrics:    m.metrics,
 	})
 
 	// inherit context from manager so that cancelling the manager's context
@@ -316,6 +426,7 @@ func (m *SubscriptionManager) create(ctx context.Context, ev concurrent.CreateWo
 	ev.Props.ErrC = sub.sub.Err()
 	ev.Props.WorkerHandler = concurrent.WorkerHandlerFunc(sub.handle)
 	ev.Props.UserData = sub
+	m.metrics.addActive(1)
 
 	return nil
 }
@@ -323,9 +434,22 @@ func (m *SubscriptionManager) create(ctx context.Context, ev concurrent.CreateWo
 func (m *SubscriptionManager) destroy(ev concurrent.DestroyWorkerEvent) error {
 	sub := ev.Worker.UserData.(*Subscription)
 	sub.Unsubscribe()
+	m.metrics.addActive(-1)
 	return nil
 }
 
+// Metrics returns the metrics shared by the subscriptions of
+// the manager, so that subscribers can report the events
+// they deliver
+func (m *SubscriptionManager) Metrics() *SubscriptionMetrics {
+	return m.metrics
+}
+
+// Stats is the implementation of stats.Collector for SubscriptionManager
+func (m *SubscriptionManager) Stats() stats.Metrics {
+	return m.metrics.Stats()
+}
+
 // Create a new subscription identified by the
 // specified key
 func (m *SubscriptionManager) Create(
@@ -0,0 +1,118 @@
+package eth
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeLogClient struct {
+	Client
+	live    chan<- types.Log
+	logs    []types.Log
+	queries []ethereum.FilterQuery
+}
+
+func (c *fakeLogClient) SubscribeFilterLogs(
+	ctx context.Context,
+	q ethereum.FilterQuery,
+	ch chan<- types.Log,
+) (ethereum.Subscription, error) {
+	c.live = ch
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		<-quit
+		return nil
+	}), nil
+}
+
+func (c *fakeLogClient) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	c.queries = append(c.queries, q)
+	return c.logs, nil
+}
+
+func receiveEvent(t *testing.T, c <-chan interface{}) types.Log {
+	select {
+	case ev := <-c:
+		return ev.(types.Log)
+	case <-time.After(time.Second):
+		assert.Fail(t, "timed out waiting for event")
+		return types.Log{}
+	}
+}
+
+func TestLogSubscriberBackfill(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := &fakeLogClient{logs: []types.Log{
+		{BlockNumber: 5, Index: 0},
+		{BlockNumber: 6, Index: 1},
+	}}
+	subscriber := &LogSubscriber{Backfill: big.NewInt(5)}
+	c := make(chan interface{})
+
+	sub, err := subscriber.Subscribe(ctx, client, c)
+	assert.Nil(t, err)
+	defer sub.Unsubscribe()
+
+	assert.Equal(t, types.Log{BlockNumber: 5, Index: 0}, receiveEvent(t, c))
+	assert.Equal(t, types.Log{BlockNumber: 6, Index: 1}, receiveEvent(t, c))
+	assert.Equal(t, []ethereum.FilterQuery{{FromBlock: big.NewInt(5)}}, client.queries)
+
+	// the last historical log is also received by the live
+	// subscription but it is only delivered once
+	client.live <- types.Log{BlockNumber: 6, Index: 1}
+	client.live <- types.Log{BlockNumber: 7, Index: 0}
+	assert.Equal(t, types.Log{BlockNumber: 7, Index: 0}, receiveEvent(t, c))
+
+	subscriber.lock.Lock()
+	defer subscriber.lock.Unlock()
+	assert.Nil(t, subscriber.Backfill)
+}
+
+func TestLogSubscriberNoBackfill(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := &fakeLogClient{}
+	subscriber := &LogSubscriber{}
+	c := make(chan interface{})
+
+	sub, err := subscriber.Subscribe(ctx, client, c)
+	assert.Nil(t, err)
+	defer sub.Unsubscribe()
+
+	client.live <- types.Log{BlockNumber: 7, Index: 0}
+	assert.Equal(t, types.Log{BlockNumber: 7, Index: 0}, receiveEvent(t, c))
+	assert.Empty(t, client.queries)
+}
+
+func TestLogSubscriberRemoved(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := &fakeLogClient{}
+	subscriber := &LogSubscriber{}
+	c := make(chan interface{})
+
+	sub, err := subscriber.Subscribe(ctx, client, c)
+	assert.Nil(t, err)
+	defer sub.Unsubscribe()
+
+	client.live <- types.Log{BlockNumber: 8, Index: 3}
+	assert.Equal(t, types.Log{BlockNumber: 8, Index: 3}, receiveEvent(t, c))
+
+	// the removed log is forwarded and the logs of the new
+	// chain from its position onwards are delivered
+	client.live <- types.Log{BlockNumber: 8, Index: 3, Removed: true}
+	assert.Equal(t, types.Log{BlockNumber: 8, Index: 3, Removed: true}, receiveEvent(t, c))
+
+	client.live <- types.Log{BlockNumber: 8, Index: 1}
+	assert.Equal(t, types.Log{BlockNumber: 8, Index: 1}, receiveEvent(t, c))
+}
@@ -0,0 +1,189 @@
+package eth
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/oasislabs/oasis-gateway/stats"
+)
+
+// AdaptiveTimeoutProps are the properties used to derive the
+// timeouts of the calls to the node
+type AdaptiveTimeoutProps struct {
+	// Percentile of the latencies of a method, between 0 and 1,
+	// that is used as the base of its timeout
+	Percentile float64
+
+	// Factor multiplies the latency at Percentile to get the timeout
+	Factor float64
+
+	// Min is the lower bound of the timeouts
+	Min time.Duration
+
+	// Max is the upper bound of the timeouts. It is also the timeout
+	// used until a method has collected MinSamples latencies
+	Max time.Duration
+
+	// WindowSize is the number of latest latencies kept per method
+	WindowSize int
+
+	// MinSamples is the number of latencies a method needs before
+	// its timeout is derived from them
+	MinSamples int
+}
+
+// AdaptiveTimeout keeps a rolling window of the latencies of each
+// method called on the node and derives from them the timeout of the
+// next call, so that when the node slows down suddenly the calls fail
+// fast instead of accumulating until the gateway runs out of memory.
+//
+// A nil AdaptiveTimeout is valid and calls do not time out
+type AdaptiveTimeout struct {
+	props    AdaptiveTimeoutProps
+	lock     sync.Mutex
+	windows  map[string]*latencyWindow
+	timeouts stats.Counter
+}
+
+// NewAdaptiveTimeout creates a new AdaptiveTimeout
+func NewAdaptiveTimeout(props AdaptiveTimeoutProps) *AdaptiveTimeout {
+	if props.Percentile <= 0 || props.Percentile > 1 {
+		panic("percentile must be in (0, 1]")
+	}
+
+	if props.Factor < 1 {
+		panic("factor must be at least 1")
+	}
+
+	if props.Max < props.Min {
+		panic("max must be at least min")
+	}
+
+	if props.WindowSize <= 0 {
+		panic("window size must be positive")
+	}
+
+	if props.MinSamples > props.WindowSize {
+		props.MinSamples = props.WindowSize
+	}
+
+	return &AdaptiveTimeout{
+		props:   props,
+		windows: make(map[string]*latencyWindow),
+	}
+}
+
+// Timeout returns the timeout of the next call to the method. It
+// returns 0 if the calls do not time out
+func (t *AdaptiveTimeout) Timeout(method string) time.Duration {
+	if t == nil {
+		return 0
+	}
+
+	t.lock.Lock()
+	window, ok := t.windows[method]
+	if !ok || len(window.samples) < t.props.MinSamples {
+		t.lock.Unlock()
+		return t.props.Max
+	}
+	latency := window.percentile(t.props.Percentile)
+	t.lock.Unlock()
+
+	timeout := time.Duration(float64(latency) * t.props.Factor)
+	if timeout < t.props.Min {
+		return t.props.Min
+	}
+	if timeout > t.props.Max {
+		return t.props.Max
+	}
+
+	return timeout
+}
+
+// Observe records the latency of a call to the method that
+// completed before its timeout
+func (t *AdaptiveTimeout) Observe(method string, latency time.Duration) {
+	if t == nil {
+		return
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	window, ok := t.windows[method]
+	if !ok {
+		window = &latencyWindow{samples: make([]time.Duration, 0, t.props.WindowSize)}
+		t.windows[method] = window
+	}
+	window.add(latency, t.props.WindowSize)
+}
+
+// TimedOut records a call that did not complete before its timeout.
+// Its latency is not observed, so that a slow node does not loosen
+// the timeouts of the calls that follow
+func (t *AdaptiveTimeout) TimedOut(method string) {
+	if t == nil {
+		return
+	}
+
+	t.timeouts.Incr()
+}
+
+// Stats is the implementation of stats.Collector for AdaptiveTimeout.
+// It reports the number of calls that timed out and the current
+// timeout in milliseconds of each method that has been called
+func (t *AdaptiveTimeout) Stats() stats.Metrics {
+	if t == nil {
+		return nil
+	}
+
+	t.lock.Lock()
+	methods := make([]string, 0, len(t.windows))
+	for method := range t.windows {
+		methods = append(methods, method)
+	}
+	t.lock.Unlock()
+
+	timeouts := make(stats.Metrics, len(methods))
+	for _, method := range methods {
+		timeouts[method] = int64(t.Timeout(method) / time.Millisecond)
+	}
+
+	return stats.Metrics{
+		"timedOut": t.timeouts.Value(),
+		"timeouts": timeouts,
+	}
+}
+
+// latencyWindow keeps the latest latencies of a method
+type latencyWindow struct {
+	next    int
+	samples []time.Duration
+}
+
+func (w *latencyWindow) add(latency time.Duration, size int) {
+	if len(w.samples) < size {
+		w.samples = append(w.samples, latency)
+		return
+	}
+
+	w.samples[w.next] = latency
+	w.next = (w.next + 1) % size
+}
+
+func (w *latencyWindow) percentile(p float64) time.Duration {
+	sorted := make([]time.Duration, len(w.samples))
+	copy(sorted, w.samples)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i] < sorted[j]
+	})
+
+	index := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if index < 0 {
+		index = 0
+	}
+
+	return sorted[index]
+}
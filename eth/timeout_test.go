@@ -0,0 +1,103 @@
+package eth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	ethereum "github.com/ethereum/go-ethereum"
+	stderr "github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestAdaptiveTimeoutNil(t *testing.T) {
+	var timeout *AdaptiveTimeout
+	timeout.Observe("eth_call", time.Second)
+	assert.Equal(t, time.Duration(0), timeout.Timeout("eth_call"))
+}
+
+func TestAdaptiveTimeoutBeforeMinSamples(t *testing.T) {
+	timeout := NewAdaptiveTimeout(AdaptiveTimeoutProps{
+		Percentile: 0.99,
+		Factor:     3,
+		Min:        time.Millisecond,
+		Max:        time.Second,
+		WindowSize: 8,
+		MinSamples: 4,
+	})
+
+	timeout.Observe("eth_call", 10*time.Millisecond)
+	assert.Equal(t, time.Second, timeout.Timeout("eth_call"))
+}
+
+func TestAdaptiveTimeoutPercentile(t *testing.T) {
+	timeout := NewAdaptiveTimeout(AdaptiveTimeoutProps{
+		Percentile: 0.5,
+		Factor:     2,
+		Min:        time.Millisecond,
+		Max:        time.Second,
+		WindowSize: 4,
+		MinSamples: 4,
+	})
+
+	for _, latency := range []time.Duration{10, 40, 20, 30} {
+		timeout.Observe("eth_call", latency*time.Millisecond)
+	}
+	assert.Equal(t, 40*time.Millisecond, timeout.Timeout("eth_call"))
+
+	// the oldest latencies leave the window
+	for _, latency := range []time.Duration{100, 100, 100} {
+		timeout.Observe("eth_call", latency*time.Millisecond)
+	}
+	assert.Equal(t, 200*time.Millisecond, timeout.Timeout("eth_call"))
+}
+
+func TestAdaptiveTimeoutBounds(t *testing.T) {
+	timeout := NewAdaptiveTimeout(AdaptiveTimeoutProps{
+		Percentile: 0.99,
+		Factor:     3,
+		Min:        50 * time.Millisecond,
+		Max:        time.Second,
+		WindowSize: 4,
+		MinSamples: 1,
+	})
+
+	timeout.Observe("eth_call", time.Millisecond)
+	assert.Equal(t, 50*time.Millisecond, timeout.Timeout("eth_call"))
+
+	timeout.Observe("eth_estimateGas", time.Second)
+	assert.Equal(t, time.Second, timeout.Timeout("eth_estimateGas"))
+}
+
+func TestPooledClientCallTimeout(t *testing.T) {
+	pool := mockPool{conn: &Conn{eclient: &mockEthClient{}, rclient: &mockRpcClient{}}}
+	timeout := NewAdaptiveTimeout(AdaptiveTimeoutProps{
+		Percentile: 0.99,
+		Factor:     1,
+		Min:        10 * time.Millisecond,
+		Max:        10 * time.Millisecond,
+		WindowSize: 4,
+		MinSamples: 1,
+	})
+	c := NewPooledClient(PooledClientProps{
+		Pool:        pool,
+		RetryConfig: TestRetryConfig,
+		Timeout:     timeout,
+	})
+
+	pool.conn.eclient.(*mockEthClient).
+		On("EstimateGas", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			<-args.Get(0).(context.Context).Done()
+		}).
+		Return(uint64(0), context.DeadlineExceeded)
+
+	_, err := c.EstimateGas(context.Background(), ethereum.CallMsg{})
+	assert.Error(t, err)
+	assert.True(t, stderr.Is(err, ErrCallTimeout))
+
+	// a call that times out is not retried
+	pool.conn.eclient.(*mockEthClient).AssertNumberOfCalls(t, "EstimateGas", 1)
+	assert.Equal(t, uint64(1), timeout.Stats()["timedOut"])
+}
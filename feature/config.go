@@ -0,0 +1,56 @@
+package feature
+
+import (
+	"strings"
+
+	"github.com/oasislabs/oasis-gateway/config"
+	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+type Config struct {
+	// Disabled are the features disabled when the gateway starts
+	Disabled []Feature
+}
+
+func (c *Config) Log(fields log.Fields) {
+	names := make([]string, 0, len(c.Disabled))
+	for _, f := range c.Disabled {
+		names = append(names, f.String())
+	}
+	fields.Add("features.disabled", strings.Join(names, ","))
+}
+
+func (c *Config) Configure(v *viper.Viper) error {
+	c.Disabled = nil
+	for _, name := range v.GetStringSlice("features.disabled") {
+		f, ok := Parse(name)
+		if !ok {
+			return config.ErrInvalidValue{
+				Key:          "features.disabled",
+				InvalidValue: name,
+				Values:       names(),
+			}
+		}
+		c.Disabled = append(c.Disabled, f)
+	}
+
+	return nil
+}
+
+func (c *Config) Bind(v *viper.Viper, cmd *cobra.Command) error {
+	cmd.PersistentFlags().StringSlice("features.disabled", nil,
+		"features of the public API that are disabled. Options are "+
+			strings.Join(names(), ", ")+". The routes of a disabled feature return 404.")
+	return nil
+}
+
+func names() []string {
+	names := make([]string, 0, len(Features))
+	for _, f := range Features {
+		names = append(names, f.String())
+	}
+
+	return names
+}
@@ -0,0 +1,120 @@
+package feature
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/oasislabs/oasis-gateway/errors"
+)
+
+// Feature is a group of routes of the public API that
+// can be enabled or disabled as a whole
+type Feature string
+
+const (
+	// Deploy allows the deployment of services
+	Deploy Feature = "deploy"
+
+	// Execute allows the execution of services
+	Execute Feature = "execute"
+
+	// Query allows the read only access to services, such as
+	// queries and the retrieval of their code
+	Query Feature = "query"
+
+	// Subscribe allows the management of subscriptions to events
+	Subscribe Feature = "subscribe"
+
+	// Poll allows polling for the events of a session
+	Poll Feature = "poll"
+)
+
+// Features are all the features that can be toggled
+var Features = []Feature{Deploy, Execute, Query, Subscribe, Poll}
+
+func (f Feature) String() string {
+	return string(f)
+}
+
+// Parse returns the feature with the name
+func Parse(name string) (Feature, bool) {
+	for _, f := range Features {
+		if f.String() == name {
+			return f, true
+		}
+	}
+
+	return "", false
+}
+
+// Flags keeps whether each feature is enabled. Features are
+// enabled unless they are disabled explicitly, and they can be
+// toggled while the gateway is running.
+//
+// A nil Flags is valid and has all the features enabled
+type Flags struct {
+	lock     sync.RWMutex
+	disabled map[Feature]bool
+}
+
+// NewFlags creates a new set of flags in which the
+// provided features are disabled
+func NewFlags(disabled []Feature) *Flags {
+	flags := &Flags{disabled: make(map[Feature]bool)}
+	for _, f := range disabled {
+		flags.disabled[f] = true
+	}
+
+	return flags
+}
+
+// Enabled returns true if the feature is enabled
+func (f *Flags) Enabled(feature Feature) bool {
+	if f == nil {
+		return true
+	}
+
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	return !f.disabled[feature]
+}
+
+// Set enables or disables the feature with the name
+func (f *Flags) Set(name string, enabled bool) errors.Err {
+	feature, ok := Parse(name)
+	if !ok {
+		return errors.New(errors.ErrUnknownFeature, fmt.Errorf("feature %s does not exist", name))
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if enabled {
+		delete(f.disabled, feature)
+	} else {
+		f.disabled[feature] = true
+	}
+
+	return nil
+}
+
+// States returns whether each feature is enabled, sorted by name
+func (f *Flags) States() []State {
+	states := make([]State, 0, len(Features))
+	for _, feature := range Features {
+		states = append(states, State{Feature: feature, Enabled: f.Enabled(feature)})
+	}
+
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].Feature < states[j].Feature
+	})
+	return states
+}
+
+// State is whether a feature is enabled
+type State struct {
+	Feature Feature
+	Enabled bool
+}
@@ -0,0 +1,77 @@
+package feature
+
+import (
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/oasislabs/oasis-gateway/rpc"
+	"github.com/stretchr/testify/assert"
+)
+
+var Logger = log.NewLogrus(log.LogrusLoggerProperties{
+	Output: ioutil.Discard,
+})
+
+func TestFlagsNil(t *testing.T) {
+	var flags *Flags
+	assert.True(t, flags.Enabled(Deploy))
+}
+
+func TestFlagsSet(t *testing.T) {
+	flags := NewFlags([]Feature{Deploy, Query})
+	assert.False(t, flags.Enabled(Deploy))
+	assert.True(t, flags.Enabled(Execute))
+
+	assert.Nil(t, flags.Set("deploy", true))
+	assert.Nil(t, flags.Set("execute", false))
+
+	assert.Equal(t, []State{
+		{Feature: Deploy, Enabled: true},
+		{Feature: Execute, Enabled: false},
+		{Feature: Poll, Enabled: true},
+		{Feature: Query, Enabled: false},
+		{Feature: Subscribe, Enabled: true},
+	}, flags.States())
+}
+
+func TestFlagsSetUnknown(t *testing.T) {
+	flags := NewFlags(nil)
+
+	err := flags.Set("mint", false)
+	assert.Equal(t, "[2027] error code InputError with desc Provided unknown feature. with cause feature mint does not exist", err.Error())
+}
+
+func TestHttpMiddleware(t *testing.T) {
+	flags := NewFlags([]Feature{Deploy})
+	handler := NewHttpMiddleware(flags, RouteFeatures{
+		"/deploy":  Deploy,
+		"/execute": Execute,
+	}, Logger, rpc.HttpMiddlewareFunc(func(req *http.Request) (interface{}, error) {
+		return 0, nil
+	}))
+
+	for _, path := range []string{"/execute", "/info"} {
+		req, err := http.NewRequest("POST", path, nil)
+		assert.Nil(t, err)
+
+		res, err := handler.ServeHTTP(req)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, res)
+	}
+
+	req, err := http.NewRequest("POST", "/deploy", nil)
+	assert.Nil(t, err)
+
+	res, err := handler.ServeHTTP(req)
+	assert.Nil(t, res)
+	assert.Equal(t, http.StatusNotFound, err.(*rpc.HttpError).StatusCode)
+	assert.Equal(t, "[6006] error code NotFound with desc API is disabled in this deployment. with cause feature deploy is disabled", err.(*rpc.HttpError).Cause.Error())
+
+	// the feature is served again once it is enabled
+	assert.Nil(t, flags.Set("deploy", true))
+	res, err = handler.ServeHTTP(req)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, res)
+}
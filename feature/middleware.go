@@ -0,0 +1,58 @@
+package feature
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/oasislabs/oasis-gateway/errors"
+	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/oasislabs/oasis-gateway/rpc"
+)
+
+// RouteFeatures maps the path of a route to the feature it
+// belongs to. Routes not listed are always enabled
+type RouteFeatures map[string]Feature
+
+// HttpMiddleware rejects the requests to the routes whose
+// feature is disabled, so that they look as if the routes
+// were not served
+type HttpMiddleware struct {
+	flags  *Flags
+	routes RouteFeatures
+	logger log.Logger
+	next   rpc.HttpMiddleware
+}
+
+// NewHttpMiddleware creates a new HttpMiddleware
+func NewHttpMiddleware(flags *Flags, routes RouteFeatures, logger log.Logger, next rpc.HttpMiddleware) *HttpMiddleware {
+	if logger == nil {
+		panic("log must be set")
+	}
+
+	if next == nil {
+		panic("next must be set")
+	}
+
+	return &HttpMiddleware{
+		flags:  flags,
+		routes: routes,
+		logger: logger.ForClass("feature", "HttpMiddleware"),
+		next:   next,
+	}
+}
+
+// ServeHTTP is the implementation of rpc.HttpMiddleware for HttpMiddleware
+func (m *HttpMiddleware) ServeHTTP(req *http.Request) (interface{}, error) {
+	feature, ok := m.routes[req.URL.Path]
+	if ok && !m.flags.Enabled(feature) {
+		err := errors.New(errors.ErrFeatureDisabled, fmt.Errorf("feature %s is disabled", feature))
+		m.logger.Debug(req.Context(), "request to a disabled feature", log.MapFields{
+			"call_type": "FeatureDisabledFailure",
+			"path":      req.URL.Path,
+			"feature":   feature.String(),
+		}, err)
+		return nil, rpc.HttpNotFound(req.Context(), err)
+	}
+
+	return m.next.ServeHTTP(req)
+}
@@ -3,27 +3,45 @@ package gateway
 import (
 	"errors"
 	"math"
+	"net"
+	"strings"
+	"time"
 
 	"github.com/oasislabs/oasis-gateway/auth"
 	"github.com/oasislabs/oasis-gateway/backend"
 	"github.com/oasislabs/oasis-gateway/callback"
 	"github.com/oasislabs/oasis-gateway/config"
+	"github.com/oasislabs/oasis-gateway/discovery"
+	"github.com/oasislabs/oasis-gateway/feature"
 	"github.com/oasislabs/oasis-gateway/log"
 	"github.com/oasislabs/oasis-gateway/mqueue"
+	"github.com/oasislabs/oasis-gateway/restart"
 	"github.com/oasislabs/oasis-gateway/rpc"
+	"github.com/oasislabs/oasis-gateway/slo"
+	"github.com/oasislabs/oasis-gateway/stats/statsd"
+	"github.com/oasislabs/oasis-gateway/usage"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
 // Config is the general application's configuration
 type Config struct {
+	RoleConfig        RoleConfig
 	BindPublicConfig  BindPublicConfig
 	BindPrivateConfig BindPrivateConfig
 	BackendConfig     backend.Config
 	MailboxConfig     mqueue.Config
 	AuthConfig        auth.Config
 	CallbackConfig    callback.Config
+	DiscoveryConfig   discovery.Config
+	UsageConfig       usage.Config
+	StartupConfig     StartupConfig
+	RestartConfig     restart.Config
 	LoggingConfig     LoggingConfig
+	LeakConfig        LeakConfig
+	StatsDConfig      statsd.Config
+	FeatureConfig     feature.Config
+	SLOConfig         slo.Config
 }
 
 func (c *Config) Use() string {
@@ -36,24 +54,42 @@ func (c *Config) EnvPrefix() string {
 
 func (c *Config) Binders() []config.Binder {
 	return []config.Binder{
+		&c.RoleConfig,
 		&c.BindPublicConfig,
 		&c.BindPrivateConfig,
 		&c.BackendConfig,
 		&c.MailboxConfig,
 		&c.AuthConfig,
 		&c.CallbackConfig,
+		&c.DiscoveryConfig,
+		&c.UsageConfig,
+		&c.StartupConfig,
+		&c.RestartConfig,
 		&c.LoggingConfig,
+		&c.LeakConfig,
+		&c.StatsDConfig,
+		&c.FeatureConfig,
+		&c.SLOConfig,
 	}
 }
 
 func (c *Config) Log(fields log.Fields) {
+	c.RoleConfig.Log(fields)
 	c.BindPublicConfig.Log(fields)
 	c.BindPrivateConfig.Log(fields)
 	c.BackendConfig.Log(fields)
 	c.MailboxConfig.Log(fields)
 	c.AuthConfig.Log(fields)
 	c.CallbackConfig.Log(fields)
+	c.DiscoveryConfig.Log(fields)
+	c.UsageConfig.Log(fields)
+	c.StartupConfig.Log(fields)
+	c.RestartConfig.Log(fields)
 	c.LoggingConfig.Log(fields)
+	c.LeakConfig.Log(fields)
+	c.StatsDConfig.Log(fields)
+	c.FeatureConfig.Log(fields)
+	c.SLOConfig.Log(fields)
 }
 
 // BindConfig is the configuration for binding the exposed APIs
@@ -206,6 +242,11 @@ func (c *BindPublicConfig) Bind(v *viper.Viper, cmd *cobra.Command) error {
 
 type BindPrivateConfig struct {
 	BindConfig
+
+	// AuthToken is the bearer token that the requests to the
+	// private API must present. It can only be empty if the
+	// private API is bound to a loopback interface
+	AuthToken string
 }
 
 func (c *BindPrivateConfig) Log(fields log.Fields) {
@@ -218,6 +259,7 @@ func (c *BindPrivateConfig) Log(fields log.Fields) {
 	fields.Add("bind_private.https_enabled", c.BindConfig.HttpsEnabled)
 	fields.Add("bind_private.tls_certificate_path", c.BindConfig.TlsCertificatePath)
 	fields.Add("bind_private.tls_private_key_path", c.BindConfig.TlsPrivateKeyPath)
+	fields.Add("bind_private.auth_token_set", len(c.AuthToken) > 0)
 }
 
 func (c *BindPrivateConfig) Name() string {
@@ -225,19 +267,62 @@ func (c *BindPrivateConfig) Name() string {
 }
 
 func (c *BindPrivateConfig) Configure(v *viper.Viper) error {
-	return c.BindConfig.Configure("bind_private", v)
+	if err := c.BindConfig.Configure("bind_private", v); err != nil {
+		return err
+	}
+
+	// the private API serves the stats and the admin controls of
+	// the gateway, so it is only left unauthenticated if it cannot
+	// be reached from other hosts
+	c.AuthToken = v.GetString("bind_private.auth_token")
+	if len(c.AuthToken) == 0 && !isLoopback(c.HttpInterface) {
+		return errors.New("bind_private.auth_token must be set if " +
+			"bind_private.http_interface is not a loopback interface")
+	}
+
+	return nil
 }
 
 func (c *BindPrivateConfig) Bind(v *viper.Viper, cmd *cobra.Command) error {
-	return c.BindConfig.Bind("bind_private", v, cmd)
+	if err := c.BindConfig.Bind("bind_private", v, cmd); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("bind_private.auth_token", "",
+		"bearer token that the requests to the private API must present in the Authorization header. "+
+			"It must be set if bind_private.http_interface is not a loopback interface")
+
+	return nil
+}
+
+// isLoopback returns true if the interface only accepts
+// connections from the same host
+func isLoopback(iface string) bool {
+	if iface == "localhost" {
+		return true
+	}
+
+	ip := net.ParseIP(iface)
+	return ip != nil && ip.IsLoopback()
 }
 
 type LoggingConfig struct {
-	Level string
+	Level  string
+	Schema log.Schema
+
+	// Levels override the level of some components
+	Levels []log.Override
 }
 
 func (c *LoggingConfig) Log(fields log.Fields) {
+	overrides := make([]string, 0, len(c.Levels))
+	for _, o := range c.Levels {
+		overrides = append(overrides, o.Component+"="+o.Level.String())
+	}
+
 	fields.Add("logging.level", c.Level)
+	fields.Add("logging.schema", c.Schema.String())
+	fields.Add("logging.levels", strings.Join(overrides, ","))
 }
 
 func (c *LoggingConfig) Configure(v *viper.Viper) error {
@@ -246,11 +331,155 @@ func (c *LoggingConfig) Configure(v *viper.Viper) error {
 		c.Level = "debug"
 	}
 
+	c.Schema = log.Schema(v.GetString("logging.schema"))
+	switch c.Schema {
+	case "":
+		c.Schema = log.SchemaLegacy
+	case log.SchemaLegacy, log.SchemaV1:
+	default:
+		return config.ErrInvalidValue{
+			Key:          "logging.schema",
+			InvalidValue: c.Schema.String(),
+			Values:       []string{log.SchemaLegacy.String(), log.SchemaV1.String()},
+		}
+	}
+
+	c.Levels = nil
+	for _, s := range v.GetStringSlice("logging.levels") {
+		o, err := log.ParseOverride(s)
+		if err != nil {
+			return config.ErrInvalidValue{
+				Key:          "logging.levels",
+				InvalidValue: s,
+				Values:       []string{"<component>=debug", "<component>=info", "<component>=warn", "<component>=error"},
+			}
+		}
+		c.Levels = append(c.Levels, o)
+	}
+
 	return nil
 }
 
 func (c *LoggingConfig) Bind(v *viper.Viper, cmd *cobra.Command) error {
 	cmd.PersistentFlags().String("logging.level", "debug",
 		"sets the minimum logging level for the logger")
+	cmd.PersistentFlags().String("logging.schema", log.SchemaLegacy.String(),
+		"layout of the json log entries. Options are "+log.SchemaLegacy.String()+
+			" to write the fields with the keys they are logged with, which may change between releases, and "+
+			log.SchemaV1.String()+" to write the fields of the versioned schema with stable keys and types "+
+			"along with a schema_version, and all the other fields under fields.")
+	cmd.PersistentFlags().StringSlice("logging.levels", nil,
+		"levels of the components that log at a level other than logging.level, as component=level, "+
+			"e.g. mqueue/redis=warn. A component is the pkg and class of its entries joined by a slash, "+
+			"and the level applies to all the components whose name starts with it.")
+	return nil
+}
+
+// DefaultLeakGauges are the gauges watched by default to detect
+// resources that are never released
+var DefaultLeakGauges = []string{
+	"runtime.NumGoroutine",
+	"mqueue.mem.Server.workers.active",
+	"mqueue.mem.Server.storage.keys",
+	"tx.Executor.pending",
+	"tx.Executor.workers.active",
+}
+
+// LeakConfig defines how the gauges of the gateway are watched
+// to detect resources that are leaking
+type LeakConfig struct {
+	// Interval is the time between two checks of the gauges.
+	// If 0 the gauges are not checked
+	Interval time.Duration
+
+	// MinGrowths is the number of consecutive checks in which a
+	// gauge has to grow before a warning is logged
+	MinGrowths int
+
+	// Gauges are the paths of the gauges watched
+	Gauges []string
+}
+
+func (c *LeakConfig) Log(fields log.Fields) {
+	fields.Add("leak.interval_ms", int64(c.Interval/time.Millisecond))
+	fields.Add("leak.min_growths", c.MinGrowths)
+	fields.Add("leak.gauges", c.Gauges)
+}
+
+func (c *LeakConfig) Configure(v *viper.Viper) error {
+	interval := v.GetInt64("leak.interval_ms")
+	if interval < 0 {
+		return errors.New("leak.interval_ms cannot be negative")
+	}
+	c.Interval = time.Duration(interval) * time.Millisecond
+
+	c.MinGrowths = v.GetInt("leak.min_growths")
+	if c.MinGrowths <= 0 {
+		return errors.New("leak.min_growths must be positive")
+	}
+
+	c.Gauges = v.GetStringSlice("leak.gauges")
+	return nil
+}
+
+func (c *LeakConfig) Bind(v *viper.Viper, cmd *cobra.Command) error {
+	cmd.PersistentFlags().Int64("leak.interval_ms", 60000,
+		"time in milliseconds between checks of the gauges watched for leaks. "+
+			"If 0 the gauges are not checked.")
+	cmd.PersistentFlags().Int("leak.min_growths", 5,
+		"number of consecutive checks in which a gauge has to grow before a warning is logged.")
+	cmd.PersistentFlags().StringSlice("leak.gauges", DefaultLeakGauges,
+		"paths of the gauges watched for leaks, as reported by the snapshots of the private API.")
+	return nil
+}
+
+// Role defines which APIs an instance of the gateway serves
+type Role string
+
+const (
+	// RoleFull instances serve all the APIs and submit transactions
+	RoleFull Role = "full"
+
+	// RoleReplica instances only serve the APIs to poll for events and
+	// manage subscriptions against a mailbox shared with full instances.
+	// They do not own a wallet
+	RoleReplica Role = "replica"
+)
+
+func (r Role) String() string {
+	return string(r)
+}
+
+type RoleConfig struct {
+	Role Role
+}
+
+func (c *RoleConfig) Log(fields log.Fields) {
+	fields.Add("role", c.Role)
+}
+
+func (c *RoleConfig) Configure(v *viper.Viper) error {
+	c.Role = Role(v.GetString("role"))
+
+	switch c.Role {
+	case "":
+		c.Role = RoleFull
+	case RoleFull, RoleReplica:
+	default:
+		return config.ErrInvalidValue{
+			Key:          "role",
+			InvalidValue: c.Role.String(),
+			Values:       []string{RoleFull.String(), RoleReplica.String()},
+		}
+	}
+
+	return nil
+}
+
+func (c *RoleConfig) Bind(v *viper.Viper, cmd *cobra.Command) error {
+	cmd.PersistentFlags().String("role", RoleFull.String(),
+		"role of the instance. Options are "+RoleFull.String()+", "+RoleReplica.String()+
+			". A "+RoleReplica.String()+" only serves event polling and subscriptions "+
+			"and requires a shared mailbox provider.")
 	return nil
 }
@@ -0,0 +1,37 @@
+package gateway
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func configureBindPrivate(iface, token string) (BindPrivateConfig, error) {
+	v := viper.New()
+	v.Set("bind_private.http_interface", iface)
+	v.Set("bind_private.auth_token", token)
+
+	var config BindPrivateConfig
+	err := config.Configure(v)
+	return config, err
+}
+
+func TestBindPrivateConfigLoopback(t *testing.T) {
+	for _, iface := range []string{"127.0.0.1", "::1", "localhost"} {
+		config, err := configureBindPrivate(iface, "")
+		assert.Nil(t, err, iface)
+		assert.Empty(t, config.AuthToken)
+	}
+}
+
+func TestBindPrivateConfigRequiresToken(t *testing.T) {
+	for _, iface := range []string{"0.0.0.0", "10.0.0.1", "gateway.internal"} {
+		_, err := configureBindPrivate(iface, "")
+		assert.Error(t, err, iface)
+	}
+
+	config, err := configureBindPrivate("0.0.0.0", "secret")
+	assert.Nil(t, err)
+	assert.Equal(t, "secret", config.AuthToken)
+}
@@ -0,0 +1,71 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/oasislabs/oasis-gateway/discovery"
+)
+
+// NewInstance describes the gateway instance as it is
+// registered with the service discovery backend
+func NewInstance(config *Config) discovery.Instance {
+	address := config.DiscoveryConfig.AdvertiseAddr
+	if len(address) == 0 {
+		address = fmt.Sprintf("%s:%d",
+			config.BindPublicConfig.HttpInterface, config.BindPublicConfig.HttpPort)
+	}
+
+	id := config.DiscoveryConfig.InstanceID
+	if len(id) == 0 {
+		id = fmt.Sprintf("%s-%s", config.DiscoveryConfig.ServiceName, address)
+	}
+
+	healthAddress := config.DiscoveryConfig.AdvertiseHealthAddr
+	if len(healthAddress) == 0 {
+		healthAddress = fmt.Sprintf("%s:%d",
+			config.BindPrivateConfig.HttpInterface, config.BindPrivateConfig.HttpPort)
+	}
+
+	scheme := "http"
+	if config.BindPrivateConfig.HttpsEnabled {
+		scheme = "https"
+	}
+
+	return discovery.Instance{
+		ID:      id,
+		Name:    config.DiscoveryConfig.ServiceName,
+		Address: address,
+		Role:    string(config.RoleConfig.Role),
+		HealthURL: fmt.Sprintf("%s://%s/v0/api/health", scheme, healthAddress),
+	}
+}
+
+// StartDiscovery registers the instance with the configured service
+// discovery backend and keeps the registration alive until the
+// context is cancelled, at which point the instance is deregistered.
+// It returns nil if no provider is configured
+func StartDiscovery(ctx context.Context, config *Config, group *ServiceGroup) (*discovery.Registration, error) {
+	if config.DiscoveryConfig.Provider == discovery.ProviderNone {
+		return nil, nil
+	}
+
+	registrar, err := discovery.NewRegistrar(nil, &config.DiscoveryConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	registration := discovery.NewRegistration(discovery.Services{
+		Logger:    RootLogger,
+		Registrar: registrar,
+	}, discovery.Props{
+		Instance: NewInstance(config),
+		TTL:      config.DiscoveryConfig.TTL,
+	})
+	if err := group.Registry.Register(registration.Name(), registration); err != nil {
+		return nil, err
+	}
+	registration.Start(ctx)
+
+	return registration, nil
+}
@@ -2,30 +2,57 @@ package gateway
 
 import (
 	"context"
+	"errors"
+	"io"
 
+	"github.com/oasislabs/oasis-gateway/api/v0/alias"
+	"github.com/oasislabs/oasis-gateway/api/v0/debug"
 	"github.com/oasislabs/oasis-gateway/api/v0/event"
+	featureapi "github.com/oasislabs/oasis-gateway/api/v0/feature"
+	"github.com/oasislabs/oasis-gateway/api/v0/federation"
 	"github.com/oasislabs/oasis-gateway/api/v0/health"
+	"github.com/oasislabs/oasis-gateway/api/v0/idempotency"
 	"github.com/oasislabs/oasis-gateway/api/v0/info"
+	loggingapi "github.com/oasislabs/oasis-gateway/api/v0/logging"
+	maintenanceapi "github.com/oasislabs/oasis-gateway/api/v0/maintenance"
 	"github.com/oasislabs/oasis-gateway/api/v0/service"
+	"github.com/oasislabs/oasis-gateway/api/v0/session"
+	usageapi "github.com/oasislabs/oasis-gateway/api/v0/usage"
 	"github.com/oasislabs/oasis-gateway/auth"
 	authcore "github.com/oasislabs/oasis-gateway/auth/core"
 	"github.com/oasislabs/oasis-gateway/backend"
 	backendcore "github.com/oasislabs/oasis-gateway/backend/core"
 	"github.com/oasislabs/oasis-gateway/callback"
 	callbackclient "github.com/oasislabs/oasis-gateway/callback/client"
+	"github.com/oasislabs/oasis-gateway/feature"
 	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/oasislabs/oasis-gateway/maintenance"
 	"github.com/oasislabs/oasis-gateway/mqueue"
 	mqueuecore "github.com/oasislabs/oasis-gateway/mqueue/core"
+	mqfederation "github.com/oasislabs/oasis-gateway/mqueue/federation"
+	"github.com/oasislabs/oasis-gateway/quota"
 	"github.com/oasislabs/oasis-gateway/rpc"
+	"github.com/oasislabs/oasis-gateway/slo"
+	"github.com/oasislabs/oasis-gateway/stats"
+	"github.com/oasislabs/oasis-gateway/stats/statsd"
+	"github.com/oasislabs/oasis-gateway/usage"
 	"github.com/sirupsen/logrus"
 )
 
 // RootLogger is the base logger of the application, all
 // loggers used in the gateway should derive from this
 var RootLogger = log.NewLogrus(log.LogrusLoggerProperties{
-	Level: logrus.WarnLevel,
+	Levels: RootLevels,
 })
 
+// RootLevels holds the levels of the components that log
+// through the RootLogger, which can be changed at runtime
+var RootLevels = log.NewLevels(logrus.WarnLevel)
+
+// ErrReplicaMailboxNotShared is returned when a replica is configured
+// with a mailbox that cannot be shared amongst instances
+var ErrReplicaMailboxNotShared = errors.New("role replica requires a shared mailbox provider")
+
 // RootContext is the base logger of the application,
 // all contexts created in the gateway should derive
 // from this
@@ -37,6 +64,30 @@ type ServiceGroup struct {
 	Request       *backendcore.RequestManager
 	Backend       backendcore.Client
 	Authenticator authcore.Auth
+
+	// Usage records the usage of the gateway by each AAD. It
+	// is nil if the usage is not recorded
+	Usage *usage.Recorder
+
+	// Registry holds the collectors of all the services of
+	// the group, which are registered as they are constructed
+	Registry *stats.Registry
+
+	// Snapshots takes snapshots of the gauges of the Registry
+	// so that their growth can be inspected
+	Snapshots *stats.Snapshotter
+
+	// Features keeps which features of the public API are
+	// served. If nil all the features are served
+	Features *feature.Flags
+
+	// Maintenance keeps whether the gateway is under maintenance.
+	// If nil the gateway is never under maintenance
+	Maintenance *maintenance.Mode
+
+	// cancel cancels the context the services of the group were
+	// created with, which stops their background workers
+	cancel context.CancelFunc
 }
 
 type ServiceFactories struct {
@@ -75,7 +126,8 @@ func setDefaultFactories(factories *ServiceFactories) *ServiceFactories {
 // RootLogger is used
 func InitLogger(config *LoggingConfig) {
 	props := log.LogrusLoggerProperties{
-		Level: logrus.DebugLevel,
+		Level:  logrus.DebugLevel,
+		Schema: config.Schema,
 	}
 
 	switch config.Level {
@@ -89,15 +141,46 @@ func InitLogger(config *LoggingConfig) {
 		props.Level = logrus.DebugLevel
 	}
 
+	RootLevels = log.NewLevels(props.Level, config.Levels...)
+	props.Levels = RootLevels
 	RootLogger = log.NewLogrus(props)
 }
 
 func NewServiceGroupWithFactories(ctx context.Context, config *Config, factories *ServiceFactories) (*ServiceGroup, error) {
 	factories = setDefaultFactories(factories)
-	mqueue, err := factories.MailboxFactory.New(ctx, mqueue.Services{Logger: RootLogger}, &config.MailboxConfig)
+
+	ctx, cancel := context.WithCancel(ctx)
+	group, err := newServiceGroup(ctx, config, factories)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	group.cancel = cancel
+	return group, nil
+}
+
+func newServiceGroup(ctx context.Context, config *Config, factories *ServiceFactories) (*ServiceGroup, error) {
+	if config.RoleConfig.Role == RoleReplica {
+		// replicas only serve events that are produced by other
+		// instances, so they need a mailbox shared with them
+		if config.MailboxConfig.Provider == mqueue.MailboxMem {
+			return nil, ErrReplicaMailboxNotShared
+		}
+		config.BackendConfig.ReadOnly = true
+	}
+	registry := stats.NewRegistry()
+
+	mqueue, err := factories.MailboxFactory.New(ctx, mqueue.Services{
+		Logger:   RootLogger,
+		Registry: registry,
+	}, &config.MailboxConfig)
 	if err != nil {
 		return nil, err
 	}
+	if err := registry.Register(mqueue.Name(), mqueue); err != nil {
+		return nil, err
+	}
 
 	callbacks, err := factories.CallbacksFactory.New(ctx, &callback.ClientServices{
 		Logger: RootLogger,
@@ -105,29 +188,109 @@ func NewServiceGroupWithFactories(ctx context.Context, config *Config, factories
 	if err != nil {
 		return nil, err
 	}
+	if err := registry.Register(callbacks.Name(), callbacks); err != nil {
+		return nil, err
+	}
 
 	client, err := factories.BackendClientFactory.New(ctx, &backend.ClientServices{
 		Logger:    RootLogger,
 		Callbacks: callbacks,
+		Registry:  registry,
+		Slow:      config.SLOConfig.Slow,
 	}, &config.BackendConfig)
 	if err != nil {
 		return nil, err
 	}
+	if err := registry.Register(client.Name(), client); err != nil {
+		return nil, err
+	}
+
+	// backends that can resolve names on-chain are used as the
+	// resolver for the service addresses
+	resolver, _ := client.(backendcore.AddressResolver)
 
 	request, err := factories.BackendRequestManager.New(ctx, &backend.Deps{
-		Logger: RootLogger,
-		MQueue: mqueue,
-		Client: client,
+		Logger:      RootLogger,
+		MQueue:      mqueue,
+		Client:      client,
+		DeployDedup: config.BackendConfig.DeployDedup,
+		Idempotency: backendcore.IdempotencyRegistryProps{
+			TTL: config.BackendConfig.IdempotencyConfig.TTL,
+		},
+		Resolver: resolver,
+		QueryCache: backendcore.QueryCacheProps{
+			TTL:        config.BackendConfig.QueryCacheConfig.TTL,
+			MaxEntries: config.BackendConfig.QueryCacheConfig.MaxEntries,
+		},
+		Quota: backendcore.QuotaProps{
+			QueueSize:   config.BackendConfig.QuotaConfig.QueueSize,
+			SpendLimit:  config.BackendConfig.QuotaConfig.SpendLimit,
+			WarnPercent: config.BackendConfig.QuotaConfig.WarnPercent,
+			MaxInFlight: config.BackendConfig.QuotaConfig.MaxInFlight,
+		},
+		Output: backendcore.OutputStoreProps{
+			MaxSize:     config.BackendConfig.OutputConfig.MaxSize,
+			PreviewSize: config.BackendConfig.OutputConfig.PreviewSize,
+			TTL:         config.BackendConfig.OutputConfig.TTL,
+		},
+		Subscription: backendcore.SubscriptionLagProps{
+			MaxLag:          config.BackendConfig.SubscriptionConfig.MaxLag,
+			MaxAge:          config.BackendConfig.SubscriptionConfig.MaxAge,
+			AutoUnsubscribe: config.BackendConfig.SubscriptionConfig.AutoUnsubscribe,
+		},
 	})
 	if err != nil {
 		return nil, err
 	}
+	if err := registry.Register(request.Name(), request); err != nil {
+		return nil, err
+	}
+
+	recorder := usage.NewRecorderFromConfig(ctx, RootLogger, &config.UsageConfig)
+	if recorder != nil {
+		request.Bus().Subscribe(recorder)
+		if err := registry.Register(recorder.Name(), recorder); err != nil {
+			return nil, err
+		}
+	}
 
 	authenticator, err := factories.AuthFactory.New(&config.AuthConfig)
 	if err != nil {
 		return nil, err
 	}
 	authenticator.SetLogger(RootLogger)
+	if err := registry.Register(authenticator.Name(), authenticator); err != nil {
+		return nil, err
+	}
+	if err := registry.Register(RuntimeService{}.Name(), RuntimeService{}); err != nil {
+		return nil, err
+	}
+
+	if config.LeakConfig.Interval > 0 {
+		detector := stats.NewLeakDetector(stats.LeakDetectorServices{
+			Logger:    RootLogger,
+			Collector: registry,
+		}, stats.LeakDetectorProps{
+			Gauges:     config.LeakConfig.Gauges,
+			Interval:   config.LeakConfig.Interval,
+			MinGrowths: config.LeakConfig.MinGrowths,
+		})
+		detector.Start(ctx)
+		if err := registry.Register(detector.Name(), detector); err != nil {
+			return nil, err
+		}
+	}
+
+	exporter, err := statsd.NewExporterFromConfig(RootLogger, registry, &config.StatsDConfig)
+	if err != nil {
+		return nil, err
+	}
+	if exporter != nil {
+		exporter.Start(ctx)
+		if err := registry.Register(exporter.Name(), exporter); err != nil {
+			return nil, err
+		}
+	}
 
 	return &ServiceGroup{
 		Mailbox:       mqueue,
@@ -135,9 +298,47 @@ func NewServiceGroupWithFactories(ctx context.Context, config *Config, factories
 		Backend:       client,
 		Authenticator: authenticator,
 		Callback:      callbacks,
+		Usage:         recorder,
+		Registry:      registry,
+		Snapshots:     stats.NewSnapshotter(registry, stats.SnapshotterProps{}),
+		Features:      feature.NewFlags(config.FeatureConfig.Disabled),
+		Maintenance:   maintenance.NewMode(),
 	}, nil
 }
 
+// Close releases the resources of the services of the group, like
+// the private keys of the wallets of the backend
+func (g *ServiceGroup) Close() error {
+	if closer, ok := g.Backend.(io.Closer); ok {
+		return closer.Close()
+	}
+
+	return nil
+}
+
+// Shutdown stops the services of the group. The requests that are
+// running in the background are drained until the context is done,
+// then the resources of the backend are released and the context
+// the services were created with is cancelled, which stops the
+// mailbox workers and closes the connections to the nodes. The
+// servers must be drained before, so that no new requests arrive
+func (g *ServiceGroup) Shutdown(ctx context.Context) error {
+	var err error
+	if g.Request != nil {
+		err = g.Request.Shutdown(ctx)
+	}
+
+	if cerr := g.Close(); cerr != nil && err == nil {
+		err = cerr
+	}
+
+	if g.cancel != nil {
+		g.cancel()
+	}
+
+	return err
+}
+
 func NewServiceGroup(ctx context.Context, config *Config) (*ServiceGroup, error) {
 	return NewServiceGroupWithFactories(ctx, config, nil)
 }
@@ -148,49 +349,176 @@ type Routers struct {
 	Private *rpc.HttpRouter
 }
 
-func NewRouters(config *Config, group *ServiceGroup) *Routers {
-	services := NewServices()
-	services.Add(group.Mailbox)
-	services.Add(group.Callback)
-	services.Add(group.Request)
-	services.Add(group.Backend)
-	services.Add(group.Authenticator)
-	services.Add(RuntimeService{})
-
-	var routers Routers
-	routers.Public = NewPublicRouter(config, group)
-	services.Add(HttpRouterService{
-		name:   "PublicRouter",
-		router: routers.Public,
-	})
-
-	routers.Private = NewPrivateRouter(config, services, group)
+func NewRouters(config *Config, group *ServiceGroup) (*Routers, error) {
+	public, err := NewPublicRouter(config, group)
+	if err != nil {
+		return nil, err
+	}
+	if err := group.Registry.Register("PublicRouter", public); err != nil {
+		return nil, err
+	}
 
-	return &routers
+	return &Routers{
+		Public:  public,
+		Private: NewPrivateRouter(config, group),
+	}, nil
 }
 
-func NewPrivateRouter(config *Config, services Services, group *ServiceGroup) *rpc.HttpRouter {
+func NewPrivateRouter(config *Config, group *ServiceGroup) *rpc.HttpRouter {
 	binder := rpc.NewHttpBinder(rpc.HttpBinderProperties{
 		Encoder: rpc.JsonEncoder{},
 		Logger:  RootLogger,
 		HandlerFactory: rpc.HttpHandlerFactoryFunc(func(factory rpc.EntityFactory, handler rpc.Handler) rpc.HttpMiddleware {
-			// TODO(stan): we may want to add some authentication mechanism
-			// to the private router
-			return rpc.NewHttpJsonHandler(rpc.HttpJsonHandlerProperties{
-				Limit:   config.BindPrivateConfig.MaxBodyBytes,
-				Handler: handler,
-				Logger:  RootLogger,
-				Factory: factory,
+			// the federation routes are exempt from the token because
+			// the forwarded requests are signed with the federation secret
+			return authcore.NewHttpMiddlewareToken(authcore.HttpMiddlewareTokenProps{
+				Token: config.BindPrivateConfig.AuthToken,
+				Exempt: map[string]bool{
+					mqfederation.InsertPath: true,
+					mqfederation.RemovePath: true,
+				},
+				Logger: RootLogger,
+				Next: rpc.NewHttpJsonHandler(rpc.HttpJsonHandlerProperties{
+					Limit:   config.BindPrivateConfig.MaxBodyBytes,
+					Handler: handler,
+					Logger:  RootLogger,
+					Factory: factory,
+				}),
 			})
 		}),
 	})
 
-	health.BindHandler(&health.Deps{Collector: services}, binder)
+	health.BindHandler(&health.Deps{Collector: group.Registry, Snapshots: group.Snapshots}, binder)
+	alias.BindHandler(alias.Services{Logger: RootLogger, Client: group.Request}, binder)
+	idempotency.BindHandler(idempotency.Services{Logger: RootLogger, Client: group.Request}, binder)
+	session.BindPrivateHandler(session.Services{Logger: RootLogger, Client: group.Request}, binder)
+
+	loggingapi.BindHandler(loggingapi.Services{Logger: RootLogger, Client: RootLevels}, binder)
+
+	if group.Features != nil {
+		featureapi.BindHandler(featureapi.Services{Logger: RootLogger, Client: group.Features}, binder)
+	}
+
+	if group.Maintenance != nil {
+		maintenanceapi.BindHandler(maintenanceapi.Services{
+			Logger: RootLogger,
+			Client: group.Request,
+			Mode:   group.Maintenance,
+		}, binder)
+	}
+
+	// only backends that can re-execute transactions
+	// expose the transaction traces
+	if tracer, ok := group.Backend.(backendcore.TransactionTracer); ok {
+		debug.BindHandler(debug.Services{Logger: RootLogger, Client: tracer}, binder)
+	}
+
+	if group.Usage != nil {
+		usageapi.BindHandler(usageapi.Services{Logger: RootLogger, Client: group.Usage}, binder)
+	}
+
+	if config.MailboxConfig.FederationConfig.Accept {
+		federation.BindHandler(federation.Services{
+			Logger: RootLogger,
+			Receiver: mqfederation.NewReceiver(mqfederation.ReceiverServices{
+				MQueue: group.Mailbox,
+			}, mqfederation.ReceiverProps{
+				Secret: []byte(config.MailboxConfig.FederationConfig.Secret),
+			}),
+		}, binder)
+	}
 
 	return binder.Build()
 }
 
-func NewPublicRouter(config *Config, group *ServiceGroup) *rpc.HttpRouter {
+// PublicRouteFeatures are the features the routes of the public
+// router belong to. Routes not listed are always served
+var PublicRouteFeatures = feature.RouteFeatures{
+	"/v0/api/service/deploy":       feature.Deploy,
+	"/v0/api/service/execute":      feature.Execute,
+	"/v0/api/service/executeBatch": feature.Execute,
+
+	"/v0/api/service/getCode":      feature.Query,
+	"/v0/api/service/getExpiry":    feature.Query,
+	"/v0/api/service/getPublicKey": feature.Query,
+	"/v0/api/service/query":        feature.Query,
+	"/v0/api/service/deployments":  feature.Query,
+
+	"/v0/api/event/subscribe":   feature.Subscribe,
+	"/v0/api/event/unsubscribe": feature.Subscribe,
+
+	"/v0/api/service/poll":      feature.Poll,
+	"/v0/api/service/getOutput": feature.Poll,
+	"/v0/api/event/poll":        feature.Poll,
+}
+
+// MaintenanceRoutes are the routes of the public router that are
+// served while the gateway is under maintenance, so that clients
+// can retrieve the events that are already queued
+var MaintenanceRoutes = []string{
+	"/v0/api/service/poll",
+	"/v0/api/service/getOutput",
+	"/v0/api/session/heartbeat",
+	"/v0/api/event/poll",
+}
+
+// PublicRouteScopes are the scopes a request needs to be granted
+// to access the routes of the public router. Routes not listed are
+// accessible to any authenticated request
+var PublicRouteScopes = authcore.RouteScopes{
+	"/v0/api/service/deploy":       authcore.ScopeServiceDeploy,
+	"/v0/api/service/execute":      authcore.ScopeServiceExecute,
+	"/v0/api/service/executeBatch": authcore.ScopeServiceExecute,
+	"/v0/api/service/poll":         authcore.ScopeEventPoll,
+	"/v0/api/service/getOutput":    authcore.ScopeEventPoll,
+	"/v0/api/event/poll":           authcore.ScopeEventPoll,
+	"/v0/api/event/subscribe":      authcore.ScopeSubscriptionManage,
+	"/v0/api/event/unsubscribe":    authcore.ScopeSubscriptionManage,
+	"/v0/api/event/token":          authcore.ScopeTokenIssue,
+
+	"/v0/api/service/getCode":      authcore.ScopeServiceRead,
+	"/v0/api/service/getExpiry":    authcore.ScopeServiceRead,
+	"/v0/api/service/getPublicKey": authcore.ScopeServiceRead,
+	"/v0/api/service/query":        authcore.ScopeServiceRead,
+	"/v0/api/service/deployments":  authcore.ScopeServiceRead,
+}
+
+func NewPublicRouter(config *Config, group *ServiceGroup) (*rpc.HttpRouter, error) {
+	sessions := config.AuthConfig.Session
+	if sessions.Mode != authcore.SessionModeDerived {
+		sessions.Bindings = authcore.NewSessionBindings(config.AuthConfig.Binding)
+		if sessions.Bindings.Enabled() {
+			if err := group.Registry.Register("SessionBindings", sessions.Bindings); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	lockout := authcore.NewLockout(config.AuthConfig.Lockout)
+	if lockout.Enabled() {
+		if err := group.Registry.Register("AuthLockout", lockout); err != nil {
+			return nil, err
+		}
+	}
+
+	replay := config.AuthConfig.ReplayGuard
+	if replay.Enabled() {
+		if err := group.Registry.Register("ReplayGuard", replay); err != nil {
+			return nil, err
+		}
+	}
+
+	objectives := slo.NewTracker(slo.Props{
+		Default: config.SLOConfig.Default,
+		Routes:  config.SLOConfig.Routes,
+		Slow:    config.SLOConfig.Slow,
+	})
+	if objectives.Enabled() {
+		if err := group.Registry.Register("SLO", objectives); err != nil {
+			return nil, err
+		}
+	}
+
 	binder := rpc.NewHttpBinder(rpc.HttpBinderProperties{
 		Encoder: rpc.JsonEncoder{},
 		Logger:  RootLogger,
@@ -202,7 +530,33 @@ func NewPublicRouter(config *Config, group *ServiceGroup) *rpc.HttpRouter {
 				Factory: factory,
 			})
 
-			return authcore.NewHttpMiddlewareAuth(group.Authenticator, RootLogger, jsonHandler)
+			var next rpc.HttpMiddleware = authcore.NewHttpMiddlewareAuthorize(PublicRouteScopes, RootLogger,
+				session.NewHttpMiddleware(group.Request, jsonHandler))
+			if group.Usage != nil {
+				next = usage.NewHttpMiddleware(group.Usage, next)
+			}
+
+			quotas := config.BackendConfig.QuotaConfig
+			if quotas.MaxInFlight > 0 || quotas.SpendLimit > 0 {
+				next = quota.NewHttpMiddleware(group.Request, RootLogger, next)
+			}
+
+			// the routes of disabled features are rejected before
+			// the request is authenticated, as if they were not served
+			next = feature.NewHttpMiddleware(group.Features, PublicRouteFeatures, RootLogger,
+				authcore.NewHttpMiddlewareAuthWithProps(authcore.HttpMiddlewareAuthProps{
+					Auth:     group.Authenticator,
+					Logger:   RootLogger,
+					Sessions: sessions,
+					Lockout:  lockout,
+					Replay:   replay,
+					Next:     next,
+				}))
+
+			// the latency is measured for all the requests, including
+			// the ones rejected by the other middlewares
+			return slo.NewHttpMiddleware(objectives, RootLogger,
+				maintenance.NewHttpMiddleware(group.Maintenance, MaintenanceRoutes, RootLogger, next))
 		}),
 	})
 
@@ -210,16 +564,41 @@ func NewPublicRouter(config *Config, group *ServiceGroup) *rpc.HttpRouter {
 		binder.AddPreProcessor(rpc.NewHttpCorsPreProcessor(config.BindPublicConfig.HttpCorsPreProcessorProps))
 	}
 
-	service.BindHandler(service.Services{
+	services := service.Services{
 		Logger:   RootLogger,
 		Client:   group.Request,
 		Verifier: group.Authenticator,
-	}, binder)
-	event.BindHandler(event.Services{
+		WASM: service.WASMProps{
+			Enabled:  config.BackendConfig.WASMConfig.Enabled,
+			MaxSize:  config.BackendConfig.WASMConfig.MaxSize,
+			Validate: config.BackendConfig.WASMConfig.Validate,
+		},
+		Poll: service.PollProps{
+			DefaultCount: config.BackendConfig.PollConfig.DefaultCount,
+			MaxCount:     config.BackendConfig.PollConfig.MaxCount,
+		},
+	}
+	events := event.Services{
 		Logger: RootLogger,
 		Client: group.Request,
-	}, binder)
+		Poll: event.PollProps{
+			DefaultCount: config.BackendConfig.PollConfig.DefaultCount,
+			MaxCount:     config.BackendConfig.PollConfig.MaxCount,
+		},
+	}
+	if config.AuthConfig.Capabilities != nil {
+		events.Tokens = config.AuthConfig.Capabilities
+	}
+	event.BindHandler(events, binder)
+
+	if config.RoleConfig.Role == RoleReplica {
+		service.BindPollHandler(services, binder)
+		return binder.Build(), nil
+	}
+
+	service.BindHandler(services, binder)
 	info.BindHandler(info.Services{Logger: RootLogger, Client: group.Request}, binder)
+	session.BindHandler(session.Services{Logger: RootLogger, Client: group.Request}, binder)
 
-	return binder.Build()
+	return binder.Build(), nil
 }
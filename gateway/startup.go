@@ -0,0 +1,170 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/oasislabs/oasis-gateway/auth"
+	"github.com/oasislabs/oasis-gateway/backend"
+	"github.com/oasislabs/oasis-gateway/concurrent"
+	"github.com/oasislabs/oasis-gateway/eth"
+	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/oasislabs/oasis-gateway/mqueue"
+	"github.com/oasislabs/oasis-gateway/usage"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// StartupConfig defines how the gateway waits for
+// its dependencies to be available when it starts
+type StartupConfig struct {
+	// WaitTimeout is the maximum time the gateway waits for its
+	// dependencies. If 0 the dependencies are not waited for
+	WaitTimeout time.Duration
+
+	// MaxRetryInterval is the maximum time between two attempts
+	// to reach a dependency
+	MaxRetryInterval time.Duration
+}
+
+func (c *StartupConfig) Log(fields log.Fields) {
+	fields.Add("startup.wait_timeout_ms", int64(c.WaitTimeout/time.Millisecond))
+	fields.Add("startup.max_retry_interval_ms", int64(c.MaxRetryInterval/time.Millisecond))
+}
+
+func (c *StartupConfig) Configure(v *viper.Viper) error {
+	timeout := v.GetInt64("startup.wait_timeout_ms")
+	if timeout < 0 {
+		return errors.New("startup.wait_timeout_ms cannot be negative")
+	}
+	c.WaitTimeout = time.Duration(timeout) * time.Millisecond
+
+	interval := v.GetInt64("startup.max_retry_interval_ms")
+	if interval <= 0 {
+		return errors.New("startup.max_retry_interval_ms must be positive")
+	}
+	c.MaxRetryInterval = time.Duration(interval) * time.Millisecond
+
+	return nil
+}
+
+func (c *StartupConfig) Bind(v *viper.Viper, cmd *cobra.Command) error {
+	cmd.PersistentFlags().Int64("startup.wait_timeout_ms", 0,
+		"maximum time in milliseconds the gateway waits at startup for redis and the "+
+			"backend node to be reachable before it exits. If 0 the gateway does not wait.")
+	cmd.PersistentFlags().Int64("startup.max_retry_interval_ms", 5000,
+		"maximum time in milliseconds between two attempts to reach a dependency at startup.")
+	return nil
+}
+
+// Dependency is an external service that the
+// gateway needs to be reachable to start
+type Dependency struct {
+	// Name identifies the dependency in the logs
+	Name string
+
+	// Check returns an error if the dependency is not available
+	Check func(ctx context.Context) error
+}
+
+// pingRedis checks that the redis instance or cluster
+// seeds at the addresses are reachable
+func pingRedis(addrs []string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		client := redis.NewUniversalClient(&redis.UniversalOptions{Addrs: addrs})
+		defer client.Close()
+		return client.Ping().Err()
+	}
+}
+
+// Dependencies returns the dependencies of the gateway
+// with the configuration
+func Dependencies(config *Config) []Dependency {
+	var deps []Dependency
+
+	switch c := config.MailboxConfig.MailboxConfig.(type) {
+	case *mqueue.MailboxRedisSingleConfig:
+		deps = append(deps, Dependency{Name: "mailbox.redis_single", Check: pingRedis([]string{c.Addr})})
+	case *mqueue.MailboxRedisClusterConfig:
+		deps = append(deps, Dependency{Name: "mailbox.redis_cluster", Check: pingRedis(c.Addrs)})
+	}
+
+	if config.UsageConfig.Provider == usage.ProviderRedis {
+		deps = append(deps, Dependency{Name: "usage.redis", Check: pingRedis(config.UsageConfig.RedisAddrs)})
+	}
+
+	if config.AuthConfig.Replay.Window > 0 && config.AuthConfig.Replay.Provider == auth.ReplayProviderRedis {
+		deps = append(deps, Dependency{Name: "auth.replay.redis", Check: pingRedis(config.AuthConfig.Replay.RedisAddrs)})
+	}
+
+	if c, ok := config.BackendConfig.BackendConfig.(*backend.EthereumConfig); ok {
+		deps = append(deps, Dependency{Name: "eth", Check: func(ctx context.Context) error {
+			return eth.Ping(ctx, c.URL, c.Proxy)
+		}})
+	}
+
+	return deps
+}
+
+// WaitForDependencies waits until all the dependencies of the gateway
+// are reachable, so that the gateway does not depend on the order in
+// which it is started with respect to them
+func WaitForDependencies(ctx context.Context, config *Config) error {
+	return WaitFor(ctx, &config.StartupConfig, Dependencies(config))
+}
+
+// WaitFor checks each dependency with exponential backoff until it is
+// available. It fails if a dependency is not available before the wait
+// timeout. If the wait timeout is 0 it returns immediately
+func WaitFor(ctx context.Context, config *StartupConfig, deps []Dependency) error {
+	if config.WaitTimeout == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, config.WaitTimeout)
+	defer cancel()
+
+	for _, dep := range deps {
+		var lastErr error
+		attempts := 0
+		_, err := concurrent.RetryWithConfig(ctx, concurrent.SupplierFunc(func() (interface{}, error) {
+			attempts++
+			if err := dep.Check(ctx); err != nil {
+				lastErr = err
+				RootLogger.Warn(ctx, "dependency not available", log.MapFields{
+					"call_type":  "DependencyWaitFailure",
+					"dependency": dep.Name,
+					"attempts":   attempts,
+					"err":        err.Error(),
+				})
+				return nil, err
+			}
+
+			return nil, nil
+		}), concurrent.RetryConfig{
+			Random:            true,
+			UnlimitedAttempts: true,
+			BaseExp:           2,
+			BaseTimeout:       100 * time.Millisecond,
+			MaxRetryTimeout:   config.MaxRetryInterval,
+		})
+		if err != nil {
+			if lastErr == nil {
+				lastErr = err
+			}
+			return fmt.Errorf("dependency %s not available after %s: %s",
+				dep.Name, config.WaitTimeout, lastErr.Error())
+		}
+
+		RootLogger.Info(ctx, "dependency available", log.MapFields{
+			"call_type":  "DependencyWaitSuccess",
+			"dependency": dep.Name,
+			"attempts":   attempts,
+		})
+	}
+
+	return nil
+}
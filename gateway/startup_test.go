@@ -0,0 +1,60 @@
+package gateway
+
+import (
+	"context"
+	stderr "errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWaitForDisabled(t *testing.T) {
+	called := false
+	err := WaitFor(context.Background(), &StartupConfig{}, []Dependency{{
+		Name: "dep",
+		Check: func(ctx context.Context) error {
+			called = true
+			return stderr.New("unavailable")
+		},
+	}})
+
+	assert.Nil(t, err)
+	assert.False(t, called)
+}
+
+func TestWaitForRetries(t *testing.T) {
+	attempts := 0
+	err := WaitFor(context.Background(), &StartupConfig{
+		WaitTimeout:      time.Second,
+		MaxRetryInterval: time.Millisecond,
+	}, []Dependency{{
+		Name: "dep",
+		Check: func(ctx context.Context) error {
+			attempts++
+			if attempts < 3 {
+				return stderr.New("unavailable")
+			}
+			return nil
+		},
+	}})
+
+	assert.Nil(t, err)
+	assert.Equal(t, 3, attempts)
+}
+
+func TestWaitForTimeout(t *testing.T) {
+	err := WaitFor(context.Background(), &StartupConfig{
+		WaitTimeout:      50 * time.Millisecond,
+		MaxRetryInterval: 10 * time.Millisecond,
+	}, []Dependency{{
+		Name: "dep",
+		Check: func(ctx context.Context) error {
+			return stderr.New("unavailable")
+		},
+	}})
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "dependency dep not available")
+	assert.Contains(t, err.Error(), "unavailable")
+}
@@ -8,6 +8,7 @@ type ContextKey string
 
 const (
 	ContextKeyTraceID ContextKey = "logContextKeyTraceID"
+	ContextKeyFields  ContextKey = "logContextKeyFields"
 )
 
 func PutTraceID(ctx context.Context, traceID int64) context.Context {
@@ -27,3 +28,24 @@ func GetTraceID(ctx context.Context) int64 {
 
 	return traceID
 }
+
+// PutFields attaches fields to the context that are added to
+// every log entry written with the context. The fields already
+// attached to the context are kept unless they are overwritten
+func PutFields(ctx context.Context, fields map[string]string) context.Context {
+	current := GetFields(ctx)
+	merged := make(map[string]string, len(current)+len(fields))
+	for key, value := range current {
+		merged[key] = value
+	}
+	for key, value := range fields {
+		merged[key] = value
+	}
+
+	return context.WithValue(ctx, ContextKeyFields, merged)
+}
+
+func GetFields(ctx context.Context) map[string]string {
+	fields, _ := ctx.Value(ContextKeyFields).(map[string]string)
+	return fields
+}
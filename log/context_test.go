@@ -23,3 +23,18 @@ func TestGetTraceIDInteger(t *testing.T) {
 	traceID := GetTraceID(ctx)
 	assert.Equal(t, int64(1234), traceID)
 }
+
+func TestGetFieldsNotFound(t *testing.T) {
+	assert.Nil(t, GetFields(context.Background()))
+}
+
+func TestPutFields(t *testing.T) {
+	ctx := PutFields(context.Background(), map[string]string{"session.app": "app"})
+	assert.Equal(t, map[string]string{"session.app": "app"}, GetFields(ctx))
+}
+
+func TestPutFieldsMerge(t *testing.T) {
+	ctx := PutFields(context.Background(), map[string]string{"a": "1", "b": "1"})
+	ctx = PutFields(ctx, map[string]string{"b": "2", "c": "2"})
+	assert.Equal(t, map[string]string{"a": "1", "b": "2", "c": "2"}, GetFields(ctx))
+}
@@ -0,0 +1,140 @@
+package log
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Component returns the name of the component of a logger created
+// with ForClass, which is the pkg and the class joined by a slash
+func Component(pkg, class string) string {
+	return pkg + "/" + class
+}
+
+// Override is a level set for a component and its descendants
+type Override struct {
+	Component string
+	Level     logrus.Level
+}
+
+// ParseOverride parses an override in the form component=level,
+// e.g. mqueue/redis=warn
+func ParseOverride(s string) (Override, error) {
+	parts := strings.SplitN(s, "=", 2)
+	if len(parts) != 2 || len(strings.Trim(parts[0], "/")) == 0 {
+		return Override{}, fmt.Errorf("override %s is not in the form component=level", s)
+	}
+
+	level, err := logrus.ParseLevel(parts[1])
+	if err != nil {
+		return Override{}, err
+	}
+
+	return Override{Component: strings.Trim(parts[0], "/"), Level: level}, nil
+}
+
+// Levels keeps the minimum level of the entries logged by each
+// component. The components form a hierarchy by their names, so
+// mqueue/redis/MQueue logs at the level set for mqueue/redis if
+// there is one, otherwise at the level set for mqueue, otherwise
+// at the default level. Levels can be changed at runtime. Fatal
+// entries are logged regardless of the level
+type Levels struct {
+	lock      sync.RWMutex
+	level     logrus.Level
+	overrides map[string]logrus.Level
+}
+
+// NewLevels creates a new set of levels with the default level
+// and the provided overrides
+func NewLevels(level logrus.Level, overrides ...Override) *Levels {
+	levels := &Levels{level: level, overrides: make(map[string]logrus.Level)}
+	for _, o := range overrides {
+		levels.overrides[o.Component] = o.Level
+	}
+
+	return levels
+}
+
+// Default returns the level of the components without an override
+func (l *Levels) Default() logrus.Level {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+
+	return l.level
+}
+
+// SetDefault sets the level of the components without an override
+func (l *Levels) SetDefault(level logrus.Level) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.level = level
+}
+
+// Set overrides the level of the component and its descendants
+func (l *Levels) Set(component string, level logrus.Level) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.overrides[strings.Trim(component, "/")] = level
+}
+
+// Unset removes the override of the component, so that it logs at
+// the level of its closest ancestor. It returns false if the
+// component did not have an override
+func (l *Levels) Unset(component string) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	component = strings.Trim(component, "/")
+	_, ok := l.overrides[component]
+	delete(l.overrides, component)
+	return ok
+}
+
+// Overrides returns the overrides sorted by component
+func (l *Levels) Overrides() []Override {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+
+	overrides := make([]Override, 0, len(l.overrides))
+	for component, level := range l.overrides {
+		overrides = append(overrides, Override{Component: component, Level: level})
+	}
+
+	sort.Slice(overrides, func(i, j int) bool {
+		return overrides[i].Component < overrides[j].Component
+	})
+	return overrides
+}
+
+// Level returns the level the component logs at
+func (l *Levels) Level(component string) logrus.Level {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+
+	for len(component) > 0 {
+		if level, ok := l.overrides[component]; ok {
+			return level
+		}
+
+		i := strings.LastIndex(component, "/")
+		if i < 0 {
+			break
+		}
+		component = component[:i]
+	}
+
+	return l.level
+}
+
+// Enabled returns true if the entries of the component at the
+// provided level are logged
+func (l *Levels) Enabled(component string, level logrus.Level) bool {
+	return level <= l.Level(component)
+}
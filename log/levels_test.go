@@ -0,0 +1,72 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseOverride(t *testing.T) {
+	o, err := ParseOverride("mqueue/redis=warn")
+	assert.Nil(t, err)
+	assert.Equal(t, Override{Component: "mqueue/redis", Level: logrus.WarnLevel}, o)
+
+	_, err = ParseOverride("mqueue/redis")
+	assert.Error(t, err)
+
+	_, err = ParseOverride("=warn")
+	assert.Error(t, err)
+
+	_, err = ParseOverride("mqueue=loud")
+	assert.Error(t, err)
+}
+
+func TestLevelsHierarchy(t *testing.T) {
+	levels := NewLevels(logrus.InfoLevel,
+		Override{Component: "mqueue", Level: logrus.ErrorLevel},
+		Override{Component: "mqueue/redis", Level: logrus.WarnLevel})
+
+	assert.Equal(t, logrus.WarnLevel, levels.Level("mqueue/redis/MQueue"))
+	assert.Equal(t, logrus.ErrorLevel, levels.Level("mqueue/Compaction"))
+	assert.Equal(t, logrus.InfoLevel, levels.Level("mqueueredis/MQueue"))
+	assert.Equal(t, logrus.InfoLevel, levels.Level(""))
+
+	assert.True(t, levels.Unset("mqueue/redis"))
+	assert.False(t, levels.Unset("mqueue/redis"))
+	assert.Equal(t, logrus.ErrorLevel, levels.Level("mqueue/redis/MQueue"))
+
+	levels.SetDefault(logrus.DebugLevel)
+	levels.Set("eth", logrus.WarnLevel)
+	assert.Equal(t, logrus.DebugLevel, levels.Level("tx/wallet/Executor"))
+	assert.Equal(t, []Override{
+		{Component: "eth", Level: logrus.WarnLevel},
+		{Component: "mqueue", Level: logrus.ErrorLevel},
+	}, levels.Overrides())
+}
+
+func TestLoggerComponentLevels(t *testing.T) {
+	buffer := bytes.NewBufferString("")
+	levels := NewLevels(logrus.DebugLevel)
+	logger := NewLogrus(LogrusLoggerProperties{
+		Output:    buffer,
+		Formatter: &logrus.JSONFormatter{TimestampFormat: "none"},
+		Levels:    levels,
+	})
+	redis := logger.ForClass("mqueue/redis", "MQueue")
+	executor := logger.ForClass("tx/wallet", "Executor")
+
+	levels.Set("mqueue/redis", logrus.WarnLevel)
+
+	redis.Info(context.Background(), "silenced")
+	assert.Equal(t, "", buffer.String())
+
+	executor.Debug(context.Background(), "logged")
+	assert.Contains(t, buffer.String(), "\"msg\":\"logged\"")
+	buffer.Reset()
+
+	redis.Warn(context.Background(), "logged")
+	assert.Contains(t, buffer.String(), "\"msg\":\"logged\"")
+}
@@ -12,28 +12,49 @@ type LogrusLoggerProperties struct {
 	Formatter logrus.Formatter
 	Level     logrus.Level
 	Output    io.Writer
+
+	// Schema defines the layout of the fields of the entries.
+	// If not set SchemaLegacy is used
+	Schema Schema
+
+	// Levels holds the level of each component, which can be
+	// changed while the logger is in use. If not set all the
+	// components log at Level
+	Levels *Levels
 }
 
 type LogrusLogger struct {
-	root *logrus.Logger
-	log  *logrus.Logger
+	root   *logrus.Logger
+	log    *logrus.Logger
+	levels *Levels
 }
 
 type LogrusEntry struct {
-	root  *logrus.Logger
-	entry *logrus.Entry
+	root      *logrus.Logger
+	entry     *logrus.Entry
+	levels    *Levels
+	component string
 }
 
 func NewLogrus(properties LogrusLoggerProperties) Logger {
 	log := logrus.New()
 
-	if properties.Formatter == nil {
-		log.SetFormatter(&logrus.JSONFormatter{})
-	} else {
-		log.SetFormatter(properties.Formatter)
+	formatter := properties.Formatter
+	if formatter == nil {
+		formatter = &logrus.JSONFormatter{}
+	}
+	if properties.Schema == SchemaV1 {
+		formatter = NewSchemaFormatter(formatter)
 	}
+	log.SetFormatter(formatter)
 
-	log.SetLevel(properties.Level)
+	// the level of each entry is checked against the levels of
+	// its component, so logrus logs all the entries it is handed
+	levels := properties.Levels
+	if levels == nil {
+		levels = NewLevels(properties.Level)
+	}
+	log.SetLevel(logrus.TraceLevel)
 
 	if properties.Output == nil {
 		log.SetOutput(os.Stdout)
@@ -41,7 +62,7 @@ func NewLogrus(properties LogrusLoggerProperties) Logger {
 		log.SetOutput(properties.Output)
 	}
 
-	return LogrusLogger{root: log, log: log}
+	return LogrusLogger{root: log, log: log, levels: levels}
 }
 
 func (l LogrusLogger) ForClass(pkg string, class string) Logger {
@@ -51,25 +72,39 @@ func (l LogrusLogger) ForClass(pkg string, class string) Logger {
 			"pkg":   pkg,
 			"class": class,
 		}),
+		levels:    l.levels,
+		component: Component(pkg, class),
 	}
 }
 
 func (l LogrusLogger) Debug(ctx context.Context, msg string, loggables ...Loggable) {
+	if !l.levels.Enabled("", logrus.DebugLevel) {
+		return
+	}
 	fields := logrusMakeFields(ctx, loggables...)
 	l.log.WithFields(fields).Debug(msg)
 }
 
 func (l LogrusLogger) Info(ctx context.Context, msg string, loggables ...Loggable) {
+	if !l.levels.Enabled("", logrus.InfoLevel) {
+		return
+	}
 	fields := logrusMakeFields(ctx, loggables...)
 	l.log.WithFields(fields).Info(msg)
 }
 
 func (l LogrusLogger) Warn(ctx context.Context, msg string, loggables ...Loggable) {
+	if !l.levels.Enabled("", logrus.WarnLevel) {
+		return
+	}
 	fields := logrusMakeFields(ctx, loggables...)
 	l.log.WithFields(fields).Warn(msg)
 }
 
 func (l LogrusLogger) Error(ctx context.Context, msg string, loggables ...Loggable) {
+	if !l.levels.Enabled("", logrus.ErrorLevel) {
+		return
+	}
 	fields := logrusMakeFields(ctx, loggables...)
 	l.log.WithFields(fields).Error(msg)
 }
@@ -90,25 +125,39 @@ func (e LogrusEntry) ForClass(pkg string, class string) Logger {
 			"pkg":   pkg,
 			"class": class,
 		}),
+		levels:    e.levels,
+		component: Component(pkg, class),
 	}
 }
 
 func (e LogrusEntry) Debug(ctx context.Context, msg string, loggables ...Loggable) {
+	if !e.levels.Enabled(e.component, logrus.DebugLevel) {
+		return
+	}
 	fields := logrusMakeFields(ctx, loggables...)
 	e.entry.WithFields(fields).Debug(msg)
 }
 
 func (e LogrusEntry) Info(ctx context.Context, msg string, loggables ...Loggable) {
+	if !e.levels.Enabled(e.component, logrus.InfoLevel) {
+		return
+	}
 	fields := logrusMakeFields(ctx, loggables...)
 	e.entry.WithFields(fields).Info(msg)
 }
 
 func (e LogrusEntry) Warn(ctx context.Context, msg string, loggables ...Loggable) {
+	if !e.levels.Enabled(e.component, logrus.WarnLevel) {
+		return
+	}
 	fields := logrusMakeFields(ctx, loggables...)
 	e.entry.WithFields(fields).Warn(msg)
 }
 
 func (e LogrusEntry) Error(ctx context.Context, msg string, loggables ...Loggable) {
+	if !e.levels.Enabled(e.component, logrus.ErrorLevel) {
+		return
+	}
 	fields := logrusMakeFields(ctx, loggables...)
 	e.entry.WithFields(fields).Error(msg)
 }
@@ -125,6 +174,10 @@ func (e LogrusEntry) SetOutput(w io.Writer) {
 func logrusMakeFields(ctx context.Context, loggables ...Loggable) logrus.Fields {
 	fields := LogrusFields{logrus.Fields{}}
 
+	for key, value := range GetFields(ctx) {
+		fields.Add(key, value)
+	}
+
 	for _, loggable := range loggables {
 		loggable.Log(&fields)
 	}
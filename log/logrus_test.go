@@ -93,3 +93,29 @@ func TestLoggerEntryLevels(t *testing.T) {
 		"\"traceId\":1234"+
 		"}\n", string(p))
 }
+
+func TestLoggerContextFields(t *testing.T) {
+	ctx := PutFields(context.Background(), map[string]string{"potato": "baked", "session.app": "app"})
+	buffer := bytes.NewBufferString("")
+
+	logger := NewLogrus(LogrusLoggerProperties{
+		Level:     logrus.InfoLevel,
+		Output:    buffer,
+		Formatter: &logrus.JSONFormatter{TimestampFormat: "none"},
+	})
+
+	// the fields provided explicitly take precedence over the
+	// fields of the context
+	logger.Info(ctx, "some message", MapFields{"potato": "fried"})
+	p, err := ioutil.ReadAll(buffer)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "{"+
+		"\"level\":\"info\","+
+		"\"msg\":\"some message\","+
+		"\"potato\":\"fried\","+
+		"\"session.app\":\"app\","+
+		"\"time\":\"none\","+
+		"\"traceId\":-1"+
+		"}\n", string(p))
+}
@@ -0,0 +1,150 @@
+package log
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Schema defines the layout of the structured log entries
+type Schema string
+
+const (
+	// SchemaLegacy writes the fields of an entry at the top level
+	// with the keys they are logged with. The keys may change
+	// between releases of the gateway
+	SchemaLegacy Schema = "legacy"
+
+	// SchemaV1 writes the fields of SchemaFields at the top level
+	// with stable keys and types, along with the schema_version,
+	// and nests all the other fields under SchemaKeyFields
+	SchemaV1 Schema = "v1"
+)
+
+func (s Schema) String() string {
+	return string(s)
+}
+
+// SchemaVersion is the version of the schema written by SchemaV1.
+// It is incremented whenever a field of SchemaFields changes
+const SchemaVersion = 1
+
+const (
+	// SchemaKeyVersion is the key of the version of the schema
+	SchemaKeyVersion = "schema_version"
+
+	// SchemaKeyFields is the key of the object that holds the
+	// fields that are not part of the schema
+	SchemaKeyFields = "fields"
+)
+
+// SchemaFieldType is the JSON type of a field of the schema
+type SchemaFieldType string
+
+const (
+	SchemaFieldString  SchemaFieldType = "string"
+	SchemaFieldInteger SchemaFieldType = "integer"
+)
+
+// SchemaField is a field written at the top level of the entries
+// of SchemaV1. Its key and type do not change within a version
+type SchemaField struct {
+	Key  string
+	Type SchemaFieldType
+}
+
+// SchemaFields are the fields of SchemaVersion, besides the
+// time, level and msg fields written by the formatter
+var SchemaFields = []SchemaField{
+	{Key: "pkg", Type: SchemaFieldString},
+	{Key: "class", Type: SchemaFieldString},
+	{Key: "traceId", Type: SchemaFieldInteger},
+	{Key: "call_type", Type: SchemaFieldString},
+	{Key: "err", Type: SchemaFieldString},
+	{Key: "errorCode", Type: SchemaFieldInteger},
+}
+
+// SchemaAliases map the keys some fields are logged with to the key
+// of the schema field they are written as, so that the code can
+// change the keys it logs without changing the schema
+var SchemaAliases = map[string]string{
+	"callType": "call_type",
+}
+
+// SchemaFormatter is a logrus.Formatter that lays out the fields
+// of the entries according to SchemaV1 before they are formatted
+type SchemaFormatter struct {
+	Formatter logrus.Formatter
+}
+
+// NewSchemaFormatter creates a formatter that formats the entries
+// laid out according to SchemaV1 with the provided formatter
+func NewSchemaFormatter(formatter logrus.Formatter) *SchemaFormatter {
+	return &SchemaFormatter{Formatter: formatter}
+}
+
+// Format is the implementation of logrus.Formatter
+func (f *SchemaFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	schemaEntry := *entry
+	schemaEntry.Data = schemaData(entry.Data)
+	return f.Formatter.Format(&schemaEntry)
+}
+
+// schemaData lays out the fields of an entry according to SchemaV1.
+// A field whose value does not have the type of the schema field is
+// nested with the other fields rather than breaking the schema
+func schemaData(data logrus.Fields) logrus.Fields {
+	fields := make(logrus.Fields, len(data))
+	for key, value := range data {
+		if alias, ok := SchemaAliases[key]; ok {
+			if _, exists := data[alias]; !exists {
+				key = alias
+			}
+		}
+		fields[key] = value
+	}
+
+	res := logrus.Fields{SchemaKeyVersion: SchemaVersion}
+	for _, field := range SchemaFields {
+		value, ok := fields[field.Key]
+		if !ok {
+			continue
+		}
+
+		if value, ok := schemaValue(field.Type, value); ok {
+			res[field.Key] = value
+			delete(fields, field.Key)
+		}
+	}
+
+	if len(fields) > 0 {
+		res[SchemaKeyFields] = fields
+	}
+
+	return res
+}
+
+func schemaValue(t SchemaFieldType, value interface{}) (interface{}, bool) {
+	switch t {
+	case SchemaFieldString:
+		switch v := value.(type) {
+		case string:
+			return v, true
+		case error:
+			return v.Error(), true
+		case fmt.Stringer:
+			return v.String(), true
+		default:
+			return nil, false
+		}
+	case SchemaFieldInteger:
+		switch value.(type) {
+		case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+			return value, true
+		default:
+			return nil, false
+		}
+	default:
+		return nil, false
+	}
+}
@@ -0,0 +1,76 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	stderr "errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoggerSchemaV1(t *testing.T) {
+	ctx := PutTraceID(context.Background(), int64(1234))
+	buffer := bytes.NewBufferString("")
+
+	logger := NewLogrus(LogrusLoggerProperties{
+		Level:     logrus.InfoLevel,
+		Output:    buffer,
+		Formatter: &logrus.JSONFormatter{TimestampFormat: "none"},
+		Schema:    SchemaV1,
+	}).ForClass("example", "MyStruct")
+
+	logger.Info(ctx, "some message", MapFields{
+		"call_type": "SomethingSuccess",
+		"err":       stderr.New("failure"),
+		"potato":    "fried",
+	})
+	p, err := ioutil.ReadAll(buffer)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "{"+
+		"\"call_type\":\"SomethingSuccess\","+
+		"\"class\":\"MyStruct\","+
+		"\"err\":\"failure\","+
+		"\"fields\":{\"potato\":\"fried\"},"+
+		"\"level\":\"info\","+
+		"\"msg\":\"some message\","+
+		"\"pkg\":\"example\","+
+		"\"schema_version\":1,"+
+		"\"time\":\"none\","+
+		"\"traceId\":1234"+
+		"}\n", string(p))
+}
+
+func TestLoggerSchemaV1Aliases(t *testing.T) {
+	buffer := bytes.NewBufferString("")
+
+	logger := NewLogrus(LogrusLoggerProperties{
+		Level:     logrus.InfoLevel,
+		Output:    buffer,
+		Formatter: &logrus.JSONFormatter{TimestampFormat: "none"},
+		Schema:    SchemaV1,
+	})
+
+	// fields logged with an alias are written with the key of the
+	// schema, and fields with the wrong type are not part of it
+	logger.Info(context.Background(), "some message", MapFields{
+		"callType":  "SomethingSuccess",
+		"errorCode": "1000",
+		"msg":       "overwritten",
+	})
+	p, err := ioutil.ReadAll(buffer)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "{"+
+		"\"call_type\":\"SomethingSuccess\","+
+		"\"fields\":{\"errorCode\":\"1000\",\"msg\":\"overwritten\"},"+
+		"\"level\":\"info\","+
+		"\"msg\":\"some message\","+
+		"\"schema_version\":1,"+
+		"\"time\":\"none\","+
+		"\"traceId\":-1"+
+		"}\n", string(p))
+}
@@ -0,0 +1,73 @@
+package maintenance
+
+import (
+	"sync"
+	"time"
+)
+
+// Notice is the information given to the clients while
+// the gateway is under maintenance
+type Notice struct {
+	// Message is the notice of the operator for the clients
+	Message string
+
+	// RetryAfter is the time after which the clients are
+	// advised to retry their requests
+	RetryAfter time.Duration
+
+	// Since is the time at which the maintenance started
+	Since time.Time
+}
+
+// Mode keeps whether the gateway is under maintenance. While under
+// maintenance the public API only serves the polling of events that
+// were already queued.
+//
+// A nil Mode is valid and is never under maintenance
+type Mode struct {
+	lock   sync.RWMutex
+	active bool
+	notice Notice
+}
+
+// NewMode creates a new Mode that is not under maintenance
+func NewMode() *Mode {
+	return &Mode{}
+}
+
+// Enable puts the gateway under maintenance with the notice. If
+// the notice does not have a start time the current time is used
+func (m *Mode) Enable(notice Notice) Notice {
+	if notice.Since.IsZero() {
+		notice.Since = time.Now()
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.active = true
+	m.notice = notice
+	return notice
+}
+
+// Disable ends the maintenance
+func (m *Mode) Disable() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.active = false
+	m.notice = Notice{}
+}
+
+// Status returns the notice of the current maintenance and
+// true if the gateway is under maintenance
+func (m *Mode) Status() (Notice, bool) {
+	if m == nil {
+		return Notice{}, false
+	}
+
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	return m.notice, m.active
+}
@@ -0,0 +1,68 @@
+package maintenance
+
+import (
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/oasislabs/oasis-gateway/rpc"
+	"github.com/stretchr/testify/assert"
+)
+
+var Logger = log.NewLogrus(log.LogrusLoggerProperties{
+	Output: ioutil.Discard,
+})
+
+func TestModeNil(t *testing.T) {
+	var mode *Mode
+	_, active := mode.Status()
+	assert.False(t, active)
+}
+
+func TestModeEnableDisable(t *testing.T) {
+	mode := NewMode()
+	since := time.Unix(1000, 0)
+
+	mode.Enable(Notice{Message: "upgrade", RetryAfter: time.Minute, Since: since})
+	notice, active := mode.Status()
+	assert.True(t, active)
+	assert.Equal(t, Notice{Message: "upgrade", RetryAfter: time.Minute, Since: since}, notice)
+
+	mode.Disable()
+	_, active = mode.Status()
+	assert.False(t, active)
+}
+
+func TestHttpMiddleware(t *testing.T) {
+	mode := NewMode()
+	handler := NewHttpMiddleware(mode, []string{"/poll"}, Logger,
+		rpc.HttpMiddlewareFunc(func(req *http.Request) (interface{}, error) {
+			return 0, nil
+		}))
+
+	req, err := http.NewRequest("POST", "/execute", nil)
+	assert.Nil(t, err)
+
+	res, err := handler.ServeHTTP(req)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, res)
+
+	mode.Enable(Notice{Message: "upgrade", RetryAfter: time.Minute, Since: time.Unix(1000, 0)})
+
+	res, err = handler.ServeHTTP(req)
+	assert.Nil(t, res)
+	httpErr := err.(*rpc.HttpError)
+	assert.Equal(t, http.StatusServiceUnavailable, httpErr.StatusCode)
+	assert.Equal(t, "60", httpErr.Header.Get("Retry-After"))
+	assert.Equal(t, Details{Message: "upgrade", RetryAfter: 60, Since: 1000}, httpErr.Details)
+
+	// polling is still served so that clients can retrieve their events
+	req, err = http.NewRequest("POST", "/poll", nil)
+	assert.Nil(t, err)
+
+	res, err = handler.ServeHTTP(req)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, res)
+}
@@ -0,0 +1,87 @@
+package maintenance
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/oasislabs/oasis-gateway/errors"
+	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/oasislabs/oasis-gateway/rpc"
+)
+
+// Details are the details of the error returned to the
+// requests rejected while under maintenance
+type Details struct {
+	// Message is the notice of the operator for the clients
+	Message string `json:"message"`
+
+	// RetryAfter is the time in seconds after which the
+	// clients are advised to retry their requests
+	RetryAfter uint64 `json:"retryAfter"`
+
+	// Since is the time in seconds since epoch at which
+	// the maintenance started
+	Since int64 `json:"since"`
+}
+
+// HttpMiddleware rejects the requests received while the gateway is
+// under maintenance with a 503, except for the routes that are still
+// served so that clients can retrieve the events already queued
+type HttpMiddleware struct {
+	mode    *Mode
+	allowed map[string]bool
+	logger  log.Logger
+	next    rpc.HttpMiddleware
+}
+
+// NewHttpMiddleware creates a new HttpMiddleware. The allowed
+// routes are served while under maintenance
+func NewHttpMiddleware(mode *Mode, allowed []string, logger log.Logger, next rpc.HttpMiddleware) *HttpMiddleware {
+	if logger == nil {
+		panic("log must be set")
+	}
+
+	if next == nil {
+		panic("next must be set")
+	}
+
+	routes := make(map[string]bool, len(allowed))
+	for _, path := range allowed {
+		routes[path] = true
+	}
+
+	return &HttpMiddleware{
+		mode:    mode,
+		allowed: routes,
+		logger:  logger.ForClass("maintenance", "HttpMiddleware"),
+		next:    next,
+	}
+}
+
+// ServeHTTP is the implementation of rpc.HttpMiddleware for HttpMiddleware
+func (m *HttpMiddleware) ServeHTTP(req *http.Request) (interface{}, error) {
+	notice, active := m.mode.Status()
+	if !active || m.allowed[req.URL.Path] {
+		return m.next.ServeHTTP(req)
+	}
+
+	retryAfter := uint64(notice.RetryAfter / time.Second)
+	err := errors.New(errors.ErrUnderMaintenance, nil)
+	m.logger.Debug(req.Context(), "request received under maintenance", log.MapFields{
+		"call_type": "UnderMaintenanceFailure",
+		"path":      req.URL.Path,
+	}, err)
+
+	httpErr := rpc.HttpServiceUnavailable(req.Context(), err)
+	httpErr.Details = Details{
+		Message:    notice.Message,
+		RetryAfter: retryAfter,
+		Since:      notice.Since.Unix(),
+	}
+	if retryAfter > 0 {
+		httpErr.Header = http.Header{"Retry-After": []string{strconv.FormatUint(retryAfter, 10)}}
+	}
+
+	return nil, httpErr
+}
@@ -0,0 +1,95 @@
+package mqueue
+
+import (
+	"context"
+	"time"
+
+	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/oasislabs/oasis-gateway/mqueue/core"
+	"github.com/oasislabs/oasis-gateway/stats"
+)
+
+// CompactionServices are the services required by the Compaction
+type CompactionServices struct {
+	Logger    log.Logger
+	Compactor core.Compactor
+}
+
+// CompactionProps define the behaviour of the Compaction
+type CompactionProps struct {
+	// Interval is the time between two compaction runs
+	Interval time.Duration
+}
+
+// Compaction is the maintenance task that periodically reclaims
+// the storage used by the discarded elements of the queues
+// known to the compactor
+type Compaction struct {
+	logger    log.Logger
+	compactor core.Compactor
+	interval  time.Duration
+	counters  *stats.CounterGroup
+}
+
+// NewCompaction creates a new compaction task
+func NewCompaction(services CompactionServices, props CompactionProps) *Compaction {
+	return &Compaction{
+		logger:    services.Logger.ForClass("mqueue", "Compaction"),
+		compactor: services.Compactor,
+		interval:  props.Interval,
+		counters:  stats.NewCounterGroup("runs", "keys", "errors"),
+	}
+}
+
+// Name is the implementation of stats.Collector.Name for Compaction
+func (c *Compaction) Name() string {
+	return "mqueue.Compaction"
+}
+
+// Stats is the implementation of stats.Collector.Stats for Compaction
+func (c *Compaction) Stats() stats.Metrics {
+	return c.counters.Stats()
+}
+
+// Start runs the compaction periodically until the
+// context is cancelled
+func (c *Compaction) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(c.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				c.Run(ctx)
+			}
+		}
+	}()
+}
+
+// Run compacts all the queues known to the compactor once. Failures
+// to compact a queue are logged and the rest of the queues are
+// still compacted
+func (c *Compaction) Run(ctx context.Context) {
+	c.counters.Incr("runs")
+
+	for _, key := range c.compactor.Keys() {
+		if ctx.Err() != nil {
+			return
+		}
+
+		if _, err := c.compactor.Compact(ctx, core.CompactRequest{Key: key}); err != nil {
+			c.counters.Incr("errors")
+			c.logger.Warn(ctx, "failed to compact queue", log.MapFields{
+				"call_type": "CompactQueueFailure",
+				"key":       key,
+				"err":       err.Error(),
+			})
+			continue
+		}
+
+		c.counters.Incr("keys")
+	}
+}
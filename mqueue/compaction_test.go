@@ -0,0 +1,45 @@
+package mqueue
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/oasislabs/oasis-gateway/mqueue/core"
+	"github.com/oasislabs/oasis-gateway/mqueue/mem"
+	"github.com/oasislabs/oasis-gateway/stats"
+	"github.com/stretchr/testify/assert"
+)
+
+var logger = log.NewLogrus(log.LogrusLoggerProperties{Output: ioutil.Discard})
+
+func TestCompactionRun(t *testing.T) {
+	ctx := context.Background()
+	s := mem.NewServer(ctx, mem.Services{Logger: logger})
+
+	for _, key := range []string{"a", "b"} {
+		offset, err := s.Next(ctx, core.NextRequest{Key: key})
+		assert.Nil(t, err)
+
+		err = s.Insert(ctx, core.InsertRequest{Key: key, Element: core.Element{
+			Offset: offset,
+			Value:  "value",
+		}})
+		assert.Nil(t, err)
+	}
+
+	err := s.Discard(ctx, core.DiscardRequest{Key: "a", Offset: 1})
+	assert.Nil(t, err)
+
+	c := NewCompaction(CompactionServices{Logger: logger, Compactor: s}, CompactionProps{})
+	c.Run(ctx)
+
+	assert.Equal(t, stats.Metrics{
+		"runs":      uint64(1),
+		"keys":      uint64(2),
+		"errors":    uint64(0),
+		"undefined": uint64(0),
+	}, c.Stats())
+	assert.Equal(t, uint64(5), s.Stats()["storage"].(stats.Metrics)["bytes"])
+}
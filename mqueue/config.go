@@ -2,10 +2,15 @@ package mqueue
 
 import (
 	"errors"
+	"fmt"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/oasislabs/oasis-gateway/config"
 	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/oasislabs/oasis-gateway/mqueue/degraded"
+	"github.com/oasislabs/oasis-gateway/mqueue/redis"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -23,12 +28,23 @@ func (m MailboxProvider) String() string {
 }
 
 type Config struct {
-	Provider      MailboxProvider
-	MailboxConfig MailboxConfig
+	Provider         MailboxProvider
+	MailboxConfig    MailboxConfig
+	FederationConfig FederationConfig
+	CompactionConfig CompactionConfig
+	DegradedConfig   DegradedConfig
+
+	// RedisCheck if set verifies at startup that the redis mailbox
+	// can run the scripts of the mqueue
+	RedisCheck bool
 }
 
 func (c *Config) Log(fields log.Fields) {
 	fields.Add("mailbox.provider", c.Provider)
+	fields.Add("mailbox.redis_check", c.RedisCheck)
+	c.FederationConfig.Log(fields)
+	c.CompactionConfig.Log(fields)
+	c.DegradedConfig.Log(fields)
 
 	if c.MailboxConfig != nil {
 		c.MailboxConfig.Log(fields)
@@ -41,6 +57,20 @@ func (c *Config) Configure(v *viper.Viper) error {
 		return config.ErrKeyNotSet{Key: "mailbox.provider"}
 	}
 
+	c.RedisCheck = v.GetBool("mailbox.redis_check")
+
+	if err := c.FederationConfig.Configure(v); err != nil {
+		return err
+	}
+
+	if err := c.CompactionConfig.Configure(v); err != nil {
+		return err
+	}
+
+	if err := c.DegradedConfig.Configure(v); err != nil {
+		return err
+	}
+
 	switch c.Provider {
 	case MailboxMem:
 		c.MailboxConfig = &MailboxMemConfig{}
@@ -70,6 +100,9 @@ func (c *Config) Bind(v *viper.Viper, cmd *cobra.Command) error {
 			"Options are "+string(MailboxMem)+
 			", "+string(MailboxRedisSingle)+
 			", "+string(MailboxRedisCluster)+".")
+	cmd.PersistentFlags().Bool("mailbox.redis_check", true,
+		"verify at startup that the redis mailbox runs a supported version of redis, "+
+			"that the mqueue scripts are loaded and that they behave as expected.")
 
 	if err := (&MailboxRedisSingleConfig{}).Bind(v, cmd); err != nil {
 		return err
@@ -80,6 +113,18 @@ func (c *Config) Bind(v *viper.Viper, cmd *cobra.Command) error {
 	if err := (&MailboxMemConfig{}).Bind(v, cmd); err != nil {
 		return err
 	}
+	if err := (&EncryptionConfig{}).Bind(v, cmd); err != nil {
+		return err
+	}
+	if err := (&FederationConfig{}).Bind(v, cmd); err != nil {
+		return err
+	}
+	if err := (&CompactionConfig{}).Bind(v, cmd); err != nil {
+		return err
+	}
+	if err := (&DegradedConfig{}).Bind(v, cmd); err != nil {
+		return err
+	}
 
 	return nil
 }
@@ -91,11 +136,13 @@ type MailboxConfig interface {
 }
 
 type MailboxRedisSingleConfig struct {
-	Addr string
+	Addr       string
+	Encryption EncryptionConfig
 }
 
 func (c *MailboxRedisSingleConfig) Log(fields log.Fields) {
 	fields.Add("mailbox.redis_single.addr", c.Addr)
+	c.Encryption.Log(fields)
 }
 
 func (c *MailboxRedisSingleConfig) ID() MailboxProvider {
@@ -108,7 +155,7 @@ func (c *MailboxRedisSingleConfig) Configure(v *viper.Viper) error {
 		return errors.New("mailbox.redis_single.addr must be set")
 	}
 
-	return nil
+	return c.Encryption.Configure(v)
 }
 
 func (c *MailboxRedisSingleConfig) Bind(v *viper.Viper, cmd *cobra.Command) error {
@@ -117,11 +164,13 @@ func (c *MailboxRedisSingleConfig) Bind(v *viper.Viper, cmd *cobra.Command) erro
 }
 
 type MailboxRedisClusterConfig struct {
-	Addrs []string
+	Addrs      []string
+	Encryption EncryptionConfig
 }
 
 func (c *MailboxRedisClusterConfig) Log(fields log.Fields) {
 	fields.Add("mailbox.redis_cluster.addrs", strings.Join(c.Addrs, ","))
+	c.Encryption.Log(fields)
 }
 
 func (c *MailboxRedisClusterConfig) ID() MailboxProvider {
@@ -134,7 +183,7 @@ func (c *MailboxRedisClusterConfig) Configure(v *viper.Viper) error {
 		return errors.New("mailbox.redis_cluster.addrs must be set")
 	}
 
-	return nil
+	return c.Encryption.Configure(v)
 }
 
 func (c *MailboxRedisClusterConfig) Bind(v *viper.Viper, cmd *cobra.Command) error {
@@ -145,6 +194,101 @@ func (c *MailboxRedisClusterConfig) Bind(v *viper.Viper, cmd *cobra.Command) err
 	return nil
 }
 
+// EncryptionConfig defines the keys used to encrypt the values of the
+// elements stored in a redis mailbox
+type EncryptionConfig struct {
+	// Keys are the encryption keys by their ID. If empty the
+	// values are stored in plaintext
+	Keys map[string][]byte
+
+	// Primary is the ID of the key used to encrypt new values. The
+	// rest of the keys are only used to decrypt values encrypted
+	// before a key rotation
+	Primary string
+
+	// RequireSealed rejects the values stored in plaintext once
+	// the queues have been reencrypted
+	RequireSealed bool
+}
+
+func (c *EncryptionConfig) Log(fields log.Fields) {
+	ids := make([]string, 0, len(c.Keys))
+	for id := range c.Keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	fields.Add("mailbox.encryption.keys", strings.Join(ids, ","))
+	fields.Add("mailbox.encryption.primary", c.Primary)
+	fields.Add("mailbox.encryption.require_sealed", c.RequireSealed)
+}
+
+func (c *EncryptionConfig) Configure(v *viper.Viper) error {
+	keys := v.GetStringSlice("mailbox.encryption.keys")
+	c.Primary = v.GetString("mailbox.encryption.primary")
+	c.RequireSealed = v.GetBool("mailbox.encryption.require_sealed")
+	c.Keys = nil
+
+	if len(keys) == 0 {
+		if len(c.Primary) > 0 {
+			return errors.New("mailbox.encryption.keys must be set if " +
+				"mailbox.encryption.primary is set")
+		}
+		if c.RequireSealed {
+			return errors.New("mailbox.encryption.keys must be set if " +
+				"mailbox.encryption.require_sealed is set")
+		}
+		return nil
+	}
+
+	c.Keys = make(map[string][]byte, len(keys))
+	for _, k := range keys {
+		id, key, err := redis.ParseKey(k)
+		if err != nil {
+			return fmt.Errorf("invalid mailbox.encryption.keys: %s", err.Error())
+		}
+		c.Keys[id] = key
+	}
+
+	if len(c.Keys) == 1 && len(c.Primary) == 0 {
+		for id := range c.Keys {
+			c.Primary = id
+		}
+	}
+
+	if _, err := c.Keyring(); err != nil {
+		return fmt.Errorf("invalid mailbox.encryption configuration: %s", err.Error())
+	}
+
+	return nil
+}
+
+func (c *EncryptionConfig) Bind(v *viper.Viper, cmd *cobra.Command) error {
+	cmd.PersistentFlags().StringSlice("mailbox.encryption.keys", nil,
+		"array of keys of the form <id>:<base64 key> used to encrypt the values "+
+			"stored in a redis mailbox. Keys are 32 bytes long. If not set the values are stored in plaintext.")
+	cmd.PersistentFlags().String("mailbox.encryption.primary", "",
+		"ID of the key used to encrypt new values. Required if more than one key is set.")
+	cmd.PersistentFlags().Bool("mailbox.encryption.require_sealed", false,
+		"reject the values stored in plaintext. Set it once the values stored before "+
+			"encryption was enabled are reencrypted with mqueue-reencrypt.")
+	return nil
+}
+
+// Keyring creates the keyring defined by the configuration. It
+// returns nil if no keys are configured
+func (c *EncryptionConfig) Keyring() (*redis.Keyring, error) {
+	if len(c.Keys) == 0 {
+		return nil, nil
+	}
+
+	return redis.NewKeyring(redis.KeyringProps{
+		Keys:          c.Keys,
+		Primary:       c.Primary,
+		RequireSealed: c.RequireSealed,
+	})
+}
+
 type MailboxMemConfig struct{}
 
 func (c *MailboxMemConfig) Log(fields log.Fields) {}
@@ -160,3 +304,159 @@ func (c *MailboxMemConfig) Configure(v *viper.Viper) error {
 func (c *MailboxMemConfig) Bind(v *viper.Viper, cmd *cobra.Command) error {
 	return nil
 }
+
+// FederationConfig defines how the gateway exchanges events with
+// other gateways
+type FederationConfig struct {
+	// ForwardURL is the url of the private API of the gateway
+	// to which the events inserted in the mailbox are forwarded
+	ForwardURL string
+
+	// Accept if set the gateway accepts events forwarded by
+	// other gateways
+	Accept bool
+
+	// Secret shared by the gateways to authenticate the
+	// forwarded requests
+	Secret string
+
+	// QueueSize is the maximum number of changes waiting to
+	// be forwarded
+	QueueSize int
+}
+
+func (c *FederationConfig) Log(fields log.Fields) {
+	fields.Add("mailbox.federation.forward_url", c.ForwardURL)
+	fields.Add("mailbox.federation.accept", c.Accept)
+	fields.Add("mailbox.federation.queue_size", c.QueueSize)
+}
+
+func (c *FederationConfig) Configure(v *viper.Viper) error {
+	c.ForwardURL = v.GetString("mailbox.federation.forward_url")
+	c.Accept = v.GetBool("mailbox.federation.accept")
+	c.Secret = v.GetString("mailbox.federation.secret")
+	c.QueueSize = v.GetInt("mailbox.federation.queue_size")
+
+	if (len(c.ForwardURL) > 0 || c.Accept) && len(c.Secret) == 0 {
+		return errors.New("mailbox.federation.secret must be set if " +
+			"mailbox.federation.forward_url or mailbox.federation.accept are set")
+	}
+
+	return nil
+}
+
+func (c *FederationConfig) Bind(v *viper.Viper, cmd *cobra.Command) error {
+	cmd.PersistentFlags().String("mailbox.federation.forward_url", "",
+		"url of the private API of the gateway to which events are forwarded")
+	cmd.PersistentFlags().Bool("mailbox.federation.accept", false,
+		"if set the gateway accepts events forwarded by other gateways on its private API")
+	cmd.PersistentFlags().String("mailbox.federation.secret", "",
+		"secret shared by the gateways to authenticate forwarded events")
+	cmd.PersistentFlags().Int("mailbox.federation.queue_size", 1024,
+		"maximum number of events waiting to be forwarded. Events inserted while the queue is full are not forwarded")
+	return nil
+}
+
+// CompactionConfig defines how often the storage used by the
+// discarded elements of the queues is reclaimed
+type CompactionConfig struct {
+	// Interval is the time between two compactions of the queues.
+	// If 0 the queues are not compacted
+	Interval time.Duration
+}
+
+func (c *CompactionConfig) Log(fields log.Fields) {
+	fields.Add("mailbox.compaction.interval_ms", int64(c.Interval/time.Millisecond))
+}
+
+func (c *CompactionConfig) Configure(v *viper.Viper) error {
+	interval := v.GetInt64("mailbox.compaction.interval_ms")
+	if interval < 0 {
+		return errors.New("mailbox.compaction.interval_ms cannot be negative")
+	}
+
+	c.Interval = time.Duration(interval) * time.Millisecond
+	return nil
+}
+
+func (c *CompactionConfig) Bind(v *viper.Viper, cmd *cobra.Command) error {
+	cmd.PersistentFlags().Int64("mailbox.compaction.interval_ms", 60000,
+		"time in milliseconds between compactions of the discarded elements "+
+			"of the mailbox queues. If 0 the queues are not compacted.")
+	return nil
+}
+
+// DegradedOff disables the degraded mode, so that the operations
+// on the mailbox fail while redis is unavailable
+const DegradedOff = "off"
+
+// DegradedConfig defines how the gateway behaves while the redis
+// mailbox is unavailable
+type DegradedConfig struct {
+	// Mode is how the events published while redis is unavailable
+	// are handled. If empty the degraded mode is disabled
+	Mode degraded.Mode
+
+	// BufferSize is the maximum number of events kept in memory
+	// while redis is unavailable in degraded.ModeBuffer
+	BufferSize int
+
+	// RetryInterval is the time between two checks of whether
+	// redis has recovered
+	RetryInterval time.Duration
+}
+
+func (c *DegradedConfig) Log(fields log.Fields) {
+	mode := DegradedOff
+	if len(c.Mode) > 0 {
+		mode = c.Mode.String()
+	}
+	fields.Add("mailbox.degraded.mode", mode)
+	fields.Add("mailbox.degraded.buffer_size", c.BufferSize)
+	fields.Add("mailbox.degraded.retry_interval_ms", int64(c.RetryInterval/time.Millisecond))
+}
+
+func (c *DegradedConfig) Configure(v *viper.Viper) error {
+	switch mode := v.GetString("mailbox.degraded.mode"); mode {
+	case "", DegradedOff:
+		c.Mode = ""
+	case degraded.ModeReject.String(), degraded.ModeBuffer.String():
+		c.Mode = degraded.Mode(mode)
+	default:
+		return config.ErrInvalidValue{
+			Key:          "mailbox.degraded.mode",
+			InvalidValue: mode,
+			Values: []string{
+				DegradedOff,
+				degraded.ModeReject.String(),
+				degraded.ModeBuffer.String(),
+			},
+		}
+	}
+
+	c.BufferSize = v.GetInt("mailbox.degraded.buffer_size")
+	if c.BufferSize < 0 {
+		return errors.New("mailbox.degraded.buffer_size cannot be negative")
+	}
+
+	interval := v.GetInt64("mailbox.degraded.retry_interval_ms")
+	if interval < 0 {
+		return errors.New("mailbox.degraded.retry_interval_ms cannot be negative")
+	}
+	c.RetryInterval = time.Duration(interval) * time.Millisecond
+
+	return nil
+}
+
+func (c *DegradedConfig) Bind(v *viper.Viper, cmd *cobra.Command) error {
+	cmd.PersistentFlags().String("mailbox.degraded.mode", DegradedOff,
+		"how the gateway behaves while the redis mailbox is unavailable. "+
+			"Options are "+DegradedOff+
+			", "+degraded.ModeReject.String()+
+			", "+degraded.ModeBuffer.String()+".")
+	cmd.PersistentFlags().Int("mailbox.degraded.buffer_size", degraded.DefaultBufferSize,
+		"maximum number of events kept in memory while the redis mailbox is unavailable in buffer mode")
+	cmd.PersistentFlags().Int64("mailbox.degraded.retry_interval_ms", 1000,
+		"time in milliseconds between two checks of whether the redis mailbox has recovered")
+	return nil
+}
@@ -2,6 +2,7 @@ package core
 
 import (
 	"context"
+	"time"
 
 	"github.com/oasislabs/oasis-gateway/stats"
 )
@@ -19,6 +20,16 @@ type Element struct {
 	// type of the value stored. It may be useful when
 	// deserializing
 	Type string
+
+	// Timestamp is the unix time in milliseconds at which the
+	// element was inserted. It is set by the queue on Insert
+	// unless the inserter sets it
+	Timestamp int64
+}
+
+// Timestamp returns the unix time in milliseconds of t
+func Timestamp(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
 }
 
 // Elements is an ordered set of elements
@@ -26,13 +37,41 @@ type Elements struct {
 	// Offset is the base offset from which the elements are taken. That is
 	// if Offset is N, that means that the Elements array starts with
 	// offset N, and if element N is not present in the array it means that it
-	// is still pending
+	// is still pending. If the elements are retrieved by time, Offset is
+	// the offset of the first element retrieved
 	Offset uint64
 
 	// Elements is the collection of elements starting from offset Offset
 	Elements []Element
 }
 
+// Filter returns the elements whose type is one of the provided
+// types. If no types are provided all the elements are returned
+func (e Elements) Filter(types []string) Elements {
+	if len(types) == 0 {
+		return e
+	}
+
+	els := make([]Element, 0, len(e.Elements))
+	for _, el := range e.Elements {
+		if acceptsType(types, el.Type) {
+			els = append(els, el)
+		}
+	}
+
+	return Elements{Offset: e.Offset, Elements: els}
+}
+
+func acceptsType(types []string, t string) bool {
+	for _, accepted := range types {
+		if accepted == t {
+			return true
+		}
+	}
+
+	return false
+}
+
 // InsertRequest is the request to insert elements into a queue
 type InsertRequest struct {
 	// Key unique identifier of the queue
@@ -56,6 +95,35 @@ type RetrieveRequest struct {
 	// Count is the number of elements at most that will be returned as
 	// part of the request
 	Count uint
+
+	// Types if set restricts the elements returned to the ones of
+	// one of the provided types. The window of elements is still
+	// defined by Offset and Count, so fewer than Count elements may
+	// be returned even if more elements of those types are available
+	Types []string
+
+	// Since if set retrieves the elements by the time they were
+	// inserted instead of by Offset. At most Count elements inserted
+	// at or after Since and before Until are returned, ordered by the
+	// time they were inserted
+	Since time.Time
+
+	// Until if set restricts the elements retrieved by time to the
+	// ones inserted before Until. If Since is not set all the elements
+	// inserted before Until are retrieved
+	Until time.Time
+}
+
+// ByTime returns true if the elements are retrieved
+// by the time they were inserted
+func (r RetrieveRequest) ByTime() bool {
+	return !r.Since.IsZero() || !r.Until.IsZero()
+}
+
+// Accepts returns true if an element of the provided type
+// is returned by the request
+func (r RetrieveRequest) Accepts(t string) bool {
+	return len(r.Types) == 0 || acceptsType(r.Types, t)
 }
 
 // DiscardRequest to request the queue to discard all the
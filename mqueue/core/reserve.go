@@ -0,0 +1,36 @@
+package core
+
+import (
+	"context"
+
+	stderr "github.com/pkg/errors"
+)
+
+// ErrReserveGapTooLarge is returned when more offsets than allowed
+// would need to be reserved to reach the requested offset
+var ErrReserveGapTooLarge = stderr.New("offset is too far ahead of the queue")
+
+// ReserveRequest to reserve all the offsets of a queue up to Offset
+type ReserveRequest struct {
+	// Key unique identifier of the queue
+	Key string
+
+	// Offset is the last offset that is reserved
+	Offset uint64
+
+	// MaxGap is the maximum number of offsets the request can
+	// reserve. If more are needed to reach Offset none is reserved
+	// and ErrReserveGapTooLarge is returned
+	MaxGap uint64
+}
+
+// Reserver is implemented by the queues that can reserve several
+// offsets in a single operation, which is needed to insert elements
+// whose offset was assigned by another queue
+type Reserver interface {
+	// Reserve reserves the offsets of the queue that are not reserved
+	// yet up to and including Offset, and returns how many offsets were
+	// reserved. Offsets already reserved are left as they are, so the
+	// request can be repeated
+	Reserve(context.Context, ReserveRequest) (uint64, error)
+}
@@ -0,0 +1,178 @@
+package core
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/oasislabs/oasis-gateway/stats"
+)
+
+// MaxReportedKeys is the maximum number of keys for which the
+// storage used is reported individually in the stats
+const MaxReportedKeys = 32
+
+// Usage is the storage used by a queue
+type Usage struct {
+	// Elements is the number of elements kept by the queue,
+	// including the elements that are reserved or discarded
+	Elements uint64
+
+	// Discarded is the number of elements kept by the queue
+	// that have already been discarded
+	Discarded uint64
+
+	// Bytes is the number of bytes used by the values of
+	// the elements kept by the queue
+	Bytes uint64
+}
+
+// CompactRequest to ask the queue identified by the
+// provided key to reclaim the storage used by discarded
+// elements
+type CompactRequest struct {
+	// Key unique identifier of the queue
+	Key string
+}
+
+// Compactor is implemented by the queues that can reclaim the
+// storage used by discarded elements
+type Compactor interface {
+	// Keys returns the keys of the queues known to the compactor
+	Keys() []string
+
+	// Compact reclaims the storage used by discarded elements of
+	// the queue and returns the storage the queue uses afterwards
+	Compact(context.Context, CompactRequest) (Usage, error)
+}
+
+// Limiter is implemented by the queues that bound the number
+// of elements a queue can keep
+type Limiter interface {
+	// MaxElements returns the maximum number of elements a queue
+	// keeps. Offsets cannot be reserved once a queue keeps that
+	// many elements until some are discarded
+	MaxElements() uint64
+}
+
+// LimitOf returns the maximum number of elements the queues of the
+// MQueue keep, looking into the wrapped queues if the MQueue wraps
+// another one. It returns false if the queues are not bounded
+func LimitOf(m MQueue) (uint64, bool) {
+	for m != nil {
+		if limiter, ok := m.(Limiter); ok {
+			return limiter.MaxElements(), true
+		}
+
+		unwrapper, ok := m.(Unwrapper)
+		if !ok {
+			return 0, false
+		}
+		m = unwrapper.Unwrap()
+	}
+
+	return 0, false
+}
+
+// StorageAccount keeps track of the storage used by each queue
+type StorageAccount struct {
+	mu    sync.Mutex
+	usage map[string]Usage
+}
+
+// NewStorageAccount creates a new empty StorageAccount
+func NewStorageAccount() *StorageAccount {
+	return &StorageAccount{usage: make(map[string]Usage)}
+}
+
+// Insert accounts for an element of the provided size
+// inserted in the queue
+func (a *StorageAccount) Insert(key string, bytes uint64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	usage := a.usage[key]
+	usage.Elements++
+	usage.Bytes += bytes
+	a.usage[key] = usage
+}
+
+// Set sets the storage used by the queue
+func (a *StorageAccount) Set(key string, usage Usage) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.usage[key] = usage
+}
+
+// Remove stops accounting for the queue
+func (a *StorageAccount) Remove(key string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	delete(a.usage, key)
+}
+
+// Get returns the storage used by the queue
+func (a *StorageAccount) Get(key string) (Usage, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	usage, ok := a.usage[key]
+	return usage, ok
+}
+
+// Keys returns the keys of the accounted queues sorted
+func (a *StorageAccount) Keys() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	keys := make([]string, 0, len(a.usage))
+	for key := range a.usage {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+// Stats returns the total storage used by the queues and the bytes
+// used by the MaxReportedKeys queues that use the most storage
+func (a *StorageAccount) Stats() stats.Metrics {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var total Usage
+	keys := make([]string, 0, len(a.usage))
+	for key, usage := range a.usage {
+		total.Elements += usage.Elements
+		total.Discarded += usage.Discarded
+		total.Bytes += usage.Bytes
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		bi, bj := a.usage[keys[i]].Bytes, a.usage[keys[j]].Bytes
+		if bi != bj {
+			return bi > bj
+		}
+		return keys[i] < keys[j]
+	})
+
+	if len(keys) > MaxReportedKeys {
+		keys = keys[:MaxReportedKeys]
+	}
+
+	largest := make(stats.Metrics, len(keys))
+	for _, key := range keys {
+		largest[key] = a.usage[key].Bytes
+	}
+
+	return stats.Metrics{
+		"keys":      len(a.usage),
+		"elements":  total.Elements,
+		"discarded": total.Discarded,
+		"bytes":     total.Bytes,
+		"largest":   largest,
+	}
+}
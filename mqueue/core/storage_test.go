@@ -0,0 +1,61 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/oasislabs/oasis-gateway/stats"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStorageAccountInsert(t *testing.T) {
+	a := NewStorageAccount()
+
+	a.Insert("key", 5)
+	a.Insert("key", 3)
+
+	usage, ok := a.Get("key")
+	assert.True(t, ok)
+	assert.Equal(t, Usage{Elements: 2, Bytes: 8}, usage)
+}
+
+func TestStorageAccountRemove(t *testing.T) {
+	a := NewStorageAccount()
+
+	a.Insert("key", 5)
+	a.Remove("key")
+
+	_, ok := a.Get("key")
+	assert.False(t, ok)
+	assert.Empty(t, a.Keys())
+}
+
+func TestStorageAccountStats(t *testing.T) {
+	a := NewStorageAccount()
+
+	a.Set("b", Usage{Elements: 2, Discarded: 1, Bytes: 20})
+	a.Set("a", Usage{Elements: 1, Bytes: 10})
+
+	assert.Equal(t, []string{"a", "b"}, a.Keys())
+	assert.Equal(t, stats.Metrics{
+		"keys":      2,
+		"elements":  uint64(3),
+		"discarded": uint64(1),
+		"bytes":     uint64(30),
+		"largest": stats.Metrics{
+			"a": uint64(10),
+			"b": uint64(20),
+		},
+	}, a.Stats())
+}
+
+func TestStorageAccountStatsLargest(t *testing.T) {
+	a := NewStorageAccount()
+
+	for i := 0; i < MaxReportedKeys+1; i++ {
+		a.Insert(string(rune('A'+i)), uint64(i+1))
+	}
+
+	largest := a.Stats()["largest"].(stats.Metrics)
+	assert.Equal(t, MaxReportedKeys, len(largest))
+	assert.NotContains(t, largest, "A")
+}
@@ -0,0 +1,213 @@
+package core
+
+import (
+	"context"
+	"strconv"
+	"sync"
+)
+
+// FieldRequest identifies a field of the hash stored at Key
+type FieldRequest struct {
+	// Key unique identifier of the hash
+	Key string
+
+	// Field within the hash
+	Field string
+}
+
+// SetFieldRequest to set the value of a field of the hash
+// stored at Key
+type SetFieldRequest struct {
+	// Key unique identifier of the hash
+	Key string
+
+	// Field within the hash
+	Field string
+
+	// Value to set on the field
+	Value string
+
+	// IfAbsent when set only sets the value if the field does
+	// not exist yet
+	IfAbsent bool
+}
+
+// DeleteFieldRequest to remove a field of the hash stored at Key
+type DeleteFieldRequest struct {
+	// Key unique identifier of the hash
+	Key string
+
+	// Field within the hash
+	Field string
+
+	// IfValue if set only removes the field if its value
+	// is IfValue
+	IfValue string
+}
+
+// IncrFieldRequest to increment the integer value of a field
+// of the hash stored at Key
+type IncrFieldRequest struct {
+	// Key unique identifier of the hash
+	Key string
+
+	// Field within the hash
+	Field string
+
+	// Amount added to the value of the field
+	Amount uint64
+}
+
+// FieldsRequest to retrieve all the fields of the hash stored at Key
+type FieldsRequest struct {
+	// Key unique identifier of the hash
+	Key string
+}
+
+// Store is a durable key value store kept along with the queues of
+// the mailbox, so that it is shared by all the gateways that share
+// the mailbox. Unlike the queues, which expire when they are not used
+// and hold a bounded number of elements, the entries of a Store do not
+// expire and are not bounded. Values are kept in the fields of hashes
+// identified by a key
+type Store interface {
+	// GetField returns the value of the field and whether
+	// it exists
+	GetField(context.Context, FieldRequest) (string, bool, error)
+
+	// SetField sets the value of the field. It returns the value the
+	// field has after the operation and whether it was set, which
+	// only fails to happen when IfAbsent is set and the field exists
+	SetField(context.Context, SetFieldRequest) (string, bool, error)
+
+	// DeleteField removes the field and returns whether it was removed
+	DeleteField(context.Context, DeleteFieldRequest) (bool, error)
+
+	// Fields returns all the fields of the hash
+	Fields(context.Context, FieldsRequest) (map[string]string, error)
+
+	// IncrField atomically increments the integer value of the field,
+	// which starts at 0, and returns the value after the increment
+	IncrField(context.Context, IncrFieldRequest) (uint64, error)
+}
+
+// Unwrapper is implemented by the MQueue implementations that
+// wrap another MQueue
+type Unwrapper interface {
+	Unwrap() MQueue
+}
+
+// StoreOf returns the Store provided by the MQueue, looking into
+// the wrapped queues if the MQueue wraps another one. It returns
+// false if the MQueue does not provide a Store
+func StoreOf(m MQueue) (Store, bool) {
+	for m != nil {
+		if store, ok := m.(Store); ok {
+			return store, true
+		}
+
+		unwrapper, ok := m.(Unwrapper)
+		if !ok {
+			return nil, false
+		}
+		m = unwrapper.Unwrap()
+	}
+
+	return nil, false
+}
+
+// MemStore is an in memory implementation of Store
+type MemStore struct {
+	mu     sync.Mutex
+	hashes map[string]map[string]string
+}
+
+// NewMemStore creates a new empty MemStore
+func NewMemStore() *MemStore {
+	return &MemStore{hashes: make(map[string]map[string]string)}
+}
+
+// GetField is the implementation of Store for MemStore
+func (s *MemStore) GetField(ctx context.Context, req FieldRequest) (string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	value, ok := s.hashes[req.Key][req.Field]
+	return value, ok, nil
+}
+
+// SetField is the implementation of Store for MemStore
+func (s *MemStore) SetField(ctx context.Context, req SetFieldRequest) (string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hash, ok := s.hashes[req.Key]
+	if !ok {
+		hash = make(map[string]string)
+		s.hashes[req.Key] = hash
+	}
+
+	if value, ok := hash[req.Field]; ok && req.IfAbsent {
+		return value, false, nil
+	}
+
+	hash[req.Field] = req.Value
+	return req.Value, true, nil
+}
+
+// DeleteField is the implementation of Store for MemStore
+func (s *MemStore) DeleteField(ctx context.Context, req DeleteFieldRequest) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hash := s.hashes[req.Key]
+	value, ok := hash[req.Field]
+	if !ok || (len(req.IfValue) > 0 && value != req.IfValue) {
+		return false, nil
+	}
+
+	delete(hash, req.Field)
+	if len(hash) == 0 {
+		delete(s.hashes, req.Key)
+	}
+
+	return true, nil
+}
+
+// Fields is the implementation of Store for MemStore
+func (s *MemStore) Fields(ctx context.Context, req FieldsRequest) (map[string]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fields := make(map[string]string, len(s.hashes[req.Key]))
+	for field, value := range s.hashes[req.Key] {
+		fields[field] = value
+	}
+
+	return fields, nil
+}
+
+// IncrField is the implementation of Store for MemStore
+func (s *MemStore) IncrField(ctx context.Context, req IncrFieldRequest) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hash, ok := s.hashes[req.Key]
+	if !ok {
+		hash = make(map[string]string)
+		s.hashes[req.Key] = hash
+	}
+
+	var n uint64
+	if value, ok := hash[req.Field]; ok {
+		v, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		n = v
+	}
+
+	n += req.Amount
+	hash[req.Field] = strconv.FormatUint(n, 10)
+	return n, nil
+}
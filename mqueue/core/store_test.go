@@ -0,0 +1,79 @@
+package core
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemStoreSetField(t *testing.T) {
+	s := NewMemStore()
+
+	value, ok, err := s.SetField(context.Background(), SetFieldRequest{Key: "key", Field: "field", Value: "a"})
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "a", value)
+
+	value, ok, err = s.SetField(context.Background(), SetFieldRequest{Key: "key", Field: "field", Value: "b", IfAbsent: true})
+	assert.Nil(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, "a", value)
+
+	value, ok, err = s.GetField(context.Background(), FieldRequest{Key: "key", Field: "field"})
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "a", value)
+}
+
+func TestMemStoreDeleteField(t *testing.T) {
+	s := NewMemStore()
+	_, _, _ = s.SetField(context.Background(), SetFieldRequest{Key: "key", Field: "field", Value: "a"})
+
+	ok, err := s.DeleteField(context.Background(), DeleteFieldRequest{Key: "key", Field: "field", IfValue: "b"})
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	ok, err = s.DeleteField(context.Background(), DeleteFieldRequest{Key: "key", Field: "field"})
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	fields, err := s.Fields(context.Background(), FieldsRequest{Key: "key"})
+	assert.Nil(t, err)
+	assert.Empty(t, fields)
+}
+
+func TestMemStoreIncrField(t *testing.T) {
+	s := NewMemStore()
+
+	for i := uint64(1); i <= 3; i++ {
+		n, err := s.IncrField(context.Background(), IncrFieldRequest{Key: "key", Field: "next", Amount: 2})
+		assert.Nil(t, err)
+		assert.Equal(t, 2*i, n)
+	}
+}
+
+type wrapper struct {
+	MQueue
+	wrapped MQueue
+}
+
+func (w wrapper) Unwrap() MQueue {
+	return w.wrapped
+}
+
+type storeQueue struct {
+	MQueue
+	*MemStore
+}
+
+func TestStoreOf(t *testing.T) {
+	q := storeQueue{MemStore: NewMemStore()}
+
+	store, ok := StoreOf(wrapper{wrapped: q})
+	assert.True(t, ok)
+	assert.Equal(t, q.MemStore, store.(storeQueue).MemStore)
+
+	_, ok = StoreOf(wrapper{})
+	assert.False(t, ok)
+}
@@ -0,0 +1,10 @@
+package core
+
+import (
+	stderr "github.com/pkg/errors"
+)
+
+// ErrUnavailable is returned by an mqueue that cannot reach the
+// service that stores its queues, so that the callers can tell an
+// outage apart from a failure of the operation itself
+var ErrUnavailable = stderr.New("mqueue is unavailable")
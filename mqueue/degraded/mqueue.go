@@ -0,0 +1,443 @@
+package degraded
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/oasislabs/oasis-gateway/mqueue/core"
+	"github.com/oasislabs/oasis-gateway/stats"
+	stderr "github.com/pkg/errors"
+)
+
+// Mode defines how the MQueue handles the elements inserted
+// while the wrapped mqueue is unavailable
+type Mode string
+
+const (
+	// ModeReject fails the operations issued while the wrapped
+	// mqueue is unavailable with core.ErrUnavailable
+	ModeReject Mode = "reject"
+
+	// ModeBuffer keeps the elements inserted while the wrapped
+	// mqueue is unavailable in memory and inserts them once it
+	// recovers. All the other operations are rejected
+	ModeBuffer Mode = "buffer"
+)
+
+func (m Mode) String() string {
+	return string(m)
+}
+
+// ErrReserveNotSupported is returned by Reserve if the wrapped
+// mqueue cannot reserve offsets
+var ErrReserveNotSupported = stderr.New("mqueue does not support reserving offsets")
+
+// ErrStoreNotSupported is returned by the operations of core.Store
+// if the wrapped mqueue does not provide a store
+var ErrStoreNotSupported = stderr.New("mqueue does not provide a store")
+
+// DefaultBufferSize is the number of elements that can wait to be
+// inserted if no size is configured
+const DefaultBufferSize = 1024
+
+// DefaultRetryInterval is the time between two checks of whether
+// the wrapped mqueue has recovered if no interval is configured
+const DefaultRetryInterval = time.Second
+
+// probeKey is the key of the queue whose existence is checked to
+// find out whether the wrapped mqueue has recovered
+const probeKey = "mqueue.degraded.probe"
+
+// Services required by the MQueue
+type Services struct {
+	Logger log.Logger
+	MQueue core.MQueue
+
+	// IsUnavailable returns true if an error returned by the
+	// wrapped mqueue is caused by its service being unreachable
+	IsUnavailable func(error) bool
+}
+
+// Props define the behaviour of the MQueue
+type Props struct {
+	// Mode defines how the elements inserted while the wrapped
+	// mqueue is unavailable are handled
+	Mode Mode
+
+	// BufferSize is the maximum number of elements waiting to be
+	// inserted in ModeBuffer. Elements inserted while the buffer is
+	// full are rejected. If 0 DefaultBufferSize is used
+	BufferSize int
+
+	// RetryInterval is the time between two checks of whether the
+	// wrapped mqueue has recovered. If 0 DefaultRetryInterval is used
+	RetryInterval time.Duration
+}
+
+// MQueue is an mqueue that keeps the gateway serving when the wrapped
+// mqueue becomes unavailable. Once an operation fails because the
+// wrapped mqueue cannot be reached, the MQueue is degraded and fails
+// the following operations with core.ErrUnavailable without issuing
+// them, or buffers the inserted elements in ModeBuffer. The wrapped
+// mqueue is checked in the background, and once it recovers the
+// buffered elements are inserted in the order in which they were
+// inserted before any new operation is issued. The operations on the
+// store of the wrapped mqueue are never buffered
+type MQueue struct {
+	logger        log.Logger
+	mqueue        core.MQueue
+	isUnavailable func(error) bool
+	mode          Mode
+	bufferSize    int
+	retryInterval time.Duration
+
+	lock     sync.Mutex
+	degraded bool
+	buffer   []core.InsertRequest
+	dropped  stats.Counter
+	replayed stats.Counter
+}
+
+// NewMQueue creates a new MQueue. The wrapped mqueue is checked
+// while it is unavailable until the context is cancelled
+func NewMQueue(ctx context.Context, services Services, props Props) *MQueue {
+	if services.Logger == nil {
+		panic("Logger must be set")
+	}
+	if services.MQueue == nil {
+		panic("MQueue must be set")
+	}
+	if services.IsUnavailable == nil {
+		panic("IsUnavailable must be set")
+	}
+	if props.BufferSize <= 0 {
+		props.BufferSize = DefaultBufferSize
+	}
+	if props.RetryInterval <= 0 {
+		props.RetryInterval = DefaultRetryInterval
+	}
+
+	m := &MQueue{
+		logger:        services.Logger.ForClass("mqueue/degraded", "MQueue"),
+		mqueue:        services.MQueue,
+		isUnavailable: services.IsUnavailable,
+		mode:          props.Mode,
+		bufferSize:    props.BufferSize,
+		retryInterval: props.RetryInterval,
+	}
+
+	go m.recoverLoop(ctx)
+	return m
+}
+
+// Unwrap returns the wrapped mqueue
+func (m *MQueue) Unwrap() core.MQueue {
+	return m.mqueue
+}
+
+// Degraded returns true if the wrapped mqueue is unavailable
+// or the buffered elements have not been inserted yet
+func (m *MQueue) Degraded() bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return m.degraded
+}
+
+// Name is the implementation of core.MQueue.Name for MQueue
+func (m *MQueue) Name() string {
+	return "mqueue.degraded.MQueue"
+}
+
+// Stats is the implementation of core.MQueue.Stats for MQueue
+func (m *MQueue) Stats() stats.Metrics {
+	m.lock.Lock()
+	degraded := m.degraded
+	pending := len(m.buffer)
+	m.lock.Unlock()
+
+	return stats.Metrics{
+		"local":    m.mqueue.Stats(),
+		"degraded": degraded,
+		"pending":  pending,
+		"dropped":  m.dropped.Value(),
+		"replayed": m.replayed.Value(),
+	}
+}
+
+// degrade marks the wrapped mqueue as unavailable if the error is
+// caused by it being unreachable. It returns core.ErrUnavailable
+// in that case and the error otherwise
+func (m *MQueue) degrade(ctx context.Context, err error) error {
+	if err == nil || !m.isUnavailable(err) {
+		return err
+	}
+
+	m.lock.Lock()
+	wasDegraded := m.degraded
+	m.degraded = true
+	m.lock.Unlock()
+
+	if !wasDegraded {
+		m.logger.Warn(ctx, "mqueue is unavailable, running in degraded mode", log.MapFields{
+			"call_type": "MQueueUnavailable",
+			"mode":      m.mode.String(),
+			"err":       err.Error(),
+		})
+	}
+
+	return core.ErrUnavailable
+}
+
+// checkAvailable fails fast if the wrapped mqueue is degraded
+func (m *MQueue) checkAvailable() error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.degraded {
+		return core.ErrUnavailable
+	}
+
+	return nil
+}
+
+// bufferInsert keeps the request to be inserted once the wrapped
+// mqueue recovers. It returns core.ErrUnavailable if the request
+// cannot be buffered
+func (m *MQueue) bufferInsert(ctx context.Context, req core.InsertRequest) error {
+	if m.mode != ModeBuffer {
+		return core.ErrUnavailable
+	}
+
+	m.lock.Lock()
+	full := len(m.buffer) >= m.bufferSize
+	if !full {
+		m.buffer = append(m.buffer, req)
+	}
+	m.lock.Unlock()
+
+	if full {
+		m.dropped.Incr()
+		m.logger.Warn(ctx, "degraded mode buffer is full, element is rejected", log.MapFields{
+			"call_type": "DegradedBufferFull",
+			"key":       req.Key,
+			"offset":    req.Element.Offset,
+		})
+		return core.ErrUnavailable
+	}
+
+	return nil
+}
+
+func (m *MQueue) recoverLoop(ctx context.Context) {
+	ticker := time.NewTicker(m.retryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if m.Degraded() {
+				m.recover(ctx)
+			}
+		}
+	}
+}
+
+// recover checks whether the wrapped mqueue is reachable and inserts
+// the buffered elements in order. The MQueue stays degraded until
+// all the buffered elements are inserted
+func (m *MQueue) recover(ctx context.Context) {
+	if _, err := m.mqueue.Exists(ctx, core.ExistsRequest{Key: probeKey}); err != nil {
+		return
+	}
+
+	for {
+		m.lock.Lock()
+		if len(m.buffer) == 0 {
+			m.degraded = false
+			m.lock.Unlock()
+			m.logger.Info(ctx, "mqueue has recovered", log.MapFields{
+				"call_type": "MQueueRecovered",
+				"replayed":  m.replayed.Value(),
+			})
+			return
+		}
+		req := m.buffer[0]
+		m.lock.Unlock()
+
+		err := m.mqueue.Insert(ctx, req)
+		if err != nil && m.isUnavailable(err) {
+			return
+		}
+
+		m.lock.Lock()
+		m.buffer[0] = core.InsertRequest{}
+		m.buffer = m.buffer[1:]
+		m.lock.Unlock()
+
+		if err != nil {
+			m.dropped.Incr()
+			m.logger.Warn(ctx, "failed to insert buffered element", log.MapFields{
+				"call_type": "DegradedReplayFailure",
+				"key":       req.Key,
+				"offset":    req.Element.Offset,
+				"err":       err.Error(),
+			})
+			continue
+		}
+
+		m.replayed.Incr()
+	}
+}
+
+// Insert is the implementation of core.MQueue.Insert for MQueue
+func (m *MQueue) Insert(ctx context.Context, req core.InsertRequest) error {
+	if err := m.checkAvailable(); err != nil {
+		return m.bufferInsert(ctx, req)
+	}
+
+	if err := m.degrade(ctx, m.mqueue.Insert(ctx, req)); err != nil {
+		if err == core.ErrUnavailable {
+			return m.bufferInsert(ctx, req)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Retrieve is the implementation of core.MQueue.Retrieve for MQueue
+func (m *MQueue) Retrieve(ctx context.Context, req core.RetrieveRequest) (core.Elements, error) {
+	if err := m.checkAvailable(); err != nil {
+		return core.Elements{}, err
+	}
+
+	els, err := m.mqueue.Retrieve(ctx, req)
+	return els, m.degrade(ctx, err)
+}
+
+// Discard is the implementation of core.MQueue.Discard for MQueue
+func (m *MQueue) Discard(ctx context.Context, req core.DiscardRequest) error {
+	if err := m.checkAvailable(); err != nil {
+		return err
+	}
+
+	return m.degrade(ctx, m.mqueue.Discard(ctx, req))
+}
+
+// Next is the implementation of core.MQueue.Next for MQueue
+func (m *MQueue) Next(ctx context.Context, req core.NextRequest) (uint64, error) {
+	if err := m.checkAvailable(); err != nil {
+		return 0, err
+	}
+
+	offset, err := m.mqueue.Next(ctx, req)
+	return offset, m.degrade(ctx, err)
+}
+
+// Remove is the implementation of core.MQueue.Remove for MQueue
+func (m *MQueue) Remove(ctx context.Context, req core.RemoveRequest) error {
+	if err := m.checkAvailable(); err != nil {
+		return err
+	}
+
+	return m.degrade(ctx, m.mqueue.Remove(ctx, req))
+}
+
+// Exists is the implementation of core.MQueue.Exists for MQueue
+func (m *MQueue) Exists(ctx context.Context, req core.ExistsRequest) (bool, error) {
+	if err := m.checkAvailable(); err != nil {
+		return false, err
+	}
+
+	ok, err := m.mqueue.Exists(ctx, req)
+	return ok, m.degrade(ctx, err)
+}
+
+// Reserve is the implementation of core.Reserver.Reserve for MQueue
+func (m *MQueue) Reserve(ctx context.Context, req core.ReserveRequest) (uint64, error) {
+	reserver, ok := m.mqueue.(core.Reserver)
+	if !ok {
+		return 0, ErrReserveNotSupported
+	}
+
+	if err := m.checkAvailable(); err != nil {
+		return 0, err
+	}
+
+	n, err := reserver.Reserve(ctx, req)
+	return n, m.degrade(ctx, err)
+}
+
+// store returns the store of the wrapped mqueue if it is available
+func (m *MQueue) store() (core.Store, error) {
+	store, ok := core.StoreOf(m.mqueue)
+	if !ok {
+		return nil, ErrStoreNotSupported
+	}
+
+	if err := m.checkAvailable(); err != nil {
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// GetField is the implementation of core.Store.GetField for MQueue
+func (m *MQueue) GetField(ctx context.Context, req core.FieldRequest) (string, bool, error) {
+	store, err := m.store()
+	if err != nil {
+		return "", false, err
+	}
+
+	value, ok, err := store.GetField(ctx, req)
+	return value, ok, m.degrade(ctx, err)
+}
+
+// SetField is the implementation of core.Store.SetField for MQueue
+func (m *MQueue) SetField(ctx context.Context, req core.SetFieldRequest) (string, bool, error) {
+	store, err := m.store()
+	if err != nil {
+		return "", false, err
+	}
+
+	value, ok, err := store.SetField(ctx, req)
+	return value, ok, m.degrade(ctx, err)
+}
+
+// DeleteField is the implementation of core.Store.DeleteField for MQueue
+func (m *MQueue) DeleteField(ctx context.Context, req core.DeleteFieldRequest) (bool, error) {
+	store, err := m.store()
+	if err != nil {
+		return false, err
+	}
+
+	ok, err := store.DeleteField(ctx, req)
+	return ok, m.degrade(ctx, err)
+}
+
+// Fields is the implementation of core.Store.Fields for MQueue
+func (m *MQueue) Fields(ctx context.Context, req core.FieldsRequest) (map[string]string, error) {
+	store, err := m.store()
+	if err != nil {
+		return nil, err
+	}
+
+	fields, err := store.Fields(ctx, req)
+	return fields, m.degrade(ctx, err)
+}
+
+// IncrField is the implementation of core.Store.IncrField for MQueue
+func (m *MQueue) IncrField(ctx context.Context, req core.IncrFieldRequest) (uint64, error) {
+	store, err := m.store()
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := store.IncrField(ctx, req)
+	return n, m.degrade(ctx, err)
+}
@@ -0,0 +1,228 @@
+package degraded
+
+import (
+	"context"
+	"io/ioutil"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/oasislabs/oasis-gateway/mqueue/core"
+	"github.com/oasislabs/oasis-gateway/mqueue/mem"
+	stderr "github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	ctx    = context.Background()
+	logger = log.NewLogrus(log.LogrusLoggerProperties{Output: ioutil.Discard})
+)
+
+var errDown = stderr.New("connection refused")
+
+// flakyMQueue is an mqueue that fails all the operations
+// with errDown while it is down
+type flakyMQueue struct {
+	core.MQueue
+	lock sync.Mutex
+	down bool
+}
+
+func (m *flakyMQueue) setDown(down bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.down = down
+}
+
+func (m *flakyMQueue) check() error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.down {
+		return errDown
+	}
+	return nil
+}
+
+// Unwrap exposes the store of the mem mqueue, whose
+// operations do not fail while the mqueue is down
+func (m *flakyMQueue) Unwrap() core.MQueue {
+	return m.MQueue
+}
+
+func (m *flakyMQueue) Insert(ctx context.Context, req core.InsertRequest) error {
+	if err := m.check(); err != nil {
+		return err
+	}
+	return m.MQueue.Insert(ctx, req)
+}
+
+func (m *flakyMQueue) Next(ctx context.Context, req core.NextRequest) (uint64, error) {
+	if err := m.check(); err != nil {
+		return 0, err
+	}
+	return m.MQueue.Next(ctx, req)
+}
+
+func (m *flakyMQueue) Exists(ctx context.Context, req core.ExistsRequest) (bool, error) {
+	if err := m.check(); err != nil {
+		return false, err
+	}
+	return m.MQueue.Exists(ctx, req)
+}
+
+func newDegraded(ctx context.Context, mode Mode, size int) (*MQueue, *flakyMQueue) {
+	flaky := &flakyMQueue{MQueue: mem.NewServer(ctx, mem.Services{Logger: logger})}
+	return NewMQueue(ctx, Services{
+		Logger:        logger,
+		MQueue:        flaky,
+		IsUnavailable: func(err error) bool { return err == errDown },
+	}, Props{
+		Mode:          mode,
+		BufferSize:    size,
+		RetryInterval: time.Millisecond,
+	}), flaky
+}
+
+func insert(m core.MQueue, key string, offset uint64) error {
+	return m.Insert(ctx, core.InsertRequest{
+		Key:     key,
+		Element: core.Element{Offset: offset, Value: "value", Type: "type"},
+	})
+}
+
+func waitRecovered(t *testing.T, m *MQueue) {
+	for i := 0; i < 1000 && m.Degraded(); i++ {
+		time.Sleep(time.Millisecond)
+	}
+	assert.False(t, m.Degraded())
+}
+
+func TestMQueueAvailable(t *testing.T) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	m, _ := newDegraded(ctx, ModeReject, 0)
+
+	offset, err := m.Next(ctx, core.NextRequest{Key: "key"})
+	assert.Nil(t, err)
+	assert.Nil(t, insert(m, "key", offset))
+	assert.False(t, m.Degraded())
+}
+
+func TestMQueueRejectWhileUnavailable(t *testing.T) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	m, flaky := newDegraded(ctx, ModeReject, 0)
+
+	offset, err := m.Next(ctx, core.NextRequest{Key: "key"})
+	assert.Nil(t, err)
+
+	flaky.setDown(true)
+	assert.Equal(t, core.ErrUnavailable, insert(m, "key", offset))
+	assert.True(t, m.Degraded())
+
+	_, err = m.Next(ctx, core.NextRequest{Key: "key"})
+	assert.Equal(t, core.ErrUnavailable, err)
+
+	flaky.setDown(false)
+	waitRecovered(t, m)
+
+	els, err := m.Retrieve(ctx, core.RetrieveRequest{Key: "key", Offset: 0, Count: 1})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(els.Elements))
+}
+
+func TestMQueueBufferReplaysOnRecovery(t *testing.T) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	m, flaky := newDegraded(ctx, ModeBuffer, 4)
+
+	for i := 0; i < 2; i++ {
+		_, err := m.Next(ctx, core.NextRequest{Key: "key"})
+		assert.Nil(t, err)
+	}
+
+	flaky.setDown(true)
+	assert.Nil(t, insert(m, "key", 1))
+	assert.Nil(t, insert(m, "key", 0))
+	assert.Equal(t, 2, m.Stats()["pending"])
+
+	_, err := m.Retrieve(ctx, core.RetrieveRequest{Key: "key", Offset: 0, Count: 2})
+	assert.Equal(t, core.ErrUnavailable, err)
+
+	flaky.setDown(false)
+	waitRecovered(t, m)
+
+	els, err := m.Retrieve(ctx, core.RetrieveRequest{Key: "key", Offset: 0, Count: 2})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(els.Elements))
+	assert.Equal(t, uint64(2), m.Stats()["replayed"])
+}
+
+func TestMQueueBufferFull(t *testing.T) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	m, flaky := newDegraded(ctx, ModeBuffer, 1)
+
+	flaky.setDown(true)
+	assert.Nil(t, insert(m, "key", 0))
+	assert.Equal(t, core.ErrUnavailable, insert(m, "key", 1))
+	assert.Equal(t, uint64(1), m.Stats()["dropped"])
+}
+
+func TestMQueueCommandErrorNotDegraded(t *testing.T) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	m, _ := newDegraded(ctx, ModeBuffer, 0)
+
+	// the queue does not exist, which is a failure of the
+	// operation and not of the mqueue
+	err := insert(m, "missing", 0)
+	assert.Error(t, err)
+	assert.NotEqual(t, core.ErrUnavailable, err)
+	assert.False(t, m.Degraded())
+}
+
+func TestMQueueStoreRejectWhileUnavailable(t *testing.T) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	m, flaky := newDegraded(ctx, ModeBuffer, 4)
+
+	store, ok := core.StoreOf(m)
+	assert.True(t, ok)
+	assert.Equal(t, m, store)
+
+	_, _, err := store.SetField(ctx, core.SetFieldRequest{Key: "key", Field: "field", Value: "value"})
+	assert.Nil(t, err)
+
+	flaky.setDown(true)
+	assert.Nil(t, insert(m, "key", 0))
+	assert.True(t, m.Degraded())
+
+	// store operations are not buffered
+	_, _, err = store.GetField(ctx, core.FieldRequest{Key: "key", Field: "field"})
+	assert.Equal(t, core.ErrUnavailable, err)
+	_, err = store.IncrField(ctx, core.IncrFieldRequest{Key: "key", Field: "count", Amount: 1})
+	assert.Equal(t, core.ErrUnavailable, err)
+
+	flaky.setDown(false)
+	waitRecovered(t, m)
+
+	value, ok, err := store.GetField(ctx, core.FieldRequest{Key: "key", Field: "field"})
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "value", value)
+}
+
+func TestMQueueStoreNotSupported(t *testing.T) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	m := NewMQueue(ctx, Services{
+		Logger:        logger,
+		MQueue:        struct{ core.MQueue }{mem.NewServer(ctx, mem.Services{Logger: logger})},
+		IsUnavailable: func(err error) bool { return err == errDown },
+	}, Props{Mode: ModeReject})
+
+	_, _, err := m.GetField(ctx, core.FieldRequest{Key: "key", Field: "field"})
+	assert.Equal(t, ErrStoreNotSupported, err)
+}
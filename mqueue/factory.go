@@ -6,12 +6,16 @@ import (
 
 	"github.com/oasislabs/oasis-gateway/log"
 	"github.com/oasislabs/oasis-gateway/mqueue/core"
+	"github.com/oasislabs/oasis-gateway/mqueue/degraded"
+	"github.com/oasislabs/oasis-gateway/mqueue/federation"
 	"github.com/oasislabs/oasis-gateway/mqueue/mem"
 	"github.com/oasislabs/oasis-gateway/mqueue/redis"
+	"github.com/oasislabs/oasis-gateway/stats"
 )
 
 type Services struct {
-	Logger log.Logger
+	Logger   log.Logger
+	Registry *stats.Registry
 }
 
 type MailboxFactory interface {
@@ -25,6 +29,99 @@ func (f MailboxFactoryFunc) New(ctx context.Context, services Services, config *
 }
 
 var NewMailbox = MailboxFactoryFunc(func(ctx context.Context, services Services, config *Config) (core.MQueue, error) {
+	m, err := newProviderMailbox(ctx, services, config)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkRedis(ctx, config, m); err != nil {
+		return nil, err
+	}
+
+	if err := startCompaction(ctx, services, config, m); err != nil {
+		return nil, err
+	}
+
+	m = withDegradedMode(ctx, services, config, m)
+
+	if len(config.FederationConfig.ForwardURL) == 0 {
+		return m, nil
+	}
+
+	return federation.NewMQueue(ctx, federation.Services{
+		Logger: services.Logger,
+		MQueue: m,
+		Forwarder: federation.NewClient(federation.ClientProps{
+			URL:    config.FederationConfig.ForwardURL,
+			Secret: []byte(config.FederationConfig.Secret),
+		}),
+	}, federation.Props{
+		QueueSize: config.FederationConfig.QueueSize,
+	}), nil
+})
+
+// checkRedis verifies that a redis mailbox can serve the gateway if the
+// check is enabled, so that the gateway fails to start rather than
+// failing the requests that use the mailbox
+func checkRedis(ctx context.Context, config *Config, m core.MQueue) error {
+	r, ok := m.(*redis.MQueue)
+	if !ok || !config.RedisCheck {
+		return nil
+	}
+
+	if err := r.Check(ctx); err != nil {
+		return fmt.Errorf("redis mailbox is not compatible with the gateway: %s", err.Error())
+	}
+
+	return nil
+}
+
+// startCompaction starts the compaction of the mailbox queues if
+// it is enabled and the provider supports it
+func startCompaction(ctx context.Context, services Services, config *Config, m core.MQueue) error {
+	compactor, ok := m.(core.Compactor)
+	if !ok || config.CompactionConfig.Interval == 0 {
+		return nil
+	}
+
+	compaction := NewCompaction(CompactionServices{
+		Logger:    services.Logger,
+		Compactor: compactor,
+	}, CompactionProps{
+		Interval: config.CompactionConfig.Interval,
+	})
+	if err := services.Registry.Register(compaction.Name(), compaction); err != nil {
+		return err
+	}
+	compaction.Start(ctx)
+	return nil
+}
+
+// withDegradedMode wraps the mailbox so that the gateway keeps
+// serving while redis is unavailable if the degraded mode is enabled
+func withDegradedMode(ctx context.Context, services Services, config *Config, m core.MQueue) core.MQueue {
+	if len(config.DegradedConfig.Mode) == 0 {
+		return m
+	}
+
+	switch config.MailboxConfig.ID() {
+	case MailboxRedisSingle, MailboxRedisCluster:
+	default:
+		return m
+	}
+
+	return degraded.NewMQueue(ctx, degraded.Services{
+		Logger:        services.Logger,
+		MQueue:        m,
+		IsUnavailable: redis.IsErrUnavailable,
+	}, degraded.Props{
+		Mode:          config.DegradedConfig.Mode,
+		BufferSize:    config.DegradedConfig.BufferSize,
+		RetryInterval: config.DegradedConfig.RetryInterval,
+	})
+}
+
+func newProviderMailbox(ctx context.Context, services Services, config *Config) (core.MQueue, error) {
 	if config.MailboxConfig.ID() != config.Provider {
 		return nil, ErrBackendConfigConflict
 	}
@@ -41,17 +138,23 @@ var NewMailbox = MailboxFactoryFunc(func(ctx context.Context, services Services,
 	default:
 		return nil, ErrUnknownBackend{Backend: config.MailboxConfig.ID().String()}
 	}
-})
+}
 
 func NewRedisSingleMailbox(
 	ctx context.Context,
 	services Services,
 	config *MailboxRedisSingleConfig,
 ) (core.MQueue, error) {
+	keyring, err := config.Encryption.Keyring()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create mailbox keyring %s", err.Error())
+	}
+
 	m, err := redis.NewSingleMQueue(redis.SingleInstanceProps{
 		Props: redis.Props{
 			Context: ctx,
 			Logger:  services.Logger,
+			Keyring: keyring,
 		},
 		Addr: config.Addr,
 	})
@@ -66,10 +169,16 @@ func NewRedisClusterMailbox(
 	services Services,
 	config *MailboxRedisClusterConfig,
 ) (core.MQueue, error) {
+	keyring, err := config.Encryption.Keyring()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create mailbox keyring %s", err.Error())
+	}
+
 	m, err := redis.NewClusterMQueue(redis.ClusterProps{
 		Props: redis.Props{
 			Context: ctx,
 			Logger:  services.Logger,
+			Keyring: keyring,
 		},
 		Addrs: config.Addrs,
 	})
@@ -0,0 +1,117 @@
+package federation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/oasislabs/oasis-gateway/mqueue/core"
+)
+
+const (
+	// InsertPath is the path of the private API endpoint of the
+	// receiving gateway that accepts forwarded elements
+	InsertPath = "/v0/api/federation/insert"
+
+	// RemovePath is the path of the private API endpoint of the
+	// receiving gateway that accepts forwarded queue removals
+	RemovePath = "/v0/api/federation/remove"
+)
+
+// HttpClient is the basic interface for the
+// underlying http client used by the Client
+type HttpClient interface {
+	Do(*http.Request) (*http.Response, error)
+}
+
+// ClientProps are the properties that define the behaviour
+// of the client
+type ClientProps struct {
+	// URL is the base url of the private API of the gateway
+	// to which requests are forwarded
+	URL string
+
+	// Secret is the secret shared with the receiving gateway
+	// used to sign the requests
+	Secret []byte
+}
+
+// Client forwards changes in the local queues to the private
+// API of another gateway
+type Client struct {
+	url    string
+	client HttpClient
+	signer *Signer
+}
+
+// NewClient creates a new client
+func NewClient(props ClientProps) *Client {
+	return NewClientWithDeps(&http.Client{Timeout: 10 * time.Second}, props)
+}
+
+// NewClientWithDeps creates a new client using the provided
+// http client
+func NewClientWithDeps(client HttpClient, props ClientProps) *Client {
+	return &Client{
+		url:    strings.TrimSuffix(props.URL, "/"),
+		client: client,
+		signer: NewSigner(props.Secret),
+	}
+}
+
+// Insert forwards an inserted element to the remote gateway
+func (c *Client) Insert(ctx context.Context, req core.InsertRequest) error {
+	forward := InsertRequest{
+		Key:       req.Key,
+		Offset:    req.Element.Offset,
+		Type:      req.Element.Type,
+		Value:     req.Element.Value,
+		Timestamp: time.Now().Unix(),
+		Nonce:     newNonce(),
+	}
+	forward.Signature = c.signer.SignInsert(&forward)
+
+	return c.post(ctx, InsertPath, forward)
+}
+
+// Remove forwards the removal of a queue to the remote gateway
+func (c *Client) Remove(ctx context.Context, req core.RemoveRequest) error {
+	forward := RemoveRequest{
+		Key:       req.Key,
+		Timestamp: time.Now().Unix(),
+		Nonce:     newNonce(),
+	}
+	forward.Signature = c.signer.SignRemove(&forward)
+
+	return c.post(ctx, RemovePath, forward)
+}
+
+func (c *Client) post(ctx context.Context, path string, body interface{}) error {
+	p, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", c.url+path, bytes.NewReader(p))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("forward to %s failed with status %d", path, res.StatusCode)
+	}
+
+	return nil
+}
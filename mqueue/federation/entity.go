@@ -0,0 +1,46 @@
+package federation
+
+// InsertRequest is the request a gateway sends to another gateway
+// to replicate an element inserted in one of its queues
+type InsertRequest struct {
+	// Key unique identifier of the queue
+	Key string `json:"key"`
+
+	// Offset of the element within the queue
+	Offset uint64 `json:"offset"`
+
+	// Type of the value stored
+	Type string `json:"type"`
+
+	// Value stored by the element
+	Value string `json:"value"`
+
+	// Timestamp is the unix time in seconds at which the
+	// request was signed
+	Timestamp int64 `json:"timestamp"`
+
+	// Nonce is a random value that makes each request unique,
+	// so that a request cannot be replayed
+	Nonce string `json:"nonce"`
+
+	// Signature authenticates the request
+	Signature string `json:"signature"`
+}
+
+// RemoveRequest is the request a gateway sends to another gateway
+// to replicate the removal of one of its queues
+type RemoveRequest struct {
+	// Key unique identifier of the queue
+	Key string `json:"key"`
+
+	// Timestamp is the unix time in seconds at which the
+	// request was signed
+	Timestamp int64 `json:"timestamp"`
+
+	// Nonce is a random value that makes each request unique,
+	// so that a request cannot be replayed
+	Nonce string `json:"nonce"`
+
+	// Signature authenticates the request
+	Signature string `json:"signature"`
+}
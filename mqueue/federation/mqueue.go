@@ -0,0 +1,323 @@
+package federation
+
+import (
+	"context"
+	"time"
+
+	"github.com/oasislabs/oasis-gateway/errors"
+	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/oasislabs/oasis-gateway/mqueue/core"
+	"github.com/oasislabs/oasis-gateway/stats"
+	stderr "github.com/pkg/errors"
+)
+
+// MaxOffsetGap is the maximum number of offsets the receiving
+// gateway reserves ahead to align its queue with the offset of
+// a forwarded element
+const MaxOffsetGap = 1024
+
+var (
+	ErrOffsetGapTooLarge   = stderr.New("forwarded offset is too far ahead of the queue")
+	ErrReserveNotSupported = stderr.New("mqueue does not support reserving offsets")
+)
+
+const (
+	forwardInsert = "forwardInsert"
+	forwardRemove = "forwardRemove"
+)
+
+// DefaultForwardQueueSize is the number of changes that can wait to be
+// forwarded if no size is configured
+const DefaultForwardQueueSize = 1024
+
+// Forwarder forwards the changes of the local queues
+// to another gateway
+type Forwarder interface {
+	Insert(context.Context, core.InsertRequest) error
+	Remove(context.Context, core.RemoveRequest) error
+}
+
+// Services required by the MQueue
+type Services struct {
+	Logger    log.Logger
+	MQueue    core.MQueue
+	Forwarder Forwarder
+}
+
+// Props define the behaviour of the MQueue
+type Props struct {
+	// QueueSize is the maximum number of changes waiting to be
+	// forwarded. Changes made while the queue is full are not
+	// forwarded. If 0 DefaultForwardQueueSize is used
+	QueueSize int
+}
+
+// change is a change of the local queues waiting to be forwarded.
+// Only one of insert and remove is set
+type change struct {
+	insert *core.InsertRequest
+	remove *core.RemoveRequest
+}
+
+// MQueue is an mqueue that forwards the elements inserted in the
+// local mqueue to another gateway so that clients can poll for
+// events from that gateway as well. The local mqueue is the source
+// of truth, so the changes are forwarded in the background in the
+// order in which they are made, and failures to forward are logged
+// but do not fail the operation on the local mqueue
+type MQueue struct {
+	logger    log.Logger
+	mqueue    core.MQueue
+	forwarder Forwarder
+	tracker   *stats.MethodTracker
+	changes   chan change
+	dropped   stats.Counter
+}
+
+// NewMQueue creates a new forwarding mqueue. The changes are
+// forwarded until the context is cancelled
+func NewMQueue(ctx context.Context, services Services, props Props) *MQueue {
+	if props.QueueSize <= 0 {
+		props.QueueSize = DefaultForwardQueueSize
+	}
+
+	m := &MQueue{
+		logger:    services.Logger.ForClass("mqueue/federation", "MQueue"),
+		mqueue:    services.MQueue,
+		forwarder: services.Forwarder,
+		tracker: stats.NewLabeledMethodTracker(stats.Labels{"mqueue": "federation"},
+			forwardInsert, forwardRemove),
+		changes: make(chan change, props.QueueSize),
+	}
+
+	go m.forwardLoop(ctx)
+	return m
+}
+
+// Unwrap returns the local mqueue
+func (m *MQueue) Unwrap() core.MQueue {
+	return m.mqueue
+}
+
+// Name is the implementation of core.MQueue.Name for MQueue
+func (m *MQueue) Name() string {
+	return "mqueue.federation.MQueue"
+}
+
+// Stats is the implementation of core.MQueue.Stats for MQueue
+func (m *MQueue) Stats() stats.Metrics {
+	return stats.Metrics{
+		"local":   m.mqueue.Stats(),
+		"forward": m.tracker.Stats(),
+		"pending": len(m.changes),
+		"dropped": m.dropped.Value(),
+	}
+}
+
+// enqueue adds the change to the changes waiting to be forwarded.
+// It does not block, so a change is dropped if the queue is full
+func (m *MQueue) enqueue(ctx context.Context, c change, key string) {
+	select {
+	case m.changes <- c:
+	default:
+		m.dropped.Incr()
+		m.logger.Warn(ctx, "forward queue is full, change is not forwarded", log.MapFields{
+			"call_type": "ForwardQueueFull",
+			"key":       key,
+		})
+	}
+}
+
+func (m *MQueue) forwardLoop(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case c := <-m.changes:
+			if c.insert != nil {
+				m.forwardInsert(ctx, *c.insert)
+			} else {
+				m.forwardRemove(ctx, *c.remove)
+			}
+		}
+	}
+}
+
+func (m *MQueue) forwardInsert(ctx context.Context, req core.InsertRequest) {
+	if _, err := m.tracker.Instrument(forwardInsert, func() (interface{}, error) {
+		return nil, m.forwarder.Insert(ctx, req)
+	}); err != nil {
+		m.logger.Warn(ctx, "failed to forward element", log.MapFields{
+			"call_type": "ForwardInsertFailure",
+			"key":       req.Key,
+			"offset":    req.Element.Offset,
+			"err":       err.Error(),
+		})
+	}
+}
+
+func (m *MQueue) forwardRemove(ctx context.Context, req core.RemoveRequest) {
+	if _, err := m.tracker.Instrument(forwardRemove, func() (interface{}, error) {
+		return nil, m.forwarder.Remove(ctx, req)
+	}); err != nil {
+		m.logger.Warn(ctx, "failed to forward queue removal", log.MapFields{
+			"call_type": "ForwardRemoveFailure",
+			"key":       req.Key,
+			"err":       err.Error(),
+		})
+	}
+}
+
+// Insert is the implementation of core.MQueue.Insert for MQueue
+func (m *MQueue) Insert(ctx context.Context, req core.InsertRequest) error {
+	if err := m.mqueue.Insert(ctx, req); err != nil {
+		return err
+	}
+
+	m.enqueue(ctx, change{insert: &req}, req.Key)
+	return nil
+}
+
+// Retrieve is the implementation of core.MQueue.Retrieve for MQueue
+func (m *MQueue) Retrieve(ctx context.Context, req core.RetrieveRequest) (core.Elements, error) {
+	return m.mqueue.Retrieve(ctx, req)
+}
+
+// Discard is the implementation of core.MQueue.Discard for MQueue.
+// Discards are not forwarded since each gateway is polled
+// independently by its clients
+func (m *MQueue) Discard(ctx context.Context, req core.DiscardRequest) error {
+	return m.mqueue.Discard(ctx, req)
+}
+
+// Next is the implementation of core.MQueue.Next for MQueue
+func (m *MQueue) Next(ctx context.Context, req core.NextRequest) (uint64, error) {
+	return m.mqueue.Next(ctx, req)
+}
+
+// Remove is the implementation of core.MQueue.Remove for MQueue
+func (m *MQueue) Remove(ctx context.Context, req core.RemoveRequest) error {
+	if err := m.mqueue.Remove(ctx, req); err != nil {
+		return err
+	}
+
+	m.enqueue(ctx, change{remove: &req}, req.Key)
+	return nil
+}
+
+// Exists is the implementation of core.MQueue.Exists for MQueue
+func (m *MQueue) Exists(ctx context.Context, req core.ExistsRequest) (bool, error) {
+	return m.mqueue.Exists(ctx, req)
+}
+
+// ReceiverServices are the services required by the Receiver
+type ReceiverServices struct {
+	MQueue core.MQueue
+}
+
+// ReceiverProps define the behaviour of the Receiver
+type ReceiverProps struct {
+	// Secret is the secret shared with the forwarding gateways
+	Secret []byte
+
+	// MaxNonces is the maximum number of nonces of the received
+	// requests kept to detect replays. If 0 DefaultMaxNonces is used
+	MaxNonces int
+}
+
+// Receiver applies the requests forwarded by another gateway
+// to the local mqueue
+type Receiver struct {
+	mqueue core.MQueue
+	signer *Signer
+	nonces *nonceCache
+	now    func() time.Time
+}
+
+// NewReceiver creates a new receiver. If the provided mqueue
+// forwards its changes, the receiver applies the requests to
+// the local mqueue so that forwarded elements are not sent back
+func NewReceiver(services ReceiverServices, props ReceiverProps) *Receiver {
+	mqueue := services.MQueue
+	if m, ok := mqueue.(*MQueue); ok {
+		mqueue = m.Unwrap()
+	}
+
+	return &Receiver{
+		mqueue: mqueue,
+		signer: NewSigner(props.Secret),
+		nonces: newNonceCache(props.MaxNonces),
+		now:    time.Now,
+	}
+}
+
+// verify checks the signature and timestamp of a request and
+// that its nonce has not been used by another request
+func (r *Receiver) verify(expected, signature string, timestamp int64, nonce string) errors.Err {
+	now := r.now()
+	if err := r.signer.verify(expected, signature, timestamp, now); err != nil {
+		return err
+	}
+
+	// the timestamp check already rejects the requests older than
+	// the clock skew, so nonces only need to be kept until then
+	expires := time.Unix(timestamp, 0).Add(MaxClockSkew)
+	if err := r.nonces.use(nonce, expires, now); err != nil {
+		return errors.New(errors.ErrFederationSignature, err)
+	}
+
+	return nil
+}
+
+// Insert verifies and inserts a forwarded element. Offsets in a queue
+// need to be reserved before an element can be inserted, so the
+// receiver reserves the offsets that the queue has not reserved yet
+// up to the offset of the forwarded element. The offsets reserved
+// ahead are set by the elements that are forwarded afterwards
+func (r *Receiver) Insert(ctx context.Context, req *InsertRequest) errors.Err {
+	if err := r.verify(r.signer.SignInsert(req), req.Signature, req.Timestamp, req.Nonce); err != nil {
+		return err
+	}
+
+	reserver, ok := r.mqueue.(core.Reserver)
+	if !ok {
+		return errors.New(errors.ErrQueueNext, ErrReserveNotSupported)
+	}
+
+	if _, err := reserver.Reserve(ctx, core.ReserveRequest{
+		Key:    req.Key,
+		Offset: req.Offset,
+		MaxGap: MaxOffsetGap,
+	}); err != nil {
+		if stderr.Cause(err) == core.ErrReserveGapTooLarge {
+			return errors.New(errors.ErrOutOfRange, ErrOffsetGapTooLarge)
+		}
+		return errors.New(errors.ErrQueueNext, err)
+	}
+
+	if err := r.mqueue.Insert(ctx, core.InsertRequest{
+		Key: req.Key,
+		Element: core.Element{
+			Offset: req.Offset,
+			Type:   req.Type,
+			Value:  req.Value,
+		},
+	}); err != nil {
+		return errors.New(errors.ErrQueueInsert, err)
+	}
+
+	return nil
+}
+
+// Remove verifies and applies a forwarded queue removal
+func (r *Receiver) Remove(ctx context.Context, req *RemoveRequest) errors.Err {
+	if err := r.verify(r.signer.SignRemove(req), req.Signature, req.Timestamp, req.Nonce); err != nil {
+		return err
+	}
+
+	if err := r.mqueue.Remove(ctx, core.RemoveRequest{Key: req.Key}); err != nil {
+		return errors.New(errors.ErrQueueRemove, err)
+	}
+
+	return nil
+}
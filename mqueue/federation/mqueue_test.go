@@ -0,0 +1,307 @@
+package federation
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/oasislabs/oasis-gateway/errors"
+	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/oasislabs/oasis-gateway/mqueue/core"
+	"github.com/oasislabs/oasis-gateway/mqueue/mem"
+	stderr "github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	ctx    = context.Background()
+	logger = log.NewLogrus(log.LogrusLoggerProperties{Output: ioutil.Discard})
+	secret = []byte("secret")
+)
+
+// loopbackForwarder signs the forwarded requests and applies
+// them directly to a receiver
+type loopbackForwarder struct {
+	signer   *Signer
+	receiver *Receiver
+}
+
+func (f loopbackForwarder) Insert(ctx context.Context, req core.InsertRequest) error {
+	forward := &InsertRequest{
+		Key:       req.Key,
+		Offset:    req.Element.Offset,
+		Type:      req.Element.Type,
+		Value:     req.Element.Value,
+		Timestamp: f.receiver.now().Unix(),
+		Nonce:     newNonce(),
+	}
+	forward.Signature = f.signer.SignInsert(forward)
+	if err := f.receiver.Insert(ctx, forward); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (f loopbackForwarder) Remove(ctx context.Context, req core.RemoveRequest) error {
+	forward := &RemoveRequest{Key: req.Key, Timestamp: f.receiver.now().Unix(), Nonce: newNonce()}
+	forward.Signature = f.signer.SignRemove(forward)
+	if err := f.receiver.Remove(ctx, forward); err != nil {
+		return err
+	}
+	return nil
+}
+
+type failingForwarder struct{}
+
+func (failingForwarder) Insert(context.Context, core.InsertRequest) error {
+	return stderr.New("unreachable")
+}
+
+func (failingForwarder) Remove(context.Context, core.RemoveRequest) error {
+	return stderr.New("unreachable")
+}
+
+// blockingForwarder blocks forwarding until it is released
+type blockingForwarder struct {
+	release chan struct{}
+}
+
+func (f blockingForwarder) Insert(context.Context, core.InsertRequest) error {
+	<-f.release
+	return nil
+}
+
+func (f blockingForwarder) Remove(context.Context, core.RemoveRequest) error {
+	<-f.release
+	return nil
+}
+
+func newFederated() (*MQueue, core.MQueue) {
+	remote := mem.NewServer(ctx, mem.Services{Logger: logger})
+	receiver := NewReceiver(ReceiverServices{MQueue: remote}, ReceiverProps{Secret: secret})
+	local := NewMQueue(ctx, Services{
+		Logger:    logger,
+		MQueue:    mem.NewServer(ctx, mem.Services{Logger: logger}),
+		Forwarder: loopbackForwarder{signer: NewSigner(secret), receiver: receiver},
+	}, Props{})
+	return local, remote
+}
+
+func insert(t *testing.T, m core.MQueue, offset uint64, value string) {
+	err := m.Insert(ctx, core.InsertRequest{
+		Key:     "key",
+		Element: core.Element{Offset: offset, Type: "type", Value: value},
+	})
+	assert.Nil(t, err)
+}
+
+// retrieveForwarded waits until count elements have been
+// forwarded to the remote queue and returns them
+func retrieveForwarded(t *testing.T, remote core.MQueue, count int) []core.Element {
+	var els core.Elements
+	assert.Eventually(t, func() bool {
+		var err error
+		els, err = remote.Retrieve(ctx, core.RetrieveRequest{Key: "key", Offset: 0, Count: 10})
+		return err == nil && len(els.Elements) == count
+	}, time.Second, time.Millisecond)
+
+	return els.Elements
+}
+
+// withoutTimestamps verifies that the elements have been
+// timestamped and clears the timestamps for comparison
+func withoutTimestamps(t *testing.T, els []core.Element) []core.Element {
+	for i := range els {
+		assert.NotZero(t, els[i].Timestamp)
+		els[i].Timestamp = 0
+	}
+
+	return els
+}
+
+func TestMQueueForwardInsert(t *testing.T) {
+	local, remote := newFederated()
+
+	offset, err := local.Next(ctx, core.NextRequest{Key: "key"})
+	assert.Nil(t, err)
+	insert(t, local, offset, "value")
+
+	els := retrieveForwarded(t, remote, 1)
+	assert.Equal(t, []core.Element{{Offset: offset, Type: "type", Value: "value"}}, withoutTimestamps(t, els))
+}
+
+func TestMQueueForwardInsertOutOfOrder(t *testing.T) {
+	local, remote := newFederated()
+
+	for i := 0; i < 3; i++ {
+		_, err := local.Next(ctx, core.NextRequest{Key: "key"})
+		assert.Nil(t, err)
+	}
+
+	insert(t, local, 2, "value2")
+	insert(t, local, 0, "value0")
+	insert(t, local, 1, "value1")
+
+	els := retrieveForwarded(t, remote, 3)
+	assert.Equal(t, []core.Element{
+		{Offset: 0, Type: "type", Value: "value0"},
+		{Offset: 1, Type: "type", Value: "value1"},
+		{Offset: 2, Type: "type", Value: "value2"},
+	}, withoutTimestamps(t, els))
+
+	// the offsets reserved for the out of order elements are
+	// reused, so the queues are aligned
+	next, err := remote.Next(ctx, core.NextRequest{Key: "key"})
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(3), next)
+}
+
+func TestMQueueForwardRemove(t *testing.T) {
+	local, remote := newFederated()
+
+	offset, err := local.Next(ctx, core.NextRequest{Key: "key"})
+	assert.Nil(t, err)
+	insert(t, local, offset, "value")
+
+	retrieveForwarded(t, remote, 1)
+	err = local.Remove(ctx, core.RemoveRequest{Key: "key"})
+	assert.Nil(t, err)
+
+	assert.Eventually(t, func() bool {
+		exists, err := remote.Exists(ctx, core.ExistsRequest{Key: "key"})
+		return err == nil && !exists
+	}, time.Second, time.Millisecond)
+}
+
+func TestMQueueForwardFailureKeepsLocal(t *testing.T) {
+	local := NewMQueue(ctx, Services{
+		Logger:    logger,
+		MQueue:    mem.NewServer(ctx, mem.Services{Logger: logger}),
+		Forwarder: failingForwarder{},
+	}, Props{})
+
+	offset, err := local.Next(ctx, core.NextRequest{Key: "key"})
+	assert.Nil(t, err)
+	insert(t, local, offset, "value")
+
+	els, err := local.Retrieve(ctx, core.RetrieveRequest{Key: "key", Offset: 0, Count: 10})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(els.Elements))
+}
+
+func TestMQueueForwardQueueFull(t *testing.T) {
+	forwarder := blockingForwarder{release: make(chan struct{})}
+	defer close(forwarder.release)
+	local := NewMQueue(ctx, Services{
+		Logger:    logger,
+		MQueue:    mem.NewServer(ctx, mem.Services{Logger: logger}),
+		Forwarder: forwarder,
+	}, Props{QueueSize: 1})
+
+	for i := 0; i < 3; i++ {
+		offset, err := local.Next(ctx, core.NextRequest{Key: "key"})
+		assert.Nil(t, err)
+		insert(t, local, offset, "value")
+
+		// wait for the first change to be taken by the
+		// forwarder, which blocks until it is released
+		assert.Eventually(t, func() bool {
+			return i > 0 || len(local.changes) == 0
+		}, time.Second, time.Millisecond)
+	}
+
+	assert.Equal(t, uint64(1), local.dropped.Value())
+	els, err := local.Retrieve(ctx, core.RetrieveRequest{Key: "key", Offset: 0, Count: 10})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(els.Elements))
+}
+
+func TestReceiverInsertReplayed(t *testing.T) {
+	remote := mem.NewServer(ctx, mem.Services{Logger: logger})
+	receiver := NewReceiver(ReceiverServices{MQueue: remote}, ReceiverProps{Secret: secret})
+	req := &InsertRequest{Key: "key", Offset: 0, Value: "value", Timestamp: receiver.now().Unix(), Nonce: newNonce()}
+	req.Signature = NewSigner(secret).SignInsert(req)
+
+	assert.Nil(t, receiver.Insert(ctx, req))
+
+	err := receiver.Insert(ctx, req)
+
+	assert.Equal(t, errors.New(errors.ErrFederationSignature, ErrRequestReplayed), err)
+}
+
+func TestReceiverRemoveNoNonce(t *testing.T) {
+	remote := mem.NewServer(ctx, mem.Services{Logger: logger})
+	receiver := NewReceiver(ReceiverServices{MQueue: remote}, ReceiverProps{Secret: secret})
+	req := &RemoveRequest{Key: "key", Timestamp: receiver.now().Unix()}
+	req.Signature = NewSigner(secret).SignRemove(req)
+
+	err := receiver.Remove(ctx, req)
+
+	assert.Equal(t, errors.New(errors.ErrFederationSignature, ErrInvalidNonce), err)
+}
+
+func TestReceiverInsertBadSignature(t *testing.T) {
+	remote := mem.NewServer(ctx, mem.Services{Logger: logger})
+	receiver := NewReceiver(ReceiverServices{MQueue: remote}, ReceiverProps{Secret: secret})
+
+	err := receiver.Insert(ctx, &InsertRequest{
+		Key:       "key",
+		Timestamp: receiver.now().Unix(),
+		Signature: "bad",
+	})
+
+	assert.Error(t, err)
+	exists, _ := remote.Exists(ctx, core.ExistsRequest{Key: "key"})
+	assert.False(t, exists)
+}
+
+func TestReceiverInsertGapTooLarge(t *testing.T) {
+	remote := mem.NewServer(ctx, mem.Services{Logger: logger})
+	receiver := NewReceiver(ReceiverServices{MQueue: remote}, ReceiverProps{Secret: secret})
+	req := &InsertRequest{Key: "key", Offset: 10 * MaxOffsetGap, Timestamp: receiver.now().Unix(), Nonce: newNonce()}
+	req.Signature = NewSigner(secret).SignInsert(req)
+
+	err := receiver.Insert(ctx, req)
+
+	assert.Equal(t, errors.New(errors.ErrOutOfRange, ErrOffsetGapTooLarge), err)
+}
+
+func TestNewReceiverUnwrapsMQueue(t *testing.T) {
+	remote := mem.NewServer(ctx, mem.Services{Logger: logger})
+	m := NewMQueue(ctx, Services{Logger: logger, MQueue: remote, Forwarder: failingForwarder{}}, Props{})
+
+	receiver := NewReceiver(ReceiverServices{MQueue: m}, ReceiverProps{Secret: secret})
+
+	assert.Equal(t, remote, receiver.mqueue)
+}
+
+func TestClientInsert(t *testing.T) {
+	var received *http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client := NewClient(ClientProps{URL: server.URL + "/", Secret: secret})
+	err := client.Insert(ctx, core.InsertRequest{Key: "key", Element: core.Element{Offset: 1}})
+
+	assert.Nil(t, err)
+	assert.Equal(t, InsertPath, received.URL.Path)
+	assert.Equal(t, "application/json", received.Header.Get("Content-Type"))
+}
+
+func TestClientRemoveErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	client := NewClient(ClientProps{URL: server.URL, Secret: secret})
+	err := client.Remove(ctx, core.RemoveRequest{Key: "key"})
+
+	assert.Error(t, err)
+}
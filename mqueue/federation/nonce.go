@@ -0,0 +1,92 @@
+package federation
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	stderr "github.com/pkg/errors"
+)
+
+const (
+	// DefaultMaxNonces is the number of nonces a receiver keeps
+	// if no maximum is configured
+	DefaultMaxNonces = 100000
+
+	// MinNonceLength is the minimum length of the nonce of a
+	// forwarded request
+	MinNonceLength = 16
+
+	// MaxNonceLength is the maximum length of the nonce of a
+	// forwarded request
+	MaxNonceLength = 128
+)
+
+var (
+	ErrInvalidNonce    = stderr.New("request nonce has an invalid length")
+	ErrRequestReplayed = stderr.New("request nonce has already been used")
+	ErrNonceCacheFull  = stderr.New("too many requests received within the allowed clock skew")
+)
+
+// newNonce returns a random nonce for a forwarded request
+func newNonce() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic("failed to read random bytes for nonce")
+	}
+
+	return hex.EncodeToString(b[:])
+}
+
+// nonceCache keeps the nonces of the requests received until their
+// timestamp is too old for them to be accepted, so that a captured
+// request cannot be applied again
+type nonceCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	entries    map[string]time.Time
+}
+
+func newNonceCache(maxEntries int) *nonceCache {
+	if maxEntries <= 0 {
+		maxEntries = DefaultMaxNonces
+	}
+
+	return &nonceCache{
+		maxEntries: maxEntries,
+		entries:    make(map[string]time.Time),
+	}
+}
+
+// use marks the nonce as used until it expires. It fails if the
+// nonce is already in use
+func (c *nonceCache) use(nonce string, expires, now time.Time) error {
+	if len(nonce) < MinNonceLength || len(nonce) > MaxNonceLength {
+		return ErrInvalidNonce
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if expiry, ok := c.entries[nonce]; ok && now.Before(expiry) {
+		return ErrRequestReplayed
+	}
+
+	if len(c.entries) >= c.maxEntries {
+		for key, expiry := range c.entries {
+			if !now.Before(expiry) {
+				delete(c.entries, key)
+			}
+		}
+	}
+
+	// evicting a nonce that has not expired would allow the
+	// request that used it to be replayed
+	if len(c.entries) >= c.maxEntries {
+		return ErrNonceCacheFull
+	}
+
+	c.entries[nonce] = expires
+	return nil
+}
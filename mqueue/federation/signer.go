@@ -0,0 +1,99 @@
+package federation
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"hash"
+	"time"
+
+	"github.com/oasislabs/oasis-gateway/errors"
+	stderr "github.com/pkg/errors"
+)
+
+// MaxClockSkew is the maximum difference allowed between the
+// timestamp of a forwarded request and the local clock of the
+// gateway that receives it
+const MaxClockSkew = 5 * time.Minute
+
+var (
+	ErrSignatureMismatch = stderr.New("signature does not match the request")
+	ErrRequestExpired    = stderr.New("request timestamp is out of the allowed clock skew")
+)
+
+const (
+	insertOp = "insert"
+	removeOp = "remove"
+)
+
+// Signer authenticates the requests exchanged between gateways
+// with an HMAC-SHA256 over the content of the request using a
+// secret shared by the gateways
+type Signer struct {
+	secret []byte
+}
+
+// NewSigner creates a new signer with the provided shared secret
+func NewSigner(secret []byte) *Signer {
+	return &Signer{secret: secret}
+}
+
+// SignInsert returns the signature of an InsertRequest
+func (s *Signer) SignInsert(req *InsertRequest) string {
+	mac := hmac.New(sha256.New, s.secret)
+	writeString(mac, insertOp)
+	writeString(mac, req.Key)
+	writeUint64(mac, req.Offset)
+	writeString(mac, req.Type)
+	writeString(mac, req.Value)
+	writeUint64(mac, uint64(req.Timestamp))
+	writeString(mac, req.Nonce)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignRemove returns the signature of a RemoveRequest
+func (s *Signer) SignRemove(req *RemoveRequest) string {
+	mac := hmac.New(sha256.New, s.secret)
+	writeString(mac, removeOp)
+	writeString(mac, req.Key)
+	writeUint64(mac, uint64(req.Timestamp))
+	writeString(mac, req.Nonce)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyInsert verifies the signature and timestamp of an InsertRequest
+func (s *Signer) VerifyInsert(req *InsertRequest, now time.Time) errors.Err {
+	return s.verify(s.SignInsert(req), req.Signature, req.Timestamp, now)
+}
+
+// VerifyRemove verifies the signature and timestamp of a RemoveRequest
+func (s *Signer) VerifyRemove(req *RemoveRequest, now time.Time) errors.Err {
+	return s.verify(s.SignRemove(req), req.Signature, req.Timestamp, now)
+}
+
+func (s *Signer) verify(expected, signature string, timestamp int64, now time.Time) errors.Err {
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return errors.New(errors.ErrFederationSignature, ErrSignatureMismatch)
+	}
+
+	skew := now.Sub(time.Unix(timestamp, 0))
+	if skew > MaxClockSkew || skew < -MaxClockSkew {
+		return errors.New(errors.ErrFederationSignature, ErrRequestExpired)
+	}
+
+	return nil
+}
+
+// writeString writes the string length prefixed so that
+// the concatenation of fields is not ambiguous
+func writeString(h hash.Hash, s string) {
+	writeUint64(h, uint64(len(s)))
+	_, _ = h.Write([]byte(s))
+}
+
+func writeUint64(h hash.Hash, v uint64) {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], v)
+	_, _ = h.Write(b[:])
+}
@@ -0,0 +1,68 @@
+package federation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/oasislabs/oasis-gateway/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignerVerifyInsertOK(t *testing.T) {
+	signer := NewSigner([]byte("secret"))
+	now := time.Now()
+	req := &InsertRequest{Key: "key", Offset: 1, Type: "type", Value: "value", Timestamp: now.Unix()}
+	req.Signature = signer.SignInsert(req)
+
+	assert.Nil(t, signer.VerifyInsert(req, now))
+}
+
+func TestSignerVerifyInsertTampered(t *testing.T) {
+	signer := NewSigner([]byte("secret"))
+	now := time.Now()
+	req := &InsertRequest{Key: "key", Offset: 1, Type: "type", Value: "value", Timestamp: now.Unix()}
+	req.Signature = signer.SignInsert(req)
+	req.Offset = 2
+
+	err := signer.VerifyInsert(req, now)
+
+	assert.Equal(t, errors.New(errors.ErrFederationSignature, ErrSignatureMismatch), err)
+}
+
+func TestSignerVerifyInsertWrongSecret(t *testing.T) {
+	now := time.Now()
+	req := &InsertRequest{Key: "key", Offset: 1, Timestamp: now.Unix()}
+	req.Signature = NewSigner([]byte("other")).SignInsert(req)
+
+	err := NewSigner([]byte("secret")).VerifyInsert(req, now)
+
+	assert.Equal(t, errors.New(errors.ErrFederationSignature, ErrSignatureMismatch), err)
+}
+
+func TestSignerVerifyInsertExpired(t *testing.T) {
+	signer := NewSigner([]byte("secret"))
+	now := time.Now()
+	req := &InsertRequest{Key: "key", Offset: 1, Timestamp: now.Add(-2 * MaxClockSkew).Unix()}
+	req.Signature = signer.SignInsert(req)
+
+	err := signer.VerifyInsert(req, now)
+
+	assert.Equal(t, errors.New(errors.ErrFederationSignature, ErrRequestExpired), err)
+}
+
+func TestSignerSignFieldsNotAmbiguous(t *testing.T) {
+	signer := NewSigner([]byte("secret"))
+
+	assert.NotEqual(t,
+		signer.SignInsert(&InsertRequest{Type: "ab", Value: "c"}),
+		signer.SignInsert(&InsertRequest{Type: "a", Value: "bc"}))
+}
+
+func TestSignerVerifyRemoveOK(t *testing.T) {
+	signer := NewSigner([]byte("secret"))
+	now := time.Now()
+	req := &RemoveRequest{Key: "key", Timestamp: now.Unix()}
+	req.Signature = signer.SignRemove(req)
+
+	assert.Nil(t, signer.VerifyRemove(req, now))
+}
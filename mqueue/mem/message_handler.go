@@ -2,8 +2,11 @@ package mem
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/oasislabs/oasis-gateway/concurrent"
+	"github.com/oasislabs/oasis-gateway/errors"
+	"github.com/oasislabs/oasis-gateway/log"
 	"github.com/oasislabs/oasis-gateway/mqueue/core"
 )
 
@@ -18,6 +21,10 @@ type insertRequest struct {
 type retrieveRequest struct {
 	Offset uint64
 	Count  uint
+	Types  []string
+	Since  int64
+	Until  int64
+	ByTime bool
 }
 
 type discardRequest struct {
@@ -28,18 +35,27 @@ type discardRequest struct {
 
 type nextRequest struct{}
 
+type reserveRequest struct {
+	Offset uint64
+	MaxGap uint64
+}
+
+type compactRequest struct{}
+
 // MessageHandler implements a very simple messaging queue-like
 // functionality serving requests for a single queue.
 type MessageHandler struct {
 	key    string
 	window SlidingWindow
+	logger log.Logger
 }
 
 // NewMessageHandler creates a new instance of a worker
-func NewMessageHandler(key string) *MessageHandler {
+func NewMessageHandler(key string, logger log.Logger) *MessageHandler {
 	w := &MessageHandler{
 		key:    key,
 		window: NewSlidingWindow(SlidingWindowProps{MaxSize: maxElementsPerQueue}),
+		logger: logger,
 	}
 
 	return w
@@ -52,7 +68,12 @@ func (w *MessageHandler) handle(ctx context.Context, ev concurrent.WorkerEvent)
 	case concurrent.ErrorWorkerEvent:
 		return w.handleErrorEvent(ctx, ev)
 	default:
-		panic("receive unexpected event type")
+		w.logger.Warn(ctx, "received unexpected event", log.MapFields{
+			"call_type": "HandleEventFailure",
+			"key":       w.key,
+			"type":      fmt.Sprintf("%T", ev),
+		})
+		return nil, concurrent.ErrUnexpectedEvent{Event: ev}
 	}
 }
 
@@ -64,12 +85,21 @@ func (w *MessageHandler) handleRequestEvent(ctx context.Context, ev concurrent.R
 	case retrieveRequest:
 		return w.retrieve(req)
 	case discardRequest:
-		err := w.discard(req)
+		err := w.discard(ctx, req)
 		return nil, err
 	case nextRequest:
 		return w.next(req)
+	case reserveRequest:
+		return w.reserve(req)
+	case compactRequest:
+		return w.compact(ctx, req)
 	default:
-		panic("invalid request received for worker")
+		w.logger.Warn(ctx, "received unexpected request", log.MapFields{
+			"call_type": "HandleRequestFailure",
+			"key":       w.key,
+			"type":      fmt.Sprintf("%T", req),
+		})
+		return nil, concurrent.ErrUnexpectedRequest{Request: req}
 	}
 }
 
@@ -81,24 +111,62 @@ func (w *MessageHandler) handleErrorEvent(ctx context.Context, ev concurrent.Err
 }
 
 func (w *MessageHandler) insert(req insertRequest) error {
-	return w.window.Set(req.Element.Offset, req.Element.Type, req.Element.Value)
+	return w.window.Set(req.Element.Offset, req.Element.Type, req.Element.Value, req.Element.Timestamp)
 }
 
 func (w *MessageHandler) retrieve(req retrieveRequest) (core.Elements, error) {
-	return w.window.Get(req.Offset, req.Count)
+	var els core.Elements
+	var err error
+	if req.ByTime {
+		els, err = w.window.GetRange(req.Since, req.Until, req.Count)
+	} else {
+		els, err = w.window.Get(req.Offset, req.Count)
+	}
+	if err != nil {
+		return core.Elements{}, err
+	}
+
+	return els.Filter(req.Types), nil
 }
 
-func (w *MessageHandler) discard(req discardRequest) error {
+func (w *MessageHandler) discard(ctx context.Context, req discardRequest) error {
 	if !req.KeepPrevious {
 		if _, err := w.window.Slide(req.Offset); err != nil {
 			return err
 		}
 	}
 
-	_, err := w.window.Discard(req.Offset, req.Count)
-	return err
+	if _, err := w.window.Discard(req.Offset, req.Count); err != nil {
+		// internal errors mean the state of the window is not the
+		// expected one, as opposed to a request for a wrong offset
+		if err.ErrorCode() == errors.ErrInvalidStateChangeError {
+			w.logger.Warn(ctx, "failed to discard elements", log.MapFields{
+				"call_type": "DiscardFailure",
+				"key":       w.key,
+			}, err)
+		}
+		return err
+	}
+
+	return nil
 }
 
 func (w *MessageHandler) next(req nextRequest) (uint64, error) {
 	return w.window.ReserveNext()
 }
+
+func (w *MessageHandler) reserve(req reserveRequest) (uint64, error) {
+	return w.window.ReserveTo(req.Offset, req.MaxGap)
+}
+
+func (w *MessageHandler) compact(ctx context.Context, req compactRequest) (core.Usage, error) {
+	if _, err := w.window.Compact(); err != nil {
+		w.logger.Warn(ctx, "failed to compact queue", log.MapFields{
+			"call_type": "CompactFailure",
+			"key":       w.key,
+		}, err)
+		return core.Usage{}, err
+	}
+
+	return w.window.Usage(), nil
+}
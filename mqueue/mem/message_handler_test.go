@@ -16,7 +16,7 @@ func (e InvalidEvent) GetWorker() *concurrent.Worker {
 }
 
 func TestMessageHandlerHandleError(t *testing.T) {
-	handler := NewMessageHandler("key")
+	handler := NewMessageHandler("key", logger)
 
 	v, err := handler.handle(context.TODO(), concurrent.ErrorWorkerEvent{
 		Worker: nil,
@@ -28,20 +28,22 @@ func TestMessageHandlerHandleError(t *testing.T) {
 }
 
 func TestMessageHandlerHandleUnknown(t *testing.T) {
-	handler := NewMessageHandler("key")
+	handler := NewMessageHandler("key", logger)
 
-	assert.Panics(t, func() {
-		_, _ = handler.handle(context.TODO(), InvalidEvent{})
-	})
+	v, err := handler.handle(context.TODO(), InvalidEvent{})
+
+	assert.Nil(t, v)
+	assert.Equal(t, concurrent.ErrUnexpectedEvent{Event: InvalidEvent{}}, err)
 }
 
 func TestMessageHandlerHandleWorkerRequestUnknown(t *testing.T) {
-	handler := NewMessageHandler("key")
+	handler := NewMessageHandler("key", logger)
 
-	assert.Panics(t, func() {
-		_, _ = handler.handle(context.TODO(), concurrent.RequestWorkerEvent{
-			Worker: nil,
-			Value:  "unknown",
-		})
+	v, err := handler.handle(context.TODO(), concurrent.RequestWorkerEvent{
+		Worker: nil,
+		Value:  "unknown",
 	})
+
+	assert.Nil(t, v)
+	assert.Equal(t, concurrent.ErrUnexpectedRequest{Request: "unknown"}, err)
 }
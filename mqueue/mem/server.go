@@ -12,9 +12,16 @@ import (
 
 const maxInactivityTimeout = time.Duration(10) * time.Minute
 
+// maxRequestTimeout is the time a request whose context has no deadline
+// waits for the worker of its queue before it fails, so that a worker
+// that stalls does not block its callers forever
+const maxRequestTimeout = time.Duration(10) * time.Second
+
 type Server struct {
-	master *concurrent.Master
-	logger log.Logger
+	master  *concurrent.Master
+	logger  log.Logger
+	account *core.StorageAccount
+	store   *core.MemStore
 }
 
 type Services struct {
@@ -23,12 +30,15 @@ type Services struct {
 
 func NewServer(ctx context.Context, services Services) *Server {
 	s := &Server{
-		logger: services.Logger.ForClass("mqueue/mem", "Server"),
+		logger:  services.Logger.ForClass("mqueue/mem", "Server"),
+		account: core.NewStorageAccount(),
+		store:   core.NewMemStore(),
 	}
 
 	s.master = concurrent.NewMaster(concurrent.MasterProps{
 		MasterHandler:         concurrent.MasterHandlerFunc(s.handle),
 		CreateWorkerOnRequest: true,
+		OnWorkerPanic:         s.workerPanic,
 	})
 
 	if err := s.master.Start(ctx); err != nil {
@@ -45,12 +55,20 @@ func (m *Server) handle(ctx context.Context, ev concurrent.MasterEvent) error {
 	case concurrent.DestroyWorkerEvent:
 		return m.destroy(ctx, ev)
 	default:
-		panic("received unknown request")
+		return concurrent.ErrUnexpectedEvent{Event: ev}
 	}
 }
 
+func (s *Server) workerPanic(key string, err error) {
+	s.logger.Warn(context.Background(), "queue worker loop panicked and is restarted", log.MapFields{
+		"call_type": "WorkerPanic",
+		"key":       key,
+		"err":       err.Error(),
+	})
+}
+
 func (s *Server) create(ctx context.Context, ev concurrent.CreateWorkerEvent) error {
-	worker := NewMessageHandler(ev.Key)
+	worker := NewMessageHandler(ev.Key, s.logger)
 
 	ev.Props.ErrC = nil
 	ev.Props.WorkerHandler = concurrent.WorkerHandlerFunc(worker.handle)
@@ -61,20 +79,58 @@ func (s *Server) create(ctx context.Context, ev concurrent.CreateWorkerEvent) er
 }
 
 func (s *Server) destroy(ctx context.Context, ev concurrent.DestroyWorkerEvent) error {
-	// nothing to do on a destroy to cleanup the worker
+	// the worker holds all the state of the queue, so only the
+	// accounting needs to be cleaned up
+	s.account.Remove(ev.Key)
 	return nil
 }
 
+// withTimeout bounds the time a request waits for the worker of its
+// queue if the caller has not set a deadline
+func withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok {
+		return ctx, func() {}
+	}
+
+	return context.WithTimeout(ctx, maxRequestTimeout)
+}
+
 // Insert inserts the element to the provided offset.
 func (s *Server) Insert(ctx context.Context, req core.InsertRequest) error {
-	_, err := s.master.Request(ctx, req.Key, insertRequest{Element: req.Element})
-	return err
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	if req.Element.Timestamp == 0 {
+		req.Element.Timestamp = core.Timestamp(time.Now())
+	}
+	if _, err := s.master.Request(ctx, req.Key, insertRequest{Element: req.Element}); err != nil {
+		return err
+	}
+
+	s.account.Insert(req.Key, uint64(len(req.Element.Value)+len(req.Element.Type)))
+	return nil
 }
 
 // Retrieve all available elements from the
 // messaging queue after the provided offset
 func (s *Server) Retrieve(ctx context.Context, req core.RetrieveRequest) (core.Elements, error) {
-	v, err := s.master.Request(ctx, req.Key, retrieveRequest{Offset: req.Offset, Count: req.Count})
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	r := retrieveRequest{
+		Offset: req.Offset,
+		Count:  req.Count,
+		Types:  req.Types,
+		ByTime: req.ByTime(),
+	}
+	if !req.Since.IsZero() {
+		r.Since = core.Timestamp(req.Since)
+	}
+	if !req.Until.IsZero() {
+		r.Until = core.Timestamp(req.Until)
+	}
+
+	v, err := s.master.Request(ctx, req.Key, r)
 	if err != nil {
 		return core.Elements{}, err
 	}
@@ -85,6 +141,9 @@ func (s *Server) Retrieve(ctx context.Context, req core.RetrieveRequest) (core.E
 // Discard all elements that have a prior or equal
 // offset to the provided offset
 func (s *Server) Discard(ctx context.Context, req core.DiscardRequest) error {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
 	_, err := s.master.Request(ctx, req.Key, discardRequest{
 		KeepPrevious: req.KeepPrevious,
 		Count:        req.Count,
@@ -95,6 +154,9 @@ func (s *Server) Discard(ctx context.Context, req core.DiscardRequest) error {
 
 // Next element offset that can be used for the queue.
 func (s *Server) Next(ctx context.Context, req core.NextRequest) (uint64, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
 	v, err := s.master.Request(ctx, req.Key, nextRequest{})
 	if err != nil {
 		return 0, err
@@ -103,21 +165,108 @@ func (s *Server) Next(ctx context.Context, req core.NextRequest) (uint64, error)
 	return v.(uint64), nil
 }
 
+// Reserve reserves the offsets of the queue up to the provided offset
+func (s *Server) Reserve(ctx context.Context, req core.ReserveRequest) (uint64, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	v, err := s.master.Request(ctx, req.Key, reserveRequest{
+		Offset: req.Offset,
+		MaxGap: req.MaxGap,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return v.(uint64), nil
+}
+
 // Remove the key's queue and it's associated resources
 func (s *Server) Remove(ctx context.Context, req core.RemoveRequest) error {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
 	return s.master.Destroy(ctx, req.Key)
 }
 
 // Exists returns true if there is a queue allocated with the
 // provided key
 func (s *Server) Exists(ctx context.Context, req core.ExistsRequest) (bool, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
 	return s.master.Exists(ctx, req.Key)
 }
 
+// MaxElements returns the maximum number of elements a queue keeps.
+// The window keeps its last element free, so one fewer elements than
+// its size can be reserved
+func (s *Server) MaxElements() uint64 {
+	return maxElementsPerQueue - 1
+}
+
+// Keys returns the keys of the queues for which storage is accounted
+func (s *Server) Keys() []string {
+	return s.account.Keys()
+}
+
+// Compact releases the values of the discarded elements of the
+// queue and returns the storage the queue uses afterwards
+func (s *Server) Compact(ctx context.Context, req core.CompactRequest) (core.Usage, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	ok, err := s.master.Exists(ctx, req.Key)
+	if err != nil {
+		return core.Usage{}, err
+	}
+	if !ok {
+		s.account.Remove(req.Key)
+		return core.Usage{}, nil
+	}
+
+	v, err := s.master.Request(ctx, req.Key, compactRequest{})
+	if err != nil {
+		return core.Usage{}, err
+	}
+
+	usage := v.(core.Usage)
+	s.account.Set(req.Key, usage)
+	return usage, nil
+}
+
+// GetField returns the value of a field of the Store
+func (s *Server) GetField(ctx context.Context, req core.FieldRequest) (string, bool, error) {
+	return s.store.GetField(ctx, req)
+}
+
+// SetField sets the value of a field of the Store
+func (s *Server) SetField(ctx context.Context, req core.SetFieldRequest) (string, bool, error) {
+	return s.store.SetField(ctx, req)
+}
+
+// DeleteField removes a field of the Store
+func (s *Server) DeleteField(ctx context.Context, req core.DeleteFieldRequest) (bool, error) {
+	return s.store.DeleteField(ctx, req)
+}
+
+// Fields returns all the fields of a hash of the Store
+func (s *Server) Fields(ctx context.Context, req core.FieldsRequest) (map[string]string, error) {
+	return s.store.Fields(ctx, req)
+}
+
+// IncrField increments the value of a field of the Store
+func (s *Server) IncrField(ctx context.Context, req core.IncrFieldRequest) (uint64, error) {
+	return s.store.IncrField(ctx, req)
+}
+
 func (s *Server) Name() string {
 	return "mqueue.mem.Server"
 }
 
 func (s *Server) Stats() stats.Metrics {
-	return nil
+	return stats.Metrics{
+		"storage": s.account.Stats(),
+		"workers": s.master.Stats(),
+	}
 }
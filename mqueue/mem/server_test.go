@@ -4,9 +4,11 @@ import (
 	"context"
 	"io/ioutil"
 	"testing"
+	"time"
 
 	"github.com/oasislabs/oasis-gateway/log"
 	"github.com/oasislabs/oasis-gateway/mqueue/core"
+	"github.com/oasislabs/oasis-gateway/stats"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 )
@@ -19,6 +21,17 @@ var (
 	})
 )
 
+// withoutTimestamps verifies that the elements have been
+// timestamped and clears the timestamps for comparison
+func withoutTimestamps(t *testing.T, els core.Elements) core.Elements {
+	for i := range els.Elements {
+		assert.NotZero(t, els.Elements[i].Timestamp)
+		els.Elements[i].Timestamp = 0
+	}
+
+	return els
+}
+
 func TestServerInsert(t *testing.T) {
 	s := NewServer(context.TODO(), Services{Logger: logger})
 
@@ -40,7 +53,7 @@ func TestServerRetrieve(t *testing.T) {
 	assert.Equal(t, core.Elements{
 		Offset:   uint64(0),
 		Elements: []core.Element{},
-	}, els)
+	}, withoutTimestamps(t, els))
 
 	var offset uint64
 	offset, err = s.Next(ctx, core.NextRequest{Key: "key"})
@@ -62,6 +75,87 @@ func TestServerRetrieve(t *testing.T) {
 				Value:  "value",
 			},
 		},
+	}, withoutTimestamps(t, els))
+}
+
+func TestServerRetrieveTypes(t *testing.T) {
+	s := NewServer(context.TODO(), Services{Logger: logger})
+
+	for _, typ := range []string{"a", "b", "a"} {
+		offset, err := s.Next(ctx, core.NextRequest{Key: "key"})
+		assert.Nil(t, err)
+
+		err = s.Insert(ctx, core.InsertRequest{Key: "key", Element: core.Element{
+			Offset: offset,
+			Type:   typ,
+			Value:  "value",
+		}})
+		assert.Nil(t, err)
+	}
+
+	els, err := s.Retrieve(ctx, core.RetrieveRequest{
+		Key:    "key",
+		Offset: 0,
+		Count:  uint(3),
+		Types:  []string{"a"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, core.Elements{
+		Offset: 0,
+		Elements: []core.Element{
+			{Offset: 0, Type: "a", Value: "value"},
+			{Offset: 2, Type: "a", Value: "value"},
+		},
+	}, withoutTimestamps(t, els))
+
+	els, err = s.Retrieve(ctx, core.RetrieveRequest{
+		Key:    "key",
+		Offset: 0,
+		Count:  uint(3),
+		Types:  []string{"c"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, core.Elements{Offset: 0, Elements: []core.Element{}}, withoutTimestamps(t, els))
+}
+
+func TestServerRetrieveByTime(t *testing.T) {
+	s := NewServer(context.TODO(), Services{Logger: logger})
+
+	for i := 0; i < 3; i++ {
+		offset, err := s.Next(ctx, core.NextRequest{Key: "key"})
+		assert.Nil(t, err)
+
+		err = s.Insert(ctx, core.InsertRequest{Key: "key", Element: core.Element{
+			Offset:    offset,
+			Value:     "value",
+			Timestamp: int64(3000 - i*1000),
+		}})
+		assert.Nil(t, err)
+	}
+
+	els, err := s.Retrieve(ctx, core.RetrieveRequest{
+		Key:   "key",
+		Count: uint(10),
+		Since: time.Unix(2, 0),
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, core.Elements{
+		Offset: 1,
+		Elements: []core.Element{
+			{Offset: 1, Value: "value", Timestamp: 2000},
+			{Offset: 0, Value: "value", Timestamp: 3000},
+		},
+	}, els)
+
+	els, err = s.Retrieve(ctx, core.RetrieveRequest{
+		Key:   "key",
+		Count: uint(10),
+		Until: time.Unix(2, 0),
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, core.Elements{
+		Offset:   2,
+		Elements: []core.Element{{Offset: 2, Value: "value", Timestamp: 1000}},
 	}, els)
 }
 
@@ -99,7 +193,7 @@ func TestServerDiscardKeepPreviousFalse(t *testing.T) {
 				Value:  "value",
 			},
 		},
-	}, els)
+	}, withoutTimestamps(t, els))
 }
 
 func TestServerDiscardKeepPreviousTrue(t *testing.T) {
@@ -141,7 +235,7 @@ func TestServerDiscardKeepPreviousTrue(t *testing.T) {
 				Value:  "value",
 			},
 		},
-	}, els)
+	}, withoutTimestamps(t, els))
 }
 
 func TestServerNext(t *testing.T) {
@@ -156,6 +250,25 @@ func TestServerNext(t *testing.T) {
 	assert.Equal(t, uint64(1), offset)
 }
 
+func TestServerReserve(t *testing.T) {
+	s := NewServer(context.TODO(), Services{Logger: logger})
+
+	n, err := s.Reserve(ctx, core.ReserveRequest{Key: "key", Offset: 3, MaxGap: 10})
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(4), n)
+
+	n, err = s.Reserve(ctx, core.ReserveRequest{Key: "key", Offset: 2, MaxGap: 10})
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0), n)
+
+	_, err = s.Reserve(ctx, core.ReserveRequest{Key: "key", Offset: 10, MaxGap: 2})
+	assert.Error(t, err)
+
+	offset, err := s.Next(ctx, core.NextRequest{Key: "key"})
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(4), offset)
+}
+
 func TestServerRemove(t *testing.T) {
 	s := NewServer(context.TODO(), Services{Logger: logger})
 
@@ -182,6 +295,22 @@ func TestServerNextErrLimitReached(t *testing.T) {
 	assert.Equal(t, 1024, it)
 }
 
+func TestServerMaxElements(t *testing.T) {
+	s := NewServer(context.TODO(), Services{Logger: logger})
+
+	for i := uint64(0); i < s.MaxElements(); i++ {
+		_, err := s.Next(ctx, core.NextRequest{Key: "key"})
+		assert.Nil(t, err)
+	}
+
+	_, err := s.Next(ctx, core.NextRequest{Key: "key"})
+	assert.Error(t, err)
+
+	limit, ok := core.LimitOf(s)
+	assert.True(t, ok)
+	assert.Equal(t, s.MaxElements(), limit)
+}
+
 func TestServerName(t *testing.T) {
 	s := NewServer(context.TODO(), Services{Logger: logger})
 	assert.Equal(t, "mqueue.mem.Server", s.Name())
@@ -190,5 +319,96 @@ func TestServerName(t *testing.T) {
 func TestServerStats(t *testing.T) {
 	s := NewServer(context.TODO(), Services{Logger: logger})
 
-	assert.Nil(t, s.Stats())
+	assert.Equal(t, stats.Metrics{
+		"storage": stats.Metrics{
+			"keys":      0,
+			"elements":  uint64(0),
+			"discarded": uint64(0),
+			"bytes":     uint64(0),
+			"largest":   stats.Metrics{},
+		},
+		"workers": stats.Metrics{
+			"active":       int64(0),
+			"loopRestarts": uint64(0),
+			"workerPanics": uint64(0),
+			"workerExits":  uint64(0),
+		},
+	}, s.Stats())
+}
+
+func TestServerCompact(t *testing.T) {
+	s := NewServer(context.TODO(), Services{Logger: logger})
+
+	for i := 0; i < 4; i++ {
+		offset, err := s.Next(ctx, core.NextRequest{Key: "key"})
+		assert.Nil(t, err)
+
+		err = s.Insert(ctx, core.InsertRequest{Key: "key", Element: core.Element{
+			Offset: offset,
+			Value:  "value",
+		}})
+		assert.Nil(t, err)
+	}
+
+	err := s.Discard(ctx, core.DiscardRequest{Key: "key", KeepPrevious: true, Offset: 1, Count: 2})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"key"}, s.Keys())
+
+	usage, err := s.Compact(ctx, core.CompactRequest{Key: "key"})
+	assert.Nil(t, err)
+	assert.Equal(t, core.Usage{Elements: 4, Discarded: 2, Bytes: 10}, usage)
+	assert.Equal(t, uint64(10), s.Stats()["storage"].(stats.Metrics)["bytes"])
+}
+
+func TestServerCompactRemoved(t *testing.T) {
+	s := NewServer(context.TODO(), Services{Logger: logger})
+
+	_, err := s.Next(ctx, core.NextRequest{Key: "key"})
+	assert.Nil(t, err)
+
+	err = s.Remove(ctx, core.RemoveRequest{Key: "key"})
+	assert.Nil(t, err)
+
+	usage, err := s.Compact(ctx, core.CompactRequest{Key: "key"})
+	assert.Nil(t, err)
+	assert.Equal(t, core.Usage{}, usage)
+	assert.Empty(t, s.Keys())
+}
+
+func TestServerContextDone(t *testing.T) {
+	s := NewServer(context.TODO(), Services{Logger: logger})
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	_, err := s.Next(cancelled, core.NextRequest{Key: "key"})
+	assert.Equal(t, context.Canceled, err)
+
+	err = s.Insert(cancelled, core.InsertRequest{Key: "key", Element: core.Element{Offset: 0, Value: "value"}})
+	assert.Equal(t, context.Canceled, err)
+
+	_, err = s.Retrieve(cancelled, core.RetrieveRequest{Key: "key", Offset: 0, Count: 1})
+	assert.Equal(t, context.Canceled, err)
+
+	// the requests have not been handled
+	offset, err := s.Next(ctx, core.NextRequest{Key: "key"})
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0), offset)
+}
+
+func TestServerWithTimeout(t *testing.T) {
+	reqCtx, cancel := withTimeout(ctx)
+	defer cancel()
+	deadline, ok := reqCtx.Deadline()
+	assert.True(t, ok)
+	assert.True(t, time.Until(deadline) <= maxRequestTimeout)
+
+	expected := time.Now().Add(time.Minute)
+	callerCtx, cancelCaller := context.WithDeadline(ctx, expected)
+	defer cancelCaller()
+	reqCtx, cancel = withTimeout(callerCtx)
+	defer cancel()
+	deadline, ok = reqCtx.Deadline()
+	assert.True(t, ok)
+	assert.Equal(t, expected, deadline)
 }
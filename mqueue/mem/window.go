@@ -1,7 +1,7 @@
 package mem
 
 import (
-	"fmt"
+	"sort"
 
 	"github.com/oasislabs/oasis-gateway/errors"
 	"github.com/oasislabs/oasis-gateway/mqueue/core"
@@ -15,6 +15,7 @@ type element struct {
 	Offset    uint64
 	Value     string
 	Type      string
+	Timestamp int64
 }
 
 var (
@@ -22,6 +23,7 @@ var (
 	ErrOffsetOutOfWindow = stderr.New("offset is out of the window's range")
 	ErrOffsetNotReserved = stderr.New("offset is not reserved")
 	ErrOffsetAlreadySet  = stderr.New("offset is already set")
+	ErrOffsetInUse       = stderr.New("offset is already reserved")
 )
 
 // SlidingWindow is a sliding window of elements that keep track of
@@ -98,9 +100,10 @@ func (w *SlidingWindow) Get(offset uint64, count uint) (core.Elements, errors.Er
 		element := &w.elements[i]
 		if element.Reserved && element.Set && !element.Discarded {
 			res.Elements = append(res.Elements, core.Element{
-				Offset: element.Offset,
-				Value:  element.Value,
-				Type:   element.Type,
+				Offset:    element.Offset,
+				Value:     element.Value,
+				Type:      element.Type,
+				Timestamp: element.Timestamp,
 			})
 		}
 	}
@@ -108,6 +111,43 @@ func (w *SlidingWindow) Get(offset uint64, count uint) (core.Elements, errors.Er
 	return res, nil
 }
 
+// GetRange returns at most count elements inserted at or after
+// since and before until, ordered by the time they were inserted.
+// If until is 0 the range is not bounded
+func (w *SlidingWindow) GetRange(since, until int64, count uint) (core.Elements, errors.Err) {
+	els := make([]core.Element, 0, 16)
+	for i := uint(0); i < w.nextUnreservedIndex; i++ {
+		element := &w.elements[i]
+		if !element.Reserved || !element.Set || element.Discarded {
+			continue
+		}
+		if element.Timestamp < since || (until > 0 && element.Timestamp >= until) {
+			continue
+		}
+
+		els = append(els, core.Element{
+			Offset:    element.Offset,
+			Value:     element.Value,
+			Type:      element.Type,
+			Timestamp: element.Timestamp,
+		})
+	}
+
+	sort.SliceStable(els, func(i, j int) bool {
+		return els[i].Timestamp < els[j].Timestamp
+	})
+	if uint(len(els)) > count {
+		els = els[:count]
+	}
+
+	res := core.Elements{Elements: els}
+	if len(els) > 0 {
+		res.Offset = els[0].Offset
+	}
+
+	return res, nil
+}
+
 // ReserveNext reserves the next offset available in the
 // window, or an error if it is not possible to provide
 // a next offset because either the window cannot grow more
@@ -124,12 +164,12 @@ func (w *SlidingWindow) ReserveNext() (uint64, errors.Err) {
 	offset := uint64(w.nextUnreservedIndex) + w.offset
 
 	if offset-w.offset >= uint64(len(w.elements)) {
-		panic("offset is out of the window's range")
+		return 0, errors.New(errors.ErrInvalidStateChangeError, ErrOffsetOutOfWindow)
 	}
 
 	index := uint(offset - w.offset)
 	if w.elements[index].Reserved || w.elements[index].Set {
-		panic("attempt to reserve element in use")
+		return 0, errors.New(errors.ErrInvalidStateChangeError, ErrOffsetInUse)
 	}
 
 	w.elements[index].Reserved = true
@@ -139,16 +179,42 @@ func (w *SlidingWindow) ReserveNext() (uint64, errors.Err) {
 	return offset, nil
 }
 
+// ReserveTo reserves the offsets that are not reserved yet up to
+// and including offset and returns how many were reserved. No offset
+// is reserved if more than maxGap offsets are needed to reach offset.
+// If the window cannot make room for all of them, the offsets reserved
+// until then are kept
+func (w *SlidingWindow) ReserveTo(offset, maxGap uint64) (uint64, errors.Err) {
+	next := w.offset + uint64(w.nextUnreservedIndex)
+	if offset < next {
+		return 0, nil
+	}
+
+	gap := offset - next + 1
+	if gap > maxGap {
+		return 0, errors.New(errors.ErrOutOfRange, core.ErrReserveGapTooLarge)
+	}
+
+	for i := uint64(0); i < gap; i++ {
+		if _, err := w.ReserveNext(); err != nil {
+			return i, err
+		}
+	}
+
+	return gap, nil
+}
+
 // Offset returns the current base offset where the first element
 // contained by the window is
 func (w *SlidingWindow) Offset() uint64 {
 	return w.offset
 }
 
-// Set sets the value for the element at offset `offset`. If the
-// offset is not in the window's range or the element's state is not
-// reserved or already set an error will be returned
-func (w *SlidingWindow) Set(offset uint64, valueType, value string) errors.Err {
+// Set sets the value for the element at offset `offset` and the
+// time at which it was inserted. If the offset is not in the window's
+// range or the element's state is not reserved or already set an
+// error will be returned
+func (w *SlidingWindow) Set(offset uint64, valueType, value string, timestamp int64) errors.Err {
 	if w.offset > offset || offset > w.offset+uint64(len(w.elements)) {
 		return errors.New(errors.ErrOutOfRange, ErrOffsetOutOfWindow)
 	}
@@ -165,6 +231,7 @@ func (w *SlidingWindow) Set(offset uint64, valueType, value string) errors.Err {
 	w.elements[index].Set = true
 	w.elements[index].Type = valueType
 	w.elements[index].Value = value
+	w.elements[index].Timestamp = timestamp
 
 	w.updateUnsetIndex(index)
 
@@ -192,7 +259,8 @@ func (w *SlidingWindow) Slide(offset uint64) (uint, errors.Err) {
 
 // Discard marks the requested elements as discarded. Discarded
 // elements are not returned on a Get request, and if possible,
-// the window is slided
+// the window is slided. If the window fails to slide the elements
+// are still discarded and an internal error is returned
 func (w *SlidingWindow) Discard(offset uint64, count uint) (uint, errors.Err) {
 	if count == 0 {
 		return 0, nil
@@ -225,15 +293,56 @@ func (w *SlidingWindow) Discard(offset uint64, count uint) (uint, errors.Err) {
 	if index == 0 {
 		// if the discard started at the beginning of the window we can directly
 		// slide it up to the maximum contiguous index
-		_, err := w.Slide(uint64(maxContiguousIndex) + w.offset + 1)
-		if err != nil {
-			panic(fmt.Sprintf("Failed to slide window after discard %s", err.Error()))
+		if _, err := w.Slide(uint64(maxContiguousIndex) + w.offset + 1); err != nil {
+			return counter, errors.New(errors.ErrInvalidStateChangeError,
+				stderr.Wrap(err, "failed to slide window after discard"))
 		}
 	}
 
 	return counter, nil
 }
 
+// Compact releases the values held by the discarded elements that
+// cannot be dropped yet because there are elements before them still
+// in use, and slides the window past the discarded elements at its
+// beginning. It returns the number of elements compacted, and an
+// internal error if the window fails to slide
+func (w *SlidingWindow) Compact() (uint, errors.Err) {
+	counter := uint(0)
+
+	for i := uint(0); i < w.nextUnreservedIndex; i++ {
+		element := &w.elements[i]
+		if element.Discarded && (len(element.Value) > 0 || len(element.Type) > 0) {
+			element.Value = ""
+			element.Type = ""
+			counter++
+		}
+	}
+
+	n, err := w.slide(w.offset)
+	if err != nil {
+		return counter, errors.New(errors.ErrInvalidStateChangeError,
+			stderr.Wrap(err, "failed to slide window on compaction"))
+	}
+
+	return counter + n, nil
+}
+
+// Usage returns the storage used by the elements in the window
+func (w *SlidingWindow) Usage() core.Usage {
+	usage := core.Usage{Elements: uint64(w.nextUnreservedIndex)}
+
+	for i := uint(0); i < w.nextUnreservedIndex; i++ {
+		element := &w.elements[i]
+		if element.Discarded {
+			usage.Discarded++
+		}
+		usage.Bytes += uint64(len(element.Value) + len(element.Type))
+	}
+
+	return usage
+}
+
 // makeRoom either grows the window or slides it in order to
 // make room for new elements. It returns the number of elements
 // that have been made available
@@ -287,6 +396,7 @@ func (w *SlidingWindow) slide(offset uint64) (uint, errors.Err) {
 		w.elements[i].Reserved = false
 		w.elements[i].Offset = 0
 		w.elements[i].Value = ""
+		w.elements[i].Timestamp = 0
 	}
 
 	w.offset += uint64(limit)
@@ -20,7 +20,7 @@ func TestSlidingWindowSet(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, uint64(0), next)
 
-	err = w.Set(next, "", "value")
+	err = w.Set(next, "", "value", 0)
 	assert.Nil(t, err)
 
 	els, err := w.Get(0, 1)
@@ -38,10 +38,10 @@ func TestSlidingWindowAlreadySet(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, uint64(0), next)
 
-	err = w.Set(next, "", "value")
+	err = w.Set(next, "", "value", 0)
 	assert.Nil(t, err)
 
-	err = w.Set(next, "", "value")
+	err = w.Set(next, "", "value", 0)
 	assert.Equal(t, ErrOffsetAlreadySet, err.Cause())
 }
 
@@ -59,7 +59,7 @@ func TestSlidingWindowSetMultipleSlideFixed(t *testing.T) {
 		assert.Nil(t, err)
 		assert.Equal(t, uint64(i), next)
 
-		err = w.Set(next, "", strconv.Itoa(i))
+		err = w.Set(next, "", strconv.Itoa(i), 0)
 		assert.Nil(t, err)
 	}
 
@@ -87,7 +87,7 @@ func TestSlidingWindowSetMultipleSlideWithGrowth(t *testing.T) {
 		assert.Nil(t, err)
 		assert.Equal(t, uint64(i), next)
 
-		err = w.Set(next, "", strconv.Itoa(i))
+		err = w.Set(next, "", strconv.Itoa(i), 0)
 		assert.Nil(t, err)
 	}
 
@@ -106,7 +106,7 @@ func TestSlidingWindowSetNotReserved(t *testing.T) {
 		MaxSize: 16,
 	})
 
-	err := w.Set(0, "", "value")
+	err := w.Set(0, "", "value", 0)
 	assert.Equal(t, ErrOffsetNotReserved, err.Cause())
 }
 
@@ -120,7 +120,7 @@ func TestSlidingWindowReserveToLimit(t *testing.T) {
 		assert.Nil(t, err)
 		assert.Equal(t, uint64(i), next)
 
-		err = w.Set(next, "", strconv.Itoa(i))
+		err = w.Set(next, "", strconv.Itoa(i), 0)
 		assert.Nil(t, err)
 	}
 
@@ -139,7 +139,7 @@ func TestSlidingWindowSlideAll(t *testing.T) {
 		assert.Nil(t, err)
 		assert.Equal(t, uint64(i), next)
 
-		err = w.Set(next, "", strconv.Itoa(i))
+		err = w.Set(next, "", strconv.Itoa(i), 0)
 		assert.Nil(t, err)
 	}
 
@@ -213,7 +213,7 @@ func TestDiscardAndSlide(t *testing.T) {
 		assert.Nil(t, err)
 		assert.Equal(t, uint64(i), next)
 
-		err = w.Set(next, "", strconv.Itoa(i))
+		err = w.Set(next, "", strconv.Itoa(i), 0)
 		assert.Nil(t, err)
 	}
 
@@ -237,7 +237,7 @@ func TestGetDiscarded(t *testing.T) {
 		assert.Nil(t, err)
 		assert.Equal(t, uint64(i), next)
 
-		err = w.Set(next, "", strconv.Itoa(i))
+		err = w.Set(next, "", strconv.Itoa(i), 0)
 		assert.Nil(t, err)
 	}
 
@@ -255,3 +255,37 @@ func TestGetDiscarded(t *testing.T) {
 		{Offset: 0x8, Value: "8", Type: ""},
 		{Offset: 0x9, Value: "9", Type: ""}}, els.Elements)
 }
+
+func TestSlidingWindowCompact(t *testing.T) {
+	w := NewSlidingWindow(SlidingWindowProps{
+		MaxSize: 16,
+	})
+
+	for i := 0; i < 10; i++ {
+		next, err := w.ReserveNext()
+		assert.Nil(t, err)
+
+		err = w.Set(next, "t", strconv.Itoa(i), 0)
+		assert.Nil(t, err)
+	}
+
+	_, err := w.Discard(2, 5)
+	assert.Nil(t, err)
+	assert.Equal(t, core.Usage{Elements: 10, Discarded: 5, Bytes: 20}, w.Usage())
+
+	n, err := w.Compact()
+	assert.Nil(t, err)
+	assert.Equal(t, uint(5), n)
+	assert.Equal(t, uint64(0), w.Offset())
+	assert.Equal(t, core.Usage{Elements: 10, Discarded: 5, Bytes: 10}, w.Usage())
+
+	// once the elements before the discarded elements are discarded
+	// the compaction has nothing else to reclaim
+	_, err = w.Discard(0, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(7), w.Offset())
+	n, err = w.Compact()
+	assert.Nil(t, err)
+	assert.Equal(t, uint(0), n)
+	assert.Equal(t, core.Usage{Elements: 3, Discarded: 0, Bytes: 6}, w.Usage())
+}
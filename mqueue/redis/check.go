@@ -0,0 +1,254 @@
+package redis
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/oasislabs/oasis-gateway/mqueue/core"
+)
+
+// MinVersion is the oldest redis version the scripts of the MQueue
+// run on. It is the first version with cluster support, and all the
+// lua libraries the scripts use are available in it
+const MinVersion = "3.0.0"
+
+// scriptFunctions are the functions that redis.lua defines and that
+// the commands of the MQueue call
+var scriptFunctions = []string{
+	"mqnext", "mqreserve", "mqinsert", "mqretrieve", "mqretrieverange",
+	"mqdiscard", "mqremove", "mqcompact", "mqreplace",
+}
+
+// scriptsloaded returns the functions passed as arguments that
+// are not defined. rawget is used because accessing a global that
+// does not exist fails the script
+const scriptsloaded = `local missing = {}
+for _, name in ipairs(ARGV) do
+  if type(rawget(_G, name)) ~= 'function' then
+    table.insert(missing, name)
+  end
+end
+return missing`
+
+// nodesFunc calls fn for each node of the redis deployment that
+// serves the queues
+type nodesFunc func(fn func(addr string, c Client) error) error
+
+// ErrIncompatible is returned by Check if the redis deployment
+// cannot serve the MQueue
+type ErrIncompatible struct {
+	// Addr is the address of the node that failed the check. It is
+	// empty if the check is not run against a specific node
+	Addr string
+
+	// Check is the check that failed
+	Check string
+
+	// Cause is what made the check fail
+	Cause error
+
+	// Hint describes how to fix the deployment
+	Hint string
+}
+
+// Error is the implementation of error for ErrIncompatible
+func (e ErrIncompatible) Error() string {
+	if len(e.Addr) == 0 {
+		return fmt.Sprintf("redis failed the %s check: %s. %s", e.Check, e.Cause, e.Hint)
+	}
+	return fmt.Sprintf("redis at %s failed the %s check: %s. %s", e.Addr, e.Check, e.Cause, e.Hint)
+}
+
+// IsErrIncompatible returns true if the error is an ErrIncompatible
+func IsErrIncompatible(err error) bool {
+	_, ok := err.(ErrIncompatible)
+	return ok
+}
+
+// Check verifies that the redis deployment can serve the MQueue, so
+// that an incompatible deployment is found when the gateway starts
+// rather than on the first request that uses it. It checks the version
+// of each node and that the scripts of the MQueue are loaded in it, and
+// then runs the scripts against a scratch queue and verifies their
+// results
+func (m *MQueue) Check(ctx context.Context) error {
+	if err := m.nodes(func(addr string, c Client) error {
+		return m.checkNode(ctx, addr, c)
+	}); err != nil {
+		return err
+	}
+
+	return m.selfTest(ctx)
+}
+
+func (m *MQueue) checkNode(ctx context.Context, addr string, c Client) error {
+	info, err := c.Info("server").Result()
+	if err != nil {
+		return ErrIncompatible{Addr: addr, Check: "version", Cause: err,
+			Hint: "Verify that the address of the mailbox is correct and that redis is running."}
+	}
+
+	version, err := parseVersion(info)
+	if err != nil {
+		return ErrIncompatible{Addr: addr, Check: "version", Cause: err,
+			Hint: "Verify that the mailbox address points to a redis server."}
+	}
+
+	if compareVersions(version, MinVersion) < 0 {
+		return ErrIncompatible{Addr: addr, Check: "version",
+			Cause: fmt.Errorf("version %s is not supported", version),
+			Hint:  fmt.Sprintf("Upgrade redis to version %s or later.", MinVersion)}
+	}
+
+	args := make([]interface{}, len(scriptFunctions))
+	for i, name := range scriptFunctions {
+		args[i] = name
+	}
+
+	v, err := c.Eval(scriptsloaded, nil, args...).Result()
+	if err != nil {
+		return ErrIncompatible{Addr: addr, Check: "scripts", Cause: err,
+			Hint: "Verify that lua scripting is enabled in redis."}
+	}
+
+	missing, _ := v.([]interface{})
+	if len(missing) > 0 {
+		h, p, err := net.SplitHostPort(addr)
+		if err != nil {
+			h, p = addr, "6379"
+		}
+		names := make([]string, len(missing))
+		for i, name := range missing {
+			names[i] = fmt.Sprint(name)
+		}
+		return ErrIncompatible{Addr: addr, Check: "scripts",
+			Cause: fmt.Errorf("functions %s are not defined", strings.Join(names, ", ")),
+			Hint: fmt.Sprintf("Load the scripts with `redis-cli -h %s -p %s --eval mqueue/redis/redis.lua`. "+
+				"They need to be loaded again after redis restarts or its scripts are flushed.",
+				h, p)}
+	}
+
+	m.logger.Info(ctx, "redis node is compatible with the mqueue", log.MapFields{
+		"call_type": "CheckNodeSuccess",
+		"addr":      addr,
+		"version":   version,
+	})
+
+	return nil
+}
+
+// selfTest runs the scripts against a scratch queue and verifies that
+// they behave as expected. The queue is removed afterwards
+func (m *MQueue) selfTest(ctx context.Context) error {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return err
+	}
+
+	// the key has a hash tag so that the queue and its index are
+	// stored in the same slot of a redis cluster
+	key := "{mqueue.check." + hex.EncodeToString(b[:]) + "}"
+	fail := func(step string, cause error) error {
+		return ErrIncompatible{Check: "script self-test",
+			Cause: fmt.Errorf("%s on %s: %s", step, key, cause),
+			Hint: "Load the scripts of this version of the gateway with " +
+				"`redis-cli --eval mqueue/redis/redis.lua` on every redis node."}
+	}
+
+	// the scratch queue is removed even if the self-test fails
+	defer func() {
+		_, _ = m.exec(ctx, removeRequest{Key: key})
+		m.account.Remove(key)
+		_, _ = m.exec(ctx, deleteFieldRequest{Key: key, Field: "check"})
+	}()
+
+	offset, err := m.next(ctx, core.NextRequest{Key: key})
+	if err != nil {
+		return fail("next", err)
+	}
+	if offset != 0 {
+		return fail("next", fmt.Errorf("returned offset %d instead of 0", offset))
+	}
+
+	if err := m.insert(ctx, core.InsertRequest{Key: key, Element: core.Element{
+		Offset: offset,
+		Type:   "check",
+		Value:  "check",
+	}}); err != nil {
+		return fail("insert", err)
+	}
+
+	els, err := m.retrieve(ctx, core.RetrieveRequest{Key: key, Offset: 0, Count: 1})
+	if err != nil {
+		return fail("retrieve", err)
+	}
+	if len(els.Elements) != 1 || els.Elements[0].Offset != 0 || els.Elements[0].Value != "check" {
+		return fail("retrieve", fmt.Errorf("returned %+v instead of the inserted element", els.Elements))
+	}
+
+	if err := m.discard(ctx, core.DiscardRequest{Key: key, Offset: 0, Count: 1}); err != nil {
+		return fail("discard", err)
+	}
+
+	if err := m.remove(ctx, core.RemoveRequest{Key: key}); err != nil {
+		return fail("remove", err)
+	}
+
+	value, ok, err := m.SetField(ctx, core.SetFieldRequest{Key: key, Field: "check", Value: "check"})
+	if err != nil {
+		return fail("set field", err)
+	}
+	if !ok || value != "check" {
+		return fail("set field", fmt.Errorf("returned %q, %t instead of the value set", value, ok))
+	}
+
+	ok, err = m.DeleteField(ctx, core.DeleteFieldRequest{Key: key, Field: "check"})
+	if err != nil {
+		return fail("delete field", err)
+	}
+	if !ok {
+		return fail("delete field", fmt.Errorf("did not find the field set"))
+	}
+
+	return nil
+}
+
+// parseVersion returns the version of redis from the
+// server section of the output of INFO
+func parseVersion(info string) (string, error) {
+	for _, line := range strings.Split(info, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "redis_version:") {
+			return strings.TrimPrefix(line, "redis_version:"), nil
+		}
+	}
+
+	return "", fmt.Errorf("redis_version not found in server info")
+}
+
+// compareVersions compares two dot separated versions and returns
+// a negative number if a is older than b, 0 if they are the same
+// version and a positive number otherwise
+func compareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if x != y {
+			return x - y
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,120 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/go-redis/redis"
+	"github.com/oasislabs/oasis-gateway/stats"
+	"github.com/stretchr/testify/assert"
+)
+
+// checkClient is a Client that emulates the scripts
+// run by Check on a single queue
+type checkClient struct {
+	version  string
+	missing  []interface{}
+	el       *redisElement
+	retrieve func(el *redisElement) []interface{}
+}
+
+func (c *checkClient) Eval(script string, keys []string, args ...interface{}) *redis.Cmd {
+	switch op(script) {
+	case scriptsloaded:
+		return redis.NewCmdResult(c.missing, nil)
+	case mqnext:
+		return redis.NewCmdResult(int64(0), nil)
+	case mqinsert:
+		c.el = &redisElement{Set: true, Offset: args[0].(uint64), Type: args[1].(string), Value: args[2].(string)}
+		return redis.NewCmdResult("OK", nil)
+	case mqretrieve:
+		if c.retrieve != nil {
+			return redis.NewCmdResult(c.retrieve(c.el), nil)
+		}
+		p, _ := json.Marshal(c.el)
+		return redis.NewCmdResult([]interface{}{string(p)}, nil)
+	case mqdiscard:
+		return redis.NewCmdResult("OK", nil)
+	case mqremove:
+		return redis.NewCmdResult(int64(1), nil)
+	case storeset:
+		return redis.NewCmdResult([]interface{}{int64(1), args[1]}, nil)
+	case storedel:
+		return redis.NewCmdResult(int64(1), nil)
+	default:
+		panic("unexpected script " + script)
+	}
+}
+
+func (c *checkClient) Exists(key ...string) *redis.IntCmd {
+	return redis.NewIntResult(1, nil)
+}
+
+func (c *checkClient) Info(section ...string) *redis.StringCmd {
+	return redis.NewStringResult("# Server\r\nredis_version:"+c.version+"\r\nredis_mode:standalone\r\n", nil)
+}
+
+func newCheckMQueue(client *checkClient) *MQueue {
+	m := newTestMQueue(client, nil)
+	m.tracker = stats.NewMethodTracker(store)
+	m.nodes = func(fn func(addr string, c Client) error) error {
+		return fn("127.0.0.1:6379", client)
+	}
+	return m
+}
+
+func TestCheck(t *testing.T) {
+	m := newCheckMQueue(&checkClient{version: "5.0.7"})
+
+	assert.Nil(t, m.Check(context.Background()))
+	assert.Empty(t, m.Keys())
+}
+
+func TestCheckVersionNotSupported(t *testing.T) {
+	m := newCheckMQueue(&checkClient{version: "2.8.24"})
+
+	err := m.Check(context.Background())
+	assert.True(t, IsErrIncompatible(err))
+	assert.Equal(t, "version", err.(ErrIncompatible).Check)
+	assert.Equal(t, "127.0.0.1:6379", err.(ErrIncompatible).Addr)
+}
+
+func TestCheckScriptsNotLoaded(t *testing.T) {
+	m := newCheckMQueue(&checkClient{version: "5.0.7", missing: []interface{}{"mqnext", "mqinsert"}})
+
+	err := m.Check(context.Background())
+	assert.True(t, IsErrIncompatible(err))
+	assert.Equal(t, "scripts", err.(ErrIncompatible).Check)
+	assert.True(t, strings.Contains(err.Error(), "mqnext, mqinsert"))
+	assert.True(t, strings.Contains(err.Error(), "redis-cli -h 127.0.0.1 -p 6379 --eval mqueue/redis/redis.lua"))
+}
+
+func TestCheckSelfTestFailure(t *testing.T) {
+	m := newCheckMQueue(&checkClient{version: "5.0.7", retrieve: func(el *redisElement) []interface{} {
+		return nil
+	}})
+
+	err := m.Check(context.Background())
+	assert.True(t, IsErrIncompatible(err))
+	assert.Equal(t, "script self-test", err.(ErrIncompatible).Check)
+	assert.True(t, strings.HasPrefix(err.(ErrIncompatible).Cause.Error(), "retrieve on {mqueue.check."))
+	assert.Empty(t, m.Keys())
+}
+
+func TestParseVersion(t *testing.T) {
+	version, err := parseVersion("# Server\r\nredis_version:6.0.9\r\n")
+	assert.Nil(t, err)
+	assert.Equal(t, "6.0.9", version)
+
+	_, err = parseVersion("# Server\r\n")
+	assert.Error(t, err)
+}
+
+func TestCompareVersions(t *testing.T) {
+	assert.True(t, compareVersions("3.0.0", MinVersion) == 0)
+	assert.True(t, compareVersions("3.0", MinVersion) == 0)
+	assert.True(t, compareVersions("2.8.24", MinVersion) < 0)
+	assert.True(t, compareVersions("10.0.1", MinVersion) > 0)
+}
@@ -1,5 +1,7 @@
 package redis
 
+import "strings"
+
 type op string
 
 type command interface {
@@ -9,13 +11,46 @@ type command interface {
 }
 
 const (
-	mqnext     op = "return mqnext(KEYS[1])"
-	mqinsert   op = "return mqinsert(KEYS[1], ARGV[1], ARGV[2], ARGV[3])"
-	mqretrieve op = "return mqretrieve(KEYS[1], ARGV[1], ARGV[2])"
-	mqdiscard  op = "return mqdiscard(KEYS[1], ARGV[1], ARGV[2], ARGV[3])"
-	mqremove   op = "return mqremove(KEYS[1])"
+	mqnext          op = "return mqnext(KEYS[1], KEYS[2])"
+	mqreserve       op = "return mqreserve(KEYS[1], KEYS[2], ARGV[1], ARGV[2])"
+	mqinsert        op = "return mqinsert(KEYS[1], KEYS[2], ARGV[1], ARGV[2], ARGV[3], ARGV[4])"
+	mqretrieve      op = "return mqretrieve(KEYS[1], KEYS[2], ARGV[1], ARGV[2])"
+	mqretrieverange op = "return mqretrieverange(KEYS[1], KEYS[2], ARGV[1], ARGV[2], ARGV[3])"
+	mqdiscard       op = "return mqdiscard(KEYS[1], KEYS[2], ARGV[1], ARGV[2], ARGV[3])"
+	mqremove        op = "return mqremove(KEYS[1], KEYS[2])"
+	mqcompact       op = "return mqcompact(KEYS[1], KEYS[2])"
+	mqreplace       op = "return mqreplace(KEYS[1], ARGV[1], ARGV[2], ARGV[3])"
 )
 
+// keys returns the keys used by the scripts for a queue, which are the
+// queue itself and the sorted set that indexes its elements by the time
+// they were inserted. The index must hash to the same slot as the queue,
+// so that both can be used from the same script in a redis cluster. If
+// that is not possible the queue has no index and the scripts scan the
+// queue to retrieve elements by time
+func keys(key string) []string {
+	if hasHashTag(key) {
+		return []string{key, key + ":ts"}
+	}
+
+	if strings.IndexByte(key, '}') >= 0 {
+		return []string{key}
+	}
+
+	return []string{key, "{" + key + "}:ts"}
+}
+
+// hasHashTag returns true if only a part of the key is
+// hashed to find its slot in a redis cluster
+func hasHashTag(key string) bool {
+	start := strings.IndexByte(key, '{')
+	if start < 0 {
+		return false
+	}
+
+	return strings.IndexByte(key[start+1:], '}') > 0
+}
+
 type nextRequest struct {
 	Key string
 }
@@ -25,18 +60,37 @@ func (r nextRequest) Op() op {
 }
 
 func (r nextRequest) Keys() []string {
-	return []string{r.Key}
+	return keys(r.Key)
 }
 
 func (r nextRequest) Args() []interface{} {
 	return nil
 }
 
+type reserveRequest struct {
+	Key    string
+	Offset uint64
+	MaxGap uint64
+}
+
+func (r reserveRequest) Op() op {
+	return mqreserve
+}
+
+func (r reserveRequest) Keys() []string {
+	return keys(r.Key)
+}
+
+func (r reserveRequest) Args() []interface{} {
+	return []interface{}{r.Offset, r.MaxGap}
+}
+
 type insertRequest struct {
-	Offset  uint64
-	Key     string
-	Content string
-	Type    string
+	Offset    uint64
+	Key       string
+	Content   string
+	Type      string
+	Timestamp int64
 }
 
 func (r insertRequest) Op() op {
@@ -44,11 +98,11 @@ func (r insertRequest) Op() op {
 }
 
 func (r insertRequest) Keys() []string {
-	return []string{r.Key}
+	return keys(r.Key)
 }
 
 func (r insertRequest) Args() []interface{} {
-	return []interface{}{r.Offset, r.Type, r.Content}
+	return []interface{}{r.Offset, r.Type, r.Content, r.Timestamp}
 }
 
 type retrieveRequest struct {
@@ -62,13 +116,32 @@ func (r retrieveRequest) Op() op {
 }
 
 func (r retrieveRequest) Keys() []string {
-	return []string{r.Key}
+	return keys(r.Key)
 }
 
 func (r retrieveRequest) Args() []interface{} {
 	return []interface{}{r.Offset, r.Count}
 }
 
+type retrieveRangeRequest struct {
+	Count uint
+	Since int64
+	Until int64
+	Key   string
+}
+
+func (r retrieveRangeRequest) Op() op {
+	return mqretrieverange
+}
+
+func (r retrieveRangeRequest) Keys() []string {
+	return keys(r.Key)
+}
+
+func (r retrieveRangeRequest) Args() []interface{} {
+	return []interface{}{r.Since, r.Until, r.Count}
+}
+
 type discardRequest struct {
 	KeepPrevious bool
 	Count        uint
@@ -81,7 +154,7 @@ func (r discardRequest) Op() op {
 }
 
 func (r discardRequest) Keys() []string {
-	return []string{r.Key}
+	return keys(r.Key)
 }
 
 func (r discardRequest) Args() []interface{} {
@@ -97,9 +170,44 @@ func (r removeRequest) Op() op {
 }
 
 func (r removeRequest) Keys() []string {
-	return []string{r.Key}
+	return keys(r.Key)
 }
 
 func (r removeRequest) Args() []interface{} {
 	return nil
 }
+
+type compactRequest struct {
+	Key string
+}
+
+func (r compactRequest) Op() op {
+	return mqcompact
+}
+
+func (r compactRequest) Keys() []string {
+	return keys(r.Key)
+}
+
+func (r compactRequest) Args() []interface{} {
+	return nil
+}
+
+type reencryptRequest struct {
+	Offset  uint64
+	Key     string
+	Current string
+	Content string
+}
+
+func (r reencryptRequest) Op() op {
+	return mqreplace
+}
+
+func (r reencryptRequest) Keys() []string {
+	return []string{r.Key}
+}
+
+func (r reencryptRequest) Args() []interface{} {
+	return []interface{}{r.Offset, r.Current, r.Content}
+}
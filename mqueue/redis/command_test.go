@@ -9,23 +9,33 @@ import (
 func TestNextRequest(t *testing.T) {
 	req := nextRequest{Key: "key"}
 
-	assert.Equal(t, []string{"key"}, req.Keys())
+	assert.Equal(t, []string{"key", "{key}:ts"}, req.Keys())
 	assert.Equal(t, []interface{}(nil), req.Args())
 }
 
+func TestReserveRequest(t *testing.T) {
+	req := reserveRequest{Key: "key", Offset: 3, MaxGap: 10}
+
+	assert.Equal(t, mqreserve, req.Op())
+	assert.Equal(t, []string{"key", "{key}:ts"}, req.Keys())
+	assert.Equal(t, []interface{}{uint64(3), uint64(10)}, req.Args())
+}
+
 func TestInsertRequest(t *testing.T) {
 	req := insertRequest{
-		Offset:  1,
-		Key:     "key",
-		Content: "content",
-		Type:    "type",
+		Offset:    1,
+		Key:       "key",
+		Content:   "content",
+		Type:      "type",
+		Timestamp: 1000,
 	}
 
-	assert.Equal(t, []string{"key"}, req.Keys())
+	assert.Equal(t, []string{"key", "{key}:ts"}, req.Keys())
 	assert.Equal(t, []interface{}{
 		uint64(1),
 		"type",
 		"content",
+		int64(1000),
 	}, req.Args())
 }
 
@@ -36,13 +46,36 @@ func TestRetrieveRequest(t *testing.T) {
 		Count:  1,
 	}
 
-	assert.Equal(t, []string{"key"}, req.Keys())
+	assert.Equal(t, []string{"key", "{key}:ts"}, req.Keys())
 	assert.Equal(t, []interface{}{
 		uint64(1),
 		uint(1),
 	}, req.Args())
 }
 
+func TestRetrieveRangeRequest(t *testing.T) {
+	req := retrieveRangeRequest{
+		Since: 1000,
+		Until: 2000,
+		Key:   "key",
+		Count: 1,
+	}
+
+	assert.Equal(t, []string{"key", "{key}:ts"}, req.Keys())
+	assert.Equal(t, []interface{}{
+		int64(1000),
+		int64(2000),
+		uint(1),
+	}, req.Args())
+}
+
+func TestKeys(t *testing.T) {
+	assert.Equal(t, []string{"key", "{key}:ts"}, keys("key"))
+	assert.Equal(t, []string{"{session}:sub:1", "{session}:sub:1:ts"}, keys("{session}:sub:1"))
+	assert.Equal(t, []string{"{}"}, keys("{}"))
+	assert.Equal(t, []string{"a}b"}, keys("a}b"))
+}
+
 func TestDiscardRequest(t *testing.T) {
 	req := discardRequest{
 		KeepPrevious: true,
@@ -51,7 +84,7 @@ func TestDiscardRequest(t *testing.T) {
 		Key:          "key",
 	}
 
-	assert.Equal(t, []string{"key"}, req.Keys())
+	assert.Equal(t, []string{"key", "{key}:ts"}, req.Keys())
 	assert.Equal(t, []interface{}{
 		uint64(1),
 		uint(1),
@@ -64,6 +97,31 @@ func TestRemoveRequest(t *testing.T) {
 		Key: "key",
 	}
 
-	assert.Equal(t, []string{"key"}, req.Keys())
+	assert.Equal(t, []string{"key", "{key}:ts"}, req.Keys())
+	assert.Equal(t, []interface{}(nil), req.Args())
+}
+
+func TestCompactRequest(t *testing.T) {
+	req := compactRequest{
+		Key: "key",
+	}
+
+	assert.Equal(t, []string{"key", "{key}:ts"}, req.Keys())
 	assert.Equal(t, []interface{}(nil), req.Args())
 }
+
+func TestReencryptRequest(t *testing.T) {
+	req := reencryptRequest{
+		Offset:  1,
+		Key:     "key",
+		Current: "current",
+		Content: "content",
+	}
+
+	assert.Equal(t, []string{"key"}, req.Keys())
+	assert.Equal(t, []interface{}{
+		uint64(1),
+		"current",
+		"content",
+	}, req.Args())
+}
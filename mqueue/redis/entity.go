@@ -1,8 +1,10 @@
 package redis
 
 type redisElement struct {
-	Set    bool   `json:"set"`
-	Offset uint64 `json:"offset"`
-	Type   string `json:"value_type"`
-	Value  string `json:"value"`
+	Set       bool   `json:"set"`
+	Discarded bool   `json:"discarded"`
+	Offset    uint64 `json:"offset"`
+	Type      string `json:"value_type"`
+	Value     string `json:"value"`
+	Timestamp int64  `json:"timestamp"`
 }
@@ -3,6 +3,9 @@ package redis
 import (
 	"errors"
 	"fmt"
+	"io"
+	"net"
+	"strings"
 )
 
 var (
@@ -62,3 +65,39 @@ func IsErrRedisExec(err error) bool {
 	_, ok := err.(ErrRedisExec)
 	return ok
 }
+
+// unavailableReplies are the prefixes of the errors returned by redis
+// or its client when the server cannot serve any request
+var unavailableReplies = []string{
+	"redis: client is closed",
+	"redis: connection pool timeout",
+	"redis: cluster has no nodes",
+	"CLUSTERDOWN",
+	"LOADING",
+	"MASTERDOWN",
+}
+
+// IsErrUnavailable returns true if the command failed because redis
+// could not be reached rather than because of the command itself
+func IsErrUnavailable(err error) bool {
+	exec, ok := err.(ErrRedisExec)
+	if !ok || exec.Cause == nil {
+		return false
+	}
+
+	if exec.Cause == io.EOF || exec.Cause == io.ErrUnexpectedEOF {
+		return true
+	}
+
+	if _, ok := exec.Cause.(net.Error); ok {
+		return true
+	}
+
+	for _, reply := range unavailableReplies {
+		if strings.HasPrefix(exec.Cause.Error(), reply) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -1,6 +1,9 @@
 package redis
 
 import (
+	"errors"
+	"io"
+	"net"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -15,3 +18,16 @@ func TestIsErrRedisExec(t *testing.T) {
 	err := ErrRedisExec{Cause: nil}
 	assert.True(t, IsErrRedisExec(err))
 }
+
+func TestIsErrUnavailable(t *testing.T) {
+	assert.True(t, IsErrUnavailable(ErrRedisExec{Cause: io.EOF}))
+	assert.True(t, IsErrUnavailable(ErrRedisExec{Cause: &net.OpError{Op: "dial", Err: errors.New("connection refused")}}))
+	assert.True(t, IsErrUnavailable(ErrRedisExec{Cause: errors.New("redis: connection pool timeout")}))
+	assert.True(t, IsErrUnavailable(ErrRedisExec{Cause: errors.New("CLUSTERDOWN The cluster is down")}))
+}
+
+func TestIsErrUnavailableCommandError(t *testing.T) {
+	assert.False(t, IsErrUnavailable(ErrRedisExec{Cause: nil}))
+	assert.False(t, IsErrUnavailable(ErrRedisExec{Cause: errors.New("ERR queue not found")}))
+	assert.False(t, IsErrUnavailable(ErrDeserialize{Cause: io.EOF}))
+}
@@ -0,0 +1,193 @@
+package redis
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	// KeySize is the size in bytes of the keys used to encrypt
+	// the values of the elements
+	KeySize = 32
+
+	// sealedPrefix identifies the values stored encrypted. Values
+	// are JSON encoded before they are sealed, so a plaintext value
+	// never starts with the prefix
+	sealedPrefix = "enc:v1:"
+)
+
+var (
+	ErrUnknownKey      = errors.New("value is sealed with an unknown key")
+	ErrMalformedSealed = errors.New("sealed value is malformed")
+	ErrNotSealed       = errors.New("value is stored in plaintext")
+)
+
+// KeyringProps are the properties used to create a Keyring
+type KeyringProps struct {
+	// Keys are the keys of the keyring by their ID. Keys that are
+	// not the primary key are only used to open values sealed
+	// before a key rotation
+	Keys map[string][]byte
+
+	// Primary is the ID of the key used to seal new values
+	Primary string
+
+	// RequireSealed if set rejects the values stored in plaintext
+	// instead of returning them as they are. It is meant to be set
+	// once the queues written before encryption was enabled are
+	// reencrypted
+	RequireSealed bool
+}
+
+// Keyring encrypts the values of the elements before they are
+// stored in redis and decrypts them once they are retrieved. Each
+// sealed value records the ID of the key that sealed it, so the
+// keys can be rotated by adding a new primary key while keeping
+// the previous keys until the queues are reencrypted.
+//
+// A nil Keyring is valid and stores the values in plaintext
+type Keyring struct {
+	primary       string
+	aeads         map[string]cipher.AEAD
+	requireSealed bool
+}
+
+// NewKeyring creates a new Keyring
+func NewKeyring(props KeyringProps) (*Keyring, error) {
+	if _, ok := props.Keys[props.Primary]; !ok {
+		return nil, fmt.Errorf("primary key %s is not in the keyring", props.Primary)
+	}
+
+	aeads := make(map[string]cipher.AEAD, len(props.Keys))
+	for id, key := range props.Keys {
+		if len(id) == 0 || strings.Contains(id, ":") {
+			return nil, fmt.Errorf("key ID %q cannot be empty or contain ':'", id)
+		}
+
+		if len(key) != KeySize {
+			return nil, fmt.Errorf("key %s must be %d bytes long", id, KeySize)
+		}
+
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+
+		aeads[id] = aead
+	}
+
+	return &Keyring{
+		primary:       props.Primary,
+		aeads:         aeads,
+		requireSealed: props.RequireSealed,
+	}, nil
+}
+
+// ParseKey parses a key definition of the form <id>:<base64 key>
+func ParseKey(s string) (string, []byte, error) {
+	parts := strings.SplitN(s, ":", 2)
+	if len(parts) != 2 {
+		return "", nil, errors.New("key must be of the form <id>:<base64 key>")
+	}
+
+	key, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to decode key %s: %s", parts[0], err.Error())
+	}
+
+	return parts[0], key, nil
+}
+
+// Seal encrypts the value of the element at the offset of the queue
+// with the primary key. The queue key and offset are authenticated so
+// that a sealed value cannot be moved to another element
+func (k *Keyring) Seal(key string, offset uint64, value string) (string, error) {
+	if k == nil {
+		return value, nil
+	}
+
+	aead := k.aeads[k.primary]
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(value)+aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+
+	sealed := aead.Seal(nonce, nonce, []byte(value), additionalData(key, offset))
+	return sealedPrefix + k.primary + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts a value returned by Seal. Values stored in plaintext
+// are returned as they are, so that queues written before encryption
+// was enabled can still be read, unless the keyring requires sealed
+// values
+func (k *Keyring) Open(key string, offset uint64, value string) (string, error) {
+	id, sealed, ok := splitSealed(value)
+	if !ok {
+		if k != nil && k.requireSealed {
+			return "", ErrNotSealed
+		}
+		return value, nil
+	}
+
+	if k == nil {
+		return "", ErrUnknownKey
+	}
+
+	aead, ok := k.aeads[id]
+	if !ok {
+		return "", ErrUnknownKey
+	}
+
+	p, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil || len(p) < aead.NonceSize() {
+		return "", ErrMalformedSealed
+	}
+
+	opened, err := aead.Open(nil, p[:aead.NonceSize()], p[aead.NonceSize():], additionalData(key, offset))
+	if err != nil {
+		return "", ErrMalformedSealed
+	}
+
+	return string(opened), nil
+}
+
+// IsPrimary returns true if the value is stored as the keyring
+// would store it, either sealed with the primary key or in
+// plaintext if the keyring is nil
+func (k *Keyring) IsPrimary(value string) bool {
+	id, _, ok := splitSealed(value)
+	if k == nil {
+		return !ok
+	}
+
+	return ok && id == k.primary
+}
+
+func splitSealed(value string) (string, string, bool) {
+	if !strings.HasPrefix(value, sealedPrefix) {
+		return "", "", false
+	}
+
+	parts := strings.SplitN(value[len(sealedPrefix):], ":", 2)
+	if len(parts) != 2 {
+		return "", "", false
+	}
+
+	return parts[0], parts[1], true
+}
+
+func additionalData(key string, offset uint64) []byte {
+	return []byte(key + ":" + strconv.FormatUint(offset, 10))
+}
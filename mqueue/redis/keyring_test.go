@@ -0,0 +1,215 @@
+package redis
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/go-redis/redis"
+	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/oasislabs/oasis-gateway/mqueue/core"
+	"github.com/oasislabs/oasis-gateway/stats"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	keyA = bytes.Repeat([]byte{1}, KeySize)
+	keyB = bytes.Repeat([]byte{2}, KeySize)
+)
+
+func newTestKeyring(t *testing.T, primary string) *Keyring {
+	keyring, err := NewKeyring(KeyringProps{
+		Keys:    map[string][]byte{"a": keyA, "b": keyB},
+		Primary: primary,
+	})
+	assert.Nil(t, err)
+	return keyring
+}
+
+func TestNewKeyringErrors(t *testing.T) {
+	_, err := NewKeyring(KeyringProps{Keys: map[string][]byte{"a": keyA}, Primary: "b"})
+	assert.Error(t, err)
+
+	_, err = NewKeyring(KeyringProps{Keys: map[string][]byte{"a": keyA[:16]}, Primary: "a"})
+	assert.Error(t, err)
+
+	_, err = NewKeyring(KeyringProps{Keys: map[string][]byte{"a:b": keyA}, Primary: "a:b"})
+	assert.Error(t, err)
+}
+
+func TestParseKey(t *testing.T) {
+	id, key, err := ParseKey("a:" + base64.StdEncoding.EncodeToString(keyA))
+	assert.Nil(t, err)
+	assert.Equal(t, "a", id)
+	assert.Equal(t, keyA, key)
+
+	_, _, err = ParseKey("a")
+	assert.Error(t, err)
+
+	_, _, err = ParseKey("a:!!")
+	assert.Error(t, err)
+}
+
+func TestKeyringSealOpen(t *testing.T) {
+	keyring := newTestKeyring(t, "a")
+
+	sealed, err := keyring.Seal("queue", 1, `"value"`)
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(sealed, "enc:v1:a:"))
+	assert.True(t, keyring.IsPrimary(sealed))
+
+	value, err := keyring.Open("queue", 1, sealed)
+	assert.Nil(t, err)
+	assert.Equal(t, `"value"`, value)
+
+	// the sealed value cannot be moved to another element
+	_, err = keyring.Open("queue", 2, sealed)
+	assert.Equal(t, ErrMalformedSealed, err)
+	_, err = keyring.Open("other", 1, sealed)
+	assert.Equal(t, ErrMalformedSealed, err)
+}
+
+func TestKeyringRotation(t *testing.T) {
+	sealed, err := newTestKeyring(t, "a").Seal("queue", 1, `"value"`)
+	assert.Nil(t, err)
+
+	rotated := newTestKeyring(t, "b")
+	assert.False(t, rotated.IsPrimary(sealed))
+
+	value, err := rotated.Open("queue", 1, sealed)
+	assert.Nil(t, err)
+	assert.Equal(t, `"value"`, value)
+
+	withoutA, err := NewKeyring(KeyringProps{Keys: map[string][]byte{"b": keyB}, Primary: "b"})
+	assert.Nil(t, err)
+	_, err = withoutA.Open("queue", 1, sealed)
+	assert.Equal(t, ErrUnknownKey, err)
+}
+
+func TestKeyringPlaintext(t *testing.T) {
+	keyring := newTestKeyring(t, "a")
+
+	value, err := keyring.Open("queue", 1, `"value"`)
+	assert.Nil(t, err)
+	assert.Equal(t, `"value"`, value)
+	assert.False(t, keyring.IsPrimary(`"value"`))
+}
+
+func TestKeyringRequireSealed(t *testing.T) {
+	keyring, err := NewKeyring(KeyringProps{
+		Keys:          map[string][]byte{"a": keyA},
+		Primary:       "a",
+		RequireSealed: true,
+	})
+	assert.Nil(t, err)
+
+	_, err = keyring.Open("queue", 1, `"value"`)
+	assert.Equal(t, ErrNotSealed, err)
+
+	sealed, err := keyring.Seal("queue", 1, `"value"`)
+	assert.Nil(t, err)
+	value, err := keyring.Open("queue", 1, sealed)
+	assert.Nil(t, err)
+	assert.Equal(t, `"value"`, value)
+}
+
+func TestKeyringNil(t *testing.T) {
+	var keyring *Keyring
+
+	sealed, err := keyring.Seal("queue", 1, `"value"`)
+	assert.Nil(t, err)
+	assert.Equal(t, `"value"`, sealed)
+	assert.True(t, keyring.IsPrimary(sealed))
+
+	_, err = keyring.Open("queue", 1, "enc:v1:a:AAAA")
+	assert.Equal(t, ErrUnknownKey, err)
+}
+
+// listClient is a Client that keeps the elements of a single
+// queue in memory to emulate the redis scripts
+type listClient struct {
+	els []redisElement
+}
+
+func (c *listClient) Eval(script string, keys []string, args ...interface{}) *redis.Cmd {
+	switch op(script) {
+	case mqinsert:
+		offset := args[0].(uint64)
+		c.els[offset] = redisElement{Set: true, Offset: offset, Type: args[1].(string), Value: args[2].(string)}
+		return redis.NewCmdResult("OK", nil)
+	case mqretrieve:
+		var res []interface{}
+		for _, el := range c.els {
+			p, _ := json.Marshal(el)
+			res = append(res, string(p))
+		}
+		return redis.NewCmdResult(res, nil)
+	case mqreplace:
+		offset := args[0].(uint64)
+		if c.els[offset].Value != args[1].(string) {
+			return redis.NewCmdResult(int64(0), nil)
+		}
+		c.els[offset].Value = args[2].(string)
+		return redis.NewCmdResult(int64(1), nil)
+	default:
+		panic("unexpected script " + script)
+	}
+}
+
+func (c *listClient) Exists(key ...string) *redis.IntCmd {
+	return redis.NewIntResult(1, nil)
+}
+
+func (c *listClient) Info(section ...string) *redis.StringCmd {
+	return redis.NewStringResult("", nil)
+}
+
+func newTestMQueue(client Client, keyring *Keyring) *MQueue {
+	return &MQueue{
+		client:  client,
+		logger:  log.NewLogrus(log.LogrusLoggerProperties{}),
+		tracker: stats.NewMethodTracker(insert, retrieve, rewrite),
+		account: core.NewStorageAccount(),
+		keyring: keyring,
+	}
+}
+
+func TestMQueueReencrypt(t *testing.T) {
+	ctx := context.Background()
+	client := &listClient{els: make([]redisElement, 3)}
+
+	plain := newTestMQueue(client, nil)
+	assert.Nil(t, plain.Insert(ctx, core.InsertRequest{
+		Key: "queue", Element: core.Element{Offset: 0, Type: "t", Value: "v0"}}))
+
+	before := newTestMQueue(client, newTestKeyring(t, "a"))
+	assert.Nil(t, before.Insert(ctx, core.InsertRequest{
+		Key: "queue", Element: core.Element{Offset: 1, Type: "t", Value: "secret"}}))
+	assert.False(t, strings.Contains(client.els[1].Value, "secret"))
+
+	after := newTestMQueue(client, newTestKeyring(t, "b"))
+	assert.Nil(t, after.Insert(ctx, core.InsertRequest{
+		Key: "queue", Element: core.Element{Offset: 2, Type: "t", Value: "v2"}}))
+
+	n, err := after.Reencrypt(ctx, "queue")
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(2), n)
+	for _, el := range client.els {
+		assert.True(t, strings.HasPrefix(el.Value, "enc:v1:b:"))
+	}
+
+	els, err := after.Retrieve(ctx, core.RetrieveRequest{Key: "queue", Offset: 0, Count: 3})
+	assert.Nil(t, err)
+	assert.Equal(t, core.Elements{Offset: 0, Elements: []core.Element{
+		{Offset: 0, Type: "t", Value: "v0"},
+		{Offset: 1, Type: "t", Value: "secret"},
+		{Offset: 2, Type: "t", Value: "v2"},
+	}}, els)
+
+	n, err = after.Reencrypt(ctx, "queue")
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0), n)
+}
@@ -3,6 +3,8 @@ package redis
 import (
 	"context"
 	"encoding/json"
+	"math"
+	"time"
 
 	"github.com/go-redis/redis"
 	"github.com/oasislabs/oasis-gateway/log"
@@ -15,8 +17,12 @@ const (
 	retrieve string = "retrieve"
 	discard  string = "discard"
 	next     string = "next"
+	reserve  string = "reserve"
 	remove   string = "remove"
 	exists   string = "exists"
+	compact  string = "compact"
+	rewrite  string = "reencrypt"
+	store    string = "store"
 )
 
 // Client is the interface to the redis client used implementing
@@ -24,11 +30,16 @@ const (
 type Client interface {
 	Eval(script string, keys []string, args ...interface{}) *redis.Cmd
 	Exists(key ...string) *redis.IntCmd
+	Info(section ...string) *redis.StringCmd
 }
 
 type Props struct {
 	Context context.Context
 	Logger  log.Logger
+
+	// Keyring if set is used to encrypt the values of the
+	// elements before they are stored in redis
+	Keyring *Keyring
 }
 
 type ClusterProps struct {
@@ -48,10 +59,48 @@ type SingleInstanceProps struct {
 
 // MQueue implements the messaging queue functionality required
 // from the mqueue package using Redis as a backend
+//
+// The MQueue accounts for the storage used by the queues in which
+// it inserts elements, so when multiple gateways share the same redis
+// deployment each gateway reports the queues it has written to
 type MQueue struct {
 	client  Client
 	logger  log.Logger
 	tracker *stats.MethodTracker
+	account *core.StorageAccount
+	keyring *Keyring
+	scan    scanFunc
+	nodes   nodesFunc
+}
+
+// scanFunc calls fn for each key of the redis deployment that
+// matches the pattern
+type scanFunc func(match string, fn func(key string) error) error
+
+// scanClient returns a scanFunc for the keys of a single redis instance
+func scanClient(c *redis.Client) scanFunc {
+	return func(match string, fn func(key string) error) error {
+		iter := c.Scan(0, match, 0).Iterator()
+		for iter.Next() {
+			key := iter.Val()
+			t, err := c.Type(key).Result()
+			if err != nil {
+				return err
+			}
+
+			// queues are stored as lists, other types of keys
+			// are not managed by the MQueue
+			if t != "list" {
+				continue
+			}
+
+			if err := fn(key); err != nil {
+				return err
+			}
+		}
+
+		return iter.Err()
+	}
 }
 
 // NewClusterMQueue creates a new instance of a redis client
@@ -63,9 +112,22 @@ func NewClusterMQueue(props ClusterProps) (*MQueue, error) {
 	})
 
 	return &MQueue{
-		client:  c,
-		logger:  logger,
-		tracker: stats.NewMethodTracker(insert, retrieve, discard, next, remove, exists),
+		client: c,
+		logger: logger,
+		tracker: stats.NewLabeledMethodTracker(stats.Labels{"mqueue": "redis-cluster"},
+			insert, retrieve, discard, next, reserve, remove, exists, compact, rewrite, store),
+		account: core.NewStorageAccount(),
+		keyring: props.Keyring,
+		scan: func(match string, fn func(key string) error) error {
+			return c.ForEachMaster(func(client *redis.Client) error {
+				return scanClient(client)(match, fn)
+			})
+		},
+		nodes: func(fn func(addr string, c Client) error) error {
+			return c.ForEachMaster(func(client *redis.Client) error {
+				return fn(client.Options().Addr, client)
+			})
+		},
 	}, nil
 }
 
@@ -78,9 +140,16 @@ func NewSingleMQueue(props SingleInstanceProps) (*MQueue, error) {
 	})
 
 	return &MQueue{
-		client:  c,
-		logger:  logger,
-		tracker: stats.NewMethodTracker(insert, retrieve, discard, next, remove),
+		client: c,
+		logger: logger,
+		tracker: stats.NewLabeledMethodTracker(stats.Labels{"mqueue": "redis-single"},
+			insert, retrieve, discard, next, reserve, remove, compact, rewrite, store),
+		account: core.NewStorageAccount(),
+		keyring: props.Keyring,
+		scan:    scanClient(c),
+		nodes: func(fn func(addr string, c Client) error) error {
+			return fn(props.Addr, c)
+		},
 	}, nil
 }
 
@@ -89,7 +158,9 @@ func (m *MQueue) Name() string {
 }
 
 func (m *MQueue) Stats() stats.Metrics {
-	return m.tracker.Stats()
+	metrics := m.tracker.Stats()
+	metrics["storage"] = m.account.Stats()
+	return metrics
 }
 
 func (m *MQueue) exec(ctx context.Context, cmd command) (interface{}, error) {
@@ -110,11 +181,22 @@ func (m *MQueue) insert(ctx context.Context, req core.InsertRequest) error {
 		return ErrSerialize{Cause: err}
 	}
 
+	content, err := m.keyring.Seal(req.Key, req.Element.Offset, string(serialized))
+	if err != nil {
+		return ErrSerialize{Cause: err}
+	}
+
+	timestamp := req.Element.Timestamp
+	if timestamp == 0 {
+		timestamp = core.Timestamp(time.Now())
+	}
+
 	v, err := m.exec(ctx, insertRequest{
-		Key:     req.Key,
-		Offset:  req.Element.Offset,
-		Type:    req.Element.Type,
-		Content: string(serialized),
+		Key:       req.Key,
+		Offset:    req.Element.Offset,
+		Type:      req.Element.Type,
+		Content:   content,
+		Timestamp: timestamp,
 	})
 
 	if err != nil {
@@ -125,6 +207,7 @@ func (m *MQueue) insert(ctx context.Context, req core.InsertRequest) error {
 		return ErrOpNotOk
 	}
 
+	m.account.Insert(req.Key, uint64(len(content)+len(req.Element.Type)))
 	return nil
 }
 
@@ -140,49 +223,47 @@ func (m *MQueue) Retrieve(ctx context.Context, req core.RetrieveRequest) (core.E
 }
 
 func (m *MQueue) retrieve(ctx context.Context, req core.RetrieveRequest) (core.Elements, error) {
-	els, err := m.exec(ctx, retrieveRequest{
-		Key:    req.Key,
-		Offset: req.Offset,
-		Count:  req.Count,
-	})
-
+	els, err := m.retrieveRaw(ctx, req)
 	if err != nil {
-		return core.Elements{}, ErrRedisExec{Cause: err}
+		return core.Elements{}, err
 	}
 
 	var res []core.Element
-	var offsetSet bool
 	var offset uint64
+	if len(els) > 0 {
+		// the offset needs to be set to the first element in the window regardless
+		// of whether it is set or not.
+		offset = els[0].Offset
+	}
 
-	for _, el := range els.([]interface{}) {
-		var decoded redisElement
-		if err := json.Unmarshal([]byte(el.(string)), &decoded); err != nil {
-			return core.Elements{}, ErrDeserialize{Cause: err}
+	for _, el := range els {
+		// just ignore all elements that have not been set yet
+		if !el.Set {
+			continue
 		}
 
-		if !offsetSet {
-			// the offset needs to be set to the first element in the window regardless
-			// of whether it is set or not.
-			offset = decoded.Offset
-			offsetSet = true
+		// elements filtered out are not opened
+		if !req.Accepts(el.Type) {
+			continue
 		}
 
-		// just ignore all elements that have not been set yet
-		if !decoded.Set {
-			continue
+		serialized, err := m.keyring.Open(req.Key, el.Offset, el.Value)
+		if err != nil {
+			return core.Elements{}, ErrDeserialize{Cause: err}
 		}
 
 		// value is serialized in our redis script as a string, so we need to deserialize
 		// the contents of the value as a string
 		var value string
-		if err := json.Unmarshal([]byte(decoded.Value), &value); err != nil {
+		if err := json.Unmarshal([]byte(serialized), &value); err != nil {
 			return core.Elements{}, ErrDeserialize{Cause: err}
 		}
 
 		res = append(res, core.Element{
-			Offset: decoded.Offset,
-			Type:   decoded.Type,
-			Value:  value,
+			Offset:    el.Offset,
+			Type:      el.Type,
+			Value:     value,
+			Timestamp: el.Timestamp,
 		})
 	}
 
@@ -192,6 +273,43 @@ func (m *MQueue) retrieve(ctx context.Context, req core.RetrieveRequest) (core.E
 	}, nil
 }
 
+// retrieveRaw returns the elements of the window as they are stored
+func (m *MQueue) retrieveRaw(ctx context.Context, req core.RetrieveRequest) ([]redisElement, error) {
+	var cmd command = retrieveRequest{
+		Key:    req.Key,
+		Offset: req.Offset,
+		Count:  req.Count,
+	}
+	if req.ByTime() {
+		r := retrieveRangeRequest{Key: req.Key, Count: req.Count}
+		if !req.Since.IsZero() {
+			r.Since = core.Timestamp(req.Since)
+		}
+		if !req.Until.IsZero() {
+			r.Until = core.Timestamp(req.Until)
+		}
+		cmd = r
+	}
+
+	els, err := m.exec(ctx, cmd)
+
+	if err != nil {
+		return nil, ErrRedisExec{Cause: err}
+	}
+
+	var res []redisElement
+	for _, el := range els.([]interface{}) {
+		var decoded redisElement
+		if err := json.Unmarshal([]byte(el.(string)), &decoded); err != nil {
+			return nil, ErrDeserialize{Cause: err}
+		}
+
+		res = append(res, decoded)
+	}
+
+	return res, nil
+}
+
 func (m *MQueue) Discard(ctx context.Context, req core.DiscardRequest) error {
 	_, err := m.tracker.Instrument(discard, func() (interface{}, error) {
 		return nil, m.discard(ctx, req)
@@ -240,6 +358,34 @@ func (m *MQueue) next(ctx context.Context, req core.NextRequest) (uint64, error)
 	return uint64(v.(int64)), nil
 }
 
+// Reserve reserves the offsets of the queue up to the provided offset
+func (m *MQueue) Reserve(ctx context.Context, req core.ReserveRequest) (uint64, error) {
+	n, err := m.tracker.Instrument(reserve, func() (interface{}, error) {
+		return m.reserve(ctx, req)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return n.(uint64), nil
+}
+
+func (m *MQueue) reserve(ctx context.Context, req core.ReserveRequest) (uint64, error) {
+	v, err := m.exec(ctx, reserveRequest{
+		Key:    req.Key,
+		Offset: req.Offset,
+		MaxGap: req.MaxGap,
+	})
+	if err != nil {
+		if err.Error() == core.ErrReserveGapTooLarge.Error() {
+			return 0, core.ErrReserveGapTooLarge
+		}
+		return 0, ErrRedisExec{Cause: err}
+	}
+
+	return uint64(v.(int64)), nil
+}
+
 func (m *MQueue) Remove(ctx context.Context, req core.RemoveRequest) error {
 	_, err := m.tracker.Instrument(remove, func() (interface{}, error) {
 		return nil, m.remove(ctx, req)
@@ -273,9 +419,170 @@ func (m *MQueue) remove(ctx context.Context, req core.RemoveRequest) error {
 		return ErrRedisExec{Cause: err}
 	}
 
+	m.account.Remove(req.Key)
+
 	if v.(int64) == 0 {
 		return ErrQueueNotFound
 	}
 
 	return nil
 }
+
+// Keys returns the keys of the queues for which storage is accounted
+func (m *MQueue) Keys() []string {
+	return m.account.Keys()
+}
+
+// Compact drops the discarded elements that are not needed anymore
+// to keep track of the queue's window and releases the values of the
+// rest of the discarded elements. It returns the storage the queue
+// uses afterwards
+func (m *MQueue) Compact(ctx context.Context, req core.CompactRequest) (core.Usage, error) {
+	usage, err := m.tracker.Instrument(compact, func() (interface{}, error) {
+		return m.compact(ctx, req)
+	})
+	if err != nil {
+		return core.Usage{}, err
+	}
+
+	return usage.(core.Usage), nil
+}
+
+func (m *MQueue) compact(ctx context.Context, req core.CompactRequest) (core.Usage, error) {
+	v, err := m.exec(ctx, compactRequest{
+		Key: req.Key,
+	})
+	if err != nil {
+		return core.Usage{}, ErrRedisExec{Cause: err}
+	}
+
+	res, ok := v.([]interface{})
+	if !ok || len(res) != 4 {
+		return core.Usage{}, ErrOpNotOk
+	}
+
+	var values [4]uint64
+	for i, r := range res {
+		n, ok := r.(int64)
+		if !ok {
+			return core.Usage{}, ErrOpNotOk
+		}
+		values[i] = uint64(n)
+	}
+
+	usage := core.Usage{
+		Elements:  values[1],
+		Discarded: values[2],
+		Bytes:     values[3],
+	}
+
+	if usage.Elements == 0 {
+		// the queue has expired or has been removed by
+		// another instance
+		m.account.Remove(req.Key)
+	} else {
+		m.account.Set(req.Key, usage)
+	}
+
+	return usage, nil
+}
+
+// Reencrypt rewrites the values of the elements of the queue that are
+// not stored with the primary key of the keyring, so that the keys
+// that are not primary anymore can be removed from the keyring. If
+// the MQueue has no keyring, the values are stored in plaintext.
+// It returns the number of elements rewritten
+func (m *MQueue) Reencrypt(ctx context.Context, key string) (uint64, error) {
+	n, err := m.tracker.Instrument(rewrite, func() (interface{}, error) {
+		return m.reencrypt(ctx, key)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return n.(uint64), nil
+}
+
+func (m *MQueue) reencrypt(ctx context.Context, key string) (uint64, error) {
+	els, err := m.retrieveRaw(ctx, core.RetrieveRequest{
+		Key:    key,
+		Offset: 0,
+		Count:  math.MaxUint32,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var rewritten uint64
+	for _, el := range els {
+		if !el.Set || el.Discarded || m.keyring.IsPrimary(el.Value) {
+			continue
+		}
+
+		value, err := m.keyring.Open(key, el.Offset, el.Value)
+		if err != nil {
+			return rewritten, ErrDeserialize{Cause: err}
+		}
+
+		content, err := m.keyring.Seal(key, el.Offset, value)
+		if err != nil {
+			return rewritten, ErrSerialize{Cause: err}
+		}
+
+		v, err := m.exec(ctx, reencryptRequest{
+			Key:     key,
+			Offset:  el.Offset,
+			Current: el.Value,
+			Content: content,
+		})
+		if err != nil {
+			return rewritten, ErrRedisExec{Cause: err}
+		}
+
+		// the element is not rewritten if it was discarded or
+		// removed since it was retrieved
+		if v.(int64) == 1 {
+			rewritten++
+		}
+	}
+
+	return rewritten, nil
+}
+
+// ReencryptAll reencrypts all the queues of the redis deployment
+// whose keys match the pattern. It is meant to be run after a key
+// rotation, before the previous keys are removed from the keyring.
+// The queues that fail to be reencrypted are logged and skipped
+func (m *MQueue) ReencryptAll(ctx context.Context, match string) (ReencryptResult, error) {
+	var res ReencryptResult
+	err := m.scan(match, func(key string) error {
+		n, err := m.Reencrypt(ctx, key)
+		res.Elements += n
+		if err != nil {
+			m.logger.Warn(ctx, "failed to reencrypt queue", log.MapFields{
+				"call_type": "ReencryptFailure",
+				"key":       key,
+				"err":       err.Error(),
+			})
+			res.Failed++
+			return nil
+		}
+
+		res.Queues++
+		return nil
+	})
+
+	return res, err
+}
+
+// ReencryptResult is the outcome of ReencryptAll
+type ReencryptResult struct {
+	// Queues is the number of queues reencrypted
+	Queues uint64
+
+	// Failed is the number of queues that failed to be reencrypted
+	Failed uint64
+
+	// Elements is the number of elements rewritten
+	Elements uint64
+}
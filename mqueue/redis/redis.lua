@@ -13,9 +13,42 @@ local mqbasenlen = function(key)
   end
 end
 
+-- mqexpire refreshes the expiration of the queue and of the
+-- index of its elements by the time they were inserted. The
+-- index is nil for queues whose index cannot be stored in the
+-- same slot of a redis cluster
+local mqexpire = function(key, index)
+  redis.call('expire', key, expire_time)
+  if index then
+    redis.call('expire', index, expire_time)
+  end
+end
+
+-- mqunindex removes from the index the offsets from first up to but
+-- not including last, which are the offsets of the elements that the
+-- window slid past. The index is nil for queues without an index
+local mqunindex = function(index, first, last)
+  if not index then
+    return
+  end
+
+  local batch = {}
+  for offset = first, last - 1 do
+    table.insert(batch, offset)
+    if table.getn(batch) == 512 then
+      redis.call('zrem', index, unpack(batch))
+      batch = {}
+    end
+  end
+
+  if table.getn(batch) > 0 then
+    redis.call('zrem', index, unpack(batch))
+  end
+end
+
 -- mqnext_offset returns the next available offset for a
 -- theoretical window on an endless stream
-local mqnext = function(key)
+local mqnext = function(key, index)
   local base_n_len = mqbasenlen(key)
   local base = base_n_len[1]
   local len = base_n_len[2]
@@ -23,15 +56,42 @@ local mqnext = function(key)
 
   local payload = cjson.encode({offset = offset, set = false, discarded = false})
   assert(redis.call('rpush', key, payload) == len + 1)
-  redis.call('expire', key, expire_time)
+  mqexpire(key, index)
   return offset
 end
 
+-- mqreserve reserves the offsets that are not reserved yet up to and
+-- including offset and returns how many were reserved. It fails without
+-- reserving any offset if more than max_gap offsets are needed
+local mqreserve = function(key, index, offset, max_gap)
+  offset = tonumber(offset)
+  local base_n_len = mqbasenlen(key)
+  local base = base_n_len[1]
+  local len = base_n_len[2]
+  local next = base + len
+
+  if offset < next then
+    return 0
+  end
+
+  local gap = offset - next + 1
+  if gap > tonumber(max_gap) then
+    return redis.error_reply('offset is too far ahead of the queue')
+  end
+
+  for i = next, offset do
+    redis.call('rpush', key, cjson.encode({offset = i, set = false, discarded = false}))
+  end
+  mqexpire(key, index)
+  return gap
+end
+
 -- mqinsert inserts the value for the provided offset over
 -- the window to an already existing element. If the element does
 -- not exist, the operation fails. get_next_offset must be called
--- so that a specific offset is provided before it can be used
-local mqinsert = function(key, offset, value_type, value)
+-- so that a specific offset is provided before it can be used.
+-- The offset is added to the index with the timestamp of the insertion
+local mqinsert = function(key, time_index, offset, value_type, value, timestamp)
   local base_n_len = mqbasenlen(key)
   local base = base_n_len[1]
   local len = base_n_len[2]
@@ -39,14 +99,19 @@ local mqinsert = function(key, offset, value_type, value)
 
   assert(index >= 0 and index < len)
 
-  local payload = cjson.encode({offset = tonumber(offset), value = value, value_type = value_type, set = true, discarded = false})
-  redis.call('expire', key, expire_time)
-  return redis.call('lset', key, index, payload)
+  local payload = cjson.encode({offset = tonumber(offset), value = value, value_type = value_type,
+    timestamp = tonumber(timestamp), set = true, discarded = false})
+  local res = redis.call('lset', key, index, payload)
+  if time_index then
+    redis.call('zadd', time_index, tonumber(timestamp), tonumber(offset))
+  end
+  mqexpire(key, time_index)
+  return res
 end
 
 -- mqretrieve returns a window of elements within the list
 -- as a contiguous set of elements that have been set
-local mqretrieve = function(key, offset, count)
+local mqretrieve = function(key, index, offset, count)
   if redis.call('exists', key) == 0 then
     return {}
   end
@@ -79,15 +144,89 @@ local mqretrieve = function(key, offset, count)
     stop = start
   end
 
-  redis.call('expire', key, expire_time)
+  mqexpire(key, index)
   return redis.call('lrange', key, start, stop)
 end
 
+-- mqscanrange is mqretrieverange for queues without an index,
+-- which scans all the elements of the queue
+local mqscanrange = function(key, since, before, count)
+  local matched = {}
+  for _, el in ipairs(redis.call('lrange', key, 0, -1)) do
+    local timestamp = cjson.decode(el)['timestamp']
+    if timestamp and timestamp >= since and (before == 0 or timestamp < before) then
+      table.insert(matched, {timestamp = timestamp, el = el})
+    end
+  end
+
+  table.sort(matched, function(a, b) return a.timestamp < b.timestamp end)
+
+  local res = {}
+  for i = 1, math.min(count, table.getn(matched)) do
+    table.insert(res, matched[i].el)
+  end
+
+  return res
+end
+
+-- mqretrieverange returns at most count elements inserted at or after
+-- since and before before, ordered by the time they were inserted. If
+-- before is 0 the range is not bounded. The index may still keep
+-- offsets that are not live anymore, so it is scanned until count
+-- live elements are found or the range is exhausted
+local mqretrieverange = function(key, index, since, before, count)
+  if redis.call('exists', key) == 0 then
+    return {}
+  end
+
+  if not index then
+    mqexpire(key, index)
+    return mqscanrange(key, tonumber(since), tonumber(before), tonumber(count))
+  end
+
+  count = tonumber(count)
+  local max = '+inf'
+  if tonumber(before) > 0 then
+    max = '(' .. before
+  end
+
+  local base_n_len = mqbasenlen(key)
+  local base = base_n_len[1]
+  local len = base_n_len[2]
+
+  local res = {}
+  local skip = 0
+  local page = math.max(count, 16)
+  while table.getn(res) < count do
+    local offsets = redis.call('zrangebyscore', index, since, max, 'LIMIT', skip, page)
+    for _, offset in ipairs(offsets) do
+      local i = tonumber(offset) - base
+      if i >= 0 and i < len then
+        local el = redis.call('lindex', key, i)
+        if not cjson.decode(el)['discarded'] then
+          table.insert(res, el)
+          if table.getn(res) == count then
+            break
+          end
+        end
+      end
+    end
+
+    if table.getn(offsets) < page then
+      break
+    end
+    skip = skip + page
+  end
+
+  mqexpire(key, index)
+  return res
+end
+
 -- mqdiscard discards all elements up to offset if keep_previous is false.
 -- It also discards all the elements up to offset + count that have been set.
 -- The window cannot be left empty because at least one element is needed
 -- to keep track of which is the current window offset.
-local mqdiscard = function(key, offset, count, keep_previous)
+local mqdiscard = function(key, index, offset, count, keep_previous)
   offset = tonumber(offset)
   count = tonumber(count)
 
@@ -132,15 +271,18 @@ local mqdiscard = function(key, offset, count, keep_previous)
       end
     end
 
+    -- the offsets the window slid past are not live anymore
+    mqunindex(index, base, mqbasenlen(key)[1])
+
     if count > 0 then
-      return mqdiscard(key, offset, count, true)
+      return mqdiscard(key, index, offset, count, true)
     end
 
     return "OK"
   end
 
   if base == offset then
-    return mqdiscard(key, offset + count, 0, false)
+    return mqdiscard(key, index, offset + count, 0, false)
   end
 
   -- mark as discarded all the elements that cannot be discarded
@@ -155,49 +297,158 @@ local mqdiscard = function(key, offset, count, keep_previous)
     end
   end
 
-  redis.call('expire', key, expire_time)
+  mqexpire(key, index)
   return "OK"
 end
 
+-- mqcompact drops the discarded elements at the beginning of the window
+-- and releases the values of the discarded elements that cannot be
+-- dropped yet because there are elements before them still in use.
+-- The offsets that are not in the window anymore are dropped from the
+-- index by offset.
+-- The expiration of the key is not refreshed, so that compaction does
+-- not keep alive queues that are not used anymore. It returns
+-- {compacted, elements, discarded, bytes} where bytes is the size of
+-- the payloads kept for the queue after compaction
+local mqcompact = function(key, index)
+  local len = redis.call('llen', key)
+  if len == 0 then
+    return {0, 0, 0, 0}
+  end
+
+  local compacted = 0
+  local first = mqbasenlen(key)[1]
+
+  -- the last element is kept to keep track of the window offset
+  while len > 1 do
+    local el = redis.call('lindex', key, 0)
+    if not cjson.decode(el)['discarded'] then
+      break
+    end
+    redis.call('lpop', key)
+    len = len - 1
+    compacted = compacted + 1
+  end
+
+  local discarded = 0
+  local bytes = 0
+  local els = redis.call('lrange', key, 0, -1)
+  for index, el in ipairs(els) do
+    local decoded = cjson.decode(el)
+    if decoded['discarded'] then
+      discarded = discarded + 1
+      if decoded['set'] then
+        el = cjson.encode({offset = decoded['offset'], set = false, discarded = true})
+        redis.call('lset', key, index - 1, el)
+        compacted = compacted + 1
+      end
+    end
+    bytes = bytes + string.len(el)
+  end
+
+  local base = cjson.decode(els[1])['offset']
+  mqunindex(index, first, base)
+
+  -- the index may keep more offsets than the window if they were
+  -- not removed when the window slid, in which case the offsets
+  -- before the window are removed one by one
+  if index and redis.call('zcard', index) > len then
+    local stale = {}
+    for _, offset in ipairs(redis.call('zrange', index, 0, -1)) do
+      if tonumber(offset) < base then
+        table.insert(stale, offset)
+      end
+    end
+    for i = 1, table.getn(stale), 512 do
+      redis.call('zrem', index, unpack(stale, i, math.min(i + 511, table.getn(stale))))
+    end
+  end
+
+  return {compacted, len, discarded, bytes}
+end
+
+-- mqreplace replaces the value of the element at offset with value
+-- if the element is set, has not been discarded and its value is
+-- still current. The expiration of the key is not refreshed, so that
+-- rewriting the values does not keep alive queues that are not used
+-- anymore. It returns 1 if the value is replaced and 0 otherwise
+local mqreplace = function(key, offset, current, value)
+  local base_n_len = mqbasenlen(key)
+  local base = base_n_len[1]
+  local len = base_n_len[2]
+  local index = offset - base
+
+  if index < 0 or index >= len then
+    return 0
+  end
+
+  local decoded = cjson.decode(redis.call('lindex', key, index))
+  if not decoded['set'] or decoded['discarded'] or decoded['value'] ~= current then
+    return 0
+  end
+
+  decoded['value'] = value
+  redis.call('lset', key, index, cjson.encode(decoded))
+  return 1
+end
+
 -- remove the key and all associated resources
-local mqremove = function(key)
+local mqremove = function(key, index)
+  if index then
+    redis.call('del', index)
+  end
   return redis.call('del', key)
 end
 
 -- attach the API to the global namespace so that it can be
 -- accessed from other scripts
+rawset(_G, "mqreplace", mqreplace)
+rawset(_G, "mqcompact", mqcompact)
 rawset(_G, "mqremove", mqremove)
 rawset(_G, "mqdiscard", mqdiscard)
 rawset(_G, "mqretrieve", mqretrieve)
+rawset(_G, "mqretrieverange", mqretrieverange)
 rawset(_G, "mqinsert", mqinsert)
 rawset(_G, "mqnext", mqnext)
+rawset(_G, "mqreserve", mqreserve)
 
 -- test the basic functionality of the script
 local test = function()
   redis.call('flushall')
 
   for i = 0, 10  do
-    assert(mqnext('example') == i)
-    mqinsert('example', i, 'test', cjson.encode({data = i}))
+    assert(mqnext('example', 'example:ts') == i)
+    mqinsert('example', 'example:ts', i, 'test', cjson.encode({data = i}), 1000 + i)
   end
 
-  local t = mqretrieve('example', 11, 11)
+  local t = mqretrieve('example', 'example:ts', 11, 11)
   assert(table.getn(t) == 0)
 
-  local t = mqretrieve('example', 0, 10)
+  local t = mqretrieverange('example', 'example:ts', 1002, 1005, 10)
+  assert(table.getn(t) == 3)
+  for i = 0, 2  do
+    assert(cjson.decode(t[i+1])['offset'] == i + 2)
+    assert(cjson.decode(t[i+1])['timestamp'] == 1002 + i)
+  end
+
+  local t = mqretrieverange('example', 'example:ts', 1008, 0, 2)
+  assert(table.getn(t) == 2)
+  assert(cjson.decode(t[1])['offset'] == 8)
+
+  local t = mqretrieve('example', 'example:ts', 0, 10)
   assert(table.getn(t) == 11)
   for i = 0, 10  do
     assert(cjson.decode(t[i+1])['offset'] == i)
   end
 
-  mqdiscard('example', 2, 0, false)
-  local t = mqretrieve('example', 0, 10)
+  mqdiscard('example', 'example:ts', 2, 0, false)
+  local t = mqretrieve('example', 'example:ts', 0, 10)
   for i = 0, 8  do
     assert(cjson.decode(t[i+1])['offset'] == i + 2)
   end
 
-  mqdiscard('example', 3, 1, true)
-  local t = mqretrieve('example', 0, 10)
+  mqdiscard('example', 'example:ts', 3, 1, true)
+  local t = mqretrieve('example', 'example:ts', 0, 10)
   assert(table.getn(t) == 9)
   assert(cjson.decode(t[1])['offset'] == 2)
   assert(cjson.decode(t[1])['discarded'] == false)
@@ -206,23 +457,94 @@ local test = function()
   assert(cjson.decode(t[3])['offset'] == 4)
   assert(cjson.decode(t[3])['discarded'] == false)
 
-  mqdiscard('example', 2, 1, true)
-  local t = mqretrieve('example', 0, 10)
+  mqdiscard('example', 'example:ts', 2, 1, true)
+  local t = mqretrieve('example', 'example:ts', 0, 10)
 
   assert(table.getn(t) == 7)
   for i = 0, 6  do
     assert(cjson.decode(t[i+1])['offset'] == i + 4)
   end
 
-  mqdiscard('example', 0, 10, true)
-  local t = mqretrieve('example', 0, 10)
+  mqdiscard('example', 'example:ts', 0, 10, true)
+  local t = mqretrieve('example', 'example:ts', 0, 10)
   assert(table.getn(t) == 1)
 
+  for i = 11, 15  do
+    assert(mqnext('example', 'example:ts') == i)
+    mqinsert('example', 'example:ts', i, 'test', cjson.encode({data = i}), 1000 + i)
+  end
+
+  mqdiscard('example', 'example:ts', 12, 2, true)
+  local c = mqcompact('example', 'example:ts')
+  assert(c[1] == 2)
+  assert(c[2] == 6)
+  assert(c[3] == 2)
+  local t = mqretrieve('example', 'example:ts', 10, 10)
+  assert(table.getn(t) == 6)
+  assert(cjson.decode(t[3])['set'] == false)
+  assert(cjson.decode(t[3])['value'] == nil)
+
+  mqdiscard('example', 'example:ts', 10, 2, true)
+  local c = mqcompact('example', 'example:ts')
+  assert(c[1] == 0)
+  assert(c[2] == 2)
+  assert(c[3] == 0)
+
   local ttl = redis.call('ttl', 'example')
   assert(ttl <= 600 and ttl > 100)
 
-  mqremove('example')
+  local t = mqretrieverange('example', 'example:ts', 0, 0, 20)
+  assert(table.getn(t) == 2)
+  assert(redis.call('zcard', 'example:ts') == 2)
+
+  mqremove('example', 'example:ts')
   assert(redis.call('exists', 'example') == 0)
+  assert(redis.call('exists', 'example:ts') == 0)
+
+  for i = 0, 3  do
+    assert(mqnext('unindexed', nil) == i)
+    mqinsert('unindexed', nil, i, 'test', cjson.encode({data = i}), 1003 - i)
+  end
+
+  local t = mqretrieverange('unindexed', nil, 1001, 0, 2)
+  assert(table.getn(t) == 2)
+  assert(cjson.decode(t[1])['offset'] == 2)
+  assert(cjson.decode(t[2])['offset'] == 1)
+
+  mqremove('unindexed', nil)
+  assert(redis.call('exists', 'unindexed') == 0)
+
+  -- the discarded elements are skipped by the range retrieval
+  -- and the offsets the window slides past leave the index
+  for i = 0, 5  do
+    assert(mqnext('ranged', 'ranged:ts') == i)
+    mqinsert('ranged', 'ranged:ts', i, 'test', cjson.encode({data = i}), 1000 + i)
+  end
+
+  mqdiscard('ranged', 'ranged:ts', 1, 2, true)
+  local t = mqretrieverange('ranged', 'ranged:ts', 0, 0, 2)
+  assert(table.getn(t) == 2)
+  assert(cjson.decode(t[1])['offset'] == 0)
+  assert(cjson.decode(t[2])['offset'] == 3)
+
+  mqdiscard('ranged', 'ranged:ts', 4, 0, false)
+  assert(redis.call('zcard', 'ranged:ts') == 2)
+  local t = mqretrieverange('ranged', 'ranged:ts', 0, 0, 10)
+  assert(table.getn(t) == 2)
+  assert(cjson.decode(t[1])['offset'] == 4)
+
+  -- stale offsets left in the index are removed on compaction
+  redis.call('zadd', 'ranged:ts', 900, 0)
+  mqcompact('ranged', 'ranged:ts')
+  assert(redis.call('zcard', 'ranged:ts') == 2)
+  mqremove('ranged', 'ranged:ts')
+
+  assert(mqreserve('reserved', nil, 3, 10) == 4)
+  assert(mqreserve('reserved', nil, 2, 10) == 0)
+  assert(mqnext('reserved', nil) == 4)
+  assert(mqreserve('reserved', nil, 6, 1)['err'] ~= nil)
+  assert(mqnext('reserved', nil) == 5)
+  mqremove('reserved', nil)
 end
 
 if ARGV[1] == "test" then
@@ -0,0 +1,204 @@
+package redis
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-redis/redis"
+	"github.com/oasislabs/oasis-gateway/mqueue/core"
+)
+
+const (
+	storeget op = "return redis.call('hget', KEYS[1], ARGV[1])"
+	storeset op = `if ARGV[3] == '1' and redis.call('hsetnx', KEYS[1], ARGV[1], ARGV[2]) == 0 then
+  return {0, redis.call('hget', KEYS[1], ARGV[1])}
+end
+redis.call('hset', KEYS[1], ARGV[1], ARGV[2])
+return {1, ARGV[2]}`
+	storedel op = `if ARGV[2] ~= '' and redis.call('hget', KEYS[1], ARGV[1]) ~= ARGV[2] then
+  return 0
+end
+return redis.call('hdel', KEYS[1], ARGV[1])`
+	storefields op = "return redis.call('hgetall', KEYS[1])"
+	storeincr   op = "return redis.call('hincrby', KEYS[1], ARGV[1], ARGV[2])"
+)
+
+// storeKey returns the redis key of a hash of the Store. The hashes
+// are kept apart from the queues so that they are not mistaken for
+// one, and they are never given an expiration
+func storeKey(key string) string {
+	return "store:" + key
+}
+
+type getFieldRequest struct {
+	Key   string
+	Field string
+}
+
+func (r getFieldRequest) Op() op {
+	return storeget
+}
+
+func (r getFieldRequest) Keys() []string {
+	return []string{storeKey(r.Key)}
+}
+
+func (r getFieldRequest) Args() []interface{} {
+	return []interface{}{r.Field}
+}
+
+type setFieldRequest struct {
+	Key      string
+	Field    string
+	Value    string
+	IfAbsent bool
+}
+
+func (r setFieldRequest) Op() op {
+	return storeset
+}
+
+func (r setFieldRequest) Keys() []string {
+	return []string{storeKey(r.Key)}
+}
+
+func (r setFieldRequest) Args() []interface{} {
+	ifAbsent := "0"
+	if r.IfAbsent {
+		ifAbsent = "1"
+	}
+	return []interface{}{r.Field, r.Value, ifAbsent}
+}
+
+type deleteFieldRequest struct {
+	Key     string
+	Field   string
+	IfValue string
+}
+
+func (r deleteFieldRequest) Op() op {
+	return storedel
+}
+
+func (r deleteFieldRequest) Keys() []string {
+	return []string{storeKey(r.Key)}
+}
+
+func (r deleteFieldRequest) Args() []interface{} {
+	return []interface{}{r.Field, r.IfValue}
+}
+
+type fieldsRequest struct {
+	Key string
+}
+
+func (r fieldsRequest) Op() op {
+	return storefields
+}
+
+func (r fieldsRequest) Keys() []string {
+	return []string{storeKey(r.Key)}
+}
+
+func (r fieldsRequest) Args() []interface{} {
+	return nil
+}
+
+type incrFieldRequest struct {
+	Key    string
+	Field  string
+	Amount uint64
+}
+
+func (r incrFieldRequest) Op() op {
+	return storeincr
+}
+
+func (r incrFieldRequest) Keys() []string {
+	return []string{storeKey(r.Key)}
+}
+
+func (r incrFieldRequest) Args() []interface{} {
+	return []interface{}{r.Field, r.Amount}
+}
+
+// GetField is the implementation of core.Store for MQueue
+func (m *MQueue) GetField(ctx context.Context, req core.FieldRequest) (string, bool, error) {
+	v, err := m.tracker.Instrument(store, func() (interface{}, error) {
+		return m.exec(ctx, getFieldRequest{Key: req.Key, Field: req.Field})
+	})
+	if err == redis.Nil {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, ErrRedisExec{Cause: err}
+	}
+
+	return v.(string), true, nil
+}
+
+// SetField is the implementation of core.Store for MQueue
+func (m *MQueue) SetField(ctx context.Context, req core.SetFieldRequest) (string, bool, error) {
+	v, err := m.tracker.Instrument(store, func() (interface{}, error) {
+		return m.exec(ctx, setFieldRequest{
+			Key:      req.Key,
+			Field:    req.Field,
+			Value:    req.Value,
+			IfAbsent: req.IfAbsent,
+		})
+	})
+	if err != nil {
+		return "", false, ErrRedisExec{Cause: err}
+	}
+
+	res, ok := v.([]interface{})
+	if !ok || len(res) != 2 {
+		return "", false, ErrDeserialize{Cause: errors.New("unexpected result for set field")}
+	}
+
+	value, _ := res[1].(string)
+	return value, res[0].(int64) == 1, nil
+}
+
+// DeleteField is the implementation of core.Store for MQueue
+func (m *MQueue) DeleteField(ctx context.Context, req core.DeleteFieldRequest) (bool, error) {
+	v, err := m.tracker.Instrument(store, func() (interface{}, error) {
+		return m.exec(ctx, deleteFieldRequest{Key: req.Key, Field: req.Field, IfValue: req.IfValue})
+	})
+	if err != nil {
+		return false, ErrRedisExec{Cause: err}
+	}
+
+	return v.(int64) == 1, nil
+}
+
+// Fields is the implementation of core.Store for MQueue
+func (m *MQueue) Fields(ctx context.Context, req core.FieldsRequest) (map[string]string, error) {
+	v, err := m.tracker.Instrument(store, func() (interface{}, error) {
+		return m.exec(ctx, fieldsRequest{Key: req.Key})
+	})
+	if err != nil {
+		return nil, ErrRedisExec{Cause: err}
+	}
+
+	// the fields are returned as a flat list of field and value pairs
+	res := v.([]interface{})
+	fields := make(map[string]string, len(res)/2)
+	for i := 0; i+1 < len(res); i += 2 {
+		fields[res[i].(string)] = res[i+1].(string)
+	}
+
+	return fields, nil
+}
+
+// IncrField is the implementation of core.Store for MQueue
+func (m *MQueue) IncrField(ctx context.Context, req core.IncrFieldRequest) (uint64, error) {
+	v, err := m.tracker.Instrument(store, func() (interface{}, error) {
+		return m.exec(ctx, incrFieldRequest{Key: req.Key, Field: req.Field, Amount: req.Amount})
+	})
+	if err != nil {
+		return 0, ErrRedisExec{Cause: err}
+	}
+
+	return uint64(v.(int64)), nil
+}
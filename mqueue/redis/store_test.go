@@ -0,0 +1,44 @@
+package redis
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetFieldRequest(t *testing.T) {
+	req := setFieldRequest{
+		Key:      "key",
+		Field:    "field",
+		Value:    "value",
+		IfAbsent: true,
+	}
+
+	assert.Equal(t, []string{"store:key"}, req.Keys())
+	assert.Equal(t, []interface{}{"field", "value", "1"}, req.Args())
+}
+
+func TestDeleteFieldRequest(t *testing.T) {
+	req := deleteFieldRequest{
+		Key:     "key",
+		Field:   "field",
+		IfValue: "value",
+	}
+
+	assert.Equal(t, []string{"store:key"}, req.Keys())
+	assert.Equal(t, []interface{}{"field", "value"}, req.Args())
+}
+
+func TestFieldsRequest(t *testing.T) {
+	req := fieldsRequest{Key: "key"}
+
+	assert.Equal(t, []string{"store:key"}, req.Keys())
+	assert.Equal(t, []interface{}(nil), req.Args())
+}
+
+func TestIncrFieldRequest(t *testing.T) {
+	req := incrFieldRequest{Key: "key", Field: "field", Amount: 3}
+
+	assert.Equal(t, []string{"store:key"}, req.Keys())
+	assert.Equal(t, []interface{}{"field", uint64(3)}, req.Args())
+}
@@ -2,6 +2,9 @@ package noise
 
 import (
 	"context"
+	"fmt"
+
+	"github.com/oasislabs/oasis-gateway/concurrent"
 )
 
 type response struct {
@@ -18,7 +21,9 @@ type request struct {
 // Client manages a fixed pool of connections and distributes work amongst
 // them so that the caller does not need to worry about concurrency
 type Client struct {
-	c chan request
+	c          chan request
+	cancel     context.CancelFunc
+	supervisor *concurrent.Supervisor
 }
 
 // ClientProps sets up the connection pool
@@ -28,15 +33,22 @@ type ClientProps struct {
 	SessionProps SessionProps
 }
 
-// DialContext creates a new pool of connections
+// DialContext creates a new pool of connections. The loops that serve
+// the connections are restarted if they panic, and run until the
+// context is cancelled or the pool is closed
 func DialContext(ctx context.Context, props ClientProps) (*Client, error) {
 	ctx, cancel := context.WithCancel(ctx)
-	defer cancel()
-	pool := &Client{c: make(chan request, 64)}
+	pool := &Client{
+		c:          make(chan request, 64),
+		cancel:     cancel,
+		supervisor: concurrent.NewSupervisor(concurrent.DefaultSupervisorProps),
+	}
 
 	for i := 0; i < props.Conns; i++ {
 		// TODO(stan): this can be done in parallel
 		if err := pool.dialConnection(ctx, props.Client, &props.SessionProps); err != nil {
+			// stop the loops of the connections already established
+			cancel()
 			return nil, err
 		}
 	}
@@ -44,31 +56,75 @@ func DialContext(ctx context.Context, props ClientProps) (*Client, error) {
 	return pool, nil
 }
 
+// Close stops the loops that serve the connections of the pool
+func (p *Client) Close() {
+	p.cancel()
+}
+
+// Restarts returns the number of times the loops that
+// serve the connections were restarted after a panic
+func (p *Client) Restarts() uint64 {
+	return p.supervisor.Restarts()
+}
+
 // Request issues a request to one of the connections in the pool and
 // retrieves the response. The pool is concurrency safe.
 func (p *Client) Request(ctx context.Context, req RequestPayload) (ResponsePayload, error) {
-	res := make(chan response)
-	p.c <- request{Context: ctx, Request: req, Response: res}
-	response := <-res
-	return response.Response, response.Error
+	// the response is buffered so that a connection does not
+	// block on a caller that stopped waiting for it
+	res := make(chan response, 1)
+	select {
+	case p.c <- request{Context: ctx, Request: req, Response: res}:
+	case <-ctx.Done():
+		return ResponsePayload{}, ctx.Err()
+	}
+
+	select {
+	case response := <-res:
+		return response.Response, response.Error
+	case <-ctx.Done():
+		return ResponsePayload{}, ctx.Err()
+	}
 }
 
-func startConnLoop(ctx context.Context, conn *Conn, c <-chan request) {
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case req, ok := <-c:
-			if !ok {
-				return
-			}
+func connLoop(conn *Conn, c <-chan request) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case req, ok := <-c:
+				if !ok {
+					return nil
+				}
 
-			res, err := conn.Request(req.Context, req.Request)
-			req.Response <- response{Error: err, Response: res}
+				handleConnRequest(conn, req)
+			}
 		}
 	}
 }
 
+// handleConnRequest issues the request on the connection. If the
+// request panics the caller receives an error before the panic is
+// passed on to restart the loop
+func handleConnRequest(conn *Conn, req request) {
+	// the caller already stopped waiting for the response
+	if err := req.Context.Err(); err != nil {
+		req.Response <- response{Error: err}
+		return
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			req.Response <- response{Error: fmt.Errorf("request failed with panic %v", r)}
+			panic(r)
+		}
+	}()
+
+	res, err := conn.Request(req.Context, req.Request)
+	req.Response <- response{Error: err, Response: res}
+}
+
 func (p *Client) dialConnection(ctx context.Context, client Requester, props *SessionProps) error {
 	conn, err := DialConnContext(ctx, client, props)
 	if err != nil {
@@ -77,6 +133,6 @@ func (p *Client) dialConnection(ctx context.Context, client Requester, props *Se
 		return err
 	}
 
-	go startConnLoop(ctx, conn, p.c)
+	p.supervisor.Go(ctx, connLoop(conn, p.c), nil)
 	return nil
 }
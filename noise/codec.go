@@ -69,14 +69,16 @@ type CloseMessage struct{}
 // IncomingFrame is the frame used on top of the noise protocol to send messages
 // and be able to multiplex them using the session ID as the identifier
 type IncomingFrame struct {
-	Payload codec.Raw `codec:"payload"`
+	Payload  codec.Raw `codec:"payload"`
+	Fragment *Fragment `codec:"fragment,omitempty"`
 }
 
 // OutgoingFrame is the frame used on top of the noise protocol to send messages
 // and be able to multiplex them using the session ID as the identifier
 type OutgoingFrame struct {
-	SessionID []byte `codec:"session"`
-	Payload   []byte `codec:"payload"`
+	SessionID []byte    `codec:"session"`
+	Payload   []byte    `codec:"payload"`
+	Fragment  *Fragment `codec:"fragment,omitempty"`
 }
 
 // ResponseBody is used to deserialize a received response
@@ -142,7 +144,14 @@ func SerializeIntoFrame(w io.Writer, r io.Reader, sessionID []byte) error {
 // SerializeBufferIntoFrame serializes the contents of a buffer into
 // a RequestMessage
 func SerializeBufferIntoFrame(w io.Writer, buf *bytes.Buffer, sessionID []byte) error {
-	err := SerializeFrame(w, &OutgoingFrame{SessionID: sessionID, Payload: buf.Bytes()})
+	return SerializeBufferIntoFragmentFrame(w, buf, sessionID, nil)
+}
+
+// SerializeBufferIntoFragmentFrame serializes the contents of a buffer
+// into a frame that carries a fragment of a request. If the fragment
+// is nil the frame carries a whole request
+func SerializeBufferIntoFragmentFrame(w io.Writer, buf *bytes.Buffer, sessionID []byte, fragment *Fragment) error {
+	err := SerializeFrame(w, &OutgoingFrame{SessionID: sessionID, Payload: buf.Bytes(), Fragment: fragment})
 	// Consume all the buffer bytes since we read them regardless of whether
 	// there's an error
 	buf.Reset()
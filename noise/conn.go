@@ -37,6 +37,14 @@ type Conn struct {
 
 	in  *bytes.Buffer
 	out *bytes.Buffer
+
+	// maxMessageSize is the maximum size of the messages sent
+	// on the session
+	maxMessageSize int
+
+	// fragmentation is true if the remote endpoint agreed on
+	// maxMessageSize and reassembles fragmented requests
+	fragmentation bool
 }
 
 // DialConnContext creates a new connection and completes the handshake with the
@@ -53,12 +61,17 @@ func DialConnContext(ctx context.Context, client Requester, props *SessionProps)
 	}
 
 	conn := &Conn{
-		client:  client,
-		session: session,
-		in:      bytes.NewBuffer(make([]byte, 0, 512)),
-		out:     bytes.NewBuffer(make([]byte, 0, 512)),
+		client:         client,
+		session:        session,
+		in:             bytes.NewBuffer(make([]byte, 0, 512)),
+		out:            bytes.NewBuffer(make([]byte, 0, 512)),
+		maxMessageSize: MaxMessageSize,
 	}
-	if err := conn.doHandshake(ctx); err != nil {
+	if props.MaxMessageSize > 0 && props.MaxMessageSize < MaxMessageSize {
+		conn.maxMessageSize = int(props.MaxMessageSize)
+	}
+
+	if err := conn.doHandshake(ctx, props.MaxMessageSize > 0); err != nil {
 		return nil, err
 	}
 
@@ -109,52 +122,117 @@ func (c *Conn) request(ctx context.Context, req RequestPayload) (ResponsePayload
 		return ResponsePayload{}, err
 	}
 
+	maxPayload := c.maxMessageSize - cipherOverhead - frameOverhead
+	if c.in.Len() > maxPayload {
+		if !c.fragmentation {
+			c.in.Reset()
+			return ResponsePayload{}, ErrMessageTooLarge
+		}
+
+		if err := c.sendFragments(ctx, maxPayload); err != nil {
+			return ResponsePayload{}, err
+		}
+	} else if err := c.roundTrip(ctx, nil); err != nil {
+		return ResponsePayload{}, err
+	}
+
+	var res ResponseMessage
+	// parse the response to make sure that it's a ResponseMessage
+	if err := DeserializeResponseMessage(c.in, &res); err != nil {
+		return ResponsePayload{}, err
+	}
+
+	return res.Response, nil
+}
+
+// sendFragments splits the request message in c.in into fragments
+// of at most size bytes and sends them in order. Once it returns
+// c.in holds the response to the last fragment
+func (c *Conn) sendFragments(ctx context.Context, size int) error {
+	p := make([]byte, c.in.Len())
+	copy(p, c.in.Bytes())
+	c.in.Reset()
+
+	fragments := split(p, size)
+	for i, fragment := range fragments {
+		c.in.Write(fragment)
+		if err := c.roundTrip(ctx, &Fragment{
+			Index: uint32(i),
+			Count: uint32(len(fragments)),
+		}); err != nil {
+			return err
+		}
+
+		if i < len(fragments)-1 && c.in.Len() > 0 {
+			c.in.Reset()
+			return errors.New("remote endpoint responded to a request fragment")
+		}
+	}
+
+	return nil
+}
+
+// roundTrip encrypts the payload in c.in, sends it in a frame to
+// the remote endpoint and decrypts the response back into c.in
+func (c *Conn) roundTrip(ctx context.Context, fragment *Fragment) error {
 	// encrypt the request contents with the ciphers in the session
 	if _, err := c.session.Write(c.out, c.in); err != nil {
-		return ResponsePayload{}, err
+		return err
 	}
 
 	// serialize the contents of the buffer along with the session ID
 	// into a frame that can finally be sent on the network
-	if err := SerializeIntoFrame(c.in, c.out, c.session.ID()); err != nil {
-		return ResponsePayload{}, err
+	if err := SerializeBufferIntoFragmentFrame(c.in, c.out, c.session.ID(), fragment); err != nil {
+		return err
 	}
 
 	// send request whose contents are in c.out. At this point
 	// c.in should be empty
 	if err := c.client.Request(ctx, c.out, c.in); err != nil {
-		return ResponsePayload{}, err
+		return err
 	}
 
 	// decrypt session contents
 	_, err := c.session.Read(c.in, c.out)
-	if err != nil {
-		return ResponsePayload{}, err
-	}
-
-	var res ResponseMessage
-	// parse the response to make sure that it's a ResponseMessage
-	if err := DeserializeResponseMessage(c.in, &res); err != nil {
-		return ResponsePayload{}, err
-	}
-
-	return res.Response, nil
+	return err
 }
 
-// doHandshake performs the initial handshake with the remote endpoint
-func (c *Conn) doHandshake(ctx context.Context) error {
+// doHandshake performs the initial handshake with the remote endpoint.
+// If negotiate is set the maximum message size is offered to the
+// remote endpoint with the first message of the handshake
+func (c *Conn) doHandshake(ctx context.Context, negotiate bool) error {
 	in := bytes.NewBuffer([]byte{})
 	out := bytes.NewBuffer([]byte{})
 
+	if negotiate {
+		p, err := marshalNegotiation(&Negotiation{MaxMessageSize: uint32(c.maxMessageSize)})
+		if err != nil {
+			return err
+		}
+		in.Write(p)
+	}
+
 	for i := 0; i < 10 && !c.session.CanUpgrade(); i++ {
-		// since we are not sending any specific payload to the remote end
-		// or expect any incoming payload sendFrame is used here
+		// the only payload sent to the remote end or expected from
+		// it is the negotiation of the maximum message size
 		if err := c.sendFrame(ctx, out, in); err != nil && err != ErrReadyUpgrade {
 			return err
 		}
 
 		if out.Len() > 0 {
-			return errors.New("noise payload not expected from remote endpoint during handshake")
+			if !negotiate || c.fragmentation {
+				return errors.New("noise payload not expected from remote endpoint during handshake")
+			}
+
+			var n Negotiation
+			if err := unmarshalNegotiation(out.Bytes(), &n); err != nil {
+				return err
+			}
+			if int(n.MaxMessageSize) < c.maxMessageSize {
+				c.maxMessageSize = int(n.MaxMessageSize)
+			}
+			c.fragmentation = true
+			out.Reset()
 		}
 	}
 
@@ -0,0 +1,79 @@
+package noise
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/ugorji/go/codec"
+)
+
+const (
+	// MaxMessageSize is the maximum size of a noise message
+	MaxMessageSize = 65535
+
+	// cipherOverhead is the size of the authentication tag
+	// the cipher adds to each encrypted message
+	cipherOverhead = 16
+
+	// frameOverhead is the space reserved in a message for the
+	// session ID and the encoding of the frame that carries it
+	frameOverhead = 128
+)
+
+var (
+	ErrMessageTooLarge = errors.New("request exceeds the maximum message size of the session")
+)
+
+// Negotiation is the payload the endpoints exchange during the
+// handshake to agree on the maximum size of the messages of the
+// session. The smallest of the sizes offered is used
+type Negotiation struct {
+	MaxMessageSize uint32 `codec:"max_message_size"`
+}
+
+// Fragment identifies a part of a request message that was split
+// because it exceeded the maximum message size of the session.
+// The remote endpoint reassembles the request once it receives
+// the last fragment, and only answers the last fragment with a
+// response. The other fragments are answered with an empty payload
+type Fragment struct {
+	// Index of the fragment within the request
+	Index uint32 `codec:"index"`
+
+	// Count is the number of fragments of the request
+	Count uint32 `codec:"count"`
+}
+
+// marshalNegotiation serializes a Negotiation
+func marshalNegotiation(n *Negotiation) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := codec.NewEncoder(&buf, &codec.CborHandle{}).Encode(n); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// unmarshalNegotiation deserializes a Negotiation
+func unmarshalNegotiation(p []byte, n *Negotiation) error {
+	if err := codec.NewDecoderBytes(p, &codec.CborHandle{}).Decode(n); err != nil {
+		return err
+	}
+
+	if n.MaxMessageSize <= cipherOverhead+frameOverhead {
+		return errors.New("negotiated maximum message size is too small")
+	}
+
+	return nil
+}
+
+// split splits p into fragments of at most size bytes
+func split(p []byte, size int) [][]byte {
+	fragments := make([][]byte, 0, (len(p)+size-1)/size)
+	for len(p) > size {
+		fragments = append(fragments, p[:size])
+		p = p[size:]
+	}
+
+	return append(fragments, p)
+}
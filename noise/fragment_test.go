@@ -0,0 +1,135 @@
+package noise
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/flynn/noise"
+	"github.com/stretchr/testify/assert"
+	"github.com/ugorji/go/codec"
+)
+
+// responder is a remote endpoint that completes the handshake,
+// optionally answers the negotiation of the maximum message size and
+// reassembles fragmented requests. Its response echoes the method
+// of the request
+type responder struct {
+	t         *testing.T
+	state     *noise.HandshakeState
+	dec       *noise.CipherState
+	enc       *noise.CipherState
+	maxSize   uint32
+	negotiate bool
+	frames    int
+	pending   []byte
+}
+
+func (r *responder) Request(ctx context.Context, w io.Writer, rd io.Reader) error {
+	p, _ := ioutil.ReadAll(rd)
+	var f IncomingFrame
+	assert.Nil(r.t, UnmarshalFrame(p, &f))
+	var payload []byte
+	assert.Nil(r.t, codec.NewDecoderBytes(f.Payload, &codec.CborHandle{}).Decode(&payload))
+	r.frames++
+
+	if r.dec == nil {
+		if r.state.MessageIndex() == 0 {
+			msg, _, _, err := r.state.ReadMessage(nil, payload)
+			assert.Nil(r.t, err)
+			var reply []byte
+			if r.negotiate && len(msg) > 0 {
+				reply, _ = marshalNegotiation(&Negotiation{MaxMessageSize: r.maxSize})
+			}
+			out, _, _, err := r.state.WriteMessage(nil, reply)
+			assert.Nil(r.t, err)
+			_, err = w.Write(out)
+			return err
+		}
+		_, cs1, cs2, err := r.state.ReadMessage(nil, payload)
+		assert.Nil(r.t, err)
+		r.dec, r.enc = cs1, cs2
+		return nil
+	}
+
+	plain, err := r.dec.Decrypt(nil, nil, payload)
+	assert.Nil(r.t, err)
+	assert.True(r.t, len(payload) <= int(r.maxSize))
+	r.pending = append(r.pending, plain...)
+	if f.Fragment != nil && f.Fragment.Index < f.Fragment.Count-1 {
+		_, err := w.Write(r.enc.Encrypt(nil, nil, nil))
+		return err
+	}
+
+	var req OutgoingRequestMessage
+	assert.Nil(r.t, codec.NewDecoderBytes(r.pending, &codec.CborHandle{}).Decode(&req))
+	r.pending = nil
+	var buf bytes.Buffer
+	assert.Nil(r.t, codec.NewEncoder(&buf, &codec.CborHandle{}).Encode([]interface{}{"Response", map[string]interface{}{
+		"Body": map[string]interface{}{"Success": req.Request.Method}}}))
+	_, err = w.Write(r.enc.Encrypt(nil, nil, buf.Bytes()))
+	return err
+}
+
+func newResponder(t *testing.T, negotiate bool, size uint32) *responder {
+	pair, _ := noise.DH25519.GenerateKeypair(rand.Reader)
+	state, err := noise.NewHandshakeState(noise.Config{
+		CipherSuite:   noise.NewCipherSuite(noise.DH25519, noise.CipherChaChaPoly, noise.HashSHA256),
+		Random:        rand.Reader,
+		Pattern:       noise.HandshakeXX,
+		StaticKeypair: pair,
+	})
+	assert.Nil(t, err)
+	return &responder{t: t, state: state, negotiate: negotiate, maxSize: size}
+}
+
+func TestConnFragmentsLargeRequests(t *testing.T) {
+	r := newResponder(t, true, 4096)
+	conn, err := DialConnContext(context.Background(), r, &SessionProps{Initiator: true, MaxMessageSize: MaxMessageSize})
+	assert.Nil(t, err)
+	assert.Equal(t, 4096, conn.maxMessageSize)
+
+	res, err := conn.Request(context.Background(), RequestPayload{Method: "small"})
+	assert.Nil(t, err)
+	assert.Equal(t, "small", res.Success)
+
+	// a request larger than the negotiated size is sent in
+	// fragments and the session remains usable afterwards
+	big := strings.Repeat("a", 20000)
+	before := r.frames
+	res, err = conn.Request(context.Background(), RequestPayload{Method: big})
+	assert.Nil(t, err)
+	assert.Equal(t, big, res.Success)
+	assert.True(t, r.frames-before > 4)
+
+	res, err = conn.Request(context.Background(), RequestPayload{Method: "again"})
+	assert.Nil(t, err)
+	assert.Equal(t, "again", res.Success)
+}
+
+func TestConnWithoutNegotiation(t *testing.T) {
+	r := newResponder(t, false, MaxMessageSize)
+	conn, err := DialConnContext(context.Background(), r, &SessionProps{Initiator: true, MaxMessageSize: MaxMessageSize})
+	assert.Nil(t, err)
+	assert.False(t, conn.fragmentation)
+
+	res, err := conn.Request(context.Background(), RequestPayload{Method: "small"})
+	assert.Nil(t, err)
+	assert.Equal(t, "small", res.Success)
+
+	_, err = conn.Request(context.Background(), RequestPayload{Method: strings.Repeat("a", 70000)})
+	assert.Equal(t, ErrMessageTooLarge, err)
+}
+
+func TestConnNegotiationNotOffered(t *testing.T) {
+	r := newResponder(t, true, MaxMessageSize)
+	conn, err := DialConnContext(context.Background(), r, &SessionProps{Initiator: true})
+	assert.Nil(t, err)
+	res, err := conn.Request(context.Background(), RequestPayload{Method: "small"})
+	assert.Nil(t, err)
+	assert.Equal(t, "small", res.Success)
+}
@@ -218,6 +218,13 @@ type SessionProps struct {
 	// Initiator sets the role of this Session instance for the handshake. If
 	// true, this Session initiates the handshake
 	Initiator bool
+
+	// MaxMessageSize if set is offered to the remote endpoint during the
+	// handshake as the maximum size of the messages of the session. If
+	// the remote endpoint answers with its own size, requests larger
+	// than the smallest of both are split into fragments. Otherwise
+	// requests cannot exceed the size. Responses are never fragmented
+	MaxMessageSize uint32
 }
 
 func genSessionID(id []byte) error {
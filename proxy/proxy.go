@@ -0,0 +1,211 @@
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	stderr "github.com/pkg/errors"
+	xproxy "golang.org/x/net/proxy"
+)
+
+const (
+	// Environment is the value that configures a destination to use
+	// the proxies defined in the HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+	// environment variables
+	Environment = ""
+
+	// Direct is the value that configures a destination to be
+	// connected to without a proxy, even if one is set in
+	// the environment
+	Direct = "direct"
+)
+
+// Proxy defines how the connections to a destination are routed.
+// A nil Proxy uses the proxies defined in the environment
+type Proxy struct {
+	url    *url.URL
+	direct bool
+}
+
+// Parse creates a Proxy from its configured value, which can be
+// Environment, Direct or the url of an http, https or socks5 proxy
+func Parse(value string) (*Proxy, error) {
+	switch value {
+	case Environment:
+		return nil, nil
+	case Direct:
+		return &Proxy{direct: true}, nil
+	}
+
+	u, err := url.Parse(value)
+	if err != nil {
+		return nil, stderr.Wrapf(err, "failed to parse proxy url %s", value)
+	}
+
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("proxy scheme %s is not supported", u.Scheme)
+	}
+
+	if len(u.Host) == 0 {
+		return nil, fmt.Errorf("proxy url %s does not have a host", value)
+	}
+
+	return &Proxy{url: u}, nil
+}
+
+// Explicit returns true if the proxy is configured for the
+// destination rather than taken from the environment
+func (p *Proxy) Explicit() bool {
+	return p != nil
+}
+
+// String returns the configured value of the proxy. The
+// credentials of the proxy url are redacted
+func (p *Proxy) String() string {
+	switch {
+	case p == nil:
+		return Environment
+	case p.direct:
+		return Direct
+	default:
+		u := *p.url
+		if u.User != nil {
+			u.User = url.User("xxxxx")
+		}
+		return u.String()
+	}
+}
+
+// URL returns the url of the proxy through which target is reached,
+// or nil if target is reached without a proxy
+func (p *Proxy) URL(target *url.URL) (*url.URL, error) {
+	switch {
+	case p == nil:
+		return http.ProxyFromEnvironment(&http.Request{URL: target})
+	case p.direct:
+		return nil, nil
+	default:
+		return p.url, nil
+	}
+}
+
+// Transport returns an http transport that routes requests
+// through the proxy
+func (p *Proxy) Transport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = func(req *http.Request) (*url.URL, error) {
+		return p.URL(req.URL)
+	}
+	return transport
+}
+
+// HttpClient returns an http client that routes requests
+// through the proxy
+func (p *Proxy) HttpClient() *http.Client {
+	return &http.Client{Transport: p.Transport()}
+}
+
+// DialContext establishes a tcp connection to address through the
+// proxy. Connections through http proxies are tunneled with CONNECT
+func (p *Proxy) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+
+	u, err := p.URL(&url.URL{Scheme: "https", Host: address})
+	if err != nil {
+		return nil, err
+	}
+
+	if u == nil {
+		return dialer.DialContext(ctx, network, address)
+	}
+
+	switch u.Scheme {
+	case "socks5":
+		var auth *xproxy.Auth
+		if u.User != nil {
+			password, _ := u.User.Password()
+			auth = &xproxy.Auth{User: u.User.Username(), Password: password}
+		}
+
+		d, err := xproxy.SOCKS5("tcp", u.Host, auth, dialer)
+		if err != nil {
+			return nil, err
+		}
+
+		return d.(xproxy.ContextDialer).DialContext(ctx, network, address)
+	case "http", "https":
+		return dialConnect(ctx, dialer, u, address)
+	default:
+		return nil, fmt.Errorf("proxy scheme %s is not supported", u.Scheme)
+	}
+}
+
+// dialConnect opens a tunnel to address through the http
+// proxy at u using the CONNECT method
+func dialConnect(ctx context.Context, dialer *net.Dialer, u *url.URL, address string) (net.Conn, error) {
+	conn, err := dialer.DialContext(ctx, "tcp", u.Host)
+	if err != nil {
+		return nil, stderr.Wrapf(err, "failed to dial proxy %s", u.Host)
+	}
+
+	if u.Scheme == "https" {
+		conn = tls.Client(conn, &tls.Config{ServerName: u.Hostname()})
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+		defer func() { _ = conn.SetDeadline(time.Time{}) }()
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: address},
+		Host:   address,
+		Header: make(http.Header),
+	}
+	if u.User != nil {
+		password, _ := u.User.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(u.User.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, stderr.Wrapf(err, "failed to write CONNECT request to proxy %s", u.Host)
+	}
+
+	reader := bufio.NewReader(conn)
+	res, err := http.ReadResponse(reader, req)
+	if err != nil {
+		conn.Close()
+		return nil, stderr.Wrapf(err, "failed to read CONNECT response from proxy %s", u.Host)
+	}
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy %s refused to CONNECT to %s with status %s", u.Host, address, res.Status)
+	}
+
+	return &bufferedConn{Conn: conn, reader: reader}, nil
+}
+
+// bufferedConn is a connection whose first bytes may have already
+// been read into a buffer while reading the CONNECT response
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
@@ -0,0 +1,117 @@
+package quota
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	auth "github.com/oasislabs/oasis-gateway/auth/core"
+	backend "github.com/oasislabs/oasis-gateway/backend/core"
+	"github.com/oasislabs/oasis-gateway/errors"
+	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/oasislabs/oasis-gateway/rpc"
+)
+
+const (
+	// HttpHeaderInFlightLimit is the number of requests a session
+	// may have in flight
+	HttpHeaderInFlightLimit = "X-Quota-InFlight-Limit"
+
+	// HttpHeaderInFlightRemaining is the number of requests the
+	// session can still start before it reaches the limit
+	HttpHeaderInFlightRemaining = "X-Quota-InFlight-Remaining"
+
+	// HttpHeaderSpendLimit is the amount of gas the issuer
+	// of the session may spend
+	HttpHeaderSpendLimit = "X-Quota-Spend-Limit"
+
+	// HttpHeaderSpendRemaining is the amount of gas the issuer
+	// of the session can still spend
+	HttpHeaderSpendRemaining = "X-Quota-Spend-Remaining"
+)
+
+// Client interface for the underlying operations needed by
+// the HttpMiddleware
+type Client interface {
+	// GetQuotaState returns how much of its quotas a session has used
+	GetQuotaState(context.Context, backend.GetQuotaStateRequest) (backend.QuotaState, errors.Err)
+}
+
+// HttpMiddleware adds the state of the quotas of the session to the
+// responses to its requests, so that clients can slow down before
+// their requests are rejected. It must be placed after the
+// authentication middleware so that the session is set in the context.
+// The headers are added once the request is served, so that they
+// account for the request itself. None of the quotas is a limit on
+// the requests of a time window, so the X-RateLimit-* headers and
+// their Reset time do not apply: the requests in flight are released
+// as they complete and the gas spent is never reset
+type HttpMiddleware struct {
+	client Client
+	logger log.Logger
+	next   rpc.HttpMiddleware
+}
+
+// NewHttpMiddleware creates a new HttpMiddleware
+func NewHttpMiddleware(client Client, logger log.Logger, next rpc.HttpMiddleware) *HttpMiddleware {
+	if client == nil {
+		panic("client must be set")
+	}
+
+	if logger == nil {
+		panic("log must be set")
+	}
+
+	if next == nil {
+		panic("next must be set")
+	}
+
+	return &HttpMiddleware{
+		client: client,
+		logger: logger.ForClass("quota", "HttpMiddleware"),
+		next:   next,
+	}
+}
+
+// ServeHTTP is the implementation of rpc.HttpMiddleware for HttpMiddleware
+func (m *HttpMiddleware) ServeHTTP(req *http.Request) (interface{}, error) {
+	v, err := m.next.ServeHTTP(req)
+
+	header := rpc.ResponseHeader(req.Context())
+	if header == nil {
+		return v, err
+	}
+
+	ctx := req.Context()
+	session, _ := ctx.Value(auth.Session{}).(string)
+	aad, _ := ctx.Value(auth.AAD{}).(string)
+	state, serr := m.client.GetQuotaState(ctx, backend.GetQuotaStateRequest{SessionKey: session, AAD: aad})
+	if serr != nil {
+		// the headers are advisory, so the request is
+		// served without them
+		m.logger.Debug(ctx, "failed to retrieve quota state", log.MapFields{
+			"call_type": "QuotaStateFailure",
+		}, serr)
+		return v, err
+	}
+
+	if state.MaxInFlight > 0 {
+		header.Set(HttpHeaderInFlightLimit, strconv.FormatUint(state.MaxInFlight, 10))
+		header.Set(HttpHeaderInFlightRemaining, strconv.FormatUint(remaining(state.MaxInFlight, state.InFlight), 10))
+	}
+
+	if state.SpendLimit > 0 {
+		header.Set(HttpHeaderSpendLimit, strconv.FormatUint(state.SpendLimit, 10))
+		header.Set(HttpHeaderSpendRemaining, strconv.FormatUint(remaining(state.SpendLimit, state.Spent), 10))
+	}
+
+	return v, err
+}
+
+func remaining(limit, used uint64) uint64 {
+	if used >= limit {
+		return 0
+	}
+
+	return limit - used
+}
@@ -0,0 +1,96 @@
+package quota
+
+import (
+	"context"
+	stderr "errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	auth "github.com/oasislabs/oasis-gateway/auth/core"
+	backend "github.com/oasislabs/oasis-gateway/backend/core"
+	"github.com/oasislabs/oasis-gateway/errors"
+	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/oasislabs/oasis-gateway/rpc"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+var logger = log.NewLogrus(log.LogrusLoggerProperties{
+	Level:  logrus.DebugLevel,
+	Output: ioutil.Discard,
+})
+
+type mockClient struct {
+	req   backend.GetQuotaStateRequest
+	state backend.QuotaState
+	err   errors.Err
+}
+
+func (c *mockClient) GetQuotaState(ctx context.Context, req backend.GetQuotaStateRequest) (backend.QuotaState, errors.Err) {
+	c.req = req
+	return c.state, c.err
+}
+
+func serve(client Client, next rpc.HttpMiddleware) *httptest.ResponseRecorder {
+	route := rpc.NewHttpRoute(rpc.HttpRouteProps{
+		Logger:  logger,
+		Encoder: rpc.JsonEncoder{},
+		Handlers: rpc.MethodHandlers{
+			"POST": NewHttpMiddleware(client, logger, next),
+		},
+	})
+
+	req, _ := http.NewRequest("POST", "/execute", nil)
+	ctx := context.WithValue(req.Context(), auth.AAD{}, "aad")
+	ctx = context.WithValue(ctx, auth.Session{}, "session")
+
+	recorder := httptest.NewRecorder()
+	route.ServeHTTP(recorder, req.WithContext(ctx))
+	return recorder
+}
+
+func TestHttpMiddlewareHeaders(t *testing.T) {
+	client := &mockClient{state: backend.QuotaState{
+		MaxInFlight: 4,
+		InFlight:    1,
+		SpendLimit:  100,
+		Spent:       120,
+	}}
+
+	recorder := serve(client, rpc.HttpMiddlewareFunc(func(req *http.Request) (interface{}, error) {
+		return map[string]string{"result": "ok"}, nil
+	}))
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, backend.GetQuotaStateRequest{SessionKey: "session", AAD: "aad"}, client.req)
+	assert.Equal(t, "4", recorder.Header().Get(HttpHeaderInFlightLimit))
+	assert.Equal(t, "3", recorder.Header().Get(HttpHeaderInFlightRemaining))
+	assert.Equal(t, "100", recorder.Header().Get(HttpHeaderSpendLimit))
+	assert.Equal(t, "0", recorder.Header().Get(HttpHeaderSpendRemaining))
+}
+
+func TestHttpMiddlewareHeadersOnError(t *testing.T) {
+	client := &mockClient{state: backend.QuotaState{MaxInFlight: 1, InFlight: 1}}
+
+	recorder := serve(client, rpc.HttpMiddlewareFunc(func(req *http.Request) (interface{}, error) {
+		return nil, errors.New(errors.ErrInFlightLimitReached, nil)
+	}))
+
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	assert.Equal(t, "1", recorder.Header().Get(HttpHeaderInFlightLimit))
+	assert.Equal(t, "0", recorder.Header().Get(HttpHeaderInFlightRemaining))
+	assert.Empty(t, recorder.Header().Get(HttpHeaderSpendLimit))
+}
+
+func TestHttpMiddlewareStateFailure(t *testing.T) {
+	client := &mockClient{err: errors.New(errors.ErrStore, stderr.New("unavailable"))}
+
+	recorder := serve(client, rpc.HttpMiddlewareFunc(func(req *http.Request) (interface{}, error) {
+		return map[string]string{"result": "ok"}, nil
+	}))
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Empty(t, recorder.Header().Get(HttpHeaderInFlightLimit))
+}
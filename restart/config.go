@@ -0,0 +1,49 @@
+package restart
+
+import (
+	"errors"
+	"time"
+
+	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// Config defines how the gateway hands over its
+// listening sockets to a new process
+type Config struct {
+	// Enabled if set a SIGUSR2 starts a new process of the
+	// same binary that takes over the listening sockets
+	Enabled bool
+
+	// DrainTimeout is the maximum time the process that is
+	// replaced waits for the requests in flight to complete
+	DrainTimeout time.Duration
+}
+
+func (c *Config) Log(fields log.Fields) {
+	fields.Add("restart.enabled", c.Enabled)
+	fields.Add("restart.drain_timeout_ms", int64(c.DrainTimeout/time.Millisecond))
+}
+
+func (c *Config) Configure(v *viper.Viper) error {
+	c.Enabled = v.GetBool("restart.enabled")
+
+	timeout := v.GetInt64("restart.drain_timeout_ms")
+	if timeout < 0 {
+		return errors.New("restart.drain_timeout_ms cannot be negative")
+	}
+	c.DrainTimeout = time.Duration(timeout) * time.Millisecond
+
+	return nil
+}
+
+func (c *Config) Bind(v *viper.Viper, cmd *cobra.Command) error {
+	cmd.PersistentFlags().Bool("restart.enabled", false,
+		"if set, a SIGUSR2 starts a new process of the same binary that takes over the "+
+			"listening sockets, so that the binary can be upgraded without dropping connections.")
+	cmd.PersistentFlags().Int64("restart.drain_timeout_ms", 30000,
+		"maximum time in milliseconds that the gateway waits for the requests in flight "+
+			"to complete when it shuts down.")
+	return nil
+}
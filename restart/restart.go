@@ -0,0 +1,193 @@
+// Package restart allows a new process of the gateway to take over the
+// listening sockets of the running one. The sockets are passed to the
+// new process as inherited file descriptors, so connections that are
+// waiting to be accepted are served by the new process while the old
+// one completes the requests in flight
+package restart
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+const (
+	// EnvListeners is the environment variable with the comma
+	// separated names of the listeners passed to a new process.
+	// The listener at position i is the file descriptor 3+i
+	EnvListeners = "OASIS_DG_RESTART_LISTENERS"
+
+	// EnvParent is the environment variable with the pid of
+	// the process that passed the listeners
+	EnvParent = "OASIS_DG_RESTART_PARENT"
+
+	// firstFD is the first file descriptor after stdin,
+	// stdout and stderr
+	firstFD = 3
+)
+
+type namedListener struct {
+	name     string
+	listener net.Listener
+}
+
+// filer is implemented by the listeners that
+// can return a copy of their file descriptor
+type filer interface {
+	File() (*os.File, error)
+}
+
+// Listeners keeps the listeners of the process, so that they
+// can be passed to a new process of the same binary
+type Listeners struct {
+	lock      sync.Mutex
+	parent    int
+	inherited map[string]net.Listener
+	active    []namedListener
+}
+
+// Inherit returns the Listeners of the process with the listeners
+// passed by the process that started it, if any
+func Inherit() (*Listeners, error) {
+	value := os.Getenv(EnvListeners)
+	parent, _ := strconv.Atoi(os.Getenv(EnvParent))
+	os.Unsetenv(EnvListeners)
+	os.Unsetenv(EnvParent)
+
+	if len(value) == 0 {
+		return inheritFiles(nil, nil, 0)
+	}
+
+	names := strings.Split(value, ",")
+	files := make([]*os.File, 0, len(names))
+	for i, name := range names {
+		files = append(files, os.NewFile(uintptr(firstFD+i), name))
+	}
+
+	return inheritFiles(names, files, parent)
+}
+
+// inheritFiles creates the Listeners with a listener for each file,
+// identified by the name at the same position
+func inheritFiles(names []string, files []*os.File, parent int) (*Listeners, error) {
+	l := &Listeners{
+		parent:    parent,
+		inherited: make(map[string]net.Listener),
+	}
+
+	for i, name := range names {
+		// the listener holds its own copy of the file descriptor
+		listener, err := net.FileListener(files[i])
+		files[i].Close()
+		if err != nil {
+			l.closeInherited()
+			return nil, fmt.Errorf("failed to inherit listener %s: %s", name, err.Error())
+		}
+
+		l.inherited[name] = listener
+	}
+
+	return l, nil
+}
+
+// Inherited returns true if the listeners were
+// passed by the process that started this one
+func (l *Listeners) Inherited() bool {
+	return l.parent != 0
+}
+
+// Listen returns the inherited listener with the name or, if there is
+// none, a new listener on the address. The inherited listener is bound
+// to the address the previous process used, which may differ
+func (l *Listeners) Listen(name, network, address string) (net.Listener, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	listener, ok := l.inherited[name]
+	if ok {
+		delete(l.inherited, name)
+	} else {
+		var err error
+		listener, err = net.Listen(network, address)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	l.active = append(l.active, namedListener{name: name, listener: listener})
+	return listener, nil
+}
+
+func (l *Listeners) closeInherited() {
+	for name, listener := range l.inherited {
+		listener.Close()
+		delete(l.inherited, name)
+	}
+}
+
+// Ready notifies the process that passed the listeners that this process
+// is serving requests, so that it can stop accepting connections and
+// shut down. Inherited listeners that were not used are closed
+func (l *Listeners) Ready() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.closeInherited()
+
+	// if the parent already exited the process has been reparented,
+	// and the signal would reach an unrelated process
+	if l.parent == 0 || os.Getppid() != l.parent {
+		return nil
+	}
+
+	return syscall.Kill(l.parent, syscall.SIGTERM)
+}
+
+// Start starts a new process of the same binary with the same arguments
+// and environment, which inherits the listeners returned by Listen. The
+// new process signals this one with a SIGTERM once it is ready
+func (l *Listeners) Start() (*os.Process, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(l.active))
+	files := []*os.File{os.Stdin, os.Stdout, os.Stderr}
+	defer func() {
+		for _, file := range files[firstFD:] {
+			file.Close()
+		}
+	}()
+
+	for _, active := range l.active {
+		f, ok := active.listener.(filer)
+		if !ok {
+			return nil, fmt.Errorf("listener %s cannot be passed to a new process", active.name)
+		}
+
+		file, err := f.File()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get file of listener %s: %s", active.name, err.Error())
+		}
+
+		names = append(names, active.name)
+		files = append(files, file)
+	}
+
+	env := append(os.Environ(),
+		EnvListeners+"="+strings.Join(names, ","),
+		EnvParent+"="+strconv.Itoa(os.Getpid()))
+
+	return os.StartProcess(executable, os.Args, &os.ProcAttr{
+		Env:   env,
+		Files: files,
+	})
+}
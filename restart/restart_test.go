@@ -0,0 +1,70 @@
+package restart
+
+import (
+	"net"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListenNotInherited(t *testing.T) {
+	l, err := inheritFiles(nil, nil, 0)
+	assert.Nil(t, err)
+	assert.False(t, l.Inherited())
+
+	listener, err := l.Listen("public", "tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+
+	assert.Nil(t, l.Ready())
+}
+
+func TestListenInherited(t *testing.T) {
+	original, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer original.Close()
+
+	file, err := original.(*net.TCPListener).File()
+	assert.Nil(t, err)
+
+	l, err := inheritFiles([]string{"public"}, []*os.File{file}, 1)
+	assert.Nil(t, err)
+	assert.True(t, l.Inherited())
+
+	// the inherited listener is used regardless of the address
+	listener, err := l.Listen("public", "tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+	assert.Equal(t, original.Addr().String(), listener.Addr().String())
+
+	// listeners that were not inherited are created
+	other, err := l.Listen("private", "tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer other.Close()
+	assert.NotEqual(t, original.Addr().String(), other.Addr().String())
+}
+
+func TestInheritFilesNotListener(t *testing.T) {
+	file, err := os.Open(os.DevNull)
+	assert.Nil(t, err)
+
+	_, err = inheritFiles([]string{"public"}, []*os.File{file}, 1)
+	assert.Error(t, err)
+}
+
+func TestReadyClosesUnusedListeners(t *testing.T) {
+	original, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer original.Close()
+
+	file, err := original.(*net.TCPListener).File()
+	assert.Nil(t, err)
+
+	// the parent does not match the process that started the
+	// test, so it is not signalled
+	l, err := inheritFiles([]string{"unused"}, []*os.File{file}, -1)
+	assert.Nil(t, err)
+	assert.Nil(t, l.Ready())
+	assert.Empty(t, l.inherited)
+}
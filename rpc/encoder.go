@@ -44,7 +44,12 @@ type Encoder interface {
 // JsonEncoder is a payload encoder that serializes to JSON
 type JsonEncoder struct{}
 
-// Encode is the implementation of Encoder for JsonEncoder
+// Encode is the implementation of Encoder for JsonEncoder. Payloads
+// that implement Serializer are written as they serialize themselves
 func (e JsonEncoder) Encode(writer io.Writer, v interface{}) error {
+	if s, ok := v.(Serializer); ok {
+		return s.Serialize(writer)
+	}
+
 	return stderr.Wrap(json.NewEncoder(writer).Encode(v), "failed to encode json")
 }
@@ -10,6 +10,10 @@ type Error struct {
 	// Description is a human readable description of the error that occurred
 	// to aid the client in debugging
 	Description string `json:"description"`
+
+	// Details if set is additional structured information about the
+	// error, such as when the client can retry the request
+	Details interface{} `json:"details,omitempty"`
 }
 
 // Error is the implementation of go's error interface for Error
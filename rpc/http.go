@@ -17,6 +17,24 @@ import (
 
 const HttpHeaderTraceID = "X-OASIS-TRACE-ID"
 
+// HttpHeaderProvider is implemented by response bodies that need to
+// set additional headers on the http response
+type HttpHeaderProvider interface {
+	// HttpHeaders returns the headers to add to the response
+	HttpHeaders() http.Header
+}
+
+type responseHeaderKey struct{}
+
+// ResponseHeader returns the header of the response to the request
+// served with the context, so that middlewares can add headers to the
+// response whether the request succeeds or fails. It returns nil if
+// the request is not served by an HttpRoute
+func ResponseHeader(ctx context.Context) http.Header {
+	header, _ := ctx.Value(responseHeaderKey{}).(http.Header)
+	return header
+}
+
 // HttpPreProcessor processes a request and can directly write a response
 // to the writer if required.
 type HttpPreProcessor interface {
@@ -52,6 +70,13 @@ type HttpError struct {
 
 	// StatusCode is the HTTP status code that defines the error cause
 	StatusCode int
+
+	// Header if set are headers added to the response
+	Header http.Header
+
+	// Details if set is additional structured information
+	// about the error returned to the client
+	Details interface{}
 }
 
 // Log implementation of log.Loggable
@@ -106,6 +131,11 @@ func HttpNotImplemented(ctx context.Context, error errors.Error) *HttpError {
 	return MakeHttpError(ctx, error, http.StatusNotImplemented)
 }
 
+// HttpServiceUnavailable returns an HTTP service unavailable error
+func HttpServiceUnavailable(ctx context.Context, error errors.Error) *HttpError {
+	return MakeHttpError(ctx, error, http.StatusServiceUnavailable)
+}
+
 // HttpInternalServerError returns an HTTP internal server error
 func HttpInternalServerError(ctx context.Context, error errors.Error) *HttpError {
 	return MakeHttpError(ctx, error, http.StatusInternalServerError)
@@ -142,6 +172,9 @@ type HttpRouteProps struct {
 	Encoder       Encoder
 	Handlers      MethodHandlers
 	PreProcessors []HttpPreProcessor
+
+	// Labels if set are attached to the metrics of the route
+	Labels stats.Labels
 }
 
 // NewHttpRoute creates a new route instance
@@ -160,6 +193,7 @@ func NewHttpRoute(props HttpRouteProps) *HttpRoute {
 			Methods:    methods,
 			Results:    []string{"200", "204", "400", "401", "403", "405", "409", "500", "error", "preprocessor"},
 			WindowSize: 64,
+			Labels:     props.Labels,
 		}),
 		encoder: props.Encoder,
 	}
@@ -186,6 +220,14 @@ func (h *HttpRoute) reportSuccess(
 
 	res.Header().Add(HttpHeaderTraceID, strconv.FormatInt(log.GetTraceID(req.Context()), 10))
 
+	if provider, ok := body.(HttpHeaderProvider); ok {
+		for key, values := range provider.HttpHeaders() {
+			for _, value := range values {
+				res.Header().Add(key, value)
+			}
+		}
+	}
+
 	if body == nil {
 		res.WriteHeader(http.StatusNoContent)
 		h.logger.Info(req.Context(), "", log.MapFields{
@@ -254,6 +296,7 @@ func (h *HttpRoute) serveHTTP(res http.ResponseWriter, req *http.Request) (int,
 		return h.reportError(res, req, &HttpError{StatusCode: http.StatusMethodNotAllowed})
 	}
 
+	req = req.WithContext(context.WithValue(req.Context(), responseHeaderKey{}, res.Header()))
 	v, err := handler.ServeHTTP(req)
 	if err != nil {
 		return h.reportAnyError(res, req, err)
@@ -281,12 +324,18 @@ func (h *HttpRoute) reportError(res http.ResponseWriter, req *http.Request, err
 	method := req.Method
 
 	res.Header().Add(HttpHeaderTraceID, strconv.FormatInt(log.GetTraceID(req.Context()), 10))
+	for key, values := range err.Header {
+		for _, value := range values {
+			res.Header().Add(key, value)
+		}
+	}
 	res.WriteHeader(err.StatusCode)
 
 	if err.Cause != nil {
 		if eerr := h.encoder.Encode(res, Error{
 			ErrorCode:   err.Cause.ErrorCode().Code(),
 			Description: err.Cause.ErrorCode().Desc(),
+			Details:     err.Details,
 		}); eerr != nil {
 
 			h.logger.Debug(req.Context(), "failed to encode error response to response writer", log.MapFields{
@@ -430,6 +479,11 @@ func mapHttpError(err errors.Error) *HttpError {
 			Cause:      &err,
 			StatusCode: http.StatusNotFound,
 		}
+	case errors.Unavailable:
+		return &HttpError{
+			Cause:      &err,
+			StatusCode: http.StatusServiceUnavailable,
+		}
 	default:
 		return &HttpError{
 			Cause:      &err,
@@ -746,6 +800,7 @@ func (b *HttpBinder) Build() *HttpRouter {
 			Encoder:       b.encoder,
 			Handlers:      handlers,
 			PreProcessors: b.preProcessors,
+			Labels:        stats.Labels{"api": path},
 		})
 
 		mux[path] = route
@@ -51,6 +51,26 @@ func (m HttpMiddlewareOK) ServeHTTP(req *http.Request) (interface{}, error) {
 	return m.body, nil
 }
 
+type headerBody struct {
+	Result string `json:"result"`
+}
+
+func (b headerBody) HttpHeaders() http.Header {
+	return http.Header{"Cache-Control": []string{"max-age=10"}}
+}
+
+type HttpMiddlewareResponseHeader struct {
+	err error
+}
+
+func (m HttpMiddlewareResponseHeader) ServeHTTP(req *http.Request) (interface{}, error) {
+	ResponseHeader(req.Context()).Set("X-Test", "value")
+	if m.err != nil {
+		return nil, m.err
+	}
+	return map[string]string{"result": "ok"}, nil
+}
+
 type HttpMiddlewarePanic struct{}
 
 func (m HttpMiddlewarePanic) ServeHTTP(req *http.Request) (interface{}, error) {
@@ -70,6 +90,13 @@ func setupRouter() *HttpRouter {
 			"GET": HttpMiddlewareOK{body: map[string]string{"result": "ok"}},
 			"PUT": HttpMiddlewareOK{body: nil},
 		},
+		"/headers": map[string]HttpMiddleware{
+			"GET": HttpMiddlewareOK{body: headerBody{Result: "ok"}},
+		},
+		"/responseHeader": map[string]HttpMiddleware{
+			"GET":  HttpMiddlewareResponseHeader{},
+			"POST": HttpMiddlewareResponseHeader{err: errors.New(errors.ErrInternalError, nil)},
+		},
 		"/panic": map[string]HttpMiddleware{
 			"GET": HttpMiddlewarePanic{},
 		},
@@ -151,6 +178,40 @@ func TestHttpRouterServeHTTPOKWithBody(t *testing.T) {
 	assert.Equal(t, "{\"result\":\"ok\"}\n", string(s))
 }
 
+func TestHttpRouterServeHTTPOKWithHeaders(t *testing.T) {
+	router := setupRouter()
+
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/headers", nil)
+
+	router.ServeHTTP(recorder, req)
+
+	s, err := ioutil.ReadAll(recorder.Body)
+
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "max-age=10", recorder.Header().Get("Cache-Control"))
+	assert.Equal(t, "{\"result\":\"ok\"}\n", string(s))
+}
+
+func TestHttpRouterServeHTTPResponseHeader(t *testing.T) {
+	router := setupRouter()
+
+	for method, code := range map[string]int{"GET": http.StatusOK, "POST": http.StatusInternalServerError} {
+		recorder := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, "/responseHeader", nil)
+
+		router.ServeHTTP(recorder, req)
+
+		assert.Equal(t, code, recorder.Code)
+		assert.Equal(t, "value", recorder.Header().Get("X-Test"))
+	}
+}
+
+func TestResponseHeaderNoRoute(t *testing.T) {
+	assert.Nil(t, ResponseHeader(context.Background()))
+}
+
 func TestHttpRouterServeHTTPPanic(t *testing.T) {
 	router := setupRouter()
 
@@ -0,0 +1,102 @@
+package slo
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+type Config struct {
+	// Default is the objective of the routes that do not have
+	// one defined in Routes. If 0 those routes have no objective
+	Default time.Duration
+
+	// Routes are the objectives of the routes by their path
+	Routes map[string]time.Duration
+
+	// Slow are the thresholds above which requests and
+	// transactions are logged with the time of their stages
+	Slow SlowProps
+}
+
+func (c *Config) Log(fields log.Fields) {
+	routes := make([]string, 0, len(c.Routes))
+	for path, objective := range c.Routes {
+		routes = append(routes, fmt.Sprintf("%s=%d", path, int64(objective/time.Millisecond)))
+	}
+	fields.Add("slo.default_ms", int64(c.Default/time.Millisecond))
+	fields.Add("slo.routes", strings.Join(routes, ","))
+	fields.Add("slo.slow_ms", int64(c.Slow.Total/time.Millisecond))
+	fields.Add("slo.slow_stage_ms", int64(c.Slow.Stage/time.Millisecond))
+}
+
+func (c *Config) Configure(v *viper.Viper) error {
+	def := v.GetInt64("slo.default_ms")
+	if def < 0 {
+		return errors.New("slo.default_ms cannot be negative")
+	}
+	c.Default = time.Duration(def) * time.Millisecond
+
+	c.Routes = make(map[string]time.Duration)
+	for _, route := range v.GetStringSlice("slo.routes") {
+		path, objective, err := ParseRoute(route)
+		if err != nil {
+			return err
+		}
+		c.Routes[path] = objective
+	}
+
+	slow := v.GetInt64("slo.slow_ms")
+	if slow < 0 {
+		return errors.New("slo.slow_ms cannot be negative")
+	}
+	c.Slow.Total = time.Duration(slow) * time.Millisecond
+
+	slowStage := v.GetInt64("slo.slow_stage_ms")
+	if slowStage < 0 {
+		return errors.New("slo.slow_stage_ms cannot be negative")
+	}
+	c.Slow.Stage = time.Duration(slowStage) * time.Millisecond
+
+	return nil
+}
+
+func (c *Config) Bind(v *viper.Viper, cmd *cobra.Command) error {
+	cmd.PersistentFlags().Int64("slo.default_ms", 0,
+		"latency objective in milliseconds of the routes of the public API that do not "+
+			"have one set in slo.routes. If 0 those routes have no objective.")
+	cmd.PersistentFlags().StringSlice("slo.routes", nil,
+		"latency objectives of routes of the public API of the form <path>=<ms>, "+
+			"e.g. /v0/api/service/execute=500. Requests slower than their objective are "+
+			"logged and counted as breaches.")
+	cmd.PersistentFlags().Int64("slo.slow_ms", 0,
+		"time in milliseconds above which requests to the public API and transactions are logged "+
+			"as slow along with the time taken by each of their stages. If 0 they are not logged "+
+			"for their total time.")
+	cmd.PersistentFlags().Int64("slo.slow_stage_ms", 0,
+		"time in milliseconds above which a single stage, like auth, estimate, sign, send or "+
+			"receipt, makes a request or transaction be logged as slow. If 0 they are not logged "+
+			"for the time of their stages.")
+	return nil
+}
+
+// ParseRoute parses the objective of a route of the form <path>=<ms>
+func ParseRoute(s string) (string, time.Duration, error) {
+	parts := strings.SplitN(s, "=", 2)
+	if len(parts) != 2 || len(parts[0]) == 0 {
+		return "", 0, fmt.Errorf("slo route %q must be of the form <path>=<ms>", s)
+	}
+
+	ms, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || ms <= 0 {
+		return "", 0, fmt.Errorf("slo route %q must have a positive objective in milliseconds", s)
+	}
+
+	return parts[0], time.Duration(ms) * time.Millisecond, nil
+}
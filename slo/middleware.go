@@ -0,0 +1,86 @@
+package slo
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/oasislabs/oasis-gateway/rpc"
+)
+
+// HttpMiddleware tags each request with the latency objective of its
+// route, measures the time taken to serve it and logs a warning
+// when the objective is breached. The breaches are logged with the
+// trace ID of the request so that alerts can be traced back. Requests
+// slower than the slow thresholds are logged along with the time
+// taken by each of the stages recorded while serving them
+type HttpMiddleware struct {
+	tracker *Tracker
+	logger  log.Logger
+	next    rpc.HttpMiddleware
+}
+
+// NewHttpMiddleware creates a new HttpMiddleware
+func NewHttpMiddleware(tracker *Tracker, logger log.Logger, next rpc.HttpMiddleware) *HttpMiddleware {
+	if logger == nil {
+		panic("log must be set")
+	}
+
+	if next == nil {
+		panic("next must be set")
+	}
+
+	return &HttpMiddleware{
+		tracker: tracker,
+		logger:  logger.ForClass("slo", "HttpMiddleware"),
+		next:    next,
+	}
+}
+
+// ServeHTTP is the implementation of rpc.HttpMiddleware for HttpMiddleware
+func (m *HttpMiddleware) ServeHTTP(req *http.Request) (interface{}, error) {
+	objective, ok := m.tracker.Objective(req.URL.Path)
+	slow := m.tracker.Slow()
+	if !ok && !slow.Enabled() {
+		return m.next.ServeHTTP(req)
+	}
+
+	ctx := req.Context()
+	if ok {
+		ctx = log.PutFields(ctx, map[string]string{
+			"slo_ms": strconv.FormatInt(int64(objective/time.Millisecond), 10),
+		})
+	}
+
+	var stages *Stages
+	if slow.Enabled() {
+		stages = NewStages()
+		ctx = WithStages(ctx, stages)
+	}
+	req = req.WithContext(ctx)
+
+	start := time.Now()
+	v, err := m.next.ServeHTTP(req)
+	latency := time.Since(start)
+
+	if stages.Slow(slow, latency) {
+		m.logger.Warn(ctx, "slow request", log.MapFields{
+			"call_type":  "SlowRequest",
+			"path":       req.URL.Path,
+			"latency_ms": int64(latency / time.Millisecond),
+			"failed":     err != nil,
+		}, stages)
+	}
+
+	if m.tracker.Observe(req.URL.Path, latency) {
+		m.logger.Warn(ctx, "request breached its latency objective", log.MapFields{
+			"call_type":  "SLOBreach",
+			"path":       req.URL.Path,
+			"latency_ms": int64(latency / time.Millisecond),
+			"failed":     err != nil,
+		})
+	}
+
+	return v, err
+}
@@ -0,0 +1,139 @@
+package slo
+
+import (
+	"time"
+
+	"github.com/oasislabs/oasis-gateway/stats"
+)
+
+// defaultRoute is the name under which the requests to routes
+// without an objective of their own are counted
+const defaultRoute = "default"
+
+// Props are the properties used to create a Tracker
+type Props struct {
+	// Default is the objective of the routes that do not have
+	// one defined in Routes. If 0 those routes have no objective
+	Default time.Duration
+
+	// Routes are the objectives of the routes by their path
+	Routes map[string]time.Duration
+
+	// Slow are the thresholds above which a request is logged
+	// with the time taken by each of its stages
+	Slow SlowProps
+}
+
+type routeStats struct {
+	requests stats.Counter
+	breaches stats.Counter
+}
+
+// Tracker keeps the latency objectives of the routes and counts
+// the requests that breach them.
+//
+// A nil Tracker is valid and no route has an objective
+type Tracker struct {
+	def    time.Duration
+	routes map[string]time.Duration
+	slow   SlowProps
+
+	// the counters are all allocated at creation time so that
+	// requests to unknown paths cannot grow the metrics
+	stats map[string]*routeStats
+}
+
+// NewTracker creates a new Tracker. If no objective nor slow
+// threshold is set it returns nil
+func NewTracker(props Props) *Tracker {
+	if props.Default <= 0 && len(props.Routes) == 0 && !props.Slow.Enabled() {
+		return nil
+	}
+
+	routes := make(map[string]time.Duration, len(props.Routes))
+	counters := make(map[string]*routeStats, len(props.Routes)+1)
+	for path, objective := range props.Routes {
+		routes[path] = objective
+		counters[path] = &routeStats{}
+	}
+	if props.Default > 0 {
+		counters[defaultRoute] = &routeStats{}
+	}
+
+	return &Tracker{def: props.Default, routes: routes, slow: props.Slow, stats: counters}
+}
+
+// Enabled returns true if any route has an objective or
+// slow requests are logged
+func (t *Tracker) Enabled() bool {
+	return t != nil
+}
+
+// Slow returns the thresholds above which a request is slow
+func (t *Tracker) Slow() SlowProps {
+	if t == nil {
+		return SlowProps{}
+	}
+
+	return t.slow
+}
+
+// Objective returns the latency objective of the route. It returns
+// false if the route has no objective
+func (t *Tracker) Objective(path string) (time.Duration, bool) {
+	if t == nil {
+		return 0, false
+	}
+
+	if objective, ok := t.routes[path]; ok {
+		return objective, true
+	}
+
+	return t.def, t.def > 0
+}
+
+// Observe records the latency of a request to the route and returns
+// true if the request breached the objective of the route
+func (t *Tracker) Observe(path string, latency time.Duration) bool {
+	objective, ok := t.Objective(path)
+	if !ok {
+		return false
+	}
+
+	counters, ok := t.stats[path]
+	if !ok {
+		counters = t.stats[defaultRoute]
+	}
+
+	counters.requests.Incr()
+	if latency <= objective {
+		return false
+	}
+
+	counters.breaches.Incr()
+	return true
+}
+
+// Stats is the implementation of stats.Collector for Tracker. It
+// reports the objective, requests and breaches of each route
+func (t *Tracker) Stats() stats.Metrics {
+	if t == nil {
+		return nil
+	}
+
+	metrics := make(stats.Metrics, len(t.stats))
+	for path, counters := range t.stats {
+		objective := t.def
+		if path != defaultRoute {
+			objective = t.routes[path]
+		}
+
+		metrics[path] = stats.Metrics{
+			"objective_ms": int64(objective / time.Millisecond),
+			"requests":     counters.requests.Value(),
+			"breaches":     counters.breaches.Value(),
+		}
+	}
+
+	return metrics
+}
@@ -0,0 +1,151 @@
+package slo
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/oasislabs/oasis-gateway/rpc"
+	"github.com/oasislabs/oasis-gateway/stats"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRoute(t *testing.T) {
+	path, objective, err := ParseRoute("/v0/api/service/execute=500")
+	assert.Nil(t, err)
+	assert.Equal(t, "/v0/api/service/execute", path)
+	assert.Equal(t, 500*time.Millisecond, objective)
+
+	for _, route := range []string{"/execute", "=500", "/execute=0", "/execute=fast"} {
+		_, _, err := ParseRoute(route)
+		assert.Error(t, err, route)
+	}
+}
+
+func TestTrackerNil(t *testing.T) {
+	tracker := NewTracker(Props{})
+	assert.Nil(t, tracker)
+	assert.False(t, tracker.Enabled())
+	assert.False(t, tracker.Observe("/execute", time.Hour))
+	assert.Nil(t, tracker.Stats())
+}
+
+func TestTrackerObserve(t *testing.T) {
+	tracker := NewTracker(Props{
+		Default: time.Second,
+		Routes:  map[string]time.Duration{"/execute": 100 * time.Millisecond},
+	})
+
+	assert.False(t, tracker.Observe("/execute", 50*time.Millisecond))
+	assert.True(t, tracker.Observe("/execute", 150*time.Millisecond))
+	assert.False(t, tracker.Observe("/poll", 150*time.Millisecond))
+	assert.True(t, tracker.Observe("/unknown", 2*time.Second))
+
+	assert.Equal(t, stats.Metrics{
+		"/execute": stats.Metrics{"objective_ms": int64(100), "requests": uint64(2), "breaches": uint64(1)},
+		"default":  stats.Metrics{"objective_ms": int64(1000), "requests": uint64(2), "breaches": uint64(1)},
+	}, tracker.Stats())
+}
+
+func TestTrackerNoDefault(t *testing.T) {
+	tracker := NewTracker(Props{
+		Routes: map[string]time.Duration{"/execute": 100 * time.Millisecond},
+	})
+
+	_, ok := tracker.Objective("/poll")
+	assert.False(t, ok)
+	assert.False(t, tracker.Observe("/poll", time.Hour))
+	assert.Len(t, tracker.Stats(), 1)
+}
+
+func TestHttpMiddlewareBreach(t *testing.T) {
+	buffer := bytes.NewBufferString("")
+	logger := log.NewLogrus(log.LogrusLoggerProperties{
+		Level:     logrus.InfoLevel,
+		Output:    buffer,
+		Formatter: &logrus.JSONFormatter{TimestampFormat: "none"},
+	})
+
+	tracker := NewTracker(Props{
+		Routes: map[string]time.Duration{"/execute": time.Millisecond},
+	})
+	handler := NewHttpMiddleware(tracker, logger,
+		rpc.HttpMiddlewareFunc(func(req *http.Request) (interface{}, error) {
+			assert.Equal(t, "1", log.GetFields(req.Context())["slo_ms"])
+			time.Sleep(5 * time.Millisecond)
+			return 0, nil
+		}))
+
+	req, err := http.NewRequest("POST", "/execute", nil)
+	assert.Nil(t, err)
+	req = req.WithContext(log.PutTraceID(req.Context(), int64(1234)))
+
+	res, err := handler.ServeHTTP(req)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, res)
+
+	var entry map[string]interface{}
+	assert.Nil(t, json.Unmarshal(buffer.Bytes(), &entry))
+	assert.Equal(t, "SLOBreach", entry["call_type"])
+	assert.Equal(t, "/execute", entry["path"])
+	assert.Equal(t, "1", entry["slo_ms"])
+	assert.Equal(t, float64(1234), entry["traceId"])
+	assert.Equal(t, false, entry["failed"])
+	assert.True(t, entry["latency_ms"].(float64) >= 5)
+}
+
+func TestHttpMiddlewareNoObjective(t *testing.T) {
+	buffer := bytes.NewBufferString("")
+	logger := log.NewLogrus(log.LogrusLoggerProperties{
+		Level:  logrus.InfoLevel,
+		Output: buffer,
+	})
+
+	handler := NewHttpMiddleware(nil, logger,
+		rpc.HttpMiddlewareFunc(func(req *http.Request) (interface{}, error) {
+			time.Sleep(time.Millisecond)
+			return 0, nil
+		}))
+
+	req, err := http.NewRequest("POST", "/execute", nil)
+	assert.Nil(t, err)
+
+	_, err = handler.ServeHTTP(req)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, buffer.Len())
+}
+
+func TestHttpMiddlewareSlow(t *testing.T) {
+	buffer := bytes.NewBufferString("")
+	logger := log.NewLogrus(log.LogrusLoggerProperties{
+		Level:     logrus.InfoLevel,
+		Output:    buffer,
+		Formatter: &logrus.JSONFormatter{TimestampFormat: "none"},
+	})
+
+	tracker := NewTracker(Props{Slow: SlowProps{Stage: time.Millisecond}})
+	handler := NewHttpMiddleware(tracker, logger,
+		rpc.HttpMiddlewareFunc(func(req *http.Request) (interface{}, error) {
+			GetStages(req.Context()).Observe("auth", time.Microsecond)
+			GetStages(req.Context()).Observe("send", 5*time.Millisecond)
+			return 0, nil
+		}))
+
+	req, err := http.NewRequest("POST", "/execute", nil)
+	assert.Nil(t, err)
+
+	_, err = handler.ServeHTTP(req)
+	assert.Nil(t, err)
+
+	var entry map[string]interface{}
+	assert.Nil(t, json.Unmarshal(buffer.Bytes(), &entry))
+	assert.Equal(t, "SlowRequest", entry["call_type"])
+	assert.Equal(t, "/execute", entry["path"])
+	assert.Equal(t, float64(0), entry["stage_auth_ms"])
+	assert.Equal(t, float64(5), entry["stage_send_ms"])
+	assert.Nil(t, entry["slo_ms"])
+}
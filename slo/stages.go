@@ -0,0 +1,129 @@
+package slo
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/oasislabs/oasis-gateway/log"
+)
+
+// SlowProps are the thresholds above which a request is logged
+// as slow along with the time taken by each of its stages
+type SlowProps struct {
+	// Total is the time above which a request is slow. If
+	// 0 the total time of a request is not checked
+	Total time.Duration
+
+	// Stage is the time above which a single stage of a request
+	// makes it slow. If 0 the stages are not checked
+	Stage time.Duration
+}
+
+// Enabled returns true if any threshold is set
+func (p SlowProps) Enabled() bool {
+	return p.Total > 0 || p.Stage > 0
+}
+
+type stagesKey struct{}
+
+// Stages records the time taken by each stage of a request, such as
+// auth, estimate, sign, send and receipt. The time of a stage that is
+// run more than once, like a transaction that is sent again, is the
+// sum of all its runs.
+//
+// A nil Stages is valid and records nothing, so that the stages
+// can be tracked whether or not slow requests are logged
+type Stages struct {
+	lock   sync.Mutex
+	start  time.Time
+	stages map[string]time.Duration
+}
+
+// NewStages creates a new Stages for a request that starts now
+func NewStages() *Stages {
+	return &Stages{start: time.Now(), stages: make(map[string]time.Duration)}
+}
+
+// WithStages attaches the stages to the context so that the
+// components that serve the request can record theirs
+func WithStages(ctx context.Context, stages *Stages) context.Context {
+	return context.WithValue(ctx, stagesKey{}, stages)
+}
+
+// GetStages returns the stages attached to the context or
+// nil if there are none
+func GetStages(ctx context.Context) *Stages {
+	stages, _ := ctx.Value(stagesKey{}).(*Stages)
+	return stages
+}
+
+// Observe adds the duration to the time of the stage
+func (s *Stages) Observe(name string, d time.Duration) {
+	if s == nil {
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.stages[name] += d
+}
+
+// Track starts timing a run of the stage. The returned function
+// must be called once the run completes
+func (s *Stages) Track(name string) func() {
+	if s == nil {
+		return func() {}
+	}
+
+	start := time.Now()
+	return func() { s.Observe(name, time.Since(start)) }
+}
+
+// Elapsed returns the time since the request started
+func (s *Stages) Elapsed() time.Duration {
+	if s == nil {
+		return 0
+	}
+
+	return time.Since(s.start)
+}
+
+// Slow returns true if the request took longer than the thresholds
+// either in total or in any of its stages
+func (s *Stages) Slow(props SlowProps, total time.Duration) bool {
+	if props.Total > 0 && total > props.Total {
+		return true
+	}
+
+	if s == nil || props.Stage <= 0 {
+		return false
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, d := range s.stages {
+		if d > props.Stage {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Log is the implementation of log.Loggable for Stages. Each stage
+// is logged in milliseconds as stage_<name>_ms
+func (s *Stages) Log(fields log.Fields) {
+	if s == nil {
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for name, d := range s.stages {
+		fields.Add("stage_"+name+"_ms", int64(d/time.Millisecond))
+	}
+}
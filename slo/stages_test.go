@@ -0,0 +1,56 @@
+package slo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type mapFields map[string]interface{}
+
+func (f mapFields) Add(key string, value interface{}) {
+	f[key] = value
+}
+
+func TestStagesNil(t *testing.T) {
+	var stages *Stages
+	stages.Observe("sign", time.Second)
+	stages.Track("sign")()
+
+	fields := mapFields{}
+	stages.Log(fields)
+	assert.Empty(t, fields)
+	assert.Nil(t, GetStages(context.Background()))
+	assert.False(t, stages.Slow(SlowProps{Stage: time.Millisecond}, 0))
+	assert.True(t, stages.Slow(SlowProps{Total: time.Millisecond}, time.Second))
+}
+
+func TestStagesSlow(t *testing.T) {
+	stages := NewStages()
+	stages.Observe("estimate", 10*time.Millisecond)
+	stages.Observe("send", 30*time.Millisecond)
+	stages.Observe("send", 30*time.Millisecond)
+
+	assert.False(t, stages.Slow(SlowProps{}, time.Hour))
+	assert.False(t, stages.Slow(SlowProps{Total: time.Second, Stage: 100 * time.Millisecond}, 500*time.Millisecond))
+	assert.True(t, stages.Slow(SlowProps{Total: time.Second}, 2*time.Second))
+	assert.True(t, stages.Slow(SlowProps{Stage: 50 * time.Millisecond}, 0))
+}
+
+func TestStagesLog(t *testing.T) {
+	stages := NewStages()
+	ctx := WithStages(context.Background(), stages)
+	assert.Equal(t, stages, GetStages(ctx))
+
+	GetStages(ctx).Observe("auth", 5*time.Millisecond)
+	GetStages(ctx).Observe("receipt", 1500*time.Microsecond)
+
+	fields := mapFields{}
+	stages.Log(fields)
+	assert.Equal(t, mapFields{
+		"stage_auth_ms":    int64(5),
+		"stage_receipt_ms": int64(1),
+	}, fields)
+}
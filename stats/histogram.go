@@ -0,0 +1,92 @@
+package stats
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultLatencyBuckets are the upper bounds in seconds of the buckets
+// of the latency histograms kept by a MethodTracker
+var DefaultLatencyBuckets = []float64{
+	0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10,
+}
+
+// Histogram counts the samples observed in buckets of increasing
+// upper bounds. Unlike an IntWindow it keeps all the samples observed
+// since it was created, so that the rate of samples of each bucket
+// can be derived by the systems the histogram is exported to
+type Histogram struct {
+	lock   sync.Mutex
+	bounds []float64
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// NewHistogram creates a new histogram with buckets of the provided
+// upper bounds, which must be sorted in increasing order
+func NewHistogram(bounds ...float64) *Histogram {
+	return &Histogram{
+		bounds: bounds,
+		counts: make([]uint64, len(bounds)),
+	}
+}
+
+// Observe adds a sample to the histogram
+func (h *Histogram) Observe(v float64) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	for i, bound := range h.bounds {
+		if v <= bound {
+			h.counts[i]++
+			break
+		}
+	}
+
+	h.count++
+	h.sum += v
+}
+
+// ObserveDuration adds a duration sample in seconds to the histogram
+func (h *Histogram) ObserveDuration(d time.Duration) {
+	h.Observe(d.Seconds())
+}
+
+// Snapshot returns the current state of the histogram
+func (h *Histogram) Snapshot() HistogramSnapshot {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	counts := make([]uint64, len(h.counts))
+	var cumulative uint64
+	for i, count := range h.counts {
+		cumulative += count
+		counts[i] = cumulative
+	}
+
+	return HistogramSnapshot{
+		Bounds: append([]float64(nil), h.bounds...),
+		Counts: counts,
+		Count:  h.count,
+		Sum:    h.sum,
+	}
+}
+
+// HistogramSnapshot is the state of a Histogram at a point in time.
+// It is the value of a histogram in Metrics. It is not numeric, so it
+// is not part of the Gauges of the Metrics
+type HistogramSnapshot struct {
+	// Bounds are the upper bounds of the buckets
+	Bounds []float64 `json:"bounds"`
+
+	// Counts are the number of samples lower or equal to the
+	// upper bound of each bucket
+	Counts []uint64 `json:"counts"`
+
+	// Count is the number of samples observed
+	Count uint64 `json:"count"`
+
+	// Sum is the sum of the samples observed
+	Sum float64 `json:"sum"`
+}
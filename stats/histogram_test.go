@@ -0,0 +1,35 @@
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistogramSnapshot(t *testing.T) {
+	h := NewHistogram(1, 5, 10)
+
+	h.Observe(0.5)
+	h.Observe(1)
+	h.Observe(7)
+	h.Observe(20)
+
+	assert.Equal(t, HistogramSnapshot{
+		Bounds: []float64{1, 5, 10},
+		Counts: []uint64{2, 2, 3},
+		Count:  4,
+		Sum:    28.5,
+	}, h.Snapshot())
+}
+
+func TestHistogramObserveDuration(t *testing.T) {
+	h := NewHistogram(DefaultLatencyBuckets...)
+
+	h.ObserveDuration(30 * time.Millisecond)
+
+	snapshot := h.Snapshot()
+	assert.Equal(t, uint64(0), snapshot.Counts[2])
+	assert.Equal(t, uint64(1), snapshot.Counts[3])
+	assert.InDelta(t, 0.03, snapshot.Sum, 1e-9)
+}
@@ -36,10 +36,13 @@ type TrackResult struct {
 // track is defined at initialization time to avoid concurrency
 // issues with the implementation, so it favours immutability.
 // If an unexpected method is tracked the result is stored in
-// the special "undefined" category.
+// the special "undefined" category. Methods can be tracked
+// concurrently from multiple goroutines.
 type MethodTracker struct {
-	count     map[string]*CounterGroup
-	latencies map[string]*IntWindow
+	labels     Labels
+	count      map[string]*CounterGroup
+	latencies  map[string]*IntWindow
+	histograms map[string]*Histogram
 }
 
 // MethodTrackerProps are the properties used to define
@@ -48,6 +51,10 @@ type MethodTrackerProps struct {
 	Methods    []string
 	Results    []string
 	WindowSize uint32
+
+	// Labels if set are attached to the metrics of each method
+	// along with the method label
+	Labels Labels
 }
 
 // NewMethodTrackerWithResult creates a new MethodTracker with
@@ -55,21 +62,38 @@ type MethodTrackerProps struct {
 func NewMethodTrackerWithResult(props *MethodTrackerProps) *MethodTracker {
 	count := make(map[string]*CounterGroup)
 	latencies := make(map[string]*IntWindow)
+	histograms := make(map[string]*Histogram)
 
 	for _, key := range props.Methods {
 		count[key] = NewCounterGroup(props.Results...)
 		latencies[key] = NewIntWindow(props.WindowSize)
+		histograms[key] = NewHistogram(DefaultLatencyBuckets...)
 	}
 
 	count["undefined"] = NewCounterGroup(props.Results...)
 	latencies["undefined"] = NewIntWindow(props.WindowSize)
+	histograms["undefined"] = NewHistogram(DefaultLatencyBuckets...)
 
 	return &MethodTracker{
-		count:     count,
-		latencies: latencies,
+		labels:     props.Labels,
+		count:      count,
+		latencies:  latencies,
+		histograms: histograms,
 	}
 }
 
+// NewLabeledMethodTracker creates a new method tracker using the
+// defaults ResultTypeBool for Result types whose metrics carry
+// the provided labels
+func NewLabeledMethodTracker(labels Labels, methods ...string) *MethodTracker {
+	return NewMethodTrackerWithResult(&MethodTrackerProps{
+		Methods:    methods,
+		Results:    []string{"ok", "error"},
+		WindowSize: 64,
+		Labels:     labels,
+	})
+}
+
 // NewMethodTracker creates a new method tracker using the defaults
 // ResultTypeBool for Result types.
 func NewMethodTracker(methods ...string) *MethodTracker {
@@ -148,24 +172,35 @@ func (t *MethodTracker) AddCount(name string, result string) {
 }
 
 // StoreLatency is a method to manually store a new latency
-// sample for a method
+// sample in nanoseconds for a method
 func (t *MethodTracker) StoreLatency(name string, latency int64) {
 	l, ok := t.latencies[name]
 	if !ok {
 		l = t.latencies["undefined"]
+		name = "undefined"
 	}
 
 	l.Add(latency)
+	t.histograms[name].ObserveDuration(time.Duration(latency))
 }
 
-// Stats is the implementation of Collector for MethodTracker
+// Stats is the implementation of Collector for MethodTracker. The
+// latency of each method has the average latency in nanoseconds of
+// the latest calls and the histogram of the latencies in seconds
+// of all the calls
 func (t *MethodTracker) Stats() Metrics {
 	stats := make(Metrics)
 
 	for method, count := range t.count {
+		latency := t.latencies[method].Stats()
+		latency["seconds"] = t.histograms[method].Snapshot()
+
 		methodStats := make(Metrics)
 		methodStats["count"] = count.Stats()
-		methodStats["latency"] = t.latencies[method].Stats()
+		methodStats["latency"] = latency
+		if len(t.labels) > 0 {
+			methodStats.WithLabels(t.labels.Merge(Labels{"method": method}))
+		}
 		stats[method] = methodStats
 	}
 
@@ -162,3 +162,21 @@ func TestMethodTrackerWithResult(t *testing.T) {
 	assert.Equal(t, float64(0),
 		stats["undefined"].(Metrics)["latency"].(Metrics)["avg"].(float64))
 }
+
+func TestMethodTrackerWithLabels(t *testing.T) {
+	tracker := NewLabeledMethodTracker(Labels{"backend": "eth"}, "method1")
+
+	stats := tracker.Stats()
+	assert.Equal(t, Labels{"backend": "eth", "method": "method1"},
+		stats["method1"].(Metrics).Labels())
+	assert.Equal(t, Labels{"backend": "eth", "method": "undefined"},
+		stats["undefined"].(Metrics).Labels())
+}
+
+func TestMethodTrackerNoLabels(t *testing.T) {
+	tracker := NewMethodTracker("method1")
+
+	stats := tracker.Stats()
+	_, ok := stats["method1"].(Metrics)[LabelsKey]
+	assert.False(t, ok)
+}
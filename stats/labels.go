@@ -0,0 +1,64 @@
+package stats
+
+import (
+	"sort"
+	"strings"
+)
+
+// LabelsKey is the key under which a Metrics instance stores
+// the labels attached to it
+const LabelsKey = "labels"
+
+// Labels are the dimensions that identify a series of metrics,
+// such as the backend, the wallet address or the API that
+// generated them
+type Labels map[string]string
+
+// Merge returns a new set of labels with the labels of l and
+// other. If both define the same label the value of other is used
+func (l Labels) Merge(other Labels) Labels {
+	labels := make(Labels, len(l)+len(other))
+	for key, value := range l {
+		labels[key] = value
+	}
+	for key, value := range other {
+		labels[key] = value
+	}
+	return labels
+}
+
+// String returns a canonical representation of the labels
+// sorted by label name
+func (l Labels) String() string {
+	keys := make([]string, 0, len(l))
+	for key := range l {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, key+"="+l[key])
+	}
+	return strings.Join(pairs, ",")
+}
+
+// WithLabels attaches labels to the metrics. Labels already
+// attached to the metrics are kept unless labels overwrites them
+func (m Metrics) WithLabels(labels Labels) Metrics {
+	if len(labels) == 0 {
+		return m
+	}
+
+	m[LabelsKey] = m.Labels().Merge(labels)
+	return m
+}
+
+// Labels returns the labels attached to the metrics
+func (m Metrics) Labels() Labels {
+	labels, ok := m[LabelsKey].(Labels)
+	if !ok {
+		return Labels{}
+	}
+	return labels
+}
@@ -0,0 +1,45 @@
+package stats
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLabelsMerge(t *testing.T) {
+	labels := Labels{"backend": "eth", "method": "a"}
+
+	merged := labels.Merge(Labels{"method": "b", "wallet": "0x01"})
+
+	assert.Equal(t, Labels{"backend": "eth", "method": "b", "wallet": "0x01"}, merged)
+	assert.Equal(t, Labels{"backend": "eth", "method": "a"}, labels)
+}
+
+func TestLabelsString(t *testing.T) {
+	labels := Labels{"wallet": "0x01", "backend": "eth"}
+
+	assert.Equal(t, "backend=eth,wallet=0x01", labels.String())
+}
+
+func TestLabelsStringEmpty(t *testing.T) {
+	assert.Equal(t, "", Labels{}.String())
+}
+
+func TestMetricsWithLabels(t *testing.T) {
+	metrics := Metrics{"count": uint64(1)}
+
+	metrics.WithLabels(Labels{"backend": "eth"}).WithLabels(Labels{"method": "a"})
+
+	assert.Equal(t, Labels{"backend": "eth", "method": "a"}, metrics.Labels())
+	assert.Equal(t, uint64(1), metrics["count"])
+}
+
+func TestMetricsWithLabelsEmpty(t *testing.T) {
+	metrics := Metrics{}
+
+	metrics.WithLabels(Labels{})
+
+	_, ok := metrics[LabelsKey]
+	assert.False(t, ok)
+	assert.Equal(t, Labels{}, metrics.Labels())
+}
@@ -0,0 +1,136 @@
+package stats
+
+import (
+	"context"
+	"time"
+
+	"github.com/oasislabs/oasis-gateway/log"
+)
+
+// LeakDetectorServices are the services required by the LeakDetector
+type LeakDetectorServices struct {
+	Logger    log.Logger
+	Collector Collector
+}
+
+// LeakDetectorProps define the behaviour of the LeakDetector
+type LeakDetectorProps struct {
+	// Gauges are the paths of the gauges of the collector that
+	// are watched. Paths that the collector does not report
+	// are ignored
+	Gauges []string
+
+	// Interval is the time between two checks of the gauges
+	Interval time.Duration
+
+	// MinGrowths is the number of consecutive checks in which a
+	// gauge has to grow before it is reported
+	MinGrowths int
+}
+
+// LeakDetector periodically checks a set of gauges and logs a warning
+// when one of them grows monotonically for a number of checks, which
+// usually means that the resources it counts are never released
+type LeakDetector struct {
+	logger     log.Logger
+	collector  Collector
+	gauges     []string
+	interval   time.Duration
+	minGrowths int
+	counters   *CounterGroup
+
+	// last, base and growths are only accessed by the loop
+	// of the detector. last holds the value of each gauge in the
+	// previous check, base the value of the gauge before it started
+	// growing and growths the number of consecutive checks in
+	// which it grew
+	last    Gauges
+	base    Gauges
+	growths map[string]int
+}
+
+// NewLeakDetector creates a new LeakDetector
+func NewLeakDetector(services LeakDetectorServices, props LeakDetectorProps) *LeakDetector {
+	minGrowths := props.MinGrowths
+	if minGrowths <= 0 {
+		minGrowths = 1
+	}
+
+	return &LeakDetector{
+		logger:     services.Logger.ForClass("stats", "LeakDetector"),
+		collector:  services.Collector,
+		gauges:     props.Gauges,
+		interval:   props.Interval,
+		minGrowths: minGrowths,
+		counters:   NewCounterGroup("checks", "warnings"),
+		base:       make(Gauges),
+		growths:    make(map[string]int),
+	}
+}
+
+// Name is the implementation of Collector.Name for LeakDetector
+func (d *LeakDetector) Name() string {
+	return "stats.LeakDetector"
+}
+
+// Stats is the implementation of Collector.Stats for LeakDetector
+func (d *LeakDetector) Stats() Metrics {
+	return d.counters.Stats()
+}
+
+// Start checks the gauges periodically until the
+// context is cancelled
+func (d *LeakDetector) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(d.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				d.Check(ctx)
+			}
+		}
+	}()
+}
+
+// Check compares the current value of the gauges with the value of
+// the previous check. A warning is logged for each gauge that has grown
+// in every one of the last MinGrowths checks. It is not safe to call
+// Check concurrently
+func (d *LeakDetector) Check(ctx context.Context) {
+	d.counters.Incr("checks")
+	current := Flatten(d.collector.Stats())
+
+	for _, path := range d.gauges {
+		v, ok := current[path]
+		if !ok {
+			continue
+		}
+
+		last, ok := d.last[path]
+		if !ok || v <= last {
+			d.base[path] = v
+			d.growths[path] = 0
+			continue
+		}
+
+		d.growths[path]++
+		if d.growths[path]%d.minGrowths != 0 {
+			continue
+		}
+
+		d.counters.Incr("warnings")
+		d.logger.Warn(ctx, "gauge grew monotonically, resources may be leaking", log.MapFields{
+			"call_type": "GaugeGrowthDetected",
+			"gauge":     path,
+			"base":      d.base[path],
+			"value":     v,
+			"checks":    d.growths[path],
+		})
+	}
+
+	d.last = current
+}
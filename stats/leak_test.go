@@ -0,0 +1,59 @@
+package stats
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/stretchr/testify/assert"
+)
+
+type gaugeCollector struct {
+	value int
+}
+
+func (c *gaugeCollector) Stats() Metrics {
+	return Metrics{"workers": Metrics{"active": c.value}}
+}
+
+func newLeakDetector(collector Collector) *LeakDetector {
+	return NewLeakDetector(LeakDetectorServices{
+		Logger: log.NewLogrus(log.LogrusLoggerProperties{
+			Output: ioutil.Discard,
+		}),
+		Collector: collector,
+	}, LeakDetectorProps{
+		Gauges:     []string{"workers.active", "missing"},
+		MinGrowths: 2,
+	})
+}
+
+func TestLeakDetectorMonotonicGrowth(t *testing.T) {
+	ctx := context.Background()
+	collector := &gaugeCollector{}
+	detector := newLeakDetector(collector)
+
+	for i := 0; i < 5; i++ {
+		collector.value = i
+		detector.Check(ctx)
+	}
+
+	// the gauge grew in 4 checks, which is reported
+	// after the second and the fourth
+	assert.Equal(t, uint64(5), detector.Stats()["checks"])
+	assert.Equal(t, uint64(2), detector.Stats()["warnings"])
+}
+
+func TestLeakDetectorGrowthReset(t *testing.T) {
+	ctx := context.Background()
+	collector := &gaugeCollector{}
+	detector := newLeakDetector(collector)
+
+	for _, v := range []int{1, 2, 1, 2, 2, 3} {
+		collector.value = v
+		detector.Check(ctx)
+	}
+
+	assert.Equal(t, uint64(0), detector.Stats()["warnings"])
+}
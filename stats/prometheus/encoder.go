@@ -0,0 +1,289 @@
+package prometheus
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/oasislabs/oasis-gateway/stats"
+)
+
+// ContentType is the content type of the text exposition format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefaultPrefix is the prefix of the metrics of the gateway
+const DefaultPrefix = "oasis_gateway"
+
+// Type is the type of a metric family
+type Type string
+
+const (
+	TypeCounter   Type = "counter"
+	TypeGauge     Type = "gauge"
+	TypeHistogram Type = "histogram"
+)
+
+// sample is a single value of a metric family
+type sample struct {
+	suffix string
+	labels stats.Labels
+	value  float64
+}
+
+// family are the samples of the metrics with the same name
+type family struct {
+	name    string
+	t       Type
+	samples []sample
+}
+
+// Encoder encodes metrics in the Prometheus text exposition format.
+// The metrics are named after their path joined by underscores, and
+// carry the labels attached to them and their ancestors. A metric
+// that attaches a label whose value is its own name, like the methods
+// of a labeled stats.MethodTracker, is identified by the label rather
+// than by its name, so that all the methods are samples of the same
+// family. The counts of a stats.MethodTracker are exported as
+// counters named calls_total with the result as a label, histograms
+// as histograms and all the other numeric values as gauges
+type Encoder struct {
+	// Prefix if set is prepended to the name of every metric
+	Prefix string
+}
+
+// Encode writes the metrics to the writer
+func (e Encoder) Encode(w io.Writer, metrics stats.Metrics) error {
+	families := make(map[string]*family)
+
+	var path []string
+	if len(e.Prefix) > 0 {
+		path = append(path, e.Prefix)
+	}
+	collect(families, path, stats.Labels{}, metrics)
+
+	names := make([]string, 0, len(families))
+	for name := range families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	buf := bufio.NewWriter(w)
+	for _, name := range names {
+		writeFamily(buf, families[name])
+	}
+
+	return buf.Flush()
+}
+
+func collect(families map[string]*family, path []string, labels stats.Labels, v interface{}) {
+	switch v := v.(type) {
+	case stats.Metrics:
+		labels = labels.Merge(v.Labels())
+		for name, child := range v {
+			if name == stats.LabelsKey {
+				continue
+			}
+			collectChild(families, path, labels, name, child)
+		}
+	case map[string]interface{}:
+		for name, child := range v {
+			collectChild(families, path, labels, name, child)
+		}
+	case stats.HistogramSnapshot:
+		add(families, path, TypeHistogram, histogramSamples(labels, v)...)
+	default:
+		f, ok := toFloat(v)
+		if !ok {
+			return
+		}
+
+		if isCount(path) {
+			// the counts of a MethodTracker by result
+			path = append(path[:len(path)-1:len(path)-1], "calls_total")
+			add(families, path, TypeCounter, sample{labels: labels, value: f})
+			return
+		}
+
+		add(families, path, TypeGauge, sample{labels: labels, value: f})
+	}
+}
+
+func collectChild(families map[string]*family, path []string, labels stats.Labels, name string, child interface{}) {
+	if isCount(path) {
+		// the leaves of a count are the results, which are
+		// carried as a label of the counter
+		collect(families, path, labels.Merge(stats.Labels{"result": name}), child)
+		return
+	}
+
+	if metrics, ok := child.(stats.Metrics); ok {
+		for key, value := range metrics.Labels() {
+			if value == name && labels[key] != value {
+				collect(families, path, labels, child)
+				return
+			}
+		}
+	}
+
+	collect(families, append(path[:len(path):len(path)], name), labels, child)
+}
+
+func isCount(path []string) bool {
+	return len(path) > 1 && path[len(path)-1] == "count"
+}
+
+func add(families map[string]*family, path []string, t Type, samples ...sample) {
+	name := metricName(path)
+	f, ok := families[name]
+	if !ok {
+		f = &family{name: name, t: t}
+		families[name] = f
+	}
+
+	// a metric of another type with the same name
+	// cannot be part of the family
+	if f.t != t {
+		return
+	}
+
+	f.samples = append(f.samples, samples...)
+}
+
+func histogramSamples(labels stats.Labels, h stats.HistogramSnapshot) []sample {
+	samples := make([]sample, 0, len(h.Bounds)+3)
+	for i, bound := range h.Bounds {
+		samples = append(samples, sample{
+			suffix: "_bucket",
+			labels: labels.Merge(stats.Labels{"le": formatFloat(bound)}),
+			value:  float64(h.Counts[i]),
+		})
+	}
+
+	return append(samples,
+		sample{suffix: "_bucket", labels: labels.Merge(stats.Labels{"le": "+Inf"}), value: float64(h.Count)},
+		sample{suffix: "_sum", labels: labels, value: h.Sum},
+		sample{suffix: "_count", labels: labels, value: float64(h.Count)})
+}
+
+func writeFamily(w *bufio.Writer, f *family) {
+	// samples are sorted by their labels so that the output is
+	// stable, and the samples of a histogram are kept together
+	sort.SliceStable(f.samples, func(i, j int) bool {
+		return labelsKey(f.samples[i].labels) < labelsKey(f.samples[j].labels)
+	})
+
+	_, _ = w.WriteString("# TYPE " + f.name + " " + string(f.t) + "\n")
+
+	written := make(map[string]bool, len(f.samples))
+	for _, s := range f.samples {
+		line := f.name + s.suffix + formatLabels(s.labels)
+		if written[line] {
+			continue
+		}
+		written[line] = true
+
+		_, _ = w.WriteString(line + " " + formatFloat(s.value) + "\n")
+	}
+}
+
+// labelsKey is the key by which the samples are sorted, which
+// ignores the le label so that the buckets keep their order
+func labelsKey(labels stats.Labels) string {
+	if _, ok := labels["le"]; !ok {
+		return labels.String()
+	}
+
+	rest := make(stats.Labels, len(labels))
+	for key, value := range labels {
+		if key != "le" {
+			rest[key] = value
+		}
+	}
+	return rest.String()
+}
+
+func formatLabels(labels stats.Labels) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, sanitize(key)+"=\""+escape(labels[key])+"\"")
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func metricName(path []string) string {
+	return sanitize(strings.Join(path, "_"))
+}
+
+// sanitize replaces the characters that are not allowed in a name
+func sanitize(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_':
+			b.WriteRune(r)
+		case r >= '0' && r <= '9' && i > 0:
+			b.WriteRune(r)
+		default:
+			b.WriteRune('_')
+		}
+	}
+	return b.String()
+}
+
+func escape(value string) string {
+	return strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "\n", "\\n").Replace(value)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,66 @@
+package prometheus
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/oasislabs/oasis-gateway/stats"
+	"github.com/stretchr/testify/assert"
+)
+
+func encode(t *testing.T, metrics stats.Metrics) string {
+	var buf bytes.Buffer
+	assert.Nil(t, Encoder{Prefix: "oasis_gateway"}.Encode(&buf, metrics))
+	return buf.String()
+}
+
+func TestEncodeGauges(t *testing.T) {
+	out := encode(t, stats.Metrics{
+		"runtime": stats.Metrics{"NumGoroutine": 12},
+		"tx.Executor": stats.Metrics{
+			"pending": int64(2),
+			"drained": true,
+			"wallet":  "0x01",
+		},
+	})
+
+	assert.Equal(t, "# TYPE oasis_gateway_runtime_NumGoroutine gauge\n"+
+		"oasis_gateway_runtime_NumGoroutine 12\n"+
+		"# TYPE oasis_gateway_tx_Executor_drained gauge\n"+
+		"oasis_gateway_tx_Executor_drained 1\n"+
+		"# TYPE oasis_gateway_tx_Executor_pending gauge\n"+
+		"oasis_gateway_tx_Executor_pending 2\n", out)
+}
+
+func TestEncodeMethodTracker(t *testing.T) {
+	tracker := stats.NewLabeledMethodTracker(stats.Labels{"mqueue": "redis"}, "insert", "retrieve")
+	tracker.AddCount("insert", "ok")
+	tracker.AddCount("insert", "error")
+	tracker.AddCount("retrieve", "ok")
+	tracker.StoreLatency("insert", 20000000)
+
+	out := encode(t, stats.Metrics{"mqueue": tracker.Stats()})
+
+	assert.Contains(t, out, "# TYPE oasis_gateway_mqueue_calls_total counter\n")
+	assert.Contains(t, out, "oasis_gateway_mqueue_calls_total{method=\"insert\",mqueue=\"redis\",result=\"error\"} 1\n")
+	assert.Contains(t, out, "oasis_gateway_mqueue_calls_total{method=\"insert\",mqueue=\"redis\",result=\"ok\"} 1\n")
+	assert.Contains(t, out, "oasis_gateway_mqueue_calls_total{method=\"retrieve\",mqueue=\"redis\",result=\"ok\"} 1\n")
+
+	assert.Contains(t, out, "# TYPE oasis_gateway_mqueue_latency_seconds histogram\n"+
+		"oasis_gateway_mqueue_latency_seconds_bucket{le=\"0.005\",method=\"insert\",mqueue=\"redis\"} 0\n"+
+		"oasis_gateway_mqueue_latency_seconds_bucket{le=\"0.01\",method=\"insert\",mqueue=\"redis\"} 0\n"+
+		"oasis_gateway_mqueue_latency_seconds_bucket{le=\"0.025\",method=\"insert\",mqueue=\"redis\"} 1\n")
+	assert.Contains(t, out, "oasis_gateway_mqueue_latency_seconds_bucket{le=\"+Inf\",method=\"insert\",mqueue=\"redis\"} 1\n"+
+		"oasis_gateway_mqueue_latency_seconds_sum{method=\"insert\",mqueue=\"redis\"} 0.02\n"+
+		"oasis_gateway_mqueue_latency_seconds_count{method=\"insert\",mqueue=\"redis\"} 1\n")
+	assert.Contains(t, out, "# TYPE oasis_gateway_mqueue_latency_avg gauge\n")
+}
+
+func TestEncodeLabelEscaping(t *testing.T) {
+	out := encode(t, stats.Metrics{
+		"requests": stats.Metrics{"total": 3}.WithLabels(stats.Labels{"api": "a\"b\\c"}),
+	})
+
+	assert.Equal(t, "# TYPE oasis_gateway_requests_total gauge\n"+
+		"oasis_gateway_requests_total{api=\"a\\\"b\\\\c\"} 3\n", out)
+}
@@ -0,0 +1,111 @@
+package stats
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Registry keeps track of the collectors of all the subsystems
+// so that their metrics can be exposed from a single place. Subsystems
+// register their collector when they are constructed.
+//
+// A nil Registry is valid and discards all the registrations, so
+// that subsystems can be constructed without one
+type Registry struct {
+	lock       sync.RWMutex
+	collectors map[string]Collector
+}
+
+// NewRegistry creates a new empty registry
+func NewRegistry() *Registry {
+	return &Registry{collectors: make(map[string]Collector)}
+}
+
+// Register adds the collector to the registry under the provided
+// name. It fails if a collector is already registered with that
+// name, since names are expected to identify a single subsystem
+func (r *Registry) Register(name string, collector Collector) error {
+	if r == nil {
+		return nil
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if _, ok := r.collectors[name]; ok {
+		return fmt.Errorf("registry already contains collector %s", name)
+	}
+
+	r.collectors[name] = collector
+	return nil
+}
+
+// Unregister removes the collector registered with that name
+// if present
+func (r *Registry) Unregister(name string) {
+	if r == nil {
+		return
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	delete(r.collectors, name)
+}
+
+// Get returns the collector registered with that name if found
+func (r *Registry) Get(name string) (Collector, bool) {
+	if r == nil {
+		return nil, false
+	}
+
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	collector, ok := r.collectors[name]
+	return collector, ok
+}
+
+// Names returns the sorted names of the registered collectors
+func (r *Registry) Names() []string {
+	if r == nil {
+		return nil
+	}
+
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	names := make([]string, 0, len(r.collectors))
+	for name := range r.collectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Stats is the implementation of Collector for Registry. It
+// returns the metrics of each registered collector by name
+func (r *Registry) Stats() Metrics {
+	group := make(Metrics)
+	if r == nil {
+		return group
+	}
+
+	r.lock.RLock()
+	collectors := make(map[string]Collector, len(r.collectors))
+	for name, collector := range r.collectors {
+		collectors[name] = collector
+	}
+	r.lock.RUnlock()
+
+	// collectors are queried without holding the lock so that
+	// a slow collector does not block registrations
+	for name, collector := range collectors {
+		if s := collector.Stats(); s != nil {
+			group[name] = s
+		}
+	}
+
+	return group
+}
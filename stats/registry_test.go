@@ -0,0 +1,66 @@
+package stats
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type staticCollector Metrics
+
+func (c staticCollector) Stats() Metrics {
+	return Metrics(c)
+}
+
+func TestRegistryRegister(t *testing.T) {
+	registry := NewRegistry()
+
+	registry.Register("b", staticCollector{"count": 1})
+	registry.Register("a", staticCollector{"count": 2})
+
+	assert.Equal(t, []string{"a", "b"}, registry.Names())
+	collector, ok := registry.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, Metrics{"count": 2}, collector.Stats())
+}
+
+func TestRegistryRegisterDuplicate(t *testing.T) {
+	registry := NewRegistry()
+	assert.Nil(t, registry.Register("a", staticCollector{}))
+
+	err := registry.Register("a", staticCollector{})
+	assert.Error(t, err)
+	assert.Equal(t, "registry already contains collector a", err.Error())
+}
+
+func TestRegistryUnregister(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register("a", staticCollector{})
+
+	registry.Unregister("a")
+
+	_, ok := registry.Get("a")
+	assert.False(t, ok)
+	assert.Equal(t, []string{}, registry.Names())
+}
+
+func TestRegistryStats(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register("a", staticCollector{"count": 1})
+	registry.Register("b", staticCollector(nil))
+
+	assert.Equal(t, Metrics{
+		"a": Metrics{"count": 1},
+	}, registry.Stats())
+}
+
+func TestRegistryNil(t *testing.T) {
+	var registry *Registry
+
+	registry.Register("a", staticCollector{})
+
+	_, ok := registry.Get("a")
+	assert.False(t, ok)
+	assert.Nil(t, registry.Names())
+	assert.Equal(t, Metrics{}, registry.Stats())
+}
@@ -0,0 +1,166 @@
+package stats
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultMaxSnapshots is the number of snapshots kept by a
+// Snapshotter if not provided
+const DefaultMaxSnapshots = 16
+
+// Gauges are the numeric values of a set of metrics by their path. The
+// path of a value is the names of the nested metrics that contain it
+// joined by dots
+type Gauges map[string]float64
+
+// Flatten returns the numeric values of the metrics by their path. The
+// values that are not numeric are ignored
+func Flatten(metrics Metrics) Gauges {
+	gauges := make(Gauges)
+	for name, v := range metrics {
+		flatten(name, v, gauges)
+	}
+
+	return gauges
+}
+
+func flatten(path string, v interface{}, gauges Gauges) {
+	switch v := v.(type) {
+	case Metrics:
+		for name, e := range v {
+			flatten(path+"."+name, e, gauges)
+		}
+	case map[string]interface{}:
+		for name, e := range v {
+			flatten(path+"."+name, e, gauges)
+		}
+	default:
+		if f, ok := toFloat(v); ok {
+			gauges[path] = f
+		}
+	}
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
+
+// Diff returns the change of each gauge from the first set of gauges
+// to the second. A gauge missing from one of the sets is considered
+// to be 0 in that set
+func Diff(from, to Gauges) Gauges {
+	deltas := make(Gauges, len(to))
+	for path, v := range to {
+		deltas[path] = v - from[path]
+	}
+
+	for path, v := range from {
+		if _, ok := to[path]; !ok {
+			deltas[path] = -v
+		}
+	}
+
+	return deltas
+}
+
+// Snapshot is the state of the gauges of a collector at a point in time
+type Snapshot struct {
+	// ID identifies the snapshot within the Snapshotter that took it
+	ID uint64
+
+	// Time is when the snapshot was taken
+	Time time.Time
+
+	// Gauges are the numeric metrics of the collector
+	Gauges Gauges
+}
+
+// SnapshotterProps are the properties used to create a Snapshotter
+type SnapshotterProps struct {
+	// MaxSnapshots is the number of most recent snapshots kept so
+	// that they can be compared with newer ones. If 0 the
+	// DefaultMaxSnapshots is used
+	MaxSnapshots int
+}
+
+// Snapshotter takes snapshots of the gauges of a collector and keeps
+// the most recent ones, so that the growth of the gauges between two
+// snapshots can be inspected
+type Snapshotter struct {
+	collector    Collector
+	maxSnapshots int
+
+	lock      sync.Mutex
+	nextID    uint64
+	snapshots []Snapshot
+}
+
+// NewSnapshotter creates a new Snapshotter for the collector
+func NewSnapshotter(collector Collector, props SnapshotterProps) *Snapshotter {
+	maxSnapshots := props.MaxSnapshots
+	if maxSnapshots <= 0 {
+		maxSnapshots = DefaultMaxSnapshots
+	}
+
+	return &Snapshotter{
+		collector:    collector,
+		maxSnapshots: maxSnapshots,
+		nextID:       1,
+	}
+}
+
+// Take takes a new snapshot of the gauges of the collector. The oldest
+// snapshot is dropped if the Snapshotter already keeps the maximum
+func (s *Snapshotter) Take() Snapshot {
+	// the collector is queried without holding the lock
+	// since collecting the metrics may be slow
+	gauges := Flatten(s.collector.Stats())
+	now := time.Now()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	snapshot := Snapshot{ID: s.nextID, Time: now, Gauges: gauges}
+	s.nextID++
+
+	if len(s.snapshots) >= s.maxSnapshots {
+		s.snapshots = s.snapshots[1:]
+	}
+	s.snapshots = append(s.snapshots, snapshot)
+
+	return snapshot
+}
+
+// Get returns the snapshot with the ID if it is still kept
+func (s *Snapshotter) Get(id uint64) (Snapshot, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, snapshot := range s.snapshots {
+		if snapshot.ID == id {
+			return snapshot, true
+		}
+	}
+
+	return Snapshot{}, false
+}
@@ -0,0 +1,58 @@
+package stats
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlatten(t *testing.T) {
+	gauges := Flatten(Metrics{
+		"runtime": Metrics{"goroutines": 10},
+		"queue": map[string]interface{}{
+			"size":  uint64(3),
+			"state": "running",
+		},
+		"ratio": 0.5,
+	})
+
+	assert.Equal(t, Gauges{
+		"runtime.goroutines": 10,
+		"queue.size":         3,
+		"ratio":              0.5,
+	}, gauges)
+}
+
+func TestDiff(t *testing.T) {
+	deltas := Diff(Gauges{"a": 1, "b": 5, "c": 2}, Gauges{"a": 4, "b": 5, "d": 3})
+
+	assert.Equal(t, Gauges{"a": 3, "b": 0, "c": -2, "d": 3}, deltas)
+}
+
+func TestSnapshotterTake(t *testing.T) {
+	snapshotter := NewSnapshotter(staticCollector{"count": 1}, SnapshotterProps{})
+
+	first := snapshotter.Take()
+	second := snapshotter.Take()
+
+	assert.Equal(t, uint64(1), first.ID)
+	assert.Equal(t, uint64(2), second.ID)
+	assert.Equal(t, Gauges{"count": 1}, second.Gauges)
+
+	snapshot, ok := snapshotter.Get(first.ID)
+	assert.True(t, ok)
+	assert.Equal(t, first, snapshot)
+}
+
+func TestSnapshotterDropsOldest(t *testing.T) {
+	snapshotter := NewSnapshotter(staticCollector{}, SnapshotterProps{MaxSnapshots: 2})
+
+	first := snapshotter.Take()
+	snapshotter.Take()
+	last := snapshotter.Take()
+
+	_, ok := snapshotter.Get(first.ID)
+	assert.False(t, ok)
+	_, ok = snapshotter.Get(last.ID)
+	assert.True(t, ok)
+}
@@ -0,0 +1,104 @@
+package statsd
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/oasislabs/oasis-gateway/config"
+	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/oasislabs/oasis-gateway/stats"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// ProtocolDisabled does not push the metrics
+const ProtocolDisabled Protocol = ""
+
+type Config struct {
+	Protocol      Protocol
+	Addr          string
+	FlushInterval time.Duration
+	Prefix        string
+	Tags          []string
+}
+
+func (c *Config) Log(fields log.Fields) {
+	fields.Add("statsd.protocol", c.Protocol)
+	fields.Add("statsd.addr", c.Addr)
+	fields.Add("statsd.flush_interval_ms", int64(c.FlushInterval/time.Millisecond))
+	fields.Add("statsd.prefix", c.Prefix)
+	fields.Add("statsd.tags", strings.Join(c.Tags, ","))
+}
+
+func (c *Config) Configure(v *viper.Viper) error {
+	c.Protocol = Protocol(v.GetString("statsd.protocol"))
+	switch c.Protocol {
+	case ProtocolDisabled:
+		return nil
+	case ProtocolStatsD, ProtocolDogStatsD:
+	default:
+		return config.ErrInvalidValue{
+			Key:          "statsd.protocol",
+			InvalidValue: c.Protocol.String(),
+			Values:       []string{ProtocolStatsD.String(), ProtocolDogStatsD.String()},
+		}
+	}
+
+	c.Addr = v.GetString("statsd.addr")
+	if len(c.Addr) == 0 {
+		return config.ErrKeyNotSet{Key: "statsd.addr"}
+	}
+
+	flushInterval := v.GetInt64("statsd.flush_interval_ms")
+	if flushInterval <= 0 {
+		return errors.New("statsd.flush_interval_ms must be positive")
+	}
+	c.FlushInterval = time.Duration(flushInterval) * time.Millisecond
+
+	c.Prefix = v.GetString("statsd.prefix")
+	c.Tags = v.GetStringSlice("statsd.tags")
+	return nil
+}
+
+func (c *Config) Bind(v *viper.Viper, cmd *cobra.Command) error {
+	cmd.PersistentFlags().String("statsd.protocol", ProtocolDisabled.String(),
+		"protocol used to push the metrics to a statsd server. Options are "+
+			ProtocolStatsD.String()+" and "+ProtocolDogStatsD.String()+
+			". If not set the metrics are not pushed.")
+	cmd.PersistentFlags().String("statsd.addr", "127.0.0.1:8125",
+		"udp address of the statsd server to which the metrics are pushed.")
+	cmd.PersistentFlags().Int64("statsd.flush_interval_ms", 10000,
+		"interval in milliseconds at which the metrics are pushed.")
+	cmd.PersistentFlags().String("statsd.prefix", "oasis_gateway",
+		"prefix of the name of the metrics pushed.")
+	cmd.PersistentFlags().StringSlice("statsd.tags", nil,
+		"tags added to the metrics pushed, such as env:production. Only sent with the "+
+			ProtocolDogStatsD.String()+" protocol.")
+	return nil
+}
+
+// NewExporterFromConfig creates the Exporter defined by the
+// configuration. It returns nil if the metrics are not pushed
+func NewExporterFromConfig(logger log.Logger, collector stats.Collector, config *Config) (*Exporter, error) {
+	if config.Protocol == ProtocolDisabled {
+		return nil, nil
+	}
+
+	conn, err := net.Dial("udp", config.Addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewExporter(Services{
+		Logger:    logger,
+		Collector: collector,
+		Writer:    conn,
+	}, Props{
+		Protocol:      config.Protocol,
+		FlushInterval: config.FlushInterval,
+		Prefix:        config.Prefix,
+		Tags:          config.Tags,
+	}), nil
+}
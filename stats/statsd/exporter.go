@@ -0,0 +1,214 @@
+package statsd
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/oasislabs/oasis-gateway/stats"
+)
+
+// DefaultMaxPacketSize is the maximum size of the packets sent if
+// not provided. It keeps the packets within the MTU of most networks
+// so that they are not fragmented
+const DefaultMaxPacketSize = 1432
+
+// Protocol is the wire protocol used to push the metrics
+type Protocol string
+
+const (
+	// ProtocolStatsD is the plain StatsD protocol, which
+	// does not support tags
+	ProtocolStatsD Protocol = "statsd"
+
+	// ProtocolDogStatsD is the DogStatsD extension of the
+	// StatsD protocol, which supports tags
+	ProtocolDogStatsD Protocol = "dogstatsd"
+)
+
+func (p Protocol) String() string {
+	return string(p)
+}
+
+// Services are the services required by the Exporter
+type Services struct {
+	Logger log.Logger
+
+	// Collector provides the metrics exported
+	Collector stats.Collector
+
+	// Writer is where the packets are written. Each call to
+	// Write is expected to send a single packet
+	Writer io.Writer
+}
+
+// Props define the behaviour of the Exporter
+type Props struct {
+	// Protocol is the protocol used to encode the metrics
+	Protocol Protocol
+
+	// FlushInterval is the time between two pushes of the metrics
+	FlushInterval time.Duration
+
+	// Prefix if set is prepended to the name of every metric
+	Prefix string
+
+	// Tags are added to every metric. They are only sent
+	// with the DogStatsD protocol
+	Tags []string
+
+	// MaxPacketSize is the maximum size of a packet. If 0
+	// the DefaultMaxPacketSize is used
+	MaxPacketSize int
+}
+
+// Exporter periodically pushes the numeric metrics of a collector to
+// a StatsD server as gauges. The metrics are named after their path in
+// the collector, and a metric that is a counter in the gateway is
+// pushed with its absolute value
+type Exporter struct {
+	logger        log.Logger
+	collector     stats.Collector
+	writer        io.Writer
+	protocol      Protocol
+	flushInterval time.Duration
+	prefix        string
+	tags          string
+	maxPacketSize int
+	counters      *stats.CounterGroup
+}
+
+// NewExporter creates a new Exporter. The metrics are not
+// pushed until the exporter is started
+func NewExporter(services Services, props Props) *Exporter {
+	maxPacketSize := props.MaxPacketSize
+	if maxPacketSize <= 0 {
+		maxPacketSize = DefaultMaxPacketSize
+	}
+
+	var tags string
+	if props.Protocol == ProtocolDogStatsD && len(props.Tags) > 0 {
+		tags = "|#" + strings.Join(props.Tags, ",")
+	}
+
+	return &Exporter{
+		logger:        services.Logger.ForClass("stats/statsd", "Exporter"),
+		collector:     services.Collector,
+		writer:        services.Writer,
+		protocol:      props.Protocol,
+		flushInterval: props.FlushInterval,
+		prefix:        props.Prefix,
+		tags:          tags,
+		maxPacketSize: maxPacketSize,
+		counters:      stats.NewCounterGroup("flushes", "packets", "errors"),
+	}
+}
+
+// Name is the implementation of stats.Collector.Name for Exporter
+func (e *Exporter) Name() string {
+	return "stats.statsd.Exporter"
+}
+
+// Stats is the implementation of stats.Collector.Stats for Exporter
+func (e *Exporter) Stats() stats.Metrics {
+	return e.counters.Stats()
+}
+
+// Start pushes the metrics periodically until the
+// context is cancelled
+func (e *Exporter) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(e.flushInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				e.Flush(ctx)
+			}
+		}
+	}()
+}
+
+// Flush pushes the current value of the metrics once. The metrics are
+// split in as many packets as needed, and a packet that fails to be
+// sent does not prevent the rest from being sent
+func (e *Exporter) Flush(ctx context.Context) {
+	e.counters.Incr("flushes")
+
+	for _, packet := range e.encode(stats.Flatten(e.collector.Stats())) {
+		if _, err := e.writer.Write(packet); err != nil {
+			e.counters.Incr("errors")
+			e.logger.Warn(ctx, "failed to push metrics", log.MapFields{
+				"call_type": "PushMetricsFailure",
+				"err":       err.Error(),
+			})
+			continue
+		}
+
+		e.counters.Incr("packets")
+	}
+}
+
+// encode encodes the gauges in packets of newline separated metrics
+// that do not exceed the maximum packet size. A single metric that
+// exceeds it is sent in a packet of its own
+func (e *Exporter) encode(gauges stats.Gauges) [][]byte {
+	paths := make([]string, 0, len(gauges))
+	for path := range gauges {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var (
+		packets [][]byte
+		buf     bytes.Buffer
+	)
+	for _, path := range paths {
+		line := e.encodeGauge(path, gauges[path])
+		if buf.Len() > 0 && buf.Len()+1+len(line) > e.maxPacketSize {
+			packets = append(packets, append([]byte(nil), buf.Bytes()...))
+			buf.Reset()
+		}
+
+		if buf.Len() > 0 {
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(line)
+	}
+
+	if buf.Len() > 0 {
+		packets = append(packets, buf.Bytes())
+	}
+
+	return packets
+}
+
+func (e *Exporter) encodeGauge(path string, v float64) string {
+	name := sanitize(path)
+	if len(e.prefix) > 0 {
+		name = e.prefix + "." + name
+	}
+
+	return name + ":" + strconv.FormatFloat(v, 'f', -1, 64) + "|g" + e.tags
+}
+
+// sanitize replaces the characters that have a meaning
+// in the protocol so that they are not part of a name
+func sanitize(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ':', '|', '@', '#', ',', ' ', '\n':
+			return '_'
+		default:
+			return r
+		}
+	}, name)
+}
@@ -0,0 +1,113 @@
+package statsd
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/oasislabs/oasis-gateway/stats"
+	"github.com/stretchr/testify/assert"
+)
+
+var logger = log.NewLogrus(log.LogrusLoggerProperties{
+	Output: ioutil.Discard,
+})
+
+type staticCollector stats.Metrics
+
+func (c staticCollector) Stats() stats.Metrics {
+	return stats.Metrics(c)
+}
+
+type packetWriter struct {
+	packets []string
+	err     error
+}
+
+func (w *packetWriter) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+
+	w.packets = append(w.packets, string(p))
+	return len(p), nil
+}
+
+var collector = staticCollector{
+	"runtime": stats.Metrics{"NumGoroutine": 12},
+	"tx.Executor": stats.Metrics{
+		"pending": int64(2),
+		"wallet":  "0x01",
+	},
+}
+
+func TestExporterFlushStatsD(t *testing.T) {
+	writer := &packetWriter{}
+	exporter := NewExporter(Services{
+		Logger:    logger,
+		Collector: collector,
+		Writer:    writer,
+	}, Props{
+		Protocol: ProtocolStatsD,
+		Prefix:   "gateway",
+		Tags:     []string{"env:test"},
+	})
+
+	exporter.Flush(context.Background())
+
+	assert.Equal(t, []string{
+		"gateway.runtime.NumGoroutine:12|g\ngateway.tx.Executor.pending:2|g",
+	}, writer.packets)
+}
+
+func TestExporterFlushDogStatsD(t *testing.T) {
+	writer := &packetWriter{}
+	exporter := NewExporter(Services{
+		Logger:    logger,
+		Collector: staticCollector{"queue|size": 0.5},
+		Writer:    writer,
+	}, Props{
+		Protocol: ProtocolDogStatsD,
+		Tags:     []string{"env:test", "region:eu"},
+	})
+
+	exporter.Flush(context.Background())
+
+	assert.Equal(t, []string{"queue_size:0.5|g|#env:test,region:eu"}, writer.packets)
+}
+
+func TestExporterFlushSplitsPackets(t *testing.T) {
+	writer := &packetWriter{}
+	exporter := NewExporter(Services{
+		Logger:    logger,
+		Collector: collector,
+		Writer:    writer,
+	}, Props{
+		Protocol:      ProtocolStatsD,
+		MaxPacketSize: 30,
+	})
+
+	exporter.Flush(context.Background())
+
+	assert.Equal(t, []string{
+		"runtime.NumGoroutine:12|g",
+		"tx.Executor.pending:2|g",
+	}, writer.packets)
+	assert.Equal(t, uint64(2), exporter.Stats()["packets"])
+}
+
+func TestExporterFlushErr(t *testing.T) {
+	writer := &packetWriter{err: errors.New("connection refused")}
+	exporter := NewExporter(Services{
+		Logger:    logger,
+		Collector: collector,
+		Writer:    writer,
+	}, Props{Protocol: ProtocolStatsD})
+
+	exporter.Flush(context.Background())
+
+	assert.Equal(t, uint64(1), exporter.Stats()["errors"])
+	assert.Equal(t, uint64(0), exporter.Stats()["packets"])
+}
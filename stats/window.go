@@ -1,9 +1,13 @@
 package stats
 
+import "sync"
+
 // IntWindow keeps a window of data based on the number
 // of data it can hold. When the window is full, it discards
-// the oldest data to leave space for new data
+// the oldest data to leave space for new data. It is safe
+// for concurrent use
 type IntWindow struct {
+	lock       sync.Mutex
 	offset     uint32
 	end        uint32
 	maxSamples uint32
@@ -24,6 +28,9 @@ func NewIntWindow(maxSamples uint32) *IntWindow {
 // Add a new sample to the window, shifting the window
 // if maxSamples has been exceeded
 func (w *IntWindow) Add(sample int64) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
 	w.window[w.end] = sample
 	w.end++
 
@@ -47,6 +54,9 @@ func (w *IntWindow) Add(sample int64) {
 
 // Stats is the implementation of Collector for IntWindow
 func (w *IntWindow) Stats() Metrics {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
 	return Metrics{
 		"avg": IntAverage(w.window[w.offset:w.end]),
 	}
@@ -121,6 +121,27 @@ func (s *ApiTestSuite) TestPathNoContentType() {
 	assert.Equal(s.T(), "{\"errorCode\":2004,\"description\":\"Content-type should be application/json.\"}\n", string(res.Body))
 }
 
+func (s *ApiTestSuite) TestPathMissingScope() {
+	res, err := s.client.Request(apitest.Request{
+		Route: apitest.Route{
+			Method: "POST",
+			Path:   "/v0/api/service/deploy",
+		},
+		Body: []byte("{}"),
+		Headers: map[string]string{
+			insecure.HeaderKey:           "mykey",
+			insecure.HeaderScopes:        "service.execute,event.poll",
+			auth.RequestHeaderSessionKey: "mysession",
+			"Content-type":               "application/json",
+			"Content-length":             "2",
+		},
+	})
+	assert.Nil(s.T(), err)
+
+	assert.Equal(s.T(), http.StatusForbidden, res.Code)
+	assert.Equal(s.T(), "{\"errorCode\":7006,\"description\":\"Request is not authorized to access the API.\"}\n", string(res.Body))
+}
+
 func TestApiTestSuite(t *testing.T) {
 	suite.Run(t, new(ApiTestSuite))
 }
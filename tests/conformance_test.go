@@ -0,0 +1,25 @@
+package tests
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/oasislabs/oasis-gateway/conformance"
+	"github.com/oasislabs/oasis-gateway/tests/gatewaytest"
+)
+
+// TestConformance runs the conformance suite against the gateway
+// configured with OASIS_DG_CONFIG_PATH, so that it runs against each
+// of the mailbox providers the configurations use
+func TestConformance(t *testing.T) {
+	provider, err := gatewaytest.NewServices(context.TODO(), Config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(gatewaytest.NewPublicRouter(Config, provider))
+	defer server.Close()
+
+	conformance.Run(t, conformance.Props{BaseURL: server.URL})
+}
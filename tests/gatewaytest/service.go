@@ -26,10 +26,15 @@ func NewPublicRouter(config *gateway.Config, provider *Provider) *rpc.HttpRouter
 	request := provider.MustGet(reflect.TypeOf(&backendcore.RequestManager{})).(*backendcore.RequestManager)
 	authenticator := provider.MustGet(reflect.TypeOf((*authcore.Auth)(nil)).Elem()).(authcore.Auth)
 
-	return gateway.NewPublicRouter(config, &gateway.ServiceGroup{
+	router, err := gateway.NewPublicRouter(config, &gateway.ServiceGroup{
 		Request:       request,
 		Authenticator: authenticator,
 	})
+	if err != nil {
+		panic(fmt.Sprintf("failed to create public router %s", err.Error()))
+	}
+
+	return router
 }
 
 func NewServices(ctx context.Context, config *gateway.Config) (*Provider, error) {
@@ -43,7 +48,7 @@ func NewServices(ctx context.Context, config *gateway.Config) (*Provider, error)
 
 	callbacktest.ImplementMock(callbackclient)
 	ethclient.On("BalanceAt", mock.Anything, mock.Anything, mock.Anything).
-		Return(big.NewInt(1), nil)
+		Return(big.NewInt(1000000000000000000), nil)
 
 	ethclient.On("NonceAt", mock.Anything, mock.Anything).Return(uint64(0), nil)
 
@@ -85,9 +90,28 @@ func NewServices(ctx context.Context, config *gateway.Config) (*Provider, error)
 	provider.MustAdd(backendclient)
 
 	request, err := backend.NewRequestManagerWithDeps(ctx, &backend.Deps{
-		Logger: gateway.RootLogger,
-		MQueue: mqueue,
-		Client: backendclient,
+		Logger:      gateway.RootLogger,
+		MQueue:      mqueue,
+		Client:      backendclient,
+		DeployDedup: config.BackendConfig.DeployDedup,
+		Idempotency: backendcore.IdempotencyRegistryProps{
+			TTL: config.BackendConfig.IdempotencyConfig.TTL,
+		},
+		QueryCache: backendcore.QueryCacheProps{
+			TTL:        config.BackendConfig.QueryCacheConfig.TTL,
+			MaxEntries: config.BackendConfig.QueryCacheConfig.MaxEntries,
+		},
+		Quota: backendcore.QuotaProps{
+			QueueSize:   config.BackendConfig.QuotaConfig.QueueSize,
+			SpendLimit:  config.BackendConfig.QuotaConfig.SpendLimit,
+			WarnPercent: config.BackendConfig.QuotaConfig.WarnPercent,
+			MaxInFlight: config.BackendConfig.QuotaConfig.MaxInFlight,
+		},
+		Output: backendcore.OutputStoreProps{
+			MaxSize:     config.BackendConfig.OutputConfig.MaxSize,
+			PreviewSize: config.BackendConfig.OutputConfig.PreviewSize,
+			TTL:         config.BackendConfig.OutputConfig.TTL,
+		},
 	})
 	if err != nil {
 		return nil, err
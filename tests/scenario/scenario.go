@@ -0,0 +1,209 @@
+// Package scenario implements scripted scenarios for the mock
+// clients used in tests, so that the retry and failover behaviours
+// of the gateway can be tested deterministically. A scenario defines
+// for each method of a client the ordered outcomes of its first calls
+// and the latency, jitter and failure ratio of the calls that follow.
+//
+// Scenarios can be loaded from YAML:
+//
+//	seed: 42
+//	methods:
+//	  SendTransaction:
+//	    latency: 10ms
+//	    jitter: 5ms
+//	    failure_ratio: 0.25
+//	    error: connection reset by peer
+//	    responses:
+//	      - error: timeout
+//	      - latency: 100ms
+//	      - {}
+package scenario
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultError is the error returned by a failed call if
+// the scenario does not set one
+const defaultError = "scenario injected failure"
+
+// Step is the scripted outcome of a single call
+type Step struct {
+	// Latency of the call
+	Latency time.Duration `yaml:"latency"`
+
+	// Error if set makes the call fail with that error
+	Error string `yaml:"error"`
+}
+
+// Method defines the outcomes of the calls to a method
+type Method struct {
+	// Responses are the outcomes of the first calls to the method,
+	// in order. Once they are consumed the calls follow the rest
+	// of the properties of the method
+	Responses []Step `yaml:"responses"`
+
+	// Latency of the calls
+	Latency time.Duration `yaml:"latency"`
+
+	// Jitter is the maximum latency added at random to Latency
+	Jitter time.Duration `yaml:"jitter"`
+
+	// FailureRatio is the probability between 0 and 1 that a
+	// call fails
+	FailureRatio float64 `yaml:"failure_ratio"`
+
+	// Error is the error returned by the calls that fail
+	Error string `yaml:"error"`
+}
+
+// Scenario defines the outcomes of the calls to the methods of
+// a client. Methods not defined in the scenario always succeed
+// without latency
+type Scenario struct {
+	// Seed of the random generator used for the jitter and the
+	// failures, so that a scenario is reproducible
+	Seed int64 `yaml:"seed"`
+
+	// Methods by their name
+	Methods map[string]Method `yaml:"methods"`
+}
+
+// Parse parses a scenario from its YAML definition
+func Parse(p []byte) (*Scenario, error) {
+	var s Scenario
+	if err := yaml.Unmarshal(p, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse scenario: %s", err.Error())
+	}
+
+	for name, method := range s.Methods {
+		if method.FailureRatio < 0 || method.FailureRatio > 1 {
+			return nil, fmt.Errorf("failure_ratio of %s must be between 0 and 1", name)
+		}
+
+		if method.Latency < 0 || method.Jitter < 0 {
+			return nil, fmt.Errorf("latency and jitter of %s cannot be negative", name)
+		}
+	}
+
+	return &s, nil
+}
+
+// Load parses the scenario defined in the YAML file at path
+func Load(path string) (*Scenario, error) {
+	p, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return Parse(p)
+}
+
+// Outcome is the outcome of a call
+type Outcome struct {
+	// Latency of the call
+	Latency time.Duration
+
+	// Err is the error returned by the call, if any
+	Err error
+}
+
+// Player plays a scenario on the calls of a mock client.
+//
+// A nil Player is valid and all calls succeed without latency
+type Player struct {
+	scenario *Scenario
+
+	lock  sync.Mutex
+	rand  *rand.Rand
+	calls map[string]int
+}
+
+// NewPlayer creates a new Player for the scenario
+func NewPlayer(scenario *Scenario) *Player {
+	return &Player{
+		scenario: scenario,
+		rand:     rand.New(rand.NewSource(scenario.Seed)),
+		calls:    make(map[string]int),
+	}
+}
+
+// Next returns the outcome of the next call to the method
+func (p *Player) Next(method string) Outcome {
+	if p == nil {
+		return Outcome{}
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	call := p.calls[method]
+	p.calls[method]++
+
+	m, ok := p.scenario.Methods[method]
+	if !ok {
+		return Outcome{}
+	}
+
+	if call < len(m.Responses) {
+		step := m.Responses[call]
+		outcome := Outcome{Latency: step.Latency}
+		if len(step.Error) > 0 {
+			outcome.Err = errors.New(step.Error)
+		}
+		return outcome
+	}
+
+	outcome := Outcome{Latency: m.Latency}
+	if m.Jitter > 0 {
+		outcome.Latency += time.Duration(p.rand.Int63n(int64(m.Jitter) + 1))
+	}
+
+	if m.FailureRatio > 0 && p.rand.Float64() < m.FailureRatio {
+		msg := m.Error
+		if len(msg) == 0 {
+			msg = defaultError
+		}
+		outcome.Err = errors.New(msg)
+	}
+
+	return outcome
+}
+
+// Play waits for the latency of the next call to the method and
+// returns its error. It returns the error of the context if the
+// context is done before the latency elapses
+func (p *Player) Play(ctx context.Context, method string) error {
+	outcome := p.Next(method)
+	if outcome.Latency > 0 {
+		timer := time.NewTimer(outcome.Latency)
+		defer timer.Stop()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	return outcome.Err
+}
+
+// Calls returns the number of calls made to the method
+func (p *Player) Calls(method string) int {
+	if p == nil {
+		return 0
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.calls[method]
+}
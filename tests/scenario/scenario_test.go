@@ -0,0 +1,104 @@
+package scenario
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoad(t *testing.T) {
+	s, err := Load("testdata/flaky_node.yaml")
+	assert.Nil(t, err)
+	assert.Equal(t, &Scenario{
+		Seed: 42,
+		Methods: map[string]Method{
+			"SendTransaction": {
+				Responses: []Step{
+					{Error: "timeout"},
+					{Latency: 5 * time.Millisecond},
+				},
+				Latency:      time.Millisecond,
+				Jitter:       2 * time.Millisecond,
+				FailureRatio: 0.25,
+				Error:        "connection reset by peer",
+			},
+		},
+	}, s)
+}
+
+func TestParseErrInvalid(t *testing.T) {
+	_, err := Parse([]byte("methods:\n  A:\n    failure_ratio: 2\n"))
+	assert.Error(t, err)
+
+	_, err = Parse([]byte("methods:\n  A:\n    latency: -1s\n"))
+	assert.Error(t, err)
+
+	_, err = Parse([]byte("methods: ["))
+	assert.Error(t, err)
+}
+
+func TestPlayerResponses(t *testing.T) {
+	s, err := Load("testdata/flaky_node.yaml")
+	assert.Nil(t, err)
+	p := NewPlayer(s)
+
+	outcome := p.Next("SendTransaction")
+	assert.Equal(t, "timeout", outcome.Err.Error())
+	assert.Equal(t, time.Duration(0), outcome.Latency)
+
+	outcome = p.Next("SendTransaction")
+	assert.Nil(t, outcome.Err)
+	assert.Equal(t, 5*time.Millisecond, outcome.Latency)
+
+	for i := 0; i < 100; i++ {
+		outcome = p.Next("SendTransaction")
+		assert.True(t, outcome.Latency >= time.Millisecond)
+		assert.True(t, outcome.Latency <= 3*time.Millisecond)
+		if outcome.Err != nil {
+			assert.Equal(t, "connection reset by peer", outcome.Err.Error())
+		}
+	}
+
+	// methods not in the scenario always succeed
+	assert.Equal(t, Outcome{}, p.Next("EstimateGas"))
+	assert.Equal(t, 102, p.Calls("SendTransaction"))
+	assert.Equal(t, 1, p.Calls("EstimateGas"))
+}
+
+func TestPlayerDeterministic(t *testing.T) {
+	s := &Scenario{
+		Seed:    7,
+		Methods: map[string]Method{"A": {FailureRatio: 0.5, Jitter: time.Second}},
+	}
+
+	first, second := NewPlayer(s), NewPlayer(s)
+	failures := 0
+	for i := 0; i < 100; i++ {
+		a, b := first.Next("A"), second.Next("A")
+		assert.Equal(t, a, b)
+		if a.Err != nil {
+			assert.Equal(t, defaultError, a.Err.Error())
+			failures++
+		}
+	}
+
+	assert.True(t, failures > 25 && failures < 75)
+}
+
+func TestPlayerPlayContextDone(t *testing.T) {
+	p := NewPlayer(&Scenario{Methods: map[string]Method{"A": {Latency: time.Hour}}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.Equal(t, context.Canceled, p.Play(ctx, "A"))
+}
+
+func TestPlayerNil(t *testing.T) {
+	var p *Player
+
+	assert.Nil(t, p.Play(context.Background(), "A"))
+	assert.Equal(t, 0, p.Calls("A"))
+}
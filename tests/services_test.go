@@ -12,6 +12,7 @@ import (
 	"github.com/oasislabs/oasis-gateway/rpc"
 	"github.com/oasislabs/oasis-gateway/tests/apitest"
 	"github.com/oasislabs/oasis-gateway/tests/gatewaytest"
+	"github.com/oasislabs/oasis-gateway/tests/scenario"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
@@ -67,6 +68,39 @@ func (s *ServicesTestSuite) TestDeployServiceErrEstimateGas() {
 		}}, ev)
 }
 
+func (s *ServicesTestSuite) TestDeployServiceScenarioTransientFailure() {
+	script, err := scenario.Parse([]byte(`
+methods:
+  EstimateGas:
+    responses:
+      - error: connection reset by peer
+`))
+	assert.Nil(s.T(), err)
+	ethtest.ImplementMockWithScenario(s.ethclient, scenario.NewPlayer(script))
+
+	// the first deployment fails because of the scripted error
+	// and the next one succeeds
+	ev, err := s.client.DeployServiceSync(context.TODO(), service.DeployServiceRequest{
+		Data: "0x0000000000000000000000000000000000000000",
+	})
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), service.ErrorEvent{
+		ID: 0x0,
+		Cause: rpc.Error{
+			ErrorCode:   1002,
+			Description: "Internal Error. Please check the status of the service.",
+		}}, ev)
+
+	ev, err = s.client.DeployServiceSync(context.TODO(), service.DeployServiceRequest{
+		Data: "0x0000000000000000000000000000000000000000",
+	})
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), service.DeployServiceEvent{
+		ID:      1,
+		Address: "0x0000000000000000000000000000000000000000",
+	}, ev)
+}
+
 func (s *ServicesTestSuite) TestDeployServiceOK() {
 	ethtest.ImplementMock(s.ethclient)
 
@@ -0,0 +1,215 @@
+package tx
+
+import (
+	"context"
+	"math/big"
+	"sync"
+
+	stderr "github.com/pkg/errors"
+
+	callback "github.com/oasislabs/oasis-gateway/callback/client"
+	"github.com/oasislabs/oasis-gateway/errors"
+	"github.com/oasislabs/oasis-gateway/eth"
+	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/oasislabs/oasis-gateway/slo"
+)
+
+// batchTransaction is a transaction of a batch that has
+// been signed and is waiting to be submitted
+type batchTransaction struct {
+	index int
+	info  *TransactionInfo
+	tx    eth.Transaction
+	res   eth.SendTransactionResponse
+	err   error
+}
+
+// executeBatch executes the transactions of a batch with consecutive
+// nonces. The transactions are signed in order and then submitted
+// concurrently, so that the batch is not serialized on the time each
+// transaction takes to be mined. Unlike a single transaction, the
+// submission of a transaction of a batch is not retried, since the
+// transactions that follow it already use the nonces after its own
+func (e *WalletOwner) executeBatch(ctx context.Context, req ExecuteBatchRequest) ([]ExecuteBatchResult, errors.Err) {
+	ctx = callback.WithAAD(ctx, req.AAD)
+
+	// the batch may have waited for the owner for longer than its
+	// lifetime, in which case it is not sent at all
+	if err := ctx.Err(); err != nil {
+		return nil, contextError(err)
+	}
+
+	results := make([]ExecuteBatchResult, len(req.Requests))
+	pending := make([]*TransactionInfo, 0, len(req.Requests))
+	indexes := make([]int, 0, len(req.Requests))
+	var gas uint64
+	for i, r := range req.Requests {
+		r.AAD = req.AAD
+		req.Requests[i] = r
+
+		limit, err := e.transactionGas(ctx, r)
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+
+		info := &TransactionInfo{
+			AAD:     r.AAD,
+			ID:      r.ID,
+			Sender:  e.wallet.Address(),
+			Address: r.Address,
+			Gas:     limit,
+			Data:    r.Data,
+		}
+		if err := e.middleware.PreSign(ctx, info); err != nil {
+			results[i].Err = middlewareError(err)
+			continue
+		}
+
+		pending = append(pending, info)
+		indexes = append(indexes, i)
+		gas += limit
+	}
+
+	if len(pending) == 0 {
+		return results, nil
+	}
+
+	gasPrice, err := e.gasPrice.GasPrice(ctx, GasPriceRequest{})
+	if err != nil {
+		return nil, errors.New(errors.ErrGasPrice, err)
+	}
+
+	// the balance covers the batch at the highest of the gas
+	// prices its transactions are sent with
+	maxPrice := gasPrice
+	for _, i := range indexes {
+		price := new(big.Int).SetUint64(req.Requests[i].GasPrice)
+		if price.Cmp(maxPrice) > 0 {
+			maxPrice = price
+		}
+	}
+
+	// the balance is checked for the whole batch so that a batch
+	// that the wallet cannot pay for is handed to another wallet
+	// before any of its transactions is signed
+	if err := e.checkBalance(ctx, sendTransactionRequest{
+		AAD: req.AAD,
+		ID:  pending[0].ID,
+		Gas: gas,
+	}, maxPrice); err != nil {
+		return nil, err
+	}
+
+	signed := slo.GetStages(ctx).Track("sign")
+	txs := make([]*batchTransaction, 0, len(pending))
+	for k, info := range pending {
+		i := indexes[k]
+		r := req.Requests[i]
+
+		price := gasPrice
+		if r.GasPrice > 0 {
+			price = new(big.Int).SetUint64(r.GasPrice)
+		}
+
+		tx, err := e.generateAndSignTransaction(ctx, sendTransactionRequest{
+			AAD:      r.AAD,
+			ID:       r.ID,
+			Address:  r.Address,
+			Gas:      info.Gas,
+			GasPrice: r.GasPrice,
+			Data:     r.Data,
+			Expiry:   r.Expiry,
+		}, e.transactionNonce(), info.Gas, price)
+		if err != nil {
+			// the nonce is not used, so that the transactions that
+			// follow do not leave a gap in the nonces of the wallet
+			e.nonce--
+			results[i].Err = errors.New(errors.ErrSignedTx, err)
+			continue
+		}
+
+		if err := e.middleware.PostSign(ctx, info, tx); err != nil {
+			e.nonce--
+			results[i].Err = middlewareError(err)
+			continue
+		}
+
+		txs = append(txs, &batchTransaction{index: i, info: info, tx: tx})
+	}
+	signed()
+
+	sent := slo.GetStages(ctx).Track("send")
+	var wg sync.WaitGroup
+	for _, btx := range txs {
+		wg.Add(1)
+		go func(btx *batchTransaction) {
+			defer wg.Done()
+			btx.res, btx.err = e.submitTransaction(ctx, btx.tx, req.Requests[btx.index].Expiry)
+		}(btx)
+	}
+	wg.Wait()
+	sent()
+
+	failed := false
+	for _, btx := range txs {
+		r := req.Requests[btx.index]
+		if btx.err != nil {
+			failed = true
+			err := e.batchSendError(ctx, btx.err)
+			e.middleware.PostSend(ctx, btx.info, eth.SendTransactionResponse{}, err)
+			e.logger.Debug(ctx, "failed to send transaction of batch", log.MapFields{
+				"call_type": "ExecuteBatchTransactionFailure",
+				"id":        r.ID,
+				"address":   r.Address,
+			}, err)
+			results[btx.index].Err = err
+			continue
+		}
+
+		e.middleware.PostSend(ctx, btx.info, btx.res, nil)
+		e.callbacks.TransactionCommitted(ctx, callback.TransactionCommittedBody{
+			AAD:     req.AAD,
+			Address: e.wallet.Address().Hex(),
+			Hash:    btx.res.Hash,
+		})
+
+		res, err := e.transactionResult(ctx, r, btx.res)
+		results[btx.index] = ExecuteBatchResult{Response: res, Err: err}
+	}
+
+	// a transaction that was not accepted leaves a gap in the nonces
+	// used by the batch, so the nonce is fetched again to fill it
+	if failed {
+		if err := e.updateNonce(context.Background()); err != nil {
+			e.logger.Warn(ctx, "failed to reset nonce after batch failure", log.MapFields{
+				"call_type": "ResetNonceFailure",
+				"id":        pending[0].ID,
+			}, err)
+		}
+	}
+
+	// failing to update the balance should not fail the execution of
+	// the transactions
+	_ = e.updateBalance(ctx)
+
+	return results, nil
+}
+
+// batchSendError returns the error of a transaction of a batch that
+// could not be submitted
+func (e *WalletOwner) batchSendError(ctx context.Context, err error) errors.Err {
+	switch {
+	case ctx.Err() != nil:
+		return contextError(ctx.Err())
+	case stderr.Is(err, eth.ErrExceedsBalance):
+		e.callbacks.WalletOutOfFunds(ctx, callback.WalletOutOfFundsBody{
+			Address: e.wallet.Address().Hex(),
+		})
+		return errors.New(errors.ErrSendTransaction, err)
+	case stderr.Is(err, eth.ErrTransactionExpired):
+		return errors.New(errors.ErrTransactionExpired, err)
+	default:
+		return errors.New(errors.ErrSendTransaction, err)
+	}
+}
@@ -0,0 +1,94 @@
+package tx
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/oasislabs/oasis-gateway/errors"
+	"github.com/oasislabs/oasis-gateway/eth/ethtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func sentWithNonce(nonce uint64) interface{} {
+	return mock.MatchedBy(func(tx *types.Transaction) bool {
+		return tx.Nonce() == nonce
+	})
+}
+
+func TestExecuteBatchConsecutiveNonces(t *testing.T) {
+	mockclient := &ethtest.MockClient{}
+	ethtest.ImplementMock(mockclient)
+	owner, err := newOwnerWithGasLimit(mockclient, GasLimitProps{})
+	assert.Nil(t, err)
+	start := owner.nonce
+
+	results, xerr := owner.executeBatch(context.TODO(), ExecuteBatchRequest{
+		AAD: "aad",
+		Requests: []ExecuteRequest{
+			{ID: 0, Address: address, Data: []byte("")},
+			{ID: 1, Address: address, Data: []byte("")},
+			{ID: 2, Address: address, Data: []byte("")},
+		},
+	})
+
+	assert.Nil(t, xerr)
+	assert.Equal(t, 3, len(results))
+	for _, res := range results {
+		assert.Nil(t, res.Err)
+	}
+	mockclient.AssertNumberOfCalls(t, "SendTransaction", 3)
+	for nonce := start; nonce < start+3; nonce++ {
+		mockclient.AssertCalled(t, "SendTransaction", mock.Anything, sentWithNonce(nonce))
+	}
+	assert.Equal(t, start+3, owner.nonce)
+}
+
+func TestExecuteBatchPartialFailure(t *testing.T) {
+	mockclient := &ethtest.MockClient{}
+	ethtest.ImplementMock(mockclient)
+	owner, err := newOwnerWithGasLimit(mockclient, GasLimitProps{Confidential: 50000, Max: 100000})
+	assert.Nil(t, err)
+	start := owner.nonce
+
+	results, xerr := owner.executeBatch(context.TODO(), ExecuteBatchRequest{
+		AAD: "aad",
+		Requests: []ExecuteRequest{
+			{ID: 0, Address: address, Data: []byte("")},
+			{ID: 1, Address: address, Data: []byte(""), GasLimit: 100001},
+			{ID: 2, Address: address, Data: []byte("")},
+		},
+	})
+
+	assert.Nil(t, xerr)
+	assert.Nil(t, results[0].Err)
+	assert.Equal(t, errors.ErrGasLimitExceeded, results[1].Err.ErrorCode())
+	assert.Nil(t, results[2].Err)
+
+	// the rejected request does not consume a nonce
+	mockclient.AssertNumberOfCalls(t, "SendTransaction", 2)
+	mockclient.AssertCalled(t, "SendTransaction", mock.Anything, sentWithNonce(start))
+	mockclient.AssertCalled(t, "SendTransaction", mock.Anything, sentWithNonce(start+1))
+}
+
+func TestExecuteBatchGasPriceExceeded(t *testing.T) {
+	mockclient := &ethtest.MockClient{}
+	ethtest.ImplementMock(mockclient)
+	owner, err := newOwnerWithGasLimit(mockclient, GasLimitProps{})
+	assert.Nil(t, err)
+	owner.maxGasPrice = 5 * DefaultGasPrice
+
+	results, xerr := owner.executeBatch(context.TODO(), ExecuteBatchRequest{
+		AAD: "aad",
+		Requests: []ExecuteRequest{
+			{ID: 0, Address: address, Data: []byte(""), GasPrice: 2 * DefaultGasPrice},
+			{ID: 1, Address: address, Data: []byte(""), GasPrice: 6 * DefaultGasPrice},
+		},
+	})
+
+	assert.Nil(t, xerr)
+	assert.Nil(t, results[0].Err)
+	assert.Equal(t, errors.ErrGasPriceExceeded, results[1].Err.ErrorCode())
+	mockclient.AssertNumberOfCalls(t, "SendTransaction", 1)
+}
@@ -1,5 +1,35 @@
 package tx
 
+import "github.com/oasislabs/oasis-gateway/errors"
+
+// OutputMode defines the mechanism used by a WalletOwner to
+// retrieve the return data of a transaction once it has been
+// mined
+type OutputMode string
+
+const (
+	// OutputModeInvoke uses the output returned by the node
+	// when the transaction is submitted. This is only available
+	// on nodes that implement oasis_invoke
+	OutputModeInvoke OutputMode = "invoke"
+
+	// OutputModeTrace retrieves the output by replaying the
+	// transaction with debug_traceTransaction. The node needs
+	// to expose the debug namespace
+	OutputModeTrace OutputMode = "trace"
+
+	// OutputModeCall retrieves the output by executing the same
+	// payload as a call against the state of the block previous
+	// to the one in which the transaction was mined. The output
+	// is approximate if other transactions in the same block
+	// change the state the call depends on
+	OutputModeCall OutputMode = "call"
+)
+
+func (m OutputMode) String() string {
+	return string(m)
+}
+
 // ExecuteRequest is the request to execute an Ethereum transaction
 type ExecuteRequest struct {
 	// AAD is the identifier of the original issuer for the transaction data
@@ -13,10 +43,45 @@ type ExecuteRequest struct {
 
 	// Transaction data
 	Data []byte
+
+	// Expiry is the block number after which the transaction should be
+	// discarded by the runtime if it has not been included in a block.
+	// If it is 0 the transaction is submitted without an expiry
+	Expiry uint64
+
+	// GasLimit if set is the gas limit of the transaction instead
+	// of the estimated one
+	GasLimit uint64
+
+	// GasPrice if set is the gas price of the transaction instead
+	// of the one chosen by the GasPriceStrategy
+	GasPrice uint64
 }
 
 type ExecuteResponse struct {
 	Address string
 	Output  string
 	Hash    string
+
+	// GasUsed is the gas consumed by the transaction
+	GasUsed uint64
+}
+
+// ExecuteBatchRequest is the request to execute several Ethereum
+// transactions from the same wallet with consecutive nonces
+type ExecuteBatchRequest struct {
+	// AAD is the identifier of the original issuer of the transactions
+	AAD string
+
+	// Requests are the transactions of the batch in the order in
+	// which their nonces are assigned. The AAD of each request is
+	// ignored in favour of the AAD of the batch
+	Requests []ExecuteRequest
+}
+
+// ExecuteBatchResult is the outcome of a transaction of a batch.
+// Only one of Response and Err is set
+type ExecuteBatchResult struct {
+	Response ExecuteResponse
+	Err      errors.Err
 }
@@ -3,15 +3,18 @@ package tx
 import (
 	"context"
 	"crypto/ecdsa"
+	stderr "errors"
+	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/oasislabs/oasis-gateway/concurrent"
 	"github.com/oasislabs/oasis-gateway/errors"
 	"github.com/oasislabs/oasis-gateway/eth"
 	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/oasislabs/oasis-gateway/slo"
 	"github.com/oasislabs/oasis-gateway/stats"
 )
 
@@ -21,15 +24,92 @@ type ExecutorServices struct {
 	Logger    log.Logger
 	Client    eth.Client
 	Callbacks Callbacks
+
+	// Middleware is invoked by the wallet owners at each stage
+	// of the processing of a transaction. It is optional
+	Middleware Middleware
 }
 
 type ExecutorProps struct {
 	PrivateKeys []*ecdsa.PrivateKey
+
+	// Accounts are the private keys of the wallets of each account.
+	// The wallets of an account only sign the transactions of the
+	// AADs mapped to it, so that the tenants of a gateway can spend
+	// from their own wallets. It is optional
+	Accounts map[string][]*ecdsa.PrivateKey
+
+	// AccountAADs maps AADs to the account whose wallets sign their
+	// transactions. The transactions of the AADs that are not mapped
+	// are signed by the wallets of PrivateKeys
+	AccountAADs map[string]string
+
+	// OutputMode defines how the output of a transaction is
+	// retrieved by the wallet owners
+	OutputMode OutputMode
+
+	// RetryConfig overrides the retry policy of the wallet owners
+	// for recoverable transaction submission failures. It is optional
+	RetryConfig *concurrent.RetryConfig
+
+	// MaxLifetime is the maximum time a request can take to be
+	// executed. Requests that take longer fail with a timeout, so
+	// that clients do not wait forever on a transaction that the
+	// node dropped. If it is 0 requests do not time out
+	MaxLifetime time.Duration
+
+	// Scheduling defines the order in which the requests of the AADs
+	// that share the wallets of an account are handed to its wallet
+	// owners. By default requests are handed in the order they arrive
+	Scheduling SchedulerProps
+
+	// GasPrice defines how the gas price of the transactions
+	// is chosen. By default DefaultGasPrice is used
+	GasPrice GasPriceProps
+
+	// GasLimit defines the gas limit of the transactions
+	GasLimit GasLimitProps
+
+	// DynamicFeeChainID is the chain ID of a chain that supports
+	// EIP-1559 dynamic fee transactions. If it is set and
+	// GasPrice.DynamicFees is enabled the wallet owners submit dynamic
+	// fee transactions for the chain, otherwise legacy transactions
+	DynamicFeeChainID uint64
+
+	// Selection defines how the wallet owner of an account that
+	// submits a transaction is chosen. By default the transaction
+	// is handed to the first wallet owner that is idle
+	Selection WalletSelectorProps
+
+	// Slow are the thresholds above which the wallet owners log a
+	// transaction with the time taken by each of its stages
+	Slow slo.SlowProps
 }
 
 type Executor struct {
 	WalletAddresses []common.Address
+	keys            []*SecureKey
+	outputMode      OutputMode
+	retryConfig     *concurrent.RetryConfig
+	slow            slo.SlowProps
+	maxLifetime     time.Duration
+	expired         stats.Counter
+	pending         int64
 	master          *concurrent.Master
+	accounts        map[string]*concurrent.Master
+	aads            map[string]string
+	scheduling      SchedulerProps
+	scheduler       *Scheduler
+	schedulers      map[string]*Scheduler
+	selection       WalletSelectorProps
+	selector        *WalletSelector
+	selectors       map[string]*WalletSelector
+	gasPrice        GasPriceStrategy
+	gasLimit        GasLimitProps
+	dynamicFees     *DynamicFees
+	replaceInterval time.Duration
+	maxGasPrice     uint64
+	middleware      Middleware
 	client          eth.Client
 	logger          log.Logger
 	callbacks       Callbacks
@@ -38,34 +118,126 @@ type Executor struct {
 func NewExecutor(ctx context.Context, services *ExecutorServices, props *ExecutorProps) (*Executor, error) {
 	s := &Executor{
 		WalletAddresses: make([]common.Address, 0, len(props.PrivateKeys)),
+		keys:            make([]*SecureKey, 0, len(props.PrivateKeys)),
+		accounts:        make(map[string]*concurrent.Master, len(props.Accounts)),
+		aads:            make(map[string]string, len(props.AccountAADs)),
+		scheduling:      props.Scheduling,
+		schedulers:      make(map[string]*Scheduler, len(props.Accounts)),
+		selection:       props.Selection,
+		selectors:       make(map[string]*WalletSelector, len(props.Accounts)),
+		outputMode:      props.OutputMode,
+		retryConfig:     props.RetryConfig,
+		slow:            props.Slow,
+		maxLifetime:     props.MaxLifetime,
+		gasPrice:        NewGasPriceStrategy(services.Client, props.GasPrice),
+		gasLimit:        props.GasLimit,
+		dynamicFees:     NewDynamicFees(props.DynamicFeeChainID, props.GasPrice),
+		replaceInterval: props.GasPrice.EscalationInterval,
+		maxGasPrice:     props.GasPrice.Max,
+		middleware:      services.Middleware,
 		client:          services.Client,
 		callbacks:       services.Callbacks,
 		logger:          services.Logger.ForClass("tx/wallet", "Executor"),
 	}
 
-	s.master = concurrent.NewMaster(concurrent.MasterProps{
+	for aad, account := range props.AccountAADs {
+		if len(props.Accounts[account]) == 0 {
+			return nil, fmt.Errorf("AAD %s is mapped to account %s which has no wallets", aad, account)
+		}
+		s.aads[aad] = account
+	}
+
+	// each account has its own master, so that its wallet owners
+	// only receive the transactions of the AADs mapped to it
+	master, wallets, err := s.startMaster(ctx, props.PrivateKeys)
+	if err != nil {
+		s.destroyKeys()
+		return nil, err
+	}
+	s.master = master
+	s.scheduler = s.newScheduler(len(props.PrivateKeys))
+	s.selector = NewWalletSelector(wallets, s.selection)
+
+	for name, keys := range props.Accounts {
+		master, wallets, err := s.startMaster(ctx, keys)
+		if err != nil {
+			_ = s.Close()
+			return nil, err
+		}
+		s.accounts[name] = master
+		s.schedulers[name] = s.newScheduler(len(keys))
+		s.selectors[name] = NewWalletSelector(wallets, s.selection)
+	}
+
+	return s, nil
+}
+
+// startMaster starts a master with a wallet owner for each of the
+// private keys and returns the addresses of the wallets, which are
+// the keys of their owners. The executor takes ownership of the
+// keys, which are destroyed on Close
+func (s *Executor) startMaster(ctx context.Context, privateKeys []*ecdsa.PrivateKey) (*concurrent.Master, []string, error) {
+	master := concurrent.NewMaster(concurrent.MasterProps{
 		MasterHandler:         concurrent.MasterHandlerFunc(s.handle),
 		CreateWorkerOnRequest: true,
+		OnWorkerPanic:         s.workerPanic,
 	})
 
-	if err := s.master.Start(ctx); err != nil {
-		return nil, err
+	if err := master.Start(ctx); err != nil {
+		return nil, nil, err
 	}
 
 	// Create a worker for each provided private key
-	for _, pk := range props.PrivateKeys {
-		address := crypto.PubkeyToAddress(pk.PublicKey)
-		s.WalletAddresses = append(s.WalletAddresses, address)
-		req := createOwnerRequest{PrivateKey: pk}
-		if err := s.master.Create(ctx, address.Hex(), &req); err != nil {
-			if err := s.master.Stop(); err != nil {
-				return nil, err
+	wallets := make([]string, 0, len(privateKeys))
+	for _, pk := range privateKeys {
+		key := NewSecureKey(pk)
+		s.keys = append(s.keys, key)
+		s.WalletAddresses = append(s.WalletAddresses, key.Address())
+		req := createOwnerRequest{Key: key}
+		if err := master.Create(ctx, key.Address().Hex(), &req); err != nil {
+			if err := master.Stop(); err != nil {
+				return nil, nil, err
 			}
-			return nil, err
+			return nil, nil, err
 		}
+		wallets = append(wallets, key.Address().Hex())
 	}
 
-	return s, nil
+	return master, wallets, nil
+}
+
+// newScheduler creates the scheduler of a master with the given
+// number of wallet owners, so that each wallet owner is handed at
+// most one request at a time and the others wait to be scheduled
+func (s *Executor) newScheduler(wallets int) *Scheduler {
+	if wallets == 0 {
+		wallets = 1
+	}
+	return NewScheduler(wallets, s.scheduling)
+}
+
+// Close stops the wallet owners and destroys the private keys
+// of the wallets. The executor cannot be used once closed
+func (s *Executor) Close() error {
+	var err error
+	if s.master != nil {
+		err = s.master.Stop()
+	}
+
+	for _, master := range s.accounts {
+		if stopErr := master.Stop(); stopErr != nil && err == nil {
+			err = stopErr
+		}
+	}
+
+	s.destroyKeys()
+	return err
+}
+
+func (s *Executor) destroyKeys() {
+	for _, key := range s.keys {
+		key.Destroy()
+	}
 }
 
 func (m *Executor) Name() string {
@@ -73,16 +245,42 @@ func (m *Executor) Name() string {
 }
 
 func (m *Executor) Stats() stats.Metrics {
-	metrics := make(stats.Metrics)
+	metrics := stats.Metrics{
+		"expired": m.expired.Value(),
+		"pending": atomic.LoadInt64(&m.pending),
+	}
+	m.collectStats(m.master, metrics)
+	metrics["scheduler"] = m.scheduler.Stats()
+	metrics["selector"] = m.selector.Stats()
+
+	if len(m.accounts) > 0 {
+		accounts := make(stats.Metrics, len(m.accounts))
+		for name, master := range m.accounts {
+			account := make(stats.Metrics)
+			m.collectStats(master, account)
+			account["scheduler"] = m.schedulers[name].Stats()
+			account["selector"] = m.selectors[name].Stats()
+			accounts[name] = account
+		}
+		metrics["accounts"] = accounts
+	}
+
+	return metrics
+}
+
+// collectStats adds the metrics of the workers of the master and
+// of each of its wallet owners
+func (m *Executor) collectStats(master *concurrent.Master, metrics stats.Metrics) {
+	metrics["workers"] = master.Stats()
 
 	ctx := context.Background()
-	responses, err := m.master.Broadcast(ctx, statsRequest{})
+	responses, err := master.Broadcast(ctx, statsRequest{})
 	if err != nil {
 		m.logger.Warn(ctx, "failed to fetch stats from wallet owners", log.MapFields{
 			"call_type": "StatsCollectionFailure",
 			"err":       err.Error(),
 		})
-		return metrics
+		return
 	}
 
 	for _, res := range responses {
@@ -94,8 +292,6 @@ func (m *Executor) Stats() stats.Metrics {
 			metrics[res.Key] = res.Value
 		}
 	}
-
-	return metrics
 }
 
 func (m *Executor) handle(ctx context.Context, ev concurrent.MasterEvent) error {
@@ -105,24 +301,41 @@ func (m *Executor) handle(ctx context.Context, ev concurrent.MasterEvent) error
 	case concurrent.DestroyWorkerEvent:
 		return m.destroy(ctx, ev)
 	default:
-		panic("received unknown request")
+		return concurrent.ErrUnexpectedEvent{Event: ev}
 	}
 }
 
+func (s *Executor) workerPanic(key string, err error) {
+	s.logger.Warn(context.Background(), "wallet owner loop panicked and is restarted", log.MapFields{
+		"call_type": "WorkerPanic",
+		"wallet":    key,
+		"err":       err.Error(),
+	})
+}
+
 func (s *Executor) create(ctx context.Context, ev concurrent.CreateWorkerEvent) error {
 	req := ev.Value.(*createOwnerRequest)
 
 	owner, err := NewWalletOwner(
 		ctx,
 		&WalletOwnerServices{
-			Client:    s.client,
-			Callbacks: s.callbacks,
-			Logger:    s.logger,
+			Client:     s.client,
+			Callbacks:  s.callbacks,
+			Logger:     s.logger,
+			Middleware: s.middleware,
+			GasPrice:   s.gasPrice,
 		},
 		&WalletOwnerProps{
-			PrivateKey: req.PrivateKey,
-			Signer:     types.FrontierSigner{},
-			Nonce:      0,
+			Key:             req.Key,
+			Signer:          types.FrontierSigner{},
+			Nonce:           0,
+			OutputMode:      s.outputMode,
+			RetryConfig:     s.retryConfig,
+			Slow:            s.slow,
+			GasLimit:        s.gasLimit,
+			DynamicFees:     s.dynamicFees,
+			ReplaceInterval: s.replaceInterval,
+			MaxGasPrice:     s.maxGasPrice,
 		})
 	if err != nil {
 		return err
@@ -140,16 +353,158 @@ func (s *Executor) destroy(ctx context.Context, ev concurrent.DestroyWorkerEvent
 	return nil
 }
 
-// Executes the desired transaction.
+// Executes the desired transaction. The request is abandoned once its
+// context is done, either because the caller cancelled it or because it
+// exceeded its maximum lifetime
 func (s *Executor) Execute(ctx context.Context, req ExecuteRequest) (ExecuteResponse, errors.Err) {
-	res, err := s.master.Execute(ctx, req)
+	res, err := s.withLifetime(ctx, req.ID, req.Address, func(ctx context.Context) (interface{}, errors.Err) {
+		return s.dispatch(ctx, req.AAD, req.ID, req)
+	})
+	if err != nil {
+		return ExecuteResponse{}, err
+	}
+
+	return res.(ExecuteResponse), nil
+}
+
+// ExecuteBatch executes the transactions of the batch from the same
+// wallet with consecutive nonces. The transactions are submitted
+// without waiting for the previous ones to be mined, so a batch takes
+// about as long as a single transaction. The batch fails as a whole
+// only if none of its transactions can be sent, otherwise the outcome
+// of each transaction is returned in the order of the requests. The
+// maximum lifetime of a request applies to the whole batch
+func (s *Executor) ExecuteBatch(ctx context.Context, req ExecuteBatchRequest) ([]ExecuteBatchResult, errors.Err) {
+	if len(req.Requests) == 0 {
+		return nil, nil
+	}
+
+	first := req.Requests[0]
+	res, err := s.withLifetime(ctx, first.ID, first.Address, func(ctx context.Context) (interface{}, errors.Err) {
+		return s.dispatch(ctx, req.AAD, first.ID, req)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return res.([]ExecuteBatchResult), nil
+}
+
+// withLifetime runs fn with a context that expires once the maximum
+// lifetime of a request is exceeded
+func (s *Executor) withLifetime(
+	ctx context.Context,
+	id uint64,
+	address string,
+	fn func(context.Context) (interface{}, errors.Err),
+) (interface{}, errors.Err) {
+	if s.maxLifetime <= 0 {
+		return fn(ctx)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.maxLifetime)
+	defer cancel()
+
+	// the wallet owner may be blocked on a transaction that will
+	// never complete, so the request is only waited on until its
+	// lifetime expires. The wallet owner skips the request or resets
+	// its nonce once it finds out the context is done
+	res, err := fn(ctx)
+	if err == nil || ctx.Err() != context.DeadlineExceeded {
+		return res, err
+	}
+
+	s.expired.Incr()
+	err = errors.New(errors.ErrTransactionTimeout,
+		fmt.Errorf("transaction did not complete within %s", s.maxLifetime))
+	s.logger.Warn(ctx, "transaction exceeded its maximum lifetime", log.MapFields{
+		"call_type": "ExecuteTransactionTimeout",
+		"id":        id,
+		"address":   address,
+	}, err)
+	return nil, err
+}
+
+// dispatch hands the request to a wallet owner of the account of
+// the AAD and waits for its result
+func (s *Executor) dispatch(ctx context.Context, aad string, id uint64, req interface{}) (interface{}, errors.Err) {
+	atomic.AddInt64(&s.pending, 1)
+	defer atomic.AddInt64(&s.pending, -1)
+
+	master, scheduler, selector := s.master, s.scheduler, s.selector
+	if account, ok := s.aads[aad]; ok {
+		master, scheduler, selector = s.accounts[account], s.schedulers[account], s.selectors[account]
+	}
+
+	// the request waits for its turn with the scheduler rather than
+	// with the master, so that the AADs that share the wallets are
+	// handed to the wallet owners according to the scheduling policy
+	if err := scheduler.Acquire(ctx, aad); err != nil {
+		return nil, contextError(err)
+	}
+	defer scheduler.Release()
+
+	var res interface{}
+	var err error
+	if selector.Shared() {
+		res, err = master.Execute(ctx, req)
+	} else {
+		res, err = s.executeSelected(ctx, master, selector, id, req)
+	}
 	if err != nil {
 		if e, ok := err.(errors.Err); ok {
-			return ExecuteResponse{}, e
+			return nil, e
+		}
+
+		// the master stops waiting for the wallet owner
+		// once the context of the request is done
+		if ctx.Err() != nil {
+			return nil, contextError(ctx.Err())
 		}
 
-		return ExecuteResponse{}, errors.New(errors.ErrExecuteTransaction, err)
+		return nil, errors.New(errors.ErrExecuteTransaction, err)
 	}
 
-	return res.(ExecuteResponse), nil
+	return res, nil
+}
+
+// executeSelected hands the request to the wallet owner chosen by the
+// selector. A wallet that does not have the funds to pay for the
+// transaction fails it before it is signed, so the request is handed
+// to another wallet until one can pay for it or all have been tried
+func (s *Executor) executeSelected(
+	ctx context.Context,
+	master *concurrent.Master,
+	selector *WalletSelector,
+	id uint64,
+	req interface{},
+) (interface{}, error) {
+	tried := make(map[string]bool)
+	var lastErr error
+	for {
+		wallet, ok := selector.Select(tried)
+		if !ok {
+			if lastErr != nil {
+				return nil, lastErr
+			}
+			return nil, errors.New(errors.ErrExecuteTransaction, stderr.New("no wallets available to execute the transaction"))
+		}
+		tried[wallet] = true
+
+		res, err := master.Request(ctx, wallet, req)
+		selector.Done(wallet)
+
+		e, ok := err.(errors.Err)
+		if !ok || e.ErrorCode().Code() != errors.ErrInsufficientGatewayFunds.Code() {
+			return res, err
+		}
+
+		selector.Drained(wallet)
+		lastErr = err
+		s.logger.Info(ctx, "wallet out of funds, transaction handed to another wallet", log.MapFields{
+			"call_type": "RebalanceTransaction",
+			"id":        id,
+			"wallet":    wallet,
+		})
+	}
 }
@@ -0,0 +1,13 @@
+package tx_test
+
+import (
+	"testing"
+
+	"github.com/oasislabs/oasis-gateway/tx/txbench"
+)
+
+func BenchmarkExecutor(b *testing.B) {
+	for _, scenario := range txbench.Scenarios {
+		b.Run(scenario.Name, txbench.Benchmark(scenario))
+	}
+}
@@ -0,0 +1,282 @@
+package tx
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/oasislabs/oasis-gateway/callback/callbacktest"
+	"github.com/oasislabs/oasis-gateway/errors"
+	"github.com/oasislabs/oasis-gateway/eth"
+	"github.com/oasislabs/oasis-gateway/eth/ethtest"
+	"github.com/oasislabs/oasis-gateway/stats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newTestExecutor(t *testing.T, client *ethtest.MockClient, lifetime time.Duration) *Executor {
+	callbackclient := &callbacktest.MockClient{}
+	callbacktest.ImplementMock(callbackclient)
+
+	executor, err := NewExecutor(context.Background(), &ExecutorServices{
+		Logger:    Logger,
+		Client:    client,
+		Callbacks: callbackclient,
+	}, &ExecutorProps{
+		PrivateKeys: []*ecdsa.PrivateKey{GetPrivateKey()},
+		MaxLifetime: lifetime,
+	})
+	assert.Nil(t, err)
+	return executor
+}
+
+func TestExecutorMaxLifetime(t *testing.T) {
+	mockclient := &ethtest.MockClient{}
+	ethtest.ImplementMockWithOverwrite(mockclient, ethtest.MockMethods{
+		"SendTransaction": {
+			Arguments: []interface{}{mock.Anything, mock.Anything},
+			Return:    []interface{}{eth.SendTransactionResponse{}, context.DeadlineExceeded},
+			Run: func(args mock.Arguments) {
+				// the node never responds for the transaction
+				<-args.Get(0).(context.Context).Done()
+			},
+		},
+	})
+	executor := newTestExecutor(t, mockclient, 10*time.Millisecond)
+
+	_, err := executor.Execute(context.Background(), ExecuteRequest{
+		Address: address,
+		Data:    []byte(""),
+	})
+
+	assert.Equal(t, errors.ErrTransactionTimeout, err.ErrorCode())
+	assert.Equal(t, uint64(1), executor.Stats()["expired"])
+}
+
+func TestExecutorMaxLifetimeNotExceeded(t *testing.T) {
+	mockclient := &ethtest.MockClient{}
+	ethtest.ImplementMock(mockclient)
+	executor := newTestExecutor(t, mockclient, time.Minute)
+
+	_, err := executor.Execute(context.Background(), ExecuteRequest{
+		Address: address,
+		Data:    []byte(""),
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0), executor.Stats()["expired"])
+	assert.Equal(t, int64(0), executor.Stats()["pending"])
+}
+
+func TestExecutorCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	mockclient := &ethtest.MockClient{}
+	ethtest.ImplementMockWithOverwrite(mockclient, ethtest.MockMethods{
+		"SendTransaction": {
+			Arguments: []interface{}{mock.Anything, mock.Anything},
+			Return:    []interface{}{eth.SendTransactionResponse{}, context.Canceled},
+			Run: func(args mock.Arguments) {
+				// the caller gives up while the transaction is sent
+				cancel()
+				<-args.Get(0).(context.Context).Done()
+			},
+		},
+	})
+	executor := newTestExecutor(t, mockclient, time.Minute)
+
+	_, err := executor.Execute(ctx, ExecuteRequest{
+		Address: address,
+		Data:    []byte(""),
+	})
+
+	assert.Equal(t, errors.ErrRequestCancelled, err.ErrorCode())
+	assert.Equal(t, uint64(0), executor.Stats()["expired"])
+}
+
+func TestExecutorBurstOnOneWallet(t *testing.T) {
+	release := make(chan struct{})
+	mockclient := &ethtest.MockClient{}
+	ethtest.ImplementMockWithOverwrite(mockclient, ethtest.MockMethods{
+		"SendTransaction": {
+			Arguments: []interface{}{mock.Anything, mock.Anything},
+			Return:    []interface{}{eth.SendTransactionResponse{Status: 1}, nil},
+			Run: func(args mock.Arguments) {
+				// the wallet is busy until all the transactions are queued
+				<-release
+			},
+		},
+	})
+	executor := newTestExecutor(t, mockclient, time.Minute)
+	defer func() { _ = executor.Close() }()
+
+	// a burst of transactions on a busy wallet waits
+	// for the wallet instead of failing
+	var wg sync.WaitGroup
+	failed := int32(0)
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := executor.Execute(context.Background(), ExecuteRequest{
+				Address: address,
+				Data:    []byte(""),
+			}); err != nil {
+				atomic.AddInt32(&failed, 1)
+			}
+		}()
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(0), atomic.LoadInt32(&failed))
+}
+
+func TestExecutorCloseDestroysKeys(t *testing.T) {
+	mockclient := &ethtest.MockClient{}
+	ethtest.ImplementMock(mockclient)
+	executor := newTestExecutor(t, mockclient, time.Minute)
+
+	assert.Nil(t, executor.Close())
+	for _, key := range executor.keys {
+		assert.True(t, key.Destroyed())
+	}
+}
+
+func TestExecutorAccounts(t *testing.T) {
+	mockclient := &ethtest.MockClient{}
+	ethtest.ImplementMock(mockclient)
+	callbackclient := &callbacktest.MockClient{}
+	callbacktest.ImplementMock(callbackclient)
+
+	accountKey, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	accountAddress := crypto.PubkeyToAddress(accountKey.PublicKey).Hex()
+	defaultAddress := crypto.PubkeyToAddress(GetPrivateKey().PublicKey).Hex()
+
+	executor, err := NewExecutor(context.Background(), &ExecutorServices{
+		Logger:    Logger,
+		Client:    mockclient,
+		Callbacks: callbackclient,
+	}, &ExecutorProps{
+		PrivateKeys: []*ecdsa.PrivateKey{GetPrivateKey()},
+		Accounts:    map[string][]*ecdsa.PrivateKey{"team": {accountKey}},
+		AccountAADs: map[string]string{"aad-team": "team"},
+	})
+	assert.Nil(t, err)
+	defer func() { _ = executor.Close() }()
+	assert.Len(t, executor.WalletAddresses, 2)
+
+	for _, aad := range []string{"aad-team", "aad-team", "aad-other"} {
+		_, err := executor.Execute(context.Background(), ExecuteRequest{
+			AAD:     aad,
+			Address: address,
+			Data:    []byte(""),
+		})
+		assert.Nil(t, err)
+	}
+
+	metrics := executor.Stats()
+	transactions := func(metrics stats.Metrics, address string) interface{} {
+		return metrics[address].(stats.Metrics)["transactions"].(map[string]interface{})["ok"]
+	}
+	assert.Equal(t, uint64(1), transactions(metrics, defaultAddress))
+	account := metrics["accounts"].(stats.Metrics)["team"].(stats.Metrics)
+	assert.Equal(t, uint64(2), transactions(account, accountAddress))
+}
+
+func TestExecutorAccountUnknown(t *testing.T) {
+	mockclient := &ethtest.MockClient{}
+	ethtest.ImplementMock(mockclient)
+
+	_, err := NewExecutor(context.Background(), &ExecutorServices{
+		Logger: Logger,
+		Client: mockclient,
+	}, &ExecutorProps{
+		PrivateKeys: []*ecdsa.PrivateKey{GetPrivateKey()},
+		AccountAADs: map[string]string{"aad-team": "team"},
+	})
+	assert.Error(t, err)
+}
+
+func TestExecutorSelectionRebalancesDrainedWallet(t *testing.T) {
+	drainedKey, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	drainedAddress := crypto.PubkeyToAddress(drainedKey.PublicKey)
+	fundedAddress := crypto.PubkeyToAddress(GetPrivateKey().PublicKey)
+
+	mockclient := &ethtest.MockClient{}
+	mockclient.On("BalanceAt", mock.Anything, drainedAddress, mock.Anything).
+		Return(big.NewInt(1), nil)
+	mockclient.On("BalanceAt", mock.Anything, fundedAddress, mock.Anything).
+		Return(big.NewInt(1000000000000000000), nil)
+	ethtest.ImplementMock(mockclient)
+	callbackclient := &callbacktest.MockClient{}
+	callbacktest.ImplementMock(callbackclient)
+
+	executor, err := NewExecutor(context.Background(), &ExecutorServices{
+		Logger:    Logger,
+		Client:    mockclient,
+		Callbacks: callbackclient,
+	}, &ExecutorProps{
+		PrivateKeys: []*ecdsa.PrivateKey{drainedKey, GetPrivateKey()},
+		Selection: WalletSelectorProps{
+			Selection:    WalletSelectionRoundRobin,
+			DrainedRetry: time.Hour,
+		},
+	})
+	assert.Nil(t, err)
+	defer func() { _ = executor.Close() }()
+
+	for i := 0; i < 3; i++ {
+		_, err := executor.Execute(context.Background(), ExecuteRequest{
+			Address: address,
+			Data:    []byte(""),
+		})
+		assert.Nil(t, err)
+	}
+
+	metrics := executor.Stats()
+	selector := metrics["selector"].(stats.Metrics)
+	assert.Equal(t, uint64(1), selector["rebalanced"])
+	assert.Equal(t, uint64(3),
+		metrics[fundedAddress.Hex()].(stats.Metrics)["transactions"].(map[string]interface{})["ok"])
+	assert.Equal(t, uint64(1),
+		metrics[drainedAddress.Hex()].(stats.Metrics)["transactions"].(map[string]interface{})["error"])
+}
+
+func TestExecutorSelectionAllDrained(t *testing.T) {
+	mockclient := &ethtest.MockClient{}
+	ethtest.ImplementMockWithOverwrite(mockclient, ethtest.MockMethods{
+		"BalanceAt": {
+			Arguments: []interface{}{mock.Anything, mock.Anything, mock.Anything},
+			Return:    []interface{}{big.NewInt(1), nil},
+		},
+	})
+	callbackclient := &callbacktest.MockClient{}
+	callbacktest.ImplementMock(callbackclient)
+
+	executor, err := NewExecutor(context.Background(), &ExecutorServices{
+		Logger:    Logger,
+		Client:    mockclient,
+		Callbacks: callbackclient,
+	}, &ExecutorProps{
+		PrivateKeys: []*ecdsa.PrivateKey{GetPrivateKey()},
+		Selection:   WalletSelectorProps{Selection: WalletSelectionLeastPending},
+	})
+	assert.Nil(t, err)
+	defer func() { _ = executor.Close() }()
+
+	_, eerr := executor.Execute(context.Background(), ExecuteRequest{
+		Address: address,
+		Data:    []byte(""),
+	})
+
+	assert.Equal(t, errors.ErrInsufficientGatewayFunds.Code(), eerr.ErrorCode().Code())
+}
@@ -0,0 +1,45 @@
+package tx
+
+import (
+	"fmt"
+
+	"github.com/oasislabs/oasis-gateway/errors"
+)
+
+// DefaultConfidentialGasLimit is the gas limit used if none is
+// configured for the transactions whose gas cannot be estimated
+const DefaultConfidentialGasLimit uint64 = 15177522
+
+// GasLimitProps defines the gas limit of the transactions
+// submitted by the wallet owners
+type GasLimitProps struct {
+	// Confidential is the gas limit of the transactions to services,
+	// whose gas cannot be estimated because they may be confidential.
+	// If it is 0 DefaultConfidentialGasLimit is used
+	Confidential uint64
+
+	// Max is the upper bound of the gas limit of a transaction.
+	// Transactions that require more gas are rejected before they
+	// are signed. If it is 0 the gas limit is not bounded
+	Max uint64
+}
+
+// confidential returns the gas limit of the transactions
+// whose gas cannot be estimated
+func (p GasLimitProps) confidential() uint64 {
+	if p.Confidential == 0 {
+		return DefaultConfidentialGasLimit
+	}
+
+	return p.Confidential
+}
+
+// check verifies that the gas limit does not exceed the maximum
+func (p GasLimitProps) check(gas uint64) errors.Err {
+	if p.Max > 0 && gas > p.Max {
+		return errors.New(errors.ErrGasLimitExceeded,
+			fmt.Errorf("transaction requires %d gas and at most %d is allowed", gas, p.Max))
+	}
+
+	return nil
+}
@@ -0,0 +1,364 @@
+package tx
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/oasislabs/oasis-gateway/errors"
+	"github.com/oasislabs/oasis-gateway/eth"
+)
+
+// GasPriceMode defines how the gas price of a transaction is chosen
+type GasPriceMode string
+
+const (
+	// GasPriceModeFixed uses the same gas price for all the transactions
+	GasPriceModeFixed GasPriceMode = "fixed"
+
+	// GasPriceModeNode uses the gas price suggested by the node
+	GasPriceModeNode GasPriceMode = "node"
+
+	// GasPriceModePercentile uses a percentile of the gas prices of
+	// the transactions included in the most recent blocks
+	GasPriceModePercentile GasPriceMode = "percentile"
+)
+
+func (m GasPriceMode) String() string {
+	return string(m)
+}
+
+// DefaultGasPrice is the gas price used if none is configured
+const DefaultGasPrice uint64 = 1000000000
+
+// DefaultPriorityFee is the maxPriorityFeePerGas of the dynamic
+// fee transactions used if none is configured
+const DefaultPriorityFee uint64 = 1000000000
+
+// GasPriceRequest is the request for the gas price of a transaction
+type GasPriceRequest struct {
+	// Elapsed is the time since the wallet owner started
+	// submitting the transaction
+	Elapsed time.Duration
+}
+
+// GasPriceStrategy chooses the gas price of the transactions
+// submitted by the wallet owners
+type GasPriceStrategy interface {
+	GasPrice(ctx context.Context, req GasPriceRequest) (*big.Int, error)
+}
+
+// GasPriceProps are the properties used to create a GasPriceStrategy
+type GasPriceProps struct {
+	// Mode defines how the gas price is chosen. If not
+	// set GasPriceModeFixed is used
+	Mode GasPriceMode
+
+	// Price is the gas price of GasPriceModeFixed, also used by
+	// GasPriceModePercentile when the recent blocks have no
+	// transactions. If it is 0 DefaultGasPrice is used
+	Price uint64
+
+	// Percentile of the gas prices of the recent transactions
+	// used by GasPriceModePercentile
+	Percentile float64
+
+	// Blocks is the number of recent blocks whose transactions
+	// are used by GasPriceModePercentile
+	Blocks uint64
+
+	// EscalationInterval if set increases the gas price by
+	// EscalationFactor for each interval the wallet owner has
+	// been submitting a transaction. The wallet owners replace
+	// the transactions that are not mined at each interval
+	EscalationInterval time.Duration
+
+	// EscalationFactor multiplies the gas price at each
+	// EscalationInterval
+	EscalationFactor float64
+
+	// Max is the upper bound of the gas price. If it is 0
+	// the gas price is not bounded
+	Max uint64
+
+	// DynamicFees if set submits EIP-1559 dynamic fee transactions
+	// on the chains that support them. Their maxFeePerGas is the gas
+	// price chosen by the strategy
+	DynamicFees bool
+
+	// PriorityFee is the maxPriorityFeePerGas of the dynamic fee
+	// transactions, bounded by their maxFeePerGas. If it is 0
+	// DefaultPriorityFee is used
+	PriorityFee uint64
+}
+
+// DynamicFees define the EIP-1559 dynamic fee transactions
+// submitted by a WalletOwner
+type DynamicFees struct {
+	// ChainID is the chain the transactions are signed for
+	ChainID *big.Int
+
+	// PriorityFee is the maxPriorityFeePerGas of the transactions.
+	// Their maxFeePerGas is the gas price chosen by the
+	// GasPriceStrategy of the WalletOwner
+	PriorityFee *big.Int
+}
+
+// NewDynamicFees creates the DynamicFees defined by props for the
+// chain. It returns nil if dynamic fees are not enabled or if the
+// chain does not support them, in which case legacy transactions
+// are submitted
+func NewDynamicFees(chainID uint64, props GasPriceProps) *DynamicFees {
+	if !props.DynamicFees || chainID == 0 {
+		return nil
+	}
+
+	fee := props.PriorityFee
+	if fee == 0 {
+		fee = DefaultPriorityFee
+	}
+
+	return &DynamicFees{
+		ChainID:     new(big.Int).SetUint64(chainID),
+		PriorityFee: new(big.Int).SetUint64(fee),
+	}
+}
+
+// NewGasPriceStrategy creates the GasPriceStrategy defined by props
+func NewGasPriceStrategy(client eth.Client, props GasPriceProps) GasPriceStrategy {
+	price := props.Price
+	if price == 0 {
+		price = DefaultGasPrice
+	}
+	fixed := FixedGasPrice{Price: new(big.Int).SetUint64(price)}
+
+	var strategy GasPriceStrategy
+	switch props.Mode {
+	case GasPriceModeNode:
+		strategy = NodeGasPrice{Client: client}
+	case GasPriceModePercentile:
+		strategy = NewPercentileGasPrice(client, PercentileGasPriceProps{
+			Percentile: props.Percentile,
+			Blocks:     props.Blocks,
+			Fallback:   fixed,
+		})
+	default:
+		strategy = fixed
+	}
+
+	if props.EscalationInterval > 0 {
+		strategy = EscalatingGasPrice{
+			Strategy: strategy,
+			Interval: props.EscalationInterval,
+			Factor:   props.EscalationFactor,
+		}
+	}
+
+	if props.Max > 0 {
+		strategy = maxGasPrice{
+			strategy: strategy,
+			max:      new(big.Int).SetUint64(props.Max),
+		}
+	}
+
+	return strategy
+}
+
+// FixedGasPrice uses the same gas price for all the transactions
+type FixedGasPrice struct {
+	Price *big.Int
+}
+
+// GasPrice is the implementation of GasPriceStrategy for FixedGasPrice
+func (s FixedGasPrice) GasPrice(ctx context.Context, req GasPriceRequest) (*big.Int, error) {
+	return new(big.Int).Set(s.Price), nil
+}
+
+// NodeGasPrice uses the gas price suggested by the node
+type NodeGasPrice struct {
+	Client eth.Client
+}
+
+// GasPrice is the implementation of GasPriceStrategy for NodeGasPrice
+func (s NodeGasPrice) GasPrice(ctx context.Context, req GasPriceRequest) (*big.Int, error) {
+	return s.Client.SuggestGasPrice(ctx)
+}
+
+// PercentileGasPriceProps are the properties used
+// to create a PercentileGasPrice
+type PercentileGasPriceProps struct {
+	// Percentile of the gas prices that is used
+	Percentile float64
+
+	// Blocks is the number of recent blocks whose
+	// transactions are used
+	Blocks uint64
+
+	// Fallback is used when the recent blocks have no transactions
+	Fallback GasPriceStrategy
+}
+
+// PercentileGasPrice uses a percentile of the gas prices of the
+// transactions included in the most recent blocks. The gas prices
+// of a block are only fetched once
+type PercentileGasPrice struct {
+	mu         sync.Mutex
+	client     eth.Client
+	percentile float64
+	blocks     uint64
+	fallback   GasPriceStrategy
+	prices     map[uint64][]*big.Int
+}
+
+// NewPercentileGasPrice creates a new PercentileGasPrice
+func NewPercentileGasPrice(client eth.Client, props PercentileGasPriceProps) *PercentileGasPrice {
+	blocks := props.Blocks
+	if blocks == 0 {
+		blocks = 1
+	}
+
+	return &PercentileGasPrice{
+		client:     client,
+		percentile: props.Percentile,
+		blocks:     blocks,
+		fallback:   props.Fallback,
+		prices:     make(map[uint64][]*big.Int, blocks),
+	}
+}
+
+// GasPrice is the implementation of GasPriceStrategy for PercentileGasPrice
+func (s *PercentileGasPrice) GasPrice(ctx context.Context, req GasPriceRequest) (*big.Int, error) {
+	prices, err := s.recentGasPrices(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(prices) == 0 {
+		return s.fallback.GasPrice(ctx, req)
+	}
+
+	sort.Slice(prices, func(i, j int) bool {
+		return prices[i].Cmp(prices[j]) < 0
+	})
+
+	i := int(math.Ceil(s.percentile*float64(len(prices)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	return new(big.Int).Set(prices[i]), nil
+}
+
+// recentGasPrices returns the gas prices of the transactions
+// of the recent blocks, fetching those of the blocks that
+// were not fetched yet
+func (s *PercentileGasPrice) recentGasPrices(ctx context.Context) ([]*big.Int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	latest, err := s.client.BlockGasPrices(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	s.prices[latest.BlockNumber] = latest.GasPrices
+
+	first := uint64(0)
+	if latest.BlockNumber >= s.blocks {
+		first = latest.BlockNumber - s.blocks + 1
+	}
+
+	var prices []*big.Int
+	for number := first; number <= latest.BlockNumber; number++ {
+		block, ok := s.prices[number]
+		if !ok {
+			res, err := s.client.BlockGasPrices(ctx, new(big.Int).SetUint64(number))
+			if err != nil {
+				return nil, err
+			}
+			block = res.GasPrices
+			s.prices[number] = block
+		}
+		prices = append(prices, block...)
+	}
+
+	for number := range s.prices {
+		if number < first {
+			delete(s.prices, number)
+		}
+	}
+
+	return prices, nil
+}
+
+// EscalatingGasPrice increases the gas price chosen by another
+// strategy the longer the wallet owner has been submitting the
+// transaction. The wallet owner replaces a transaction that has
+// not been mined at each interval, so that it is included at a
+// price more likely to be accepted
+type EscalatingGasPrice struct {
+	// Strategy chooses the gas price that is escalated
+	Strategy GasPriceStrategy
+
+	// Interval is the time after which the gas price escalates
+	Interval time.Duration
+
+	// Factor multiplies the gas price at each Interval
+	Factor float64
+}
+
+// GasPrice is the implementation of GasPriceStrategy for EscalatingGasPrice
+func (s EscalatingGasPrice) GasPrice(ctx context.Context, req GasPriceRequest) (*big.Int, error) {
+	price, err := s.Strategy.GasPrice(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	steps := float64(req.Elapsed / s.Interval)
+	if steps == 0 || s.Factor <= 1 {
+		return price, nil
+	}
+
+	// the gas price is expected to be bounded by a maximum, so an
+	// escalation that overflows is bounded rather than failed
+	factor := math.Pow(s.Factor, steps)
+	if math.IsInf(factor, 1) {
+		factor = math.MaxFloat64
+	}
+
+	escalated := new(big.Float).SetInt(price)
+	escalated.Mul(escalated, big.NewFloat(factor))
+	price, _ = escalated.Int(nil)
+	return price, nil
+}
+
+// maxGasPrice bounds the gas price chosen by another strategy
+type maxGasPrice struct {
+	strategy GasPriceStrategy
+	max      *big.Int
+}
+
+func (s maxGasPrice) GasPrice(ctx context.Context, req GasPriceRequest) (*big.Int, error) {
+	price, err := s.strategy.GasPrice(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if price.Cmp(s.max) > 0 {
+		return new(big.Int).Set(s.max), nil
+	}
+	return price, nil
+}
+
+// checkGasPrice verifies that the gas price requested for a transaction
+// does not exceed the maximum. If max is 0 the gas price is not bounded
+func checkGasPrice(price, max uint64) errors.Err {
+	if max > 0 && price > max {
+		return errors.New(errors.ErrGasPriceExceeded,
+			fmt.Errorf("transaction requests a gas price of %d and at most %d is allowed", price, max))
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package tx
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/oasislabs/oasis-gateway/eth"
+	"github.com/oasislabs/oasis-gateway/eth/ethtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func gasPrices(prices ...int64) []*big.Int {
+	res := make([]*big.Int, 0, len(prices))
+	for _, price := range prices {
+		res = append(res, big.NewInt(price))
+	}
+	return res
+}
+
+func TestGasPriceFixedDefault(t *testing.T) {
+	strategy := NewGasPriceStrategy(nil, GasPriceProps{})
+
+	price, err := strategy.GasPrice(context.Background(), GasPriceRequest{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, new(big.Int).SetUint64(DefaultGasPrice), price)
+}
+
+func TestGasPriceNode(t *testing.T) {
+	mockclient := &ethtest.MockClient{}
+	ethtest.ImplementMockWithOverwrite(mockclient, ethtest.MockMethods{
+		"SuggestGasPrice": {
+			Arguments: []interface{}{mock.Anything},
+			Return:    []interface{}{big.NewInt(5), nil},
+		},
+	})
+	strategy := NewGasPriceStrategy(mockclient, GasPriceProps{Mode: GasPriceModeNode})
+
+	price, err := strategy.GasPrice(context.Background(), GasPriceRequest{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, big.NewInt(5), price)
+}
+
+func TestGasPricePercentile(t *testing.T) {
+	mockclient := &ethtest.MockClient{}
+	mockclient.On("BlockGasPrices", mock.Anything, (*big.Int)(nil)).
+		Return(eth.BlockGasPrices{BlockNumber: 10, GasPrices: gasPrices(8, 1)}, nil)
+	mockclient.On("BlockGasPrices", mock.Anything, big.NewInt(9)).
+		Return(eth.BlockGasPrices{BlockNumber: 9, GasPrices: gasPrices(4, 2, 6)}, nil).Once()
+	mockclient.On("BlockGasPrices", mock.Anything, big.NewInt(8)).
+		Return(eth.BlockGasPrices{BlockNumber: 8, GasPrices: gasPrices(10)}, nil).Once()
+	strategy := NewGasPriceStrategy(mockclient, GasPriceProps{
+		Mode:       GasPriceModePercentile,
+		Percentile: 0.5,
+		Blocks:     3,
+	})
+
+	price, err := strategy.GasPrice(context.Background(), GasPriceRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, big.NewInt(4), price)
+
+	// the prices of the blocks already fetched are not fetched again
+	price, err = strategy.GasPrice(context.Background(), GasPriceRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, big.NewInt(4), price)
+	mockclient.AssertNumberOfCalls(t, "BlockGasPrices", 4)
+}
+
+func TestGasPricePercentileNoTransactions(t *testing.T) {
+	mockclient := &ethtest.MockClient{}
+	ethtest.ImplementMock(mockclient)
+	strategy := NewGasPriceStrategy(mockclient, GasPriceProps{
+		Mode:       GasPriceModePercentile,
+		Price:      7,
+		Percentile: 0.5,
+		Blocks:     3,
+	})
+
+	price, err := strategy.GasPrice(context.Background(), GasPriceRequest{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, big.NewInt(7), price)
+}
+
+func TestGasPriceEscalating(t *testing.T) {
+	strategy := NewGasPriceStrategy(nil, GasPriceProps{
+		Price:              100,
+		EscalationInterval: time.Second,
+		EscalationFactor:   1.5,
+		Max:                300,
+	})
+
+	for _, c := range []struct {
+		elapsed time.Duration
+		price   int64
+	}{
+		{0, 100},
+		{999 * time.Millisecond, 100},
+		{time.Second, 150},
+		{2 * time.Second, 225},
+		{3 * time.Second, 300},
+		{time.Hour, 300},
+	} {
+		price, err := strategy.GasPrice(context.Background(), GasPriceRequest{Elapsed: c.elapsed})
+		assert.Nil(t, err)
+		assert.Equal(t, big.NewInt(c.price), price, c.elapsed)
+	}
+}
@@ -0,0 +1,168 @@
+package tx
+
+import (
+	"crypto/ecdsa"
+	"encoding/hex"
+	stderr "errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"unsafe"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/oasislabs/oasis-gateway/eth"
+)
+
+var (
+	ErrInvalidPrivateKey = stderr.New("private key is not a valid hex encoded secp256k1 key")
+	ErrKeyDestroyed      = stderr.New("private key has been destroyed")
+)
+
+// ParsePrivateKey parses a hex encoded private key. Unlike
+// crypto.HexToECDSA, the intermediate buffers are zeroized and the
+// errors returned never include any part of the key
+func ParsePrivateKey(s string) (*ecdsa.PrivateKey, error) {
+	b, err := hex.DecodeString(s)
+	defer zeroize(b)
+	if err != nil {
+		return nil, ErrInvalidPrivateKey
+	}
+
+	key, err := crypto.ToECDSA(b)
+	if err != nil {
+		return nil, ErrInvalidPrivateKey
+	}
+
+	return key, nil
+}
+
+// SecureKey holds the private key of a wallet. The key is kept in
+// locked memory where the platform allows it, so that it is not
+// written to swap, and it is zeroized once the key is destroyed. A
+// SecureKey never formats the private key, it only prints the address
+// of the key, so it can be safely logged or included in errors
+type SecureKey struct {
+	address common.Address
+	locked  bool
+
+	lock sync.RWMutex
+	key  *ecdsa.PrivateKey
+}
+
+// NewSecureKey creates a new SecureKey that takes ownership of the
+// private key. The private key must not be used once the SecureKey
+// is destroyed
+func NewSecureKey(key *ecdsa.PrivateKey) *SecureKey {
+	return &SecureKey{
+		address: crypto.PubkeyToAddress(key.PublicKey),
+		locked:  lockMemory(wordsBytes(key.D.Bits())),
+		key:     key,
+	}
+}
+
+// Address returns the address derived from the key
+func (k *SecureKey) Address() common.Address {
+	return k.address
+}
+
+// Locked returns true if the key is kept in memory that cannot
+// be swapped out
+func (k *SecureKey) Locked() bool {
+	return k.locked
+}
+
+// Destroyed returns true if the key has been destroyed
+func (k *SecureKey) Destroyed() bool {
+	k.lock.RLock()
+	defer k.lock.RUnlock()
+	return k.key == nil
+}
+
+// SignTx signs the transaction with the key
+func (k *SecureKey) SignTx(tx *types.Transaction, signer types.Signer) (*types.Transaction, error) {
+	k.lock.RLock()
+	defer k.lock.RUnlock()
+
+	if k.key == nil {
+		return nil, ErrKeyDestroyed
+	}
+
+	return types.SignTx(tx, signer, k.key)
+}
+
+// SignDynamicFeeTx signs the dynamic fee transaction with the key
+func (k *SecureKey) SignDynamicFeeTx(tx *eth.DynamicFeeTransaction) (*eth.DynamicFeeTransaction, error) {
+	k.lock.RLock()
+	defer k.lock.RUnlock()
+
+	if k.key == nil {
+		return nil, ErrKeyDestroyed
+	}
+
+	hash := tx.SigningHash()
+	sig, err := crypto.Sign(hash[:], k.key)
+	if err != nil {
+		return nil, err
+	}
+
+	return tx.WithSignature(sig)
+}
+
+// Destroy zeroizes the private key. It waits for the signatures in
+// progress to complete, after which the key cannot be used anymore.
+// Destroying a key more than once has no effect
+func (k *SecureKey) Destroy() {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	if k.key == nil {
+		return
+	}
+
+	// the memory is not unlocked because the locked pages
+	// may be shared with other keys
+	zeroize(wordsBytes(k.key.D.Bits()))
+	k.key.D.SetInt64(0)
+	k.key = nil
+}
+
+// String is the implementation of fmt.Stringer for SecureKey
+func (k *SecureKey) String() string {
+	return fmt.Sprintf("SecureKey(%s)", k.address.Hex())
+}
+
+// GoString is the implementation of fmt.GoStringer for SecureKey
+func (k *SecureKey) GoString() string {
+	return k.String()
+}
+
+// Format is the implementation of fmt.Formatter for SecureKey, so
+// that no verb can print the fields of the key
+func (k *SecureKey) Format(f fmt.State, verb rune) {
+	_, _ = f.Write([]byte(k.String()))
+}
+
+// MarshalText is the implementation of encoding.TextMarshaler for
+// SecureKey, so that encoders only output the address of the key
+func (k *SecureKey) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+func zeroize(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+// wordsBytes returns the memory of the words of a big.Int as bytes
+func wordsBytes(words []big.Word) []byte {
+	if len(words) == 0 {
+		return nil
+	}
+
+	size := int(unsafe.Sizeof(words[0]))
+	return (*[1 << 30]byte)(unsafe.Pointer(&words[0]))[: len(words)*size : len(words)*size]
+}
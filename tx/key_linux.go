@@ -0,0 +1,14 @@
+package tx
+
+import "golang.org/x/sys/unix"
+
+// lockMemory prevents the memory from being swapped out. It returns
+// false if the memory could not be locked, for instance because the
+// process exceeds RLIMIT_MEMLOCK
+func lockMemory(b []byte) bool {
+	if len(b) == 0 {
+		return false
+	}
+
+	return unix.Mlock(b) == nil
+}
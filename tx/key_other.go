@@ -0,0 +1,10 @@
+//go:build !linux
+// +build !linux
+
+package tx
+
+// lockMemory is not supported on this platform, so the
+// memory of the keys may be swapped out
+func lockMemory(b []byte) bool {
+	return false
+}
@@ -0,0 +1,67 @@
+package tx
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePrivateKey(t *testing.T) {
+	key, err := ParsePrivateKey(PrivateKey)
+	assert.Nil(t, err)
+	assert.Equal(t, GetPrivateKey().D, key.D)
+}
+
+func TestParsePrivateKeyErrNoKeyMaterial(t *testing.T) {
+	invalid := strings.Repeat("1", 62) + "zz"
+	_, err := ParsePrivateKey(invalid)
+	assert.Equal(t, ErrInvalidPrivateKey, err)
+	assert.NotContains(t, err.Error(), "zz")
+
+	_, err = ParsePrivateKey(strings.Repeat("0", 64))
+	assert.Equal(t, ErrInvalidPrivateKey, err)
+}
+
+func TestSecureKeyFormatNoKeyMaterial(t *testing.T) {
+	privateKey := GetPrivateKey()
+	key := NewSecureKey(privateKey)
+	expected := "SecureKey(" + key.Address().Hex() + ")"
+
+	for _, verb := range []string{"%s", "%v", "%+v", "%#v", "%x", "%d"} {
+		assert.Equal(t, expected, fmt.Sprintf(verb, key), verb)
+	}
+
+	p, err := json.Marshal(struct{ Key *SecureKey }{key})
+	assert.Nil(t, err)
+	assert.Equal(t, `{"Key":"`+expected+`"}`, string(p))
+	assert.NotContains(t, string(p), privateKey.D.Text(16))
+}
+
+func TestSecureKeyDestroy(t *testing.T) {
+	privateKey := GetPrivateKey()
+	key := NewSecureKey(privateKey)
+	addr := key.Address()
+	wallet := NewWalletWithKey(key, types.FrontierSigner{})
+	tx := types.NewTransaction(0, common.HexToAddress(address), big.NewInt(0), 1000000, big.NewInt(1), nil)
+
+	_, signErr := wallet.SignTransaction(tx)
+	assert.Nil(t, signErr)
+
+	key.Destroy()
+	key.Destroy()
+	assert.True(t, key.Destroyed())
+	assert.Equal(t, 0, privateKey.D.Sign())
+	assert.Equal(t, addr, key.Address())
+
+	_, signErr = wallet.SignTransaction(tx)
+	assert.Error(t, signErr)
+
+	_, err := key.SignTx(tx, types.FrontierSigner{})
+	assert.Equal(t, ErrKeyDestroyed, err)
+}
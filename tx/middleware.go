@@ -0,0 +1,163 @@
+package tx
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/oasislabs/oasis-gateway/eth"
+)
+
+// TransactionInfo describes the transaction being processed by a
+// WalletOwner so that a Middleware can make decisions about it
+type TransactionInfo struct {
+	// AAD is the identifier of the original issuer for the transaction data
+	AAD string
+
+	// ID of the request that triggered the transaction
+	ID uint64
+
+	// Sender is the address of the wallet that signs the transaction
+	Sender common.Address
+
+	// Address to which the transaction is sent. It is empty
+	// for a service deployment
+	Address string
+
+	// Gas is the gas limit set for the transaction
+	Gas uint64
+
+	// Data is the payload of the transaction
+	Data []byte
+}
+
+// Middleware is a stage in the processing of a transaction by a WalletOwner.
+// Middleware allows adding functionality such as quotas, auditing or policy
+// checks without modifying the WalletOwner
+type Middleware interface {
+	// PreSign is called before a transaction is generated and signed. If
+	// an error is returned the transaction is rejected
+	PreSign(ctx context.Context, info *TransactionInfo) error
+
+	// PostSign is called once the transaction has been signed and before
+	// it is sent. If an error is returned the transaction is rejected. The
+	// transaction is a *types.Transaction, or a *eth.DynamicFeeTransaction
+	// if the wallet owner submits dynamic fee transactions
+	PostSign(ctx context.Context, info *TransactionInfo, tx eth.Transaction) error
+
+	// PostSend is called once the transaction has been sent with the
+	// result of the submission. err is nil if the submission succeeded
+	PostSend(ctx context.Context, info *TransactionInfo, res eth.SendTransactionResponse, err error)
+}
+
+// NopMiddleware implements Middleware without taking any action. It
+// can be embedded by types that only need to implement some of the stages
+type NopMiddleware struct{}
+
+// PreSign is the implementation of Middleware for NopMiddleware
+func (NopMiddleware) PreSign(context.Context, *TransactionInfo) error {
+	return nil
+}
+
+// PostSign is the implementation of Middleware for NopMiddleware
+func (NopMiddleware) PostSign(context.Context, *TransactionInfo, eth.Transaction) error {
+	return nil
+}
+
+// PostSend is the implementation of Middleware for NopMiddleware
+func (NopMiddleware) PostSend(context.Context, *TransactionInfo, eth.SendTransactionResponse, error) {
+}
+
+// PreSignFunc allows a function to act as a Middleware for the
+// PreSign stage
+type PreSignFunc func(ctx context.Context, info *TransactionInfo) error
+
+// PreSign is the implementation of Middleware for PreSignFunc
+func (f PreSignFunc) PreSign(ctx context.Context, info *TransactionInfo) error {
+	return f(ctx, info)
+}
+
+// PostSign is the implementation of Middleware for PreSignFunc
+func (f PreSignFunc) PostSign(context.Context, *TransactionInfo, eth.Transaction) error {
+	return nil
+}
+
+// PostSend is the implementation of Middleware for PreSignFunc
+func (f PreSignFunc) PostSend(context.Context, *TransactionInfo, eth.SendTransactionResponse, error) {
+}
+
+// PostSignFunc allows a function to act as a Middleware for the
+// PostSign stage
+type PostSignFunc func(ctx context.Context, info *TransactionInfo, tx eth.Transaction) error
+
+// PreSign is the implementation of Middleware for PostSignFunc
+func (f PostSignFunc) PreSign(context.Context, *TransactionInfo) error {
+	return nil
+}
+
+// PostSign is the implementation of Middleware for PostSignFunc
+func (f PostSignFunc) PostSign(ctx context.Context, info *TransactionInfo, tx eth.Transaction) error {
+	return f(ctx, info, tx)
+}
+
+// PostSend is the implementation of Middleware for PostSignFunc
+func (f PostSignFunc) PostSend(context.Context, *TransactionInfo, eth.SendTransactionResponse, error) {
+}
+
+// PostSendFunc allows a function to act as a Middleware for the
+// PostSend stage
+type PostSendFunc func(ctx context.Context, info *TransactionInfo, res eth.SendTransactionResponse, err error)
+
+// PreSign is the implementation of Middleware for PostSendFunc
+func (f PostSendFunc) PreSign(context.Context, *TransactionInfo) error {
+	return nil
+}
+
+// PostSign is the implementation of Middleware for PostSendFunc
+func (f PostSendFunc) PostSign(context.Context, *TransactionInfo, eth.Transaction) error {
+	return nil
+}
+
+// PostSend is the implementation of Middleware for PostSendFunc
+func (f PostSendFunc) PostSend(ctx context.Context, info *TransactionInfo, res eth.SendTransactionResponse, err error) {
+	f(ctx, info, res, err)
+}
+
+// MiddlewareChain composes multiple Middleware into a single one. Stages
+// are executed in the order in which the middleware were added to the
+// chain, and the first error returned by a stage halts the chain
+type MiddlewareChain []Middleware
+
+// NewMiddlewareChain creates a new chain from the provided middleware
+func NewMiddlewareChain(middleware ...Middleware) MiddlewareChain {
+	return MiddlewareChain(middleware)
+}
+
+// PreSign is the implementation of Middleware for MiddlewareChain
+func (c MiddlewareChain) PreSign(ctx context.Context, info *TransactionInfo) error {
+	for _, m := range c {
+		if err := m.PreSign(ctx, info); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// PostSign is the implementation of Middleware for MiddlewareChain
+func (c MiddlewareChain) PostSign(ctx context.Context, info *TransactionInfo, tx eth.Transaction) error {
+	for _, m := range c {
+		if err := m.PostSign(ctx, info, tx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// PostSend is the implementation of Middleware for MiddlewareChain
+func (c MiddlewareChain) PostSend(ctx context.Context, info *TransactionInfo, res eth.SendTransactionResponse, err error) {
+	for _, m := range c {
+		m.PostSend(ctx, info, res, err)
+	}
+}
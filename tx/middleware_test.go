@@ -0,0 +1,57 @@
+package tx
+
+import (
+	"context"
+	"testing"
+
+	stderr "github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/oasislabs/oasis-gateway/eth"
+)
+
+func TestMiddlewareChainOrder(t *testing.T) {
+	var stages []string
+	chain := NewMiddlewareChain(
+		PreSignFunc(func(context.Context, *TransactionInfo) error {
+			stages = append(stages, "presign1")
+			return nil
+		}),
+		PostSignFunc(func(context.Context, *TransactionInfo, eth.Transaction) error {
+			stages = append(stages, "postsign")
+			return nil
+		}),
+		PreSignFunc(func(context.Context, *TransactionInfo) error {
+			stages = append(stages, "presign2")
+			return nil
+		}),
+		PostSendFunc(func(context.Context, *TransactionInfo, eth.SendTransactionResponse, error) {
+			stages = append(stages, "postsend")
+		}),
+	)
+
+	ctx := context.Background()
+	assert.Nil(t, chain.PreSign(ctx, &TransactionInfo{}))
+	assert.Nil(t, chain.PostSign(ctx, &TransactionInfo{}, nil))
+	chain.PostSend(ctx, &TransactionInfo{}, eth.SendTransactionResponse{}, nil)
+
+	assert.Equal(t, []string{"presign1", "presign2", "postsign", "postsend"}, stages)
+}
+
+func TestMiddlewareChainHaltsOnErr(t *testing.T) {
+	called := false
+	chain := NewMiddlewareChain(
+		PreSignFunc(func(context.Context, *TransactionInfo) error {
+			return stderr.New("rejected")
+		}),
+		PreSignFunc(func(context.Context, *TransactionInfo) error {
+			called = true
+			return nil
+		}),
+	)
+
+	err := chain.PreSign(context.Background(), &TransactionInfo{})
+	assert.Error(t, err)
+	assert.Equal(t, "rejected", err.Error())
+	assert.False(t, called)
+}
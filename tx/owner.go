@@ -5,10 +5,12 @@ import (
 	"crypto/ecdsa"
 	"fmt"
 	"math/big"
+	"strings"
 	"time"
 
 	ethereum "github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
 	stderr "github.com/pkg/errors"
 
@@ -17,6 +19,7 @@ import (
 	"github.com/oasislabs/oasis-gateway/errors"
 	"github.com/oasislabs/oasis-gateway/eth"
 	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/oasislabs/oasis-gateway/slo"
 	"github.com/oasislabs/oasis-gateway/stats"
 )
 
@@ -40,8 +43,6 @@ type Callbacks interface {
 // for a transaction that succeeds
 const StatusOK = 1
 
-const gasPrice int64 = 1000000000
-
 var retryConfig = concurrent.RetryConfig{
 	Random:            false,
 	UnlimitedAttempts: false,
@@ -56,7 +57,7 @@ type signRequest struct {
 }
 
 type createOwnerRequest struct {
-	PrivateKey *ecdsa.PrivateKey
+	Key *SecureKey
 }
 
 type statsRequest struct{}
@@ -71,6 +72,16 @@ type WalletOwner struct {
 	currentBalance  *big.Int
 	startBalance    *big.Int
 	consumedBalance *big.Int
+	outputMode      OutputMode
+	retryConfig     concurrent.RetryConfig
+	transactions    *stats.CounterGroup
+	gasPrice        GasPriceStrategy
+	gasLimit        GasLimitProps
+	dynamicFees     *DynamicFees
+	replaceInterval time.Duration
+	maxGasPrice     uint64
+	middleware      Middleware
+	slow            slo.SlowProps
 	client          eth.Client
 	callbacks       Callbacks
 	logger          log.Logger
@@ -80,12 +91,55 @@ type WalletOwnerServices struct {
 	Client    eth.Client
 	Callbacks Callbacks
 	Logger    log.Logger
+
+	// Middleware is invoked at each stage of the processing of
+	// a transaction. It is optional
+	Middleware Middleware
+
+	// GasPrice chooses the gas price of the transactions. If not
+	// set DefaultGasPrice is used for all the transactions
+	GasPrice GasPriceStrategy
 }
 
 type WalletOwnerProps struct {
 	PrivateKey *ecdsa.PrivateKey
-	Signer     types.Signer
-	Nonce      uint64
+
+	// Key is the key of the wallet. If set it is used instead
+	// of PrivateKey
+	Key *SecureKey
+
+	Signer types.Signer
+	Nonce  uint64
+
+	// OutputMode defines how the output of a transaction is
+	// retrieved. If not set OutputModeInvoke is used
+	OutputMode OutputMode
+
+	// RetryConfig overrides the retry policy used when a transaction
+	// submission fails with a recoverable error. It is optional
+	RetryConfig *concurrent.RetryConfig
+
+	// Slow are the thresholds above which a transaction is logged
+	// with the time taken by each of its stages. It is optional
+	Slow slo.SlowProps
+
+	// GasLimit defines the gas limit of the transactions. By default
+	// DefaultConfidentialGasLimit is used for the transactions whose
+	// gas cannot be estimated and the gas limit is not bounded
+	GasLimit GasLimitProps
+
+	// DynamicFees if set are the EIP-1559 dynamic fee transactions
+	// the owner submits. Otherwise it submits legacy transactions
+	DynamicFees *DynamicFees
+
+	// ReplaceInterval if set is the time after which a transaction
+	// that has not been mined is replaced by a transaction with the
+	// same nonce at the gas price chosen again by the GasPriceStrategy
+	ReplaceInterval time.Duration
+
+	// MaxGasPrice is the upper bound of the gas price a request can
+	// set. If it is 0 the gas price is not bounded
+	MaxGasPrice uint64
 }
 
 // NewWalletOwner creates a new instance of a wallet
@@ -96,13 +150,48 @@ func NewWalletOwner(
 	services *WalletOwnerServices,
 	props *WalletOwnerProps,
 ) (*WalletOwner, error) {
-	wallet := NewWallet(props.PrivateKey, props.Signer)
+	outputMode := props.OutputMode
+	if len(outputMode) == 0 {
+		outputMode = OutputModeInvoke
+	}
+
+	middleware := services.Middleware
+	if middleware == nil {
+		middleware = NopMiddleware{}
+	}
+
+	gasPrice := services.GasPrice
+	if gasPrice == nil {
+		gasPrice = NewGasPriceStrategy(services.Client, GasPriceProps{})
+	}
+
+	config := retryConfig
+	if props.RetryConfig != nil {
+		config = *props.RetryConfig
+	}
+
+	key := props.Key
+	if key == nil {
+		key = NewSecureKey(props.PrivateKey)
+	}
+
+	wallet := NewWalletWithKey(key, props.Signer)
 	owner := &WalletOwner{
-		wallet:    wallet,
-		nonce:     props.Nonce,
-		client:    services.Client,
-		callbacks: services.Callbacks,
-		logger:    services.Logger.ForClass("tx", "WalletOwner"),
+		wallet:          wallet,
+		nonce:           props.Nonce,
+		outputMode:      outputMode,
+		retryConfig:     config,
+		transactions:    stats.NewCounterGroup("ok", "error"),
+		gasPrice:        gasPrice,
+		gasLimit:        props.GasLimit,
+		dynamicFees:     props.DynamicFees,
+		replaceInterval: props.ReplaceInterval,
+		maxGasPrice:     props.MaxGasPrice,
+		middleware:      middleware,
+		slow:            props.Slow,
+		client:          services.Client,
+		callbacks:       services.Callbacks,
+		logger:          services.Logger.ForClass("tx", "WalletOwner"),
 	}
 
 	if err := owner.updateBalance(ctx); err != nil {
@@ -151,7 +240,11 @@ func (e *WalletOwner) handle(ctx context.Context, ev concurrent.WorkerEvent) (in
 	case concurrent.ErrorWorkerEvent:
 		return e.handleErrorEvent(ctx, ev)
 	default:
-		panic("received unexpected event type")
+		e.logger.Warn(ctx, "received unexpected event", log.MapFields{
+			"call_type": "HandleEventFailure",
+			"type":      fmt.Sprintf("%T", ev),
+		})
+		return nil, concurrent.ErrUnexpectedEvent{Event: ev}
 	}
 }
 
@@ -162,9 +255,28 @@ func (e *WalletOwner) handleRequestEvent(ctx context.Context, ev concurrent.Requ
 	case statsRequest:
 		return e.getStats(ctx), nil
 	case ExecuteRequest:
-		return e.executeTransaction(ctx, req)
+		res, err := e.executeTransaction(ctx, req)
+		e.transactions.Incr(stats.ResultTypeBool(err == nil))
+		if err != nil {
+			return nil, err
+		}
+		return res, nil
+	case ExecuteBatchRequest:
+		results, err := e.executeBatch(ctx, req)
+		if err != nil {
+			e.transactions.Incr(stats.ResultTypeBool(false))
+			return nil, err
+		}
+		for _, res := range results {
+			e.transactions.Incr(stats.ResultTypeBool(res.Err == nil))
+		}
+		return results, nil
 	default:
-		panic("invalid request received for worker")
+		e.logger.Warn(ctx, "received unexpected request", log.MapFields{
+			"call_type": "HandleRequestFailure",
+			"type":      fmt.Sprintf("%T", req),
+		})
+		return nil, concurrent.ErrUnexpectedRequest{Request: req}
 	}
 }
 
@@ -173,7 +285,8 @@ func (e *WalletOwner) getStats(ctx context.Context) stats.Metrics {
 	metrics["startingBalance"] = fmt.Sprintf("0x%x", e.startBalance)
 	metrics["consumedBalance"] = fmt.Sprintf("0x%x", e.consumedBalance)
 	metrics["currentBalance"] = fmt.Sprintf("0x%x", e.currentBalance)
-	return metrics
+	metrics["transactions"] = e.transactions.Stats()
+	return metrics.WithLabels(stats.Labels{"wallet": e.wallet.Address().Hex()})
 }
 
 func (e *WalletOwner) handleErrorEvent(ctx context.Context, ev concurrent.ErrorWorkerEvent) (interface{}, error) {
@@ -211,10 +324,51 @@ func (e *WalletOwner) updateNonce(ctx context.Context) errors.Err {
 	return nil
 }
 
+// resetNonce fetches the nonce of the wallet again after a transaction
+// exceeded its lifetime. The transaction may have been dropped by the
+// node, in which case its nonce needs to be reused so that the following
+// transactions are not stuck behind it
+func (e *WalletOwner) resetNonce(id uint64) {
+	// the context of the request is done so the
+	// nonce is fetched with a context of its own
+	ctx := context.Background()
+	if err := e.updateNonce(ctx); err != nil {
+		e.logger.Warn(ctx, "failed to reset nonce after transaction timeout", log.MapFields{
+			"call_type": "ResetNonceFailure",
+			"id":        id,
+		}, err)
+	}
+}
+
 func (e *WalletOwner) signTransaction(tx *types.Transaction) (*types.Transaction, errors.Err) {
 	return e.wallet.SignTransaction(tx)
 }
 
+// transactionGas returns the gas limit of the transaction, which is
+// the one requested if any and the estimated one otherwise
+func (e *WalletOwner) transactionGas(ctx context.Context, req ExecuteRequest) (uint64, errors.Err) {
+	gas := req.GasLimit
+	if gas == 0 {
+		estimated := slo.GetStages(ctx).Track("estimate")
+		var err errors.Err
+		gas, err = e.estimateGas(ctx, req.ID, req.Address, req.Data)
+		estimated()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if err := e.gasLimit.check(gas); err != nil {
+		return 0, err
+	}
+
+	if err := checkGasPrice(req.GasPrice, e.maxGasPrice); err != nil {
+		return 0, err
+	}
+
+	return gas, nil
+}
+
 func (e *WalletOwner) estimateGas(ctx context.Context, id uint64, address string, data []byte) (uint64, errors.Err) {
 	if len(address) == 0 {
 		return e.estimateGasNonConfidential(ctx, id, address, data)
@@ -223,7 +377,7 @@ func (e *WalletOwner) estimateGas(ctx context.Context, id uint64, address string
 	// TODO(stan): parse the data to identify whether the service is confidential.
 	// estimateGas does not work for confidential services so in that case we provide a reasonable
 	// amount of gas that may work
-	return 15177522, nil
+	return e.gasLimit.confidential(), nil
 }
 
 func (e *WalletOwner) estimateGasNonConfidential(ctx context.Context, id uint64, address string, data []byte) (uint64, errors.Err) {
@@ -283,40 +437,190 @@ func (e *WalletOwner) estimateGasNonConfidential(ctx context.Context, id uint64,
 	return gas, nil
 }
 
-func (e *WalletOwner) generateAndSignTransaction(ctx context.Context, req sendTransactionRequest, gas uint64) (*types.Transaction, error) {
-	nonce := e.transactionNonce()
+func (e *WalletOwner) generateAndSignTransaction(
+	ctx context.Context,
+	req sendTransactionRequest,
+	nonce uint64,
+	gas uint64,
+	gasPrice *big.Int,
+) (eth.Transaction, error) {
+	// the transactions with an expiry are submitted through
+	// oasis_invoke with the expiry, which only takes legacy
+	// transactions
+	if e.dynamicFees != nil && req.Expiry == 0 {
+		return e.generateAndSignDynamicFeeTransaction(req, nonce, gas, gasPrice)
+	}
 
 	var tx *types.Transaction
 	if len(req.Address) == 0 {
 		tx = types.NewContractCreation(nonce,
-			big.NewInt(0), gas, big.NewInt(gasPrice), req.Data)
+			big.NewInt(0), gas, gasPrice, req.Data)
 	} else {
 		tx = types.NewTransaction(nonce, common.HexToAddress(req.Address),
-			big.NewInt(0), gas, big.NewInt(gasPrice), req.Data)
+			big.NewInt(0), gas, gasPrice, req.Data)
 	}
 
-	return e.wallet.SignTransaction(tx)
+	signed, err := e.wallet.SignTransaction(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	return signed, nil
+}
+
+// generateAndSignDynamicFeeTransaction generates a dynamic fee
+// transaction whose maxFeePerGas is the gas price, so that the
+// wallet never pays more per gas than with a legacy transaction
+func (e *WalletOwner) generateAndSignDynamicFeeTransaction(
+	req sendTransactionRequest,
+	nonce uint64,
+	gas uint64,
+	gasPrice *big.Int,
+) (eth.Transaction, error) {
+	var to *common.Address
+	if len(req.Address) > 0 {
+		address := common.HexToAddress(req.Address)
+		to = &address
+	}
+
+	tip := e.dynamicFees.PriorityFee
+	if tip.Cmp(gasPrice) > 0 {
+		tip = gasPrice
+	}
+
+	signed, err := e.wallet.SignDynamicFeeTransaction(eth.NewDynamicFeeTransaction(
+		e.dynamicFees.ChainID, nonce, to, gas, tip, gasPrice, req.Data))
+	if err != nil {
+		return nil, err
+	}
+
+	return signed, nil
+}
+
+// transactionGasPrice returns the gas price of the transaction, which
+// is the one requested if any and the one chosen by the
+// GasPriceStrategy otherwise. A requested gas price is never escalated
+func (e *WalletOwner) transactionGasPrice(
+	ctx context.Context,
+	req sendTransactionRequest,
+	started time.Time,
+) (*big.Int, error) {
+	if req.GasPrice > 0 {
+		return new(big.Int).SetUint64(req.GasPrice), nil
+	}
+
+	return e.gasPrice.GasPrice(ctx, GasPriceRequest{Elapsed: time.Since(started)})
+}
+
+// checkBalance verifies that the balance of the wallet covers the
+// maximum cost of the transaction before it is signed, so that the
+// client learns how much is missing rather than getting the opaque
+// rejection of the node. The transactions of the gateway do not
+// transfer value, so their cost is the gas limit times the gas price
+func (e *WalletOwner) checkBalance(ctx context.Context, req sendTransactionRequest, gasPrice *big.Int) errors.Err {
+	required := new(big.Int).Mul(new(big.Int).SetUint64(req.Gas), gasPrice)
+	if required.Cmp(e.currentBalance) <= 0 {
+		return nil
+	}
+
+	// the wallet may have been funded since the balance was last
+	// fetched. If it cannot be fetched the last balance is used
+	_ = e.updateBalance(ctx)
+	if required.Cmp(e.currentBalance) <= 0 {
+		return nil
+	}
+
+	e.callbacks.WalletOutOfFunds(ctx, callback.WalletOutOfFundsBody{
+		Address: e.wallet.Address().Hex(),
+	})
+
+	desc := fmt.Sprintf("%s Required %s wei, available %s wei.",
+		errors.ErrInsufficientGatewayFunds.Desc(), required, e.currentBalance)
+	err := errors.New(
+		errors.NewErrorCode(errors.ErrInsufficientGatewayFunds.Category(), errors.ErrInsufficientGatewayFunds.Code(), desc),
+		stderr.Errorf("wallet %s requires %s wei for the transaction and has %s wei",
+			e.wallet.Address().Hex(), required, e.currentBalance))
+	e.logger.Warn(ctx, "wallet does not have enough funds for the transaction", log.MapFields{
+		"call_type": "InsufficientFundsFailure",
+		"id":        req.ID,
+		"address":   req.Address,
+		"wallet":    e.wallet.Address().Hex(),
+		"required":  required.String(),
+		"available": e.currentBalance.String(),
+	}, err)
+	return err
 }
 
 type sendTransactionRequest struct {
-	AAD     string
-	ID      uint64
-	Address string
-	Gas     uint64
-	Data    []byte
+	AAD      string
+	ID       uint64
+	Address  string
+	Gas      uint64
+	GasPrice uint64
+	Data     []byte
+	Expiry   uint64
 }
 
 func (e *WalletOwner) sendTransaction(
 	ctx context.Context,
 	req sendTransactionRequest,
 ) (eth.SendTransactionResponse, errors.Err) {
+	info := &TransactionInfo{
+		AAD:     req.AAD,
+		ID:      req.ID,
+		Sender:  e.wallet.Address(),
+		Address: req.Address,
+		Gas:     req.Gas,
+		Data:    req.Data,
+	}
+
+	if err := e.middleware.PreSign(ctx, info); err != nil {
+		err := middlewareError(err)
+		e.logger.Debug(ctx, "transaction rejected before signing", log.MapFields{
+			"call_type": "SendTransactionRejected",
+			"id":        req.ID,
+			"address":   req.Address,
+		}, err)
+		return eth.SendTransactionResponse{}, err
+	}
+
+	started := time.Now()
 	v, err := concurrent.RetryWithConfig(ctx, concurrent.SupplierFunc(func() (interface{}, error) {
-		tx, err := e.generateAndSignTransaction(ctx, req, req.Gas)
+		// the gas price is chosen again on each attempt, so that
+		// it follows the market while the transaction is retried
+		gasPrice, err := e.transactionGasPrice(ctx, req, started)
+		if err != nil {
+			e.logger.Debug(ctx, "failed to choose the gas price of the transaction", log.MapFields{
+				"call_type": "GasPriceFailure",
+				"id":        req.ID,
+				"address":   req.Address,
+				"err":       err.Error(),
+			})
+			return eth.SendTransactionResponse{}, errors.New(errors.ErrGasPrice, err)
+		}
+
+		if err := e.checkBalance(ctx, req, gasPrice); err != nil {
+			return eth.SendTransactionResponse{}, concurrent.ErrCannotRecover{Cause: err}
+		}
+
+		signed := slo.GetStages(ctx).Track("sign")
+		tx, err := e.generateAndSignTransaction(ctx, req, e.transactionNonce(), req.Gas, gasPrice)
+		signed()
 		if err != nil {
 			return ExecuteResponse{}, errors.New(errors.ErrSignedTx, err)
 		}
 
-		res, err := e.client.SendTransaction(ctx, tx)
+		if err := e.middleware.PostSign(ctx, info, tx); err != nil {
+			// the transaction is never sent, so the nonce it
+			// consumed can be reused by the next transaction
+			e.nonce--
+			return eth.SendTransactionResponse{},
+				concurrent.ErrCannotRecover{Cause: middlewareError(err)}
+		}
+
+		sent := slo.GetStages(ctx).Track("send")
+		res, err := e.submitWithReplacement(ctx, req, info, tx, gasPrice, started)
+		sent()
 		if err != nil {
 			switch {
 			case stderr.Is(err, eth.ErrExceedsBalance):
@@ -330,6 +634,9 @@ func (e *WalletOwner) sendTransaction(
 			case stderr.Is(err, eth.ErrExceedsBlockLimit):
 				return eth.SendTransactionResponse{},
 					concurrent.ErrCannotRecover{Cause: errors.New(errors.ErrSendTransaction, err)}
+			case stderr.Is(err, eth.ErrTransactionExpired):
+				return eth.SendTransactionResponse{},
+					concurrent.ErrCannotRecover{Cause: errors.New(errors.ErrTransactionExpired, err)}
 			case stderr.Is(err, eth.ErrInvalidNonce):
 				if err := e.updateNonce(ctx); err != nil {
 					// if we fail to update the nonce we cannot proceed
@@ -347,17 +654,25 @@ func (e *WalletOwner) sendTransaction(
 		}
 
 		return res, nil
-	}), retryConfig)
+	}), e.retryConfig)
 
 	if err != nil {
-		if err, ok := err.(errors.Err); ok {
-			return eth.SendTransactionResponse{}, err
+		// the retry mechanism wraps the cause of an unrecoverable
+		// error, so the error is unwrapped to keep its error code
+		var xerr errors.Err
+		var cause errors.Error
+		if stderr.As(err, &cause) {
+			xerr = cause
+		} else {
+			xerr = errors.New(errors.ErrSendTransaction, err)
 		}
 
-		return eth.SendTransactionResponse{}, errors.New(errors.ErrSendTransaction, err)
+		e.middleware.PostSend(ctx, info, eth.SendTransactionResponse{}, xerr)
+		return eth.SendTransactionResponse{}, xerr
 	}
 
 	res := v.(eth.SendTransactionResponse)
+	e.middleware.PostSend(ctx, info, res, nil)
 	e.callbacks.TransactionCommitted(ctx, callback.TransactionCommittedBody{
 		AAD:     req.AAD,
 		Address: e.wallet.Address().Hex(),
@@ -367,9 +682,190 @@ func (e *WalletOwner) sendTransaction(
 	return res, nil
 }
 
+// contextError converts the error of a context that is done into the
+// errors.Err returned for a request that expired or was cancelled
+func contextError(err error) errors.Err {
+	if err == context.DeadlineExceeded {
+		return errors.New(errors.ErrTransactionTimeout, err)
+	}
+
+	return errors.New(errors.ErrRequestCancelled, err)
+}
+
+// middlewareError converts an error returned by a Middleware into an
+// errors.Err. Middleware may return an errors.Err to choose the error
+// code returned to the client
+func middlewareError(err error) errors.Err {
+	var cause errors.Error
+	if stderr.As(err, &cause) {
+		return cause
+	}
+
+	return errors.New(errors.ErrTransactionRejected, err)
+}
+
+// submission is the result of the submission of a transaction
+type submission struct {
+	res eth.SendTransactionResponse
+	err error
+}
+
+// submitWithReplacement submits the transaction and, for as long as it
+// is not mined, replaces it at each replaceInterval with a transaction
+// with the same nonce at the gas price chosen again by the
+// GasPriceStrategy, which an EscalatingGasPrice raises over time. The
+// submissions of the transactions that are replaced are still waited
+// for, since any of them may be the one that is mined. The result of
+// the first transaction that is mined is returned, or the error of the
+// last one if none is
+func (e *WalletOwner) submitWithReplacement(
+	ctx context.Context,
+	req sendTransactionRequest,
+	info *TransactionInfo,
+	tx eth.Transaction,
+	gasPrice *big.Int,
+	started time.Time,
+) (eth.SendTransactionResponse, error) {
+	if e.replaceInterval <= 0 {
+		return e.submitTransaction(ctx, tx, req.Expiry)
+	}
+
+	// the submissions that are still pending once a transaction is
+	// mined are cancelled
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan submission)
+	pending := 0
+	submit := func(tx eth.Transaction) {
+		pending++
+		go func() {
+			res, err := e.submitTransaction(ctx, tx, req.Expiry)
+			select {
+			case results <- submission{res: res, err: err}:
+			case <-ctx.Done():
+			}
+		}()
+	}
+
+	ticker := time.NewTicker(e.replaceInterval)
+	defer ticker.Stop()
+
+	submit(tx)
+	for {
+		select {
+		case s := <-results:
+			pending--
+			if s.err == nil || pending == 0 {
+				return s.res, s.err
+			}
+
+		case <-ticker.C:
+			replacement, price, err := e.replaceTransaction(ctx, req, info, tx.Nonce(), gasPrice, started)
+			if err != nil {
+				e.logger.Debug(ctx, "failed to replace pending transaction", log.MapFields{
+					"call_type": "ReplaceTransactionFailure",
+					"id":        req.ID,
+					"address":   req.Address,
+					"err":       err.Error(),
+				})
+				continue
+			}
+			if replacement == nil {
+				continue
+			}
+
+			e.logger.Debug(ctx, "replacing pending transaction", log.MapFields{
+				"call_type": "ReplaceTransaction",
+				"id":        req.ID,
+				"address":   req.Address,
+				"nonce":     tx.Nonce(),
+				"gas_price": price.String(),
+			})
+			gasPrice = price
+			submit(replacement)
+
+		case <-ctx.Done():
+			return eth.SendTransactionResponse{}, ctx.Err()
+		}
+	}
+}
+
+// replaceTransaction generates and signs the transaction that replaces
+// the pending transaction with the nonce. It returns a nil transaction
+// if the gas price chosen is not above the gas price of the pending
+// transaction, since the node would reject the replacement
+func (e *WalletOwner) replaceTransaction(
+	ctx context.Context,
+	req sendTransactionRequest,
+	info *TransactionInfo,
+	nonce uint64,
+	gasPrice *big.Int,
+	started time.Time,
+) (eth.Transaction, *big.Int, error) {
+	price, err := e.transactionGasPrice(ctx, req, started)
+	if err != nil {
+		return nil, nil, err
+	}
+	if price.Cmp(gasPrice) <= 0 {
+		return nil, nil, nil
+	}
+
+	if err := e.checkBalance(ctx, req, price); err != nil {
+		return nil, nil, err
+	}
+
+	tx, err := e.generateAndSignTransaction(ctx, req, nonce, req.Gas, price)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := e.middleware.PostSign(ctx, info, tx); err != nil {
+		return nil, nil, err
+	}
+
+	return tx, price, nil
+}
+
+// submitTransaction selects the submission path for a transaction. Transactions
+// with an expiry are submitted through the Oasis specific path so that the
+// runtime can discard them once they expire. Dynamic fee transactions are
+// never generated with an expiry
+func (e *WalletOwner) submitTransaction(
+	ctx context.Context,
+	tx eth.Transaction,
+	expiry uint64,
+) (eth.SendTransactionResponse, error) {
+	if dtx, ok := tx.(*eth.DynamicFeeTransaction); ok {
+		return e.client.SendDynamicFeeTransaction(ctx, dtx)
+	}
+
+	ltx := tx.(*types.Transaction)
+	if expiry > 0 {
+		return e.client.SendTransactionWithExpiry(ctx, ltx, expiry)
+	}
+
+	return e.client.SendTransaction(ctx, ltx)
+}
+
 func (e *WalletOwner) executeTransaction(ctx context.Context, req ExecuteRequest) (ExecuteResponse, errors.Err) {
-	serviceAddress := req.Address
-	gas, err := e.estimateGas(ctx, req.ID, req.Address, req.Data)
+	// callbacks triggered by the transaction are routed to
+	// the tenant of the AAD that issued it
+	ctx = callback.WithAAD(ctx, req.AAD)
+
+	if e.slow.Enabled() {
+		stages := slo.NewStages()
+		ctx = slo.WithStages(ctx, stages)
+		defer e.logSlowTransaction(ctx, req, stages)
+	}
+
+	// the request may have waited for the owner for longer than its
+	// lifetime, in which case it is not sent at all
+	if err := ctx.Err(); err != nil {
+		return ExecuteResponse{}, contextError(err)
+	}
+
+	gas, err := e.transactionGas(ctx, req)
 	if err != nil {
 		e.logger.Debug(ctx, "failed to estimate gas", log.MapFields{
 			"call_type": "ExecuteTransactionFailure",
@@ -380,14 +876,27 @@ func (e *WalletOwner) executeTransaction(ctx context.Context, req ExecuteRequest
 		return ExecuteResponse{}, err
 	}
 
+	// gas estimation may take long enough for the caller to give up
+	// on the request, in which case it is not signed so that it does
+	// not consume a nonce
+	if err := ctx.Err(); err != nil {
+		return ExecuteResponse{}, contextError(err)
+	}
+
 	res, err := e.sendTransaction(ctx, sendTransactionRequest{
-		AAD:     req.AAD,
-		ID:      req.ID,
-		Address: req.Address,
-		Data:    req.Data,
-		Gas:     gas,
+		AAD:      req.AAD,
+		ID:       req.ID,
+		Address:  req.Address,
+		Data:     req.Data,
+		Gas:      gas,
+		GasPrice: req.GasPrice,
+		Expiry:   req.Expiry,
 	})
 	if err != nil {
+		if ctx.Err() != nil {
+			e.resetNonce(req.ID)
+			return ExecuteResponse{}, contextError(ctx.Err())
+		}
 		return ExecuteResponse{}, err
 	}
 
@@ -395,6 +904,17 @@ func (e *WalletOwner) executeTransaction(ctx context.Context, req ExecuteRequest
 	// the transaction
 	_ = e.updateBalance(ctx)
 
+	return e.transactionResult(ctx, req, res)
+}
+
+// transactionResult checks the status of a transaction that has been
+// mined and retrieves its receipt and output
+func (e *WalletOwner) transactionResult(
+	ctx context.Context,
+	req ExecuteRequest,
+	res eth.SendTransactionResponse,
+) (ExecuteResponse, errors.Err) {
+	serviceAddress := req.Address
 	if res.Status != StatusOK {
 		msg := fmt.Sprintf("transaction receipt has status %d which indicates a transaction execution failure with error %s", res.Status, res.Output)
 		err := errors.New(errors.NewErrorCode(errors.InternalError, 1000, msg), stderr.New(msg))
@@ -407,7 +927,9 @@ func (e *WalletOwner) executeTransaction(ctx context.Context, req ExecuteRequest
 		return ExecuteResponse{}, err
 	}
 
+	received := slo.GetStages(ctx).Track("receipt")
 	receipt, err := e.transactionReceipt(ctx, res.Hash)
+	received()
 	if err != nil {
 		e.logger.Debug(ctx, "failure to retrieve transaction receipt", log.MapFields{
 			"call_type": "ExecuteTransactionFailure",
@@ -418,6 +940,7 @@ func (e *WalletOwner) executeTransaction(ctx context.Context, req ExecuteRequest
 		return ExecuteResponse{}, err
 	}
 
+	output := res.Output
 	if len(serviceAddress) == 0 {
 		// retrieve the code for the service to make sure that it has been deployed
 		// successfully
@@ -445,6 +968,8 @@ func (e *WalletOwner) executeTransaction(ctx context.Context, req ExecuteRequest
 		}
 
 		serviceAddress = receipt.ContractAddress.Hex()
+	} else {
+		output = e.transactionOutput(ctx, req, res)
 	}
 
 	// update the consumed gas
@@ -454,11 +979,110 @@ func (e *WalletOwner) executeTransaction(ctx context.Context, req ExecuteRequest
 
 	return ExecuteResponse{
 		Address: serviceAddress,
-		Output:  res.Output,
+		Output:  output,
 		Hash:    res.Hash,
+		GasUsed: receipt.GasUsed,
 	}, nil
 }
 
+// logSlowTransaction logs the transaction along with the time taken
+// by each of its stages if it was slower than the slow thresholds
+func (e *WalletOwner) logSlowTransaction(ctx context.Context, req ExecuteRequest, stages *slo.Stages) {
+	latency := stages.Elapsed()
+	if !stages.Slow(e.slow, latency) {
+		return
+	}
+
+	e.logger.Warn(ctx, "slow transaction", log.MapFields{
+		"call_type":  "SlowTransaction",
+		"id":         req.ID,
+		"address":    req.Address,
+		"latency_ms": int64(latency / time.Millisecond),
+	}, stages)
+}
+
+// transactionOutput retrieves the return data of a transaction that has
+// already been mined using the configured OutputMode. The transaction has
+// already succeeded at this point, so a failure to retrieve the output
+// is logged and an empty output is returned instead of an error
+func (e *WalletOwner) transactionOutput(
+	ctx context.Context,
+	req ExecuteRequest,
+	res eth.SendTransactionResponse,
+) string {
+	switch e.outputMode {
+	case OutputModeTrace:
+		trace, err := e.client.TraceTransaction(ctx, common.HexToHash(res.Hash))
+		if err != nil {
+			e.logger.Warn(ctx, "failed to trace transaction output", log.MapFields{
+				"call_type": "TransactionOutputFailure",
+				"id":        req.ID,
+				"address":   req.Address,
+				"hash":      res.Hash,
+				"err":       err.Error(),
+			})
+			return ""
+		}
+
+		if len(trace.ReturnValue) == 0 {
+			return ""
+		}
+
+		return "0x" + strings.TrimPrefix(trace.ReturnValue, "0x")
+
+	case OutputModeCall:
+		// the call is executed against the state of the block previous
+		// to the one that includes the transaction so that state changing
+		// methods report what the transaction returned and not the result
+		// of executing them again on top of it. The result is still an
+		// approximation when other transactions that precede this one in
+		// the same block modify the state that the call reads
+		number, err := e.client.TransactionBlockNumber(ctx, common.HexToHash(res.Hash))
+		if err != nil {
+			e.logger.Warn(ctx, "failed to retrieve transaction block number", log.MapFields{
+				"call_type": "TransactionOutputFailure",
+				"id":        req.ID,
+				"address":   req.Address,
+				"hash":      res.Hash,
+				"err":       err.Error(),
+			})
+			return ""
+		}
+
+		to := common.HexToAddress(req.Address)
+		p, err := e.client.CallContract(ctx, ethereum.CallMsg{
+			From: e.wallet.Address(),
+			To:   &to,
+			Data: req.Data,
+		}, parentBlockNumber(number))
+		if err != nil {
+			e.logger.Warn(ctx, "failed to call for transaction output", log.MapFields{
+				"call_type": "TransactionOutputFailure",
+				"id":        req.ID,
+				"address":   req.Address,
+				"hash":      res.Hash,
+				"err":       err.Error(),
+			})
+			return ""
+		}
+
+		return hexutil.Encode(p)
+
+	default:
+		return res.Output
+	}
+}
+
+// parentBlockNumber returns the number of the block previous to the
+// provided one, or the genesis block if number is the genesis block
+func parentBlockNumber(number *big.Int) *big.Int {
+	if number.Sign() <= 0 {
+		return new(big.Int)
+	}
+
+	return new(big.Int).Sub(number, big.NewInt(1))
+}
+
 func (e *WalletOwner) getCode(ctx context.Context, addr common.Address) (string, errors.Err) {
 	code, err := e.client.GetCode(ctx, addr)
 	if err != nil {
@@ -4,15 +4,21 @@ import (
 	"context"
 	"math/big"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
+	stderr "github.com/pkg/errors"
 
 	"github.com/oasislabs/oasis-gateway/callback/callbacktest"
 	callback "github.com/oasislabs/oasis-gateway/callback/client"
+	"github.com/oasislabs/oasis-gateway/concurrent"
+	"github.com/oasislabs/oasis-gateway/errors"
 	"github.com/oasislabs/oasis-gateway/eth"
 	"github.com/oasislabs/oasis-gateway/eth/ethtest"
+	"github.com/oasislabs/oasis-gateway/stats"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -36,7 +42,7 @@ func mockClientForNonce(client *ethtest.MockClient) {
 		mock.AnythingOfType("*context.emptyCtx"),
 		mock.AnythingOfType("common.Address"),
 		mock.AnythingOfType("*big.Int")).
-		Return(big.NewInt(1), nil)
+		Return(big.NewInt(1000000000000000000), nil)
 	client.On("TransactionReceipt",
 		mock.AnythingOfType("*context.emptyCtx"),
 		mock.AnythingOfType("common.Hash")).
@@ -210,7 +216,7 @@ func TestOwnerWalletReachedFundsThresholdOnNewOK(t *testing.T) {
 		mock.MatchedBy(func(body callback.WalletReachedFundsThresholdBody) bool {
 			return body.Address == "0x0759BC19964B467FcadaFdA49BE7986CB27183E3" &&
 				body.Before == nil &&
-				body.After.Cmp(new(big.Int).SetInt64(1)) == 0
+				body.After.Cmp(new(big.Int).SetInt64(1000000000000000000)) == 0
 		}))
 }
 
@@ -231,7 +237,549 @@ func TestWalletReachedFundsThresholdOnTransactionOK(t *testing.T) {
 	callbackclient.AssertCalled(t, "WalletReachedFundsThreshold", mock.Anything,
 		mock.MatchedBy(func(body callback.WalletReachedFundsThresholdBody) bool {
 			return body.Address == "0x0759BC19964B467FcadaFdA49BE7986CB27183E3" &&
-				body.Before.Cmp(new(big.Int).SetInt64(1)) == 0 &&
-				body.After.Cmp(new(big.Int).SetInt64(1)) == 0
+				body.Before.Cmp(new(big.Int).SetInt64(1000000000000000000)) == 0 &&
+				body.After.Cmp(new(big.Int).SetInt64(1000000000000000000)) == 0
+		}))
+}
+
+func TestExecuteTransactionInsufficientFunds(t *testing.T) {
+	mockclient := &ethtest.MockClient{}
+	ethtest.ImplementMockWithOverwrite(mockclient, ethtest.MockMethods{
+		"BalanceAt": {
+			Arguments: []interface{}{mock.Anything, mock.Anything, mock.Anything},
+			Return:    []interface{}{big.NewInt(1000), nil},
+		},
+		"EstimateGas": {
+			Arguments: []interface{}{mock.Anything, mock.Anything},
+			Return:    []interface{}{uint64(21000), nil},
+		},
+	})
+	owner, err := newOwner(mockclient)
+	assert.Nil(t, err)
+	mockcallback := owner.callbacks.(*callbacktest.MockClient)
+
+	_, err = owner.executeTransaction(context.TODO(), ExecuteRequest{
+		Data: []byte(""),
+	})
+
+	assert.Equal(t, errors.ErrInsufficientGatewayFunds.Code(), err.(errors.Err).ErrorCode().Code())
+	assert.Equal(t, "The wallet of the gateway does not have enough funds for the transaction. "+
+		"Required 21000000000000 wei, available 1000 wei.", err.(errors.Err).ErrorCode().Desc())
+	mockclient.AssertNotCalled(t, "SendTransaction", mock.Anything, mock.Anything)
+	mockclient.AssertNumberOfCalls(t, "BalanceAt", 2)
+	mockcallback.AssertCalled(t, "WalletOutOfFunds", mock.Anything, mock.Anything)
+
+	// the transaction is not signed, so it does not consume a nonce
+	assert.Equal(t, uint64(1), owner.nonce)
+}
+
+func TestExecuteTransactionOutputModeTrace(t *testing.T) {
+	mockclient := &ethtest.MockClient{}
+	ethtest.ImplementMock(mockclient)
+	owner, err := newOwner(mockclient)
+	assert.Nil(t, err)
+	owner.outputMode = OutputModeTrace
+
+	res, err := owner.executeTransaction(context.TODO(), ExecuteRequest{
+		Address: address,
+		Data:    []byte(""),
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "0x73756363657373", res.Output)
+	mockclient.AssertNumberOfCalls(t, "TraceTransaction", 1)
+}
+
+func TestExecuteTransactionOutputModeCall(t *testing.T) {
+	mockclient := &ethtest.MockClient{}
+	ethtest.ImplementMock(mockclient)
+	owner, err := newOwner(mockclient)
+	assert.Nil(t, err)
+	owner.outputMode = OutputModeCall
+
+	res, err := owner.executeTransaction(context.TODO(), ExecuteRequest{
+		Address: address,
+		Data:    []byte(""),
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "0x73756363657373", res.Output)
+	mockclient.AssertNumberOfCalls(t, "CallContract", 1)
+	mockclient.AssertCalled(t, "CallContract", mock.Anything, mock.Anything,
+		mock.MatchedBy(func(n *big.Int) bool {
+			return n != nil && n.Int64() == 9
+		}))
+}
+
+func TestExecuteTransactionOutputModeCallErr(t *testing.T) {
+	mockclient := &ethtest.MockClient{}
+	ethtest.ImplementMockWithOverwrite(mockclient, ethtest.MockMethods{
+		"CallContract": {
+			Arguments: []interface{}{mock.Anything, mock.Anything, mock.Anything},
+			Return:    []interface{}{nil, eth.ErrExceedsBlockLimit},
+		},
+	})
+	owner, err := newOwner(mockclient)
+	assert.Nil(t, err)
+	owner.outputMode = OutputModeCall
+
+	res, err := owner.executeTransaction(context.TODO(), ExecuteRequest{
+		Address: address,
+		Data:    []byte(""),
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "", res.Output)
+}
+
+func TestExecuteTransactionWithExpiry(t *testing.T) {
+	mockclient := &ethtest.MockClient{}
+	ethtest.ImplementMock(mockclient)
+	owner, err := newOwner(mockclient)
+	assert.Nil(t, err)
+
+	_, err = owner.executeTransaction(context.TODO(), ExecuteRequest{
+		Address: address,
+		Data:    []byte(""),
+		Expiry:  1024,
+	})
+
+	assert.Nil(t, err)
+	mockclient.AssertNumberOfCalls(t, "SendTransaction", 0)
+	mockclient.AssertCalled(t, "SendTransactionWithExpiry", mock.Anything, mock.Anything, uint64(1024))
+}
+
+func TestExecuteTransactionDynamicFees(t *testing.T) {
+	mockclient := &ethtest.MockClient{}
+	ethtest.ImplementMock(mockclient)
+	owner, err := newOwner(mockclient)
+	assert.Nil(t, err)
+	owner.dynamicFees = NewDynamicFees(42261, GasPriceProps{
+		DynamicFees: true,
+		PriorityFee: 2 * DefaultGasPrice,
+	})
+
+	_, err = owner.executeTransaction(context.TODO(), ExecuteRequest{
+		Address: address,
+		Data:    []byte(""),
+	})
+
+	assert.Nil(t, err)
+	mockclient.AssertNumberOfCalls(t, "SendTransaction", 0)
+	mockclient.AssertNumberOfCalls(t, "SendDynamicFeeTransaction", 1)
+
+	// the maximum fee is the gas price and bounds the priority fee
+	mockclient.AssertCalled(t, "SendDynamicFeeTransaction", mock.Anything,
+		mock.MatchedBy(func(tx *eth.DynamicFeeTransaction) bool {
+			price := new(big.Int).SetUint64(DefaultGasPrice)
+			return tx.ChainID().Uint64() == 42261 &&
+				tx.GasFeeCap().Cmp(price) == 0 &&
+				tx.GasTipCap().Cmp(price) == 0 &&
+				*tx.To() == common.HexToAddress(address)
+		}))
+}
+
+func TestExecuteTransactionDynamicFeesWithExpiry(t *testing.T) {
+	mockclient := &ethtest.MockClient{}
+	ethtest.ImplementMock(mockclient)
+	owner, err := newOwner(mockclient)
+	assert.Nil(t, err)
+	owner.dynamicFees = NewDynamicFees(42261, GasPriceProps{DynamicFees: true})
+
+	_, err = owner.executeTransaction(context.TODO(), ExecuteRequest{
+		Address: address,
+		Data:    []byte(""),
+		Expiry:  1024,
+	})
+
+	assert.Nil(t, err)
+	mockclient.AssertNumberOfCalls(t, "SendDynamicFeeTransaction", 0)
+	mockclient.AssertCalled(t, "SendTransactionWithExpiry", mock.Anything, mock.Anything, uint64(1024))
+}
+
+func TestNewDynamicFeesLegacyFallback(t *testing.T) {
+	assert.Nil(t, NewDynamicFees(0, GasPriceProps{DynamicFees: true}))
+	assert.Nil(t, NewDynamicFees(42261, GasPriceProps{DynamicFees: false}))
+
+	fees := NewDynamicFees(42261, GasPriceProps{DynamicFees: true})
+	assert.Equal(t, new(big.Int).SetUint64(DefaultPriorityFee), fees.PriorityFee)
+}
+
+// pendingClient keeps the transactions with a gas price below
+// minPrice pending until their submission is cancelled
+type pendingClient struct {
+	eth.Client
+	minPrice *big.Int
+
+	lock   sync.Mutex
+	prices []*big.Int
+}
+
+func (c *pendingClient) SendTransaction(ctx context.Context, tx *types.Transaction) (eth.SendTransactionResponse, error) {
+	c.lock.Lock()
+	c.prices = append(c.prices, tx.GasPrice())
+	c.lock.Unlock()
+
+	if tx.GasPrice().Cmp(c.minPrice) < 0 {
+		<-ctx.Done()
+		return eth.SendTransactionResponse{}, ctx.Err()
+	}
+
+	return eth.SendTransactionResponse{Status: StatusOK, Hash: tx.Hash().Hex()}, nil
+}
+
+func (c *pendingClient) Prices() []*big.Int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return append([]*big.Int(nil), c.prices...)
+}
+
+func newEscalatingOwner(t *testing.T, client *pendingClient, max uint64) *WalletOwner {
+	mockclient := &ethtest.MockClient{}
+	ethtest.ImplementMock(mockclient)
+	owner, err := newOwner(mockclient)
+	assert.Nil(t, err)
+
+	client.Client = mockclient
+	owner.client = client
+	owner.gasPrice = NewGasPriceStrategy(mockclient, GasPriceProps{
+		Price:              DefaultGasPrice,
+		EscalationInterval: 20 * time.Millisecond,
+		EscalationFactor:   2,
+		Max:                max,
+	})
+	owner.replaceInterval = 20 * time.Millisecond
+	return owner
+}
+
+func TestExecuteTransactionReplacedWithEscalatedPrice(t *testing.T) {
+	client := &pendingClient{minPrice: new(big.Int).SetUint64(4 * DefaultGasPrice)}
+	owner := newEscalatingOwner(t, client, 0)
+	nonce := owner.nonce
+
+	res, err := owner.executeTransaction(context.TODO(), ExecuteRequest{
+		Address: address,
+		Data:    []byte(""),
+	})
+
+	// the pending transaction is replaced at each interval
+	// until the gas price is high enough for it to be mined
+	assert.Nil(t, err)
+	assert.Equal(t, gasPrices(1000000000, 2000000000, 4000000000), client.Prices())
+	assert.NotEmpty(t, res.Hash)
+	assert.Equal(t, nonce+1, owner.nonce)
+}
+
+func TestExecuteTransactionReplacedUpToMaxPrice(t *testing.T) {
+	client := &pendingClient{minPrice: new(big.Int).SetUint64(4 * DefaultGasPrice)}
+	owner := newEscalatingOwner(t, client, 2*DefaultGasPrice)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	_, err := owner.executeTransaction(ctx, ExecuteRequest{
+		Address: address,
+		Data:    []byte(""),
+	})
+
+	// once the gas price reaches the maximum the
+	// transaction is not replaced anymore
+	assert.Error(t, err)
+	assert.Equal(t, gasPrices(1000000000, 2000000000), client.Prices())
+}
+
+func TestExecuteTransactionExpiredErr(t *testing.T) {
+	mockclient := &ethtest.MockClient{}
+	ethtest.ImplementMockWithOverwrite(mockclient, ethtest.MockMethods{
+		"SendTransactionWithExpiry": {
+			Arguments: []interface{}{mock.Anything, mock.Anything, mock.Anything},
+			Return:    []interface{}{eth.SendTransactionResponse{}, eth.ErrTransactionExpired},
+		},
+	})
+	owner, err := newOwner(mockclient)
+	assert.Nil(t, err)
+
+	_, xerr := owner.executeTransaction(context.TODO(), ExecuteRequest{
+		Address: address,
+		Data:    []byte(""),
+		Expiry:  1024,
+	})
+
+	assert.Error(t, xerr)
+	assert.Equal(t, errors.ErrTransactionExpired, xerr.ErrorCode())
+}
+
+func TestExecuteTransactionMiddlewarePreSignErr(t *testing.T) {
+	mockclient := &ethtest.MockClient{}
+	ethtest.ImplementMock(mockclient)
+	owner, err := newOwner(mockclient)
+	assert.Nil(t, err)
+
+	var sendErr error
+	owner.middleware = NewMiddlewareChain(
+		PreSignFunc(func(ctx context.Context, info *TransactionInfo) error {
+			return stderr.New("payload not allowed")
+		}),
+		PostSendFunc(func(ctx context.Context, info *TransactionInfo, res eth.SendTransactionResponse, err error) {
+			sendErr = err
+		}),
+	)
+
+	nonce := owner.nonce
+	_, xerr := owner.executeTransaction(context.TODO(), ExecuteRequest{
+		Address: address,
+		Data:    []byte(""),
+	})
+
+	assert.Error(t, xerr)
+	assert.Equal(t, errors.ErrTransactionRejected, xerr.ErrorCode())
+	assert.Nil(t, sendErr)
+	assert.Equal(t, nonce, owner.nonce)
+	mockclient.AssertNumberOfCalls(t, "SendTransaction", 0)
+}
+
+func TestExecuteTransactionMiddlewarePostSignErr(t *testing.T) {
+	mockclient := &ethtest.MockClient{}
+	ethtest.ImplementMock(mockclient)
+	owner, err := newOwner(mockclient)
+	assert.Nil(t, err)
+
+	owner.middleware = PostSignFunc(func(ctx context.Context, info *TransactionInfo, tx eth.Transaction) error {
+		return errors.New(errors.ErrQueueLimitReached, nil)
+	})
+
+	nonce := owner.nonce
+	_, xerr := owner.executeTransaction(context.TODO(), ExecuteRequest{
+		Address: address,
+		Data:    []byte(""),
+	})
+
+	assert.Error(t, xerr)
+	assert.Equal(t, errors.ErrQueueLimitReached, xerr.ErrorCode())
+	assert.Equal(t, nonce, owner.nonce)
+	mockclient.AssertNumberOfCalls(t, "SendTransaction", 0)
+}
+
+func TestExecuteTransactionMiddlewarePostSendOK(t *testing.T) {
+	mockclient := &ethtest.MockClient{}
+	ethtest.ImplementMock(mockclient)
+	owner, err := newOwner(mockclient)
+	assert.Nil(t, err)
+
+	var info *TransactionInfo
+	var hash string
+	owner.middleware = PostSendFunc(func(ctx context.Context, i *TransactionInfo, res eth.SendTransactionResponse, err error) {
+		assert.Nil(t, err)
+		info = i
+		hash = res.Hash
+	})
+
+	_, xerr := owner.executeTransaction(context.TODO(), ExecuteRequest{
+		ID:      1,
+		Address: address,
+		Data:    []byte("data"),
+	})
+
+	assert.Nil(t, xerr)
+	assert.Equal(t, uint64(1), info.ID)
+	assert.Equal(t, address, info.Address)
+	assert.Equal(t, owner.wallet.Address(), info.Sender)
+	assert.Equal(t, []byte("data"), info.Data)
+	assert.NotEmpty(t, hash)
+}
+
+func TestOwnerStatsLabeledByWallet(t *testing.T) {
+	mockclient := &ethtest.MockClient{}
+	ethtest.ImplementMock(mockclient)
+	owner, err := newOwner(mockclient)
+	assert.Nil(t, err)
+
+	_, err = owner.handleRequestEvent(context.TODO(), concurrent.RequestWorkerEvent{
+		Value: ExecuteRequest{Address: address, Data: []byte("")},
+	})
+	assert.Nil(t, err)
+
+	metrics := owner.getStats(context.TODO())
+	assert.Equal(t, stats.Labels{"wallet": owner.wallet.Address().Hex()}, metrics.Labels())
+	assert.Equal(t, map[string]interface{}{
+		"ok":        uint64(1),
+		"error":     uint64(0),
+		"undefined": uint64(0),
+	}, metrics["transactions"])
+}
+
+func TestExecuteTransactionLifetimeExpired(t *testing.T) {
+	mockclient := &ethtest.MockClient{}
+	ethtest.ImplementMock(mockclient)
+	owner, err := newOwner(mockclient)
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 0)
+	defer cancel()
+
+	_, xerr := owner.executeTransaction(ctx, ExecuteRequest{
+		Address: address,
+		Data:    []byte(""),
+	})
+
+	assert.Equal(t, errors.ErrTransactionTimeout, xerr.ErrorCode())
+	mockclient.AssertNumberOfCalls(t, "SendTransaction", 0)
+}
+
+func TestExecuteTransactionLifetimeResetsNonce(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	mockclient := &ethtest.MockClient{}
+	ethtest.ImplementMockWithOverwrite(mockclient, ethtest.MockMethods{
+		"SendTransaction": {
+			Arguments: []interface{}{mock.Anything, mock.Anything},
+			Return:    []interface{}{eth.SendTransactionResponse{}, stderr.New("context canceled")},
+			Run:       func(mock.Arguments) { cancel() },
+		},
+	})
+	owner, err := newOwner(mockclient)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1), owner.nonce)
+
+	_, xerr := owner.executeTransaction(ctx, ExecuteRequest{
+		Address: address,
+		Data:    []byte(""),
+	})
+
+	// the nonce consumed by the transaction is
+	// fetched again from the node
+	assert.Error(t, xerr)
+	assert.Equal(t, uint64(1), owner.nonce)
+	mockclient.AssertNumberOfCalls(t, "NonceAt", 2)
+}
+
+func TestExecuteTransactionCancelledDuringGasEstimation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	mockclient := &ethtest.MockClient{}
+	ethtest.ImplementMockWithOverwrite(mockclient, ethtest.MockMethods{
+		"EstimateGas": {
+			Arguments: []interface{}{mock.Anything, mock.Anything},
+			Return:    []interface{}{uint64(1234), nil},
+			Run:       func(mock.Arguments) { cancel() },
+		},
+	})
+	owner, err := newOwner(mockclient)
+	assert.Nil(t, err)
+
+	_, xerr := owner.executeTransaction(ctx, ExecuteRequest{
+		Data: []byte(""),
+	})
+
+	// the transaction is not signed so its nonce is not consumed
+	assert.Equal(t, errors.ErrRequestCancelled, xerr.ErrorCode())
+	assert.Equal(t, uint64(1), owner.nonce)
+	mockclient.AssertNumberOfCalls(t, "SendTransaction", 0)
+}
+
+func newOwnerWithGasLimit(client *ethtest.MockClient, limit GasLimitProps) (*WalletOwner, error) {
+	callbackclient := &callbacktest.MockClient{}
+	callbacktest.ImplementMock(callbackclient)
+	return NewWalletOwner(
+		context.TODO(),
+		&WalletOwnerServices{
+			Client:    client,
+			Callbacks: callbackclient,
+			Logger:    Logger,
+		},
+		&WalletOwnerProps{
+			PrivateKey: GetPrivateKey(),
+			Signer:     types.FrontierSigner{},
+			Nonce:      0,
+			GasLimit:   limit,
+		})
+}
+
+func sentWithGas(gas uint64) interface{} {
+	return mock.MatchedBy(func(tx *types.Transaction) bool {
+		return tx.Gas() == gas
+	})
+}
+
+func TestExecuteTransactionConfidentialGasLimit(t *testing.T) {
+	mockclient := &ethtest.MockClient{}
+	ethtest.ImplementMock(mockclient)
+	owner, err := newOwnerWithGasLimit(mockclient, GasLimitProps{Confidential: 50000})
+	assert.Nil(t, err)
+
+	_, err = owner.executeTransaction(context.TODO(), ExecuteRequest{
+		Address: address,
+		Data:    []byte(""),
+	})
+
+	assert.Nil(t, err)
+	mockclient.AssertCalled(t, "SendTransaction", mock.Anything, sentWithGas(50000))
+}
+
+func TestExecuteTransactionRequestedGasLimit(t *testing.T) {
+	mockclient := &ethtest.MockClient{}
+	ethtest.ImplementMock(mockclient)
+	owner, err := newOwnerWithGasLimit(mockclient, GasLimitProps{Max: 100000})
+	assert.Nil(t, err)
+
+	_, err = owner.executeTransaction(context.TODO(), ExecuteRequest{
+		Data:     []byte(""),
+		GasLimit: 80000,
+	})
+
+	assert.Nil(t, err)
+	mockclient.AssertNumberOfCalls(t, "EstimateGas", 0)
+	mockclient.AssertCalled(t, "SendTransaction", mock.Anything, sentWithGas(80000))
+}
+
+func TestExecuteTransactionRequestedGasPrice(t *testing.T) {
+	mockclient := &ethtest.MockClient{}
+	ethtest.ImplementMock(mockclient)
+	owner, err := newOwner(mockclient)
+	assert.Nil(t, err)
+	owner.maxGasPrice = 5 * DefaultGasPrice
+
+	_, err = owner.executeTransaction(context.TODO(), ExecuteRequest{
+		Address:  address,
+		Data:     []byte(""),
+		GasPrice: 3 * DefaultGasPrice,
+	})
+
+	assert.Nil(t, err)
+	mockclient.AssertCalled(t, "SendTransaction", mock.Anything,
+		mock.MatchedBy(func(tx *types.Transaction) bool {
+			return tx.GasPrice().Uint64() == 3*DefaultGasPrice
 		}))
 }
+
+func TestExecuteTransactionGasPriceExceeded(t *testing.T) {
+	mockclient := &ethtest.MockClient{}
+	ethtest.ImplementMock(mockclient)
+	owner, err := newOwner(mockclient)
+	assert.Nil(t, err)
+	owner.maxGasPrice = 5 * DefaultGasPrice
+
+	_, xerr := owner.executeTransaction(context.TODO(), ExecuteRequest{
+		Address:  address,
+		Data:     []byte(""),
+		GasPrice: 5*DefaultGasPrice + 1,
+	})
+
+	assert.Error(t, xerr)
+	assert.Equal(t, errors.ErrGasPriceExceeded, xerr.ErrorCode())
+	mockclient.AssertNumberOfCalls(t, "SendTransaction", 0)
+}
+
+func TestExecuteTransactionGasLimitExceeded(t *testing.T) {
+	mockclient := &ethtest.MockClient{}
+	ethtest.ImplementMock(mockclient)
+	owner, err := newOwnerWithGasLimit(mockclient, GasLimitProps{Confidential: 50000, Max: 100000})
+	assert.Nil(t, err)
+
+	_, xerr := owner.executeTransaction(context.TODO(), ExecuteRequest{
+		Address:  address,
+		Data:     []byte(""),
+		GasLimit: 100001,
+	})
+
+	assert.Error(t, xerr)
+	assert.Equal(t, errors.ErrGasLimitExceeded, xerr.ErrorCode())
+	mockclient.AssertNumberOfCalls(t, "SendTransaction", 0)
+}
@@ -0,0 +1,261 @@
+package tx
+
+import (
+	"context"
+	"sync"
+
+	"github.com/oasislabs/oasis-gateway/stats"
+)
+
+// SchedulingPolicy defines the order in which the requests waiting
+// for a wallet are handed to the wallet owners
+type SchedulingPolicy string
+
+const (
+	// SchedulingFIFO hands the requests in the order they arrive
+	SchedulingFIFO SchedulingPolicy = "fifo"
+
+	// SchedulingFair takes turns amongst the AADs with requests
+	// waiting, so that an AAD that submits many requests does
+	// not delay the requests of the others
+	SchedulingFair SchedulingPolicy = "fair"
+
+	// SchedulingWeighted takes turns amongst the AADs with requests
+	// waiting like SchedulingFair, but an AAD hands as many requests
+	// on its turn as its weight
+	SchedulingWeighted SchedulingPolicy = "weighted"
+)
+
+func (p SchedulingPolicy) String() string {
+	return string(p)
+}
+
+// SchedulerProps are the properties used to create a Scheduler
+type SchedulerProps struct {
+	// Policy is the scheduling policy. If not set SchedulingFIFO
+	// is used
+	Policy SchedulingPolicy
+
+	// Weights are the weights of the AADs for SchedulingWeighted.
+	// The AADs without a weight have a weight of 1
+	Weights map[string]uint64
+}
+
+// schedulerWaiter is a request waiting to be scheduled
+type schedulerWaiter struct {
+	ready   chan struct{}
+	granted bool
+}
+
+// Scheduler limits the number of requests handed at the same time to
+// the wallet owners of a master to the number of wallets, and decides
+// which of the requests waiting is handed next according to its policy.
+// Requests that are not scheduled stay with the Scheduler rather than
+// in the queue of the master, where they would be taken in order
+type Scheduler struct {
+	mu       sync.Mutex
+	policy   SchedulingPolicy
+	weights  map[string]uint64
+	capacity int
+	running  int
+	waiting  int
+
+	// queues keep the requests waiting for each AAD, and ring the
+	// AADs with requests waiting in the order they take turns.
+	// With SchedulingFIFO all the requests are kept in one queue
+	queues  map[string][]*schedulerWaiter
+	ring    []string
+	current int
+	credit  uint64
+
+	scheduled stats.Counter
+	queued    stats.Counter
+	cancelled stats.Counter
+}
+
+// NewScheduler creates a new Scheduler that hands at most
+// capacity requests at the same time
+func NewScheduler(capacity int, props SchedulerProps) *Scheduler {
+	if capacity <= 0 {
+		panic("capacity must be positive")
+	}
+
+	policy := props.Policy
+	if len(policy) == 0 {
+		policy = SchedulingFIFO
+	}
+
+	return &Scheduler{
+		policy:   policy,
+		weights:  props.Weights,
+		capacity: capacity,
+		queues:   make(map[string][]*schedulerWaiter),
+	}
+}
+
+// Stats returns the metrics of the scheduler
+func (s *Scheduler) Stats() stats.Metrics {
+	s.mu.Lock()
+	running, waiting := s.running, s.waiting
+	s.mu.Unlock()
+
+	return stats.Metrics{
+		"policy":    s.policy.String(),
+		"running":   running,
+		"waiting":   waiting,
+		"scheduled": s.scheduled.Value(),
+		"queued":    s.queued.Value(),
+		"cancelled": s.cancelled.Value(),
+	}
+}
+
+// Acquire blocks until the request of the AAD is scheduled or the
+// context is done. Release must be called once a scheduled request
+// completes
+func (s *Scheduler) Acquire(ctx context.Context, aad string) error {
+	s.mu.Lock()
+	if s.running < s.capacity && s.waiting == 0 {
+		s.running++
+		s.mu.Unlock()
+		s.scheduled.Incr()
+		return nil
+	}
+
+	w := &schedulerWaiter{ready: make(chan struct{})}
+	s.enqueue(s.queueKey(aad), w)
+	s.mu.Unlock()
+	s.queued.Incr()
+
+	select {
+	case <-w.ready:
+		s.scheduled.Incr()
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		granted := w.granted
+		if !granted {
+			s.remove(s.queueKey(aad), w)
+		}
+		s.mu.Unlock()
+
+		// the request may have been scheduled right as the
+		// context was done, in which case its turn is passed on
+		if granted {
+			s.Release()
+		}
+
+		s.cancelled.Incr()
+		return ctx.Err()
+	}
+}
+
+// Release hands the place of a request that completed
+// to the next request waiting
+func (s *Scheduler) Release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.running--
+	for s.running < s.capacity && s.waiting > 0 {
+		w := s.next()
+		w.granted = true
+		s.running++
+		close(w.ready)
+	}
+}
+
+func (s *Scheduler) queueKey(aad string) string {
+	if s.policy == SchedulingFIFO {
+		return ""
+	}
+	return aad
+}
+
+func (s *Scheduler) weight(key string) uint64 {
+	if s.policy != SchedulingWeighted {
+		return 1
+	}
+
+	if weight, ok := s.weights[key]; ok && weight > 0 {
+		return weight
+	}
+	return 1
+}
+
+func (s *Scheduler) enqueue(key string, w *schedulerWaiter) {
+	if len(s.queues[key]) == 0 {
+		s.ring = append(s.ring, key)
+	}
+	s.queues[key] = append(s.queues[key], w)
+	s.waiting++
+}
+
+// next takes the next request waiting from the queue of the AAD
+// whose turn it is. The AAD keeps its turn until it has handed as
+// many requests as its weight or it has no requests left
+func (s *Scheduler) next() *schedulerWaiter {
+	key := s.ring[s.current]
+	if s.credit == 0 {
+		s.credit = s.weight(key)
+	}
+
+	queue := s.queues[key]
+	w := queue[0]
+	queue[0] = nil
+	s.queues[key] = queue[1:]
+	s.waiting--
+	s.credit--
+
+	if len(s.queues[key]) == 0 {
+		s.removeFromRing(s.current)
+		return w
+	}
+
+	if s.credit == 0 {
+		s.current = (s.current + 1) % len(s.ring)
+	}
+	return w
+}
+
+// remove drops a request that is not waiting anymore
+func (s *Scheduler) remove(key string, w *schedulerWaiter) {
+	queue := s.queues[key]
+	for i, q := range queue {
+		if q != w {
+			continue
+		}
+
+		s.queues[key] = append(queue[:i], queue[i+1:]...)
+		s.waiting--
+		break
+	}
+
+	if len(s.queues[key]) > 0 {
+		return
+	}
+
+	for i, k := range s.ring {
+		if k == key {
+			s.removeFromRing(i)
+			return
+		}
+	}
+}
+
+// removeFromRing removes the AAD at index i from the ring once
+// it has no requests waiting. If it was its turn, the turn passes
+// on to the AAD that follows
+func (s *Scheduler) removeFromRing(i int) {
+	delete(s.queues, s.ring[i])
+	s.ring = append(s.ring[:i], s.ring[i+1:]...)
+
+	if i < s.current {
+		s.current--
+	} else if i == s.current {
+		s.credit = 0
+	}
+
+	if s.current >= len(s.ring) {
+		s.current = 0
+	}
+}
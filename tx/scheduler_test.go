@@ -0,0 +1,109 @@
+package tx
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// scheduleOrder fills the capacity of the scheduler, queues a request
+// for each of the AADs in order and returns the order in which the
+// queued requests are scheduled as the running ones are released
+func scheduleOrder(t *testing.T, s *Scheduler, aads []string) []string {
+	ctx := context.Background()
+	assert.Nil(t, s.Acquire(ctx, "running"))
+
+	scheduled := make(chan string, len(aads))
+	for _, aad := range aads {
+		aad := aad
+		go func() {
+			assert.Nil(t, s.Acquire(ctx, aad))
+			scheduled <- aad
+		}()
+
+		// wait for the request to be queued so that the
+		// requests are queued in the order of the AADs
+		waiting := s.Stats()["waiting"]
+		assert.Eventually(t, func() bool {
+			return s.Stats()["waiting"] != waiting
+		}, time.Second, time.Millisecond)
+	}
+
+	order := make([]string, 0, len(aads))
+	for range aads {
+		s.Release()
+		order = append(order, <-scheduled)
+	}
+
+	s.Release()
+	return order
+}
+
+func TestSchedulerFIFO(t *testing.T) {
+	s := NewScheduler(1, SchedulerProps{})
+
+	order := scheduleOrder(t, s, []string{"a", "a", "a", "b", "c"})
+
+	assert.Equal(t, []string{"a", "a", "a", "b", "c"}, order)
+	assert.Equal(t, "fifo", s.Stats()["policy"])
+}
+
+func TestSchedulerFair(t *testing.T) {
+	s := NewScheduler(1, SchedulerProps{Policy: SchedulingFair})
+
+	order := scheduleOrder(t, s, []string{"a", "a", "a", "b", "c"})
+
+	assert.Equal(t, []string{"a", "b", "c", "a", "a"}, order)
+}
+
+func TestSchedulerWeighted(t *testing.T) {
+	s := NewScheduler(1, SchedulerProps{
+		Policy:  SchedulingWeighted,
+		Weights: map[string]uint64{"a": 2},
+	})
+
+	order := scheduleOrder(t, s, []string{"a", "a", "a", "b", "b", "c"})
+
+	assert.Equal(t, []string{"a", "a", "b", "c", "a", "b"}, order)
+}
+
+func TestSchedulerCapacity(t *testing.T) {
+	s := NewScheduler(2, SchedulerProps{Policy: SchedulingFair})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	assert.Nil(t, s.Acquire(ctx, "a"))
+	assert.Nil(t, s.Acquire(ctx, "a"))
+	assert.Equal(t, context.DeadlineExceeded, s.Acquire(ctx, "a"))
+
+	stats := s.Stats()
+	assert.Equal(t, 2, stats["running"])
+	assert.Equal(t, 0, stats["waiting"])
+	assert.Equal(t, uint64(1), stats["cancelled"])
+
+	s.Release()
+	assert.Nil(t, s.Acquire(context.Background(), "b"))
+}
+
+func TestSchedulerCancelledSkipped(t *testing.T) {
+	s := NewScheduler(1, SchedulerProps{Policy: SchedulingFair})
+	assert.Nil(t, s.Acquire(context.Background(), "a"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancelled := make(chan error)
+	go func() {
+		cancelled <- s.Acquire(ctx, "b")
+	}()
+	assert.Eventually(t, func() bool {
+		return s.Stats()["waiting"] == 1
+	}, time.Second, time.Millisecond)
+
+	cancel()
+	assert.Equal(t, context.Canceled, <-cancelled)
+
+	s.Release()
+	assert.Nil(t, s.Acquire(context.Background(), "c"))
+	assert.Equal(t, 1, s.Stats()["running"])
+}
@@ -0,0 +1,192 @@
+package tx
+
+import (
+	"sync"
+	"time"
+
+	"github.com/oasislabs/oasis-gateway/stats"
+)
+
+// WalletSelection defines how the wallet owner that submits
+// a transaction is chosen
+type WalletSelection string
+
+const (
+	// WalletSelectionShared hands the transaction to the first
+	// wallet owner that is idle
+	WalletSelectionShared WalletSelection = "shared"
+
+	// WalletSelectionRoundRobin hands the transactions to the
+	// wallet owners in turns
+	WalletSelectionRoundRobin WalletSelection = "round_robin"
+
+	// WalletSelectionLeastPending hands the transaction to the
+	// wallet owner with the fewest transactions pending
+	WalletSelectionLeastPending WalletSelection = "least_pending"
+)
+
+func (s WalletSelection) String() string {
+	return string(s)
+}
+
+// DefaultDrainedRetry is the time a wallet that ran out of funds
+// is skipped if no other is configured
+const DefaultDrainedRetry = time.Minute
+
+// WalletSelectorProps are the properties used to create a WalletSelector
+type WalletSelectorProps struct {
+	// Selection defines how the wallet owner is chosen. If not
+	// set WalletSelectionShared is used
+	Selection WalletSelection
+
+	// DrainedRetry is the time a wallet that ran out of funds is
+	// skipped before it is handed transactions again. If it is 0
+	// DefaultDrainedRetry is used
+	DrainedRetry time.Duration
+}
+
+// selectedWallet is the state kept by the WalletSelector of a wallet
+type selectedWallet struct {
+	address      string
+	pending      int
+	drainedUntil time.Time
+}
+
+// WalletSelector chooses the wallet owner of a master that submits
+// each transaction. The wallets that run out of funds are skipped
+// for a while, so that their transactions are rebalanced across the
+// wallets that can still pay for them
+type WalletSelector struct {
+	mu           sync.Mutex
+	selection    WalletSelection
+	drainedRetry time.Duration
+	wallets      []*selectedWallet
+	next         int
+
+	rebalanced stats.Counter
+}
+
+// NewWalletSelector creates a new WalletSelector for the
+// wallets with the provided addresses
+func NewWalletSelector(addresses []string, props WalletSelectorProps) *WalletSelector {
+	selection := props.Selection
+	if len(selection) == 0 {
+		selection = WalletSelectionShared
+	}
+
+	drainedRetry := props.DrainedRetry
+	if drainedRetry == 0 {
+		drainedRetry = DefaultDrainedRetry
+	}
+
+	wallets := make([]*selectedWallet, 0, len(addresses))
+	for _, address := range addresses {
+		wallets = append(wallets, &selectedWallet{address: address})
+	}
+
+	return &WalletSelector{
+		selection:    selection,
+		drainedRetry: drainedRetry,
+		wallets:      wallets,
+	}
+}
+
+// Shared returns true if the transactions are not handed to a
+// wallet owner chosen by the selector but to the first one idle
+func (s *WalletSelector) Shared() bool {
+	return s.selection == WalletSelectionShared
+}
+
+// Stats returns the metrics of the selector
+func (s *WalletSelector) Stats() stats.Metrics {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	wallets := make(stats.Metrics, len(s.wallets))
+	for _, w := range s.wallets {
+		wallets[w.address] = stats.Metrics{
+			"pending": w.pending,
+			"drained": now.Before(w.drainedUntil),
+		}.WithLabels(stats.Labels{"wallet": w.address})
+	}
+
+	return stats.Metrics{
+		"selection":  s.selection.String(),
+		"rebalanced": s.rebalanced.Value(),
+		"wallets":    wallets,
+	}
+}
+
+// Select chooses the wallet that submits the next transaction amongst
+// those that have not been tried for it yet, and records that it has
+// one more transaction pending. Wallets that ran out of funds are only
+// chosen if all the others have been tried, since they may have been
+// funded in the meantime. Done must be called once the transaction
+// completes
+func (s *WalletSelector) Select(tried map[string]bool) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	selected := -1
+	selectedDrained := false
+	for n := 0; n < len(s.wallets); n++ {
+		i := (s.next + n) % len(s.wallets)
+		w := s.wallets[i]
+		if tried[w.address] {
+			continue
+		}
+
+		drained := now.Before(w.drainedUntil)
+		if selected >= 0 && drained && !selectedDrained {
+			continue
+		}
+
+		if selected < 0 || (selectedDrained && !drained) ||
+			(s.selection == WalletSelectionLeastPending && w.pending < s.wallets[selected].pending) {
+			selected, selectedDrained = i, drained
+		}
+
+		if s.selection != WalletSelectionLeastPending && !selectedDrained {
+			break
+		}
+	}
+
+	if selected < 0 {
+		return "", false
+	}
+
+	s.next = (selected + 1) % len(s.wallets)
+	s.wallets[selected].pending++
+	return s.wallets[selected].address, true
+}
+
+// Done records that a transaction handed to the wallet completed
+func (s *WalletSelector) Done(address string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, w := range s.wallets {
+		if w.address == address {
+			w.pending--
+			return
+		}
+	}
+}
+
+// Drained records that the wallet ran out of funds, so that it is
+// skipped until the DrainedRetry interval elapses
+func (s *WalletSelector) Drained(address string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, w := range s.wallets {
+		if w.address == address {
+			w.drainedUntil = time.Now().Add(s.drainedRetry)
+			break
+		}
+	}
+
+	s.rebalanced.Incr()
+}
@@ -0,0 +1,77 @@
+package tx
+
+import (
+	"testing"
+	"time"
+
+	"github.com/oasislabs/oasis-gateway/stats"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWalletSelectorRoundRobin(t *testing.T) {
+	s := NewWalletSelector([]string{"a", "b", "c"}, WalletSelectorProps{
+		Selection: WalletSelectionRoundRobin,
+	})
+
+	var selected []string
+	for i := 0; i < 4; i++ {
+		wallet, ok := s.Select(nil)
+		assert.True(t, ok)
+		selected = append(selected, wallet)
+	}
+
+	assert.Equal(t, []string{"a", "b", "c", "a"}, selected)
+}
+
+func TestWalletSelectorLeastPending(t *testing.T) {
+	s := NewWalletSelector([]string{"a", "b", "c"}, WalletSelectorProps{
+		Selection: WalletSelectionLeastPending,
+	})
+
+	for _, expected := range []string{"a", "b", "c"} {
+		wallet, _ := s.Select(nil)
+		assert.Equal(t, expected, wallet)
+	}
+
+	s.Done("b")
+	wallet, _ := s.Select(nil)
+	assert.Equal(t, "b", wallet)
+}
+
+func TestWalletSelectorDrained(t *testing.T) {
+	s := NewWalletSelector([]string{"a", "b"}, WalletSelectorProps{
+		Selection:    WalletSelectionRoundRobin,
+		DrainedRetry: time.Hour,
+	})
+
+	s.Drained("a")
+	for i := 0; i < 2; i++ {
+		wallet, _ := s.Select(nil)
+		assert.Equal(t, "b", wallet)
+	}
+
+	// a drained wallet is still tried once the others have been
+	wallet, ok := s.Select(map[string]bool{"b": true})
+	assert.True(t, ok)
+	assert.Equal(t, "a", wallet)
+
+	_, ok = s.Select(map[string]bool{"a": true, "b": true})
+	assert.False(t, ok)
+
+	metrics := s.Stats()
+	assert.Equal(t, uint64(1), metrics["rebalanced"])
+	assert.Equal(t, true, metrics["wallets"].(stats.Metrics)["a"].(stats.Metrics)["drained"])
+}
+
+func TestWalletSelectorDrainedRetry(t *testing.T) {
+	s := NewWalletSelector([]string{"a", "b"}, WalletSelectorProps{
+		Selection:    WalletSelectionRoundRobin,
+		DrainedRetry: time.Millisecond,
+	})
+
+	s.Drained("a")
+	time.Sleep(2 * time.Millisecond)
+
+	wallet, _ := s.Select(nil)
+	assert.Equal(t, "a", wallet)
+}
@@ -0,0 +1,132 @@
+// Package txbench defines the benchmark scenarios for the
+// tx.Executor. The scenarios run against the mock eth client so that
+// they measure the overhead of the executor itself and are used both
+// by the go benchmarks in the tx package and by cmd/tx-bench.
+package txbench
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"io/ioutil"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/oasislabs/oasis-gateway/callback/callbacktest"
+	"github.com/oasislabs/oasis-gateway/concurrent"
+	"github.com/oasislabs/oasis-gateway/eth"
+	"github.com/oasislabs/oasis-gateway/eth/ethtest"
+	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/oasislabs/oasis-gateway/tx"
+)
+
+// Scenario describes a workload for the executor
+type Scenario struct {
+	// Name is a human readable identifier for the scenario
+	Name string
+
+	// Wallets is the number of wallets the executor owns
+	Wallets int
+
+	// FailEvery if set makes every FailEvery-th transaction
+	// submission fail with an invalid nonce error, so that the
+	// executor needs to refresh the nonce and retry
+	FailEvery uint64
+
+	// Budget is the maximum time per operation that is considered
+	// acceptable for the scenario
+	Budget time.Duration
+}
+
+// Scenarios are the scenarios that are benchmarked by default
+var Scenarios = []Scenario{
+	{Name: "SingleWallet", Wallets: 1, Budget: time.Millisecond},
+	{Name: "MultiWallet", Wallets: 8, Budget: time.Millisecond},
+	{Name: "RetryHeavy", Wallets: 1, FailEvery: 2, Budget: 3 * time.Millisecond},
+}
+
+// retryConfig retries immediately so that the retry heavy scenarios
+// measure the cost of the retries and not the backoff
+var retryConfig = concurrent.RetryConfig{
+	Attempts:        10,
+	BaseExp:         1,
+	BaseTimeout:     time.Microsecond,
+	MaxRetryTimeout: time.Microsecond,
+}
+
+// flakyClient fails every failEvery-th transaction submission with
+// an invalid nonce
+type flakyClient struct {
+	eth.Client
+	failEvery uint64
+	sent      uint64
+}
+
+func (c *flakyClient) SendTransaction(ctx context.Context, tx *types.Transaction) (eth.SendTransactionResponse, error) {
+	if c.failEvery > 0 && atomic.AddUint64(&c.sent, 1)%c.failEvery == 0 {
+		return eth.SendTransactionResponse{}, eth.ErrInvalidNonce
+	}
+
+	return c.Client.SendTransaction(ctx, tx)
+}
+
+// NewExecutor creates an executor for the scenario backed by
+// the mock eth client
+func NewExecutor(ctx context.Context, scenario Scenario) (*tx.Executor, error) {
+	keys := make([]*ecdsa.PrivateKey, 0, scenario.Wallets)
+	for i := 0; i < scenario.Wallets; i++ {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate private key %s", err.Error())
+		}
+		keys = append(keys, key)
+	}
+
+	client := &ethtest.MockClient{}
+	ethtest.ImplementMock(client)
+
+	callbacks := &callbacktest.MockClient{}
+	callbacktest.ImplementMock(callbacks)
+
+	return tx.NewExecutor(ctx, &tx.ExecutorServices{
+		Logger:    log.NewLogrus(log.LogrusLoggerProperties{Output: ioutil.Discard}),
+		Client:    &flakyClient{Client: client, failEvery: scenario.FailEvery},
+		Callbacks: callbacks,
+	}, &tx.ExecutorProps{
+		PrivateKeys: keys,
+		RetryConfig: &retryConfig,
+	})
+}
+
+// Benchmark returns the benchmark function for the scenario. Requests
+// are issued in parallel so that all the wallets are kept busy
+func Benchmark(scenario Scenario) func(b *testing.B) {
+	return func(b *testing.B) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		executor, err := NewExecutor(ctx, scenario)
+		if err != nil {
+			b.Fatalf("failed to create executor: %s", err.Error())
+		}
+
+		var id uint64
+		b.SetParallelism(scenario.Wallets)
+		b.ResetTimer()
+
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				if _, err := executor.Execute(ctx, tx.ExecuteRequest{
+					ID:      atomic.AddUint64(&id, 1),
+					Address: "0x0000000000000000000000000000000000000001",
+					Data:    []byte{0x01},
+				}); err != nil {
+					b.Fatalf("failed to execute transaction: %s", err.Error())
+				}
+			}
+		})
+	}
+}
@@ -5,10 +5,10 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/crypto"
 	stderr "github.com/pkg/errors"
 
 	"github.com/oasislabs/oasis-gateway/errors"
+	"github.com/oasislabs/oasis-gateway/eth"
 )
 
 // Wallet is an interface for any type that signs transactions
@@ -16,32 +16,47 @@ import (
 type Wallet interface {
 	Address() common.Address
 	SignTransaction(tx *types.Transaction) (*types.Transaction, errors.Err)
+	SignDynamicFeeTransaction(tx *eth.DynamicFeeTransaction) (*eth.DynamicFeeTransaction, errors.Err)
 }
 
 type InternalWallet struct {
-	privateKey *ecdsa.PrivateKey
-	signer     types.Signer
+	key    *SecureKey
+	signer types.Signer
 }
 
 func NewWallet(
 	privateKey *ecdsa.PrivateKey,
 	signer types.Signer,
 ) *InternalWallet {
-	w := &InternalWallet{
-		privateKey: privateKey,
-		signer:     signer,
-	}
+	return NewWalletWithKey(NewSecureKey(privateKey), signer)
+}
 
-	return w
+// NewWalletWithKey creates a new wallet that signs with the key.
+// The wallet cannot sign once the key is destroyed
+func NewWalletWithKey(key *SecureKey, signer types.Signer) *InternalWallet {
+	return &InternalWallet{
+		key:    key,
+		signer: signer,
+	}
 }
 
 func (w *InternalWallet) Address() common.Address {
-	return crypto.PubkeyToAddress(w.privateKey.PublicKey)
+	return w.key.Address()
 }
 
 func (w *InternalWallet) SignTransaction(tx *types.Transaction) (*types.Transaction, errors.Err) {
 	var err error
-	tx, err = types.SignTx(tx, w.signer, w.privateKey)
+	tx, err = w.key.SignTx(tx, w.signer)
+	if err != nil {
+		err := errors.New(errors.ErrSignedTx, stderr.Wrap(err, "Failed to sign transaction"))
+		return nil, err
+	}
+
+	return tx, nil
+}
+
+func (w *InternalWallet) SignDynamicFeeTransaction(tx *eth.DynamicFeeTransaction) (*eth.DynamicFeeTransaction, errors.Err) {
+	tx, err := w.key.SignDynamicFeeTx(tx)
 	if err != nil {
 		err := errors.New(errors.ErrSignedTx, stderr.Wrap(err, "Failed to sign transaction"))
 		return nil, err
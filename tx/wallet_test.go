@@ -10,6 +10,8 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/stretchr/testify/assert"
+
+	"github.com/oasislabs/oasis-gateway/eth"
 )
 
 func initializeWallet() (Wallet, error) {
@@ -58,3 +60,18 @@ func TestWalletSignTransaction(t *testing.T) {
 	assert.NotEqual(t, new(big.Int), R)
 	assert.NotEqual(t, new(big.Int), S)
 }
+
+func TestWalletSignDynamicFeeTransaction(t *testing.T) {
+	wallet, err := initializeWallet()
+	assert.Nil(t, err)
+
+	to := common.HexToAddress("0x6f6704e5a10332af6672e50b3d9754dc460dfa4d")
+	tx, err := wallet.SignDynamicFeeTransaction(eth.NewDynamicFeeTransaction(
+		big.NewInt(42261), 0, &to, 1000000, big.NewInt(1000000000), big.NewInt(2000000000), []byte("data")))
+	assert.Nil(t, err)
+
+	data, err := tx.MarshalBinary()
+	assert.Nil(t, err)
+	assert.Equal(t, byte(eth.DynamicFeeTxType), data[0])
+	assert.NotEqual(t, common.Hash{}, tx.Hash())
+}
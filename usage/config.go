@@ -0,0 +1,117 @@
+package usage
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/oasislabs/oasis-gateway/config"
+	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+type Provider string
+
+const (
+	// ProviderDisabled does not record the usage
+	ProviderDisabled Provider = ""
+
+	// ProviderMem keeps the usage in memory
+	ProviderMem Provider = "mem"
+
+	// ProviderRedis keeps the usage in redis
+	ProviderRedis Provider = "redis"
+)
+
+func (p Provider) String() string {
+	return string(p)
+}
+
+type Config struct {
+	Provider      Provider
+	FlushInterval time.Duration
+	Retention     time.Duration
+	RedisAddrs    []string
+}
+
+func (c *Config) Log(fields log.Fields) {
+	fields.Add("usage.provider", c.Provider)
+	fields.Add("usage.flush_interval_ms", int64(c.FlushInterval/time.Millisecond))
+	fields.Add("usage.retention_days", int64(c.Retention/(24*time.Hour)))
+	fields.Add("usage.redis.addrs", strings.Join(c.RedisAddrs, ","))
+}
+
+func (c *Config) Configure(v *viper.Viper) error {
+	c.Provider = Provider(v.GetString("usage.provider"))
+	switch c.Provider {
+	case ProviderDisabled:
+		return nil
+	case ProviderMem:
+	case ProviderRedis:
+		c.RedisAddrs = v.GetStringSlice("usage.redis.addrs")
+		if len(c.RedisAddrs) == 0 {
+			return config.ErrKeyNotSet{Key: "usage.redis.addrs"}
+		}
+	default:
+		return config.ErrInvalidValue{
+			Key:          "usage.provider",
+			InvalidValue: c.Provider.String(),
+			Values:       []string{ProviderMem.String(), ProviderRedis.String()},
+		}
+	}
+
+	flushInterval := v.GetInt64("usage.flush_interval_ms")
+	if flushInterval <= 0 {
+		return errors.New("usage.flush_interval_ms must be positive")
+	}
+	c.FlushInterval = time.Duration(flushInterval) * time.Millisecond
+
+	retention := v.GetInt64("usage.retention_days")
+	if retention <= 0 {
+		return errors.New("usage.retention_days must be positive")
+	}
+	c.Retention = time.Duration(retention) * 24 * time.Hour
+
+	return nil
+}
+
+func (c *Config) Bind(v *viper.Viper, cmd *cobra.Command) error {
+	cmd.PersistentFlags().String("usage.provider", ProviderDisabled.String(),
+		"provider used to keep the daily usage of each AAD. Options are "+
+			ProviderMem.String()+" and "+ProviderRedis.String()+
+			". If not set the usage is not recorded.")
+	cmd.PersistentFlags().Int64("usage.flush_interval_ms", 10000,
+		"interval in milliseconds at which the recorded usage is persisted.")
+	cmd.PersistentFlags().Int64("usage.retention_days", 90,
+		"number of days the usage is kept.")
+	cmd.PersistentFlags().StringSlice("usage.redis.addrs", nil,
+		"addresses of the redis instance, or of the seed instances of the redis "+
+			"cluster, in which the usage is kept.")
+	return nil
+}
+
+// NewRecorderFromConfig creates the Recorder defined by the
+// configuration. It returns nil if the usage is not recorded
+func NewRecorderFromConfig(ctx context.Context, logger log.Logger, config *Config) *Recorder {
+	var store Store
+	switch config.Provider {
+	case ProviderMem:
+		store = NewMemStore()
+	case ProviderRedis:
+		store = NewRedisStore(RedisStoreProps{
+			Addrs:     config.RedisAddrs,
+			Retention: config.Retention,
+		})
+	default:
+		return nil
+	}
+
+	return NewRecorder(ctx, Services{
+		Logger: logger,
+		Store:  store,
+	}, Props{
+		FlushInterval: config.FlushInterval,
+	})
+}
@@ -0,0 +1,32 @@
+package usage
+
+import (
+	"net/http"
+
+	auth "github.com/oasislabs/oasis-gateway/auth/core"
+	"github.com/oasislabs/oasis-gateway/rpc"
+)
+
+// HttpMiddleware records the requests served by the next middleware
+// for the AAD that issued them. It must be placed after the
+// authentication middleware so that the AAD is set in the context
+type HttpMiddleware struct {
+	recorder *Recorder
+	next     rpc.HttpMiddleware
+}
+
+// NewHttpMiddleware creates a new middleware that records
+// the requests served by next
+func NewHttpMiddleware(recorder *Recorder, next rpc.HttpMiddleware) *HttpMiddleware {
+	return &HttpMiddleware{recorder: recorder, next: next}
+}
+
+// ServeHTTP is the implementation of rpc.HttpMiddleware for HttpMiddleware
+func (m *HttpMiddleware) ServeHTTP(req *http.Request) (interface{}, error) {
+	v, err := m.next.ServeHTTP(req)
+
+	aad, _ := req.Context().Value(auth.AAD{}).(string)
+	m.recorder.Request(aad, req.URL.Path, err != nil)
+
+	return v, err
+}
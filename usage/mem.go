@@ -0,0 +1,69 @@
+package usage
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// MemStore is a Store that keeps the usage in memory. It is only
+// meant for single instance deployments and testing because the
+// usage is lost when the gateway is restarted
+type MemStore struct {
+	lock   sync.Mutex
+	usages map[string]map[string]*Usage
+}
+
+// NewMemStore creates a new empty MemStore
+func NewMemStore() *MemStore {
+	return &MemStore{usages: make(map[string]map[string]*Usage)}
+}
+
+// Add is the implementation of Store.Add for MemStore
+func (s *MemStore) Add(ctx context.Context, usages []Usage) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, usage := range usages {
+		day, ok := s.usages[usage.Day]
+		if !ok {
+			day = make(map[string]*Usage)
+			s.usages[usage.Day] = day
+		}
+
+		stored, ok := day[usage.AAD]
+		if !ok {
+			stored = &Usage{AAD: usage.AAD, Day: usage.Day}
+			day[usage.AAD] = stored
+		}
+
+		stored.add(usage)
+	}
+
+	return nil
+}
+
+// Report is the implementation of Store.Report for MemStore
+func (s *MemStore) Report(ctx context.Context, req ReportRequest) ([]Usage, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var usages []Usage
+	for _, day := range req.days() {
+		var aads []string
+		for aad := range s.usages[day] {
+			if len(req.AAD) == 0 || req.AAD == aad {
+				aads = append(aads, aad)
+			}
+		}
+		sort.Strings(aads)
+
+		for _, aad := range aads {
+			usage := Usage{AAD: aad, Day: day}
+			usage.add(*s.usages[day][aad])
+			usages = append(usages, usage)
+		}
+	}
+
+	return usages, nil
+}
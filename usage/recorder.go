@@ -0,0 +1,184 @@
+package usage
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	backend "github.com/oasislabs/oasis-gateway/backend/core"
+	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/oasislabs/oasis-gateway/stats"
+)
+
+// Services are the services required by the Recorder
+type Services struct {
+	Logger log.Logger
+	Store  Store
+}
+
+// Props are the properties that define how the
+// Recorder persists the usage
+type Props struct {
+	// FlushInterval is the interval at which the usage recorded
+	// in memory is added to the Store
+	FlushInterval time.Duration
+}
+
+type usageKey struct {
+	day string
+	aad string
+}
+
+// Recorder aggregates the usage of the gateway in memory and
+// periodically adds it to the Store, so that recording the usage
+// does not add a request to the Store for each request served
+type Recorder struct {
+	logger  log.Logger
+	store   Store
+	now     func() time.Time
+	lock    sync.Mutex
+	pending map[usageKey]*Usage
+	flushes stats.Counter
+	failed  stats.Counter
+}
+
+// NewRecorder creates a new Recorder. The Recorder flushes the
+// usage at the FlushInterval until the context is done
+func NewRecorder(ctx context.Context, services Services, props Props) *Recorder {
+	r := &Recorder{
+		logger:  services.Logger.ForClass("usage", "Recorder"),
+		store:   services.Store,
+		now:     time.Now,
+		pending: make(map[usageKey]*Usage),
+	}
+
+	if props.FlushInterval > 0 {
+		go r.startLoop(ctx, props.FlushInterval)
+	}
+
+	return r
+}
+
+func (r *Recorder) Name() string {
+	return "usage.Recorder"
+}
+
+// Stats is the implementation of stats.Collector for Recorder
+func (r *Recorder) Stats() stats.Metrics {
+	r.lock.Lock()
+	pending := len(r.pending)
+	r.lock.Unlock()
+
+	return stats.Metrics{
+		"pending": pending,
+		"flushes": r.flushes.Value(),
+		"failed":  r.failed.Value(),
+	}
+}
+
+func (r *Recorder) startLoop(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			// the context is already done so the last flush
+			// needs a context of its own
+			_ = r.Flush(context.Background())
+			return
+		case <-ticker.C:
+			_ = r.Flush(ctx)
+		}
+	}
+}
+
+// record applies fn to the pending usage of the AAD for today
+func (r *Recorder) record(aad string, fn func(u *Usage)) {
+	if len(aad) == 0 {
+		return
+	}
+
+	key := usageKey{day: Day(r.now()), aad: aad}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	usage, ok := r.pending[key]
+	if !ok {
+		usage = &Usage{AAD: aad, Day: key.day, Requests: make(map[string]uint64)}
+		r.pending[key] = usage
+	}
+
+	fn(usage)
+}
+
+// Request records a request issued by the AAD to the api
+func (r *Recorder) Request(aad, api string, failed bool) {
+	r.record(aad, func(u *Usage) {
+		u.Requests[api]++
+		if failed {
+			u.Errors++
+		}
+	})
+}
+
+// Consume is the implementation of backend.Consumer for Recorder
+// so that the events generated for each AAD are recorded
+func (r *Recorder) Consume(ctx context.Context, pub backend.Publication) {
+	r.record(pub.AAD, func(u *Usage) {
+		u.Events++
+		if pub.Event.EventType() == backend.ErrorEventType {
+			u.Errors++
+		}
+	})
+}
+
+// Flush adds the usage recorded in memory to the Store. If
+// the Store fails the usage is kept to be flushed later
+func (r *Recorder) Flush(ctx context.Context) error {
+	r.lock.Lock()
+	pending := r.pending
+	r.pending = make(map[usageKey]*Usage)
+	r.lock.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	usages := make([]Usage, 0, len(pending))
+	for _, usage := range pending {
+		usages = append(usages, *usage)
+	}
+
+	if err := r.store.Add(ctx, usages); err != nil {
+		r.failed.Incr()
+		r.logger.Warn(ctx, "failed to flush usage", log.MapFields{
+			"call_type": "FlushUsageFailure",
+			"err":       err.Error(),
+		})
+
+		r.lock.Lock()
+		for key, usage := range pending {
+			if current, ok := r.pending[key]; ok {
+				usage.add(*current)
+			}
+			r.pending[key] = usage
+		}
+		r.lock.Unlock()
+		return err
+	}
+
+	r.flushes.Incr()
+	return nil
+}
+
+// Report flushes the usage recorded in memory and returns
+// the usage kept in the Store for the request
+func (r *Recorder) Report(ctx context.Context, req ReportRequest) ([]Usage, error) {
+	if err := r.Flush(ctx); err != nil {
+		return nil, err
+	}
+
+	return r.store.Report(ctx, req)
+}
@@ -0,0 +1,131 @@
+package usage
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	auth "github.com/oasislabs/oasis-gateway/auth/core"
+	backend "github.com/oasislabs/oasis-gateway/backend/core"
+	"github.com/oasislabs/oasis-gateway/log"
+	"github.com/oasislabs/oasis-gateway/rpc"
+	"github.com/stretchr/testify/assert"
+)
+
+var Context = context.TODO()
+
+var Logger = log.NewLogrus(log.LogrusLoggerProperties{
+	Output: ioutil.Discard,
+})
+
+var day = time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+
+type failingStore struct {
+	Store
+	err error
+}
+
+func (s *failingStore) Add(ctx context.Context, usages []Usage) error {
+	if s.err != nil {
+		return s.err
+	}
+	return s.Store.Add(ctx, usages)
+}
+
+func newRecorder(store Store) *Recorder {
+	recorder := NewRecorder(Context, Services{Logger: Logger, Store: store}, Props{})
+	recorder.now = func() time.Time { return day }
+	return recorder
+}
+
+func TestRecorderReport(t *testing.T) {
+	recorder := newRecorder(NewMemStore())
+
+	recorder.Request("aad1", "/v0/api/service/execute", false)
+	recorder.Request("aad1", "/v0/api/service/execute", true)
+	recorder.Request("aad2", "/v0/api/service/poll", false)
+	recorder.Request("", "/v0/api/service/poll", false)
+	recorder.Consume(Context, backend.Publication{AAD: "aad1", Event: backend.ExecuteServiceResponse{}})
+	recorder.Consume(Context, backend.Publication{AAD: "aad1", Event: backend.ErrorEvent{}})
+
+	usages, err := recorder.Report(Context, ReportRequest{From: day, To: day})
+	assert.Nil(t, err)
+	assert.Equal(t, []Usage{
+		{
+			AAD:      "aad1",
+			Day:      "2020-06-01",
+			Requests: map[string]uint64{"/v0/api/service/execute": 2},
+			Events:   2,
+			Errors:   2,
+		},
+		{
+			AAD:      "aad2",
+			Day:      "2020-06-01",
+			Requests: map[string]uint64{"/v0/api/service/poll": 1},
+		},
+	}, usages)
+
+	usages, err = recorder.Report(Context, ReportRequest{AAD: "aad2", From: day, To: day})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(usages))
+	assert.Equal(t, "aad2", usages[0].AAD)
+}
+
+func TestRecorderReportDays(t *testing.T) {
+	recorder := newRecorder(NewMemStore())
+	recorder.Request("aad", "/v0/api/service/execute", false)
+
+	recorder.now = func() time.Time { return day.Add(24 * time.Hour) }
+	recorder.Request("aad", "/v0/api/service/execute", false)
+
+	usages, err := recorder.Report(Context, ReportRequest{From: day, To: day.Add(48 * time.Hour)})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(usages))
+	assert.Equal(t, "2020-06-01", usages[0].Day)
+	assert.Equal(t, "2020-06-02", usages[1].Day)
+}
+
+func TestRecorderFlushFailureKeepsUsage(t *testing.T) {
+	store := &failingStore{Store: NewMemStore(), err: errors.New("connection refused")}
+	recorder := newRecorder(store)
+
+	recorder.Request("aad", "/v0/api/service/execute", false)
+	assert.Error(t, recorder.Flush(Context))
+
+	recorder.Request("aad", "/v0/api/service/execute", false)
+	store.err = nil
+
+	usages, err := recorder.Report(Context, ReportRequest{From: day, To: day})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]uint64{"/v0/api/service/execute": 2}, usages[0].Requests)
+	assert.Equal(t, uint64(1), recorder.Stats()["failed"])
+}
+
+func TestHttpMiddleware(t *testing.T) {
+	recorder := newRecorder(NewMemStore())
+	middleware := NewHttpMiddleware(recorder, rpc.HttpMiddlewareFunc(func(req *http.Request) (interface{}, error) {
+		if req.URL.Path == "/fail" {
+			return nil, errors.New("error")
+		}
+		return nil, nil
+	}))
+
+	for _, path := range []string{"/ok", "/fail"} {
+		req := httptest.NewRequest("POST", path, nil)
+		req = req.WithContext(context.WithValue(req.Context(), auth.AAD{}, "aad"))
+		_, _ = middleware.ServeHTTP(req)
+	}
+
+	usages, err := recorder.Report(Context, ReportRequest{From: day, To: day})
+	assert.Nil(t, err)
+	assert.Equal(t, []Usage{{
+		AAD:      "aad",
+		Day:      "2020-06-01",
+		Requests: map[string]uint64{"/ok": 1, "/fail": 1},
+		Errors:   1,
+	}}, usages)
+}
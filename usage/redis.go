@@ -0,0 +1,158 @@
+package usage
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+const (
+	eventsField   = "events"
+	errorsField   = "errors"
+	requestsField = "requests:"
+)
+
+// RedisClient is the interface to the redis client
+// used by the RedisStore
+type RedisClient interface {
+	Pipeline() redis.Pipeliner
+	SMembers(key string) *redis.StringSliceCmd
+	HGetAll(key string) *redis.StringStringMapCmd
+}
+
+// RedisStoreProps are the properties to create a RedisStore
+type RedisStoreProps struct {
+	// Addrs are the addresses of the redis instance or
+	// of the seed instances of a redis cluster
+	Addrs []string
+
+	// Retention is the time the usage of a day is
+	// kept after it was last updated
+	Retention time.Duration
+}
+
+// RedisStore is a Store that keeps the usage of each AAD and
+// day in a hash so that it is shared by all the gateways that
+// use the same redis deployment
+type RedisStore struct {
+	client    RedisClient
+	retention time.Duration
+}
+
+// NewRedisStore creates a new RedisStore connected to the
+// redis deployment at the provided addresses
+func NewRedisStore(props RedisStoreProps) *RedisStore {
+	return NewRedisStoreWithClient(redis.NewUniversalClient(&redis.UniversalOptions{
+		Addrs: props.Addrs,
+	}), props.Retention)
+}
+
+// NewRedisStoreWithClient creates a new RedisStore
+// that uses the provided client
+func NewRedisStoreWithClient(client RedisClient, retention time.Duration) *RedisStore {
+	return &RedisStore{client: client, retention: retention}
+}
+
+// aadsKey is the key of the set of AADs that
+// have usage recorded for the day
+func aadsKey(day string) string {
+	return "usage:" + day + ":aads"
+}
+
+// hashKey is the key of the hash with the
+// usage of the AAD for the day
+func hashKey(day, aad string) string {
+	return "usage:" + day + ":aad:" + aad
+}
+
+// Add is the implementation of Store.Add for RedisStore
+func (s *RedisStore) Add(ctx context.Context, usages []Usage) error {
+	if len(usages) == 0 {
+		return nil
+	}
+
+	pipe := s.client.Pipeline()
+	defer pipe.Close()
+
+	for _, usage := range usages {
+		key := hashKey(usage.Day, usage.AAD)
+		for api, count := range usage.Requests {
+			pipe.HIncrBy(key, requestsField+api, int64(count))
+		}
+		if usage.Events > 0 {
+			pipe.HIncrBy(key, eventsField, int64(usage.Events))
+		}
+		if usage.Errors > 0 {
+			pipe.HIncrBy(key, errorsField, int64(usage.Errors))
+		}
+		pipe.SAdd(aadsKey(usage.Day), usage.AAD)
+
+		if s.retention > 0 {
+			pipe.Expire(key, s.retention)
+			pipe.Expire(aadsKey(usage.Day), s.retention)
+		}
+	}
+
+	_, err := pipe.Exec()
+	return err
+}
+
+// Report is the implementation of Store.Report for RedisStore
+func (s *RedisStore) Report(ctx context.Context, req ReportRequest) ([]Usage, error) {
+	var usages []Usage
+	for _, day := range req.days() {
+		aads := []string{req.AAD}
+		if len(req.AAD) == 0 {
+			members, err := s.client.SMembers(aadsKey(day)).Result()
+			if err != nil {
+				return nil, err
+			}
+			sort.Strings(members)
+			aads = members
+		}
+
+		for _, aad := range aads {
+			fields, err := s.client.HGetAll(hashKey(day, aad)).Result()
+			if err != nil {
+				return nil, err
+			}
+			if len(fields) == 0 {
+				continue
+			}
+
+			usage, err := parseUsage(day, aad, fields)
+			if err != nil {
+				return nil, err
+			}
+			usages = append(usages, usage)
+		}
+	}
+
+	return usages, nil
+}
+
+// parseUsage parses the fields of the hash that keeps the usage
+func parseUsage(day, aad string, fields map[string]string) (Usage, error) {
+	usage := Usage{AAD: aad, Day: day, Requests: make(map[string]uint64)}
+	for field, value := range fields {
+		count, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return Usage{}, err
+		}
+
+		switch {
+		case field == eventsField:
+			usage.Events = count
+		case field == errorsField:
+			usage.Errors = count
+		case strings.HasPrefix(field, requestsField):
+			usage.Requests[strings.TrimPrefix(field, requestsField)] = count
+		}
+	}
+
+	return usage, nil
+}
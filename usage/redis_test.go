@@ -0,0 +1,28 @@
+package usage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseUsage(t *testing.T) {
+	usage, err := parseUsage("2020-06-01", "aad", map[string]string{
+		"requests:/v0/api/service/execute": "3",
+		"events":                           "2",
+		"errors":                           "1",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, Usage{
+		AAD:      "aad",
+		Day:      "2020-06-01",
+		Requests: map[string]uint64{"/v0/api/service/execute": 3},
+		Events:   2,
+		Errors:   1,
+	}, usage)
+}
+
+func TestParseUsageInvalidCount(t *testing.T) {
+	_, err := parseUsage("2020-06-01", "aad", map[string]string{"events": "x"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,80 @@
+package usage
+
+import (
+	"context"
+	"time"
+)
+
+// DayLayout is the layout of the days for which the
+// usage is recorded, which are always in UTC
+const DayLayout = "2006-01-02"
+
+// Day returns the day of t in which usage is recorded
+func Day(t time.Time) string {
+	return t.UTC().Format(DayLayout)
+}
+
+// Usage are the counters of the usage of the gateway
+// by an AAD during a day
+type Usage struct {
+	// AAD is the identifier of the user of the gateway
+	AAD string `json:"aad"`
+
+	// Day is the day in which the usage was recorded
+	Day string `json:"day"`
+
+	// Requests is the number of requests issued to each API
+	Requests map[string]uint64 `json:"requests"`
+
+	// Events is the number of events generated for the AAD
+	Events uint64 `json:"events"`
+
+	// Errors is the number of failed requests and error events
+	Errors uint64 `json:"errors"`
+}
+
+// add adds the counters of other to the usage
+func (u *Usage) add(other Usage) {
+	if u.Requests == nil {
+		u.Requests = make(map[string]uint64)
+	}
+	for api, count := range other.Requests {
+		u.Requests[api] += count
+	}
+	u.Events += other.Events
+	u.Errors += other.Errors
+}
+
+// ReportRequest is a request to retrieve the usage
+// recorded between two days
+type ReportRequest struct {
+	// AAD if set limits the report to the usage of the AAD
+	AAD string
+
+	// From is the first day of the report
+	From time.Time
+
+	// To is the last day of the report
+	To time.Time
+}
+
+// days returns the days included in the report
+func (r ReportRequest) days() []string {
+	var days []string
+	from := r.From.UTC().Truncate(24 * time.Hour)
+	for day := from; !day.After(r.To.UTC()); day = day.Add(24 * time.Hour) {
+		days = append(days, Day(day))
+	}
+
+	return days
+}
+
+// Store persists the usage counters
+type Store interface {
+	// Add adds the provided counters to the ones stored
+	Add(ctx context.Context, usages []Usage) error
+
+	// Report returns the usage recorded in the requested
+	// days, ordered by day and AAD
+	Report(ctx context.Context, req ReportRequest) ([]Usage, error)
+}